@@ -2,12 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nguyendkn/nginx-manager/configs"
 	"github.com/nguyendkn/nginx-manager/internal/database"
 	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/models"
 	"github.com/nguyendkn/nginx-manager/internal/routers"
 	"github.com/nguyendkn/nginx-manager/internal/services"
 	"github.com/nguyendkn/nginx-manager/pkg/logger"
@@ -24,6 +32,12 @@ func main() {
 	}
 	defer logger.Sync()
 
+	// Refuse to start with a missing or placeholder JWT secret or an invalid
+	// nginx control strategy
+	if err := env.Validate(); err != nil {
+		logger.Fatal("Invalid environment configuration", logger.Err(err))
+	}
+
 	// Log application startup
 	logger.Info("Starting application",
 		logger.String("app_name", env.GetAppName()),
@@ -35,18 +49,23 @@ func main() {
 	)
 
 	// Initialize Database
-	if err := initializeDatabase(); err != nil {
+	if err := initializeDatabase(env); err != nil {
 		logger.Fatal("Failed to initialize database", logger.Err(err))
 	}
 
 	// Initialize Services
-	serviceContainer := initializeServices()
+	serviceContainer := initializeServices(env)
 
 	// Create Gin router
 	r := setupRouter(env, serviceContainer)
 
+	// SIGINT or SIGTERM cancels ctx, stopping the background services
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Start background services
-	startBackgroundServices(serviceContainer)
+	var background sync.WaitGroup
+	startBackgroundServices(ctx, &background, env, serviceContainer)
 
 	// Get port from environment config
 	port := env.GetPort()
@@ -58,80 +77,302 @@ func main() {
 	)
 
 	// Start server
-	if err := r.Run(":" + port); err != nil {
-		logger.Fatal("Failed to start server", logger.Err(err))
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
 	}
+	server.RegisterOnShutdown(serviceContainer.MonitoringService.CloseConnections)
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start server", logger.Err(err))
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	shutdown(server, &background, env.GetShutdownTimeout())
 }
 
-func initializeServices() *routers.ServiceContainer {
+// shutdown stops accepting requests, waits for in-flight requests and the
+// background services to finish, then closes the database. Whatever is still
+// running when the timeout expires is abandoned.
+func shutdown(server *http.Server, background *sync.WaitGroup, timeout time.Duration) {
+	logger.Info("Shutting down", logger.Duration("timeout", timeout))
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("HTTP server did not shut down cleanly", logger.Err(err))
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		background.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Warn("Background services did not stop before the shutdown timeout")
+	}
+
+	if err := database.CloseDatabase(); err != nil {
+		logger.Error("Failed to close database", logger.Err(err))
+	}
+
+	logger.Info("Server stopped")
+}
+
+func initializeServices(env *configs.Environment) *routers.ServiceContainer {
 	logger.Info("Initializing services...")
 
 	db := database.GetDB()
 
-	// Configuration paths (should come from environment)
-	nginxConfigPath := "/etc/nginx/nginx.conf"
-	sitesPath := "/etc/nginx/sites-available"
-	backupPath := "/var/lib/nginx-manager/backups"
-	templatePath := "/var/lib/nginx-manager/templates"
-	certPath := "/etc/nginx/ssl/certs"
-	keyPath := "/etc/nginx/ssl/private"
-	jwtSecret := "your-jwt-secret-key" // TODO: Get from environment
+	// Configuration paths
+	nginxConfigPath := env.GetNginxConfigPath()
+	sitesPath := env.GetNginxSitesPath()
+	backupPath := env.GetBackupPath()
+	templatePath := env.GetTemplatePath()
+	certPath := env.GetCertPath()
+	keyPath := env.GetKeyPath()
+	jwtSecret := env.GetJWTSecret()
 
 	// Initialize core services
+	nginxRunner := services.NewExecNginxRunner(env.GetNginxBinaryPath())
+	if err := nginxRunner.SetControl(env.GetNginxControlStrategy(), env.GetNginxControlTarget()); err != nil {
+		logger.Fatal("Invalid nginx control configuration", logger.Err(err))
+	}
+	if err := nginxRunner.CheckAvailable(); err != nil {
+		logger.Warn("Nginx control commands will fail until this is fixed; check NGINX_CONTROL_STRATEGY and NGINX_BINARY_PATH",
+			logger.Err(err))
+	}
 	authService := services.NewAuthService(jwtSecret)
 	nginxService := services.NewNginxService(nginxConfigPath, sitesPath, backupPath, templatePath, authService)
+	nginxService.SetNginxRunner(nginxRunner)
+	nginxService.SetStagedDeploy(env.IsNginxStagedDeploy())
+	forwardTargets, err := services.NewForwardTargetPolicy(env.GetForwardTargetAllowlist(), env.GetForwardTargetDenylist())
+	if err != nil {
+		logger.Fatal("Invalid forward target configuration; check FORWARD_TARGET_ALLOWLIST and FORWARD_TARGET_DENYLIST", logger.Err(err))
+	}
+	nginxService.SetForwardTargetPolicy(forwardTargets)
+	nginxService.SetHealthCheckInterval(env.GetHealthCheckInterval())
+	streamService := services.NewStreamService(filepath.Join(filepath.Dir(nginxConfigPath), "streams"), authService)
+	streamService.SetNginxService(nginxService)
+	if err := streamService.CheckStreamInclude(); err != nil {
+		logger.Warn("Streams cannot be enabled until this is fixed", logger.Err(err))
+	}
 	notificationService := services.NewNotificationService()
 
 	// Initialize dependent services
+	userService := services.NewUserService(authService)
 	certificateService := services.NewCertificateService(certPath, keyPath, authService)
+	certificateService.SetForwardTargetPolicy(forwardTargets)
 	accessListService := services.NewAccessListService(authService)
+	accessListService.SetNginxService(nginxService)
+	if err := accessListService.SetGeoIPDatabase(env.GetGeoIPDatabasePath()); err != nil {
+		logger.Warn("Geo access rules disabled", logger.Err(err))
+	}
 	configService := services.NewConfigService(nginxConfigPath, backupPath, templatePath, authService)
+	configService.SetNginxRunner(nginxRunner)
+	if err := configService.SetBackupMode(env.GetConfigBackupMode()); err != nil {
+		logger.Warn("Using default config backup mode", logger.Err(err))
+	}
 	templateService := services.NewTemplateService(authService)
+	templateService.SetConfigService(configService)
 	monitoringService := services.NewMonitoringService(nginxService)
+	monitoringService.SetNginxRunner(nginxRunner)
+	if fsTypes := env.GetDiskExcludedFSTypes(); len(fsTypes) > 0 {
+		monitoringService.SetDiskExcludedFSTypes(fsTypes)
+	}
+	monitoringService.SetNetworkInterfaces(env.GetNetworkInterfaces())
+	monitoringService.SetAllowedOrigins(env.GetCORSAllowedOrigins())
+	monitoringService.SetNginxStatusCacheTTL(env.GetNginxStatusCacheTTL())
+	monitoringService.SetErrorLogPath(env.GetNginxErrorLogPath())
+	monitoringService.SetStubStatusURL(env.GetNginxStubStatusURL())
+	upstreamHealthService := services.NewUpstreamHealthService(authService,
+		env.GetHealthCheckConcurrency(), env.GetHealthCheckTimeout(), env.GetHealthCheckCacheTTL())
 
 	// Initialize analytics service (depends on monitoring service)
 	analyticsService := services.NewAnalyticsService(db, monitoringService, notificationService)
+	analyticsService.SetTimeSeriesStorage(env.IsMetricsTimeSeriesStorage())
+	httpMetricsService := services.NewHTTPMetricsService(analyticsService)
+	healthCheckService := services.NewHealthCheckService(analyticsService,
+		env.GetHealthCheckConcurrency(), env.GetHealthCheckInterval(), env.GetHealthCheckTimeout())
+	healthCheckService.SetForwardTargetPolicy(forwardTargets)
+
+	// Activity feed, recorded by lifecycle operations and pushed over the WebSocket
+	activityService := services.NewActivityService(monitoringService)
+	certificateService.SetActivityService(activityService)
+	certificateService.SetNotificationService(notificationService)
+	certificateService.SetExpiryLeadDays(env.GetCertExpiryLeadDays())
+	configService.SetActivityService(activityService)
+	analyticsService.SetMetricsCollectionInterval(env.GetMetricsCollectionInterval())
+
+	// Full backups of the configuration, written on a schedule or on demand
+	var backupTarget services.BackupTarget = &services.LocalBackupTarget{Dir: env.GetFullBackupDir()}
+	if env.GetFullBackupTarget() == "s3" {
+		bucket, region, endpoint, prefix := env.GetFullBackupS3()
+		accessKey, secretKey := env.GetFullBackupS3Credentials()
+		backupTarget = services.NewS3BackupTarget(bucket, region, endpoint, prefix, accessKey, secretKey)
+	}
+	backupService := services.NewBackupService(backupTarget)
+	backupService.SetNginxService(nginxService)
+	backupService.SetAccessListService(accessListService)
+	backupService.SetRetention(env.GetFullBackupRetention())
+	if err := backupService.SetIncludePrivateKeys(env.IsFullBackupIncludePrivateKeys(), env.GetFullBackupPassphrase()); err != nil {
+		logger.Fatal("Invalid full backup configuration", logger.Err(err))
+	}
+
+	// Settings saved through the API override the environment defaults above
+	settingsService := services.NewSettingsService()
+	registerSettingHooks(env, settingsService, certificateService, analyticsService)
+	if err := settingsService.Reload(); err != nil {
+		logger.Warn("Failed to apply stored settings", logger.Err(err))
+	}
 
 	logger.Info("Services initialized successfully")
 
 	return &routers.ServiceContainer{
-		AuthService:         authService,
-		CertificateService:  certificateService,
-		MonitoringService:   monitoringService,
-		AnalyticsService:    analyticsService,
-		NotificationService: notificationService,
-		ConfigService:       configService,
-		TemplateService:     templateService,
-		AccessListService:   accessListService,
-		NginxService:        nginxService,
+		AuthService:           authService,
+		CertificateService:    certificateService,
+		MonitoringService:     monitoringService,
+		AnalyticsService:      analyticsService,
+		NotificationService:   notificationService,
+		ConfigService:         configService,
+		TemplateService:       templateService,
+		AccessListService:     accessListService,
+		NginxService:          nginxService,
+		StreamService:         streamService,
+		UpstreamHealthService: upstreamHealthService,
+		HealthCheckService:    healthCheckService,
+		HTTPMetricsService:    httpMetricsService,
+		UserService:           userService,
+		ActivityService:       activityService,
+		SettingsService:       settingsService,
+		BackupService:         backupService,
 	}
 }
 
-func startBackgroundServices(services *routers.ServiceContainer) {
+// registerSettingHooks applies runtime settings to the services using them.
+// A cleared setting falls back to the environment default.
+func registerSettingHooks(env *configs.Environment, settingsService *services.SettingsService,
+	certificateService *services.CertificateService, analyticsService *services.AnalyticsService) {
+	settingsService.OnChange(services.CertificateRenewalDaysSettingID, func(setting *models.Setting) error {
+		days, _ := setting.IntValue()
+		certificateService.SetRenewalLeadDays(days)
+		return nil
+	})
+	settingsService.OnChange(services.CertificateExpiryLeadDaysSettingID, func(setting *models.Setting) error {
+		days, ok := setting.IntValue()
+		if !ok {
+			days = env.GetCertExpiryLeadDays()
+		}
+		certificateService.SetExpiryLeadDays(days)
+		return nil
+	})
+	settingsService.OnChange(services.LetsEncryptStagingSettingID, func(setting *models.Setting) error {
+		staging, _ := setting.BoolValue()
+		certificateService.SetLetsEncryptStaging(staging)
+		return nil
+	})
+	settingsService.OnChange(services.LetsEncryptEmailSettingID, func(setting *models.Setting) error {
+		email, _ := setting.StringValue()
+		return certificateService.SetLetsEncryptEmail(email)
+	})
+	settingsService.OnChange(services.MetricsCollectionIntervalSettingID, func(setting *models.Setting) error {
+		interval := env.GetMetricsCollectionInterval()
+		if seconds, ok := setting.IntValue(); ok {
+			interval = time.Duration(seconds) * time.Second
+		}
+		analyticsService.SetMetricsCollectionInterval(interval)
+		return nil
+	})
+}
+
+// startBackgroundServices runs the background jobs until ctx is done. Each
+// job is tracked by background so shutdown can wait for it to finish.
+func startBackgroundServices(ctx context.Context, background *sync.WaitGroup, env *configs.Environment, services *routers.ServiceContainer) {
 	logger.Info("Starting background services...")
 
-	// Start analytics metrics collection every 5 minutes
-	go func() {
-		ctx := context.Background()
-		services.AnalyticsService.StartMetricsCollection(ctx, 5*time.Minute)
-	}()
+	run := func(job func()) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			job()
+		}()
+	}
 
-	// Start metrics cleanup every hour
-	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
+	// Start analytics metrics collection
+	run(func() {
+		services.AnalyticsService.StartMetricsCollection(ctx)
+	})
+
+	// Flush per-proxy-host request metrics every minute
+	run(func() {
+		services.HTTPMetricsService.StartFlusher(ctx, time.Minute)
+	})
+
+	// Flush notifications held back by quiet hours every minute
+	run(func() {
+		services.NotificationService.StartQueueFlusher(ctx, time.Minute)
+	})
+
+	// Notify owners of certificates that are expiring and will not auto-renew
+	run(func() {
+		services.CertificateService.StartExpiryNotifications(ctx, env.GetCertExpiryCheckInterval())
+	})
+
+	// Probe proxy host upstreams and record their health
+	if env.GetHealthCheckInterval() > 0 {
+		run(func() {
+			services.HealthCheckService.StartHealthChecks(ctx)
+		})
+	}
+
+	// Write full configuration backups to the configured target
+	if interval := env.GetFullBackupInterval(); interval > 0 {
+		run(func() {
+			services.BackupService.StartScheduledBackups(ctx, interval)
+		})
+	}
+
+	// Push real-time metrics to monitoring WebSocket clients; each client's
+	// topic interval decides how often it actually receives data
+	run(func() {
+		services.MonitoringService.StartMetricsBroadcast(ctx, time.Second)
+	})
+
+	// Stream new nginx error log lines to subscribed WebSocket clients
+	run(func() {
+		services.MonitoringService.StartErrorLogTail(ctx, time.Second)
+	})
+
+	// Start metrics cleanup
+	run(func() {
+		ticker := time.NewTicker(env.GetMetricsCleanupInterval())
 		defer ticker.Stop()
 
-		for range ticker.C {
-			if err := services.AnalyticsService.CleanupExpiredMetrics(); err != nil {
-				logger.Error("Failed to cleanup expired metrics", logger.Err(err))
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := services.AnalyticsService.CleanupExpiredMetrics(); err != nil {
+					logger.Error("Failed to cleanup expired metrics", logger.Err(err))
+				}
+				if err := services.ActivityService.CleanupOldActivity(); err != nil {
+					logger.Error("Failed to cleanup old activity events", logger.Err(err))
+				}
 			}
 		}
-	}()
+	})
 
 	logger.Info("Background services started")
 }
 
-func initializeDatabase() error {
+func initializeDatabase(env *configs.Environment) error {
 	logger.Info("Initializing database...")
 
 	// Load database configuration
@@ -150,6 +391,20 @@ func initializeDatabase() error {
 		return err
 	}
 
+	// Create the time-series metrics table and move existing raw metrics into it
+	if env.IsMetricsTimeSeriesStorage() {
+		if err := database.MigrateTimeSeriesStorage(db); err != nil {
+			return err
+		}
+		moved, err := database.MoveHistoricalMetricsToRaw(db, 5000)
+		if err != nil {
+			return err
+		}
+		if moved > 0 {
+			logger.Info("Moved historical metrics to time-series storage", logger.Int64("count", moved))
+		}
+	}
+
 	// Seed initial data
 	if err := database.SeedData(db); err != nil {
 		return err
@@ -174,11 +429,20 @@ func setupRouter(env *configs.Environment, services *routers.ServiceContainer) *
 	r.Use(logger.ErrorLogger())
 	r.Use(logger.RecoveryLogger())
 
+	// Record request metrics tagged by proxy host
+	r.Use(middleware.HTTPMetricsMiddleware(services.HTTPMetricsService))
+
 	// Add CORS middleware with environment configuration
 	r.Use(middleware.CORSMiddleware(env))
 
+	// Limit request bodies; routes taking config content or flags override this
+	r.Use(middleware.BodyLimitMiddleware(env.GetMaxRequestBodySize()))
+
 	// Setup health routes
-	routers.SetupHealthRoutes(r, env)
+	routers.SetupHealthRoutes(r, env, services.MonitoringService)
+
+	// Setup the Prometheus metrics endpoint
+	routers.SetupMetricsRoutes(r, env, services.MonitoringService)
 
 	// Setup API routes with service injection
 	routers.SetupAPIRoutesWithServices(r, services)
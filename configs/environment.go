@@ -1,9 +1,33 @@
 package configs
 
 import (
+	"errors"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// insecureJWTSecrets are placeholder secrets that must never sign tokens
+var insecureJWTSecrets = []string{"your-jwt-secret-key", "nginx-manager-secret"}
+
+// nginxControlStrategies are the supported NGINX_CONTROL_STRATEGY values
+var nginxControlStrategies = []string{"direct", "systemd", "docker"}
+
+// fullBackupTargets are the supported FULL_BACKUP_TARGET values
+var fullBackupTargets = []string{"local", "s3"}
+
+var (
+	ErrJWTSecretMissing      = errors.New("JWT_SECRET must be set")
+	ErrJWTSecretInsecure     = errors.New("JWT_SECRET is a known default; set a unique random secret")
+	ErrNginxControlStrategy  = errors.New("NGINX_CONTROL_STRATEGY must be direct, systemd or docker")
+	ErrNginxContainerMissing = errors.New("NGINX_CONTAINER must be set for the docker control strategy")
+	ErrFullBackupTarget      = errors.New("FULL_BACKUP_TARGET must be local or s3")
+	ErrFullBackupBucket      = errors.New("FULL_BACKUP_S3_BUCKET must be set for the s3 backup target")
+	ErrFullBackupPassphrase  = errors.New("FULL_BACKUP_PASSPHRASE must be set when FULL_BACKUP_INCLUDE_PRIVATE_KEYS is enabled")
+	ErrNginxStubStatusURL    = errors.New("NGINX_STUB_STATUS_URL must be an http(s) URL or off")
 )
 
 // Environment holds all environment configuration
@@ -28,6 +52,88 @@ type Environment struct {
 	// Logging configuration
 	LogLevel    string `json:"log_level"`
 	LogEncoding string `json:"log_encoding"`
+
+	// Nginx deployment configuration
+	NginxStagedDeploy bool `json:"nginx_staged_deploy"`
+
+	// Nginx process control configuration
+	NginxControlStrategy string `json:"nginx_control_strategy"` // direct, systemd or docker
+	NginxServiceName     string `json:"nginx_service_name"`     // systemd unit
+	NginxContainer       string `json:"nginx_container"`        // docker container
+
+	// Config backups
+	ConfigBackupMode string `json:"config_backup_mode"` // always, on-deploy or manual
+
+	// Full disaster recovery backups
+	FullBackupInterval           int    `json:"full_backup_interval"`  // hours, 0 disables the schedule
+	FullBackupRetention          int    `json:"full_backup_retention"` // local archives kept, 0 keeps every archive
+	FullBackupTarget             string `json:"full_backup_target"`    // local or s3
+	FullBackupDir                string `json:"full_backup_dir"`       // empty uses BACKUP_PATH/full
+	FullBackupS3Bucket           string `json:"full_backup_s3_bucket"`
+	FullBackupS3Region           string `json:"full_backup_s3_region"`
+	FullBackupS3Endpoint         string `json:"full_backup_s3_endpoint"` // empty uses AWS
+	FullBackupS3Prefix           string `json:"full_backup_s3_prefix"`
+	FullBackupS3AccessKey        string `json:"-"`
+	FullBackupS3SecretKey        string `json:"-"`
+	FullBackupIncludePrivateKeys bool   `json:"full_backup_include_private_keys"`
+	FullBackupPassphrase         string `json:"-"` // encrypts archives holding private keys
+
+	// Metrics storage configuration
+	MetricsTimeSeriesStorage bool `json:"metrics_time_series_storage"`
+
+	// Disk monitoring configuration
+	DiskExcludedFSTypes         []string `json:"disk_excluded_fs_types"`          // empty uses the built-in pseudo filesystem list
+	ReadinessMinFreeDiskPercent int      `json:"readiness_min_free_disk_percent"` // /health/ready fails below this free space on /
+
+	// Network monitoring configuration
+	NetworkInterfaces []string `json:"network_interfaces"` // empty includes every interface except loopback
+
+	// Nginx status polling configuration
+	NginxStatusCacheTTL int `json:"nginx_status_cache_ttl"` // seconds
+
+	// Access list configuration
+	GeoIPDatabasePath string `json:"geoip_database_path"` // MaxMind country mmdb for geo rules, empty disables them
+
+	// Proxy host forward target restrictions; both empty allows any target
+	ForwardTargetAllowlist []string `json:"forward_target_allowlist"` // hosts, IPs or CIDRs targets must match
+	ForwardTargetDenylist  []string `json:"forward_target_denylist"`  // hosts, IPs or CIDRs targets must not match
+
+	// Upstream health check configuration
+	HealthCheckConcurrency int `json:"health_check_concurrency"`
+	HealthCheckTimeout     int `json:"health_check_timeout"`   // seconds
+	HealthCheckCacheTTL    int `json:"health_check_cache_ttl"` // seconds
+	HealthCheckInterval    int `json:"health_check_interval"`  // seconds between background probes, 0 disables them
+
+	// Certificate expiry notification configuration
+	CertExpiryCheckInterval int `json:"cert_expiry_check_interval"` // hours
+	CertExpiryLeadDays      int `json:"cert_expiry_lead_days"`
+
+	// Authentication configuration
+	JWTSecret string `json:"-"`
+
+	// Request body limit, overridden per route for large or flag-only payloads
+	MaxRequestBodySize int64 `json:"max_request_body_size"` // bytes
+
+	// Prometheus exporter configuration
+	MetricsExporterToken string `json:"-"` // bearer token required by /metrics, empty leaves it open
+
+	// Nginx and storage paths
+	NginxBinaryPath string `json:"nginx_binary_path"`
+	NginxConfigPath string `json:"nginx_config_path"`
+	NginxSitesPath  string `json:"nginx_sites_path"`
+	NginxErrorLog   string `json:"nginx_error_log"`
+	NginxStubStatus string `json:"nginx_stub_status"` // stub_status URL, or off
+	BackupPath      string `json:"backup_path"`
+	TemplatePath    string `json:"template_path"`
+	CertPath        string `json:"cert_path"`
+	KeyPath         string `json:"key_path"`
+
+	// Background job intervals
+	MetricsCollectionInterval int `json:"metrics_collection_interval"` // seconds
+	MetricsCleanupInterval    int `json:"metrics_cleanup_interval"`    // seconds
+
+	// Graceful shutdown configuration
+	ShutdownTimeout int `json:"shutdown_timeout"` // seconds
 }
 
 // LoadEnvironment loads environment variables into Environment struct
@@ -47,12 +153,94 @@ func LoadEnvironment() *Environment {
 
 		// CORS configuration
 		CORSAllowedOrigins: getEnvSliceWithDefault("CORS_ALLOWED_ORIGINS", []string{"*"}),
-		CORSAllowedMethods: getEnvSliceWithDefault("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		CORSAllowedMethods: getEnvSliceWithDefault("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
 		CORSAllowedHeaders: getEnvSliceWithDefault("CORS_ALLOWED_HEADERS", []string{"*"}),
 
 		// Logging configuration
 		LogLevel:    getEnvWithDefault("LOG_LEVEL", "info"),
 		LogEncoding: getEnvWithDefault("LOG_ENCODING", "console"),
+
+		// Nginx deployment configuration
+		NginxStagedDeploy: getEnvBoolWithDefault("NGINX_STAGED_DEPLOY", false),
+
+		// Nginx process control configuration
+		NginxControlStrategy: getEnvWithDefault("NGINX_CONTROL_STRATEGY", "direct"),
+		NginxServiceName:     getEnvWithDefault("NGINX_SERVICE_NAME", "nginx"),
+		NginxContainer:       getEnvWithDefault("NGINX_CONTAINER", ""),
+
+		// Config backup configuration
+		ConfigBackupMode: getEnvWithDefault("CONFIG_BACKUP_MODE", "always"),
+
+		// Full backup configuration
+		FullBackupInterval:           getEnvIntWithDefault("FULL_BACKUP_INTERVAL", 24),
+		FullBackupRetention:          getEnvIntWithDefault("FULL_BACKUP_RETENTION", 7),
+		FullBackupTarget:             getEnvWithDefault("FULL_BACKUP_TARGET", "local"),
+		FullBackupDir:                getEnvWithDefault("FULL_BACKUP_DIR", ""),
+		FullBackupS3Bucket:           getEnvWithDefault("FULL_BACKUP_S3_BUCKET", ""),
+		FullBackupS3Region:           getEnvWithDefault("FULL_BACKUP_S3_REGION", "us-east-1"),
+		FullBackupS3Endpoint:         getEnvWithDefault("FULL_BACKUP_S3_ENDPOINT", ""),
+		FullBackupS3Prefix:           getEnvWithDefault("FULL_BACKUP_S3_PREFIX", ""),
+		FullBackupS3AccessKey:        os.Getenv("FULL_BACKUP_S3_ACCESS_KEY"),
+		FullBackupS3SecretKey:        os.Getenv("FULL_BACKUP_S3_SECRET_KEY"),
+		FullBackupIncludePrivateKeys: getEnvBoolWithDefault("FULL_BACKUP_INCLUDE_PRIVATE_KEYS", false),
+		FullBackupPassphrase:         os.Getenv("FULL_BACKUP_PASSPHRASE"),
+
+		// Metrics storage configuration
+		MetricsTimeSeriesStorage: getEnvBoolWithDefault("METRICS_TIME_SERIES_STORAGE", false),
+
+		// Disk monitoring configuration
+		DiskExcludedFSTypes:         getEnvSliceWithDefault("DISK_EXCLUDED_FS_TYPES", nil),
+		ReadinessMinFreeDiskPercent: getEnvIntWithDefault("READINESS_MIN_FREE_DISK_PERCENT", 5),
+
+		// Network monitoring configuration
+		NetworkInterfaces: getEnvSliceWithDefault("NETWORK_INTERFACES", nil),
+
+		// Nginx status polling configuration
+		NginxStatusCacheTTL: getEnvIntWithDefault("NGINX_STATUS_CACHE_TTL", 5),
+
+		// Access list configuration
+		GeoIPDatabasePath: getEnvWithDefault("GEOIP_DATABASE_PATH", ""),
+
+		// Forward target restrictions
+		ForwardTargetAllowlist: getEnvSliceWithDefault("FORWARD_TARGET_ALLOWLIST", nil),
+		ForwardTargetDenylist:  getEnvSliceWithDefault("FORWARD_TARGET_DENYLIST", nil),
+
+		// Upstream health check configuration
+		HealthCheckConcurrency: getEnvIntWithDefault("HEALTH_CHECK_CONCURRENCY", 10),
+		HealthCheckTimeout:     getEnvIntWithDefault("HEALTH_CHECK_TIMEOUT", 5),
+		HealthCheckCacheTTL:    getEnvIntWithDefault("HEALTH_CHECK_CACHE_TTL", 30),
+		HealthCheckInterval:    getEnvIntWithDefault("HEALTH_CHECK_INTERVAL", 60),
+
+		// Certificate expiry notification configuration
+		CertExpiryCheckInterval: getEnvIntWithDefault("CERT_EXPIRY_CHECK_INTERVAL", 24),
+		CertExpiryLeadDays:      getEnvIntWithDefault("CERT_EXPIRY_LEAD_DAYS", 14),
+
+		// Authentication configuration
+		JWTSecret: os.Getenv("JWT_SECRET"),
+
+		// Prometheus exporter configuration
+		MetricsExporterToken: os.Getenv("METRICS_EXPORTER_TOKEN"),
+
+		// Request body limit configuration
+		MaxRequestBodySize: int64(getEnvIntWithDefault("MAX_REQUEST_BODY_SIZE", 1<<20)),
+
+		// Nginx and storage paths
+		NginxBinaryPath: getEnvWithDefault("NGINX_BINARY_PATH", "nginx"),
+		NginxConfigPath: getEnvWithDefault("NGINX_CONFIG_PATH", "/etc/nginx/nginx.conf"),
+		NginxSitesPath:  getEnvWithDefault("NGINX_SITES_PATH", "/etc/nginx/sites-available"),
+		NginxErrorLog:   getEnvWithDefault("NGINX_ERROR_LOG_PATH", "/var/log/nginx/error.log"),
+		NginxStubStatus: getEnvWithDefault("NGINX_STUB_STATUS_URL", "http://127.0.0.1/nginx_status"),
+		BackupPath:      getEnvWithDefault("BACKUP_PATH", "/var/lib/nginx-manager/backups"),
+		TemplatePath:    getEnvWithDefault("TEMPLATE_PATH", "/var/lib/nginx-manager/templates"),
+		CertPath:        getEnvWithDefault("CERT_PATH", "/etc/nginx/ssl/certs"),
+		KeyPath:         getEnvWithDefault("KEY_PATH", "/etc/nginx/ssl/private"),
+
+		// Background job intervals
+		MetricsCollectionInterval: getEnvIntWithDefault("METRICS_COLLECTION_INTERVAL", 300),
+		MetricsCleanupInterval:    getEnvIntWithDefault("METRICS_CLEANUP_INTERVAL", 3600),
+
+		// Graceful shutdown configuration
+		ShutdownTimeout: getEnvIntWithDefault("SHUTDOWN_TIMEOUT", 30),
 	}
 
 	return env
@@ -162,6 +350,257 @@ func (e *Environment) GetLogEncoding() string {
 	return e.LogEncoding
 }
 
+// IsNginxStagedDeploy returns true if proxy host edits are staged until explicitly applied
+func (e *Environment) IsNginxStagedDeploy() bool {
+	return e.NginxStagedDeploy
+}
+
+// GetNginxControlStrategy returns how nginx is started, stopped and reloaded
+func (e *Environment) GetNginxControlStrategy() string {
+	return e.NginxControlStrategy
+}
+
+// GetNginxControlTarget returns the systemd unit or docker container nginx
+// is controlled through, depending on the control strategy
+func (e *Environment) GetNginxControlTarget() string {
+	if e.NginxControlStrategy == "docker" {
+		return e.NginxContainer
+	}
+	return e.NginxServiceName
+}
+
+// GetConfigBackupMode returns when configuration backups are taken automatically
+func (e *Environment) GetConfigBackupMode() string {
+	return e.ConfigBackupMode
+}
+
+// Full Backup Configuration Getters
+
+// GetFullBackupInterval returns how often full backups are written, or 0 if
+// they are only taken on demand
+func (e *Environment) GetFullBackupInterval() time.Duration {
+	if e.FullBackupInterval <= 0 {
+		return 0
+	}
+	return time.Duration(e.FullBackupInterval) * time.Hour
+}
+
+// GetFullBackupRetention returns how many archives the local backup target
+// keeps, or 0 to keep every archive
+func (e *Environment) GetFullBackupRetention() int {
+	if e.FullBackupRetention < 0 {
+		return 0
+	}
+	return e.FullBackupRetention
+}
+
+// GetFullBackupTarget returns where full backups are written, local or s3
+func (e *Environment) GetFullBackupTarget() string {
+	return e.FullBackupTarget
+}
+
+// GetFullBackupDir returns the directory local full backups are written to
+func (e *Environment) GetFullBackupDir() string {
+	if e.FullBackupDir == "" {
+		return filepath.Join(e.BackupPath, "full")
+	}
+	return e.FullBackupDir
+}
+
+// GetFullBackupS3 returns the bucket, region, endpoint and key prefix of the s3 backup target
+func (e *Environment) GetFullBackupS3() (bucket, region, endpoint, prefix string) {
+	return e.FullBackupS3Bucket, e.FullBackupS3Region, e.FullBackupS3Endpoint, e.FullBackupS3Prefix
+}
+
+// GetFullBackupS3Credentials returns the access key and secret key of the s3 backup target
+func (e *Environment) GetFullBackupS3Credentials() (accessKey, secretKey string) {
+	return e.FullBackupS3AccessKey, e.FullBackupS3SecretKey
+}
+
+// IsFullBackupIncludePrivateKeys returns true if full backups carry private keys, encrypted
+func (e *Environment) IsFullBackupIncludePrivateKeys() bool {
+	return e.FullBackupIncludePrivateKeys
+}
+
+// GetFullBackupPassphrase returns the passphrase that encrypts and decrypts full backups
+func (e *Environment) GetFullBackupPassphrase() string {
+	return e.FullBackupPassphrase
+}
+
+// IsMetricsTimeSeriesStorage returns true if raw metrics are stored in the lean raw_metrics table
+func (e *Environment) IsMetricsTimeSeriesStorage() bool {
+	return e.MetricsTimeSeriesStorage
+}
+
+// GetDiskExcludedFSTypes returns the filesystem types to leave out of disk stats, or nil for the defaults
+func (e *Environment) GetDiskExcludedFSTypes() []string {
+	return e.DiskExcludedFSTypes
+}
+
+// GetReadinessMinFreeDiskPercent returns the free space on / below which the readiness check fails
+func (e *Environment) GetReadinessMinFreeDiskPercent() float64 {
+	return float64(e.ReadinessMinFreeDiskPercent)
+}
+
+// GetNetworkInterfaces returns the interfaces to include in network stats, or nil for all but loopback
+func (e *Environment) GetNetworkInterfaces() []string {
+	return e.NetworkInterfaces
+}
+
+// GetForwardTargetAllowlist returns the hosts, IPs and CIDRs proxy hosts may forward to, or nil for any
+func (e *Environment) GetForwardTargetAllowlist() []string {
+	return e.ForwardTargetAllowlist
+}
+
+// GetForwardTargetDenylist returns the hosts, IPs and CIDRs proxy hosts may not forward to
+func (e *Environment) GetForwardTargetDenylist() []string {
+	return e.ForwardTargetDenylist
+}
+
+// GetNginxStatusCacheTTL returns how long a polled nginx status is shared between callers
+func (e *Environment) GetNginxStatusCacheTTL() time.Duration {
+	return time.Duration(e.NginxStatusCacheTTL) * time.Second
+}
+
+// GetGeoIPDatabasePath returns the MaxMind country database used by geo access rules, or "" if unset
+func (e *Environment) GetGeoIPDatabasePath() string {
+	return e.GeoIPDatabasePath
+}
+
+// Health Check Configuration Getters
+
+// GetHealthCheckConcurrency returns the maximum number of concurrent upstream checks
+func (e *Environment) GetHealthCheckConcurrency() int {
+	return e.HealthCheckConcurrency
+}
+
+// GetHealthCheckTimeout returns the per-check upstream timeout
+func (e *Environment) GetHealthCheckTimeout() time.Duration {
+	return time.Duration(e.HealthCheckTimeout) * time.Second
+}
+
+// GetHealthCheckCacheTTL returns how long upstream check results are cached
+func (e *Environment) GetHealthCheckCacheTTL() time.Duration {
+	return time.Duration(e.HealthCheckCacheTTL) * time.Second
+}
+
+// GetHealthCheckInterval returns the default interval between background
+// upstream probes, or 0 if they are disabled
+func (e *Environment) GetHealthCheckInterval() time.Duration {
+	if e.HealthCheckInterval <= 0 {
+		return 0
+	}
+	return time.Duration(e.HealthCheckInterval) * time.Second
+}
+
+// Certificate Expiry Configuration Getters
+
+// GetCertExpiryCheckInterval returns how often expiring certificates are checked
+func (e *Environment) GetCertExpiryCheckInterval() time.Duration {
+	if e.CertExpiryCheckInterval <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(e.CertExpiryCheckInterval) * time.Hour
+}
+
+// GetCertExpiryLeadDays returns how many days before expiry owners are notified
+func (e *Environment) GetCertExpiryLeadDays() int {
+	return e.CertExpiryLeadDays
+}
+
+// Authentication Configuration Getters
+
+// GetJWTSecret returns the secret used to sign and verify access tokens
+func (e *Environment) GetJWTSecret() string {
+	return e.JWTSecret
+}
+
+// Path Configuration Getters
+
+// GetNginxBinaryPath returns the nginx binary to run, looked up on PATH
+// unless it is a path
+func (e *Environment) GetNginxBinaryPath() string {
+	return e.NginxBinaryPath
+}
+
+// GetNginxConfigPath returns the main nginx configuration file
+func (e *Environment) GetNginxConfigPath() string {
+	return e.NginxConfigPath
+}
+
+// GetNginxSitesPath returns the directory generated site configs are written to
+func (e *Environment) GetNginxSitesPath() string {
+	return e.NginxSitesPath
+}
+
+// GetNginxErrorLogPath returns the nginx error log shown by the monitoring API
+func (e *Environment) GetNginxErrorLogPath() string {
+	return e.NginxErrorLog
+}
+
+// GetNginxStubStatusURL returns the stub_status page scraped for nginx
+// connection stats, or "" when scraping is off
+func (e *Environment) GetNginxStubStatusURL() string {
+	if e.NginxStubStatus == "off" {
+		return ""
+	}
+	return e.NginxStubStatus
+}
+
+// GetBackupPath returns the directory configuration backups are kept in
+func (e *Environment) GetBackupPath() string {
+	return e.BackupPath
+}
+
+// GetTemplatePath returns the directory configuration templates are read from
+func (e *Environment) GetTemplatePath() string {
+	return e.TemplatePath
+}
+
+// GetCertPath returns the directory certificates are stored in
+func (e *Environment) GetCertPath() string {
+	return e.CertPath
+}
+
+// GetKeyPath returns the directory private keys are stored in
+func (e *Environment) GetKeyPath() string {
+	return e.KeyPath
+}
+
+// Background Job Configuration Getters
+
+// GetMetricsCollectionInterval returns how often analytics metrics are collected
+func (e *Environment) GetMetricsCollectionInterval() time.Duration {
+	if e.MetricsCollectionInterval <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(e.MetricsCollectionInterval) * time.Second
+}
+
+// GetMetricsCleanupInterval returns how often expired metrics and activity are removed
+func (e *Environment) GetMetricsCleanupInterval() time.Duration {
+	if e.MetricsCleanupInterval <= 0 {
+		return time.Hour
+	}
+	return time.Duration(e.MetricsCleanupInterval) * time.Second
+}
+
+// GetMaxRequestBodySize returns the default request body limit in bytes
+func (e *Environment) GetMaxRequestBodySize() int64 {
+	if e.MaxRequestBodySize <= 0 {
+		return 1 << 20
+	}
+	return e.MaxRequestBodySize
+}
+
+// GetShutdownTimeout returns how long shutdown waits for in-flight requests and background jobs
+func (e *Environment) GetShutdownTimeout() time.Duration {
+	if e.ShutdownTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(e.ShutdownTimeout) * time.Second
+}
+
 // Application Configuration Getters
 
 // GetAppName returns the application name
@@ -188,7 +627,45 @@ func (e *Environment) GetGinMode() string {
 
 // Validate validates the environment configuration
 func (e *Environment) Validate() error {
-	// Add validation logic here if needed
+	if strings.TrimSpace(e.JWTSecret) == "" {
+		return ErrJWTSecretMissing
+	}
+	for _, insecure := range insecureJWTSecrets {
+		if e.JWTSecret == insecure {
+			return ErrJWTSecretInsecure
+		}
+	}
+
+	validStrategy := false
+	for _, strategy := range nginxControlStrategies {
+		validStrategy = validStrategy || e.NginxControlStrategy == strategy
+	}
+	if !validStrategy {
+		return ErrNginxControlStrategy
+	}
+	if e.NginxControlStrategy == "docker" && strings.TrimSpace(e.NginxContainer) == "" {
+		return ErrNginxContainerMissing
+	}
+
+	validTarget := false
+	for _, target := range fullBackupTargets {
+		validTarget = validTarget || e.FullBackupTarget == target
+	}
+	if !validTarget {
+		return ErrFullBackupTarget
+	}
+	if e.FullBackupTarget == "s3" && strings.TrimSpace(e.FullBackupS3Bucket) == "" {
+		return ErrFullBackupBucket
+	}
+	if e.FullBackupIncludePrivateKeys && e.FullBackupPassphrase == "" {
+		return ErrFullBackupPassphrase
+	}
+	if stubStatusURL := e.GetNginxStubStatusURL(); stubStatusURL != "" {
+		parsed, err := url.Parse(stubStatusURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return ErrNginxStubStatusURL
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// AccessListController handles access list endpoints
+type AccessListController struct {
+	accessListService *services.AccessListService
+}
+
+// NewAccessListController creates a new access list controller
+func NewAccessListController(accessListService *services.AccessListService) *AccessListController {
+	return &AccessListController{
+		accessListService: accessListService,
+	}
+}
+
+// NormalizeAccessList handles POST /api/v1/access-lists/:id/normalize. With
+// ?dry_run=true the report of proposed changes is returned without saving.
+func (alc *AccessListController) NormalizeAccessList(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid access list ID", err)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+
+	if alc.accessListService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Access list service not available", nil)
+		return
+	}
+
+	report, err := alc.accessListService.NormalizeAccessList(userID, uint(id), dryRun, services.NewAuditContext(c))
+	if err != nil {
+		if err == services.ErrAccessListNotFound {
+			response.NotFoundJSONWithLog(c, "Access list not found")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to normalize access list", err)
+		return
+	}
+
+	message := "Access list normalized successfully"
+	if dryRun {
+		message = "Access list normalization preview"
+	}
+	response.SuccessJSONWithLog(c, report, message)
+}
+
+// ExportAccessList handles GET /api/v1/access-lists/:id/export?format=nginx|apache|json
+func (alc *AccessListController) ExportAccessList(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid access list ID", err)
+		return
+	}
+
+	if !rejectIncludes(c) {
+		return
+	}
+
+	format := c.DefaultQuery("format", services.AccessListExportNginx)
+
+	if alc.accessListService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Access list service not available", nil)
+		return
+	}
+
+	content, err := alc.accessListService.ExportAccessList(userID, uint(id), format)
+	if err != nil {
+		switch err {
+		case services.ErrUnsupportedExportFormat:
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		case services.ErrAccessListNotFound:
+			response.NotFoundJSONWithLog(c, "Access list not found")
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to export access list", err)
+		}
+		return
+	}
+
+	response.SuccessJSONWithLog(c, gin.H{
+		"format":  format,
+		"content": content,
+	}, "Access list exported successfully")
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"strconv"
 	"time"
 
@@ -22,6 +23,194 @@ func NewAnalyticsController(analyticsService *services.AnalyticsService) *Analyt
 	}
 }
 
+// GetMetricCatalog handles GET /api/v1/analytics/metrics/catalog
+func (ac *AnalyticsController) GetMetricCatalog(c *gin.Context) {
+	catalog, err := ac.analyticsService.GetMetricCatalog()
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get metric catalog", err)
+		return
+	}
+
+	result := gin.H{
+		"metrics":   catalog,
+		"count":     len(catalog),
+		"timestamp": time.Now(),
+	}
+
+	response.SuccessJSONWithLog(c, result, "Metric catalog retrieved successfully")
+}
+
+// MetricCollectorsRequest represents the enabled metric collector set
+type MetricCollectorsRequest struct {
+	Enabled []string `json:"enabled"`
+}
+
+// GetMetricCollectors handles GET /api/v1/analytics/metrics/collectors
+func (ac *AnalyticsController) GetMetricCollectors(c *gin.Context) {
+	collectors, err := ac.analyticsService.GetMetricCollectors()
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get metric collectors", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, gin.H{
+		"collectors": collectors,
+		"count":      len(collectors),
+	}, "Metric collectors retrieved successfully")
+}
+
+// UpdateMetricCollectors handles PUT /api/v1/analytics/metrics/collectors
+func (ac *AnalyticsController) UpdateMetricCollectors(c *gin.Context) {
+	var req MetricCollectorsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request data", err)
+		return
+	}
+
+	if err := ac.analyticsService.SetMetricCollectors(req.Enabled); err != nil {
+		if errors.Is(err, services.ErrInvalidMetricCollector) {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to update metric collectors", err)
+		return
+	}
+
+	collectors, err := ac.analyticsService.GetMetricCollectors()
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get metric collectors", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, gin.H{
+		"collectors": collectors,
+		"count":      len(collectors),
+	}, "Metric collectors updated successfully")
+}
+
+// GetAggregationStatus handles GET /api/v1/analytics/metrics/aggregations
+func (ac *AnalyticsController) GetAggregationStatus(c *gin.Context) {
+	metricType := c.Query("metric_type")
+	metricName := c.Query("metric_name")
+	if metricType == "" || metricName == "" {
+		response.BadRequestJSONWithLog(c, "metric_type and metric_name are required", nil)
+		return
+	}
+
+	status, err := ac.analyticsService.GetAggregationStatus(metricType, metricName)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get aggregation status", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, status, "Aggregation status retrieved successfully")
+}
+
+// BackfillAggregations handles POST /api/v1/analytics/metrics/aggregations/backfill
+func (ac *AnalyticsController) BackfillAggregations(c *gin.Context) {
+	var req services.AggregationBackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request data", err)
+		return
+	}
+
+	result, err := ac.analyticsService.BackfillAggregations(req.MetricType, req.MetricName, req.TimeRange, req.Windows)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAggregationWindow) || errors.Is(err, services.ErrInvalidBackfillRange) {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to backfill aggregations", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "Aggregations backfilled successfully")
+}
+
+// GetRetentionPolicies handles GET /api/v1/analytics/metrics/retention-policies
+func (ac *AnalyticsController) GetRetentionPolicies(c *gin.Context) {
+	policies, err := ac.analyticsService.ListRetentionPolicies()
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get retention policies", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, gin.H{
+		"policies": policies,
+		"count":    len(policies),
+	}, "Retention policies retrieved successfully")
+}
+
+// CreateRetentionPolicy handles POST /api/v1/analytics/metrics/retention-policies
+func (ac *AnalyticsController) CreateRetentionPolicy(c *gin.Context) {
+	var policy models.RetentionPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid retention policy data", err)
+		return
+	}
+
+	if err := ac.analyticsService.CreateRetentionPolicy(&policy); err != nil {
+		if errors.Is(err, services.ErrInvalidRetentionPolicy) {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to create retention policy", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, policy, "Retention policy created successfully")
+}
+
+// UpdateRetentionPolicy handles PUT /api/v1/analytics/metrics/retention-policies/{id}
+func (ac *AnalyticsController) UpdateRetentionPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid retention policy ID", err)
+		return
+	}
+
+	var policy models.RetentionPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid retention policy data", err)
+		return
+	}
+	policy.ID = uint(id)
+
+	if err := ac.analyticsService.UpdateRetentionPolicy(&policy); err != nil {
+		switch {
+		case errors.Is(err, services.ErrRetentionPolicyNotFound):
+			response.NotFoundJSONWithLog(c, "Retention policy not found")
+		case errors.Is(err, services.ErrInvalidRetentionPolicy):
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to update retention policy", err)
+		}
+		return
+	}
+
+	response.SuccessJSONWithLog(c, policy, "Retention policy updated successfully")
+}
+
+// DeleteRetentionPolicy handles DELETE /api/v1/analytics/metrics/retention-policies/{id}
+func (ac *AnalyticsController) DeleteRetentionPolicy(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid retention policy ID", err)
+		return
+	}
+
+	if err := ac.analyticsService.DeleteRetentionPolicy(uint(id)); err != nil {
+		if errors.Is(err, services.ErrRetentionPolicyNotFound) {
+			response.NotFoundJSONWithLog(c, "Retention policy not found")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to delete retention policy", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, nil, "Retention policy deleted successfully")
+}
+
 // QueryMetrics handles POST /api/v1/analytics/metrics/query
 func (ac *AnalyticsController) QueryMetrics(c *gin.Context) {
 	var query services.MetricQuery
@@ -58,6 +247,32 @@ func (ac *AnalyticsController) QueryMetrics(c *gin.Context) {
 	response.SuccessJSONWithLog(c, result, "Metrics queried successfully")
 }
 
+// AnalyzeTrends handles POST /api/v1/analytics/metrics/trends
+func (ac *AnalyticsController) AnalyzeTrends(c *gin.Context) {
+	var req services.TrendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid trend request", err)
+		return
+	}
+
+	if req.TimeRange.Start.IsZero() || req.TimeRange.End.IsZero() {
+		response.BadRequestJSONWithLog(c, "Start and end time are required", nil)
+		return
+	}
+
+	analysis, err := ac.analyticsService.AnalyzeTrends(req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAnomalyAlgorithm) {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to analyze trends", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, analysis, "Trends analyzed successfully")
+}
+
 // GetHistoricalMetrics handles GET /api/v1/analytics/metrics/{type}/{name}
 func (ac *AnalyticsController) GetHistoricalMetrics(c *gin.Context) {
 	metricType := c.Param("type")
@@ -76,6 +291,23 @@ func (ac *AnalyticsController) GetHistoricalMetrics(c *gin.Context) {
 		return
 	}
 
+	// Optionally narrow to one source entity, e.g. source=proxy_host&source_id=3
+	source := c.Query("source")
+	var sourceID *uint
+	if sourceIDStr := c.Query("source_id"); sourceIDStr != "" {
+		if source == "" {
+			response.BadRequestJSONWithLog(c, "source_id requires a source", nil)
+			return
+		}
+		id, err := strconv.ParseUint(sourceIDStr, 10, 32)
+		if err != nil {
+			response.BadRequestJSONWithLog(c, "Invalid source_id parameter", err)
+			return
+		}
+		value := uint(id)
+		sourceID = &value
+	}
+
 	// Parse time range
 	var timeRange services.TimeRange
 	if startTime != "" && endTime != "" {
@@ -104,11 +336,17 @@ func (ac *AnalyticsController) GetHistoricalMetrics(c *gin.Context) {
 		TimeRange:   timeRange,
 		Aggregation: aggregation,
 		GroupBy:     groupBy,
+		Source:      source,
+		SourceID:    sourceID,
 		Limit:       limit,
 	}
 
 	dataPoints, err := ac.analyticsService.QueryMetrics(query)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidAggregationWindow) {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
 		response.InternalServerErrorJSONWithLog(c, "Failed to query historical metrics", err)
 		return
 	}
@@ -116,6 +354,8 @@ func (ac *AnalyticsController) GetHistoricalMetrics(c *gin.Context) {
 	result := gin.H{
 		"metric_type": metricType,
 		"metric_name": metricName,
+		"source":      source,
+		"source_id":   sourceID,
 		"data_points": dataPoints,
 		"count":       len(dataPoints),
 		"time_range":  timeRange,
@@ -128,7 +368,41 @@ func (ac *AnalyticsController) GetHistoricalMetrics(c *gin.Context) {
 
 // GetSystemMetricsSummary handles GET /api/v1/analytics/system/summary
 func (ac *AnalyticsController) GetSystemMetricsSummary(c *gin.Context) {
-	// Parse time range
+	timeRange := rangeQuery(c)
+
+	// Generate comprehensive system metrics summary
+	metrics := gin.H{
+		"cpu":    ac.getMetricSummary("system", "cpu_usage", timeRange, nil),
+		"memory": ac.getMetricSummary("system", "memory_usage", timeRange, nil),
+		"disk":   ac.getMetricSummary("system", "disk_usage", timeRange, map[string]string{"mount": "/"}),
+	}
+	if network, err := ac.analyticsService.AnalyzeNetwork(timeRange); err == nil {
+		metrics["network"] = network
+	}
+
+	summary := gin.H{
+		"time_range": timeRange,
+		"metrics":    metrics,
+		"timestamp":  time.Now(),
+	}
+
+	response.SuccessJSONWithLog(c, summary, "System metrics summary retrieved successfully")
+}
+
+// GetPerformanceReport handles GET /api/v1/analytics/system/report
+func (ac *AnalyticsController) GetPerformanceReport(c *gin.Context) {
+	report, err := ac.analyticsService.GeneratePerformanceReport(rangeQuery(c))
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to generate performance report", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, report, "Performance report generated successfully")
+}
+
+// rangeQuery returns the time range ending now selected by the range query
+// parameter: 1h, 24h (the default), 7d or 30d
+func rangeQuery(c *gin.Context) services.TimeRange {
 	rangeStr := c.DefaultQuery("range", "24h")
 
 	var duration time.Duration
@@ -145,28 +419,16 @@ func (ac *AnalyticsController) GetSystemMetricsSummary(c *gin.Context) {
 		duration = 24 * time.Hour
 	}
 
-	timeRange := services.TimeRange{
+	return services.TimeRange{
 		Start: time.Now().Add(-duration),
 		End:   time.Now(),
 	}
-
-	// Generate comprehensive system metrics summary
-	summary := gin.H{
-		"time_range": timeRange,
-		"metrics": gin.H{
-			"cpu":    ac.getMetricSummary("system", "cpu_usage", timeRange),
-			"memory": ac.getMetricSummary("system", "memory_usage", timeRange),
-			"disk":   ac.getMetricSummary("system", "disk_usage", timeRange),
-		},
-		"timestamp": time.Now(),
-	}
-
-	response.SuccessJSONWithLog(c, summary, "System metrics summary retrieved successfully")
 }
 
 // CreateAlertRule handles POST /api/v1/analytics/alerts/rules
 func (ac *AnalyticsController) CreateAlertRule(c *gin.Context) {
-	var alertRule models.AlertRule
+	// A rule is enabled unless the request says otherwise
+	alertRule := models.AlertRule{IsEnabled: true}
 	if err := c.ShouldBindJSON(&alertRule); err != nil {
 		response.BadRequestJSONWithLog(c, "Invalid alert rule data", err)
 		return
@@ -247,7 +509,7 @@ func (ac *AnalyticsController) UpdateAlertRule(c *gin.Context) {
 		return
 	}
 
-	if err := ac.analyticsService.UpdateAlertRule(&alertRule, userID.(uint)); err != nil {
+	if err := ac.analyticsService.UpdateAlertRule(&alertRule, userID.(uint), services.NewAuditContext(c)); err != nil {
 		response.InternalServerErrorJSONWithLog(c, "Failed to update alert rule", err)
 		return
 	}
@@ -278,6 +540,50 @@ func (ac *AnalyticsController) DeleteAlertRule(c *gin.Context) {
 	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Alert rule deleted successfully")
 }
 
+// ExportAlertConfig handles GET /api/v1/analytics/alerts/export
+func (ac *AnalyticsController) ExportAlertConfig(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	export, err := ac.analyticsService.ExportAlertConfig(userID.(uint))
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to export alert configuration", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, export, "Alert configuration exported successfully")
+}
+
+// ImportAlertConfig handles POST /api/v1/analytics/alerts/import
+func (ac *AnalyticsController) ImportAlertConfig(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	var config services.AlertConfigExport
+	if err := c.ShouldBindJSON(&config); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid alert configuration", err)
+		return
+	}
+
+	result, err := ac.analyticsService.ImportAlertConfig(userID.(uint), &config)
+	if err != nil {
+		if importErr, ok := err.(*services.AlertConfigImportError); ok {
+			response.ValidationErrorJSONWithLog(c, map[string][]string{"config": importErr.Problems}, "Invalid alert configuration")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to import alert configuration", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "Alert configuration imported successfully")
+}
+
 // GetAlertInstances handles GET /api/v1/analytics/alerts/instances
 func (ac *AnalyticsController) GetAlertInstances(c *gin.Context) {
 	// Parse query parameters
@@ -321,6 +627,341 @@ func (ac *AnalyticsController) GetAlertInstances(c *gin.Context) {
 	response.SuccessJSONWithLog(c, result, "Alert instances retrieved successfully")
 }
 
+// GetInsights handles GET /api/v1/analytics/insights
+func (ac *AnalyticsController) GetInsights(c *gin.Context) {
+	filter := services.InsightFilter{
+		Type:     c.Query("type"),
+		Category: c.Query("category"),
+		Severity: c.Query("severity"),
+	}
+
+	if resolvedStr := c.Query("resolved"); resolvedStr != "" {
+		resolved, err := strconv.ParseBool(resolvedStr)
+		if err != nil {
+			response.BadRequestJSONWithLog(c, "Invalid resolved parameter", err)
+			return
+		}
+		filter.Resolved = &resolved
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		response.BadRequestJSONWithLog(c, "Invalid limit parameter", err)
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		response.BadRequestJSONWithLog(c, "Invalid offset parameter", err)
+		return
+	}
+	filter.Limit = limit
+	filter.Offset = offset
+
+	insights, total, err := ac.analyticsService.ListInsights(filter)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get insights", err)
+		return
+	}
+
+	result := gin.H{
+		"insights":  insights,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+		"timestamp": time.Now(),
+	}
+
+	response.SuccessJSONWithLog(c, result, "Insights retrieved successfully")
+}
+
+// ResolveInsight handles POST /api/v1/analytics/insights/{id}/resolve
+func (ac *AnalyticsController) ResolveInsight(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid insight ID", err)
+		return
+	}
+
+	insight, err := ac.analyticsService.ResolveInsight(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrInsightNotFound) {
+			response.NotFoundJSONWithLog(c, "Insight not found")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to resolve insight", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, insight, "Insight resolved successfully")
+}
+
+// CreateSilence handles POST /api/v1/analytics/alerts/silences
+func (ac *AnalyticsController) CreateSilence(c *gin.Context) {
+	var silence models.Silence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid silence data", err)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+	silence.CreatedBy = userID.(uint)
+
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now()
+	}
+	if silence.EndsAt.IsZero() {
+		response.BadRequestJSONWithLog(c, "ends_at is required", nil)
+		return
+	}
+	if silence.AlertRuleID == nil && len(silence.Matchers) == 0 {
+		response.BadRequestJSONWithLog(c, "Either alert_rule_id or matchers is required", nil)
+		return
+	}
+
+	if err := ac.analyticsService.CreateSilence(&silence); err != nil {
+		response.BadRequestJSONWithLog(c, "Failed to create silence", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, silence, "Silence created successfully")
+}
+
+// GetSilences handles GET /api/v1/analytics/alerts/silences
+func (ac *AnalyticsController) GetSilences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	activeOnly := c.Query("active") == "true"
+
+	silences, err := ac.analyticsService.GetSilences(userID.(uint), activeOnly)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get silences", err)
+		return
+	}
+
+	result := gin.H{
+		"silences":  silences,
+		"count":     len(silences),
+		"timestamp": time.Now(),
+	}
+
+	response.SuccessJSONWithLog(c, result, "Silences retrieved successfully")
+}
+
+// ExpireSilence handles DELETE /api/v1/analytics/alerts/silences/{id}
+func (ac *AnalyticsController) ExpireSilence(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid silence ID", err)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	if err := ac.analyticsService.ExpireSilence(uint(id), userID.(uint)); err != nil {
+		if err == services.ErrSilenceNotFound {
+			response.NotFoundJSONWithLog(c, "Silence not found")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to expire silence", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Silence expired successfully")
+}
+
+// CreateNotificationChannel handles POST /api/v1/analytics/notifications/channels
+func (ac *AnalyticsController) CreateNotificationChannel(c *gin.Context) {
+	// A channel is enabled unless the request says otherwise
+	channel := models.NotificationChannel{IsEnabled: true}
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid notification channel data", err)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+	channel.ID = 0
+	channel.UserID = userID.(uint)
+
+	if err := ac.analyticsService.CreateNotificationChannel(&channel); err != nil {
+		if validationErr, ok := err.(*services.ChannelValidationError); ok {
+			response.ValidationErrorJSONWithLog(c, validationErr.Fields, "Invalid notification channel configuration")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to create notification channel", err)
+		return
+	}
+
+	services.MaskChannelSecrets(&channel)
+	response.SuccessJSONWithLog(c, channel, "Notification channel created successfully")
+}
+
+// GetNotificationChannels handles GET /api/v1/analytics/notifications/channels
+func (ac *AnalyticsController) GetNotificationChannels(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	channels, err := ac.analyticsService.GetNotificationChannels(userID.(uint))
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get notification channels", err)
+		return
+	}
+
+	result := gin.H{
+		"channels":  channels,
+		"count":     len(channels),
+		"timestamp": time.Now(),
+	}
+
+	response.SuccessJSONWithLog(c, result, "Notification channels retrieved successfully")
+}
+
+// GetNotificationChannel handles GET /api/v1/analytics/notifications/channels/{id}
+func (ac *AnalyticsController) GetNotificationChannel(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid notification channel ID", err)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	channel, err := ac.analyticsService.GetMaskedNotificationChannel(uint(id), userID.(uint))
+	if err != nil {
+		if err == services.ErrNotificationChannelNotFound {
+			response.NotFoundJSONWithLog(c, "Notification channel not found")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to get notification channel", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, channel, "Notification channel retrieved successfully")
+}
+
+// UpdateNotificationChannel handles PUT /api/v1/analytics/notifications/channels/{id}
+func (ac *AnalyticsController) UpdateNotificationChannel(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid notification channel ID", err)
+		return
+	}
+
+	var channel models.NotificationChannel
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid notification channel data", err)
+		return
+	}
+	channel.ID = uint(id)
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	if err := ac.analyticsService.UpdateNotificationChannel(&channel, userID.(uint)); err != nil {
+		if validationErr, ok := err.(*services.ChannelValidationError); ok {
+			response.ValidationErrorJSONWithLog(c, validationErr.Fields, "Invalid notification channel configuration")
+			return
+		}
+		if err == services.ErrNotificationChannelNotFound {
+			response.NotFoundJSONWithLog(c, "Notification channel not found")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to update notification channel", err)
+		return
+	}
+
+	services.MaskChannelSecrets(&channel)
+	response.SuccessJSONWithLog(c, channel, "Notification channel updated successfully")
+}
+
+// DeleteNotificationChannel handles DELETE /api/v1/analytics/notifications/channels/{id}
+func (ac *AnalyticsController) DeleteNotificationChannel(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid notification channel ID", err)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	if err := ac.analyticsService.DeleteNotificationChannel(uint(id), userID.(uint)); err != nil {
+		if err == services.ErrNotificationChannelNotFound {
+			response.NotFoundJSONWithLog(c, "Notification channel not found")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to delete notification channel", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Notification channel deleted successfully")
+}
+
+// TestNotificationChannel handles POST /api/v1/analytics/notifications/channels/{id}/test
+func (ac *AnalyticsController) TestNotificationChannel(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid notification channel ID", err)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	err = ac.analyticsService.TestNotificationChannel(uint(id), userID.(uint))
+	if err == services.ErrNotificationChannelNotFound {
+		response.NotFoundJSONWithLog(c, "Notification channel not found")
+		return
+	}
+
+	result := gin.H{
+		"id":        id,
+		"success":   err == nil,
+		"timestamp": time.Now(),
+	}
+	if err != nil {
+		result["error"] = err.Error()
+	}
+
+	response.SuccessJSONWithLog(c, result, "Notification channel test completed")
+}
+
 // CreateDashboard handles POST /api/v1/analytics/dashboards
 func (ac *AnalyticsController) CreateDashboard(c *gin.Context) {
 	var dashboard models.Dashboard
@@ -342,6 +983,10 @@ func (ac *AnalyticsController) CreateDashboard(c *gin.Context) {
 	}
 
 	if err := ac.analyticsService.CreateDashboard(&dashboard); err != nil {
+		if validationErr, ok := err.(*services.WidgetValidationError); ok {
+			response.ValidationErrorJSONWithLog(c, validationErr.Fields, "Invalid dashboard widgets")
+			return
+		}
 		response.InternalServerErrorJSONWithLog(c, "Failed to create dashboard", err)
 		return
 	}
@@ -389,6 +1034,10 @@ func (ac *AnalyticsController) GetDashboard(c *gin.Context) {
 
 	dashboard, err := ac.analyticsService.GetDashboard(uint(id), userID.(uint))
 	if err != nil {
+		if errors.Is(err, services.ErrDashboardNotFound) {
+			response.NotFoundJSONWithLog(c, "Dashboard not found")
+			return
+		}
 		response.InternalServerErrorJSONWithLog(c, "Failed to get dashboard", err)
 		return
 	}
@@ -420,7 +1069,18 @@ func (ac *AnalyticsController) UpdateDashboard(c *gin.Context) {
 	}
 
 	if err := ac.analyticsService.UpdateDashboard(&dashboard, userID.(uint)); err != nil {
-		response.InternalServerErrorJSONWithLog(c, "Failed to update dashboard", err)
+		if validationErr, ok := err.(*services.WidgetValidationError); ok {
+			response.ValidationErrorJSONWithLog(c, validationErr.Fields, "Invalid dashboard widgets")
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrDashboardNotFound):
+			response.NotFoundJSONWithLog(c, "Dashboard not found")
+		case errors.Is(err, services.ErrDashboardAccessDenied):
+			response.ForbiddenJSONWithLog(c, "You can only view this dashboard")
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to update dashboard", err)
+		}
 		return
 	}
 
@@ -443,23 +1103,78 @@ func (ac *AnalyticsController) DeleteDashboard(c *gin.Context) {
 	}
 
 	if err := ac.analyticsService.DeleteDashboard(uint(id), userID.(uint)); err != nil {
-		response.InternalServerErrorJSONWithLog(c, "Failed to delete dashboard", err)
+		switch {
+		case errors.Is(err, services.ErrDashboardNotFound):
+			response.NotFoundJSONWithLog(c, "Dashboard not found")
+		case errors.Is(err, services.ErrDashboardAccessDenied):
+			response.ForbiddenJSONWithLog(c, "Only the dashboard owner can delete it")
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to delete dashboard", err)
+		}
 		return
 	}
 
 	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Dashboard deleted successfully")
 }
 
-// Helper method to get metric summary
-func (ac *AnalyticsController) getMetricSummary(metricType, metricName string, timeRange services.TimeRange) gin.H {
+// ShareDashboard handles POST /api/v1/analytics/dashboards/{id}/share
+func (ac *AnalyticsController) ShareDashboard(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid dashboard ID", err)
+		return
+	}
+
+	var req services.DashboardShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid share data", err)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	shares, err := ac.analyticsService.ShareDashboard(uint(id), userID.(uint), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDashboardNotFound):
+			response.NotFoundJSONWithLog(c, "Dashboard not found")
+		case errors.Is(err, services.ErrDashboardShareNotOwner):
+			response.ForbiddenJSONWithLog(c, err.Error())
+		case errors.Is(err, services.ErrInvalidDashboardShare):
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to share dashboard", err)
+		}
+		return
+	}
+
+	response.SuccessJSONWithLog(c, gin.H{
+		"dashboard_id": id,
+		"shares":       shares,
+		"count":        len(shares),
+	}, "Dashboard sharing updated successfully")
+}
+
+// Helper method to get metric summary. Aggregations are not kept per tag, so
+// a tagged series is summarized from raw samples.
+func (ac *AnalyticsController) getMetricSummary(metricType, metricName string, timeRange services.TimeRange, tags map[string]string) gin.H {
 	query := services.MetricQuery{
 		MetricType:  metricType,
 		MetricName:  metricName,
 		TimeRange:   timeRange,
 		Aggregation: "avg",
 		GroupBy:     "1h",
+		Tags:        tags,
 		Limit:       200,
 	}
+	if len(tags) > 0 {
+		query.GroupBy = ""
+		query.Limit = 1000
+	}
 
 	dataPoints, err := ac.analyticsService.QueryMetrics(query)
 	if err != nil || len(dataPoints) == 0 {
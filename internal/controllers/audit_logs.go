@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// auditDateLayout is the date-only form accepted by the from and to filters
+const auditDateLayout = "2006-01-02"
+
+// ListAuditLogs handles GET /api/v1/admin/audit-logs. It filters by user_id,
+// object_type, action and a from/to range given as RFC3339 or YYYY-MM-DD; a
+// date-only to covers the whole day.
+func (uc *UserController) ListAuditLogs(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	page := response.ParsePagination(c)
+	filter := services.AuditLogFilter{
+		ObjectType: models.ObjectType(c.Query("object_type")),
+		Action:     models.AuditAction(c.Query("action")),
+		Page:       page.Page,
+		Limit:      page.Limit,
+	}
+
+	if value := c.Query("user_id"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			response.BadRequestJSONWithLog(c, "Invalid user_id", err)
+			return
+		}
+		filter.UserID = uint(userID)
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(c.Query("from"), false); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid from, use RFC3339 or YYYY-MM-DD", err)
+		return
+	}
+	if filter.To, err = parseAuditTime(c.Query("to"), true); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid to, use RFC3339 or YYYY-MM-DD", err)
+		return
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		response.BadRequestJSONWithLog(c, "to must not be before from", nil)
+		return
+	}
+
+	if uc.userService == nil {
+		response.InternalServerErrorJSONWithLog(c, "User service not available", nil)
+		return
+	}
+
+	auditLogs, err := uc.userService.ListAuditLogs(actorID, filter)
+	if err != nil {
+		uc.handleUserError(c, err, "Failed to list audit logs")
+		return
+	}
+
+	response.PaginatedJSONWithLog(c, auditLogs.AuditLogs, auditLogs.Page, auditLogs.Limit, auditLogs.Total, "Audit logs retrieved successfully")
+}
+
+// parseAuditTime parses an RFC3339 or date-only bound. A date-only end bound
+// is moved to the last instant of that day.
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(auditDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
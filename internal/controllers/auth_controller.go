@@ -1,8 +1,6 @@
 package controllers
 
 import (
-	"os"
-
 	"github.com/gin-gonic/gin"
 	"github.com/nguyendkn/nginx-manager/internal/middleware"
 	"github.com/nguyendkn/nginx-manager/internal/services"
@@ -15,14 +13,9 @@ type AuthController struct {
 }
 
 // NewAuthController creates a new auth controller
-func NewAuthController() *AuthController {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "nginx-manager-secret"
-	}
-
+func NewAuthController(authService *services.AuthService) *AuthController {
 	return &AuthController{
-		authService: services.NewAuthService(jwtSecret),
+		authService: authService,
 	}
 }
 
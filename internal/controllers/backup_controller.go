@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// BackupController handles admin full backup endpoints
+type BackupController struct {
+	backupService *services.BackupService
+}
+
+// NewBackupController creates a new backup controller
+func NewBackupController(backupService *services.BackupService) *BackupController {
+	return &BackupController{
+		backupService: backupService,
+	}
+}
+
+// RestoreBackupRequest names an archive in the local backup directory
+type RestoreBackupRequest struct {
+	Archive string `json:"archive" binding:"required"`
+}
+
+// CreateBackup handles POST /api/v1/admin/backups, writing a full backup now
+func (bc *BackupController) CreateBackup(c *gin.Context) {
+	if bc.backupService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Backup service not available", nil)
+		return
+	}
+
+	result, err := bc.backupService.CreateFullBackup(c.Request.Context())
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to create backup", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "Backup created successfully")
+}
+
+// RestoreBackup handles POST /api/v1/admin/backups/restore
+func (bc *BackupController) RestoreBackup(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	var req RestoreBackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request data", err)
+		return
+	}
+
+	if bc.backupService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Backup service not available", nil)
+		return
+	}
+
+	archivePath, err := bc.backupService.ArchivePath(req.Archive)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	result, err := bc.backupService.RestoreFullBackup(userID, archivePath, services.NewAuditContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			response.NotFoundJSONWithLog(c, "Backup archive not found")
+		case errors.Is(err, services.ErrDomainInUse):
+			response.ErrorJSONWithLog(c, http.StatusConflict, "Cannot restore backup: "+err.Error(), err)
+		case errors.Is(err, services.ErrBackupFormat), errors.Is(err, services.ErrBackupDecrypt),
+			errors.Is(err, services.ErrBackupPassphraseRequired):
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to restore backup", err)
+		}
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "Backup restored successfully")
+}
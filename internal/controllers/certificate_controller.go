@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"errors"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -21,15 +23,6 @@ func NewCertificateController(certificateService *services.CertificateService) *
 	}
 }
 
-// CertificateListResponse represents paginated certificate list response
-type CertificateListResponse struct {
-	Data       []models.Certificate `json:"data"`
-	Total      int64                `json:"total"`
-	Page       int                  `json:"page"`
-	PerPage    int                  `json:"per_page"`
-	TotalPages int                  `json:"total_pages"`
-}
-
 // CertificateResponse represents single certificate response
 type CertificateResponse struct {
 	Data models.Certificate `json:"data"`
@@ -42,6 +35,19 @@ type UploadCertificateRequest struct {
 	IntermediateCertificate string `json:"intermediate_certificate"`
 }
 
+// ValidateCertificateRequest represents a certificate bundle preflight request
+type ValidateCertificateRequest struct {
+	Certificate             string   `json:"certificate" binding:"required"`
+	CertificateKey          string   `json:"certificate_key"`
+	IntermediateCertificate string   `json:"intermediate_certificate"`
+	DomainNames             []string `json:"domain_names"`
+}
+
+// ValidateForDomainsRequest represents a certificate domain coverage check
+type ValidateForDomainsRequest struct {
+	DomainNames []string `json:"domain_names" binding:"required,min=1"`
+}
+
 // TestCertificateRequest represents certificate test request
 type TestCertificateRequest struct {
 	Domains []string `json:"domains" binding:"required"`
@@ -64,41 +70,29 @@ type RenewCertificateResponse struct {
 // ListCertificates handles GET /api/v1/certificates
 func (ctrl *CertificateController) ListCertificates(c *gin.Context) {
 	userID := c.GetUint("user_id")
+	if !rejectIncludes(c) {
+		return
+	}
 
 	// Parse pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "10"))
-
-	// Calculate offset
-	offset := (page - 1) * perPage
+	page := response.ParsePagination(c)
 
 	// Get certificates with pagination
-	certificates, total, err := ctrl.certificateService.ListCertificates(userID, offset, perPage)
+	certificates, total, err := ctrl.certificateService.ListCertificates(userID, page.Offset(), page.Limit)
 	if err != nil {
 		response.InternalServerErrorJSONWithLog(c, "Failed to retrieve certificates", err)
 		return
 	}
 
-	// Calculate total pages
-	totalPages := int(total) / perPage
-	if int(total)%perPage > 0 {
-		totalPages++
-	}
-
-	responseData := CertificateListResponse{
-		Data:       certificates,
-		Total:      total,
-		Page:       page,
-		PerPage:    perPage,
-		TotalPages: totalPages,
-	}
-
-	response.SuccessJSONWithLog(c, responseData, "Certificates retrieved successfully")
+	response.PaginatedJSONWithLog(c, certificates, page.Page, page.Limit, total, "Certificates retrieved successfully")
 }
 
 // GetCertificate handles GET /api/v1/certificates/:id
 func (ctrl *CertificateController) GetCertificate(c *gin.Context) {
 	userID := c.GetUint("user_id")
+	if !rejectIncludes(c) {
+		return
+	}
 
 	// Parse certificate ID
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -139,8 +133,12 @@ func (ctrl *CertificateController) CreateCertificate(c *gin.Context) {
 	}
 
 	// Create certificate
-	certificate, err := ctrl.certificateService.CreateCertificate(userID, &req)
+	certificate, err := ctrl.certificateService.CreateCertificate(userID, &req, services.NewAuditContext(c))
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidCertificate) || errors.Is(err, services.ErrInvalidAccountEmail) {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
 		response.InternalServerErrorJSONWithLog(c, "Failed to create certificate", err)
 		return
 	}
@@ -173,12 +171,16 @@ func (ctrl *CertificateController) UpdateCertificate(c *gin.Context) {
 	}
 
 	// Update certificate
-	certificate, err := ctrl.certificateService.UpdateCertificate(userID, uint(id), &req)
+	certificate, err := ctrl.certificateService.UpdateCertificate(userID, uint(id), &req, services.NewAuditContext(c))
 	if err != nil {
 		if err == services.ErrCertificateNotFound {
 			response.NotFoundJSONWithLog(c, "Certificate not found")
 			return
 		}
+		if errors.Is(err, services.ErrInvalidCertificate) || errors.Is(err, services.ErrInvalidAccountEmail) {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
 		response.InternalServerErrorJSONWithLog(c, "Failed to update certificate", err)
 		return
 	}
@@ -205,7 +207,7 @@ func (ctrl *CertificateController) DeleteCertificate(c *gin.Context) {
 	}
 
 	// Delete certificate
-	err = ctrl.certificateService.DeleteCertificate(userID, uint(id))
+	err = ctrl.certificateService.DeleteCertificate(userID, uint(id), services.NewAuditContext(c))
 	if err != nil {
 		if err == services.ErrCertificateNotFound {
 			response.NotFoundJSONWithLog(c, "Certificate not found")
@@ -236,13 +238,18 @@ func (ctrl *CertificateController) UploadCertificate(c *gin.Context) {
 	}
 
 	// Upload certificate
-	certificate, err := ctrl.certificateService.UploadCertificate(userID, uint(id), req.Certificate, req.CertificateKey, req.IntermediateCertificate)
+	certificate, err := ctrl.certificateService.UploadCertificate(userID, uint(id), req.Certificate, req.CertificateKey, req.IntermediateCertificate, services.NewAuditContext(c))
 	if err != nil {
-		if err == services.ErrCertificateNotFound {
+		switch {
+		case err == services.ErrCertificateNotFound:
 			response.NotFoundJSONWithLog(c, "Certificate not found")
-			return
+		case errors.Is(err, services.ErrInvalidCertificate),
+			errors.Is(err, services.ErrInvalidPrivateKey),
+			errors.Is(err, services.ErrCertificateKeyMismatch):
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to upload certificate", err)
 		}
-		response.InternalServerErrorJSONWithLog(c, "Failed to upload certificate", err)
 		return
 	}
 
@@ -268,12 +275,20 @@ func (ctrl *CertificateController) RenewCertificate(c *gin.Context) {
 	}
 
 	// Renew certificate
-	certificate, err := ctrl.certificateService.RenewCertificate(userID, uint(id))
+	certificate, err := ctrl.certificateService.RenewCertificate(userID, uint(id), services.NewAuditContext(c))
 	if err != nil {
 		if err == services.ErrCertificateNotFound {
 			response.NotFoundJSONWithLog(c, "Certificate not found")
 			return
 		}
+		if errors.Is(err, services.ErrInvalidAccountEmail) {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
+		if errors.Is(err, services.ErrRenewalInProgress) {
+			response.ErrorJSONWithLog(c, http.StatusConflict, err.Error(), err)
+			return
+		}
 		response.InternalServerErrorJSONWithLog(c, "Failed to renew certificate", err)
 		return
 	}
@@ -290,6 +305,28 @@ func (ctrl *CertificateController) RenewCertificate(c *gin.Context) {
 	response.SuccessJSONWithLog(c, responseData, "Certificate renewed successfully")
 }
 
+// ValidateCertificate handles POST /api/v1/certificates/validate. The bundle
+// is parsed and checked without being stored.
+func (ctrl *CertificateController) ValidateCertificate(c *gin.Context) {
+	var req ValidateCertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request data", err)
+		return
+	}
+
+	report, err := services.ValidateCertificateBundle(req.Certificate, req.CertificateKey, req.IntermediateCertificate, req.DomainNames)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Certificate could not be parsed", err)
+		return
+	}
+
+	message := "Certificate bundle is valid"
+	if !report.Valid {
+		message = "Certificate bundle has errors"
+	}
+	response.SuccessJSONWithLog(c, report, message)
+}
+
 // TestCertificate handles POST /api/v1/certificates/test
 func (ctrl *CertificateController) TestCertificate(c *gin.Context) {
 	var req TestCertificateRequest
@@ -327,6 +364,9 @@ func (ctrl *CertificateController) TestCertificate(c *gin.Context) {
 
 // GetExpiringSoon handles GET /api/v1/certificates/expiring-soon
 func (ctrl *CertificateController) GetExpiringSoon(c *gin.Context) {
+	if !rejectIncludes(c) {
+		return
+	}
 	// Parse days parameter (default 30 days)
 	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
 
@@ -344,3 +384,125 @@ func (ctrl *CertificateController) GetExpiringSoon(c *gin.Context) {
 
 	response.SuccessJSONWithLog(c, certificates, "Expiring certificates retrieved successfully")
 }
+
+// CheckOCSPStatus handles GET /api/v1/certificates/:id/ocsp. It queries the
+// certificate's OCSP responder, which helps diagnose browser revocation errors.
+func (ctrl *CertificateController) CheckOCSPStatus(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	// Parse certificate ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid certificate ID", err)
+		return
+	}
+
+	result, err := ctrl.certificateService.CheckOCSPStatus(userID, uint(id))
+	if err != nil {
+		switch {
+		case err == services.ErrCertificateNotFound:
+			response.NotFoundJSONWithLog(c, "Certificate not found")
+		case err == services.ErrNoOCSPResponder,
+			err == services.ErrSelfSignedNoOCSP,
+			err == services.ErrOCSPIssuerNotFound,
+			errors.Is(err, services.ErrInvalidCertificate):
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		case errors.Is(err, services.ErrOCSPResponderFailed):
+			response.ErrorJSONWithLog(c, http.StatusBadGateway, err.Error(), err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to check OCSP status", err)
+		}
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "OCSP status: "+result.Status)
+}
+
+// ValidateForDomains handles POST /api/v1/certificates/:id/validate-domains.
+// It reports whether the certificate covers each domain, when it expires and
+// whether its chain is valid, before it is assigned to a host.
+func (ctrl *CertificateController) ValidateForDomains(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	// Parse certificate ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid certificate ID", err)
+		return
+	}
+
+	var req ValidateForDomainsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request data", err)
+		return
+	}
+
+	validation, err := ctrl.certificateService.ValidateForDomains(userID, uint(id), req.DomainNames)
+	if err != nil {
+		switch {
+		case err == services.ErrCertificateNotFound:
+			response.NotFoundJSONWithLog(c, "Certificate not found")
+		case errors.Is(err, services.ErrInvalidCertificate):
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to validate certificate", err)
+		}
+		return
+	}
+
+	message := "Certificate covers all domains"
+	if !validation.AllCovered {
+		message = "Certificate does not cover all domains"
+	}
+	response.SuccessJSONWithLog(c, validation, message)
+}
+
+// DownloadCertificateRequest carries the PKCS#12 passphrase
+type DownloadCertificateRequest struct {
+	Passphrase string `json:"passphrase"`
+}
+
+// DownloadCertificate handles GET and POST /api/v1/certificates/:id/download.
+// format=pem-bundle returns a zip of cert.pem, key.pem and chain.pem;
+// format=pkcs12 returns a .p12 protected by the passphrase in the POST body.
+func (ctrl *CertificateController) DownloadCertificate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	// Parse certificate ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid certificate ID", err)
+		return
+	}
+
+	var req DownloadCertificateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequestJSONWithLog(c, "Invalid request data", err)
+			return
+		}
+	}
+
+	format := c.DefaultQuery("format", services.CertificateExportPEMBundle)
+	export, err := ctrl.certificateService.ExportCertificate(userID, uint(id), format, req.Passphrase, c.ClientIP(), c.Request.UserAgent())
+	req.Passphrase = ""
+	if err != nil {
+		switch {
+		case err == services.ErrCertificateNotFound:
+			response.NotFoundJSONWithLog(c, "Certificate not found")
+		case err == services.ErrInvalidExportFormat,
+			err == services.ErrExportPassphraseRequired,
+			err == services.ErrCertificateNotIssued,
+			errors.Is(err, services.ErrInvalidCertificate):
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to export certificate", err)
+		}
+		return
+	}
+	defer clear(export.Data)
+
+	c.Header("Content-Disposition", `attachment; filename="`+export.Filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, export.ContentType, export.Data)
+}
@@ -3,8 +3,10 @@ package controllers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/models"
 	"github.com/nguyendkn/nginx-manager/internal/services"
 	"github.com/nguyendkn/nginx-manager/pkg/errors"
 	"github.com/nguyendkn/nginx-manager/pkg/response"
@@ -32,6 +34,7 @@ func NewConfigController(configService *services.ConfigService) *ConfigControlle
 // @Success 201 {object} models.NginxConfig
 // @Failure 400 {object} response.ErrorResponse
 // @Failure 401 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /api/v1/nginx/configs [post]
 func (c *ConfigController) CreateConfig(ctx *gin.Context) {
@@ -47,8 +50,12 @@ func (c *ConfigController) CreateConfig(ctx *gin.Context) {
 		return
 	}
 
-	config, err := c.configService.CreateConfig(userID.(uint), &req)
+	config, err := c.configService.CreateConfig(userID.(uint), &req, services.NewAuditContext(ctx))
 	if err != nil {
+		if err == errors.ErrConfigDuplicate {
+			response.ErrorJSONWithLog(ctx, http.StatusConflict, "Configuration with this name already exists", err)
+			return
+		}
 		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Failed to create configuration", err)
 		return
 	}
@@ -106,6 +113,9 @@ func (c *ConfigController) GetConfig(ctx *gin.Context) {
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Page size" default(10)
 // @Param type query string false "Configuration type filter"
+// @Param search query string false "Match name or description"
+// @Param sort query string false "Sort field: name, created_at or updated_at"
+// @Param order query string false "Sort order: asc or desc"
 // @Success 200 {object} services.ConfigListResponse
 // @Failure 400 {object} response.ErrorResponse
 // @Failure 401 {object} response.ErrorResponse
@@ -118,25 +128,66 @@ func (c *ConfigController) ListConfigs(ctx *gin.Context) {
 	}
 
 	// Parse query parameters
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	page := response.ParsePagination(ctx)
 	configType := ctx.Query("type")
 
-	// Validate pagination parameters
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	sort, err := services.NewListSort(ctx.Query("sort"), ctx.Query("order"), services.ConfigSortFields)
+	if err != nil {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid sort", err)
+		return
 	}
 
-	configs, err := c.configService.ListConfigs(userID.(uint), page, limit, configType)
+	configs, err := c.configService.ListConfigs(userID.(uint), page.Page, page.Limit, configType, ctx.Query("search"), sort)
 	if err != nil {
 		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Failed to list configurations", err)
 		return
 	}
 
-	response.SuccessJSONWithLog(ctx, configs, "Configurations retrieved successfully")
+	response.PaginatedJSONWithLog(ctx, configs.Configs, configs.Page, configs.Limit, configs.Total, "Configurations retrieved successfully")
+}
+
+// SearchContent searches configuration, template and proxy host content
+// @Summary Search nginx configuration content
+// @Description Full-text search across configs, templates and proxy host advanced config the user can access
+// @Tags nginx-config
+// @Produce json
+// @Param q query string true "Search text"
+// @Param context query int false "Context lines around each match" default(2)
+// @Param limit query int false "Maximum results per source" default(50)
+// @Success 200 {object} services.SearchResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /api/v1/nginx/search [get]
+func (c *ConfigController) SearchContent(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.ErrorJSONWithLog(ctx, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	query := strings.TrimSpace(ctx.Query("q"))
+	if len(query) < 2 {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Search query must be at least 2 characters", nil)
+		return
+	}
+
+	contextLines, _ := strconv.Atoi(ctx.DefaultQuery("context", "2"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+
+	if contextLines < 0 || contextLines > 10 {
+		contextLines = 2
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	results, err := c.configService.SearchContent(userID.(uint), query, contextLines, limit)
+	if err != nil {
+		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Failed to search configurations", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(ctx, results, "Search completed successfully")
 }
 
 // UpdateConfig updates an existing nginx configuration
@@ -172,7 +223,7 @@ func (c *ConfigController) UpdateConfig(ctx *gin.Context) {
 		return
 	}
 
-	config, err := c.configService.UpdateConfig(userID.(uint), uint(id), &req)
+	config, err := c.configService.UpdateConfig(userID.(uint), uint(id), &req, services.NewAuditContext(ctx))
 	if err != nil {
 		if err == errors.ErrConfigNotFound {
 			response.ErrorJSONWithLog(ctx, http.StatusNotFound, "Configuration not found", err)
@@ -182,6 +233,10 @@ func (c *ConfigController) UpdateConfig(ctx *gin.Context) {
 			response.ErrorJSONWithLog(ctx, http.StatusForbidden, "Permission denied", err)
 			return
 		}
+		if err == errors.ErrConfigDuplicate {
+			response.ErrorJSONWithLog(ctx, http.StatusConflict, "Configuration with this name already exists", err)
+			return
+		}
 		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Failed to update configuration", err)
 		return
 	}
@@ -214,7 +269,7 @@ func (c *ConfigController) DeleteConfig(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.configService.DeleteConfig(userID.(uint), uint(id)); err != nil {
+	if err := c.configService.DeleteConfig(userID.(uint), uint(id), services.NewAuditContext(ctx)); err != nil {
 		if err == errors.ErrConfigNotFound {
 			response.ErrorJSONWithLog(ctx, http.StatusNotFound, "Configuration not found", err)
 			return
@@ -230,13 +285,18 @@ func (c *ConfigController) DeleteConfig(ctx *gin.Context) {
 	response.SuccessJSONWithLog(ctx, gin.H{"id": id}, "Configuration deleted successfully")
 }
 
-// ValidateConfig validates nginx configuration content
+// ValidateConfig validates nginx configuration content. Fragments are
+// validated inside the blocks their type needs, and each nginx message is
+// returned as a structured issue located in the submitted content. With
+// ?suggestions=true issues also carry an explanation, a suggested fix and the
+// conflicting sources.
 // @Summary Validate nginx configuration
 // @Description Validate nginx configuration syntax
 // @Tags nginx-config
 // @Accept json
 // @Produce json
-// @Param content body map[string]string true "Configuration content"
+// @Param content body map[string]string true "Configuration content and optional type (main, server, upstream, location, custom)"
+// @Param suggestions query bool false "Include explanations and fix suggestions"
 // @Success 200 {object} services.ValidationResult
 // @Failure 400 {object} response.ErrorResponse
 // @Failure 401 {object} response.ErrorResponse
@@ -249,20 +309,29 @@ func (c *ConfigController) ValidateConfig(ctx *gin.Context) {
 	}
 
 	var req struct {
-		Content string `json:"content" binding:"required"`
+		Type    models.ConfigType `json:"type"`
+		Content string            `json:"content" binding:"required"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid request data", err)
 		return
 	}
+	if req.Type != "" && !req.Type.IsValid() {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid configuration type", nil)
+		return
+	}
 
-	result, err := c.configService.ValidateConfig(userID.(uint), req.Content)
+	result, err := c.configService.ValidateConfig(userID.(uint), req.Type, req.Content)
 	if err != nil {
 		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Validation failed", err)
 		return
 	}
 
+	if suggestions, _ := strconv.ParseBool(ctx.Query("suggestions")); suggestions {
+		c.configService.ExplainValidation(userID.(uint), result)
+	}
+
 	response.SuccessJSONWithLog(ctx, result, "Configuration validated")
 }
 
@@ -291,7 +360,7 @@ func (c *ConfigController) DeployConfig(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.configService.DeployConfig(userID.(uint), uint(id)); err != nil {
+	if err := c.configService.DeployConfig(userID.(uint), uint(id), services.NewAuditContext(ctx)); err != nil {
 		if err == errors.ErrConfigNotFound {
 			response.ErrorJSONWithLog(ctx, http.StatusNotFound, "Configuration not found", err)
 			return
@@ -396,8 +465,7 @@ func (c *ConfigController) CreateConfigBackup(ctx *gin.Context) {
 		req.Reason = "Manual backup"
 	}
 
-	// First check if user has access to the configuration
-	_, err = c.configService.GetConfig(userID.(uint), uint(id))
+	backup, err := c.configService.CreateBackup(userID.(uint), uint(id), req.Reason)
 	if err != nil {
 		if err == errors.ErrConfigNotFound {
 			response.ErrorJSONWithLog(ctx, http.StatusNotFound, "Configuration not found", err)
@@ -407,13 +475,11 @@ func (c *ConfigController) CreateConfigBackup(ctx *gin.Context) {
 			response.ErrorJSONWithLog(ctx, http.StatusForbidden, "Permission denied", err)
 			return
 		}
-		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Failed to access configuration", err)
+		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Failed to create backup", err)
 		return
 	}
 
-	// TODO: Implement CreateBackup method in service that can be called externally
-	// For now, return success
-	response.SuccessJSONWithLog(ctx, gin.H{"id": id, "reason": req.Reason}, "Backup created successfully")
+	response.SuccessJSONWithLog(ctx, backup, "Backup created successfully")
 }
 
 // RestoreConfigFromBackup restores a configuration from backup
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// DeadHostController handles 404 host management
+type DeadHostController struct {
+	nginxService *services.NginxService
+}
+
+// NewDeadHostController creates a new dead host controller
+func NewDeadHostController(nginxService *services.NginxService) *DeadHostController {
+	return &DeadHostController{
+		nginxService: nginxService,
+	}
+}
+
+// DeadHostRequest represents the request payload for creating or updating a dead host
+type DeadHostRequest struct {
+	DomainNames   []string               `json:"domain_names" binding:"required,min=1"`
+	CertificateID *uint                  `json:"certificate_id"`
+	SSLForced     bool                   `json:"ssl_forced"`
+	CustomPage    string                 `json:"custom_page"`
+	Enabled       bool                   `json:"enabled"`
+	Meta          map[string]interface{} `json:"meta"`
+}
+
+// DeadHostResponse represents a dead host with its generated configuration
+type DeadHostResponse struct {
+	models.DeadHost
+	PrimaryDomain string `json:"primary_domain"`
+	SSLEnabled    bool   `json:"ssl_enabled"`
+	NginxConfig   string `json:"nginx_config,omitempty"`
+}
+
+// deadHostIncludes lists the relations dead host endpoints accept in ?include=
+var deadHostIncludes = relationIncludes{
+	"certificate": "Certificate",
+}
+
+// deadHostDefaultIncludes are loaded when ?include= is not given
+var deadHostDefaultIncludes = []string{"certificate"}
+
+// List returns paginated list of dead hosts for the current user. Without
+// ?include= the certificate is embedded in each host; with it the requested
+// relations are returned once each under "included".
+func (dc *DeadHostController) List(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	// Parse query parameters
+	page := response.ParsePagination(c)
+	search := c.Query("search")
+
+	includes, err := parseIncludes(c, deadHostIncludes, deadHostDefaultIncludes...)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+	_, sideload := c.GetQuery("include")
+
+	db := database.GetDB()
+	query := ownerScope(c, db.Model(&models.DeadHost{}), userID, models.PermissionDeadHostReadAll)
+	if search != "" {
+		query = query.Where("domain_names LIKE ?", "%"+search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		logger.Error("Failed to count dead hosts", logger.Err(err), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to count dead hosts", err)
+		return
+	}
+
+	var deadHosts []models.DeadHost
+	if err := preloadIncludes(query, deadHostIncludes, includes).
+		Offset(page.Offset()).Limit(page.Limit).
+		Order("created_at DESC").
+		Find(&deadHosts).Error; err != nil {
+		logger.Error("Failed to fetch dead hosts", logger.Err(err), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to fetch dead hosts", err)
+		return
+	}
+
+	deadHostResponses := make([]DeadHostResponse, 0, len(deadHosts))
+	included := newSideloader(includes)
+	for _, host := range deadHosts {
+		if sideload && host.Certificate != nil {
+			included.add("certificate", host.Certificate.ID, host.Certificate)
+			host.Certificate = nil
+		}
+		deadHostResponses = append(deadHostResponses, DeadHostResponse{
+			DeadHost:      host,
+			PrimaryDomain: host.GetPrimaryDomain(),
+			SSLEnabled:    host.IsSSLEnabled(),
+		})
+	}
+
+	result := response.Paginated(deadHostResponses, page.Page, page.Limit, total, "Dead hosts retrieved successfully")
+	if sideload {
+		result = result.WithIncluded(included.result())
+	}
+	response.PaginatedResponseJSONWithLog(c, result)
+}
+
+// Get returns a single dead host with a preview of its nginx configuration and
+// the relations named in ?include= (the certificate by default) embedded
+func (dc *DeadHostController) Get(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid dead host ID", err)
+		return
+	}
+
+	includes, err := parseIncludes(c, deadHostIncludes, deadHostDefaultIncludes...)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	db := database.GetDB()
+	var deadHost models.DeadHost
+	if err := preloadIncludes(ownerScope(c, db, userID, models.PermissionDeadHostReadAll).Where("id = ?", id), deadHostIncludes, includes).
+		First(&deadHost).Error; err != nil {
+		response.NotFoundJSONWithLog(c, "Dead host not found")
+		return
+	}
+
+	resp := DeadHostResponse{
+		DeadHost:      deadHost,
+		PrimaryDomain: deadHost.GetPrimaryDomain(),
+		SSLEnabled:    deadHost.IsSSLEnabled(),
+	}
+	if dc.nginxService != nil {
+		resp.NginxConfig = dc.nginxService.BuildDeadHostConfig(&deadHost)
+	}
+
+	response.SuccessJSONWithLog(c, resp, "Dead host retrieved successfully")
+}
+
+// Create creates a new dead host
+func (dc *DeadHostController) Create(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	var req DeadHostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
+		return
+	}
+
+	// Validate domain names
+	if err := validateDomainNames(req.DomainNames); err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	// Check for duplicate domains across all host types
+	if err := checkDuplicateDomains(req.DomainNames, &models.DeadHost{}, 0); err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	if !checkHostReferences(c, userID, req.CertificateID, nil) {
+		return
+	}
+
+	deadHost := models.DeadHost{
+		DomainNames:   models.StringArray(req.DomainNames),
+		CertificateID: req.CertificateID,
+		SSLForced:     req.SSLForced,
+		CustomPage:    req.CustomPage,
+		Enabled:       req.Enabled,
+		UserID:        userID,
+	}
+	if req.Meta != nil {
+		deadHost.Meta = models.JSON(req.Meta)
+	}
+
+	db := database.GetDB()
+	if err := db.Create(&deadHost).Error; err != nil {
+		logger.Error("Failed to create dead host", logger.Err(err), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to create dead host", err)
+		return
+	}
+
+	dc.syncDeadHostConfig(&deadHost)
+
+	logger.Info("Dead host created successfully", logger.Uint("id", deadHost.ID), logger.Uint("user_id", userID), logger.Any("domains", req.DomainNames))
+	response.SuccessJSONWithLog(c, deadHost, "Dead host created successfully")
+}
+
+// Update updates an existing dead host
+func (dc *DeadHostController) Update(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid dead host ID", err)
+		return
+	}
+
+	var req DeadHostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
+		return
+	}
+
+	db := database.GetDB()
+	var deadHost models.DeadHost
+	if err := ownerScope(c, db, userID, models.PermissionDeadHostManage).Where("id = ?", id).First(&deadHost).Error; err != nil {
+		response.NotFoundJSONWithLog(c, "Dead host not found")
+		return
+	}
+
+	// Validate domain names
+	if err := validateDomainNames(req.DomainNames); err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	// Check for duplicate domains (excluding current host)
+	if err := checkDuplicateDomains(req.DomainNames, &models.DeadHost{}, uint(id)); err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	// A certificate the host already has is not checked again
+	certificateID := req.CertificateID
+	if sameID(certificateID, deadHost.CertificateID) {
+		certificateID = nil
+	}
+	if !checkHostReferences(c, userID, certificateID, nil) {
+		return
+	}
+
+	deadHost.DomainNames = models.StringArray(req.DomainNames)
+	deadHost.CertificateID = req.CertificateID
+	deadHost.SSLForced = req.SSLForced
+	deadHost.CustomPage = req.CustomPage
+	deadHost.Enabled = req.Enabled
+	if req.Meta != nil {
+		deadHost.Meta = models.JSON(req.Meta)
+	}
+
+	if err := db.Save(&deadHost).Error; err != nil {
+		logger.Error("Failed to update dead host", logger.Err(err), logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to update dead host", err)
+		return
+	}
+
+	dc.syncDeadHostConfig(&deadHost)
+
+	logger.Info("Dead host updated successfully", logger.Uint("id", deadHost.ID), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, deadHost, "Dead host updated successfully")
+}
+
+// Delete deletes a dead host
+func (dc *DeadHostController) Delete(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid dead host ID", err)
+		return
+	}
+
+	db := database.GetDB()
+	var deadHost models.DeadHost
+	if err := ownerScope(c, db, userID, models.PermissionDeadHostManage).Where("id = ?", id).First(&deadHost).Error; err != nil {
+		response.NotFoundJSONWithLog(c, "Dead host not found")
+		return
+	}
+
+	// Remove nginx configuration
+	if dc.nginxService != nil {
+		if err := dc.nginxService.UndeployDeadHost(&deadHost); err != nil {
+			logger.Error("Failed to remove nginx configuration", logger.Err(err), logger.Uint("dead_host_id", deadHost.ID))
+			// Continue anyway
+		}
+	}
+
+	if err := db.Delete(&deadHost).Error; err != nil {
+		logger.Error("Failed to delete dead host", logger.Err(err), logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to delete dead host", err)
+		return
+	}
+
+	logger.Info("Dead host deleted successfully", logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Dead host deleted successfully")
+}
+
+// syncDeadHostConfig deploys an enabled dead host or removes a disabled one.
+// Failures are logged and do not fail the request.
+func (dc *DeadHostController) syncDeadHostConfig(deadHost *models.DeadHost) {
+	if dc.nginxService == nil {
+		return
+	}
+
+	if deadHost.Enabled {
+		if err := dc.nginxService.DeployDeadHost(deadHost); err != nil {
+			logger.Error("Failed to apply nginx configuration", logger.Err(err), logger.Uint("dead_host_id", deadHost.ID))
+		}
+		return
+	}
+
+	if err := dc.nginxService.UndeployDeadHost(deadHost); err != nil {
+		logger.Error("Failed to remove nginx configuration", logger.Err(err), logger.Uint("dead_host_id", deadHost.ID))
+	}
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestRecreateDeadHostAfterDelete deletes a dead host and creates another for
+// the same domain, which a live proxy host's domain still blocks
+func TestRecreateDeadHostAfterDelete(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	createTestProxyHost(t, db, owner.ID, "proxied.example.com")
+	controller := NewDeadHostController(nil)
+
+	create := func(domain string) *httptest.ResponseRecorder {
+		return serveAs(t, owner.ID, http.MethodPost, "/dead-hosts", DeadHostRequest{DomainNames: []string{domain}}, controller.Create)
+	}
+
+	if recorder := create("proxied.example.com"); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("domain of a live proxy host: got %d, want %d: %s", recorder.Code, http.StatusBadRequest, recorder.Body)
+	}
+
+	recorder := create("gone.example.com")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("create: got %d: %s", recorder.Code, recorder.Body)
+	}
+	var created struct {
+		Data models.DeadHost `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprint(created.Data.ID)
+
+	if recorder := create("gone.example.com"); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("domain of a live dead host: got %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+
+	recorder = serveAs(t, owner.ID, http.MethodDelete, "/dead-hosts/"+id, nil, controller.Delete, gin.Param{Key: "id", Value: id})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("delete: got %d: %s", recorder.Code, recorder.Body)
+	}
+
+	if recorder := create("gone.example.com"); recorder.Code != http.StatusOK {
+		t.Errorf("recreate after delete: got %d: %s", recorder.Code, recorder.Body)
+	}
+}
+
+// TestDeadHostChecksCertificate creates and updates dead hosts referring to
+// missing and foreign certificates
+func TestDeadHostChecksCertificate(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	other := createTestUser(t, db, "other@example.test", models.RoleUser)
+	controller := NewDeadHostController(nil)
+
+	shared := &models.Certificate{Name: "shared", Provider: models.ProviderCustom, UserID: other.ID}
+	foreign := &models.Certificate{Name: "foreign", Provider: models.ProviderCustom, UserID: other.ID}
+	for _, certificate := range []*models.Certificate{shared, foreign} {
+		if err := db.Create(certificate).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := foreign.ID + 100
+
+	for _, tc := range []struct {
+		name          string
+		certificateID *uint
+		want          int
+	}{
+		{"foreign certificate", &foreign.ID, http.StatusForbidden},
+		{"missing certificate", &missing, http.StatusBadRequest},
+		{"no certificate", nil, http.StatusOK},
+	} {
+		t.Run("create with "+tc.name, func(t *testing.T) {
+			req := DeadHostRequest{DomainNames: []string{"create.example.com"}, CertificateID: tc.certificateID}
+			if recorder := serveAs(t, owner.ID, http.MethodPost, "/dead-hosts", req, controller.Create); recorder.Code != tc.want {
+				t.Errorf("got status %d, want %d: %s", recorder.Code, tc.want, recorder.Body)
+			}
+		})
+	}
+
+	// An admin attached another user's certificate to the owner's host
+	deadHost := &models.DeadHost{DomainNames: models.StringArray{"update.example.com"}, CertificateID: &shared.ID, UserID: owner.ID}
+	if err := db.Create(deadHost).Error; err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprint(deadHost.ID)
+
+	for _, tc := range []struct {
+		name          string
+		certificateID *uint
+		want          int
+	}{
+		{"the certificate it has", &shared.ID, http.StatusOK},
+		{"another foreign certificate", &foreign.ID, http.StatusForbidden},
+	} {
+		t.Run("update to "+tc.name, func(t *testing.T) {
+			req := DeadHostRequest{DomainNames: []string{"update.example.com"}, CertificateID: tc.certificateID}
+			recorder := serveAs(t, owner.ID, http.MethodPut, "/dead-hosts/"+id, req, controller.Update, gin.Param{Key: "id", Value: id})
+			if recorder.Code != tc.want {
+				t.Errorf("got status %d, want %d: %s", recorder.Code, tc.want, recorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// grafanaMaxRange matches the time range limit of the metrics query API
+const grafanaMaxRange = 90 * 24 * time.Hour
+
+// grafanaWindows maps a Grafana panel interval to the coarsest aggregation
+// window that still fits in it, largest first
+var grafanaWindows = []struct {
+	window   string
+	duration time.Duration
+}{
+	{"1w", 7 * 24 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"1h", time.Hour},
+	{"5m", 5 * time.Minute},
+}
+
+// GrafanaController implements the Grafana SimpleJSON datasource API on top
+// of the stored historical metrics. Responses use Grafana's plain JSON shapes
+// rather than the API response envelope.
+type GrafanaController struct {
+	analyticsService *services.AnalyticsService
+}
+
+// NewGrafanaController creates a new Grafana datasource controller
+func NewGrafanaController(analyticsService *services.AnalyticsService) *GrafanaController {
+	return &GrafanaController{
+		analyticsService: analyticsService,
+	}
+}
+
+// GrafanaSearchRequest is the body Grafana sends to /search
+type GrafanaSearchRequest struct {
+	Target string `json:"target"`
+}
+
+// GrafanaQueryRequest is the body Grafana sends to /query
+type GrafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs    int64                `json:"intervalMs"`
+	MaxDataPoints int                  `json:"maxDataPoints"`
+	Targets       []GrafanaQueryTarget `json:"targets"`
+}
+
+// GrafanaQueryTarget is one series requested by a panel. Target is
+// "metric_type/metric_name"; Data may set "aggregation" (avg, sum, min, max,
+// p50, p95, p99) for aggregated intervals.
+type GrafanaQueryTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	Hide   bool   `json:"hide"`
+	Data   struct {
+		Aggregation string `json:"aggregation"`
+	} `json:"data"`
+}
+
+// GrafanaTimeSeries is one series in a /query response. Datapoints are
+// [value, unix milliseconds] pairs.
+type GrafanaTimeSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// TestConnection handles GET /api/v1/grafana/, which Grafana calls when the
+// datasource is saved
+func (gc *GrafanaController) TestConnection(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Search handles POST /api/v1/grafana/search, listing the series that can be
+// queried as "metric_type/metric_name"
+func (gc *GrafanaController) Search(c *gin.Context) {
+	var req GrafanaSearchRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.ErrorJSONWithLog(c, http.StatusBadRequest, "Invalid search request", err)
+			return
+		}
+	}
+
+	catalog, err := gc.analyticsService.GetMetricCatalog()
+	if err != nil {
+		logger.Error("Failed to list metrics for Grafana", logger.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list metrics"})
+		return
+	}
+
+	filter := strings.ToLower(strings.TrimSpace(req.Target))
+	targets := make([]string, 0, len(catalog))
+	for _, entry := range catalog {
+		target := entry.MetricType + "/" + entry.MetricName
+		if filter == "" || strings.Contains(strings.ToLower(target), filter) {
+			targets = append(targets, target)
+		}
+	}
+
+	c.JSON(http.StatusOK, targets)
+}
+
+// Query handles POST /api/v1/grafana/query. Intervals of five minutes or
+// more are served from the aggregation of the widest window that fits;
+// shorter intervals return raw samples.
+func (gc *GrafanaController) Query(c *gin.Context) {
+	var req GrafanaQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ErrorJSONWithLog(c, http.StatusBadRequest, "Invalid query request", err)
+		return
+	}
+	if req.Range.From.IsZero() || req.Range.To.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Range from and to are required"})
+		return
+	}
+	if req.Range.To.Sub(req.Range.From) > grafanaMaxRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Time range cannot exceed 90 days"})
+		return
+	}
+
+	groupBy := ""
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	for _, w := range grafanaWindows {
+		if interval >= w.duration {
+			groupBy = w.window
+			break
+		}
+	}
+
+	series := make([]GrafanaTimeSeries, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		if target.Hide || target.Target == "" {
+			continue
+		}
+		metricType, metricName, ok := strings.Cut(target.Target, "/")
+		if !ok || metricType == "" || metricName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Target must be metric_type/metric_name: " + target.Target})
+			return
+		}
+
+		aggregation := target.Data.Aggregation
+		if aggregation == "" {
+			aggregation = "avg"
+		}
+
+		dataPoints, err := gc.analyticsService.QueryMetrics(services.MetricQuery{
+			MetricType:  metricType,
+			MetricName:  metricName,
+			TimeRange:   services.TimeRange{Start: req.Range.From, End: req.Range.To},
+			Aggregation: aggregation,
+			GroupBy:     groupBy,
+			Limit:       req.MaxDataPoints,
+		})
+		if err != nil {
+			logger.Error("Failed to query metrics for Grafana", logger.String("target", target.Target), logger.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query metrics"})
+			return
+		}
+
+		datapoints := make([][2]float64, len(dataPoints))
+		for i, point := range dataPoints {
+			datapoints[i] = [2]float64{point.Value, float64(point.Timestamp.UnixMilli())}
+		}
+		series = append(series, GrafanaTimeSeries{Target: target.Target, Datapoints: datapoints})
+	}
+
+	c.JSON(http.StatusOK, series)
+}
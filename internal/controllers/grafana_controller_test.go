@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+)
+
+// TestGrafanaBodyOverLimit posts bodies over the size limit and checks they
+// are refused with 413 rather than reported as invalid
+func TestGrafanaBodyOverLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gc := NewGrafanaController(nil)
+	router := gin.New()
+	router.Use(middleware.BodyLimitMiddleware(64))
+	router.POST("/grafana/search", gc.Search)
+	router.POST("/grafana/query", gc.Query)
+
+	body := `{"target": "` + strings.Repeat("x", 128) + `"}`
+	for _, path := range []string{"/grafana/search", "/grafana/query"} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: got status %d, want %d: %s", path, recorder.Code, http.StatusRequestEntityTooLarge, recorder.Body)
+		}
+	}
+}
@@ -1,27 +1,50 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"runtime"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nguyendkn/nginx-manager/configs"
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/services"
 	"github.com/nguyendkn/nginx-manager/pkg/logger"
 	"github.com/nguyendkn/nginx-manager/pkg/response"
 )
 
 // HealthController handles health check related endpoints
 type HealthController struct {
-	env *configs.Environment
+	env               *configs.Environment
+	monitoringService *services.MonitoringService
 }
 
-// NewHealthController creates a new health controller instance
-func NewHealthController(env *configs.Environment) *HealthController {
+// NewHealthController creates a new health controller instance. Without a
+// monitoring service the readiness check covers the database only.
+func NewHealthController(env *configs.Environment, monitoringService *services.MonitoringService) *HealthController {
 	return &HealthController{
-		env: env,
+		env:               env,
+		monitoringService: monitoringService,
 	}
 }
 
+// Dependency check states reported by the readiness endpoint
+const (
+	checkStatusHealthy   = "healthy"
+	checkStatusUnhealthy = "unhealthy"
+	checkStatusUnknown   = "unknown" // the check cannot run here and does not affect readiness
+)
+
+// dependencyCheck is the result of one readiness check
+type dependencyCheck struct {
+	Status   string                 `json:"status"`
+	Error    string                 `json:"error,omitempty"`
+	Duration string                 `json:"duration"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
 // HealthCheck handles the health check endpoint
 // @Summary Health check endpoint
 // @Description Returns the health status of the service
@@ -40,7 +63,7 @@ func (hc *HealthController) HealthCheck(c *gin.Context) {
 		"service":   hc.env.GetAppName(),
 		"version":   hc.env.GetAppVersion(),
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
-		"uptime":    time.Since(startTime).String(),
+		"uptime":    hc.uptime().String(),
 		"system": gin.H{
 			"go_version":   runtime.Version(),
 			"goroutines":   runtime.NumGoroutine(),
@@ -95,5 +118,138 @@ func (hc *HealthController) Ping(c *gin.Context) {
 	response.SuccessJSONWithLog(c, pongData, "Pong response")
 }
 
+// Live handles the liveness endpoint. It only reports that the process is
+// serving requests and never checks dependencies, so an outage elsewhere does
+// not get the container restarted.
+// @Summary Liveness probe
+// @Description Reports that the process is up
+// @Tags health
+// @Produce json
+// @Success 200 {object} response.Response
+// @Router /health/live [get]
+func (hc *HealthController) Live(c *gin.Context) {
+	response.SuccessJSON(c, gin.H{
+		"status":    "alive",
+		"version":   hc.env.GetAppVersion(),
+		"uptime":    hc.uptime().String(),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}, "Service is alive")
+}
+
+// Ready handles the readiness endpoint, checking the database, nginx and free
+// disk space. Any unhealthy check makes it respond 503 with every check's
+// result.
+// @Summary Readiness probe
+// @Description Checks the service dependencies
+// @Tags health
+// @Produce json
+// @Success 200 {object} response.Response
+// @Failure 503 {object} response.ErrorResponse
+// @Router /health/ready [get]
+func (hc *HealthController) Ready(c *gin.Context) {
+	checks := map[string]dependencyCheck{
+		"database": runCheck(hc.checkDatabase),
+	}
+	if hc.monitoringService != nil {
+		checks["nginx"] = runCheck(hc.checkNginx)
+		checks["disk"] = runCheck(hc.checkDisk)
+	}
+
+	ready := true
+	for name, check := range checks {
+		if check.Status == checkStatusUnhealthy {
+			ready = false
+			logger.Warn("Readiness check failed",
+				logger.String("check", name),
+				logger.String("error", check.Error),
+			)
+		}
+	}
+
+	readiness := gin.H{
+		"status":    "ready",
+		"version":   hc.env.GetAppVersion(),
+		"uptime":    hc.uptime().String(),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"checks":    checks,
+	}
+	if !ready {
+		readiness["status"] = "not_ready"
+		response.JSON(c, http.StatusServiceUnavailable,
+			response.Error(response.StatusServiceUnavailable, "Service is not ready", nil).WithDetails(readiness))
+		return
+	}
+
+	response.SuccessJSON(c, readiness, "Service is ready")
+}
+
+// runCheck times a check and records its result
+func runCheck(check func() (map[string]interface{}, error)) dependencyCheck {
+	start := time.Now()
+	details, err := check()
+
+	result := dependencyCheck{Status: checkStatusHealthy, Details: details}
+	switch {
+	case errors.Is(err, services.ErrDiskStatsUnsupported):
+		result.Status = checkStatusUnknown
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = checkStatusUnhealthy
+		result.Error = err.Error()
+	}
+	result.Duration = time.Since(start).String()
+	return result
+}
+
+// checkDatabase verifies the database answers queries and is migrated
+func (hc *HealthController) checkDatabase() (map[string]interface{}, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New("database is not initialized")
+	}
+	return nil, database.CheckDatabaseHealth(db)
+}
+
+// checkNginx verifies nginx is running
+func (hc *HealthController) checkNginx() (map[string]interface{}, error) {
+	if !hc.monitoringService.IsNginxRunning() {
+		return nil, errors.New("nginx is not running")
+	}
+	return nil, nil
+}
+
+// checkDisk verifies the root filesystem has the configured free space
+func (hc *HealthController) checkDisk() (map[string]interface{}, error) {
+	disk, err := hc.monitoringService.GetRootDiskStats()
+	if err != nil {
+		return nil, err
+	}
+	if disk.Total == 0 {
+		return nil, errors.New("root filesystem reports no space")
+	}
+
+	freePercent := 100 - disk.UsedPercent
+	minFree := hc.env.GetReadinessMinFreeDiskPercent()
+	details := map[string]interface{}{
+		"mount_point":      disk.MountPoint,
+		"free_bytes":       disk.Free,
+		"free_percent":     freePercent,
+		"min_free_percent": minFree,
+	}
+	if freePercent < minFree {
+		return details, fmt.Errorf("%.1f%% free on %s, below the %.0f%% minimum", freePercent, disk.MountPoint, minFree)
+	}
+	return details, nil
+}
+
+// uptime prefers the monitoring service's start time, which is set while
+// services are initialized, over this package's load time
+func (hc *HealthController) uptime() time.Duration {
+	if hc.monitoringService != nil {
+		return hc.monitoringService.Uptime()
+	}
+	return time.Since(startTime)
+}
+
 // startTime tracks when the application started
 var startTime = time.Now()
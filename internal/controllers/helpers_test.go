@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens a migrated, seeded SQLite database in a temporary directory
+// and makes it the database controllers use
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	config := &database.DatabaseConfig{Driver: "sqlite", Database: filepath.Join(t.TempDir(), "test.db")}
+	if err := database.InitDatabase(config); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	t.Cleanup(func() { database.CloseDatabase() })
+
+	db := database.GetDB()
+	if err := database.AutoMigrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := database.SeedData(db); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	return db
+}
+
+// createTestUser creates a user holding role
+func createTestUser(t *testing.T, db *gorm.DB, email string, role models.RoleName) *models.User {
+	t.Helper()
+
+	user := &models.User{Email: email, Name: email, Roles: models.StringArray{string(role)}}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user %s: %v", email, err)
+	}
+	return user
+}
+
+// serveAs runs handler for a request authenticated as userID and returns
+// the recorded response
+func serveAs(t *testing.T, userID uint, method, path string, body interface{}, handler gin.HandlerFunc, params ...gin.Param) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(method, path, &payload)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	c.Set("user_id", userID)
+	c.Set("auth_service", services.NewAuthService("test"))
+	handler(c)
+	return recorder
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"gorm.io/gorm/schema"
+)
+
+// validateDomainNames validates a list of domain names
+func validateDomainNames(domains []string) error {
+	if len(domains) == 0 {
+		return errors.New("at least one domain name is required")
+	}
+
+	for _, domain := range domains {
+		domain = strings.TrimSpace(domain)
+		if domain == "" {
+			return errors.New("domain name cannot be empty")
+		}
+
+		// Basic domain validation (you can add more sophisticated validation)
+		if len(domain) > 253 {
+			return errors.New("domain name too long: " + domain)
+		}
+
+		if strings.Contains(domain, " ") {
+			return errors.New("domain name cannot contain spaces: " + domain)
+		}
+	}
+
+	return nil
+}
+
+// checkDuplicateDomains checks if any domain already exists in a live proxy,
+// dead or redirection host; soft-deleted hosts do not block reuse. The host
+// being updated is excluded by passing its model and ID; excludeID 0 excludes
+// nothing.
+func checkDuplicateDomains(domains []string, exclude schema.Tabler, excludeID uint) error {
+	if err := services.CheckDomainsAvailable(database.GetDB(), domains, exclude.TableName(), excludeID); err != nil {
+		if errors.Is(err, services.ErrDomainInUse) {
+			return err
+		}
+		return errors.New("failed to check domain uniqueness")
+	}
+	return nil
+}
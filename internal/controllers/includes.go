@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+	"gorm.io/gorm"
+)
+
+// relationIncludes maps the relation names accepted by ?include= to the GORM
+// associations they preload. Nested associations use dotted paths, such as
+// "AccessList.Items".
+type relationIncludes map[string]string
+
+// parseIncludes reads the comma-separated ?include= parameter. Without the
+// parameter the endpoint's defaults are used; an empty value includes nothing.
+// Unknown relation names are an error listing the accepted ones.
+func parseIncludes(c *gin.Context, relations relationIncludes, defaults ...string) ([]string, error) {
+	raw, ok := c.GetQuery("include")
+	if !ok {
+		return defaults, nil
+	}
+
+	includes := []string{}
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := relations[name]; !ok {
+			return nil, fmt.Errorf("unknown include %q, expected one of: %s", name, strings.Join(relations.names(), ", "))
+		}
+		seen[name] = true
+		includes = append(includes, name)
+	}
+	return includes, nil
+}
+
+// rejectIncludes responds with 400 and returns false when ?include= names a
+// relation on an endpoint that has none to include, rather than ignoring it
+func rejectIncludes(c *gin.Context) bool {
+	if strings.Trim(c.Query("include"), ", ") == "" {
+		return true
+	}
+	err := errors.New("include is not supported by this endpoint")
+	response.BadRequestJSONWithLog(c, err.Error(), err)
+	return false
+}
+
+// preloadIncludes adds one preload per included relation, so related records
+// are fetched in a single query per relation rather than per row
+func preloadIncludes(query *gorm.DB, relations relationIncludes, includes []string) *gorm.DB {
+	for _, name := range includes {
+		query = query.Preload(relations[name])
+	}
+	return query
+}
+
+// names returns the accepted relation names in order
+func (r relationIncludes) names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sideloader collects the related records of a list response once per ID, to
+// be returned alongside the list under "included". Nested includes such as
+// "access_list.items" are collected under their top-level relation.
+type sideloader struct {
+	included map[string][]interface{}
+	seen     map[string]map[uint]bool
+}
+
+// newSideloader starts an empty collection for each included relation
+func newSideloader(includes []string) *sideloader {
+	s := &sideloader{
+		included: make(map[string][]interface{}, len(includes)),
+		seen:     make(map[string]map[uint]bool, len(includes)),
+	}
+	for _, name := range includes {
+		root, _, _ := strings.Cut(name, ".")
+		s.included[root] = []interface{}{}
+		s.seen[root] = make(map[uint]bool)
+	}
+	return s
+}
+
+// add records a related record unless it was already added or the relation
+// was not requested
+func (s *sideloader) add(relation string, id uint, record interface{}) {
+	seen, ok := s.seen[relation]
+	if !ok || seen[id] {
+		return
+	}
+	seen[id] = true
+	s.included[relation] = append(s.included[relation], record)
+}
+
+// result returns the collected records keyed by relation name
+func (s *sideloader) result() map[string][]interface{} {
+	return s.included
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestRejectIncludes checks that endpoints without relations to include
+// reject ?include= naming one instead of ignoring it
+func TestRejectIncludes(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	certificates := NewCertificateController(nil)
+	accessLists := NewAccessListController(nil)
+
+	for _, tc := range []struct {
+		name    string
+		path    string
+		handler gin.HandlerFunc
+		want    int
+	}{
+		{"certificate list", "/certificates?include=proxy_hosts", certificates.ListCertificates, http.StatusBadRequest},
+		{"certificate", "/certificates/1?include=streams", certificates.GetCertificate, http.StatusBadRequest},
+		{"expiring certificates", "/certificates/expiring-soon?include=user", certificates.GetExpiringSoon, http.StatusBadRequest},
+		{"access list export", "/access-lists/1/export?include=items", accessLists.ExportAccessList, http.StatusBadRequest},
+		// Including nothing is accepted; without a service the export then fails
+		{"empty include", "/access-lists/1/export?include=", accessLists.ExportAccessList, http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serveAs(t, owner.ID, http.MethodGet, tc.path, nil, tc.handler, gin.Param{Key: "id", Value: "1"})
+			if recorder.Code != tc.want {
+				t.Errorf("got status %d, want %d: %s", recorder.Code, tc.want, recorder.Body)
+			}
+		})
+	}
+}
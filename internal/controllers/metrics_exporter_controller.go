@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+// prometheusContentType is the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsPrefix namespaces every exported metric
+const metricsPrefix = "nginx_manager_"
+
+// MetricsExporterController exposes system metrics and application counters
+// for Prometheus to scrape
+type MetricsExporterController struct {
+	monitoringService *services.MonitoringService
+	token             string
+}
+
+// NewMetricsExporterController creates a new metrics exporter controller.
+// With a token, scrapes must send it as a bearer token.
+func NewMetricsExporterController(monitoringService *services.MonitoringService, token string) *MetricsExporterController {
+	return &MetricsExporterController{
+		monitoringService: monitoringService,
+		token:             token,
+	}
+}
+
+// Metrics handles GET /metrics
+func (mc *MetricsExporterController) Metrics(c *gin.Context) {
+	if mc.token != "" {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(mc.token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.String(http.StatusUnauthorized, "unauthorized\n")
+			return
+		}
+	}
+
+	var w prometheusWriter
+	mc.writeSystemMetrics(&w)
+	writeApplicationMetrics(&w)
+
+	c.Data(http.StatusOK, prometheusContentType, []byte(w.String()))
+}
+
+// writeSystemMetrics writes host and process metrics. A failed collection is
+// reported through nginx_manager_system_metrics_up instead of failing the
+// scrape.
+func (mc *MetricsExporterController) writeSystemMetrics(w *prometheusWriter) {
+	var metrics *services.SystemMetrics
+	var err error
+	if mc.monitoringService != nil {
+		metrics, err = mc.monitoringService.GetSystemMetrics()
+	}
+	if metrics == nil || err != nil {
+		if err != nil {
+			logger.Error("Failed to collect system metrics for export", logger.Err(err))
+		}
+		w.metric("system_metrics_up", "gauge", "Whether system metrics could be collected", nil, 0)
+		return
+	}
+	w.metric("system_metrics_up", "gauge", "Whether system metrics could be collected", nil, 1)
+
+	if metrics.CPU.UsageAvailable {
+		w.metric("cpu_usage_percent", "gauge", "Overall CPU usage", nil, metrics.CPU.Usage)
+	}
+	w.metric("load_average_1m", "gauge", "1 minute load average", nil, metrics.CPU.LoadAvg1)
+	w.metric("load_average_5m", "gauge", "5 minute load average", nil, metrics.CPU.LoadAvg5)
+	w.metric("load_average_15m", "gauge", "15 minute load average", nil, metrics.CPU.LoadAvg15)
+
+	w.metric("memory_total_bytes", "gauge", "Total system memory", nil, float64(metrics.Memory.Total))
+	w.metric("memory_used_bytes", "gauge", "Used system memory", nil, float64(metrics.Memory.Used))
+	w.metric("memory_available_bytes", "gauge", "Available system memory", nil, float64(metrics.Memory.Available))
+
+	disks := metrics.Disks
+	if len(disks) == 0 {
+		disks = []services.DiskStats{metrics.Disk}
+	}
+	w.header("disk_total_bytes", "gauge", "Size of each mounted filesystem")
+	for _, disk := range disks {
+		w.sample("disk_total_bytes", map[string]string{"mount_point": disk.MountPoint}, float64(disk.Total))
+	}
+	w.header("disk_used_bytes", "gauge", "Used space of each mounted filesystem")
+	for _, disk := range disks {
+		w.sample("disk_used_bytes", map[string]string{"mount_point": disk.MountPoint}, float64(disk.Used))
+	}
+
+	w.metric("network_receive_bytes_total", "counter", "Bytes received on monitored interfaces since boot", nil, float64(metrics.Network.BytesRecv))
+	w.metric("network_transmit_bytes_total", "counter", "Bytes sent on monitored interfaces since boot", nil, float64(metrics.Network.BytesSent))
+
+	w.metric("goroutines", "gauge", "Goroutines in the manager process", nil, float64(metrics.Process.Goroutines))
+	w.metric("go_memory_alloc_bytes", "gauge", "Heap bytes allocated by the manager process", nil, float64(metrics.Memory.GoAlloc))
+	w.metric("gc_runs_total", "counter", "Completed garbage collection cycles", nil, float64(metrics.Process.GCRuns))
+	w.metric("uptime_seconds", "gauge", "Time since the manager process started", nil, metrics.Process.Uptime.Seconds())
+}
+
+// writeApplicationMetrics writes counts of managed resources. Counts that
+// cannot be read are left out of the scrape.
+func writeApplicationMetrics(w *prometheusWriter) {
+	db := database.GetDB()
+	if db == nil {
+		return
+	}
+
+	count := func(model interface{}, query string, args ...interface{}) (float64, bool) {
+		var n int64
+		tx := db.Model(model)
+		if query != "" {
+			tx = tx.Where(query, args...)
+		}
+		if err := tx.Count(&n).Error; err != nil {
+			logger.Error("Failed to count resources for export", logger.Err(err))
+			return 0, false
+		}
+		return float64(n), true
+	}
+
+	if total, ok := count(&models.ProxyHost{}, ""); ok {
+		enabled, _ := count(&models.ProxyHost{}, "enabled = ?", true)
+		w.header("proxy_hosts", "gauge", "Proxy hosts by state")
+		w.sample("proxy_hosts", map[string]string{"state": "enabled"}, enabled)
+		w.sample("proxy_hosts", map[string]string{"state": "disabled"}, total-enabled)
+	}
+	if n, ok := count(&models.Certificate{}, ""); ok {
+		w.metric("certificates", "gauge", "Managed certificates", nil, n)
+	}
+	if n, ok := count(&models.AlertInstance{}, "status = ?", "triggered"); ok {
+		w.metric("active_alerts", "gauge", "Alerts currently triggered", nil, n)
+	}
+}
+
+// labelValueEscaper escapes label values as the text format requires
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// prometheusWriter builds a response in the Prometheus text format
+type prometheusWriter struct {
+	strings.Builder
+}
+
+// metric writes a metric with a single sample
+func (w *prometheusWriter) metric(name, metricType, help string, labels map[string]string, value float64) {
+	w.header(name, metricType, help)
+	w.sample(name, labels, value)
+}
+
+// header writes the HELP and TYPE lines of a metric
+func (w *prometheusWriter) header(name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n", metricsPrefix, name, help)
+	fmt.Fprintf(w, "# TYPE %s%s %s\n", metricsPrefix, name, metricType)
+}
+
+// sample writes one sample line, with labels in a stable order
+func (w *prometheusWriter) sample(name string, labels map[string]string, value float64) {
+	w.WriteString(metricsPrefix + name)
+	if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for key := range labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		pairs := make([]string, len(keys))
+		for i, key := range keys {
+			pairs[i] = key + `="` + labelValueEscaper.Replace(labels[key]) + `"`
+		}
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64) + "\n")
+}
@@ -1,10 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
 	"github.com/nguyendkn/nginx-manager/internal/services"
 	"github.com/nguyendkn/nginx-manager/pkg/response"
 )
@@ -12,12 +15,14 @@ import (
 // MonitoringController handles monitoring and real-time metrics endpoints
 type MonitoringController struct {
 	monitoringService *services.MonitoringService
+	activityService   *services.ActivityService
 }
 
 // NewMonitoringController creates a new monitoring controller
-func NewMonitoringController(monitoringService *services.MonitoringService) *MonitoringController {
+func NewMonitoringController(monitoringService *services.MonitoringService, activityService *services.ActivityService) *MonitoringController {
 	return &MonitoringController{
 		monitoringService: monitoringService,
+		activityService:   activityService,
 	}
 }
 
@@ -43,8 +48,49 @@ func (mc *MonitoringController) GetNginxStatus(c *gin.Context) {
 	response.SuccessJSONWithLog(c, status, "Nginx status retrieved successfully")
 }
 
-// GetActivityFeed handles GET /api/v1/monitoring/activity-feed
+// GetErrorLog handles GET /api/v1/monitoring/nginx/error-log. Supports lines,
+// since (RFC3339) and level, the least severe level to include.
+func (mc *MonitoringController) GetErrorLog(c *gin.Context) {
+	lines := services.DefaultErrorLogLines
+	if linesStr := c.Query("lines"); linesStr != "" {
+		var err error
+		if lines, err = strconv.Atoi(linesStr); err != nil || lines < 1 {
+			response.BadRequestJSONWithLog(c, "Invalid lines parameter", err)
+			return
+		}
+	}
+
+	var since time.Time
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, sinceStr); err != nil {
+			response.BadRequestJSONWithLog(c, "Invalid since parameter, expected RFC3339", err)
+			return
+		}
+	}
+
+	errorLog, err := mc.monitoringService.GetErrorLog(lines, since, c.Query("level"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidErrorLogLevel) {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to read nginx error log", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, errorLog, "Nginx error log retrieved successfully")
+}
+
+// GetActivityFeed handles GET /api/v1/monitoring/activity-feed. Supports
+// since_id for polling plus type and level filters.
 func (mc *MonitoringController) GetActivityFeed(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
 	// Parse limit parameter
 	limitStr := c.DefaultQuery("limit", "50")
 	limit, err := strconv.Atoi(limitStr)
@@ -58,7 +104,21 @@ func (mc *MonitoringController) GetActivityFeed(c *gin.Context) {
 		limit = 50
 	}
 
-	activities, err := mc.monitoringService.GetRecentActivity(limit)
+	filter := services.ActivityFilter{
+		Limit: limit,
+		Type:  c.Query("type"),
+		Level: c.Query("level"),
+	}
+	if sinceIDStr := c.Query("since_id"); sinceIDStr != "" {
+		sinceID, err := strconv.ParseUint(sinceIDStr, 10, 32)
+		if err != nil {
+			response.BadRequestJSONWithLog(c, "Invalid since_id parameter", err)
+			return
+		}
+		filter.SinceID = uint(sinceID)
+	}
+
+	activities, err := mc.activityService.GetRecentActivity(user, filter)
 	if err != nil {
 		response.InternalServerErrorJSONWithLog(c, "Failed to get activity feed", err)
 		return
@@ -74,13 +134,57 @@ func (mc *MonitoringController) GetActivityFeed(c *gin.Context) {
 	response.SuccessJSONWithLog(c, result, "Activity feed retrieved successfully")
 }
 
+// webSocketTokenTTL is how long a WebSocket token may be used to connect
+const webSocketTokenTTL = time.Minute
+
 // HandleWebSocket handles WebSocket connections for real-time updates
 func (mc *MonitoringController) HandleWebSocket(c *gin.Context) {
-	mc.monitoringService.HandleWebSocket(c)
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	mc.monitoringService.HandleWebSocket(c, user)
+}
+
+// IssueWebSocketToken handles POST /api/v1/monitoring/ws-token and returns a
+// short-lived token for connecting to /monitoring/ws?token=
+func (mc *MonitoringController) IssueWebSocketToken(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	authService, exists := c.Get("auth_service")
+	if !exists {
+		response.InternalServerErrorJSONWithLog(c, "Auth service not available", nil)
+		return
+	}
+
+	token, err := authService.(*services.AuthService).GenerateWebSocketToken(user, webSocketTokenTTL)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to generate WebSocket token", err)
+		return
+	}
+
+	result := gin.H{
+		"token":      token,
+		"expires_at": time.Now().Add(webSocketTokenTTL),
+	}
+
+	response.SuccessJSONWithLog(c, result, "WebSocket token issued successfully")
 }
 
 // GetDashboardStats handles GET /api/v1/monitoring/dashboard
 func (mc *MonitoringController) GetDashboardStats(c *gin.Context) {
+	user, exists := middleware.GetCurrentUser(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
 	// Get system metrics
 	metrics, err := mc.monitoringService.GetSystemMetrics()
 	if err != nil {
@@ -96,7 +200,7 @@ func (mc *MonitoringController) GetDashboardStats(c *gin.Context) {
 	}
 
 	// Get recent activity
-	activities, err := mc.monitoringService.GetRecentActivity(10)
+	activities, err := mc.activityService.GetRecentActivity(user, services.ActivityFilter{Limit: 10})
 	if err != nil {
 		response.InternalServerErrorJSONWithLog(c, "Failed to get recent activity", err)
 		return
@@ -138,22 +242,15 @@ func (mc *MonitoringController) ControlNginx(c *gin.Context) {
 		return
 	}
 
-	// Validate action
-	validActions := map[string]bool{
-		"start":   true,
-		"stop":    true,
-		"restart": true,
-		"reload":  true,
-		"test":    true,
-	}
-
-	if !validActions[request.Action] {
-		response.BadRequestJSONWithLog(c, "Invalid action. Allowed: start, stop, restart, reload, test", nil)
+	if err := mc.monitoringService.ControlNginx(request.Action); err != nil {
+		if errors.Is(err, services.ErrInvalidNginxAction) {
+			response.BadRequestJSONWithLog(c, "Invalid action. Allowed: "+strings.Join(services.NginxControlActions, ", "), err)
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Nginx "+request.Action+" failed", err)
 		return
 	}
 
-	// TODO: Implement nginx control actions through nginx service
-	// For now, return a success response
 	result := gin.H{
 		"action":    request.Action,
 		"success":   true,
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// NginxController handles nginx deployment endpoints
+type NginxController struct {
+	nginxService *services.NginxService
+}
+
+// NewNginxController creates a new nginx controller
+func NewNginxController(nginxService *services.NginxService) *NginxController {
+	return &NginxController{
+		nginxService: nginxService,
+	}
+}
+
+// GetPendingChanges handles GET /api/v1/nginx/pending
+func (nc *NginxController) GetPendingChanges(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	changes, err := nc.nginxService.GetPendingChanges(userID)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get pending changes", err)
+		return
+	}
+
+	result := gin.H{
+		"changes":       changes,
+		"count":         len(changes),
+		"staged_deploy": nc.nginxService.IsStagedDeploy(),
+		"timestamp":     time.Now(),
+	}
+
+	response.SuccessJSONWithLog(c, result, "Pending changes retrieved successfully")
+}
+
+// ApplyPendingChanges handles POST /api/v1/nginx/apply
+func (nc *NginxController) ApplyPendingChanges(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	result, err := nc.nginxService.ApplyPendingChanges(userID)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to apply pending changes", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "Pending changes applied successfully")
+}
+
+// ListOrphanedConfigs handles GET /api/v1/admin/nginx/orphans
+func (nc *NginxController) ListOrphanedConfigs(c *gin.Context) {
+	orphans, err := nc.nginxService.FindOrphanedConfigs()
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to list orphaned configs", err)
+		return
+	}
+
+	result := gin.H{
+		"orphans":   orphans,
+		"count":     len(orphans),
+		"timestamp": time.Now(),
+	}
+
+	response.SuccessJSONWithLog(c, result, "Orphaned configs retrieved successfully")
+}
+
+// CleanupOrphanedConfigs handles POST /api/v1/admin/nginx/orphans/cleanup.
+// An optional {"files": [...]} body limits the cleanup to those files.
+func (nc *NginxController) CleanupOrphanedConfigs(c *gin.Context) {
+	var request struct {
+		Files []string `json:"files"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			response.BadRequestJSONWithLog(c, "Invalid request format", err)
+			return
+		}
+	}
+
+	result, err := nc.nginxService.CleanupOrphanedConfigs(request.Files)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to clean up orphaned configs", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "Orphaned configs cleaned up successfully")
+}
+
+// GetDefaultCertificate handles GET /api/v1/admin/nginx/default-certificate
+func (nc *NginxController) GetDefaultCertificate(c *gin.Context) {
+	setting, err := nc.nginxService.GetDefaultCertificate()
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get default certificate", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, setting, "Default certificate retrieved successfully")
+}
+
+// UpdateDefaultCertificate handles PUT /api/v1/admin/nginx/default-certificate.
+// Hosts redeployed afterwards use the new default certificate.
+func (nc *NginxController) UpdateDefaultCertificate(c *gin.Context) {
+	var request services.DefaultCertificateSetting
+	if err := c.ShouldBindJSON(&request); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request format", err)
+		return
+	}
+
+	if err := nc.nginxService.SetDefaultCertificate(&request); err != nil {
+		switch {
+		case err == services.ErrCertificateNotFound:
+			response.NotFoundJSONWithLog(c, "Certificate not found")
+		case errors.Is(err, services.ErrInvalidDefaultCertificate):
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to update default certificate", err)
+		}
+		return
+	}
+
+	response.SuccessJSONWithLog(c, request, "Default certificate updated successfully")
+}
@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"gorm.io/gorm"
+)
+
+// ownerScope limits query to the current user's records unless they hold
+// allPermission, the read_all or manage permission of the resource
+func ownerScope(c *gin.Context, query *gorm.DB, userID uint, allPermission string) *gorm.DB {
+	authService, _ := middleware.GetAuthService(c)
+	return authService.ScopeToOwner(query, userID, allPermission)
+}
@@ -2,8 +2,9 @@ package controllers
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
-	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nguyendkn/nginx-manager/internal/database"
@@ -14,37 +15,55 @@ import (
 	"github.com/nguyendkn/nginx-manager/pkg/response"
 )
 
+// errProxyHostNoDomains refuses to enable a host, such as a fresh clone,
+// whose config would render an empty server_name
+var errProxyHostNoDomains = errors.New("proxy host has no domain names; add one before enabling it")
+
 // ProxyHostController handles proxy host management
 type ProxyHostController struct {
-	nginxService *services.NginxService
+	nginxService       *services.NginxService
+	activityService    *services.ActivityService
+	certificateService *services.CertificateService
+	configService      *services.ConfigService
 }
 
 // NewProxyHostController creates a new proxy host controller
-func NewProxyHostController(nginxService *services.NginxService) *ProxyHostController {
+func NewProxyHostController(nginxService *services.NginxService, activityService *services.ActivityService, certificateService *services.CertificateService, configService *services.ConfigService) *ProxyHostController {
 	return &ProxyHostController{
-		nginxService: nginxService,
+		nginxService:       nginxService,
+		activityService:    activityService,
+		certificateService: certificateService,
+		configService:      configService,
 	}
 }
 
 // CreateProxyHostRequest represents the request payload for creating a proxy host
 type CreateProxyHostRequest struct {
-	DomainNames           []string               `json:"domain_names" binding:"required,min=1"`
-	ForwardScheme         models.ForwardScheme   `json:"forward_scheme" binding:"required,oneof=http https"`
-	ForwardHost           string                 `json:"forward_host" binding:"required"`
-	ForwardPort           int                    `json:"forward_port" binding:"required,min=1,max=65535"`
-	AccessListID          *uint                  `json:"access_list_id"`
-	CertificateID         *uint                  `json:"certificate_id"`
-	SSLForced             bool                   `json:"ssl_forced"`
-	CachingEnabled        bool                   `json:"caching_enabled"`
-	BlockExploits         bool                   `json:"block_exploits"`
-	AllowWebsocketUpgrade bool                   `json:"allow_websocket_upgrade"`
-	HTTP2Support          bool                   `json:"http2_support"`
-	HSTSEnabled           bool                   `json:"hsts_enabled"`
-	HSTSSubdomains        bool                   `json:"hsts_subdomains"`
-	AdvancedConfig        string                 `json:"advanced_config"`
-	Enabled               bool                   `json:"enabled"`
-	Locations             map[string]interface{} `json:"locations"`
-	Meta                  map[string]interface{} `json:"meta"`
+	DomainNames           []string                  `json:"domain_names" binding:"required,min=1"`
+	ForwardScheme         models.ForwardScheme      `json:"forward_scheme" binding:"required,oneof=http https"`
+	ForwardHost           string                    `json:"forward_host" binding:"required"`
+	ForwardPort           int                       `json:"forward_port" binding:"required,min=1,max=65535"`
+	AccessListID          *uint                     `json:"access_list_id,omitempty"`
+	CertificateID         *uint                     `json:"certificate_id,omitempty"`
+	SSLForced             bool                      `json:"ssl_forced"`
+	CachingEnabled        bool                      `json:"caching_enabled"`
+	BlockExploits         bool                      `json:"block_exploits"`
+	AllowWebsocketUpgrade bool                      `json:"allow_websocket_upgrade"`
+	HTTP2Support          bool                      `json:"http2_support"`
+	HSTSEnabled           bool                      `json:"hsts_enabled"`
+	HSTSSubdomains        bool                      `json:"hsts_subdomains"`
+	OCSPStapling          bool                      `json:"ocsp_stapling"`
+	AdvancedConfig        string                    `json:"advanced_config"`
+	ProxyBind             string                    `json:"proxy_bind" binding:"omitempty,ip"`
+	ProxyBuffering        *bool                     `json:"proxy_buffering"`
+	ProxyBufferSize       string                    `json:"proxy_buffer_size"`
+	ProxyBuffers          string                    `json:"proxy_buffers"`
+	UpstreamKeepalive     int                       `json:"upstream_keepalive"`
+	RateLimit             *models.RateLimitConfig   `json:"rate_limit"`
+	HealthCheck           *models.HealthCheckConfig `json:"health_check"`
+	Enabled               bool                      `json:"enabled"`
+	Locations             map[string]interface{}    `json:"locations"`
+	Meta                  map[string]interface{}    `json:"meta"`
 }
 
 // UpdateProxyHostRequest represents the request payload for updating a proxy host
@@ -52,6 +71,15 @@ type UpdateProxyHostRequest struct {
 	CreateProxyHostRequest
 }
 
+// ProxyHostDryRunResponse is the outcome of a create or update with
+// ?dry_run=true: the proxy host as it would be saved, the configuration it
+// would deploy and the nginx validation of that configuration
+type ProxyHostDryRunResponse struct {
+	ProxyHost   *models.ProxyHost          `json:"proxy_host"`
+	NginxConfig string                     `json:"nginx_config"`
+	Validation  *services.ValidationResult `json:"validation,omitempty"`
+}
+
 // ProxyHostListResponse represents a single proxy host in list view
 type ProxyHostListResponse struct {
 	ID            uint                 `json:"id"`
@@ -66,6 +94,10 @@ type ProxyHostListResponse struct {
 	CreatedAt     string               `json:"created_at"`
 	UpdatedAt     string               `json:"updated_at"`
 
+	// Upstream health from the periodic probe; empty until first checked
+	LastHealthStatus string     `json:"last_health_status"`
+	LastCheckedAt    *time.Time `json:"last_checked_at"`
+
 	// Computed fields
 	PrimaryDomain string `json:"primary_domain"`
 	TargetURL     string `json:"target_url"`
@@ -80,22 +112,42 @@ type ProxyHostListResponse struct {
 // ProxyHostDetailResponse represents a proxy host detail view
 type ProxyHostDetailResponse struct {
 	ProxyHostListResponse
-	CachingEnabled        bool                   `json:"caching_enabled"`
-	BlockExploits         bool                   `json:"block_exploits"`
-	AllowWebsocketUpgrade bool                   `json:"allow_websocket_upgrade"`
-	HTTP2Support          bool                   `json:"http2_support"`
-	HSTSEnabled           bool                   `json:"hsts_enabled"`
-	HSTSSubdomains        bool                   `json:"hsts_subdomains"`
-	AdvancedConfig        string                 `json:"advanced_config"`
-	Locations             map[string]interface{} `json:"locations"`
-	Meta                  map[string]interface{} `json:"meta"`
+	CachingEnabled        bool                      `json:"caching_enabled"`
+	BlockExploits         bool                      `json:"block_exploits"`
+	AllowWebsocketUpgrade bool                      `json:"allow_websocket_upgrade"`
+	HTTP2Support          bool                      `json:"http2_support"`
+	HSTSEnabled           bool                      `json:"hsts_enabled"`
+	HSTSSubdomains        bool                      `json:"hsts_subdomains"`
+	OCSPStapling          bool                      `json:"ocsp_stapling"`
+	AdvancedConfig        string                    `json:"advanced_config"`
+	ProxyBind             string                    `json:"proxy_bind"`
+	ProxyBuffering        bool                      `json:"proxy_buffering"`
+	ProxyBufferSize       string                    `json:"proxy_buffer_size"`
+	ProxyBuffers          string                    `json:"proxy_buffers"`
+	UpstreamKeepalive     int                       `json:"upstream_keepalive"`
+	RateLimit             *models.RateLimitConfig   `json:"rate_limit"`
+	HealthCheck           *models.HealthCheckConfig `json:"health_check"`
+	Locations             map[string]interface{}    `json:"locations"`
+	Meta                  map[string]interface{}    `json:"meta"`
 
 	// Nginx configuration
 	NginxConfig string `json:"nginx_config,omitempty"`
 	ConfigValid bool   `json:"config_valid"`
 }
 
-// List returns paginated list of proxy hosts for the current user
+// proxyHostIncludes lists the relations proxy host endpoints accept in ?include=
+var proxyHostIncludes = relationIncludes{
+	"certificate":       "Certificate",
+	"access_list":       "AccessList",
+	"access_list.items": "AccessList.Items",
+}
+
+// proxyHostDefaultIncludes are loaded when ?include= is not given
+var proxyHostDefaultIncludes = []string{"certificate", "access_list"}
+
+// List returns paginated list of proxy hosts for the current user. Without
+// ?include= the certificate and access list are embedded in each host; with
+// it the requested relations are returned once each under "included".
 func (pc *ProxyHostController) List(c *gin.Context) {
 	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
@@ -104,23 +156,22 @@ func (pc *ProxyHostController) List(c *gin.Context) {
 	}
 
 	// Parse query parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page := response.ParsePagination(c)
 	search := c.Query("search")
 	enabled := c.Query("enabled")
 
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	includes, err := parseIncludes(c, proxyHostIncludes, proxyHostDefaultIncludes...)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
 	}
+	_, sideload := c.GetQuery("include")
 
 	// Get database connection
 	db := database.GetDB()
 
 	// Build query
-	query := db.Where("user_id = ?", userID)
+	query := ownerScope(c, db, userID, models.PermissionProxyHostReadAll)
 
 	if search != "" {
 		query = query.Where("domain_names LIKE ? OR forward_host LIKE ?", "%"+search+"%", "%"+search+"%")
@@ -145,9 +196,8 @@ func (pc *ProxyHostController) List(c *gin.Context) {
 
 	// Get paginated results
 	var proxyHosts []models.ProxyHost
-	offset := (page - 1) * limit
-	if err := query.Preload("Certificate").Preload("AccessList").
-		Offset(offset).Limit(limit).
+	if err := preloadIncludes(query, proxyHostIncludes, includes).
+		Offset(page.Offset()).Limit(page.Limit).
 		Order("created_at DESC").
 		Find(&proxyHosts).Error; err != nil {
 		logger.Error("Failed to fetch proxy hosts", logger.Err(err), logger.Uint("user_id", userID))
@@ -156,7 +206,8 @@ func (pc *ProxyHostController) List(c *gin.Context) {
 	}
 
 	// Convert to response format
-	var proxyHostResponses []ProxyHostListResponse
+	proxyHostResponses := make([]ProxyHostListResponse, 0, len(proxyHosts))
+	included := newSideloader(includes)
 	for _, host := range proxyHosts {
 		resp := ProxyHostListResponse{
 			ID:            host.ID,
@@ -174,33 +225,35 @@ func (pc *ProxyHostController) List(c *gin.Context) {
 			TargetURL:     host.GetTargetURL(),
 			SSLEnabled:    host.IsSSLEnabled(),
 			HasAccessList: host.HasAccessList(),
+
+			LastHealthStatus: host.LastHealthStatus,
+			LastCheckedAt:    host.LastCheckedAt,
 		}
 
-		if host.Certificate != nil {
+		if sideload {
+			if host.Certificate != nil {
+				included.add("certificate", host.Certificate.ID, host.Certificate)
+			}
+			if host.AccessList != nil {
+				included.add("access_list", host.AccessList.ID, host.AccessList)
+			}
+		} else {
 			resp.Certificate = host.Certificate
-		}
-		if host.AccessList != nil {
 			resp.AccessList = host.AccessList
 		}
 
 		proxyHostResponses = append(proxyHostResponses, resp)
 	}
 
-	// Pagination info
-	response.SuccessJSONWithLog(c, gin.H{
-		"data": proxyHostResponses,
-		"pagination": gin.H{
-			"page":     page,
-			"limit":    limit,
-			"total":    total,
-			"pages":    (total + int64(limit) - 1) / int64(limit),
-			"has_next": page*limit < int(total),
-			"has_prev": page > 1,
-		},
-	}, "Proxy hosts retrieved successfully")
+	result := response.Paginated(proxyHostResponses, page.Page, page.Limit, total, "Proxy hosts retrieved successfully")
+	if sideload {
+		result = result.WithIncluded(included.result())
+	}
+	response.PaginatedResponseJSONWithLog(c, result)
 }
 
-// Get returns a single proxy host by ID
+// Get returns a single proxy host by ID, with the relations named in
+// ?include= (certificate and access list by default) embedded
 func (pc *ProxyHostController) Get(c *gin.Context) {
 	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
@@ -215,10 +268,15 @@ func (pc *ProxyHostController) Get(c *gin.Context) {
 		return
 	}
 
+	includes, err := parseIncludes(c, proxyHostIncludes, proxyHostDefaultIncludes...)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
 	db := database.GetDB()
 	var proxyHost models.ProxyHost
-	if err := db.Where("id = ? AND user_id = ?", id, userID).
-		Preload("Certificate").Preload("AccessList").
+	if err := preloadIncludes(ownerScope(c, db, userID, models.PermissionProxyHostReadAll).Where("id = ?", id), proxyHostIncludes, includes).
 		First(&proxyHost).Error; err != nil {
 		logger.Error("Failed to fetch proxy host", logger.Err(err), logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
 		response.NotFoundJSONWithLog(c, "Proxy host not found")
@@ -251,6 +309,9 @@ func (pc *ProxyHostController) Get(c *gin.Context) {
 			HasAccessList: proxyHost.HasAccessList(),
 			Certificate:   proxyHost.Certificate,
 			AccessList:    proxyHost.AccessList,
+
+			LastHealthStatus: proxyHost.LastHealthStatus,
+			LastCheckedAt:    proxyHost.LastCheckedAt,
 		},
 		CachingEnabled:        proxyHost.CachingEnabled,
 		BlockExploits:         proxyHost.BlockExploits,
@@ -258,7 +319,15 @@ func (pc *ProxyHostController) Get(c *gin.Context) {
 		HTTP2Support:          proxyHost.HTTP2Support,
 		HSTSEnabled:           proxyHost.HSTSEnabled,
 		HSTSSubdomains:        proxyHost.HSTSSubdomains,
+		OCSPStapling:          proxyHost.OCSPStapling,
 		AdvancedConfig:        proxyHost.AdvancedConfig,
+		ProxyBind:             proxyHost.ProxyBind,
+		ProxyBuffering:        proxyHost.IsProxyBufferingEnabled(),
+		ProxyBufferSize:       proxyHost.ProxyBufferSize,
+		ProxyBuffers:          proxyHost.ProxyBuffers,
+		UpstreamKeepalive:     proxyHost.UpstreamKeepalive,
+		RateLimit:             proxyHost.RateLimit,
+		HealthCheck:           proxyHost.HealthCheck,
 		Locations:             proxyHost.Locations,
 		Meta:                  proxyHost.Meta,
 		NginxConfig:           nginxConfig,
@@ -276,49 +345,26 @@ func (pc *ProxyHostController) Create(c *gin.Context) {
 		return
 	}
 
-	var req CreateProxyHostRequest
+	req := defaultProxyHostRequest()
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
 		return
 	}
 
-	// Validate domain names
-	if err := pc.validateDomainNames(req.DomainNames); err != nil {
+	if err := pc.validateProxyHostRequest(&req, 0); err != nil {
 		response.BadRequestJSONWithLog(c, err.Error(), err)
 		return
 	}
-
-	// Check for duplicate domains
-	if err := pc.checkDuplicateDomains(req.DomainNames, 0); err != nil {
-		response.BadRequestJSONWithLog(c, err.Error(), err)
+	if !checkProxyHostReferences(c, userID, &req, nil) {
 		return
 	}
 
 	// Create proxy host model
-	proxyHost := models.ProxyHost{
-		DomainNames:           models.StringArray(req.DomainNames),
-		ForwardScheme:         req.ForwardScheme,
-		ForwardHost:           req.ForwardHost,
-		ForwardPort:           req.ForwardPort,
-		AccessListID:          req.AccessListID,
-		CertificateID:         req.CertificateID,
-		SSLForced:             req.SSLForced,
-		CachingEnabled:        req.CachingEnabled,
-		BlockExploits:         req.BlockExploits,
-		AllowWebsocketUpgrade: req.AllowWebsocketUpgrade,
-		HTTP2Support:          req.HTTP2Support,
-		HSTSEnabled:           req.HSTSEnabled,
-		HSTSSubdomains:        req.HSTSSubdomains,
-		AdvancedConfig:        req.AdvancedConfig,
-		Enabled:               req.Enabled,
-		UserID:                userID,
-	}
+	proxyHost := newProxyHost(userID, &req)
 
-	if req.Locations != nil {
-		proxyHost.Locations = models.JSON(req.Locations)
-	}
-	if req.Meta != nil {
-		proxyHost.Meta = models.JSON(req.Meta)
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		pc.respondDryRun(c, userID, &proxyHost)
+		return
 	}
 
 	// Save to database
@@ -330,12 +376,17 @@ func (pc *ProxyHostController) Create(c *gin.Context) {
 	}
 
 	// Generate and apply nginx configuration if enabled and service is available
+	var deployErr error
 	if proxyHost.Enabled && pc.nginxService != nil {
-		if err := pc.applyProxyHostConfig(&proxyHost); err != nil {
-			logger.Error("Failed to apply nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
+		if deployErr = pc.applyProxyHostConfig(&proxyHost); deployErr != nil {
+			logger.Error("Failed to apply nginx configuration", logger.Err(deployErr), logger.Uint("proxy_host_id", proxyHost.ID))
 			// Continue anyway, don't fail the creation
 		}
 	}
+	services.RecordAudit(db, services.NewAuditContext(c), services.NewAuditLog(userID, models.ActionCreated,
+		models.ObjectTypeProxyHost, proxyHost.ID, "Created proxy host: "+proxyHost.GetPrimaryDomain()))
+	pc.recordActivity(&proxyHost, "created", deployErr)
+	proxyHost.CertificateWarnings = pc.certificateWarnings(userID, &proxyHost)
 
 	logger.Info("Proxy host created successfully", logger.Uint("id", proxyHost.ID), logger.Uint("user_id", userID), logger.Any("domains", req.DomainNames))
 	response.SuccessJSONWithLog(c, proxyHost, "Proxy host created successfully")
@@ -365,69 +416,24 @@ func (pc *ProxyHostController) Update(c *gin.Context) {
 	// Find existing proxy host
 	db := database.GetDB()
 	var proxyHost models.ProxyHost
-	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&proxyHost).Error; err != nil {
+	if err := ownerScope(c, db, userID, models.PermissionProxyHostManage).Where("id = ?", id).First(&proxyHost).Error; err != nil {
 		response.NotFoundJSONWithLog(c, "Proxy host not found")
 		return
 	}
 
-	// Validate domain names
-	if err := pc.validateDomainNames(req.DomainNames); err != nil {
-		response.BadRequestJSONWithLog(c, err.Error(), err)
-		return
-	}
+	before := proxyHost
 
-	// Check for duplicate domains (excluding current host)
-	if err := pc.checkDuplicateDomains(req.DomainNames, uint(id)); err != nil {
+	if err := pc.validateProxyHostRequest(&req.CreateProxyHostRequest, uint(id)); err != nil {
 		response.BadRequestJSONWithLog(c, err.Error(), err)
 		return
 	}
-
-	// Update fields
-	proxyHost.DomainNames = models.StringArray(req.DomainNames)
-	proxyHost.ForwardScheme = req.ForwardScheme
-	proxyHost.ForwardHost = req.ForwardHost
-	proxyHost.ForwardPort = req.ForwardPort
-	proxyHost.AccessListID = req.AccessListID
-	proxyHost.CertificateID = req.CertificateID
-	proxyHost.SSLForced = req.SSLForced
-	proxyHost.CachingEnabled = req.CachingEnabled
-	proxyHost.BlockExploits = req.BlockExploits
-	proxyHost.AllowWebsocketUpgrade = req.AllowWebsocketUpgrade
-	proxyHost.HTTP2Support = req.HTTP2Support
-	proxyHost.HSTSEnabled = req.HSTSEnabled
-	proxyHost.HSTSSubdomains = req.HSTSSubdomains
-	proxyHost.AdvancedConfig = req.AdvancedConfig
-	proxyHost.Enabled = req.Enabled
-
-	if req.Locations != nil {
-		proxyHost.Locations = models.JSON(req.Locations)
-	}
-	if req.Meta != nil {
-		proxyHost.Meta = models.JSON(req.Meta)
-	}
-
-	// Save changes
-	if err := db.Save(&proxyHost).Error; err != nil {
-		logger.Error("Failed to update proxy host", logger.Err(err), logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
-		response.InternalServerErrorJSONWithLog(c, "Failed to update proxy host", err)
+	if !checkProxyHostReferences(c, userID, &req.CreateProxyHostRequest, &proxyHost) {
 		return
 	}
 
-	// Update nginx configuration
-	if pc.nginxService != nil {
-		if proxyHost.Enabled {
-			if err := pc.applyProxyHostConfig(&proxyHost); err != nil {
-				logger.Error("Failed to apply nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
-			}
-		} else {
-			if err := pc.removeProxyHostConfig(&proxyHost); err != nil {
-				logger.Error("Failed to remove nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
-			}
-		}
-	}
+	updateProxyHost(&proxyHost, &req.CreateProxyHostRequest)
 
-	logger.Info("Proxy host updated successfully", logger.Uint("id", proxyHost.ID), logger.Uint("user_id", userID))
-	response.SuccessJSONWithLog(c, proxyHost, "Proxy host updated successfully")
+	pc.saveProxyHostUpdate(c, userID, &before, &proxyHost)
 }
 
 // Delete deletes a proxy host
@@ -448,15 +454,16 @@ func (pc *ProxyHostController) Delete(c *gin.Context) {
 	// Find existing proxy host
 	db := database.GetDB()
 	var proxyHost models.ProxyHost
-	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&proxyHost).Error; err != nil {
+	if err := ownerScope(c, db, userID, models.PermissionProxyHostManage).Where("id = ?", id).First(&proxyHost).Error; err != nil {
 		response.NotFoundJSONWithLog(c, "Proxy host not found")
 		return
 	}
 
 	// Remove nginx configuration
+	var deployErr error
 	if pc.nginxService != nil {
-		if err := pc.removeProxyHostConfig(&proxyHost); err != nil {
-			logger.Error("Failed to remove nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
+		if deployErr = pc.removeProxyHostConfig(&proxyHost); deployErr != nil {
+			logger.Error("Failed to remove nginx configuration", logger.Err(deployErr), logger.Uint("proxy_host_id", proxyHost.ID))
 			// Continue anyway
 		}
 	}
@@ -468,10 +475,74 @@ func (pc *ProxyHostController) Delete(c *gin.Context) {
 		return
 	}
 
+	services.RecordAudit(db, services.NewAuditContext(c), services.NewAuditLog(userID, models.ActionDeleted,
+		models.ObjectTypeProxyHost, proxyHost.ID, "Deleted proxy host: "+proxyHost.GetPrimaryDomain()))
+	pc.recordActivity(&proxyHost, "deleted", deployErr)
+
 	logger.Info("Proxy host deleted successfully", logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
 	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Proxy host deleted successfully")
 }
 
+// Clone copies a proxy host into a new, disabled host without domain names,
+// for the user to fill in before enabling it
+func (pc *ProxyHostController) Clone(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid proxy host ID", err)
+		return
+	}
+
+	db := database.GetDB()
+	var source models.ProxyHost
+	if err := ownerScope(c, db, userID, models.PermissionProxyHostManage).Where("id = ?", id).First(&source).Error; err != nil {
+		response.NotFoundJSONWithLog(c, "Proxy host not found")
+		return
+	}
+	if err := pc.checkForwardTarget(source.ForwardHost); err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	// Domains are left empty so the copy cannot conflict with the original
+	req := proxyHostRequest(&source)
+	req.DomainNames = []string{}
+	req.Enabled = false
+	proxyHost := newProxyHost(userID, &req)
+	proxyHost.Locations = source.Locations.Clone()
+	proxyHost.Meta = source.Meta.Clone()
+	if source.RateLimit != nil {
+		rateLimit := *source.RateLimit
+		proxyHost.RateLimit = &rateLimit
+	}
+	if source.HealthCheck != nil {
+		healthCheck := *source.HealthCheck
+		proxyHost.HealthCheck = &healthCheck
+	}
+	if source.ProxyBuffering != nil {
+		proxyBuffering := *source.ProxyBuffering
+		proxyHost.ProxyBuffering = &proxyBuffering
+	}
+
+	if err := db.Create(&proxyHost).Error; err != nil {
+		logger.Error("Failed to clone proxy host", logger.Err(err), logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to clone proxy host", err)
+		return
+	}
+
+	services.RecordAudit(db, services.NewAuditContext(c), services.NewAuditLog(userID, models.ActionCreated,
+		models.ObjectTypeProxyHost, proxyHost.ID, "Cloned proxy host: "+source.GetPrimaryDomain()))
+	pc.recordActivity(&proxyHost, "cloned", nil)
+
+	logger.Info("Proxy host cloned successfully", logger.Uint("id", proxyHost.ID), logger.Uint("source_id", source.ID), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, proxyHost, "Proxy host cloned successfully")
+}
+
 // Toggle toggles the enabled status of a proxy host
 func (pc *ProxyHostController) Toggle(c *gin.Context) {
 	userID, exists := middleware.GetCurrentUserID(c)
@@ -490,12 +561,24 @@ func (pc *ProxyHostController) Toggle(c *gin.Context) {
 	// Find existing proxy host
 	db := database.GetDB()
 	var proxyHost models.ProxyHost
-	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&proxyHost).Error; err != nil {
+	if err := ownerScope(c, db, userID, models.PermissionProxyHostManage).Where("id = ?", id).First(&proxyHost).Error; err != nil {
 		response.NotFoundJSONWithLog(c, "Proxy host not found")
 		return
 	}
 
+	if !proxyHost.Enabled {
+		if len(proxyHost.DomainNames) == 0 {
+			response.BadRequestJSONWithLog(c, errProxyHostNoDomains.Error(), errProxyHostNoDomains)
+			return
+		}
+		if err := pc.checkForwardTarget(proxyHost.ForwardHost); err != nil {
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+			return
+		}
+	}
+
 	// Toggle enabled status
+	before := proxyHost
 	proxyHost.Enabled = !proxyHost.Enabled
 
 	// Save changes
@@ -506,22 +589,15 @@ func (pc *ProxyHostController) Toggle(c *gin.Context) {
 	}
 
 	// Update nginx configuration
-	if pc.nginxService != nil {
-		if proxyHost.Enabled {
-			if err := pc.applyProxyHostConfig(&proxyHost); err != nil {
-				logger.Error("Failed to apply nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
-			}
-		} else {
-			if err := pc.removeProxyHostConfig(&proxyHost); err != nil {
-				logger.Error("Failed to remove nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
-			}
-		}
-	}
+	deployErr := pc.syncProxyHostConfig(&proxyHost)
 
 	action := "disabled"
 	if proxyHost.Enabled {
 		action = "enabled"
 	}
+	services.RecordAudit(db, services.NewAuditContext(c), services.NewUpdateAuditLog(userID, models.ObjectTypeProxyHost, proxyHost.ID,
+		"Proxy host "+action+": "+proxyHost.GetPrimaryDomain(), &before, &proxyHost))
+	pc.recordActivity(&proxyHost, action, deployErr)
 
 	logger.Info("Proxy host toggled successfully", logger.Uint("id", uint(id)), logger.Uint("user_id", userID), logger.Bool("enabled", proxyHost.Enabled))
 	response.SuccessJSONWithLog(c, gin.H{
@@ -549,9 +625,33 @@ func (pc *ProxyHostController) BulkToggle(c *gin.Context) {
 	}
 
 	db := database.GetDB()
-	// Update proxy hosts
-	result := db.Where("id IN ? AND user_id = ?", req.IDs, userID).
-		Updates(models.ProxyHost{Enabled: req.Enabled})
+	// Hosts already in the requested state are left out of the audit log
+	var changing []models.ProxyHost
+	if err := ownerScope(c, db, userID, models.PermissionProxyHostManage).Where("id IN ? AND enabled <> ?", req.IDs, req.Enabled).Find(&changing).Error; err != nil {
+		logger.Error("Failed to fetch proxy hosts to toggle", logger.Err(err), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to update proxy hosts", err)
+		return
+	}
+	if req.Enabled {
+		for _, host := range changing {
+			if len(host.DomainNames) == 0 {
+				err := fmt.Errorf("proxy host %d: %w", host.ID, errProxyHostNoDomains)
+				response.BadRequestJSONWithLog(c, err.Error(), err)
+				return
+			}
+			if err := pc.checkForwardTarget(host.ForwardHost); err != nil {
+				err = fmt.Errorf("proxy host %d: %w", host.ID, err)
+				response.BadRequestJSONWithLog(c, err.Error(), err)
+				return
+			}
+		}
+	}
+
+	// Update proxy hosts. A map is used because struct updates skip zero
+	// values, which would silently drop enabled=false.
+	result := ownerScope(c, db.Model(&models.ProxyHost{}), userID, models.PermissionProxyHostManage).
+		Where("id IN ?", req.IDs).
+		Updates(map[string]interface{}{"enabled": req.Enabled})
 
 	if result.Error != nil {
 		logger.Error("Failed to bulk toggle proxy hosts", logger.Err(result.Error), logger.Uint("user_id", userID))
@@ -559,33 +659,34 @@ func (pc *ProxyHostController) BulkToggle(c *gin.Context) {
 		return
 	}
 
+	action := "disabled"
+	if req.Enabled {
+		action = "enabled"
+	}
+
+	audit := services.NewAuditContext(c)
+	for i := range changing {
+		before := changing[i]
+		changing[i].Enabled = req.Enabled
+		services.RecordAudit(db, audit, services.NewUpdateAuditLog(userID, models.ObjectTypeProxyHost, changing[i].ID,
+			"Proxy host "+action+": "+changing[i].GetPrimaryDomain(), &before, &changing[i]))
+	}
+
 	// Get updated proxy hosts for nginx config update
 	if pc.nginxService != nil {
 		var proxyHosts []models.ProxyHost
-		if err := db.Where("id IN ? AND user_id = ?", req.IDs, userID).Find(&proxyHosts).Error; err != nil {
+		if err := ownerScope(c, db, userID, models.PermissionProxyHostManage).Where("id IN ?", req.IDs).Find(&proxyHosts).Error; err != nil {
 			logger.Error("Failed to fetch updated proxy hosts", logger.Err(err), logger.Uint("user_id", userID))
 			// Continue anyway
 		} else {
 			// Update nginx configurations
-			for _, host := range proxyHosts {
-				if host.Enabled {
-					if err := pc.applyProxyHostConfig(&host); err != nil {
-						logger.Error("Failed to apply nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", host.ID))
-					}
-				} else {
-					if err := pc.removeProxyHostConfig(&host); err != nil {
-						logger.Error("Failed to remove nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", host.ID))
-					}
-				}
+			for i := range proxyHosts {
+				deployErr := pc.syncProxyHostConfig(&proxyHosts[i])
+				pc.recordActivity(&proxyHosts[i], action, deployErr)
 			}
 		}
 	}
 
-	action := "disabled"
-	if req.Enabled {
-		action = "enabled"
-	}
-
 	logger.Info("Proxy hosts bulk toggled successfully", logger.Int64("count", result.RowsAffected), logger.Uint("user_id", userID), logger.Bool("enabled", req.Enabled))
 	response.SuccessJSONWithLog(c, gin.H{
 		"updated": result.RowsAffected,
@@ -593,79 +694,330 @@ func (pc *ProxyHostController) BulkToggle(c *gin.Context) {
 	}, strconv.FormatInt(result.RowsAffected, 10)+" proxy hosts "+action+" successfully")
 }
 
-// validateDomainNames validates a list of domain names
-func (pc *ProxyHostController) validateDomainNames(domains []string) error {
-	if len(domains) == 0 {
-		return errors.New("at least one domain name is required")
+// certificateWarnings checks that the host's certificate covers its domains
+// and is usable. Problems are reported as warnings and never block a save.
+func (pc *ProxyHostController) certificateWarnings(userID uint, proxyHost *models.ProxyHost) []string {
+	var warnings []string
+	if pc.nginxService != nil && pc.nginxService.UsesFallbackCertificate(proxyHost.CertificateID, proxyHost.SSLForced) {
+		warnings = append(warnings, "no valid certificate is assigned; HTTPS is served with the default certificate, which browsers will not trust")
 	}
 
-	for _, domain := range domains {
-		domain = strings.TrimSpace(domain)
-		if domain == "" {
-			return errors.New("domain name cannot be empty")
-		}
+	if pc.certificateService == nil || proxyHost.CertificateID == nil {
+		return warnings
+	}
 
-		// Basic domain validation (you can add more sophisticated validation)
-		if len(domain) > 253 {
-			return errors.New("domain name too long: " + domain)
-		}
+	validation, err := pc.certificateService.ValidateForDomains(userID, *proxyHost.CertificateID, proxyHost.DomainNames)
+	if err != nil {
+		logger.Warn("Failed to validate proxy host certificate", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
+		return append(warnings, "certificate could not be checked: "+err.Error())
+	}
+	return append(warnings, validation.Warnings...)
+}
 
-		if strings.Contains(domain, " ") {
-			return errors.New("domain name cannot contain spaces: " + domain)
+// generateProxyHostConfig renders the configuration the proxy host deploys,
+// reporting whether it could be generated
+func (pc *ProxyHostController) generateProxyHostConfig(proxyHost *models.ProxyHost) (string, bool) {
+	config, err := pc.nginxService.BuildProxyHostConfig(proxyHost)
+	if err != nil {
+		logger.Warn("Failed to generate nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
+		return "", false
+	}
+	return config, true
+}
+
+// respondDryRun generates and validates the configuration of an unsaved proxy
+// host. Nothing is persisted and no files are written.
+func (pc *ProxyHostController) respondDryRun(c *gin.Context, userID uint, proxyHost *models.ProxyHost) {
+	if pc.nginxService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Nginx service not available", nil)
+		return
+	}
+
+	config, ok := pc.generateProxyHostConfig(proxyHost)
+	if !ok {
+		response.InternalServerErrorJSONWithLog(c, "Failed to generate nginx configuration", nil)
+		return
+	}
+
+	result := ProxyHostDryRunResponse{ProxyHost: proxyHost, NginxConfig: config}
+	if pc.configService != nil {
+		validation, err := pc.configService.ValidateConfig(userID, models.ConfigTypeServer, config)
+		if err != nil {
+			response.InternalServerErrorJSONWithLog(c, "Failed to validate nginx configuration", err)
+			return
 		}
+		result.Validation = validation
 	}
+	proxyHost.CertificateWarnings = pc.certificateWarnings(userID, proxyHost)
 
-	return nil
+	response.SuccessJSONWithLog(c, result, "Proxy host dry run completed")
 }
 
-// checkDuplicateDomains checks if any domain already exists in other proxy hosts
-func (pc *ProxyHostController) checkDuplicateDomains(domains []string, excludeID uint) error {
-	db := database.GetDB()
-	for _, domain := range domains {
-		var count int64
-		query := db.Model(&models.ProxyHost{}).Where("domain_names LIKE ?", "%"+domain+"%")
+// checkForwardTarget checks a forward host against the configured forward
+// target policy. Stored hosts are checked again before they are enabled or
+// cloned, since the policy may have changed since they were saved.
+func (pc *ProxyHostController) checkForwardTarget(forwardHost string) error {
+	if pc.nginxService == nil {
+		return nil
+	}
+	return pc.nginxService.CheckForwardTarget(forwardHost)
+}
 
-		if excludeID > 0 {
-			query = query.Where("id != ?", excludeID)
-		}
+// validateProxyHostRequest checks a proxy host request beyond its binding
+// rules. excludeID is the host being updated, or 0 for a new host, so its own
+// domains do not count as duplicates.
+func (pc *ProxyHostController) validateProxyHostRequest(req *CreateProxyHostRequest, excludeID uint) error {
+	// Validate domain names
+	if err := validateDomainNames(req.DomainNames); err != nil {
+		return err
+	}
 
-		if err := query.Count(&count).Error; err != nil {
-			return errors.New("failed to check domain uniqueness")
-		}
+	// Check for duplicate domains
+	if err := checkDuplicateDomains(req.DomainNames, &models.ProxyHost{}, excludeID); err != nil {
+		return err
+	}
 
-		if count > 0 {
-			return errors.New("domain already exists: " + domain)
+	// Check the forward target against the configured restrictions
+	if err := pc.checkForwardTarget(req.ForwardHost); err != nil {
+		return err
+	}
+
+	// Validate outgoing source address
+	if err := services.ValidateProxyBind(req.ProxyBind); err != nil {
+		return err
+	}
+
+	// Validate proxy buffer sizes
+	if err := services.ValidateProxyBuffers(req.ProxyBufferSize, req.ProxyBuffers); err != nil {
+		return err
+	}
+
+	// Validate upstream keepalive
+	if err := services.ValidateUpstreamKeepalive(req.UpstreamKeepalive); err != nil {
+		return err
+	}
+
+	// Validate rate limiting
+	if err := services.ValidateRateLimit(req.RateLimit); err != nil {
+		return err
+	}
+
+	// Validate upstream health checks
+	var healthCheckInterval time.Duration
+	if pc.nginxService != nil {
+		healthCheckInterval = pc.nginxService.HealthCheckInterval()
+	}
+	return services.ValidateHealthCheck(req.HealthCheck, healthCheckInterval)
+}
+
+// checkProxyHostReferences responds with 400 for a certificate or access
+// list that does not exist and 403 for another user's, returning false. On
+// update, references the host already has are not checked again, so an
+// owner can still edit a host an admin attached a shared certificate to.
+func checkProxyHostReferences(c *gin.Context, userID uint, req *CreateProxyHostRequest, existing *models.ProxyHost) bool {
+	certificateID, accessListID := req.CertificateID, req.AccessListID
+	if existing != nil {
+		if sameID(certificateID, existing.CertificateID) {
+			certificateID = nil
+		}
+		if sameID(accessListID, existing.AccessListID) {
+			accessListID = nil
 		}
 	}
 
-	return nil
+	return checkHostReferences(c, userID, certificateID, accessListID)
 }
 
-// Helper methods for nginx configuration (simplified for now)
-func (pc *ProxyHostController) generateProxyHostConfig(proxyHost *models.ProxyHost) (string, bool) {
-	// This is a simplified implementation
-	// In a real implementation, you would generate actual nginx config
-	config := "# Generated nginx config for " + proxyHost.GetPrimaryDomain() + "\n"
-	config += "server {\n"
-	config += "    server_name " + strings.Join(proxyHost.DomainNames, " ") + ";\n"
-	config += "    location / {\n"
-	config += "        proxy_pass " + proxyHost.GetTargetURL() + ";\n"
-	config += "    }\n"
-	config += "}\n"
+// checkHostReferences checks the certificate and access list any host type
+// refers to with services.CheckHostReferences, responding with 400 for a
+// missing record and 403 for another user's and returning false
+func checkHostReferences(c *gin.Context, userID uint, certificateID, accessListID *uint) bool {
+	authService, _ := middleware.GetAuthService(c)
+	err := services.CheckHostReferences(database.GetDB(), authService, userID, certificateID, accessListID)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrCertificateNotFound), errors.Is(err, services.ErrAccessListNotFound):
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+	case errors.Is(err, services.ErrCertificateNotOwned), errors.Is(err, services.ErrAccessListNotOwned):
+		response.ForbiddenJSONWithLog(c, err.Error())
+	default:
+		response.InternalServerErrorJSONWithLog(c, "Failed to check host references", err)
+	}
+	return false
+}
 
-	return config, true
+// sameID reports whether two optional IDs are equal
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// defaultProxyHostRequest returns a create request holding the settings a
+// new proxy host gets for fields the request body leaves out
+func defaultProxyHostRequest() CreateProxyHostRequest {
+	return CreateProxyHostRequest{
+		BlockExploits: true,
+		HTTP2Support:  true,
+		Enabled:       true,
+	}
+}
+
+// newProxyHost builds the proxy host described by a create request
+func newProxyHost(userID uint, req *CreateProxyHostRequest) models.ProxyHost {
+	proxyHost := models.ProxyHost{
+		DomainNames:           models.StringArray(req.DomainNames),
+		ForwardScheme:         req.ForwardScheme,
+		ForwardHost:           req.ForwardHost,
+		ForwardPort:           req.ForwardPort,
+		AccessListID:          req.AccessListID,
+		CertificateID:         req.CertificateID,
+		SSLForced:             req.SSLForced,
+		CachingEnabled:        req.CachingEnabled,
+		BlockExploits:         req.BlockExploits,
+		AllowWebsocketUpgrade: req.AllowWebsocketUpgrade,
+		HTTP2Support:          req.HTTP2Support,
+		HSTSEnabled:           req.HSTSEnabled,
+		HSTSSubdomains:        req.HSTSSubdomains,
+		OCSPStapling:          req.OCSPStapling,
+		AdvancedConfig:        req.AdvancedConfig,
+		ProxyBind:             req.ProxyBind,
+		ProxyBuffering:        req.ProxyBuffering,
+		ProxyBufferSize:       req.ProxyBufferSize,
+		ProxyBuffers:          req.ProxyBuffers,
+		UpstreamKeepalive:     req.UpstreamKeepalive,
+		RateLimit:             req.RateLimit,
+		HealthCheck:           req.HealthCheck,
+		Enabled:               req.Enabled,
+		UserID:                userID,
+	}
+
+	if req.Locations != nil {
+		proxyHost.Locations = models.JSON(req.Locations)
+	}
+	if req.Meta != nil {
+		proxyHost.Meta = models.JSON(req.Meta)
+	}
+
+	return proxyHost
 }
 
+// updateProxyHost replaces the fields of a proxy host with those of a
+// request. Locations and meta are kept when the request leaves them out.
+func updateProxyHost(proxyHost *models.ProxyHost, req *CreateProxyHostRequest) {
+	proxyHost.DomainNames = models.StringArray(req.DomainNames)
+	proxyHost.ForwardScheme = req.ForwardScheme
+	proxyHost.ForwardHost = req.ForwardHost
+	proxyHost.ForwardPort = req.ForwardPort
+	proxyHost.AccessListID = req.AccessListID
+	proxyHost.CertificateID = req.CertificateID
+	proxyHost.SSLForced = req.SSLForced
+	proxyHost.CachingEnabled = req.CachingEnabled
+	proxyHost.BlockExploits = req.BlockExploits
+	proxyHost.AllowWebsocketUpgrade = req.AllowWebsocketUpgrade
+	proxyHost.HTTP2Support = req.HTTP2Support
+	proxyHost.HSTSEnabled = req.HSTSEnabled
+	proxyHost.HSTSSubdomains = req.HSTSSubdomains
+	proxyHost.OCSPStapling = req.OCSPStapling
+	proxyHost.AdvancedConfig = req.AdvancedConfig
+	proxyHost.ProxyBind = req.ProxyBind
+	proxyHost.ProxyBuffering = req.ProxyBuffering
+	proxyHost.ProxyBufferSize = req.ProxyBufferSize
+	proxyHost.ProxyBuffers = req.ProxyBuffers
+	proxyHost.UpstreamKeepalive = req.UpstreamKeepalive
+	proxyHost.RateLimit = req.RateLimit
+	proxyHost.HealthCheck = req.HealthCheck
+	proxyHost.Enabled = req.Enabled
+
+	if req.Locations != nil {
+		proxyHost.Locations = models.JSON(req.Locations)
+	}
+	if req.Meta != nil {
+		proxyHost.Meta = models.JSON(req.Meta)
+	}
+}
+
+// saveProxyHostUpdate saves an updated proxy host, audits the change against
+// before and redeploys its configuration. With ?dry_run=true it only reports
+// what would be deployed.
+func (pc *ProxyHostController) saveProxyHostUpdate(c *gin.Context, userID uint, before, proxyHost *models.ProxyHost) {
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		pc.respondDryRun(c, userID, proxyHost)
+		return
+	}
+
+	// Save changes
+	db := database.GetDB()
+	if err := db.Save(proxyHost).Error; err != nil {
+		logger.Error("Failed to update proxy host", logger.Err(err), logger.Uint("id", proxyHost.ID), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to update proxy host", err)
+		return
+	}
+
+	services.RecordAudit(db, services.NewAuditContext(c), services.NewUpdateAuditLog(userID, models.ObjectTypeProxyHost, proxyHost.ID,
+		"Updated proxy host: "+proxyHost.GetPrimaryDomain(), before, proxyHost))
+
+	// Update nginx configuration
+	deployErr := pc.syncProxyHostConfig(proxyHost)
+	pc.recordActivity(proxyHost, "updated", deployErr)
+	proxyHost.CertificateWarnings = pc.certificateWarnings(userID, proxyHost)
+
+	logger.Info("Proxy host updated successfully", logger.Uint("id", proxyHost.ID), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, proxyHost, "Proxy host updated successfully")
+}
+
+// applyProxyHostConfig deploys the proxy host config, or stages it when staged deployment is enabled
 func (pc *ProxyHostController) applyProxyHostConfig(proxyHost *models.ProxyHost) error {
-	// This is a simplified implementation
-	// In a real implementation, you would write the config to nginx sites and reload
 	logger.Info("Applying nginx configuration", logger.Uint("proxy_host_id", proxyHost.ID))
+	return pc.nginxService.DeployProxyHost(proxyHost)
+}
+
+// syncProxyHostConfig deploys an enabled proxy host or removes a disabled one,
+// logging and returning any failure
+func (pc *ProxyHostController) syncProxyHostConfig(proxyHost *models.ProxyHost) error {
+	if pc.nginxService == nil {
+		return nil
+	}
+
+	if proxyHost.Enabled {
+		if err := pc.applyProxyHostConfig(proxyHost); err != nil {
+			logger.Error("Failed to apply nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
+			return err
+		}
+		return nil
+	}
+
+	if err := pc.removeProxyHostConfig(proxyHost); err != nil {
+		logger.Error("Failed to remove nginx configuration", logger.Err(err), logger.Uint("proxy_host_id", proxyHost.ID))
+		return err
+	}
 	return nil
 }
 
+// recordActivity adds a proxy host lifecycle event to the activity feed; a
+// deployment failure turns it into a warning
+func (pc *ProxyHostController) recordActivity(proxyHost *models.ProxyHost, action string, deployErr error) {
+	level := services.ActivityLevelInfo
+	message := "Proxy host " + proxyHost.GetPrimaryDomain() + " " + action
+	details := models.JSON{
+		"proxy_host_id": proxyHost.ID,
+		"domain":        proxyHost.GetPrimaryDomain(),
+		"target":        proxyHost.ForwardHost + ":" + strconv.Itoa(proxyHost.ForwardPort),
+		"action":        action,
+	}
+	if deployErr != nil {
+		level = services.ActivityLevelWarning
+		message += " but the nginx configuration could not be updated"
+		details["error"] = deployErr.Error()
+	}
+
+	pc.activityService.RecordForUser(proxyHost.UserID, services.ActivityTypeProxyHost, message, level, details)
+}
+
+// removeProxyHostConfig removes the proxy host config, or stages it when staged deployment is enabled
 func (pc *ProxyHostController) removeProxyHostConfig(proxyHost *models.ProxyHost) error {
-	// This is a simplified implementation
-	// In a real implementation, you would remove the config file and reload nginx
 	logger.Info("Removing nginx configuration", logger.Uint("proxy_host_id", proxyHost.ID))
-	return nil
+	return pc.nginxService.UndeployProxyHost(proxyHost)
 }
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"gorm.io/gorm"
+)
+
+// TestCreateProxyHostChecksReferences creates proxy hosts referring to another
+// user's certificate and to a deleted access list
+func TestCreateProxyHostChecksReferences(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	other := createTestUser(t, db, "other@example.test", models.RoleUser)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+
+	foreignCertificate := &models.Certificate{Name: "foreign", Provider: models.ProviderCustom, UserID: other.ID}
+	deletedAccessList := &models.AccessList{Name: "deleted", UserID: owner.ID}
+	for _, record := range []interface{}{foreignCertificate, deletedAccessList} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Delete(deletedAccessList).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	pc := NewProxyHostController(nil, nil, nil, nil)
+	request := func(domain string, certificateID, accessListID *uint) CreateProxyHostRequest {
+		return CreateProxyHostRequest{
+			DomainNames:   []string{domain},
+			ForwardScheme: "http",
+			ForwardHost:   "127.0.0.1",
+			ForwardPort:   8080,
+			CertificateID: certificateID,
+			AccessListID:  accessListID,
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		userID uint
+		req    CreateProxyHostRequest
+		want   int
+	}{
+		{"foreign certificate", owner.ID, request("a.example.com", &foreignCertificate.ID, nil), http.StatusForbidden},
+		{"deleted access list", owner.ID, request("b.example.com", nil, &deletedAccessList.ID), http.StatusBadRequest},
+		{"admin with foreign certificate", admin.ID, request("c.example.com", &foreignCertificate.ID, nil), http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serveAs(t, tc.userID, http.MethodPost, "/api/v1/proxy-hosts", tc.req, pc.Create)
+			if recorder.Code != tc.want {
+				t.Fatalf("got status %d, want %d: %s", recorder.Code, tc.want, recorder.Body)
+			}
+
+			var count int64
+			db.Model(&models.ProxyHost{}).Where("user_id = ?", tc.userID).Count(&count)
+			if saved := count > 0; saved != (tc.want == http.StatusOK) {
+				t.Errorf("proxy host saved: %v", saved)
+			}
+		})
+	}
+}
+
+// TestCreateProxyHostStoresFlags creates proxy hosts with the flags that
+// default to on turned off, and left out
+func TestCreateProxyHostStoresFlags(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	pc := NewProxyHostController(nil, nil, nil, nil)
+
+	for _, tc := range []struct {
+		name   string
+		domain string
+		flags  gin.H
+		want   bool
+	}{
+		{"turned off", "off.example.com", gin.H{"enabled": false, "block_exploits": false, "http2_support": false}, false},
+		{"left out", "default.example.com", gin.H{}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body := gin.H{"domain_names": []string{tc.domain}, "forward_scheme": "http", "forward_host": "127.0.0.1", "forward_port": 8080}
+			for key, value := range tc.flags {
+				body[key] = value
+			}
+			recorder := serveAs(t, owner.ID, http.MethodPost, "/api/v1/proxy-hosts", body, pc.Create)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("got status %d: %s", recorder.Code, recorder.Body)
+			}
+
+			var stored models.ProxyHost
+			if err := db.Where("user_id = ?", owner.ID).Last(&stored).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Enabled != tc.want || stored.BlockExploits != tc.want || stored.HTTP2Support != tc.want {
+				t.Errorf("got enabled %v, block_exploits %v, http2_support %v, want all %v",
+					stored.Enabled, stored.BlockExploits, stored.HTTP2Support, tc.want)
+			}
+		})
+	}
+}
+
+// createTestProxyHost creates an enabled proxy host for domain
+func createTestProxyHost(t *testing.T, db *gorm.DB, userID uint, domain string) *models.ProxyHost {
+	t.Helper()
+
+	proxyHost := &models.ProxyHost{
+		DomainNames:   models.StringArray{domain},
+		ForwardScheme: "http",
+		ForwardHost:   "127.0.0.1",
+		ForwardPort:   8080,
+		Enabled:       true,
+		UserID:        userID,
+	}
+	if err := db.Create(proxyHost).Error; err != nil {
+		t.Fatalf("create proxy host %s: %v", domain, err)
+	}
+	return proxyHost
+}
+
+// TestBulkToggleDisablesInDatabase bulk-disables hosts and checks that false
+// is stored, and that another user's host in the request is left alone
+func TestBulkToggleDisablesInDatabase(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	other := createTestUser(t, db, "other@example.test", models.RoleUser)
+
+	var ids []uint
+	for _, domain := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		ids = append(ids, createTestProxyHost(t, db, owner.ID, domain).ID)
+	}
+	foreign := createTestProxyHost(t, db, other.ID, "foreign.example.com")
+
+	pc := NewProxyHostController(nil, nil, nil, nil)
+	enabledOf := func(id uint) bool {
+		t.Helper()
+		var proxyHost models.ProxyHost
+		if err := db.First(&proxyHost, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		return proxyHost.Enabled
+	}
+
+	for _, enabled := range []bool{false, true} {
+		body := gin.H{"ids": append(append([]uint{}, ids...), foreign.ID), "enabled": enabled}
+		recorder := serveAs(t, owner.ID, http.MethodPost, "/api/v1/proxy-hosts/bulk-toggle", body, pc.BulkToggle)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("enabled=%v: got status %d: %s", enabled, recorder.Code, recorder.Body)
+		}
+
+		for _, id := range ids {
+			if got := enabledOf(id); got != enabled {
+				t.Errorf("enabled=%v: proxy host %d stored enabled=%v", enabled, id, got)
+			}
+		}
+		if !enabledOf(foreign.ID) {
+			t.Errorf("enabled=%v: another user's proxy host was disabled", enabled)
+		}
+	}
+}
+
+// TestForwardTargetPolicyOnStoredHosts denies a stored host's forward target
+// and checks that it can no longer be enabled or cloned
+func TestForwardTargetPolicyOnStoredHosts(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	proxyHost := createTestProxyHost(t, db, owner.ID, "denied.example.com")
+	if err := db.Model(proxyHost).Update("enabled", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprint(proxyHost.ID)
+
+	dir := t.TempDir()
+	nginxService := services.NewNginxService(filepath.Join(dir, "nginx.conf"), dir, dir, dir, services.NewAuthService("test"))
+	policy, err := services.NewForwardTargetPolicy(nil, []string{"127.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nginxService.SetForwardTargetPolicy(policy)
+	pc := NewProxyHostController(nginxService, nil, nil, nil)
+
+	for _, tc := range []struct {
+		name    string
+		method  string
+		path    string
+		body    interface{}
+		handler gin.HandlerFunc
+	}{
+		{"toggle", http.MethodPost, "/proxy-hosts/" + id + "/toggle", nil, pc.Toggle},
+		{"bulk toggle", http.MethodPost, "/proxy-hosts/bulk-toggle", gin.H{"ids": []uint{proxyHost.ID}, "enabled": true}, pc.BulkToggle},
+		{"clone", http.MethodPost, "/proxy-hosts/" + id + "/clone", nil, pc.Clone},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serveAs(t, owner.ID, tc.method, tc.path, tc.body, tc.handler, gin.Param{Key: "id", Value: id})
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("got status %d, want %d: %s", recorder.Code, http.StatusBadRequest, recorder.Body)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&models.ProxyHost{}).Where("enabled = ?", true).Or("id <> ?", proxyHost.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("got %d enabled or cloned proxy hosts, want none", count)
+	}
+}
+
+// TestCloneKeepsFlags clones a host with HTTP/2 and exploit blocking turned
+// off and checks the copy is disabled, without domains and otherwise the same
+func TestCloneKeepsFlags(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	source := &models.ProxyHost{
+		DomainNames:           models.StringArray{"source.example.com"},
+		ForwardScheme:         "http",
+		ForwardHost:           "127.0.0.1",
+		ForwardPort:           8080,
+		AllowWebsocketUpgrade: true,
+		Enabled:               true,
+		UserID:                owner.ID,
+	}
+	want := proxyHostRequest(source)
+	want.DomainNames, want.Enabled = []string{}, false
+	if err := db.Create(source).Error; err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprint(source.ID)
+
+	pc := NewProxyHostController(nil, nil, nil, nil)
+	recorder := serveAs(t, owner.ID, http.MethodPost, "/proxy-hosts/"+id+"/clone", nil, pc.Clone, gin.Param{Key: "id", Value: id})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var clone models.ProxyHost
+	if err := db.Where("id <> ?", source.ID).First(&clone).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := proxyHostRequest(&clone); !reflect.DeepEqual(got, want) {
+		t.Errorf("clone differs:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+// TestPatchClearsNullableSettings patches a host with rate limiting and a
+// health check and checks that a missing field keeps the setting while null
+// removes it
+func TestPatchClearsNullableSettings(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	proxyHost := createTestProxyHost(t, db, owner.ID, "patch.example.com")
+	proxyHost.RateLimit = &models.RateLimitConfig{RequestsPerSecond: 10, Burst: 20}
+	proxyHost.HealthCheck = &models.HealthCheckConfig{Path: "/healthz"}
+	if err := db.Save(proxyHost).Error; err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprint(proxyHost.ID)
+
+	pc := NewProxyHostController(nil, nil, nil, nil)
+	patch := func(body gin.H) models.ProxyHost {
+		t.Helper()
+		recorder := serveAs(t, owner.ID, http.MethodPatch, "/proxy-hosts/"+id, body, pc.Patch, gin.Param{Key: "id", Value: id})
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("patch %v: got status %d: %s", body, recorder.Code, recorder.Body)
+		}
+		var stored models.ProxyHost
+		if err := db.First(&stored, proxyHost.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+
+	stored := patch(gin.H{"forward_port": 8081})
+	if stored.RateLimit == nil || stored.HealthCheck == nil {
+		t.Fatalf("missing fields cleared settings: rate_limit=%v health_check=%v", stored.RateLimit, stored.HealthCheck)
+	}
+
+	stored = patch(gin.H{"rate_limit": nil})
+	if stored.RateLimit != nil {
+		t.Errorf("rate_limit = %+v after null, want nil", stored.RateLimit)
+	}
+	if stored.HealthCheck == nil || stored.HealthCheck.Path != "/healthz" {
+		t.Errorf("health_check = %+v, want it kept", stored.HealthCheck)
+	}
+
+	stored = patch(gin.H{"health_check": nil})
+	if stored.HealthCheck != nil {
+		t.Errorf("health_check = %+v after null, want nil", stored.HealthCheck)
+	}
+}
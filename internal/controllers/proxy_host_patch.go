@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// PatchProxyHostRequest is a partial proxy host update: only the fields
+// present in the body change. An access_list_id or certificate_id of 0
+// removes the access list or certificate, and a rate_limit or health_check of
+// null removes the setting.
+type PatchProxyHostRequest struct {
+	DomainNames           *[]string                          `json:"domain_names"`
+	ForwardScheme         *models.ForwardScheme              `json:"forward_scheme"`
+	ForwardHost           *string                            `json:"forward_host"`
+	ForwardPort           *int                               `json:"forward_port"`
+	AccessListID          *uint                              `json:"access_list_id"`
+	CertificateID         *uint                              `json:"certificate_id"`
+	SSLForced             *bool                              `json:"ssl_forced"`
+	CachingEnabled        *bool                              `json:"caching_enabled"`
+	BlockExploits         *bool                              `json:"block_exploits"`
+	AllowWebsocketUpgrade *bool                              `json:"allow_websocket_upgrade"`
+	HTTP2Support          *bool                              `json:"http2_support"`
+	HSTSEnabled           *bool                              `json:"hsts_enabled"`
+	HSTSSubdomains        *bool                              `json:"hsts_subdomains"`
+	OCSPStapling          *bool                              `json:"ocsp_stapling"`
+	AdvancedConfig        *string                            `json:"advanced_config"`
+	ProxyBind             *string                            `json:"proxy_bind"`
+	ProxyBuffering        *bool                              `json:"proxy_buffering"`
+	ProxyBufferSize       *string                            `json:"proxy_buffer_size"`
+	ProxyBuffers          *string                            `json:"proxy_buffers"`
+	UpstreamKeepalive     *int                               `json:"upstream_keepalive"`
+	RateLimit             optional[models.RateLimitConfig]   `json:"rate_limit"`
+	HealthCheck           optional[models.HealthCheckConfig] `json:"health_check"`
+	Enabled               *bool                              `json:"enabled"`
+	Locations             map[string]interface{}             `json:"locations"`
+	Meta                  map[string]interface{}             `json:"meta"`
+}
+
+// Patch applies a partial update to a proxy host, leaving the fields missing
+// from the body as they are
+func (pc *ProxyHostController) Patch(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid proxy host ID", err)
+		return
+	}
+
+	var patch PatchProxyHostRequest
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
+		return
+	}
+
+	// Find existing proxy host
+	db := database.GetDB()
+	var proxyHost models.ProxyHost
+	if err := ownerScope(c, db, userID, models.PermissionProxyHostManage).Where("id = ?", id).First(&proxyHost).Error; err != nil {
+		response.NotFoundJSONWithLog(c, "Proxy host not found")
+		return
+	}
+
+	before := proxyHost
+
+	// The merged host must pass the same checks as a full update
+	req := proxyHostRequest(&proxyHost)
+	patch.apply(&req)
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
+		return
+	}
+	if err := pc.validateProxyHostRequest(&req, uint(id)); err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+	if !checkProxyHostReferences(c, userID, &req, &proxyHost) {
+		return
+	}
+
+	updateProxyHost(&proxyHost, &req)
+	pc.saveProxyHostUpdate(c, userID, &before, &proxyHost)
+}
+
+// proxyHostRequest converts a proxy host to the full request that would
+// recreate it, leaving out locations and meta
+func proxyHostRequest(proxyHost *models.ProxyHost) CreateProxyHostRequest {
+	return CreateProxyHostRequest{
+		DomainNames:           proxyHost.DomainNames,
+		ForwardScheme:         proxyHost.ForwardScheme,
+		ForwardHost:           proxyHost.ForwardHost,
+		ForwardPort:           proxyHost.ForwardPort,
+		AccessListID:          proxyHost.AccessListID,
+		CertificateID:         proxyHost.CertificateID,
+		SSLForced:             proxyHost.SSLForced,
+		CachingEnabled:        proxyHost.CachingEnabled,
+		BlockExploits:         proxyHost.BlockExploits,
+		AllowWebsocketUpgrade: proxyHost.AllowWebsocketUpgrade,
+		HTTP2Support:          proxyHost.HTTP2Support,
+		HSTSEnabled:           proxyHost.HSTSEnabled,
+		HSTSSubdomains:        proxyHost.HSTSSubdomains,
+		OCSPStapling:          proxyHost.OCSPStapling,
+		AdvancedConfig:        proxyHost.AdvancedConfig,
+		ProxyBind:             proxyHost.ProxyBind,
+		ProxyBuffering:        proxyHost.ProxyBuffering,
+		ProxyBufferSize:       proxyHost.ProxyBufferSize,
+		ProxyBuffers:          proxyHost.ProxyBuffers,
+		UpstreamKeepalive:     proxyHost.UpstreamKeepalive,
+		RateLimit:             proxyHost.RateLimit,
+		HealthCheck:           proxyHost.HealthCheck,
+		Enabled:               proxyHost.Enabled,
+	}
+}
+
+// apply copies the fields present in the patch onto req
+func (p *PatchProxyHostRequest) apply(req *CreateProxyHostRequest) {
+	if p.DomainNames != nil {
+		req.DomainNames = *p.DomainNames
+	}
+	if p.ForwardScheme != nil {
+		req.ForwardScheme = *p.ForwardScheme
+	}
+	if p.ForwardHost != nil {
+		req.ForwardHost = *p.ForwardHost
+	}
+	if p.ForwardPort != nil {
+		req.ForwardPort = *p.ForwardPort
+	}
+	if p.AccessListID != nil {
+		req.AccessListID = optionalID(*p.AccessListID)
+	}
+	if p.CertificateID != nil {
+		req.CertificateID = optionalID(*p.CertificateID)
+	}
+	if p.SSLForced != nil {
+		req.SSLForced = *p.SSLForced
+	}
+	if p.CachingEnabled != nil {
+		req.CachingEnabled = *p.CachingEnabled
+	}
+	if p.BlockExploits != nil {
+		req.BlockExploits = *p.BlockExploits
+	}
+	if p.AllowWebsocketUpgrade != nil {
+		req.AllowWebsocketUpgrade = *p.AllowWebsocketUpgrade
+	}
+	if p.HTTP2Support != nil {
+		req.HTTP2Support = *p.HTTP2Support
+	}
+	if p.HSTSEnabled != nil {
+		req.HSTSEnabled = *p.HSTSEnabled
+	}
+	if p.HSTSSubdomains != nil {
+		req.HSTSSubdomains = *p.HSTSSubdomains
+	}
+	if p.OCSPStapling != nil {
+		req.OCSPStapling = *p.OCSPStapling
+	}
+	if p.AdvancedConfig != nil {
+		req.AdvancedConfig = *p.AdvancedConfig
+	}
+	if p.ProxyBind != nil {
+		req.ProxyBind = *p.ProxyBind
+	}
+	if p.ProxyBuffering != nil {
+		req.ProxyBuffering = p.ProxyBuffering
+	}
+	if p.ProxyBufferSize != nil {
+		req.ProxyBufferSize = *p.ProxyBufferSize
+	}
+	if p.ProxyBuffers != nil {
+		req.ProxyBuffers = *p.ProxyBuffers
+	}
+	if p.UpstreamKeepalive != nil {
+		req.UpstreamKeepalive = *p.UpstreamKeepalive
+	}
+	if p.RateLimit.Set {
+		req.RateLimit = p.RateLimit.Value
+	}
+	if p.HealthCheck.Set {
+		req.HealthCheck = p.HealthCheck.Value
+	}
+	if p.Enabled != nil {
+		req.Enabled = *p.Enabled
+	}
+	req.Locations = p.Locations
+	req.Meta = p.Meta
+}
+
+// optionalID turns the 0 a patch uses to clear a reference into nil
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// optional is a nullable patch field that tells a missing field, which leaves
+// the value as it is, apart from null, which clears it
+type optional[T any] struct {
+	Set   bool // the field is in the body
+	Value *T   // nil when the field is null
+}
+
+// UnmarshalJSON is only called for fields present in the body, null included
+func (o *optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	o.Value = new(T)
+	return json.Unmarshal(data, o.Value)
+}
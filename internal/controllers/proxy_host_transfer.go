@@ -0,0 +1,327 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// ProxyHostExportVersion is the format version written by Export
+const ProxyHostExportVersion = 1
+
+// Proxy host export formats
+const (
+	ProxyHostExportJSON = "json"
+	ProxyHostExportYAML = "yaml"
+)
+
+// Per-host import outcomes
+const (
+	ProxyHostImportCreated = "created"
+	ProxyHostImportSkipped = "skipped"
+	ProxyHostImportFailed  = "failed"
+)
+
+// ProxyHostExport is a portable copy of a user's proxy hosts
+type ProxyHostExport struct {
+	Version    int                 `json:"version"`
+	ExportedAt time.Time           `json:"exported_at"`
+	ProxyHosts []ExportedProxyHost `json:"proxy_hosts"`
+}
+
+// ExportedProxyHost is a proxy host create request whose certificate and
+// access list are referenced by name, since IDs differ between instances.
+// Certificate and access list IDs are ignored on import.
+type ExportedProxyHost struct {
+	CreateProxyHostRequest
+	Certificate string `json:"certificate,omitempty"`
+	AccessList  string `json:"access_list,omitempty"`
+}
+
+// ProxyHostImportItem reports the outcome of importing one proxy host
+type ProxyHostImportItem struct {
+	Index       int      `json:"index"`
+	DomainNames []string `json:"domain_names"`
+	Status      string   `json:"status"` // created, skipped or failed
+	ProxyHostID uint     `json:"proxy_host_id,omitempty"`
+	Message     string   `json:"message,omitempty"`
+}
+
+// ProxyHostImportResult reports what an import created
+type ProxyHostImportResult struct {
+	Created int                   `json:"created"`
+	Skipped int                   `json:"skipped"`
+	Failed  int                   `json:"failed"`
+	Items   []ProxyHostImportItem `json:"items"`
+}
+
+// Export returns all of the user's proxy hosts as JSON, or as a YAML download
+// with ?format=yaml
+func (pc *ProxyHostController) Export(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	format := c.DefaultQuery("format", ProxyHostExportJSON)
+	if format != ProxyHostExportJSON && format != ProxyHostExportYAML {
+		response.BadRequestJSONWithLog(c, "Unsupported export format, expected json or yaml", nil)
+		return
+	}
+
+	var proxyHosts []models.ProxyHost
+	if err := database.GetDB().Where("user_id = ?", userID).
+		Preload("Certificate").
+		Preload("AccessList").
+		Order("id ASC").
+		Find(&proxyHosts).Error; err != nil {
+		logger.Error("Failed to fetch proxy hosts", logger.Err(err), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to export proxy hosts", err)
+		return
+	}
+
+	export := ProxyHostExport{
+		Version:    ProxyHostExportVersion,
+		ExportedAt: time.Now(),
+		ProxyHosts: make([]ExportedProxyHost, 0, len(proxyHosts)),
+	}
+	for i := range proxyHosts {
+		export.ProxyHosts = append(export.ProxyHosts, exportProxyHost(&proxyHosts[i]))
+	}
+
+	if format == ProxyHostExportJSON {
+		response.SuccessJSONWithLog(c, export, "Proxy hosts exported successfully")
+		return
+	}
+
+	data, err := marshalYAML(export)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to export proxy hosts", err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="proxy-hosts.yaml"`)
+	c.Data(http.StatusOK, "application/yaml", data)
+}
+
+// Import recreates exported proxy hosts for the user. The body is an export
+// in JSON, or YAML with ?format=yaml. Each host is imported on its own:
+// hosts whose domains are already in use are skipped and invalid hosts fail
+// without stopping the rest.
+func (pc *ProxyHostController) Import(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
+		return
+	}
+
+	var export ProxyHostExport
+	switch c.DefaultQuery("format", ProxyHostExportJSON) {
+	case ProxyHostExportJSON:
+		err = json.Unmarshal(body, &export)
+	case ProxyHostExportYAML:
+		err = unmarshalYAML(body, &export)
+	default:
+		response.BadRequestJSONWithLog(c, "Unsupported import format, expected json or yaml", nil)
+		return
+	}
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid proxy host export", err)
+		return
+	}
+	if export.Version != ProxyHostExportVersion {
+		err := fmt.Errorf("unsupported export version %d, expected %d", export.Version, ProxyHostExportVersion)
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	result := ProxyHostImportResult{Items: make([]ProxyHostImportItem, 0, len(export.ProxyHosts))}
+	for i := range export.ProxyHosts {
+		item := pc.importProxyHost(userID, &export.ProxyHosts[i])
+		item.Index = i
+		switch item.Status {
+		case ProxyHostImportCreated:
+			result.Created++
+		case ProxyHostImportSkipped:
+			result.Skipped++
+		default:
+			result.Failed++
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	logger.Info("Proxy hosts imported",
+		logger.Uint("user_id", userID),
+		logger.Int("created", result.Created),
+		logger.Int("skipped", result.Skipped),
+		logger.Int("failed", result.Failed))
+	response.SuccessJSONWithLog(c, result, "Proxy hosts imported")
+}
+
+// importProxyHost creates one exported proxy host, remapping its certificate
+// and access list to the user's by name
+func (pc *ProxyHostController) importProxyHost(userID uint, exported *ExportedProxyHost) ProxyHostImportItem {
+	req := exported.CreateProxyHostRequest
+	item := ProxyHostImportItem{DomainNames: req.DomainNames}
+	fail := func(err error) ProxyHostImportItem {
+		item.Status = ProxyHostImportFailed
+		item.Message = err.Error()
+		return item
+	}
+
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return fail(err)
+	}
+
+	db := database.GetDB()
+	req.CertificateID = nil
+	if exported.Certificate != "" {
+		var certificate models.Certificate
+		if err := db.Where("user_id = ? AND name = ?", userID, exported.Certificate).Order("id ASC").First(&certificate).Error; err != nil {
+			return fail(referenceError("certificate", exported.Certificate, err))
+		}
+		req.CertificateID = &certificate.ID
+	}
+	req.AccessListID = nil
+	if exported.AccessList != "" {
+		var accessList models.AccessList
+		if err := db.Where("user_id = ? AND name = ?", userID, exported.AccessList).Order("id ASC").First(&accessList).Error; err != nil {
+			return fail(referenceError("access list", exported.AccessList, err))
+		}
+		req.AccessListID = &accessList.ID
+	}
+
+	if err := pc.validateProxyHostRequest(&req, 0); err != nil {
+		if errors.Is(err, services.ErrDomainInUse) {
+			item.Status = ProxyHostImportSkipped
+			item.Message = err.Error()
+			return item
+		}
+		return fail(err)
+	}
+
+	proxyHost := newProxyHost(userID, &req)
+	if err := db.Create(&proxyHost).Error; err != nil {
+		logger.Error("Failed to import proxy host", logger.Err(err), logger.Uint("user_id", userID))
+		return fail(err)
+	}
+
+	item.Status = ProxyHostImportCreated
+	item.ProxyHostID = proxyHost.ID
+
+	var deployErr error
+	if proxyHost.Enabled && pc.nginxService != nil {
+		if deployErr = pc.applyProxyHostConfig(&proxyHost); deployErr != nil {
+			logger.Error("Failed to apply nginx configuration", logger.Err(deployErr), logger.Uint("proxy_host_id", proxyHost.ID))
+			item.Message = "imported, but the nginx configuration could not be updated: " + deployErr.Error()
+		}
+	}
+	pc.recordActivity(&proxyHost, "imported", deployErr)
+	return item
+}
+
+// exportProxyHost converts a proxy host, with its certificate and access list
+// loaded, to its exported form
+func exportProxyHost(proxyHost *models.ProxyHost) ExportedProxyHost {
+	exported := ExportedProxyHost{
+		CreateProxyHostRequest: CreateProxyHostRequest{
+			DomainNames:           proxyHost.DomainNames,
+			ForwardScheme:         proxyHost.ForwardScheme,
+			ForwardHost:           proxyHost.ForwardHost,
+			ForwardPort:           proxyHost.ForwardPort,
+			SSLForced:             proxyHost.SSLForced,
+			CachingEnabled:        proxyHost.CachingEnabled,
+			BlockExploits:         proxyHost.BlockExploits,
+			AllowWebsocketUpgrade: proxyHost.AllowWebsocketUpgrade,
+			HTTP2Support:          proxyHost.HTTP2Support,
+			HSTSEnabled:           proxyHost.HSTSEnabled,
+			HSTSSubdomains:        proxyHost.HSTSSubdomains,
+			OCSPStapling:          proxyHost.OCSPStapling,
+			AdvancedConfig:        proxyHost.AdvancedConfig,
+			ProxyBind:             proxyHost.ProxyBind,
+			ProxyBuffering:        proxyHost.ProxyBuffering,
+			ProxyBufferSize:       proxyHost.ProxyBufferSize,
+			ProxyBuffers:          proxyHost.ProxyBuffers,
+			UpstreamKeepalive:     proxyHost.UpstreamKeepalive,
+			RateLimit:             proxyHost.RateLimit,
+			HealthCheck:           proxyHost.HealthCheck,
+			Enabled:               proxyHost.Enabled,
+			Locations:             proxyHost.Locations,
+			Meta:                  proxyHost.Meta,
+		},
+	}
+	if proxyHost.Certificate != nil {
+		exported.Certificate = proxyHost.Certificate.Name
+	}
+	if proxyHost.AccessList != nil {
+		exported.AccessList = proxyHost.AccessList.Name
+	}
+	return exported
+}
+
+// referenceError describes a certificate or access list name that could not
+// be resolved on import
+func referenceError(kind, name string, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s %q not found", kind, name)
+	}
+	return fmt.Errorf("failed to look up %s %q: %w", kind, name, err)
+}
+
+// marshalYAML writes value as YAML using its JSON field names and order, so
+// the YAML and JSON exports share one format
+func marshalYAML(value interface{}) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is valid YAML; reset the flow style it parses with to get block style
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	var clearStyle func(*yaml.Node)
+	clearStyle = func(n *yaml.Node) {
+		n.Style = 0
+		for _, child := range n.Content {
+			clearStyle(child)
+		}
+	}
+	clearStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// unmarshalYAML reads YAML written by marshalYAML into value through its
+// JSON field names
+func unmarshalYAML(data []byte, value interface{}) error {
+	var decoded interface{}
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		return fmt.Errorf("invalid YAML: %s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return json.Unmarshal(data, value)
+}
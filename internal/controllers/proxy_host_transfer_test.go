@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestExportImportRoundTrip exports a proxy host with every flag flipped from
+// its usual value, imports it under another domain and checks that the copy
+// has the same settings
+func TestExportImportRoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	pc := NewProxyHostController(nil, nil, nil, nil)
+
+	buffering := false
+	original := &models.ProxyHost{
+		DomainNames:           models.StringArray{"original.example.com"},
+		ForwardScheme:         "http",
+		ForwardHost:           "127.0.0.1",
+		ForwardPort:           8080,
+		SSLForced:             true,
+		CachingEnabled:        true,
+		BlockExploits:         false,
+		AllowWebsocketUpgrade: true,
+		HTTP2Support:          false,
+		HSTSEnabled:           true,
+		HSTSSubdomains:        true,
+		OCSPStapling:          true,
+		ProxyBuffering:        &buffering,
+		Enabled:               false,
+		UserID:                owner.ID,
+	}
+	want := exportProxyHost(original).CreateProxyHostRequest
+	want.DomainNames = models.StringArray{"copy.example.com"}
+	if err := db.Create(original).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := serveAs(t, owner.ID, http.MethodGet, "/api/v1/proxy-hosts/export", nil, pc.Export)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("export: got status %d: %s", recorder.Code, recorder.Body)
+	}
+	var exported struct {
+		Data ProxyHostExport `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Data.ProxyHosts) != 1 {
+		t.Fatalf("got %d exported proxy hosts, want 1", len(exported.Data.ProxyHosts))
+	}
+	exported.Data.ProxyHosts[0].DomainNames = []string{"copy.example.com"}
+
+	recorder = serveAs(t, owner.ID, http.MethodPost, "/api/v1/proxy-hosts/import", exported.Data, pc.Import)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("import: got status %d: %s", recorder.Code, recorder.Body)
+	}
+	var imported struct {
+		Data ProxyHostImportResult `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &imported); err != nil {
+		t.Fatal(err)
+	}
+	if imported.Data.Created != 1 {
+		t.Fatalf("got import result %+v, want one host created", imported.Data)
+	}
+
+	var stored models.ProxyHost
+	if err := db.First(&stored, imported.Data.Items[0].ProxyHostID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got := exportProxyHost(&stored).CreateProxyHostRequest; !reflect.DeepEqual(got, want) {
+		t.Errorf("imported proxy host differs:\n got %+v\nwant %+v", got, want)
+	}
+}
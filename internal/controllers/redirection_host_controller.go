@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// RedirectionHostController handles redirection host management
+type RedirectionHostController struct {
+	nginxService *services.NginxService
+}
+
+// NewRedirectionHostController creates a new redirection host controller
+func NewRedirectionHostController(nginxService *services.NginxService) *RedirectionHostController {
+	return &RedirectionHostController{
+		nginxService: nginxService,
+	}
+}
+
+// RedirectionHostRequest represents the request payload for creating or updating a redirection host
+type RedirectionHostRequest struct {
+	DomainNames       []string               `json:"domain_names" binding:"required,min=1"`
+	ForwardScheme     string                 `json:"forward_scheme" binding:"required"`
+	ForwardDomainName string                 `json:"forward_domain_name" binding:"required"`
+	StatusCode        int                    `json:"status_code"`   // defaults to 301
+	PreservePath      *bool                  `json:"preserve_path"` // defaults to true
+	CertificateID     *uint                  `json:"certificate_id"`
+	SSLForced         bool                   `json:"ssl_forced"`
+	AdvancedConfig    string                 `json:"advanced_config"`
+	Enabled           bool                   `json:"enabled"`
+	Meta              map[string]interface{} `json:"meta"`
+}
+
+// RedirectionHostResponse represents a redirection host with its generated configuration
+type RedirectionHostResponse struct {
+	models.RedirectionHost
+	PrimaryDomain string `json:"primary_domain"`
+	SSLEnabled    bool   `json:"ssl_enabled"`
+	NginxConfig   string `json:"nginx_config,omitempty"`
+}
+
+// redirectionHostIncludes lists the relations redirection host endpoints accept in ?include=
+var redirectionHostIncludes = relationIncludes{
+	"certificate": "Certificate",
+}
+
+// redirectionHostDefaultIncludes are loaded when ?include= is not given
+var redirectionHostDefaultIncludes = []string{"certificate"}
+
+// List returns paginated list of redirection hosts for the current user.
+// Without ?include= the certificate is embedded in each host; with it the
+// requested relations are returned once each under "included".
+func (rc *RedirectionHostController) List(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	// Parse query parameters
+	page := response.ParsePagination(c)
+	search := c.Query("search")
+	enabled := c.Query("enabled")
+
+	includes, err := parseIncludes(c, redirectionHostIncludes, redirectionHostDefaultIncludes...)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+	_, sideload := c.GetQuery("include")
+
+	db := database.GetDB()
+	query := ownerScope(c, db.Model(&models.RedirectionHost{}), userID, models.PermissionRedirectionHostReadAll)
+	if search != "" {
+		query = query.Where("domain_names LIKE ? OR forward_domain_name LIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+	switch enabled {
+	case "true":
+		query = query.Where("enabled = ?", true)
+	case "false":
+		query = query.Where("enabled = ?", false)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		logger.Error("Failed to count redirection hosts", logger.Err(err), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to count redirection hosts", err)
+		return
+	}
+
+	var redirectionHosts []models.RedirectionHost
+	if err := preloadIncludes(query, redirectionHostIncludes, includes).
+		Offset(page.Offset()).Limit(page.Limit).
+		Order("created_at DESC").
+		Find(&redirectionHosts).Error; err != nil {
+		logger.Error("Failed to fetch redirection hosts", logger.Err(err), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to fetch redirection hosts", err)
+		return
+	}
+
+	redirectionHostResponses := make([]RedirectionHostResponse, 0, len(redirectionHosts))
+	included := newSideloader(includes)
+	for _, host := range redirectionHosts {
+		if sideload && host.Certificate != nil {
+			included.add("certificate", host.Certificate.ID, host.Certificate)
+			host.Certificate = nil
+		}
+		redirectionHostResponses = append(redirectionHostResponses, RedirectionHostResponse{
+			RedirectionHost: host,
+			PrimaryDomain:   host.GetPrimaryDomain(),
+			SSLEnabled:      host.IsSSLEnabled(),
+		})
+	}
+
+	result := response.Paginated(redirectionHostResponses, page.Page, page.Limit, total, "Redirection hosts retrieved successfully")
+	if sideload {
+		result = result.WithIncluded(included.result())
+	}
+	response.PaginatedResponseJSONWithLog(c, result)
+}
+
+// Get returns a single redirection host with a preview of its nginx
+// configuration and the relations named in ?include= (the certificate by
+// default) embedded
+func (rc *RedirectionHostController) Get(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid redirection host ID", err)
+		return
+	}
+
+	includes, err := parseIncludes(c, redirectionHostIncludes, redirectionHostDefaultIncludes...)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	db := database.GetDB()
+	var redirectionHost models.RedirectionHost
+	if err := preloadIncludes(ownerScope(c, db, userID, models.PermissionRedirectionHostReadAll).Where("id = ?", id), redirectionHostIncludes, includes).
+		First(&redirectionHost).Error; err != nil {
+		response.NotFoundJSONWithLog(c, "Redirection host not found")
+		return
+	}
+
+	resp := RedirectionHostResponse{
+		RedirectionHost: redirectionHost,
+		PrimaryDomain:   redirectionHost.GetPrimaryDomain(),
+		SSLEnabled:      redirectionHost.IsSSLEnabled(),
+	}
+	if rc.nginxService != nil {
+		resp.NginxConfig = rc.nginxService.BuildRedirectionHostConfig(&redirectionHost)
+	}
+
+	response.SuccessJSONWithLog(c, resp, "Redirection host retrieved successfully")
+}
+
+// Create creates a new redirection host
+func (rc *RedirectionHostController) Create(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	var req RedirectionHostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
+		return
+	}
+
+	if err := rc.validateRequest(&req, 0); err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+	if !checkHostReferences(c, userID, req.CertificateID, nil) {
+		return
+	}
+
+	redirectionHost := models.RedirectionHost{UserID: userID}
+	applyRedirectionHostRequest(&redirectionHost, &req)
+
+	db := database.GetDB()
+	if err := db.Create(&redirectionHost).Error; err != nil {
+		logger.Error("Failed to create redirection host", logger.Err(err), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to create redirection host", err)
+		return
+	}
+
+	rc.syncRedirectionHostConfig(&redirectionHost)
+
+	logger.Info("Redirection host created successfully", logger.Uint("id", redirectionHost.ID), logger.Uint("user_id", userID), logger.Any("domains", req.DomainNames))
+	response.SuccessJSONWithLog(c, redirectionHost, "Redirection host created successfully")
+}
+
+// Update updates an existing redirection host
+func (rc *RedirectionHostController) Update(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid redirection host ID", err)
+		return
+	}
+
+	var req RedirectionHostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
+		return
+	}
+
+	db := database.GetDB()
+	var redirectionHost models.RedirectionHost
+	if err := ownerScope(c, db, userID, models.PermissionRedirectionHostManage).Where("id = ?", id).First(&redirectionHost).Error; err != nil {
+		response.NotFoundJSONWithLog(c, "Redirection host not found")
+		return
+	}
+
+	if err := rc.validateRequest(&req, uint(id)); err != nil {
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+		return
+	}
+
+	// A certificate the host already has is not checked again
+	certificateID := req.CertificateID
+	if sameID(certificateID, redirectionHost.CertificateID) {
+		certificateID = nil
+	}
+	if !checkHostReferences(c, userID, certificateID, nil) {
+		return
+	}
+
+	applyRedirectionHostRequest(&redirectionHost, &req)
+	if err := db.Save(&redirectionHost).Error; err != nil {
+		logger.Error("Failed to update redirection host", logger.Err(err), logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to update redirection host", err)
+		return
+	}
+
+	rc.syncRedirectionHostConfig(&redirectionHost)
+
+	logger.Info("Redirection host updated successfully", logger.Uint("id", redirectionHost.ID), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, redirectionHost, "Redirection host updated successfully")
+}
+
+// Delete deletes a redirection host
+func (rc *RedirectionHostController) Delete(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid redirection host ID", err)
+		return
+	}
+
+	db := database.GetDB()
+	var redirectionHost models.RedirectionHost
+	if err := ownerScope(c, db, userID, models.PermissionRedirectionHostManage).Where("id = ?", id).First(&redirectionHost).Error; err != nil {
+		response.NotFoundJSONWithLog(c, "Redirection host not found")
+		return
+	}
+
+	// Remove nginx configuration
+	if rc.nginxService != nil {
+		if err := rc.nginxService.UndeployRedirectionHost(&redirectionHost); err != nil {
+			logger.Error("Failed to remove nginx configuration", logger.Err(err), logger.Uint("redirection_host_id", redirectionHost.ID))
+			// Continue anyway
+		}
+	}
+
+	if err := db.Delete(&redirectionHost).Error; err != nil {
+		logger.Error("Failed to delete redirection host", logger.Err(err), logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to delete redirection host", err)
+		return
+	}
+
+	logger.Info("Redirection host deleted successfully", logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Redirection host deleted successfully")
+}
+
+// Toggle toggles the enabled status of a redirection host
+func (rc *RedirectionHostController) Toggle(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid redirection host ID", err)
+		return
+	}
+
+	db := database.GetDB()
+	var redirectionHost models.RedirectionHost
+	if err := ownerScope(c, db, userID, models.PermissionRedirectionHostManage).Where("id = ?", id).First(&redirectionHost).Error; err != nil {
+		response.NotFoundJSONWithLog(c, "Redirection host not found")
+		return
+	}
+
+	redirectionHost.Enabled = !redirectionHost.Enabled
+	if err := db.Model(&redirectionHost).Update("enabled", redirectionHost.Enabled).Error; err != nil {
+		logger.Error("Failed to toggle redirection host", logger.Err(err), logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to toggle redirection host status", err)
+		return
+	}
+
+	rc.syncRedirectionHostConfig(&redirectionHost)
+
+	action := "disabled"
+	if redirectionHost.Enabled {
+		action = "enabled"
+	}
+
+	logger.Info("Redirection host toggled successfully", logger.Uint("id", uint(id)), logger.Uint("user_id", userID), logger.Bool("enabled", redirectionHost.Enabled))
+	response.SuccessJSONWithLog(c, gin.H{
+		"id":      redirectionHost.ID,
+		"enabled": redirectionHost.Enabled,
+	}, "Redirection host "+action+" successfully")
+}
+
+// validateRequest checks domains, redirect code, scheme and target, and fills
+// in the defaults for omitted fields
+func (rc *RedirectionHostController) validateRequest(req *RedirectionHostRequest, excludeID uint) error {
+	if req.StatusCode == 0 {
+		req.StatusCode = 301
+	}
+	if req.PreservePath == nil {
+		preservePath := true
+		req.PreservePath = &preservePath
+	}
+
+	if err := validateDomainNames(req.DomainNames); err != nil {
+		return err
+	}
+	if err := checkDuplicateDomains(req.DomainNames, &models.RedirectionHost{}, excludeID); err != nil {
+		return err
+	}
+	if err := services.ValidateRedirectCode(req.StatusCode); err != nil {
+		return err
+	}
+	if err := services.ValidateRedirectScheme(req.ForwardScheme); err != nil {
+		return err
+	}
+	return services.ValidateRedirectTarget(req.ForwardDomainName)
+}
+
+// applyRedirectionHostRequest copies a validated request onto the model
+func applyRedirectionHostRequest(redirectionHost *models.RedirectionHost, req *RedirectionHostRequest) {
+	redirectionHost.DomainNames = models.StringArray(req.DomainNames)
+	redirectionHost.ForwardScheme = req.ForwardScheme
+	redirectionHost.ForwardDomainName = req.ForwardDomainName
+	redirectionHost.StatusCode = req.StatusCode
+	redirectionHost.PreservePath = *req.PreservePath
+	redirectionHost.CertificateID = req.CertificateID
+	redirectionHost.SSLForced = req.SSLForced
+	redirectionHost.AdvancedConfig = req.AdvancedConfig
+	redirectionHost.Enabled = req.Enabled
+	if req.Meta != nil {
+		redirectionHost.Meta = models.JSON(req.Meta)
+	}
+}
+
+// syncRedirectionHostConfig deploys an enabled redirection host or removes a
+// disabled one. Failures are logged and do not fail the request.
+func (rc *RedirectionHostController) syncRedirectionHostConfig(redirectionHost *models.RedirectionHost) {
+	if rc.nginxService == nil {
+		return
+	}
+
+	if redirectionHost.Enabled {
+		if err := rc.nginxService.DeployRedirectionHost(redirectionHost); err != nil {
+			logger.Error("Failed to apply nginx configuration", logger.Err(err), logger.Uint("redirection_host_id", redirectionHost.ID))
+		}
+		return
+	}
+
+	if err := rc.nginxService.UndeployRedirectionHost(redirectionHost); err != nil {
+		logger.Error("Failed to remove nginx configuration", logger.Err(err), logger.Uint("redirection_host_id", redirectionHost.ID))
+	}
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestRedirectionHostChecksCertificate creates and updates redirection hosts
+// referring to missing and foreign certificates
+func TestRedirectionHostChecksCertificate(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	other := createTestUser(t, db, "other@example.test", models.RoleUser)
+	controller := NewRedirectionHostController(nil)
+
+	shared := &models.Certificate{Name: "shared", Provider: models.ProviderCustom, UserID: other.ID}
+	foreign := &models.Certificate{Name: "foreign", Provider: models.ProviderCustom, UserID: other.ID}
+	for _, certificate := range []*models.Certificate{shared, foreign} {
+		if err := db.Create(certificate).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := foreign.ID + 100
+
+	request := func(domain string, certificateID *uint) RedirectionHostRequest {
+		return RedirectionHostRequest{
+			DomainNames:       []string{domain},
+			ForwardScheme:     "https",
+			ForwardDomainName: "new.example.com",
+			CertificateID:     certificateID,
+		}
+	}
+
+	for _, tc := range []struct {
+		name          string
+		certificateID *uint
+		want          int
+	}{
+		{"foreign certificate", &foreign.ID, http.StatusForbidden},
+		{"missing certificate", &missing, http.StatusBadRequest},
+		{"no certificate", nil, http.StatusOK},
+	} {
+		t.Run("create with "+tc.name, func(t *testing.T) {
+			recorder := serveAs(t, owner.ID, http.MethodPost, "/redirection-hosts", request("create.example.com", tc.certificateID), controller.Create)
+			if recorder.Code != tc.want {
+				t.Errorf("got status %d, want %d: %s", recorder.Code, tc.want, recorder.Body)
+			}
+		})
+	}
+
+	// An admin attached another user's certificate to the owner's host
+	redirectionHost := &models.RedirectionHost{
+		DomainNames:       models.StringArray{"update.example.com"},
+		ForwardScheme:     "https",
+		ForwardDomainName: "new.example.com",
+		StatusCode:        301,
+		CertificateID:     &shared.ID,
+		UserID:            owner.ID,
+	}
+	if err := db.Create(redirectionHost).Error; err != nil {
+		t.Fatal(err)
+	}
+	id := fmt.Sprint(redirectionHost.ID)
+
+	for _, tc := range []struct {
+		name          string
+		certificateID *uint
+		want          int
+	}{
+		{"the certificate it has", &shared.ID, http.StatusOK},
+		{"another foreign certificate", &foreign.ID, http.StatusForbidden},
+	} {
+		t.Run("update to "+tc.name, func(t *testing.T) {
+			recorder := serveAs(t, owner.ID, http.MethodPut, "/redirection-hosts/"+id, request("update.example.com", tc.certificateID),
+				controller.Update, gin.Param{Key: "id", Value: id})
+			if recorder.Code != tc.want {
+				t.Errorf("got status %d, want %d: %s", recorder.Code, tc.want, recorder.Body)
+			}
+		})
+	}
+}
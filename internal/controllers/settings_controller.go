@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// SettingsController handles system settings endpoints
+type SettingsController struct {
+	settingsService *services.SettingsService
+}
+
+// NewSettingsController creates a new settings controller
+func NewSettingsController(settingsService *services.SettingsService) *SettingsController {
+	return &SettingsController{
+		settingsService: settingsService,
+	}
+}
+
+// UpdateSettingRequest sets a single setting's value
+type UpdateSettingRequest struct {
+	Value interface{} `json:"value"`
+}
+
+// GetSettings handles GET /api/v1/settings
+func (sc *SettingsController) GetSettings(c *gin.Context) {
+	if sc.settingsService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Settings service not available", nil)
+		return
+	}
+
+	settings, err := sc.settingsService.GetAll()
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to get settings", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, settings, "Settings retrieved successfully")
+}
+
+// GetSetting handles GET /api/v1/settings/:key
+func (sc *SettingsController) GetSetting(c *gin.Context) {
+	if sc.settingsService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Settings service not available", nil)
+		return
+	}
+
+	setting, err := sc.settingsService.Get(c.Param("key"))
+	if err != nil {
+		sc.handleSettingsError(c, err, "Failed to get setting")
+		return
+	}
+
+	response.SuccessJSONWithLog(c, setting, "Setting retrieved successfully")
+}
+
+// UpdateSettings handles PUT /api/v1/settings with a map of setting IDs to
+// values, applied together
+func (sc *SettingsController) UpdateSettings(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	var values map[string]interface{}
+	if err := c.ShouldBindJSON(&values); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid settings request", err)
+		return
+	}
+	if len(values) == 0 {
+		response.BadRequestJSONWithLog(c, "No settings to update", nil)
+		return
+	}
+
+	if sc.settingsService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Settings service not available", nil)
+		return
+	}
+
+	settings, err := sc.settingsService.SetMany(actorID, values, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		sc.handleSettingsError(c, err, "Failed to update settings")
+		return
+	}
+
+	response.SuccessJSONWithLog(c, settings, "Settings updated successfully")
+}
+
+// UpdateSetting handles PUT /api/v1/settings/:key
+func (sc *SettingsController) UpdateSetting(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	var req UpdateSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid setting request", err)
+		return
+	}
+
+	if sc.settingsService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Settings service not available", nil)
+		return
+	}
+
+	setting, err := sc.settingsService.Set(actorID, c.Param("key"), req.Value, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		sc.handleSettingsError(c, err, "Failed to update setting")
+		return
+	}
+
+	response.SuccessJSONWithLog(c, setting, "Setting updated successfully")
+}
+
+// handleSettingsError maps settings errors to responses
+func (sc *SettingsController) handleSettingsError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrSettingNotFound):
+		response.NotFoundJSONWithLog(c, err.Error())
+	case errors.Is(err, services.ErrInvalidSettingValue):
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+	case errors.Is(err, services.ErrSettingManaged):
+		response.ErrorJSONWithLog(c, http.StatusConflict, err.Error(), err)
+	default:
+		response.InternalServerErrorJSONWithLog(c, message, err)
+	}
+}
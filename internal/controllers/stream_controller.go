@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// StreamController handles TCP/UDP stream proxy endpoints
+type StreamController struct {
+	streamService *services.StreamService
+}
+
+// NewStreamController creates a new stream controller
+func NewStreamController(streamService *services.StreamService) *StreamController {
+	return &StreamController{
+		streamService: streamService,
+	}
+}
+
+// List returns paginated list of streams for the current user
+func (sc *StreamController) List(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	if sc.streamService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Stream service not available", nil)
+		return
+	}
+
+	page := response.ParsePagination(c)
+
+	streams, total, err := sc.streamService.ListStreams(userID, page.Offset(), page.Limit)
+	if err != nil {
+		logger.Error("Failed to fetch streams", logger.Err(err), logger.Uint("user_id", userID))
+		response.InternalServerErrorJSONWithLog(c, "Failed to fetch streams", err)
+		return
+	}
+
+	response.PaginatedJSONWithLog(c, streams, page.Page, page.Limit, total, "Streams retrieved successfully")
+}
+
+// Get returns a single stream with a preview of its nginx configuration
+func (sc *StreamController) Get(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid stream ID", err)
+		return
+	}
+
+	if sc.streamService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Stream service not available", nil)
+		return
+	}
+
+	stream, err := sc.streamService.GetStream(userID, uint(id))
+	if err != nil {
+		sc.handleError(c, err, "Failed to fetch stream")
+		return
+	}
+
+	response.SuccessJSONWithLog(c, gin.H{
+		"stream":       stream,
+		"protocol":     stream.GetProtocol(),
+		"nginx_config": sc.streamService.BuildStreamConfig(stream),
+	}, "Stream retrieved successfully")
+}
+
+// Create creates a new stream
+func (sc *StreamController) Create(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	var req services.StreamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
+		return
+	}
+
+	if sc.streamService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Stream service not available", nil)
+		return
+	}
+
+	stream, err := sc.streamService.CreateStream(userID, &req)
+	if err != nil {
+		if stream != nil {
+			// Saved, but the configuration could not be applied
+			logger.Error("Failed to apply stream configuration", logger.Err(err), logger.Uint("stream_id", stream.ID))
+		} else {
+			sc.handleError(c, err, "Failed to create stream")
+			return
+		}
+	}
+
+	logger.Info("Stream created successfully", logger.Uint("id", stream.ID), logger.Uint("user_id", userID), logger.Int("incoming_port", stream.IncomingPort))
+	response.SuccessJSONWithLog(c, stream, "Stream created successfully")
+}
+
+// Update updates an existing stream
+func (sc *StreamController) Update(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid stream ID", err)
+		return
+	}
+
+	var req services.StreamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid request payload", err)
+		return
+	}
+
+	if sc.streamService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Stream service not available", nil)
+		return
+	}
+
+	stream, err := sc.streamService.UpdateStream(userID, uint(id), &req)
+	if err != nil {
+		if stream != nil {
+			// Saved, but the configuration could not be applied
+			logger.Error("Failed to apply stream configuration", logger.Err(err), logger.Uint("stream_id", stream.ID))
+		} else {
+			sc.handleError(c, err, "Failed to update stream")
+			return
+		}
+	}
+
+	logger.Info("Stream updated successfully", logger.Uint("id", stream.ID), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, stream, "Stream updated successfully")
+}
+
+// Delete deletes a stream
+func (sc *StreamController) Delete(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid stream ID", err)
+		return
+	}
+
+	if sc.streamService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Stream service not available", nil)
+		return
+	}
+
+	if err := sc.streamService.DeleteStream(userID, uint(id)); err != nil {
+		sc.handleError(c, err, "Failed to delete stream")
+		return
+	}
+
+	logger.Info("Stream deleted successfully", logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Stream deleted successfully")
+}
+
+// handleError maps stream service errors to responses
+func (sc *StreamController) handleError(c *gin.Context, err error, message string) {
+	var configErr *services.StreamConfigError
+	switch {
+	case err == services.ErrStreamNotFound:
+		response.NotFoundJSONWithLog(c, "Stream not found")
+	case err == services.ErrStreamPortInUse, errors.Is(err, services.ErrStreamIncludeMissing):
+		response.ErrorJSONWithLog(c, http.StatusConflict, err.Error(), err)
+	case err == services.ErrInvalidStreamPort,
+		err == services.ErrInvalidStreamProtocol,
+		err == services.ErrInvalidStreamHost,
+		errors.As(err, &configErr):
+		response.BadRequestJSONWithLog(c, err.Error(), err)
+	default:
+		response.InternalServerErrorJSONWithLog(c, message, err)
+	}
+}
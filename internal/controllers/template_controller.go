@@ -46,7 +46,7 @@ func (c *TemplateController) CreateTemplate(ctx *gin.Context) {
 		return
 	}
 
-	template, err := c.templateService.CreateTemplate(userID.(uint), &req)
+	template, err := c.templateService.CreateTemplate(userID.(uint), &req, services.NewAuditContext(ctx))
 	if err != nil {
 		if err == errors.ErrTemplateDuplicate {
 			response.ErrorJSONWithLog(ctx, http.StatusConflict, "Template with this name already exists", err)
@@ -110,6 +110,9 @@ func (c *TemplateController) GetTemplate(ctx *gin.Context) {
 // @Param limit query int false "Page size" default(10)
 // @Param category query string false "Template category filter"
 // @Param include_public query bool false "Include public templates" default(true)
+// @Param search query string false "Match name or description"
+// @Param sort query string false "Sort field: name, created_at, updated_at or usage_count"
+// @Param order query string false "Sort order: asc or desc"
 // @Success 200 {object} services.TemplateListResponse
 // @Failure 400 {object} response.ErrorResponse
 // @Failure 401 {object} response.ErrorResponse
@@ -122,26 +125,23 @@ func (c *TemplateController) ListTemplates(ctx *gin.Context) {
 	}
 
 	// Parse query parameters
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	page := response.ParsePagination(ctx)
 	category := ctx.Query("category")
 	includePublic, _ := strconv.ParseBool(ctx.DefaultQuery("include_public", "true"))
 
-	// Validate pagination parameters
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	sort, err := services.NewListSort(ctx.Query("sort"), ctx.Query("order"), services.TemplateSortFields)
+	if err != nil {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid sort", err)
+		return
 	}
 
-	templates, err := c.templateService.ListTemplates(userID.(uint), page, limit, category, includePublic)
+	templates, err := c.templateService.ListTemplates(userID.(uint), page.Page, page.Limit, category, includePublic, ctx.Query("search"), sort)
 	if err != nil {
 		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Failed to list templates", err)
 		return
 	}
 
-	response.SuccessJSONWithLog(ctx, templates, "Templates retrieved successfully")
+	response.PaginatedJSONWithLog(ctx, templates.Templates, templates.Page, templates.Limit, templates.Total, "Templates retrieved successfully")
 }
 
 // UpdateTemplate updates an existing configuration template
@@ -177,7 +177,7 @@ func (c *TemplateController) UpdateTemplate(ctx *gin.Context) {
 		return
 	}
 
-	template, err := c.templateService.UpdateTemplate(userID.(uint), uint(id), &req)
+	template, err := c.templateService.UpdateTemplate(userID.(uint), uint(id), &req, services.NewAuditContext(ctx))
 	if err != nil {
 		if err == errors.ErrTemplateNotFound {
 			response.ErrorJSONWithLog(ctx, http.StatusNotFound, "Template not found", err)
@@ -219,7 +219,7 @@ func (c *TemplateController) DeleteTemplate(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.templateService.DeleteTemplate(userID.(uint), uint(id)); err != nil {
+	if err := c.templateService.DeleteTemplate(userID.(uint), uint(id), services.NewAuditContext(ctx)); err != nil {
 		if err == errors.ErrTemplateNotFound {
 			response.ErrorJSONWithLog(ctx, http.StatusNotFound, "Template not found", err)
 			return
@@ -239,6 +239,49 @@ func (c *TemplateController) DeleteTemplate(ctx *gin.Context) {
 	response.SuccessJSONWithLog(ctx, gin.H{"id": id}, "Template deleted successfully")
 }
 
+// CloneTemplate copies a configuration template into a private template of the caller
+// @Summary Clone configuration template
+// @Description Copy a readable template, including built-in and public ones, into a private template owned by the caller
+// @Tags nginx-templates
+// @Produce json
+// @Param id path int true "Template ID"
+// @Success 200 {object} models.ConfigTemplate
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/nginx/templates/{id}/clone [post]
+func (c *TemplateController) CloneTemplate(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.ErrorJSONWithLog(ctx, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid template ID", err)
+		return
+	}
+
+	template, err := c.templateService.CloneTemplate(userID.(uint), uint(id), services.NewAuditContext(ctx))
+	if err != nil {
+		if err == errors.ErrTemplateNotFound {
+			response.ErrorJSONWithLog(ctx, http.StatusNotFound, "Template not found", err)
+			return
+		}
+		if err == errors.ErrPermissionDenied {
+			response.ErrorJSONWithLog(ctx, http.StatusForbidden, "Permission denied", err)
+			return
+		}
+		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Failed to clone template", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(ctx, template, "Template cloned successfully")
+}
+
 // RenderTemplate renders a template with given variables
 // @Summary Render configuration template
 // @Description Render a template with provided variables
@@ -289,12 +332,173 @@ func (c *TemplateController) RenderTemplate(ctx *gin.Context) {
 	response.SuccessJSONWithLog(ctx, result, "Template rendered successfully")
 }
 
-// GetCategories returns all available template categories
+// PreviewTemplate renders a template and validates the resulting configuration
+// @Summary Preview configuration template
+// @Description Render a template with sample variables and validate the result with nginx
+// @Tags nginx-templates
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param preview body services.TemplatePreviewRequest true "Template variables"
+// @Success 200 {object} services.TemplatePreviewResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/nginx/templates/{id}/preview [post]
+func (c *TemplateController) PreviewTemplate(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.ErrorJSONWithLog(ctx, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid template ID", err)
+		return
+	}
+
+	var req services.TemplatePreviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid request data", err)
+		return
+	}
+	if req.Type != "" && !req.Type.IsValid() {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid configuration type", nil)
+		return
+	}
+
+	result, err := c.templateService.PreviewTemplate(userID.(uint), uint(id), &req)
+	if err != nil {
+		if err == errors.ErrTemplateNotFound {
+			response.ErrorJSONWithLog(ctx, http.StatusNotFound, "Template not found", err)
+			return
+		}
+		if err == errors.ErrPermissionDenied {
+			response.ErrorJSONWithLog(ctx, http.StatusForbidden, "Permission denied", err)
+			return
+		}
+		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Failed to preview template", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(ctx, result, "Template previewed successfully")
+}
+
+// GetTemplateVersions lists the versions of a template, newest first
+// @Summary Get template versions
+// @Description Get the version history of a configuration template
+// @Tags nginx-templates
+// @Produce json
+// @Param id path int true "Template ID"
+// @Success 200 {object} []models.TemplateVersion
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/nginx/templates/{id}/versions [get]
+func (c *TemplateController) GetTemplateVersions(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.ErrorJSONWithLog(ctx, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid template ID", err)
+		return
+	}
+
+	versions, err := c.templateService.ListTemplateVersions(userID.(uint), uint(id))
+	if err != nil {
+		if err == errors.ErrTemplateNotFound {
+			response.ErrorJSONWithLog(ctx, http.StatusNotFound, "Template not found", err)
+			return
+		}
+		if err == errors.ErrPermissionDenied {
+			response.ErrorJSONWithLog(ctx, http.StatusForbidden, "Permission denied", err)
+			return
+		}
+		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Failed to get template versions", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(ctx, versions, "Template versions retrieved successfully")
+}
+
+// RollbackTemplate restores a template to a previous version
+// @Summary Roll back configuration template
+// @Description Restore the content and variables of a previous template version
+// @Tags nginx-templates
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param version path int true "Version number"
+// @Param rollback body object false "Optional change comment"
+// @Success 200 {object} models.ConfigTemplate
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /api/v1/nginx/templates/{id}/rollback/{version} [post]
+func (c *TemplateController) RollbackTemplate(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.ErrorJSONWithLog(ctx, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid template ID", err)
+		return
+	}
+
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil || version < 1 {
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid version number", err)
+		return
+	}
+
+	// The comment is optional, so an empty body is accepted
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Invalid request data", err)
+			return
+		}
+	}
+
+	template, err := c.templateService.RollbackTemplate(userID.(uint), uint(id), version, req.Comment, services.NewAuditContext(ctx))
+	if err != nil {
+		if err == errors.ErrTemplateNotFound || err == errors.ErrTemplateVersionNotFound {
+			response.ErrorJSONWithLog(ctx, http.StatusNotFound, err.Error(), err)
+			return
+		}
+		if err == errors.ErrPermissionDenied {
+			response.ErrorJSONWithLog(ctx, http.StatusForbidden, "Permission denied", err)
+			return
+		}
+		response.ErrorJSONWithLog(ctx, http.StatusBadRequest, "Failed to roll back template", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(ctx, template, "Template rolled back successfully")
+}
+
+// GetCategories returns all available template categories and the helper
+// functions templates can call
 // @Summary Get template categories
-// @Description Get list of all available template categories
+// @Description Get list of all available template categories and template functions
 // @Tags nginx-templates
 // @Produce json
-// @Success 200 {object} []string
+// @Success 200 {object} services.TemplateCategoriesResponse
 // @Failure 401 {object} response.ErrorResponse
 // @Router /api/v1/nginx/templates/categories [get]
 func (c *TemplateController) GetCategories(ctx *gin.Context) {
@@ -304,7 +508,10 @@ func (c *TemplateController) GetCategories(ctx *gin.Context) {
 		return
 	}
 
-	categories := c.templateService.GetCategories()
+	categories := services.TemplateCategoriesResponse{
+		Categories: c.templateService.GetCategories(),
+		Functions:  services.GetTemplateFunctions(),
+	}
 	response.SuccessJSONWithLog(ctx, categories, "Categories retrieved successfully")
 }
 
@@ -313,7 +520,7 @@ func (c *TemplateController) GetCategories(ctx *gin.Context) {
 // @Description Initialize default built-in configuration templates (admin only)
 // @Tags nginx-templates
 // @Produce json
-// @Success 200 {object} response.SuccessResponse
+// @Success 200 {object} services.BuiltInTemplatesResult
 // @Failure 401 {object} response.ErrorResponse
 // @Failure 403 {object} response.ErrorResponse
 // @Router /api/v1/nginx/templates/init-builtin [post]
@@ -324,14 +531,15 @@ func (c *TemplateController) InitializeBuiltInTemplates(ctx *gin.Context) {
 		return
 	}
 
-	// TODO: Add admin check
-	// For now, allow any authenticated user
-	_ = userID
-
-	if err := c.templateService.CreateBuiltInTemplates(); err != nil {
+	result, err := c.templateService.InitializeBuiltInTemplates(userID.(uint))
+	if err != nil {
+		if err == errors.ErrPermissionDenied {
+			response.ErrorJSONWithLog(ctx, http.StatusForbidden, "Only administrators can initialize built-in templates", err)
+			return
+		}
 		response.ErrorJSONWithLog(ctx, http.StatusInternalServerError, "Failed to initialize built-in templates", err)
 		return
 	}
 
-	response.SuccessJSONWithLog(ctx, gin.H{"message": "Built-in templates initialized"}, "Built-in templates created successfully")
+	response.SuccessJSONWithLog(ctx, result, "Built-in templates initialized")
 }
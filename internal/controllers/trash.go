@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// Trash handles GET /api/v1/proxy-hosts/trash
+func (pc *ProxyHostController) Trash(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+	if pc.nginxService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Nginx service not available", nil)
+		return
+	}
+
+	page := response.ParsePagination(c)
+	proxyHosts, total, err := pc.nginxService.ListDeletedProxyHosts(userID, page.Offset(), page.Limit)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to retrieve deleted proxy hosts", err)
+		return
+	}
+
+	response.PaginatedJSONWithLog(c, proxyHosts, page.Page, page.Limit, total, "Deleted proxy hosts retrieved successfully")
+}
+
+// Restore handles POST /api/v1/proxy-hosts/:id/restore. An enabled host is
+// deployed again.
+func (pc *ProxyHostController) Restore(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid proxy host ID", err)
+		return
+	}
+	if pc.nginxService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Nginx service not available", nil)
+		return
+	}
+
+	proxyHost, err := pc.nginxService.RestoreProxyHost(userID, uint(id), services.NewAuditContext(c))
+	if err != nil {
+		switch {
+		case err == services.ErrProxyHostNotFound:
+			response.NotFoundJSONWithLog(c, "Deleted proxy host not found")
+		case errors.Is(err, services.ErrDomainInUse):
+			response.ErrorJSONWithLog(c, http.StatusConflict, "Cannot restore proxy host: "+err.Error(), err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to restore proxy host", err)
+		}
+		return
+	}
+
+	var deployErr error
+	if proxyHost.Enabled {
+		deployErr = pc.syncProxyHostConfig(proxyHost)
+	}
+	pc.recordActivity(proxyHost, "restored", deployErr)
+	proxyHost.CertificateWarnings = pc.certificateWarnings(userID, proxyHost)
+
+	logger.Info("Proxy host restored successfully", logger.Uint("id", proxyHost.ID), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, proxyHost, "Proxy host restored successfully")
+}
+
+// Purge handles DELETE /api/v1/proxy-hosts/:id/purge, permanently deleting a
+// proxy host from the trash. Admin only.
+func (pc *ProxyHostController) Purge(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid proxy host ID", err)
+		return
+	}
+	if pc.nginxService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Nginx service not available", nil)
+		return
+	}
+
+	if err := pc.nginxService.PurgeProxyHost(userID, uint(id), services.NewAuditContext(c)); err != nil {
+		switch err {
+		case services.ErrProxyHostNotFound:
+			response.NotFoundJSONWithLog(c, "Deleted proxy host not found")
+		case services.ErrUnauthorized:
+			response.ForbiddenJSONWithLog(c, "Admin access required")
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to purge proxy host", err)
+		}
+		return
+	}
+
+	logger.Info("Proxy host purged", logger.Uint("id", uint(id)), logger.Uint("user_id", userID))
+	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Proxy host permanently deleted")
+}
+
+// Trash handles GET /api/v1/certificates/trash
+func (ctrl *CertificateController) Trash(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	page := response.ParsePagination(c)
+	certificates, total, err := ctrl.certificateService.ListDeletedCertificates(userID, page.Offset(), page.Limit)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to retrieve deleted certificates", err)
+		return
+	}
+
+	response.PaginatedJSONWithLog(c, certificates, page.Page, page.Limit, total, "Deleted certificates retrieved successfully")
+}
+
+// RestoreCertificate handles POST /api/v1/certificates/:id/restore
+func (ctrl *CertificateController) RestoreCertificate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid certificate ID", err)
+		return
+	}
+
+	certificate, err := ctrl.certificateService.RestoreCertificate(userID, uint(id), services.NewAuditContext(c))
+	if err != nil {
+		if err == services.ErrCertificateNotFound {
+			response.NotFoundJSONWithLog(c, "Deleted certificate not found")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to restore certificate", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, certificate, "Certificate restored successfully")
+}
+
+// PurgeCertificate handles DELETE /api/v1/certificates/:id/purge, permanently
+// deleting a certificate from the trash. Admin only.
+func (ctrl *CertificateController) PurgeCertificate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid certificate ID", err)
+		return
+	}
+
+	if err := ctrl.certificateService.PurgeCertificate(userID, uint(id), services.NewAuditContext(c)); err != nil {
+		switch err {
+		case services.ErrCertificateNotFound:
+			response.NotFoundJSONWithLog(c, "Deleted certificate not found")
+		case services.ErrUnauthorized:
+			response.ForbiddenJSONWithLog(c, "Admin access required")
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to purge certificate", err)
+		}
+		return
+	}
+
+	response.SuccessJSONWithLog(c, gin.H{"id": id}, "Certificate permanently deleted")
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// UpstreamHealthController handles proxy host upstream health checks
+type UpstreamHealthController struct {
+	healthService *services.UpstreamHealthService
+}
+
+// NewUpstreamHealthController creates a new upstream health controller
+func NewUpstreamHealthController(healthService *services.UpstreamHealthService) *UpstreamHealthController {
+	return &UpstreamHealthController{
+		healthService: healthService,
+	}
+}
+
+// GetFleetHealth handles GET /api/v1/proxy-hosts/health
+func (hc *UpstreamHealthController) GetFleetHealth(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	if hc.healthService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Upstream health service not available", nil)
+		return
+	}
+
+	summary, err := hc.healthService.GetFleetHealth(c.Request.Context(), userID)
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to check upstream health", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, summary, "Upstream health retrieved successfully")
+}
+
+// GetHostHealth handles GET /api/v1/proxy-hosts/:id/health
+func (hc *UpstreamHealthController) GetHostHealth(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid proxy host ID", err)
+		return
+	}
+
+	if hc.healthService == nil {
+		response.InternalServerErrorJSONWithLog(c, "Upstream health service not available", nil)
+		return
+	}
+
+	result, err := hc.healthService.CheckUpstream(c.Request.Context(), userID, uint(id))
+	if err != nil {
+		if err == services.ErrProxyHostNotFound {
+			response.NotFoundJSONWithLog(c, "Proxy host not found")
+			return
+		}
+		response.InternalServerErrorJSONWithLog(c, "Failed to check upstream health", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "Upstream health retrieved successfully")
+}
@@ -0,0 +1,342 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+	apperrors "github.com/nguyendkn/nginx-manager/pkg/errors"
+	"github.com/nguyendkn/nginx-manager/pkg/response"
+)
+
+// UserController handles admin user management endpoints
+type UserController struct {
+	userService *services.UserService
+}
+
+// NewUserController creates a new user controller
+func NewUserController(userService *services.UserService) *UserController {
+	return &UserController{
+		userService: userService,
+	}
+}
+
+// ListUsers handles GET /api/v1/users and GET /api/v1/admin/users
+func (uc *UserController) ListUsers(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	page := response.ParsePagination(c)
+
+	if uc.userService == nil {
+		response.InternalServerErrorJSONWithLog(c, "User service not available", nil)
+		return
+	}
+
+	users, err := uc.userService.ListUsers(actorID, page.Page, page.Limit, c.Query("search"))
+	if err != nil {
+		uc.handleUserError(c, err, "Failed to list users")
+		return
+	}
+
+	response.PaginatedJSONWithLog(c, users.Users, users.Page, users.Limit, users.Total, "Users retrieved successfully")
+}
+
+// GetUser handles GET /api/v1/users/:id
+func (uc *UserController) GetUser(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid user ID", err)
+		return
+	}
+
+	if uc.userService == nil {
+		response.InternalServerErrorJSONWithLog(c, "User service not available", nil)
+		return
+	}
+
+	user, err := uc.userService.GetUser(actorID, uint(userID))
+	if err != nil {
+		uc.handleUserError(c, err, "Failed to get user")
+		return
+	}
+
+	response.SuccessJSONWithLog(c, user, "User retrieved successfully")
+}
+
+// CreateUser handles POST /api/v1/admin/users
+func (uc *UserController) CreateUser(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	var req services.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid user request", err)
+		return
+	}
+
+	if uc.userService == nil {
+		response.InternalServerErrorJSONWithLog(c, "User service not available", nil)
+		return
+	}
+
+	user, err := uc.userService.CreateUser(actorID, &req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		uc.handleUserError(c, err, "Failed to create user")
+		return
+	}
+
+	response.SuccessJSONWithLog(c, user, "User created successfully")
+}
+
+// UpdateUser handles PUT /api/v1/users/:id
+func (uc *UserController) UpdateUser(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid user ID", err)
+		return
+	}
+
+	var req services.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid user request", err)
+		return
+	}
+
+	if uc.userService == nil {
+		response.InternalServerErrorJSONWithLog(c, "User service not available", nil)
+		return
+	}
+
+	user, err := uc.userService.UpdateUser(actorID, uint(userID), &req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		uc.handleUserError(c, err, "Failed to update user")
+		return
+	}
+
+	response.SuccessJSONWithLog(c, user, "User updated successfully")
+}
+
+// DeleteUser handles DELETE /api/v1/users/:id
+func (uc *UserController) DeleteUser(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid user ID", err)
+		return
+	}
+
+	if uc.userService == nil {
+		response.InternalServerErrorJSONWithLog(c, "User service not available", nil)
+		return
+	}
+
+	if err := uc.userService.DeleteUser(actorID, uint(userID), c.ClientIP(), c.Request.UserAgent()); err != nil {
+		uc.handleUserError(c, err, "Failed to delete user")
+		return
+	}
+
+	response.SuccessJSONWithLog(c, nil, "User deleted successfully")
+}
+
+// handleUserError maps user management errors to responses
+func (uc *UserController) handleUserError(c *gin.Context, err error, message string) {
+	switch {
+	case err == services.ErrUserNotFound:
+		response.ErrorWithCode(c, http.StatusNotFound, apperrors.CodeUserNotFound, "User not found", err)
+	case err == services.ErrUnauthorized:
+		response.ForbiddenJSONWithLog(c, "Admin access required")
+	case err == services.ErrEmailTaken:
+		response.ErrorWithCode(c, http.StatusConflict, apperrors.CodeEmailTaken, err.Error(), err)
+	case err == services.ErrLastAdmin:
+		response.ErrorWithCode(c, http.StatusConflict, apperrors.CodeLastAdmin, err.Error(), err)
+	case err == services.ErrDeleteSelf:
+		response.ErrorWithCode(c, http.StatusConflict, apperrors.CodeDeleteSelf, err.Error(), err)
+	case errors.Is(err, services.ErrUnknownRole):
+		response.ErrorWithCode(c, http.StatusBadRequest, apperrors.CodeUnknownRole, err.Error(), err)
+	case err == services.ErrRolesRequired:
+		response.ErrorWithCode(c, http.StatusBadRequest, apperrors.CodeRolesRequired, err.Error(), err)
+	case err == services.ErrPasswordLength:
+		response.ErrorWithCode(c, http.StatusBadRequest, apperrors.CodePasswordTooShort, err.Error(), err)
+	default:
+		response.InternalServerErrorJSONWithLog(c, message, err)
+	}
+}
+
+// TransferResourcesRequest represents a resource ownership transfer request
+type TransferResourcesRequest struct {
+	TargetUserID uint `json:"target_user_id" binding:"required"`
+}
+
+// TransferResources handles POST /api/v1/admin/users/:id/transfer
+func (uc *UserController) TransferResources(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	sourceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid user ID", err)
+		return
+	}
+
+	var req TransferResourcesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid transfer request", err)
+		return
+	}
+
+	if uc.userService == nil {
+		response.InternalServerErrorJSONWithLog(c, "User service not available", nil)
+		return
+	}
+
+	result, err := uc.userService.TransferResources(uint(sourceID), req.TargetUserID, actorID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if conflictErr, ok := err.(*services.TransferConflictError); ok {
+			response.ErrorJSONWithLog(c, http.StatusConflict, "Target user already owns resources with the same name", conflictErr)
+			return
+		}
+		switch err {
+		case services.ErrUserNotFound:
+			response.NotFoundJSONWithLog(c, "User not found")
+		case services.ErrTransferTargetNotFound:
+			response.BadRequestJSONWithLog(c, "Target user not found", err)
+		case services.ErrTransferSameUser:
+			response.BadRequestJSONWithLog(c, "Source and target user must differ", err)
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to transfer resources", err)
+		}
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "Resources transferred successfully")
+}
+
+// impersonationTokenTTL is how long an impersonation token stays valid
+const impersonationTokenTTL = 15 * time.Minute
+
+// Impersonate handles POST /api/v1/admin/users/:id/impersonate and issues a
+// short-lived token acting as the user. Admins cannot be impersonated.
+func (uc *UserController) Impersonate(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid user ID", err)
+		return
+	}
+
+	authService, exists := middleware.GetAuthService(c)
+	if !exists {
+		response.InternalServerErrorJSONWithLog(c, "Auth service not available", nil)
+		return
+	}
+
+	result, err := authService.Impersonate(actorID, uint(targetID), impersonationTokenTTL, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		switch err {
+		case services.ErrUserNotFound:
+			response.NotFoundJSONWithLog(c, "User not found")
+		case services.ErrUserDisabled:
+			response.BadRequestJSONWithLog(c, "User account is disabled", err)
+		case services.ErrImpersonateSelf:
+			response.BadRequestJSONWithLog(c, err.Error(), err)
+		case services.ErrImpersonateAdmin, services.ErrUnauthorized:
+			response.ForbiddenJSONWithLog(c, err.Error())
+		default:
+			response.InternalServerErrorJSONWithLog(c, "Failed to impersonate user", err)
+		}
+		return
+	}
+
+	response.SuccessJSONWithLog(c, result, "Impersonation token issued successfully")
+}
+
+// ListRoles handles GET /api/v1/admin/roles
+func (uc *UserController) ListRoles(c *gin.Context) {
+	if uc.userService == nil {
+		response.InternalServerErrorJSONWithLog(c, "User service not available", nil)
+		return
+	}
+
+	roles, err := uc.userService.ListRoles()
+	if err != nil {
+		response.InternalServerErrorJSONWithLog(c, "Failed to list roles", err)
+		return
+	}
+
+	response.SuccessJSONWithLog(c, roles, "Roles retrieved successfully")
+}
+
+// AssignRolesRequest replaces the roles held by a user
+type AssignRolesRequest struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+// AssignRoles handles PUT /api/v1/admin/users/:id/roles
+func (uc *UserController) AssignRoles(c *gin.Context) {
+	actorID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		response.UnauthorizedJSONWithLog(c, "User not authenticated")
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid user ID", err)
+		return
+	}
+
+	var req AssignRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestJSONWithLog(c, "Invalid role assignment request", err)
+		return
+	}
+
+	if uc.userService == nil {
+		response.InternalServerErrorJSONWithLog(c, "User service not available", nil)
+		return
+	}
+
+	user, err := uc.userService.AssignRoles(actorID, uint(userID), req.Roles, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		uc.handleUserError(c, err, "Failed to assign roles")
+		return
+	}
+
+	response.SuccessJSONWithLog(c, user, "Roles assigned successfully")
+}
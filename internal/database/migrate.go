@@ -12,6 +12,8 @@ import (
 func AllModels() []interface{} {
 	return []interface{}{
 		&models.User{},
+		&models.Permission{},
+		&models.Role{},
 		&models.Certificate{},
 		&models.AccessList{},
 		&models.AccessListItem{},
@@ -19,14 +21,22 @@ func AllModels() []interface{} {
 		&models.RedirectionHost{},
 		&models.Stream{},
 		&models.DeadHost{},
+		&models.HostDomain{},
 		&models.AuditLog{},
+		&models.ActivityEvent{},
 		&models.Token{},
 		&models.Setting{},
 		&models.NginxConfig{},
 		&models.ConfigVersion{},
 		&models.ConfigBackup{},
 		&models.ConfigTemplate{},
+		&models.TemplateVersion{},
 		&models.ConfigApproval{},
+		&models.Silence{},
+		&models.QueuedNotification{},
+		&models.RetentionPolicy{},
+		&models.PerformanceInsight{},
+		&models.DashboardShare{},
 	}
 }
 
@@ -34,20 +44,190 @@ func AllModels() []interface{} {
 func AutoMigrate(db *gorm.DB) error {
 	log.Println("Running database auto-migration...")
 
+	if err := prepareInsightFingerprints(db); err != nil {
+		return fmt.Errorf("failed to prepare insight fingerprints: %w", err)
+	}
+
 	for _, model := range AllModels() {
 		if err := db.AutoMigrate(model); err != nil {
 			return fmt.Errorf("failed to migrate %T: %w", model, err)
 		}
 	}
 
+	if err := BackfillHostDomains(db); err != nil {
+		return fmt.Errorf("failed to backfill host domains: %w", err)
+	}
+
 	log.Println("Database auto-migration completed successfully")
 	return nil
 }
 
+// prepareInsightFingerprints readies existing performance insights for the
+// unique fingerprint index: the old non-unique index is dropped, empty
+// fingerprints become NULL and, of rows sharing a fingerprint, only the
+// oldest is kept
+func prepareInsightFingerprints(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.PerformanceInsight{}) {
+		return nil
+	}
+	if migrator.HasIndex(&models.PerformanceInsight{}, "idx_performance_insights_fingerprint") {
+		if err := migrator.DropIndex(&models.PerformanceInsight{}, "idx_performance_insights_fingerprint"); err != nil {
+			return err
+		}
+	}
+
+	if err := db.Model(&models.PerformanceInsight{}).Unscoped().
+		Where("fingerprint = ?", "").
+		UpdateColumn("fingerprint", nil).Error; err != nil {
+		return err
+	}
+
+	var duplicates []uint
+	if err := db.Model(&models.PerformanceInsight{}).Unscoped().
+		Where("fingerprint IS NOT NULL").
+		Where("id NOT IN (?)", db.Model(&models.PerformanceInsight{}).Unscoped().
+			Select("MIN(id)").Where("fingerprint IS NOT NULL").Group("fingerprint")).
+		Pluck("id", &duplicates).Error; err != nil {
+		return err
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+	log.Printf("Removing %d duplicate performance insights", len(duplicates))
+	return db.Unscoped().Where("id IN ?", duplicates).Delete(&models.PerformanceInsight{}).Error
+}
+
+// BackfillHostDomains indexes the domains of live hosts missing from
+// host_domains. It is safe to re-run: indexed domains are skipped. A domain
+// already used by an earlier host is left with that host and logged, so
+// duplicates created before the index existed can be fixed by hand.
+func BackfillHostDomains(db *gorm.DB) error {
+	type indexedHost struct {
+		ID          uint
+		DomainNames models.StringArray
+	}
+
+	var indexed []models.HostDomain
+	if err := db.Find(&indexed).Error; err != nil {
+		return err
+	}
+	owners := make(map[string]models.HostDomain, len(indexed))
+	for _, row := range indexed {
+		owners[row.Domain] = row
+	}
+
+	added := 0
+	for _, model := range []interface{ TableName() string }{&models.ProxyHost{}, &models.DeadHost{}, &models.RedirectionHost{}} {
+		var hosts []indexedHost
+		if err := db.Model(model).Select("id", "domain_names").Order("id ASC").Find(&hosts).Error; err != nil {
+			return err
+		}
+
+		for _, host := range hosts {
+			for _, domain := range models.NormalizeDomains(host.DomainNames) {
+				if owner, ok := owners[domain]; ok {
+					if owner.HostType != model.TableName() || owner.HostID != host.ID {
+						log.Printf("Domain %s of %s #%d is already used by %s #%d; not indexed",
+							domain, model.TableName(), host.ID, owner.HostType, owner.HostID)
+					}
+					continue
+				}
+
+				row := models.HostDomain{Domain: domain, HostType: model.TableName(), HostID: host.ID}
+				if err := db.Create(&row).Error; err != nil {
+					return err
+				}
+				owners[domain] = row
+				added++
+			}
+		}
+	}
+
+	if added > 0 {
+		log.Printf("Indexed %d host domains", added)
+	}
+	return nil
+}
+
+// MigrateTimeSeriesStorage creates the lean raw_metrics table used when
+// time-series metric storage is enabled. On PostgreSQL the time index is a
+// BRIN index, which stays small for append-only, time-ordered data.
+func MigrateTimeSeriesStorage(db *gorm.DB) error {
+	log.Println("Running time-series metrics migration...")
+
+	if err := db.AutoMigrate(&models.RawMetric{}); err != nil {
+		return fmt.Errorf("failed to migrate %T: %w", &models.RawMetric{}, err)
+	}
+
+	if !db.Migrator().HasIndex(&models.RawMetric{}, "idx_raw_metrics_timestamp") {
+		indexSQL := "CREATE INDEX idx_raw_metrics_timestamp ON raw_metrics (timestamp)"
+		if db.Dialector.Name() == "postgres" {
+			indexSQL = "CREATE INDEX idx_raw_metrics_timestamp ON raw_metrics USING BRIN (timestamp)"
+		}
+		if err := db.Exec(indexSQL).Error; err != nil {
+			return fmt.Errorf("failed to create raw metrics time index: %w", err)
+		}
+	}
+
+	log.Println("Time-series metrics migration completed successfully")
+	return nil
+}
+
+// MoveHistoricalMetricsToRaw moves rows from historical_metrics into
+// raw_metrics in batches, hard-deleting each batch once it has been copied.
+// It is safe to re-run after an interruption and returns the number of rows moved.
+func MoveHistoricalMetricsToRaw(db *gorm.DB, batchSize int) (int64, error) {
+	if !db.Migrator().HasTable(&models.HistoricalMetric{}) {
+		return 0, nil
+	}
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+
+	var moved int64
+	for {
+		var batch []models.HistoricalMetric
+		if err := db.Order("id ASC").Limit(batchSize).Find(&batch).Error; err != nil {
+			return moved, fmt.Errorf("failed to read historical metrics: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		rawMetrics := make([]*models.RawMetric, len(batch))
+		ids := make([]uint, len(batch))
+		for i := range batch {
+			rawMetrics[i] = batch[i].ToRawMetric()
+			ids[i] = batch[i].ID
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(rawMetrics).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Where("id IN ?", ids).Delete(&models.HistoricalMetric{}).Error
+		})
+		if err != nil {
+			return moved, fmt.Errorf("failed to move historical metrics: %w", err)
+		}
+
+		moved += int64(len(batch))
+		log.Printf("Moved %d historical metrics to raw_metrics", moved)
+	}
+
+	return moved, nil
+}
+
 // SeedData creates initial data in the database
 func SeedData(db *gorm.DB) error {
 	log.Println("Seeding initial data...")
 
+	// Create default roles and keep their permissions current
+	if err := createDefaultRoles(db); err != nil {
+		return fmt.Errorf("failed to create default roles: %w", err)
+	}
+
 	// Create default admin user if not exists
 	if err := createDefaultAdmin(db); err != nil {
 		return fmt.Errorf("failed to create default admin: %w", err)
@@ -93,37 +273,151 @@ func createDefaultAdmin(db *gorm.DB) error {
 	return nil
 }
 
+// createDefaultRoles creates every permission and the built-in roles. The
+// built-in roles' permissions are reset to their defaults on every start, so
+// new permissions reach existing installs.
+func createDefaultRoles(db *gorm.DB) error {
+	permissionIDs := make(map[string]models.Permission)
+	for _, permission := range models.AllPermissions() {
+		var existing models.Permission
+		err := db.Where("name = ?", permission.Name).First(&existing).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err == gorm.ErrRecordNotFound {
+			existing = permission
+			if err := db.Create(&existing).Error; err != nil {
+				return err
+			}
+		} else if existing.Description != permission.Description {
+			if err := db.Model(&existing).Update("description", permission.Description).Error; err != nil {
+				return err
+			}
+		}
+		permissionIDs[permission.Name] = existing
+	}
+
+	for _, role := range models.DefaultRoles() {
+		permissions := make([]models.Permission, 0, len(role.Permissions))
+		for _, permission := range role.Permissions {
+			permissions = append(permissions, permissionIDs[permission.Name])
+		}
+
+		var existing models.Role
+		err := db.Where("name = ?", role.Name).First(&existing).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err == gorm.ErrRecordNotFound {
+			existing = models.Role{Name: role.Name, Description: role.Description, IsSystem: true}
+			if err := db.Create(&existing).Error; err != nil {
+				return err
+			}
+			log.Printf("Created default role: %s", role.Name)
+		}
+
+		if err := db.Model(&existing).Association("Permissions").Replace(permissions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // createDefaultSettings creates default system settings
 func createDefaultSettings(db *gorm.DB) error {
 	defaultSettings := []models.Setting{
 		{
-			ID:   "default-site",
-			Name: "Default Site",
+			ID:          "default-site",
+			Name:        "Default Site",
+			Description: "Page shown for requests that match no host",
+			Type:        models.SettingTypeString,
 			Value: models.JSON{
 				"value": "Congratulations! You have successfully installed Nginx Proxy Manager.",
 			},
 		},
 		{
-			ID:   "disable-ipv6",
-			Name: "Disable IPv6",
+			ID:          "disable-ipv6",
+			Name:        "Disable IPv6",
+			Description: "Stop generated hosts from listening on IPv6",
+			Type:        models.SettingTypeBool,
 			Value: models.JSON{
 				"value": false,
 			},
 		},
 		{
-			ID:   "cloudflare-api-token",
-			Name: "Cloudflare API Token",
+			ID:          "cloudflare-api-token",
+			Name:        "Cloudflare API Token",
+			Description: "Token used for Cloudflare DNS challenges",
+			Type:        models.SettingTypeString,
+			IsSecret:    true,
 			Value: models.JSON{
 				"value": "",
 			},
 		},
 		{
-			ID:   "default-intermediate-cert",
-			Name: "Default Intermediate Certificate",
+			ID:          "default-certificate",
+			Name:        "Default Certificate",
+			Description: "Certificate served by HTTPS hosts without a valid certificate of their own",
+			Type:        models.SettingTypeJSON,
+			Value: models.JSON{
+				"value": map[string]interface{}{"mode": "self-signed"},
+			},
+		},
+		{
+			ID:          "default-intermediate-cert",
+			Name:        "Default Intermediate Certificate",
+			Description: "Intermediate certificate used for certificates uploaded without a chain",
+			Type:        models.SettingTypeString,
+			Value: models.JSON{
+				"value": "",
+			},
+		},
+		{
+			ID:          "certificate-renewal-days",
+			Name:        "Certificate Renewal Lead Time",
+			Description: "Days before expiry that Let's Encrypt certificates are renewed",
+			Type:        models.SettingTypeInt,
+			Value: models.JSON{
+				"value": 30,
+			},
+		},
+		{
+			ID:          "certificate-expiry-lead-days",
+			Name:        "Certificate Expiry Notice",
+			Description: "Days before expiry that owners of certificates which will not auto-renew are notified; empty uses CERT_EXPIRY_LEAD_DAYS",
+			Type:        models.SettingTypeInt,
+			Value: models.JSON{
+				"value": nil,
+			},
+		},
+		{
+			ID:          "letsencrypt-staging",
+			Name:        "Let's Encrypt Staging",
+			Description: "Request Let's Encrypt certificates from the staging environment, which browsers do not trust, unless a certificate sets use_staging itself",
+			Type:        models.SettingTypeBool,
+			Value: models.JSON{
+				"value": false,
+			},
+		},
+		{
+			ID:          "letsencrypt-email",
+			Name:        "Let's Encrypt Account Email",
+			Description: "Contact address of the ACME account, used by certificates without their own account_email",
+			Type:        models.SettingTypeString,
 			Value: models.JSON{
 				"value": "",
 			},
 		},
+		{
+			ID:          "metrics-collection-interval",
+			Name:        "Metrics Collection Interval",
+			Description: "Seconds between stored system metric samples; empty uses METRICS_COLLECTION_INTERVAL",
+			Type:        models.SettingTypeInt,
+			Value: models.JSON{
+				"value": nil,
+			},
+		},
 	}
 
 	for _, setting := range defaultSettings {
@@ -140,6 +434,20 @@ func createDefaultSettings(db *gorm.DB) error {
 				return err
 			}
 			log.Printf("Created default setting: %s", setting.Name)
+			continue
+		}
+
+		// Keep the definition current without touching the stored value
+		if existing.Name != setting.Name || existing.Description != setting.Description ||
+			existing.Type != setting.Type || existing.IsSecret != setting.IsSecret {
+			if err := db.Model(&existing).Updates(map[string]interface{}{
+				"name":        setting.Name,
+				"description": setting.Description,
+				"type":        setting.Type,
+				"is_secret":   setting.IsSecret,
+			}).Error; err != nil {
+				return err
+			}
 		}
 	}
 
@@ -3,7 +3,6 @@ package middleware
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,15 +11,9 @@ import (
 	"github.com/nguyendkn/nginx-manager/pkg/response"
 )
 
-// AuthMiddleware creates JWT authentication middleware
-func AuthMiddleware() gin.HandlerFunc {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "nginx-manager-secret" // Default secret for development
-	}
-
-	authService := services.NewAuthService(jwtSecret)
-
+// AuthMiddleware creates JWT authentication middleware validating tokens with
+// authService
+func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		token := extractTokenFromHeader(c)
 		if token == "" {
@@ -37,6 +30,13 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Impersonation tokens stop working once the admin loses the role
+		if err := authService.ValidateImpersonator(claims); err != nil {
+			response.ErrorJSON(c, http.StatusUnauthorized, "Invalid token", err)
+			c.Abort()
+			return
+		}
+
 		// Get current user
 		user, err := authService.GetCurrentUser(token)
 		if err != nil {
@@ -52,19 +52,82 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("user_roles", claims.Roles)
 		c.Set("auth_service", authService)
 
+		if !claims.IsImpersonation() {
+			c.Next()
+			return
+		}
+
+		// Every request made while impersonating is audit-logged
+		c.Set("impersonator_id", claims.ImpersonatorID)
 		c.Next()
+		authService.LogImpersonatedRequest(claims, c.Request.Method, c.Request.URL.Path,
+			c.Writer.Status(), c.ClientIP(), c.Request.UserAgent())
 	})
 }
 
-// OptionalAuthMiddleware creates optional JWT authentication middleware
-func OptionalAuthMiddleware() gin.HandlerFunc {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "nginx-manager-secret"
-	}
+// DenyImpersonationMiddleware rejects requests made with an impersonation
+// token, for account operations support staff must not perform as the user
+func DenyImpersonationMiddleware() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		if _, impersonating := GetImpersonatorID(c); impersonating {
+			response.ErrorJSON(c, http.StatusForbidden, "Not allowed while impersonating a user", nil)
+			c.Abort()
+			return
+		}
 
-	authService := services.NewAuthService(jwtSecret)
+		c.Next()
+	})
+}
 
+// WebSocketAuthMiddleware authenticates WebSocket upgrade requests. Browsers
+// cannot set headers on a WebSocket handshake, so besides the Authorization
+// header it accepts a short-lived ?token= issued for the WebSocket only.
+func WebSocketAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var claims *services.JWTClaims
+		var err error
+		if token := extractTokenFromHeader(c); token != "" {
+			claims, err = authService.ValidateToken(token)
+		} else if token := c.Query("token"); token != "" {
+			claims, err = authService.ValidateWebSocketToken(token)
+		} else {
+			response.ErrorJSON(c, http.StatusUnauthorized, "Authorization token required", nil)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			response.ErrorJSON(c, http.StatusUnauthorized, "Invalid token", err)
+			c.Abort()
+			return
+		}
+
+		// A long-lived connection would escape the impersonation audit trail
+		if claims.IsImpersonation() {
+			response.ErrorJSON(c, http.StatusForbidden, "Not allowed while impersonating a user", nil)
+			c.Abort()
+			return
+		}
+
+		user, err := authService.GetActiveUser(claims.UserID)
+		if err != nil {
+			response.ErrorJSON(c, http.StatusUnauthorized, "Invalid user", err)
+			c.Abort()
+			return
+		}
+
+		c.Set("user", user)
+		c.Set("user_id", claims.UserID)
+		c.Set("user_email", claims.Email)
+		c.Set("user_roles", claims.Roles)
+		c.Set("auth_service", authService)
+
+		c.Next()
+	})
+}
+
+// OptionalAuthMiddleware creates optional JWT authentication middleware
+// validating tokens with authService
+func OptionalAuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		token := extractTokenFromHeader(c)
 		if token != "" {
@@ -221,6 +284,18 @@ func GetCurrentUserID(c *gin.Context) (uint, bool) {
 	return id, ok
 }
 
+// GetImpersonatorID gets the admin acting as the current user, if the request
+// was made with an impersonation token
+func GetImpersonatorID(c *gin.Context) (uint, bool) {
+	impersonatorID, exists := c.Get("impersonator_id")
+	if !exists {
+		return 0, false
+	}
+
+	id, ok := impersonatorID.(uint)
+	return id, ok
+}
+
 // GetAuthService gets auth service from context
 func GetAuthService(c *gin.Context) (*services.AuthService, bool) {
 	authService, exists := c.Get("auth_service")
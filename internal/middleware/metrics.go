@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+)
+
+// HTTPMetricsMiddleware records request latency and status, tagged with the
+// proxy host matching the request's Host header ("unmatched" otherwise)
+func HTTPMetricsMiddleware(metricsService *services.HTTPMetricsService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if metricsService == nil {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		tag := metricsService.Record(c.Request.Host, c.Writer.Status(), time.Since(start))
+		c.Set("proxy_host_tag", tag)
+	}
+}
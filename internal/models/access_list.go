@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // AccessList represents an access control list
@@ -35,13 +38,16 @@ type AccessListItem struct {
 	Address string `json:"address,omitempty" gorm:"size:255"`
 	Subnet  string `json:"subnet,omitempty" gorm:"size:255"`
 
+	// For geo-based rules, an ISO 3166-1 alpha-2 country code
+	CountryCode string `json:"country_code,omitempty" gorm:"size:2"`
+
 	// For authentication-based rules
 	Username string `json:"username,omitempty" gorm:"size:255"`
 	Password string `json:"password,omitempty" gorm:"size:255"`
 
 	// Additional configuration
 	Comment string `json:"comment,omitempty" gorm:"type:text"`
-	Enabled bool   `json:"enabled" gorm:"default:true"`
+	Enabled bool   `json:"enabled"`
 
 	// Relationship
 	AccessList AccessList `json:"access_list,omitempty" gorm:"foreignKey:AccessListID"`
@@ -59,12 +65,13 @@ const (
 	AccessListItemTypeIP   AccessListItemType = "ip"
 	AccessListItemTypeAuth AccessListItemType = "auth"
 	AccessListItemTypeCIDR AccessListItemType = "cidr"
+	AccessListItemTypeGeo  AccessListItemType = "geo"
 )
 
 // IsValid checks if the access list item type is valid
 func (t AccessListItemType) IsValid() bool {
 	switch t {
-	case AccessListItemTypeIP, AccessListItemTypeAuth, AccessListItemTypeCIDR:
+	case AccessListItemTypeIP, AccessListItemTypeAuth, AccessListItemTypeCIDR, AccessListItemTypeGeo:
 		return true
 	default:
 		return false
@@ -106,6 +113,16 @@ func (al *AccessList) HasIPRules() bool {
 	return false
 }
 
+// HasGeoRules checks if the access list contains enabled country-based rules
+func (al *AccessList) HasGeoRules() bool {
+	for _, item := range al.GetEnabledItems() {
+		if item.IsGeoItem() {
+			return true
+		}
+	}
+	return false
+}
+
 // HasAuthRules checks if the access list contains authentication-based rules
 func (al *AccessList) HasAuthRules() bool {
 	for _, item := range al.Items {
@@ -129,49 +146,95 @@ func (al *AccessList) GetEnabledItems() []AccessListItem {
 
 // CheckIPAccess checks if an IP address is allowed or denied
 func (al *AccessList) CheckIPAccess(ipAddress string) (bool, error) {
-	ip := net.ParseIP(ipAddress)
+	allowed, _, err := al.EvaluateIPAccess(ipAddress)
+	return allowed, err
+}
+
+// EvaluateIPAccess checks an IP address against the enabled IP and CIDR rules
+// and returns the rule that decided the result, if any. A matching deny rule
+// takes precedence over a matching allow rule; when no rule matches, access is
+// denied only if the list contains allow rules. IPv4-mapped IPv6 addresses
+// (::ffff:a.b.c.d) match IPv4 rules and vice versa.
+func (al *AccessList) EvaluateIPAccess(ipAddress string) (bool, *AccessListItem, error) {
+	ip := ParseAccessListIP(ipAddress)
 	if ip == nil {
-		return false, ErrInvalidIPAddress
+		return false, nil, ErrInvalidIPAddress
 	}
 
 	hasAllowRules := false
-	hasExplicitAllow := false
-	hasExplicitDeny := false
+	var matchedAllow *AccessListItem
 
-	for _, item := range al.GetEnabledItems() {
-		if item.Type != AccessListItemTypeIP && item.Type != AccessListItemTypeCIDR {
+	items := al.GetEnabledItems()
+	for i := range items {
+		item := &items[i]
+		if !item.IsIPItem() {
 			continue
 		}
 
-		allowed, err := item.MatchesIP(ipAddress)
-		if err != nil {
+		if item.Directive == AccessListDirectiveAllow {
+			hasAllowRules = true
+		}
+
+		matches, err := item.matchesParsedIP(ip)
+		if err != nil || !matches {
 			continue
 		}
 
-		if allowed {
-			if item.Directive == AccessListDirectiveAllow {
-				hasAllowRules = true
-				hasExplicitAllow = true
-			} else if item.Directive == AccessListDirectiveDeny {
-				hasExplicitDeny = true
-				break // Deny rules take precedence
-			}
+		if item.Directive == AccessListDirectiveDeny {
+			return false, item, nil // Deny rules take precedence
+		}
+		if matchedAllow == nil {
+			matchedAllow = item
 		}
 	}
 
-	// If there's an explicit deny, access is denied
-	if hasExplicitDeny {
-		return false, nil
+	if matchedAllow != nil {
+		return true, matchedAllow, nil
 	}
 
-	// If there are allow rules but no explicit allow, access is denied
-	if hasAllowRules && !hasExplicitAllow {
-		return false, nil
+	// If there are allow rules but none matched, access is denied; with no
+	// allow rules, access is allowed (default behavior)
+	return !hasAllowRules, nil, nil
+}
+
+// EvaluateGeoAccess checks a country code against the enabled geo rules and
+// returns the rule that decided the result, if any. Like EvaluateIPAccess, a
+// matching deny rule takes precedence, and a country matching no rule is
+// denied only if the list contains geo allow rules. An empty country code
+// (unknown or private address) matches no rule.
+func (al *AccessList) EvaluateGeoAccess(countryCode string) (bool, *AccessListItem) {
+	countryCode = strings.ToUpper(strings.TrimSpace(countryCode))
+
+	hasAllowRules := false
+	var matchedAllow *AccessListItem
+
+	items := al.GetEnabledItems()
+	for i := range items {
+		item := &items[i]
+		if !item.IsGeoItem() {
+			continue
+		}
+
+		if item.Directive == AccessListDirectiveAllow {
+			hasAllowRules = true
+		}
+
+		if countryCode == "" || !strings.EqualFold(item.CountryCode, countryCode) {
+			continue
+		}
+
+		if item.Directive == AccessListDirectiveDeny {
+			return false, item
+		}
+		if matchedAllow == nil {
+			matchedAllow = item
+		}
 	}
 
-	// If there are no allow rules, access is allowed (default behavior)
-	// If there's an explicit allow, access is allowed
-	return true, nil
+	if matchedAllow != nil {
+		return true, matchedAllow
+	}
+	return !hasAllowRules, nil
 }
 
 // ValidateRules validates all access list items
@@ -193,19 +256,24 @@ func (al *AccessList) ValidateRules() []string {
 
 // MatchesIP checks if an IP address matches this access list item
 func (ali *AccessListItem) MatchesIP(ipAddress string) (bool, error) {
-	if ali.Type != AccessListItemTypeIP && ali.Type != AccessListItemTypeCIDR {
+	if !ali.IsIPItem() {
 		return false, ErrInvalidAccessListItemType
 	}
 
-	ip := net.ParseIP(ipAddress)
+	ip := ParseAccessListIP(ipAddress)
 	if ip == nil {
 		return false, ErrInvalidIPAddress
 	}
 
+	return ali.matchesParsedIP(ip)
+}
+
+// matchesParsedIP matches an address already parsed by ParseAccessListIP
+func (ali *AccessListItem) matchesParsedIP(ip net.IP) (bool, error) {
 	switch ali.Type {
 	case AccessListItemTypeIP:
-		// Direct IP match
-		targetIP := net.ParseIP(ali.Address)
+		// Direct IP match; Equal treats ::ffff:a.b.c.d and a.b.c.d as the same address
+		targetIP := ParseAccessListIP(ali.Address)
 		if targetIP == nil {
 			return false, ErrInvalidIPAddress
 		}
@@ -213,7 +281,7 @@ func (ali *AccessListItem) MatchesIP(ipAddress string) (bool, error) {
 
 	case AccessListItemTypeCIDR:
 		// CIDR range match
-		_, cidr, err := net.ParseCIDR(ali.Subnet)
+		cidr, err := ParseAccessListCIDR(ali.Subnet)
 		if err != nil {
 			return false, err
 		}
@@ -243,17 +311,24 @@ func (ali *AccessListItem) Validate() []string {
 	case AccessListItemTypeIP:
 		if ali.Address == "" {
 			errors = append(errors, "IP address is required for IP type")
-		} else if net.ParseIP(ali.Address) == nil {
+		} else if ParseAccessListIP(ali.Address) == nil {
 			errors = append(errors, "invalid IP address format")
 		}
 
 	case AccessListItemTypeCIDR:
 		if ali.Subnet == "" {
 			errors = append(errors, "subnet is required for CIDR type")
-		} else if _, _, err := net.ParseCIDR(ali.Subnet); err != nil {
+		} else if _, err := ParseAccessListCIDR(ali.Subnet); err != nil {
 			errors = append(errors, "invalid CIDR format")
 		}
 
+	case AccessListItemTypeGeo:
+		if ali.CountryCode == "" {
+			errors = append(errors, "country code is required for geo type")
+		} else if !IsValidCountryCode(ali.CountryCode) {
+			errors = append(errors, "country code must be a two-letter ISO 3166-1 code")
+		}
+
 	case AccessListItemTypeAuth:
 		if ali.Username == "" {
 			errors = append(errors, "username is required for auth type")
@@ -273,6 +348,8 @@ func (ali *AccessListItem) GetDisplayName() string {
 		return ali.Address
 	case AccessListItemTypeCIDR:
 		return ali.Subnet
+	case AccessListItemTypeGeo:
+		return "country " + ali.CountryCode
 	case AccessListItemTypeAuth:
 		return ali.Username
 	default:
@@ -280,6 +357,60 @@ func (ali *AccessListItem) GetDisplayName() string {
 	}
 }
 
+// ParseAccessListIP parses an IPv4 or IPv6 address as nginx accepts it in an
+// allow/deny directive. Surrounding brackets are ignored; zone identifiers
+// (fe80::1%eth0) are rejected. IPv4-mapped IPv6 addresses are returned in their
+// 4-byte form.
+func ParseAccessListIP(address string) net.IP {
+	address = strings.TrimSpace(address)
+	if strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]") {
+		address = address[1 : len(address)-1]
+	}
+	if strings.Contains(address, "%") {
+		return nil
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4
+	}
+	return ip
+}
+
+// ParseAccessListCIDR parses an IPv4 or IPv6 CIDR block and returns the masked
+// network, so 2001:db8::1/32 becomes 2001:db8::/32. An IPv4-mapped IPv6 block
+// with a prefix of at least 96 bits is converted to the equivalent IPv4 block.
+func ParseAccessListCIDR(subnet string) (*net.IPNet, error) {
+	_, network, err := net.ParseCIDR(strings.TrimSpace(subnet))
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := network.Mask.Size()
+	if bits == 8*net.IPv6len && ones >= 96 {
+		if v4 := network.IP.To4(); v4 != nil {
+			return &net.IPNet{IP: v4, Mask: net.CIDRMask(ones-96, 8*net.IPv4len)}, nil
+		}
+	}
+	return network, nil
+}
+
+// IsValidCountryCode reports whether code is a two-letter uppercase country code
+func IsValidCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
 // GetNginxRule generates the nginx configuration rule for this item
 func (ali *AccessListItem) GetNginxRule() string {
 	if !ali.Enabled {
@@ -288,12 +419,23 @@ func (ali *AccessListItem) GetNginxRule() string {
 
 	switch ali.Type {
 	case AccessListItemTypeIP:
-		return fmt.Sprintf("%s %s;", ali.Directive, ali.Address)
+		ip := ParseAccessListIP(ali.Address)
+		if ip == nil {
+			return ""
+		}
+		return fmt.Sprintf("%s %s;", ali.Directive, ip.String())
 	case AccessListItemTypeCIDR:
-		return fmt.Sprintf("%s %s;", ali.Directive, ali.Subnet)
+		cidr, err := ParseAccessListCIDR(ali.Subnet)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("%s %s;", ali.Directive, cidr.String())
 	case AccessListItemTypeAuth:
 		// Auth rules are handled differently in nginx (auth_basic)
 		return ""
+	case AccessListItemTypeGeo:
+		// Geo rules need a GeoIP variable and an if guard, see ExportAccessList
+		return ""
 	default:
 		return ""
 	}
@@ -304,20 +446,34 @@ func (ali *AccessListItem) IsAuthItem() bool {
 	return ali.Type == AccessListItemTypeAuth
 }
 
+// IsGeoItem checks if this is a country-based item
+func (ali *AccessListItem) IsGeoItem() bool {
+	return ali.Type == AccessListItemTypeGeo
+}
+
 // IsIPItem checks if this is an IP-based item
 func (ali *AccessListItem) IsIPItem() bool {
 	return ali.Type == AccessListItemTypeIP || ali.Type == AccessListItemTypeCIDR
 }
 
-// SetPassword sets and hashes the password for auth items
+// SetPassword hashes the password for auth items with bcrypt. A value that is
+// already a bcrypt hash, such as one read back from the database, is stored
+// unchanged so it is not hashed twice.
 func (ali *AccessListItem) SetPassword(password string) error {
 	if ali.Type != AccessListItemTypeAuth {
 		return ErrInvalidAccessListItemType
 	}
 
-	// In a real implementation, this would hash the password
-	// For now, we'll store it as-is (should be hashed in production)
-	ali.Password = password
+	if isBcryptHash(password) {
+		ali.Password = password
+		return nil
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	ali.Password = string(hashedPassword)
 	return nil
 }
 
@@ -327,8 +483,18 @@ func (ali *AccessListItem) CheckPassword(password string) bool {
 		return false
 	}
 
-	// In a real implementation, this would compare hashed passwords
-	return ali.Password == password
+	return bcrypt.CompareHashAndPassword([]byte(ali.Password), []byte(password)) == nil
+}
+
+// IsPasswordHashed reports whether the stored password is a bcrypt hash
+func (ali *AccessListItem) IsPasswordHashed() bool {
+	return isBcryptHash(ali.Password)
+}
+
+// isBcryptHash reports whether value is a well-formed bcrypt hash
+func isBcryptHash(value string) bool {
+	_, err := bcrypt.Cost([]byte(value))
+	return err == nil
 }
 
 // Error definitions
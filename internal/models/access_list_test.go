@@ -0,0 +1,90 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+// ipRule returns an enabled IP or CIDR rule
+func ipRule(directive AccessListDirective, address string) AccessListItem {
+	item := AccessListItem{Directive: directive, Enabled: true}
+	if strings.Contains(address, "/") {
+		item.Type = AccessListItemTypeCIDR
+		item.Subnet = address
+	} else {
+		item.Type = AccessListItemTypeIP
+		item.Address = address
+	}
+	return item
+}
+
+func TestEvaluateIPAccessMixedFamilies(t *testing.T) {
+	accessList := &AccessList{Items: []AccessListItem{
+		ipRule(AccessListDirectiveDeny, "2001:db8:dead::/48"),
+		ipRule(AccessListDirectiveAllow, "2001:db8::/32"),
+		ipRule(AccessListDirectiveDeny, "192.168.1.66"),
+		ipRule(AccessListDirectiveAllow, "192.168.1.0/24"),
+	}}
+
+	for _, tc := range []struct {
+		ip      string
+		allowed bool
+	}{
+		{"2001:db8::1", true},
+		{"2001:db8:dead::1", false},
+		{"2001:db9::1", false}, // no allow rule matches
+		{"192.168.1.10", true},
+		{"192.168.1.66", false},
+		{"10.0.0.1", false},
+		{"::ffff:192.168.1.10", true},  // v4-mapped address matches the IPv4 allow rule
+		{"::ffff:192.168.1.66", false}, // and the IPv4 deny rule
+		{"[2001:db8::1]", true},
+	} {
+		allowed, err := accessList.CheckIPAccess(tc.ip)
+		if err != nil {
+			t.Errorf("%s: %v", tc.ip, err)
+			continue
+		}
+		if allowed != tc.allowed {
+			t.Errorf("%s: got allowed=%v, want %v", tc.ip, allowed, tc.allowed)
+		}
+	}
+
+	if _, err := accessList.CheckIPAccess("fe80::1%eth0"); err != ErrInvalidIPAddress {
+		t.Errorf("zone identifier: got %v, want ErrInvalidIPAddress", err)
+	}
+}
+
+// TestEvaluateIPAccessMappedRules checks that rules written as v4-mapped IPv6
+// addresses match plain IPv4 clients
+func TestEvaluateIPAccessMappedRules(t *testing.T) {
+	accessList := &AccessList{Items: []AccessListItem{
+		ipRule(AccessListDirectiveDeny, "::ffff:10.0.0.5"),
+		ipRule(AccessListDirectiveAllow, "::ffff:10.0.0.0/104"),
+	}}
+
+	for ip, want := range map[string]bool{"10.0.0.5": false, "10.1.2.3": true, "11.0.0.1": false} {
+		if allowed, err := accessList.CheckIPAccess(ip); err != nil || allowed != want {
+			t.Errorf("%s: got allowed=%v err=%v, want %v", ip, allowed, err, want)
+		}
+	}
+}
+
+func TestGetNginxRuleIPv6(t *testing.T) {
+	for _, tc := range []struct {
+		item AccessListItem
+		want string
+	}{
+		{ipRule(AccessListDirectiveDeny, "2001:db8::1/32"), "deny 2001:db8::/32;"},
+		{ipRule(AccessListDirectiveAllow, "2001:DB8::0001"), "allow 2001:db8::1;"},
+		{ipRule(AccessListDirectiveAllow, "[2001:db8::1]"), "allow 2001:db8::1;"},
+		{ipRule(AccessListDirectiveDeny, "::ffff:192.0.2.1"), "deny 192.0.2.1;"},
+		{ipRule(AccessListDirectiveDeny, "::ffff:192.0.2.0/120"), "deny 192.0.2.0/24;"},
+		{ipRule(AccessListDirectiveAllow, "::/0"), "allow ::/0;"},
+		{ipRule(AccessListDirectiveAllow, "192.0.2.0/24"), "allow 192.0.2.0/24;"},
+	} {
+		if got := tc.item.GetNginxRule(); got != tc.want {
+			t.Errorf("%s%s: got %q, want %q", tc.item.Address, tc.item.Subnet, got, tc.want)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,13 +18,33 @@ type HistoricalMetric struct {
 	MetricName   string     `gorm:"index:idx_metric_name" json:"metric_name"`
 	Value        float64    `json:"value"`
 	Tags         JSON       `gorm:"type:jsonb" json:"tags"`
-	Source       string     `json:"source"`    // system, nginx, proxy_host, certificate
-	SourceID     *uint      `json:"source_id"` // ID of related entity
-	Unit         string     `json:"unit"`      // bytes, percent, requests/sec, etc.
+	Source       string     `gorm:"size:32;index:idx_historical_metrics_source,priority:1" json:"source"` // system, nginx, proxy_host, certificate
+	SourceID     *uint      `gorm:"index:idx_historical_metrics_source,priority:2" json:"source_id"`      // ID of related entity
+	Unit         string     `json:"unit"`                                                                 // bytes, percent, requests/sec, etc.
 	Description  string     `json:"description"`
 	RetentionEnd *time.Time `json:"retention_end"` // when this metric should be deleted
 }
 
+// RawMetric is a lean, append-only metric sample used by the time-series
+// storage layout. It has no soft delete or update timestamps, keeps columns
+// narrow and is indexed by series and time; retention is enforced by deleting
+// rows older than a cutoff rather than per-row retention dates.
+type RawMetric struct {
+	ID         uint64    `gorm:"primaryKey" json:"id"`
+	Timestamp  time.Time `gorm:"not null;index:idx_raw_metrics_series,priority:3" json:"timestamp"`
+	MetricType string    `gorm:"size:32;not null;index:idx_raw_metrics_series,priority:1" json:"metric_type"`
+	MetricName string    `gorm:"size:64;not null;index:idx_raw_metrics_series,priority:2" json:"metric_name"`
+	Value      float64   `gorm:"not null" json:"value"`
+	Source     string    `gorm:"size:32;index:idx_raw_metrics_source,priority:1" json:"source"`
+	SourceID   *uint     `gorm:"index:idx_raw_metrics_source,priority:2" json:"source_id"`
+	Tags       JSON      `gorm:"type:jsonb" json:"tags"`
+}
+
+// TableName returns the table name for RawMetric
+func (RawMetric) TableName() string {
+	return "raw_metrics"
+}
+
 // AlertRule defines threshold-based alerting rules
 type AlertRule struct {
 	BaseModel
@@ -29,19 +52,31 @@ type AlertRule struct {
 	Description          string                `json:"description"`
 	MetricType           string                `gorm:"not null;index" json:"metric_type"`
 	MetricName           string                `gorm:"not null;index" json:"metric_name"`
-	Condition            string                `gorm:"not null" json:"condition"` // gt, lt, eq, ne, between
-	Threshold            float64               `json:"threshold"`
-	ThresholdMax         *float64              `json:"threshold_max"`            // for 'between' condition
-	Severity             string                `gorm:"not null" json:"severity"` // info, warning, critical
-	IsEnabled            bool                  `gorm:"default:true" json:"is_enabled"`
+	Condition            string                `gorm:"not null" json:"condition"` // gt, lt, eq, ne, between, change_gt, change_lt
+	Threshold            float64               `json:"threshold"`                 // percent for change_* conditions
+	ThresholdMax         *float64              `json:"threshold_max"`             // for 'between' condition
+	Severity             string                `gorm:"not null" json:"severity"`  // info, warning, critical
+	IsEnabled            bool                  `json:"is_enabled"`
 	EvaluationWindow     int                   `gorm:"default:300" json:"evaluation_window"` // seconds
+	Sustained            bool                  `gorm:"default:false" json:"sustained"`       // breach must hold for the whole window
+	RenotifyInterval     int                   `gorm:"default:0" json:"renotify_interval"`   // seconds between repeat notifications, 0 disables
+	NotifyOnResolve      bool                  `gorm:"default:false" json:"notify_on_resolve"`
 	NotificationChannels []NotificationChannel `gorm:"many2many:alert_rule_channels;" json:"notification_channels"`
 	Tags                 JSON                  `gorm:"type:jsonb" json:"tags"`
+	MetricFilter         JSON                  `gorm:"type:jsonb" json:"metric_filter"` // metric tags a sample must carry, e.g. {"mount": "/var"}
 	LastTriggered        *time.Time            `json:"last_triggered"`
 	UserID               uint                  `gorm:"index" json:"user_id"`
 	User                 User                  `json:"user,omitempty"`
 }
 
+// Alert delivery statuses
+const (
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusPartial   = "partial"
+	DeliveryStatusFellBack  = "fell_back"
+	DeliveryStatusFailed    = "failed"
+)
+
 // AlertInstance represents a triggered alert
 type AlertInstance struct {
 	BaseModel
@@ -55,19 +90,63 @@ type AlertInstance struct {
 	Message           string     `json:"message"`
 	Context           JSON       `gorm:"type:jsonb" json:"context"`
 	NotificationsSent int        `gorm:"default:0" json:"notifications_sent"`
+	LastNotifiedAt    *time.Time `json:"last_notified_at"`
+	DeliveryStatus    string     `json:"delivery_status"` // delivered, partial, fell_back, failed
 }
 
 // NotificationChannel defines how alerts are delivered
 type NotificationChannel struct {
 	BaseModel
 	Name          string `gorm:"not null" json:"name"`
-	Type          string `gorm:"not null" json:"type"` // email, slack, webhook, teams
-	IsEnabled     bool   `gorm:"default:true" json:"is_enabled"`
+	Type          string `gorm:"not null" json:"type"` // email, slack, webhook, teams, discord
+	IsEnabled     bool   `json:"is_enabled"`
 	Configuration JSON   `gorm:"type:jsonb" json:"configuration"`
+	IsFallback    bool   `gorm:"default:false" json:"is_fallback"` // used when a primary channel fails
 	UserID        uint   `gorm:"index" json:"user_id"`
 	User          User   `json:"user,omitempty"`
 }
 
+// QueuedNotification is a non-critical alert held back by its channel's quiet
+// hours. It keeps what the summary sent when they end needs, so the queue
+// survives restarts and outlives the alert and rule.
+type QueuedNotification struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ChannelID      uint      `gorm:"not null;index" json:"channel_id"`
+	AlertID        uint      `json:"alert_id"`
+	RuleName       string    `json:"rule_name"`
+	Severity       string    `json:"severity"`
+	Message        string    `gorm:"type:text" json:"message"`
+	CurrentValue   float64   `json:"current_value"`
+	ThresholdValue float64   `json:"threshold_value"`
+	TriggeredAt    time.Time `json:"triggered_at"`
+	QueuedAt       time.Time `gorm:"not null" json:"queued_at"`
+}
+
+// Silence suppresses alert notifications during a maintenance window
+type Silence struct {
+	BaseModel
+	AlertRuleID *uint      `gorm:"index" json:"alert_rule_id"` // optional, nil matches any rule
+	AlertRule   *AlertRule `json:"alert_rule,omitempty"`
+	Matchers    JSON       `gorm:"type:jsonb" json:"matchers"` // tag key/value pairs that must all match
+	StartsAt    time.Time  `gorm:"not null;index" json:"starts_at"`
+	EndsAt      time.Time  `gorm:"not null;index" json:"ends_at"`
+	Reason      string     `json:"reason"`
+	CreatedBy   uint       `gorm:"index" json:"created_by"`
+	User        User       `gorm:"foreignKey:CreatedBy" json:"user,omitempty"`
+}
+
+// RetentionPolicy sets how long metrics of a type, with names matching a
+// glob pattern, are kept. Zero or missing retentions keep the defaults.
+type RetentionPolicy struct {
+	BaseModel
+	Name                string `gorm:"not null" json:"name"`
+	MetricType          string `gorm:"not null;index" json:"metric_type" binding:"required"`
+	MetricName          string `gorm:"not null;default:'*'" json:"metric_name"` // glob, such as nginx.* or *
+	RawRetentionDays    int    `json:"raw_retention_days"`
+	WindowRetentionDays JSON   `gorm:"type:jsonb" json:"window_retention_days"` // aggregation window to days, such as {"5m": 7}
+	Description         string `json:"description"`
+}
+
 // Dashboard represents a customizable analytics dashboard
 type Dashboard struct {
 	BaseModel
@@ -79,7 +158,24 @@ type Dashboard struct {
 	Widgets     []DashboardWidget `json:"widgets"`
 	UserID      uint              `gorm:"index" json:"user_id"`
 	User        User              `json:"user,omitempty"`
-	SharedWith  []User            `gorm:"many2many:dashboard_shares;" json:"shared_with,omitempty"`
+	Shares      []DashboardShare  `json:"shares,omitempty"`
+	Permission  string            `gorm:"-" json:"permission,omitempty"` // the requesting user's access: owner, edit or view
+}
+
+// Dashboard access levels
+const (
+	DashboardPermissionOwner = "owner"
+	DashboardPermissionEdit  = "edit"
+	DashboardPermissionView  = "view"
+)
+
+// DashboardShare gives another user view or edit access to a dashboard
+type DashboardShare struct {
+	DashboardID uint      `gorm:"primaryKey" json:"dashboard_id"`
+	UserID      uint      `gorm:"primaryKey;index" json:"user_id"`
+	User        User      `json:"user,omitempty"`
+	Permission  string    `gorm:"not null" json:"permission"` // view, edit
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 // DashboardWidget represents a widget on a dashboard
@@ -100,18 +196,19 @@ type DashboardWidget struct {
 // PerformanceInsight represents analyzed performance data
 type PerformanceInsight struct {
 	BaseModel
-	Type            string     `gorm:"not null;index" json:"type"` // trend, anomaly, recommendation
-	Severity        string     `gorm:"not null" json:"severity"`   // info, warning, critical
-	Title           string     `gorm:"not null" json:"title"`
-	Description     string     `json:"description"`
-	Category        string     `gorm:"index" json:"category"` // performance, security, resources
-	Source          string     `json:"source"`                // system, nginx, certificate, proxy_host
-	SourceID        *uint      `json:"source_id"`
-	Data            JSON       `gorm:"type:jsonb" json:"data"`
-	Recommendations []string   `gorm:"type:jsonb" json:"recommendations"`
-	IsResolved      bool       `gorm:"default:false" json:"is_resolved"`
-	ResolvedAt      *time.Time `json:"resolved_at"`
-	ViewedBy        []User     `gorm:"many2many:insight_views;" json:"viewed_by,omitempty"`
+	Type            string      `gorm:"not null;index" json:"type"` // trend, anomaly, recommendation
+	Severity        string      `gorm:"not null" json:"severity"`   // info, warning, critical
+	Title           string      `gorm:"not null" json:"title"`
+	Description     string      `json:"description"`
+	Category        string      `gorm:"index" json:"category"` // performance, security, resources
+	Source          string      `json:"source"`                // system, nginx, certificate, proxy_host
+	SourceID        *uint       `json:"source_id"`
+	Data            JSON        `gorm:"type:jsonb" json:"data"`
+	Recommendations StringArray `gorm:"type:jsonb" json:"recommendations"`
+	IsResolved      bool        `gorm:"default:false" json:"is_resolved"`
+	ResolvedAt      *time.Time  `json:"resolved_at"`
+	ViewedBy        []User      `gorm:"many2many:insight_views;" json:"viewed_by,omitempty"`
+	Fingerprint     *string     `gorm:"size:255;uniqueIndex:idx_performance_insights_fingerprint_unique" json:"-"` // identifies the finding, so it is recorded once
 }
 
 // TrafficAnalytics stores aggregated traffic data
@@ -150,6 +247,52 @@ type MetricAggregation struct {
 	StdDev       float64    `json:"std_dev"`
 	Tags         JSON       `gorm:"type:jsonb" json:"tags"`
 	RetentionEnd *time.Time `json:"retention_end"`
+
+	// Running state for incremental updates: the sum of squared values gives
+	// the standard deviation, and the digest the percentiles
+	SumSquares float64          `json:"-"`
+	Digest     PercentileDigest `json:"-" gorm:"type:json"`
+}
+
+// PercentileDigest summarizes the values of an aggregation window as
+// centroids sorted by mean, so percentiles can be estimated as samples arrive
+// without reading the window's raw samples back
+type PercentileDigest []DigestCentroid
+
+// DigestCentroid stands for Count values averaging Mean
+type DigestCentroid struct {
+	Mean  float64 `json:"m"`
+	Count float64 `json:"n"`
+}
+
+// Scan implements sql.Scanner interface
+func (d *PercentileDigest) Scan(value interface{}) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into PercentileDigest", value)
+	}
+
+	if len(bytes) == 0 {
+		*d = nil
+		return nil
+	}
+	return json.Unmarshal(bytes, d)
+}
+
+// Value implements driver.Valuer interface
+func (d PercentileDigest) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(d)
 }
 
 // Methods for HistoricalMetric
@@ -165,6 +308,38 @@ func (hm *HistoricalMetric) SetRetention(duration time.Duration) {
 	hm.RetentionEnd = &retentionEnd
 }
 
+// ToRawMetric converts the metric to the lean time-series representation
+func (hm *HistoricalMetric) ToRawMetric() *RawMetric {
+	return &RawMetric{
+		Timestamp:  hm.Timestamp,
+		MetricType: hm.MetricType,
+		MetricName: hm.MetricName,
+		Value:      hm.Value,
+		Source:     hm.Source,
+		SourceID:   hm.SourceID,
+		Tags:       hm.Tags,
+	}
+}
+
+// Methods for RawMetric
+
+// ToHistoricalMetric converts a raw sample back to the API representation
+func (rm *RawMetric) ToHistoricalMetric() HistoricalMetric {
+	metric := HistoricalMetric{
+		Timestamp:  rm.Timestamp,
+		MetricType: rm.MetricType,
+		MetricName: rm.MetricName,
+		Value:      rm.Value,
+		Tags:       rm.Tags,
+		Source:     rm.Source,
+		SourceID:   rm.SourceID,
+	}
+	metric.ID = uint(rm.ID)
+	metric.CreatedAt = rm.Timestamp
+	metric.UpdatedAt = rm.Timestamp
+	return metric
+}
+
 // Methods for AlertRule
 func (ar *AlertRule) BeforeCreate(tx *gorm.DB) error {
 	if ar.EvaluationWindow == 0 {
@@ -193,6 +368,80 @@ func (ar *AlertRule) EvaluateCondition(value float64) bool {
 	}
 }
 
+// IsRateOfChange returns true if the rule compares percent change over the evaluation window
+func (ar *AlertRule) IsRateOfChange() bool {
+	return ar.Condition == "change_gt" || ar.Condition == "change_lt"
+}
+
+// EvaluateChange compares the percent change between the oldest and latest
+// values in the window against the threshold
+func (ar *AlertRule) EvaluateChange(oldest, latest float64) (float64, bool) {
+	if oldest == 0 {
+		return 0, false
+	}
+
+	change := (latest - oldest) / math.Abs(oldest) * 100
+
+	switch ar.Condition {
+	case "change_gt":
+		return change, change > ar.Threshold
+	case "change_lt":
+		return change, change < ar.Threshold
+	default:
+		return change, false
+	}
+}
+
+// EvaluateSustained returns true only if every sample in the window breaches the condition
+func (ar *AlertRule) EvaluateSustained(values []float64) bool {
+	if len(values) == 0 {
+		return false
+	}
+
+	for _, value := range values {
+		if !ar.EvaluateCondition(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Methods for Silence
+func (s *Silence) IsActive(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// Matches returns true if the silence applies to the rule and tags. Matchers
+// are compared against the rule's tags first, then the metric's tags.
+func (s *Silence) Matches(rule *AlertRule, metricTags JSON) bool {
+	if s.AlertRuleID != nil && *s.AlertRuleID != rule.ID {
+		return false
+	}
+
+	for key, expected := range s.Matchers {
+		value, ok := rule.Tags[key]
+		if !ok {
+			value, ok = metricTags[key]
+		}
+		if !ok || fmt.Sprint(value) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesMetric reports whether a metric sample's tags satisfy the rule's
+// metric filter. Rules without a filter match every sample.
+func (ar *AlertRule) MatchesMetric(metricTags JSON) bool {
+	for key, expected := range ar.MetricFilter {
+		value, ok := metricTags[key]
+		if !ok || fmt.Sprint(value) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+	return true
+}
+
 // Methods for Dashboard
 func (d *Dashboard) BeforeCreate(tx *gorm.DB) error {
 	// No specific logic needed for Dashboard creation
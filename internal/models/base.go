@@ -48,6 +48,37 @@ func (j JSON) Value() (driver.Value, error) {
 	return json.Marshal(j)
 }
 
+// Clone returns a deep copy of j, so nested maps and slices are not shared
+// with the original
+func (j JSON) Clone() JSON {
+	if j == nil {
+		return nil
+	}
+	return JSON(cloneJSONValue(map[string]interface{}(j)).(map[string]interface{}))
+}
+
+// cloneJSONValue deep-copies the maps and slices of a decoded JSON value
+func cloneJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = cloneJSONValue(item)
+		}
+		return copied
+	case JSON:
+		return v.Clone()
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = cloneJSONValue(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
 // StringArray type for storing string arrays in database
 type StringArray []string
 
@@ -58,8 +89,14 @@ func (sa *StringArray) Scan(value interface{}) error {
 		return nil
 	}
 
-	bytes, ok := value.([]byte)
-	if !ok {
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		// Value stores an empty array as the string "[]"
+		bytes = []byte(v)
+	default:
 		return fmt.Errorf("cannot scan %T into StringArray", value)
 	}
 
@@ -79,23 +116,6 @@ func (sa StringArray) Value() (driver.Value, error) {
 	return json.Marshal(sa)
 }
 
-// Role represents user roles
-type Role string
-
-const (
-	RoleAdmin Role = "admin"
-	RoleUser  Role = "user"
-)
-
-// IsValid checks if the role is valid
-func (r Role) IsValid() bool {
-	switch r {
-	case RoleAdmin, RoleUser:
-		return true
-	}
-	return false
-}
-
 // CertificateProvider represents certificate providers
 type CertificateProvider string
 
@@ -173,12 +193,22 @@ const (
 	ActionDeleted AuditAction = "deleted"
 	ActionLogin   AuditAction = "login"
 	ActionLogout  AuditAction = "logout"
+
+	ActionTransferred AuditAction = "transferred"
+	ActionExported    AuditAction = "exported"
+
+	ActionImpersonated        AuditAction = "impersonated"
+	ActionImpersonatedRequest AuditAction = "impersonated_request"
+
+	ActionRestored AuditAction = "restored"
+	ActionPurged   AuditAction = "purged"
 )
 
 // IsValid checks if the audit action is valid
 func (aa AuditAction) IsValid() bool {
 	switch aa {
-	case ActionCreated, ActionUpdated, ActionDeleted, ActionLogin, ActionLogout:
+	case ActionCreated, ActionUpdated, ActionDeleted, ActionLogin, ActionLogout, ActionTransferred,
+		ActionExported, ActionImpersonated, ActionImpersonatedRequest, ActionRestored, ActionPurged:
 		return true
 	}
 	return false
@@ -198,6 +228,8 @@ const (
 	ObjectTypeSetting         ObjectType = "setting"
 	ObjectTypeNginxConfig     ObjectType = "nginx_config"
 	ObjectTypeConfigTemplate  ObjectType = "config_template"
+	ObjectTypeAlertRule       ObjectType = "alert_rule"
+	ObjectTypeBackup          ObjectType = "backup"
 )
 
 // IsValid checks if the object type is valid
@@ -206,7 +238,7 @@ func (ot ObjectType) IsValid() bool {
 	case ObjectTypeUser, ObjectTypeProxyHost, ObjectTypeCertificate,
 		ObjectTypeAccessList, ObjectTypeRedirectionHost, ObjectTypeStream,
 		ObjectTypeDeadHost, ObjectTypeSetting, ObjectTypeNginxConfig,
-		ObjectTypeConfigTemplate:
+		ObjectTypeConfigTemplate, ObjectTypeAlertRule, ObjectTypeBackup:
 		return true
 	}
 	return false
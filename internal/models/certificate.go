@@ -66,6 +66,13 @@ func (c *Certificate) IsLetsEncrypt() bool {
 	return c.Provider == ProviderLetsEncrypt
 }
 
+// IsStaging reports whether the certificate was issued by the Let's Encrypt
+// staging environment, whose certificates browsers do not trust
+func (c *Certificate) IsStaging() bool {
+	staging, _ := c.GetMetaValue("staging").(bool)
+	return c.IsLetsEncrypt() && staging
+}
+
 // IsCustom checks if this is a custom certificate
 func (c *Certificate) IsCustom() bool {
 	return c.Provider == ProviderCustom
@@ -0,0 +1,179 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ErrDomainInUse is reported when a domain already belongs to another host
+var ErrDomainInUse = errors.ErrDomainDuplicate
+
+// HostDomain indexes one domain of a live proxy, dead or redirection host.
+// Domains are stored lowercased under a unique index, so each belongs to at
+// most one host. Rows are hard-deleted with their host, so unlike BaseModel
+// there is no deleted_at to keep a soft-deleted host's domains reserved.
+type HostDomain struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Domain    string    `json:"domain" gorm:"uniqueIndex;size:255;not null"`
+	HostType  string    `json:"host_type" gorm:"size:50;not null;index:idx_host_domains_host"`
+	HostID    uint      `json:"host_id" gorm:"not null;index:idx_host_domains_host"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for HostDomain model
+func (HostDomain) TableName() string {
+	return "host_domains"
+}
+
+// NormalizeDomain lowercases and trims a domain for indexing
+func NormalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSpace(domain))
+}
+
+// NormalizeDomains normalizes domains, dropping blanks and duplicates
+func NormalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	seen := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		domain = NormalizeDomain(domain)
+		if domain != "" && !seen[domain] {
+			seen[domain] = true
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
+
+// SyncHostDomains makes the indexed domains of a host match domains,
+// returning ErrDomainInUse when another host already holds one of them
+func SyncHostDomains(tx *gorm.DB, hostType string, hostID uint, domains []string) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	normalized := NormalizeDomains(domains)
+
+	if len(normalized) > 0 {
+		var taken []string
+		if err := db.Model(&HostDomain{}).
+			Where("domain IN ? AND NOT (host_type = ? AND host_id = ?)", normalized, hostType, hostID).
+			Pluck("domain", &taken).Error; err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return fmt.Errorf("%w: %s", ErrDomainInUse, taken[0])
+		}
+	}
+
+	var existing []string
+	if err := db.Model(&HostDomain{}).
+		Where("host_type = ? AND host_id = ?", hostType, hostID).
+		Pluck("domain", &existing).Error; err != nil {
+		return err
+	}
+
+	var removed []string
+	for _, domain := range existing {
+		if !containsDomain(normalized, domain) {
+			removed = append(removed, domain)
+		}
+	}
+	if len(removed) > 0 {
+		if err := db.Where("host_type = ? AND host_id = ? AND domain IN ?", hostType, hostID, removed).
+			Delete(&HostDomain{}).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, domain := range normalized {
+		if containsDomain(existing, domain) {
+			continue
+		}
+		if err := db.Create(&HostDomain{Domain: domain, HostType: hostType, HostID: hostID}).Error; err != nil {
+			return fmt.Errorf("failed to index domain %s: %w", domain, err)
+		}
+	}
+	return nil
+}
+
+// RemoveHostDomains drops the indexed domains of a host
+func RemoveHostDomains(tx *gorm.DB, hostType string, hostID uint) error {
+	return tx.Session(&gorm.Session{NewDB: true}).
+		Where("host_type = ? AND host_id = ?", hostType, hostID).
+		Delete(&HostDomain{}).Error
+}
+
+func containsDomain(domains []string, domain string) bool {
+	for _, d := range domains {
+		if d == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// syncHostDomainsOnSave indexes a host's domains after it is created or
+// saved. Partial updates that do not carry the domain list, such as toggling
+// enabled through Model(&ProxyHost{}).Update, leave the index untouched, as
+// do updates to soft-deleted hosts.
+func syncHostDomainsOnSave(tx *gorm.DB, host *BaseModel, hostType string, domains StringArray) error {
+	if host.ID == 0 || host.DeletedAt.Valid || len(domains) == 0 {
+		return nil
+	}
+	return SyncHostDomains(tx, hostType, host.ID, domains)
+}
+
+// removeHostDomainsOnDelete frees a deleted host's domains. Soft deletes count:
+// a soft-deleted host never blocks reuse of its domains.
+func removeHostDomainsOnDelete(tx *gorm.DB, hostType string, hostID uint) error {
+	if hostID == 0 {
+		return nil
+	}
+	return RemoveHostDomains(tx, hostType, hostID)
+}
+
+// AfterCreate indexes the proxy host's domains
+func (p *ProxyHost) AfterCreate(tx *gorm.DB) error {
+	return syncHostDomainsOnSave(tx, &p.BaseModel, p.TableName(), p.DomainNames)
+}
+
+// AfterUpdate re-indexes the proxy host's domains
+func (p *ProxyHost) AfterUpdate(tx *gorm.DB) error {
+	return syncHostDomainsOnSave(tx, &p.BaseModel, p.TableName(), p.DomainNames)
+}
+
+// AfterDelete frees the proxy host's domains
+func (p *ProxyHost) AfterDelete(tx *gorm.DB) error {
+	return removeHostDomainsOnDelete(tx, p.TableName(), p.ID)
+}
+
+// AfterCreate indexes the dead host's domains
+func (d *DeadHost) AfterCreate(tx *gorm.DB) error {
+	return syncHostDomainsOnSave(tx, &d.BaseModel, d.TableName(), d.DomainNames)
+}
+
+// AfterUpdate re-indexes the dead host's domains
+func (d *DeadHost) AfterUpdate(tx *gorm.DB) error {
+	return syncHostDomainsOnSave(tx, &d.BaseModel, d.TableName(), d.DomainNames)
+}
+
+// AfterDelete frees the dead host's domains
+func (d *DeadHost) AfterDelete(tx *gorm.DB) error {
+	return removeHostDomainsOnDelete(tx, d.TableName(), d.ID)
+}
+
+// AfterCreate indexes the redirection host's domains
+func (r *RedirectionHost) AfterCreate(tx *gorm.DB) error {
+	return syncHostDomainsOnSave(tx, &r.BaseModel, r.TableName(), r.DomainNames)
+}
+
+// AfterUpdate re-indexes the redirection host's domains
+func (r *RedirectionHost) AfterUpdate(tx *gorm.DB) error {
+	return syncHostDomainsOnSave(tx, &r.BaseModel, r.TableName(), r.DomainNames)
+}
+
+// AfterDelete frees the redirection host's domains
+func (r *RedirectionHost) AfterDelete(tx *gorm.DB) error {
+	return removeHostDomainsOnDelete(tx, r.TableName(), r.ID)
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // ConfigType represents the type of nginx configuration
@@ -82,6 +84,11 @@ type NginxConfig struct {
 	ValidationTime time.Time `json:"validation_time"`
 	ValidationLogs string    `json:"validation_logs" gorm:"type:text"`
 
+	// Deployment tracking
+	ContentUpdatedAt  time.Time  `json:"content_updated_at"`
+	LastDeployedAt    *time.Time `json:"last_deployed_at,omitempty"`
+	HasPendingChanges bool       `json:"has_pending_changes" gorm:"-"`
+
 	// Relationships
 	User     User            `json:"user" gorm:"foreignKey:UserID"`
 	Versions []ConfigVersion `json:"versions" gorm:"foreignKey:ConfigID"`
@@ -93,6 +100,19 @@ type NginxConfig struct {
 	TemplateVars     JSON            `json:"template_vars" gorm:"type:jsonb"`
 }
 
+// PendingChanges reports whether the content or file path changed after the
+// configuration was last deployed. Configurations that were never deployed
+// have nothing pending.
+func (c *NginxConfig) PendingChanges() bool {
+	return c.LastDeployedAt != nil && c.ContentUpdatedAt.After(*c.LastDeployedAt)
+}
+
+// AfterFind fills in HasPendingChanges for loaded configurations
+func (c *NginxConfig) AfterFind(tx *gorm.DB) error {
+	c.HasPendingChanges = c.PendingChanges()
+	return nil
+}
+
 // ConfigVersion represents a version of a configuration
 type ConfigVersion struct {
 	BaseModel
@@ -116,7 +136,7 @@ type ConfigBackup struct {
 	Content    string `json:"content" gorm:"type:text"`
 	FilePath   string `json:"file_path"` // Path to backup file
 	Reason     string `json:"reason"`    // Reason for backup
-	AutoBackup bool   `json:"auto_backup" gorm:"default:true"`
+	AutoBackup bool   `json:"auto_backup"`
 	CreatedBy  uint   `json:"created_by" gorm:"not null"`
 
 	// Relationships
@@ -142,6 +162,20 @@ type ConfigTemplate struct {
 	Configs []NginxConfig `json:"configs" gorm:"foreignKey:TemplateID"`
 }
 
+// TemplateVersion represents a version of a template's content and variables
+type TemplateVersion struct {
+	BaseModel
+	TemplateID uint   `json:"template_id" gorm:"not null;index"`
+	Version    int    `json:"version" gorm:"not null"`
+	Content    string `json:"content" gorm:"type:text"`
+	Variables  JSON   `json:"variables" gorm:"type:jsonb"`
+	Comment    string `json:"comment"`
+	CreatedBy  uint   `json:"created_by" gorm:"not null"`
+
+	// Relationships
+	CreatedByUser User `json:"created_by_user" gorm:"foreignKey:CreatedBy"`
+}
+
 // ConfigApproval represents configuration change approval workflow
 type ConfigApproval struct {
 	BaseModel
@@ -198,6 +232,11 @@ func (ConfigTemplate) TableName() string {
 	return "config_templates"
 }
 
+// TableName returns the table name for TemplateVersion
+func (TemplateVersion) TableName() string {
+	return "template_versions"
+}
+
 // TableName returns the table name for ConfigApproval
 func (ConfigApproval) TableName() string {
 	return "config_approvals"
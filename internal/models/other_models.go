@@ -8,9 +8,9 @@ type Stream struct {
 	IncomingPort   int    `json:"incoming_port" gorm:"not null;uniqueIndex"`
 	ForwardingHost string `json:"forwarding_host" gorm:"size:255;not null"`
 	ForwardingPort int    `json:"forwarding_port" gorm:"not null"`
-	TCP            bool   `json:"tcp" gorm:"default:true"`
+	TCP            bool   `json:"tcp"`
 	UDP            bool   `json:"udp" gorm:"default:false"`
-	Enabled        bool   `json:"enabled" gorm:"default:true"`
+	Enabled        bool   `json:"enabled"`
 	CertificateID  *uint  `json:"certificate_id" gorm:"index"`
 	SSLTermination bool   `json:"ssl_termination" gorm:"default:false"`
 	UserID         uint   `json:"user_id" gorm:"not null;index"`
@@ -32,9 +32,10 @@ type RedirectionHost struct {
 	ForwardScheme     string      `json:"forward_scheme" gorm:"size:10;not null"`
 	ForwardDomainName string      `json:"forward_domain_name" gorm:"size:255;not null"`
 	StatusCode        int         `json:"status_code" gorm:"default:301"`
-	PreservePath      bool        `json:"preserve_path" gorm:"default:true"`
-	Enabled           bool        `json:"enabled" gorm:"default:true"`
+	PreservePath      bool        `json:"preserve_path"`
+	Enabled           bool        `json:"enabled"`
 	CertificateID     *uint       `json:"certificate_id" gorm:"index"`
+	SSLForced         bool        `json:"ssl_forced" gorm:"default:false"`
 	AdvancedConfig    string      `json:"advanced_config" gorm:"type:text"`
 	Meta              JSON        `json:"meta" gorm:"type:json"`
 	UserID            uint        `json:"user_id" gorm:"not null;index"`
@@ -54,7 +55,9 @@ type DeadHost struct {
 	BaseModel
 	DomainNames   StringArray `json:"domain_names" gorm:"type:text"`
 	CertificateID *uint       `json:"certificate_id" gorm:"index"`
-	Enabled       bool        `json:"enabled" gorm:"default:true"`
+	SSLForced     bool        `json:"ssl_forced" gorm:"default:false"`
+	CustomPage    string      `json:"custom_page" gorm:"type:text"` // HTML served with the 404, empty for the nginx default
+	Enabled       bool        `json:"enabled"`
 	Meta          JSON        `json:"meta" gorm:"type:json"`
 	UserID        uint        `json:"user_id" gorm:"not null;index"`
 
@@ -89,6 +92,22 @@ func (AuditLog) TableName() string {
 	return "audit_logs"
 }
 
+// ActivityEvent is a lifecycle event shown in the monitoring activity feed
+type ActivityEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Timestamp time.Time `json:"timestamp" gorm:"not null;index"`
+	Type      string    `json:"type" gorm:"size:32;not null;index"`  // proxy_host, certificate, nginx_config
+	Level     string    `json:"level" gorm:"size:16;not null;index"` // info, warning, error
+	Message   string    `json:"message" gorm:"type:text"`
+	Details   JSON      `json:"details" gorm:"type:json"`
+	UserID    *uint     `json:"user_id" gorm:"index"` // nil for system events visible to every user
+}
+
+// TableName specifies the table name for ActivityEvent model
+func (ActivityEvent) TableName() string {
+	return "activity_events"
+}
+
 // Token represents an API token
 type Token struct {
 	BaseModel
@@ -107,13 +126,36 @@ func (Token) TableName() string {
 	return "tokens"
 }
 
-// Setting represents system settings
+// Setting represents system settings. The value is kept under Value["value"]
+// and must match Type; secret values are never returned by the API.
 type Setting struct {
 	BaseModel
-	ID    string `json:"id" gorm:"primaryKey;size:255"`
-	Name  string `json:"name" gorm:"size:255;not null"`
-	Value JSON   `json:"value" gorm:"type:json"`
-	Meta  JSON   `json:"meta" gorm:"type:json"`
+	ID          string      `json:"id" gorm:"primaryKey;size:255"`
+	Name        string      `json:"name" gorm:"size:255;not null"`
+	Description string      `json:"description" gorm:"size:500"`
+	Type        SettingType `json:"type" gorm:"size:20;default:'string'"`
+	IsSecret    bool        `json:"is_secret" gorm:"default:false"`
+	Value       JSON        `json:"value" gorm:"type:json"`
+	Meta        JSON        `json:"meta" gorm:"type:json"`
+}
+
+// SettingType is the type a setting's value must have
+type SettingType string
+
+const (
+	SettingTypeString SettingType = "string"
+	SettingTypeInt    SettingType = "int"
+	SettingTypeBool   SettingType = "bool"
+	SettingTypeJSON   SettingType = "json"
+)
+
+// IsValid checks if the setting type is valid
+func (t SettingType) IsValid() bool {
+	switch t {
+	case SettingTypeString, SettingTypeInt, SettingTypeBool, SettingTypeJSON:
+		return true
+	}
+	return false
 }
 
 // TableName specifies the table name for Setting model
@@ -134,6 +176,31 @@ func (s *Stream) IsUDPEnabled() bool {
 	return s.UDP
 }
 
+// Stream protocols, stored as the TCP and UDP flags
+const (
+	StreamProtocolTCP  = "tcp"
+	StreamProtocolUDP  = "udp"
+	StreamProtocolBoth = "both"
+)
+
+// GetProtocol returns the stream protocol derived from the TCP and UDP flags
+func (s *Stream) GetProtocol() string {
+	switch {
+	case s.TCP && s.UDP:
+		return StreamProtocolBoth
+	case s.UDP:
+		return StreamProtocolUDP
+	default:
+		return StreamProtocolTCP
+	}
+}
+
+// SetProtocol sets the TCP and UDP flags from a protocol name
+func (s *Stream) SetProtocol(protocol string) {
+	s.TCP = protocol == StreamProtocolTCP || protocol == StreamProtocolBoth
+	s.UDP = protocol == StreamProtocolUDP || protocol == StreamProtocolBoth
+}
+
 // Helper methods for RedirectionHost
 func (r *RedirectionHost) GetPrimaryDomain() string {
 	if len(r.DomainNames) > 0 {
@@ -158,6 +225,10 @@ func (d *DeadHost) IsSSLEnabled() bool {
 	return d.CertificateID != nil && *d.CertificateID > 0
 }
 
+func (d *DeadHost) HasCustomPage() bool {
+	return d.CustomPage != ""
+}
+
 // Helper methods for Token
 func (t *Token) IsExpired() bool {
 	if t.ExpiresAt == nil {
@@ -186,3 +257,38 @@ func (s *Setting) SetValue(value interface{}) {
 	}
 	s.Value["value"] = value
 }
+
+// IntValue returns the value as an int. JSON numbers decode as float64, so
+// only whole numbers are accepted.
+func (s *Setting) IntValue() (int, bool) {
+	switch v := s.GetValue().(type) {
+	case int:
+		return v, true
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), true
+		}
+	}
+	return 0, false
+}
+
+// BoolValue returns the value as a bool
+func (s *Setting) BoolValue() (bool, bool) {
+	v, ok := s.GetValue().(bool)
+	return v, ok
+}
+
+// StringValue returns the value as a string
+func (s *Setting) StringValue() (string, bool) {
+	v, ok := s.GetValue().(string)
+	return v, ok
+}
+
+// ClearSensitiveData hides a secret value, recording only whether one is set
+func (s *Setting) ClearSensitiveData() {
+	if !s.IsSecret {
+		return
+	}
+	value := s.GetValue()
+	s.Value = JSON{"value": nil, "is_set": value != nil && value != ""}
+}
@@ -1,26 +1,50 @@
 package models
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
 // ProxyHost represents a proxy host configuration
 type ProxyHost struct {
 	BaseModel
-	DomainNames           StringArray   `json:"domain_names" gorm:"type:text"`
-	ForwardScheme         ForwardScheme `json:"forward_scheme" gorm:"size:10;not null"`
-	ForwardHost           string        `json:"forward_host" gorm:"size:255;not null"`
-	ForwardPort           int           `json:"forward_port" gorm:"not null"`
-	AccessListID          *uint         `json:"access_list_id" gorm:"index"`
-	CertificateID         *uint         `json:"certificate_id" gorm:"index"`
-	SSLForced             bool          `json:"ssl_forced" gorm:"default:false"`
-	CachingEnabled        bool          `json:"caching_enabled" gorm:"default:false"`
-	BlockExploits         bool          `json:"block_exploits" gorm:"default:true"`
-	AllowWebsocketUpgrade bool          `json:"allow_websocket_upgrade" gorm:"default:false"`
-	HTTP2Support          bool          `json:"http2_support" gorm:"default:true"`
-	HSTSEnabled           bool          `json:"hsts_enabled" gorm:"default:false"`
-	HSTSSubdomains        bool          `json:"hsts_subdomains" gorm:"default:false"`
-	AdvancedConfig        string        `json:"advanced_config" gorm:"type:text"`
-	Enabled               bool          `json:"enabled" gorm:"default:true"`
-	Locations             JSON          `json:"locations" gorm:"type:json"`
-	Meta                  JSON          `json:"meta" gorm:"type:json"`
-	UserID                uint          `json:"user_id" gorm:"not null;index"`
+	DomainNames           StringArray        `json:"domain_names" gorm:"type:text"`
+	ForwardScheme         ForwardScheme      `json:"forward_scheme" gorm:"size:10;not null"`
+	ForwardHost           string             `json:"forward_host" gorm:"size:255;not null"`
+	ForwardPort           int                `json:"forward_port" gorm:"not null"`
+	AccessListID          *uint              `json:"access_list_id" gorm:"index"`
+	CertificateID         *uint              `json:"certificate_id" gorm:"index"`
+	SSLForced             bool               `json:"ssl_forced" gorm:"default:false"`
+	CachingEnabled        bool               `json:"caching_enabled" gorm:"default:false"`
+	BlockExploits         bool               `json:"block_exploits"`
+	AllowWebsocketUpgrade bool               `json:"allow_websocket_upgrade" gorm:"default:false"`
+	HTTP2Support          bool               `json:"http2_support"`
+	HSTSEnabled           bool               `json:"hsts_enabled" gorm:"default:false"`
+	HSTSSubdomains        bool               `json:"hsts_subdomains" gorm:"default:false"`
+	OCSPStapling          bool               `json:"ocsp_stapling" gorm:"default:false"`
+	AdvancedConfig        string             `json:"advanced_config" gorm:"type:text"`
+	ProxyBind             string             `json:"proxy_bind" gorm:"size:45"`             // outgoing source IP for upstream connections
+	ProxyBuffering        *bool              `json:"proxy_buffering"`                       // nil buffers unless websocket upgrades are allowed
+	ProxyBufferSize       string             `json:"proxy_buffer_size" gorm:"size:16"`      // e.g. "16k"
+	ProxyBuffers          string             `json:"proxy_buffers" gorm:"size:32"`          // e.g. "8 16k"
+	UpstreamKeepalive     int                `json:"upstream_keepalive" gorm:"default:0"`   // idle upstream connections kept per worker, 0 disables
+	RateLimit             *RateLimitConfig   `json:"rate_limit,omitempty" gorm:"type:json"` // nil disables rate limiting
+	Enabled               bool               `json:"enabled"`
+	PendingChanges        bool               `json:"pending_changes" gorm:"default:false;index"` // edited but not yet deployed
+	HealthCheck           *HealthCheckConfig `json:"health_check,omitempty" gorm:"type:json"`    // nil probes with the server defaults
+	LastHealthStatus      string             `json:"last_health_status" gorm:"size:16"`          // healthy or unhealthy, empty until probed
+	LastCheckedAt         *time.Time         `json:"last_checked_at"`
+	Locations             JSON               `json:"locations" gorm:"type:json"`
+	Meta                  JSON               `json:"meta" gorm:"type:json"`
+	UserID                uint               `json:"user_id" gorm:"not null;index"`
+
+	// CertificateWarnings lists problems with the selected certificate, such
+	// as domains it does not cover, found when the host was saved
+	CertificateWarnings []string `json:"certificate_warnings,omitempty" gorm:"-"`
 
 	// Relationships
 	User        User         `json:"user,omitempty" gorm:"foreignKey:UserID"`
@@ -28,6 +52,64 @@ type ProxyHost struct {
 	Certificate *Certificate `json:"certificate,omitempty" gorm:"foreignKey:CertificateID"`
 }
 
+// RateLimitConfig limits the request rate of each client address to a proxy host
+type RateLimitConfig struct {
+	RequestsPerSecond int  `json:"requests_per_second"`
+	Burst             int  `json:"burst"`   // requests queued above the rate before rejecting
+	NoDelay           bool `json:"nodelay"` // serve burst requests without spacing them out
+}
+
+// Upstream health statuses recorded by the health check probe
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// HealthCheckConfig tunes the upstream health probe of a proxy host. Zero
+// values use the server defaults.
+type HealthCheckConfig struct {
+	Disabled bool   `json:"disabled"`
+	Path     string `json:"path"`     // request path, "/" by default
+	Interval int    `json:"interval"` // seconds between probes
+	Timeout  int    `json:"timeout"`  // seconds to wait for a response
+}
+
+// Scan implements sql.Scanner interface
+func (h *HealthCheckConfig) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		if str, isString := value.(string); isString {
+			bytes = []byte(str)
+		} else {
+			return fmt.Errorf("cannot scan %T into HealthCheckConfig", value)
+		}
+	}
+	return json.Unmarshal(bytes, h)
+}
+
+// Value implements driver.Valuer interface
+func (h HealthCheckConfig) Value() (driver.Value, error) {
+	return json.Marshal(h)
+}
+
+// Scan implements sql.Scanner interface
+func (r *RateLimitConfig) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		if str, isString := value.(string); isString {
+			bytes = []byte(str)
+		} else {
+			return fmt.Errorf("cannot scan %T into RateLimitConfig", value)
+		}
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+// Value implements driver.Valuer interface
+func (r RateLimitConfig) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
 // TableName specifies the table name for ProxyHost model
 func (ProxyHost) TableName() string {
 	return "proxy_hosts"
@@ -68,6 +150,57 @@ func (p *ProxyHost) RemoveDomain(domain string) {
 	}
 }
 
+// IsProxyBufferingEnabled reports whether upstream responses are buffered.
+// Unless set explicitly, buffering is off for websocket hosts so streamed
+// responses (websockets, SSE) reach the client immediately.
+func (p *ProxyHost) IsProxyBufferingEnabled() bool {
+	if p.ProxyBuffering != nil {
+		return *p.ProxyBuffering
+	}
+	return !p.AllowWebsocketUpgrade
+}
+
+// UsesNamedUpstream reports whether the host proxies through a generated
+// upstream block, which nginx needs to keep upstream connections alive
+func (p *ProxyHost) UsesNamedUpstream() bool {
+	return p.UpstreamKeepalive > 0
+}
+
+// GetUpstreamName returns the name of the host's generated upstream block
+func (p *ProxyHost) GetUpstreamName() string {
+	return "proxy_host_" + strconv.FormatUint(uint64(p.ID), 10)
+}
+
+// GetUpstreamServer returns the forward target as an upstream server address
+func (p *ProxyHost) GetUpstreamServer() string {
+	return net.JoinHostPort(p.ForwardHost, strconv.Itoa(p.ForwardPort))
+}
+
+// HasRateLimit reports whether requests to the host are rate limited
+func (p *ProxyHost) HasRateLimit() bool {
+	return p.RateLimit != nil
+}
+
+// GetRateLimitZoneName returns the name of the host's limit_req zone, unique
+// per host since all zones share the http context
+func (p *ProxyHost) GetRateLimitZoneName() string {
+	return "proxy_host_" + strconv.FormatUint(uint64(p.ID), 10) + "_limit"
+}
+
+// IsHealthCheckEnabled reports whether the host's upstream is probed
+func (p *ProxyHost) IsHealthCheckEnabled() bool {
+	return p.Enabled && (p.HealthCheck == nil || !p.HealthCheck.Disabled)
+}
+
+// GetHealthCheckURL returns the URL the health check probe requests
+func (p *ProxyHost) GetHealthCheckURL() string {
+	path := "/"
+	if p.HealthCheck != nil && p.HealthCheck.Path != "" {
+		path = p.HealthCheck.Path
+	}
+	return string(p.ForwardScheme) + "://" + p.GetUpstreamServer() + path
+}
+
 // IsSSLEnabled checks if SSL is enabled for this proxy host
 func (p *ProxyHost) IsSSLEnabled() bool {
 	return p.CertificateID != nil && *p.CertificateID > 0
@@ -75,7 +208,7 @@ func (p *ProxyHost) IsSSLEnabled() bool {
 
 // GetTargetURL returns the target URL for proxying
 func (p *ProxyHost) GetTargetURL() string {
-	return string(p.ForwardScheme) + "://" + p.ForwardHost + ":" + string(rune(p.ForwardPort))
+	return string(p.ForwardScheme) + "://" + p.ForwardHost + ":" + strconv.Itoa(p.ForwardPort)
 }
 
 // HasAccessList checks if an access list is configured
@@ -0,0 +1,171 @@
+package models
+
+import "strings"
+
+// RoleName identifies a role; users hold role names in User.Roles
+type RoleName string
+
+const (
+	RoleAdmin    RoleName = "admin"
+	RoleOperator RoleName = "operator"
+	RoleUser     RoleName = "user"
+	RoleViewer   RoleName = "viewer"
+)
+
+// IsValid checks if the role is one of the built-in roles
+func (r RoleName) IsValid() bool {
+	switch r {
+	case RoleAdmin, RoleOperator, RoleUser, RoleViewer:
+		return true
+	}
+	return false
+}
+
+// Role is a named set of permissions assigned to users by name
+type Role struct {
+	BaseModel
+	Name        string       `json:"name" gorm:"uniqueIndex;size:50;not null"`
+	Description string       `json:"description" gorm:"size:255"`
+	IsSystem    bool         `json:"is_system" gorm:"default:false"` // seeded roles, kept in sync on startup
+	Permissions []Permission `json:"permissions,omitempty" gorm:"many2many:role_permissions"`
+}
+
+// TableName specifies the table name for Role model
+func (Role) TableName() string {
+	return "roles"
+}
+
+// HasPermission checks if the role grants a permission
+func (r *Role) HasPermission(name string) bool {
+	for _, permission := range r.Permissions {
+		if permission.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Permission is a single "resource:action" capability. The read, write and
+// delete actions cover a user's own resources; read_all and manage extend
+// reading and changing to resources owned by other users.
+type Permission struct {
+	BaseModel
+	Name        string `json:"name" gorm:"uniqueIndex;size:100;not null"`
+	Description string `json:"description" gorm:"size:255"`
+}
+
+// TableName specifies the table name for Permission model
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+// Permissions checked by services and routes
+const (
+	PermissionProxyHostRead    = "proxy_host:read"
+	PermissionProxyHostReadAll = "proxy_host:read_all"
+	PermissionProxyHostWrite   = "proxy_host:write"
+	PermissionProxyHostDelete  = "proxy_host:delete"
+	PermissionProxyHostManage  = "proxy_host:manage"
+
+	PermissionCertificateRead    = "certificate:read"
+	PermissionCertificateReadAll = "certificate:read_all"
+	PermissionCertificateWrite   = "certificate:write"
+	PermissionCertificateDelete  = "certificate:delete"
+	PermissionCertificateManage  = "certificate:manage"
+
+	PermissionAccessListRead    = "access_list:read"
+	PermissionAccessListReadAll = "access_list:read_all"
+	PermissionAccessListWrite   = "access_list:write"
+	PermissionAccessListDelete  = "access_list:delete"
+	PermissionAccessListManage  = "access_list:manage"
+
+	PermissionNginxConfigRead    = "nginx_config:read"
+	PermissionNginxConfigReadAll = "nginx_config:read_all"
+	PermissionNginxConfigWrite   = "nginx_config:write"
+	PermissionNginxConfigDelete  = "nginx_config:delete"
+	PermissionNginxConfigManage  = "nginx_config:manage"
+
+	PermissionTemplateRead    = "template:read"
+	PermissionTemplateReadAll = "template:read_all"
+	PermissionTemplateWrite   = "template:write"
+	PermissionTemplateDelete  = "template:delete"
+	PermissionTemplateManage  = "template:manage"
+
+	PermissionDeadHostRead    = "dead_host:read"
+	PermissionDeadHostReadAll = "dead_host:read_all"
+	PermissionDeadHostWrite   = "dead_host:write"
+	PermissionDeadHostDelete  = "dead_host:delete"
+	PermissionDeadHostManage  = "dead_host:manage"
+
+	PermissionRedirectionHostRead    = "redirection_host:read"
+	PermissionRedirectionHostReadAll = "redirection_host:read_all"
+	PermissionRedirectionHostWrite   = "redirection_host:write"
+	PermissionRedirectionHostDelete  = "redirection_host:delete"
+	PermissionRedirectionHostManage  = "redirection_host:manage"
+
+	PermissionStreamRead    = "stream:read"
+	PermissionStreamReadAll = "stream:read_all"
+	PermissionStreamWrite   = "stream:write"
+	PermissionStreamDelete  = "stream:delete"
+	PermissionStreamManage  = "stream:manage"
+)
+
+// AllPermissions lists every permission with its description
+func AllPermissions() []Permission {
+	var permissions []Permission
+	add := func(name, description string) {
+		permissions = append(permissions, Permission{Name: name, Description: description})
+	}
+
+	for _, resource := range []struct{ prefix, label string }{
+		{"proxy_host", "proxy hosts"},
+		{"certificate", "certificates"},
+		{"access_list", "access lists"},
+		{"nginx_config", "nginx configurations"},
+		{"template", "configuration templates"},
+		{"dead_host", "404 hosts"},
+		{"redirection_host", "redirection hosts"},
+		{"stream", "streams"},
+	} {
+		add(resource.prefix+":read", "View own "+resource.label)
+		add(resource.prefix+":read_all", "View "+resource.label+" of every user")
+		add(resource.prefix+":write", "Create and edit own "+resource.label)
+		add(resource.prefix+":delete", "Delete own "+resource.label)
+		add(resource.prefix+":manage", "Edit and delete "+resource.label+" of every user")
+	}
+
+	return permissions
+}
+
+// DefaultRoles returns the seeded roles with their permission names. Admins
+// hold every permission regardless of the role's stored permissions.
+func DefaultRoles() []Role {
+	var all, own, readOnly []string
+	for _, permission := range AllPermissions() {
+		all = append(all, permission.Name)
+		switch action := permission.Name[strings.LastIndexByte(permission.Name, ':')+1:]; action {
+		case "read", "write", "delete":
+			own = append(own, permission.Name)
+			if action == "read" {
+				readOnly = append(readOnly, permission.Name)
+			}
+		case "read_all":
+			readOnly = append(readOnly, permission.Name)
+		}
+	}
+
+	role := func(name RoleName, description string, permissionNames []string) Role {
+		permissions := make([]Permission, len(permissionNames))
+		for i, permissionName := range permissionNames {
+			permissions[i] = Permission{Name: permissionName}
+		}
+		return Role{Name: string(name), Description: description, IsSystem: true, Permissions: permissions}
+	}
+
+	return []Role{
+		role(RoleAdmin, "Full access, including user administration", all),
+		role(RoleOperator, "Manage hosts, certificates and configuration of every user", all),
+		role(RoleUser, "Manage own hosts, certificates and configuration", own),
+		role(RoleViewer, "Read-only access to every user's hosts, certificates and configuration", readOnly),
+	}
+}
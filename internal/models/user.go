@@ -96,7 +96,7 @@ func (u *User) IsPasswordHashed() bool {
 }
 
 // HasRole checks if user has a specific role
-func (u *User) HasRole(role Role) bool {
+func (u *User) HasRole(role RoleName) bool {
 	for _, r := range u.Roles {
 		if r == string(role) {
 			return true
@@ -111,14 +111,14 @@ func (u *User) IsAdmin() bool {
 }
 
 // AddRole adds a role to the user if not already present
-func (u *User) AddRole(role Role) {
+func (u *User) AddRole(role RoleName) {
 	if !u.HasRole(role) {
 		u.Roles = append(u.Roles, string(role))
 	}
 }
 
 // RemoveRole removes a role from the user
-func (u *User) RemoveRole(role Role) {
+func (u *User) RemoveRole(role RoleName) {
 	for i, r := range u.Roles {
 		if r == string(role) {
 			u.Roles = append(u.Roles[:i], u.Roles[i+1:]...)
@@ -4,24 +4,33 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/nguyendkn/nginx-manager/internal/controllers"
 	"github.com/nguyendkn/nginx-manager/internal/middleware"
+	"github.com/nguyendkn/nginx-manager/internal/models"
 	"github.com/nguyendkn/nginx-manager/internal/services"
 )
 
 // ServiceContainer holds all the initialized services
 type ServiceContainer struct {
-	AuthService         *services.AuthService
-	CertificateService  *services.CertificateService
-	MonitoringService   *services.MonitoringService
-	AnalyticsService    *services.AnalyticsService
-	NotificationService *services.NotificationService
-	ConfigService       *services.ConfigService
-	TemplateService     *services.TemplateService
-	AccessListService   *services.AccessListService
-	NginxService        *services.NginxService
+	AuthService           *services.AuthService
+	CertificateService    *services.CertificateService
+	MonitoringService     *services.MonitoringService
+	AnalyticsService      *services.AnalyticsService
+	NotificationService   *services.NotificationService
+	ConfigService         *services.ConfigService
+	TemplateService       *services.TemplateService
+	AccessListService     *services.AccessListService
+	NginxService          *services.NginxService
+	BackupService         *services.BackupService
+	StreamService         *services.StreamService
+	UpstreamHealthService *services.UpstreamHealthService
+	HealthCheckService    *services.HealthCheckService
+	HTTPMetricsService    *services.HTTPMetricsService
+	UserService           *services.UserService
+	ActivityService       *services.ActivityService
+	SettingsService       *services.SettingsService
 }
 
 // SetupAPIRoutes sets up all API routes with middleware (backward compatibility)
-func SetupAPIRoutes(r *gin.Engine) {
+func SetupAPIRoutes(r *gin.Engine, authService *services.AuthService) {
 	// Apply global rate limiting
 	r.Use(middleware.GeneralRateLimitMiddleware())
 
@@ -29,28 +38,36 @@ func SetupAPIRoutes(r *gin.Engine) {
 	v1 := r.Group("/api/v1")
 
 	// Setup auth routes
-	setupAuthRoutes(v1)
+	setupAuthRoutes(v1, authService)
+
+	// The monitoring WebSocket authenticates itself (header or ?token=)
+	setupMonitoringWebSocketRoute(v1, nil, nil, authService)
 
 	// Setup protected routes (require authentication)
 	protected := v1.Group("")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(authService))
 	{
-		setupUserRoutes(protected)
-		setupProxyHostRoutes(protected, nil)
+		setupUserRoutes(protected, nil)
+		setupProxyHostRoutes(protected, nil, nil, nil, nil, nil)
+		setupDeadHostRoutes(protected, nil)
+		setupRedirectionHostRoutes(protected, nil)
+		setupStreamRoutes(protected, nil)
 		setupCertificateRoutes(protected, nil)
-		setupMonitoringRoutes(protected, nil)
-		setupSettingsRoutes(protected)
+		setupMonitoringRoutes(protected, nil, nil)
+		setupSettingsRoutes(protected, nil)
 		setupNginxConfigRoutes(protected, nil)
+		setupAccessListRoutes(protected, nil)
 		setupTemplateRoutes(protected, nil)
 		setupAnalyticsRoutes(protected, nil)
+		setupGrafanaRoutes(protected, nil)
 	}
 
 	// Setup admin routes (require admin role)
 	admin := v1.Group("/admin")
-	admin.Use(middleware.AuthMiddleware())
+	admin.Use(middleware.AuthMiddleware(authService))
 	admin.Use(middleware.AdminOnlyMiddleware())
 	{
-		setupAdminRoutes(admin)
+		setupAdminRoutes(admin, nil, nil, nil)
 	}
 }
 
@@ -63,34 +80,43 @@ func SetupAPIRoutesWithServices(r *gin.Engine, services *ServiceContainer) {
 	v1 := r.Group("/api/v1")
 
 	// Setup auth routes
-	setupAuthRoutes(v1)
+	setupAuthRoutes(v1, services.AuthService)
+
+	// The monitoring WebSocket authenticates itself (header or ?token=)
+	setupMonitoringWebSocketRoute(v1, services.MonitoringService, services.ActivityService, services.AuthService)
 
 	// Setup protected routes (require authentication)
 	protected := v1.Group("")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(middleware.AuthMiddleware(services.AuthService))
 	{
-		setupUserRoutes(protected)
-		setupProxyHostRoutes(protected, nil)
+		setupUserRoutes(protected, services.UserService)
+		setupProxyHostRoutes(protected, services.NginxService, services.UpstreamHealthService, services.ActivityService, services.CertificateService, services.ConfigService)
+		setupDeadHostRoutes(protected, services.NginxService)
+		setupRedirectionHostRoutes(protected, services.NginxService)
+		setupStreamRoutes(protected, services.StreamService)
+		setupNginxRoutes(protected, services.NginxService)
 		setupCertificateRoutes(protected, services.CertificateService)
-		setupMonitoringRoutes(protected, services.MonitoringService)
-		setupSettingsRoutes(protected)
+		setupMonitoringRoutes(protected, services.MonitoringService, services.ActivityService)
+		setupSettingsRoutes(protected, services.SettingsService)
 		setupNginxConfigRoutes(protected, services.ConfigService)
+		setupAccessListRoutes(protected, services.AccessListService)
 		setupTemplateRoutes(protected, services.TemplateService)
 		setupAnalyticsRoutes(protected, services.AnalyticsService)
+		setupGrafanaRoutes(protected, services.AnalyticsService)
 	}
 
 	// Setup admin routes (require admin role)
 	admin := v1.Group("/admin")
-	admin.Use(middleware.AuthMiddleware())
+	admin.Use(middleware.AuthMiddleware(services.AuthService))
 	admin.Use(middleware.AdminOnlyMiddleware())
 	{
-		setupAdminRoutes(admin)
+		setupAdminRoutes(admin, services.UserService, services.NginxService, services.BackupService)
 	}
 }
 
 // setupAuthRoutes sets up authentication routes
-func setupAuthRoutes(rg *gin.RouterGroup) {
-	authController := controllers.NewAuthController()
+func setupAuthRoutes(rg *gin.RouterGroup, authService *services.AuthService) {
+	authController := controllers.NewAuthController(authService)
 
 	auth := rg.Group("/auth")
 	// Apply stricter rate limiting for auth endpoints
@@ -101,50 +127,111 @@ func setupAuthRoutes(rg *gin.RouterGroup) {
 
 		// Protected auth routes
 		protected := auth.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(authService))
 		{
 			protected.POST("/logout", authController.Logout)
 			protected.GET("/profile", authController.GetProfile)
-			protected.PUT("/profile", authController.UpdateProfile)
-			protected.POST("/change-password", authController.ChangePassword)
+			protected.PUT("/profile", middleware.DenyImpersonationMiddleware(), authController.UpdateProfile)
+			protected.POST("/change-password", middleware.DenyImpersonationMiddleware(), authController.ChangePassword)
 			protected.POST("/validate", authController.ValidateToken)
 		}
 	}
 }
 
 // setupUserRoutes sets up user management routes
-func setupUserRoutes(rg *gin.RouterGroup) {
-	// User routes will be implemented later
+func setupUserRoutes(rg *gin.RouterGroup, userService *services.UserService) {
+	userController := controllers.NewUserController(userService)
+
 	users := rg.Group("/users")
 	{
-		users.GET("", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "List users - to be implemented"})
-		})
-		users.GET("/:id", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "Get user - to be implemented"})
-		})
-		users.PUT("/:id", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "Update user - to be implemented"})
-		})
-		users.DELETE("/:id", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "Delete user - to be implemented"})
-		})
+		users.GET("", userController.ListUsers)
+		users.GET("/:id", userController.GetUser)
+		users.PUT("/:id", userController.UpdateUser)
+		users.DELETE("/:id", userController.DeleteUser)
 	}
 }
 
 // setupProxyHostRoutes sets up proxy host management routes
-func setupProxyHostRoutes(rg *gin.RouterGroup, service interface{}) {
-	proxyHostController := controllers.NewProxyHostController(nil)
+func setupProxyHostRoutes(rg *gin.RouterGroup, nginxService *services.NginxService, healthService *services.UpstreamHealthService, activityService *services.ActivityService, certificateService *services.CertificateService, configService *services.ConfigService) {
+	proxyHostController := controllers.NewProxyHostController(nginxService, activityService, certificateService, configService)
+	healthController := controllers.NewUpstreamHealthController(healthService)
 
 	proxyHosts := rg.Group("/proxy-hosts")
+	proxyHosts.Use(middleware.RequirePermissionMiddleware(models.PermissionProxyHostRead))
 	{
 		proxyHosts.GET("", proxyHostController.List)
-		proxyHosts.POST("", proxyHostController.Create)
+		proxyHosts.POST("", middleware.RequirePermissionMiddleware(models.PermissionProxyHostWrite), proxyHostController.Create)
 		proxyHosts.GET("/:id", proxyHostController.Get)
-		proxyHosts.PUT("/:id", proxyHostController.Update)
-		proxyHosts.DELETE("/:id", proxyHostController.Delete)
-		proxyHosts.POST("/:id/toggle", proxyHostController.Toggle)
-		proxyHosts.POST("/bulk-toggle", proxyHostController.BulkToggle)
+		proxyHosts.PUT("/:id", middleware.RequirePermissionMiddleware(models.PermissionProxyHostWrite), proxyHostController.Update)
+		proxyHosts.PATCH("/:id", middleware.RequirePermissionMiddleware(models.PermissionProxyHostWrite), proxyHostController.Patch)
+		proxyHosts.DELETE("/:id", middleware.RequirePermissionMiddleware(models.PermissionProxyHostDelete), proxyHostController.Delete)
+		proxyHosts.GET("/trash", proxyHostController.Trash)
+		proxyHosts.POST("/:id/restore", middleware.RequirePermissionMiddleware(models.PermissionProxyHostDelete), proxyHostController.Restore)
+		proxyHosts.DELETE("/:id/purge", middleware.AdminOnlyMiddleware(), proxyHostController.Purge)
+		proxyHosts.POST("/:id/clone", middleware.RequirePermissionMiddleware(models.PermissionProxyHostWrite), proxyHostController.Clone)
+		proxyHosts.POST("/:id/toggle", middleware.BodyLimitMiddleware(middleware.SmallBodyLimit), middleware.RequirePermissionMiddleware(models.PermissionProxyHostWrite), proxyHostController.Toggle)
+		proxyHosts.POST("/bulk-toggle", middleware.BodyLimitMiddleware(middleware.SmallBodyLimit), middleware.RequirePermissionMiddleware(models.PermissionProxyHostWrite), proxyHostController.BulkToggle)
+		proxyHosts.GET("/export", proxyHostController.Export)
+		proxyHosts.POST("/import", middleware.BodyLimitMiddleware(middleware.LargeBodyLimit), middleware.RequirePermissionMiddleware(models.PermissionProxyHostWrite), proxyHostController.Import)
+		proxyHosts.GET("/health", healthController.GetFleetHealth)
+		proxyHosts.GET("/:id/health", healthController.GetHostHealth)
+	}
+}
+
+// setupDeadHostRoutes sets up 404 host management routes
+func setupDeadHostRoutes(rg *gin.RouterGroup, nginxService *services.NginxService) {
+	deadHostController := controllers.NewDeadHostController(nginxService)
+
+	deadHosts := rg.Group("/dead-hosts")
+	deadHosts.Use(middleware.RequirePermissionMiddleware(models.PermissionDeadHostRead))
+	{
+		deadHosts.GET("", deadHostController.List)
+		deadHosts.POST("", middleware.RequirePermissionMiddleware(models.PermissionDeadHostWrite), deadHostController.Create)
+		deadHosts.GET("/:id", deadHostController.Get)
+		deadHosts.PUT("/:id", middleware.RequirePermissionMiddleware(models.PermissionDeadHostWrite), deadHostController.Update)
+		deadHosts.DELETE("/:id", middleware.RequirePermissionMiddleware(models.PermissionDeadHostDelete), deadHostController.Delete)
+	}
+}
+
+// setupRedirectionHostRoutes sets up redirection host management routes
+func setupRedirectionHostRoutes(rg *gin.RouterGroup, nginxService *services.NginxService) {
+	redirectionHostController := controllers.NewRedirectionHostController(nginxService)
+
+	redirectionHosts := rg.Group("/redirection-hosts")
+	redirectionHosts.Use(middleware.RequirePermissionMiddleware(models.PermissionRedirectionHostRead))
+	{
+		redirectionHosts.GET("", redirectionHostController.List)
+		redirectionHosts.POST("", middleware.RequirePermissionMiddleware(models.PermissionRedirectionHostWrite), redirectionHostController.Create)
+		redirectionHosts.GET("/:id", redirectionHostController.Get)
+		redirectionHosts.PUT("/:id", middleware.RequirePermissionMiddleware(models.PermissionRedirectionHostWrite), redirectionHostController.Update)
+		redirectionHosts.DELETE("/:id", middleware.RequirePermissionMiddleware(models.PermissionRedirectionHostDelete), redirectionHostController.Delete)
+		redirectionHosts.POST("/:id/toggle", middleware.BodyLimitMiddleware(middleware.SmallBodyLimit), middleware.RequirePermissionMiddleware(models.PermissionRedirectionHostWrite), redirectionHostController.Toggle)
+	}
+}
+
+// setupStreamRoutes sets up TCP/UDP stream proxy routes
+func setupStreamRoutes(rg *gin.RouterGroup, service *services.StreamService) {
+	streamController := controllers.NewStreamController(service)
+
+	streams := rg.Group("/streams")
+	streams.Use(middleware.RequirePermissionMiddleware(models.PermissionStreamRead))
+	{
+		streams.GET("", streamController.List)
+		streams.POST("", middleware.RequirePermissionMiddleware(models.PermissionStreamWrite), streamController.Create)
+		streams.GET("/:id", streamController.Get)
+		streams.PUT("/:id", middleware.RequirePermissionMiddleware(models.PermissionStreamWrite), streamController.Update)
+		streams.DELETE("/:id", middleware.RequirePermissionMiddleware(models.PermissionStreamDelete), streamController.Delete)
+	}
+}
+
+// setupNginxRoutes sets up nginx deployment routes
+func setupNginxRoutes(rg *gin.RouterGroup, service *services.NginxService) {
+	nginxController := controllers.NewNginxController(service)
+
+	nginx := rg.Group("/nginx")
+	{
+		nginx.GET("/pending", nginxController.GetPendingChanges)
+		nginx.POST("/apply", nginxController.ApplyPendingChanges)
 	}
 }
 
@@ -153,22 +240,31 @@ func setupCertificateRoutes(rg *gin.RouterGroup, service *services.CertificateSe
 	certificateController := controllers.NewCertificateController(service)
 
 	certificates := rg.Group("/certificates")
+	certificates.Use(middleware.RequirePermissionMiddleware(models.PermissionCertificateRead))
 	{
 		certificates.GET("", certificateController.ListCertificates)
-		certificates.POST("", certificateController.CreateCertificate)
+		certificates.POST("", middleware.RequirePermissionMiddleware(models.PermissionCertificateWrite), certificateController.CreateCertificate)
 		certificates.GET("/expiring-soon", certificateController.GetExpiringSoon)
 		certificates.POST("/test", certificateController.TestCertificate)
+		certificates.POST("/validate", certificateController.ValidateCertificate)
 		certificates.GET("/:id", certificateController.GetCertificate)
-		certificates.PUT("/:id", certificateController.UpdateCertificate)
-		certificates.DELETE("/:id", certificateController.DeleteCertificate)
-		certificates.POST("/:id/upload", certificateController.UploadCertificate)
-		certificates.POST("/:id/renew", certificateController.RenewCertificate)
+		certificates.PUT("/:id", middleware.RequirePermissionMiddleware(models.PermissionCertificateWrite), certificateController.UpdateCertificate)
+		certificates.DELETE("/:id", middleware.RequirePermissionMiddleware(models.PermissionCertificateDelete), certificateController.DeleteCertificate)
+		certificates.GET("/trash", certificateController.Trash)
+		certificates.POST("/:id/restore", middleware.RequirePermissionMiddleware(models.PermissionCertificateDelete), certificateController.RestoreCertificate)
+		certificates.DELETE("/:id/purge", middleware.AdminOnlyMiddleware(), certificateController.PurgeCertificate)
+		certificates.POST("/:id/upload", middleware.RequirePermissionMiddleware(models.PermissionCertificateWrite), certificateController.UploadCertificate)
+		certificates.POST("/:id/renew", middleware.RequirePermissionMiddleware(models.PermissionCertificateWrite), certificateController.RenewCertificate)
+		certificates.GET("/:id/ocsp", certificateController.CheckOCSPStatus)
+		certificates.POST("/:id/validate-domains", certificateController.ValidateForDomains)
+		certificates.GET("/:id/download", middleware.RequirePermissionMiddleware(models.PermissionCertificateWrite), certificateController.DownloadCertificate)
+		certificates.POST("/:id/download", middleware.RequirePermissionMiddleware(models.PermissionCertificateWrite), certificateController.DownloadCertificate)
 	}
 }
 
 // setupMonitoringRoutes sets up monitoring and real-time metrics routes
-func setupMonitoringRoutes(rg *gin.RouterGroup, service *services.MonitoringService) {
-	monitoringController := controllers.NewMonitoringController(service)
+func setupMonitoringRoutes(rg *gin.RouterGroup, service *services.MonitoringService, activityService *services.ActivityService) {
+	monitoringController := controllers.NewMonitoringController(service, activityService)
 
 	monitoring := rg.Group("/monitoring")
 	{
@@ -176,33 +272,41 @@ func setupMonitoringRoutes(rg *gin.RouterGroup, service *services.MonitoringServ
 		monitoring.GET("/system-metrics", monitoringController.GetSystemMetrics)
 		monitoring.GET("/nginx-status", monitoringController.GetNginxStatus)
 		monitoring.GET("/activity-feed", monitoringController.GetActivityFeed)
-		monitoring.GET("/ws", monitoringController.HandleWebSocket)
-		monitoring.POST("/nginx/control", monitoringController.ControlNginx)
+		// WebSocket tokens would outlive the audit trail of an impersonation
+		monitoring.POST("/ws-token", middleware.DenyImpersonationMiddleware(), monitoringController.IssueWebSocketToken)
+		monitoring.POST("/nginx/control", middleware.RequirePermissionMiddleware(models.PermissionNginxConfigManage), monitoringController.ControlNginx)
+		monitoring.GET("/nginx/error-log", middleware.RequirePermissionMiddleware(models.PermissionNginxConfigReadAll), monitoringController.GetErrorLog)
 	}
 }
 
-// setupSettingsRoutes sets up settings management routes
-func setupSettingsRoutes(rg *gin.RouterGroup) {
-	// Settings routes will be implemented later
+// setupMonitoringWebSocketRoute sets up the real-time metrics WebSocket, which
+// accepts a short-lived ?token= because browsers cannot send auth headers
+func setupMonitoringWebSocketRoute(rg *gin.RouterGroup, service *services.MonitoringService, activityService *services.ActivityService, authService *services.AuthService) {
+	monitoringController := controllers.NewMonitoringController(service, activityService)
+
+	rg.GET("/monitoring/ws", middleware.WebSocketAuthMiddleware(authService), monitoringController.HandleWebSocket)
+}
+
+// setupSettingsRoutes sets up system settings routes; changing settings
+// requires an admin
+func setupSettingsRoutes(rg *gin.RouterGroup, service *services.SettingsService) {
+	settingsController := controllers.NewSettingsController(service)
+
 	settings := rg.Group("/settings")
 	{
-		settings.GET("", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "Get settings - to be implemented"})
-		})
-		settings.PUT("", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "Update settings - to be implemented"})
-		})
-		settings.GET("/:key", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "Get setting by key - to be implemented"})
-		})
-		settings.PUT("/:key", func(c *gin.Context) {
-			c.JSON(200, gin.H{"message": "Update setting by key - to be implemented"})
-		})
+		settings.GET("", settingsController.GetSettings)
+		settings.PUT("", middleware.AdminOnlyMiddleware(), settingsController.UpdateSettings)
+		settings.GET("/:key", settingsController.GetSetting)
+		settings.PUT("/:key", middleware.AdminOnlyMiddleware(), settingsController.UpdateSetting)
 	}
 }
 
 // setupAdminRoutes sets up admin-only routes
-func setupAdminRoutes(rg *gin.RouterGroup) {
+func setupAdminRoutes(rg *gin.RouterGroup, userService *services.UserService, nginxService *services.NginxService, backupService *services.BackupService) {
+	userController := controllers.NewUserController(userService)
+	nginxController := controllers.NewNginxController(nginxService)
+	backupController := controllers.NewBackupController(backupService)
+
 	// System administration routes
 	rg.GET("/system/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "System health - to be implemented"})
@@ -213,13 +317,30 @@ func setupAdminRoutes(rg *gin.RouterGroup) {
 	})
 
 	// User management for admins
-	rg.GET("/users", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "Admin: List all users - to be implemented"})
-	})
+	rg.GET("/users", userController.ListUsers)
+	rg.POST("/users", userController.CreateUser)
 
-	rg.POST("/users", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "Admin: Create user - to be implemented"})
-	})
+	rg.POST("/users/:id/transfer", userController.TransferResources)
+	rg.POST("/users/:id/impersonate", userController.Impersonate)
+	rg.PUT("/users/:id/roles", userController.AssignRoles)
+
+	// Roles and the permissions they grant
+	rg.GET("/roles", userController.ListRoles)
+
+	// Audit trail of changes, filterable for compliance review
+	rg.GET("/audit-logs", userController.ListAuditLogs)
+
+	// Config files left on disk without a proxy host record
+	rg.GET("/nginx/orphans", nginxController.ListOrphanedConfigs)
+	rg.POST("/nginx/orphans/cleanup", nginxController.CleanupOrphanedConfigs)
+
+	// Certificate served by hosts that want HTTPS but have no valid certificate
+	rg.GET("/nginx/default-certificate", nginxController.GetDefaultCertificate)
+	rg.PUT("/nginx/default-certificate", nginxController.UpdateDefaultCertificate)
+
+	// Full configuration backups for disaster recovery
+	rg.POST("/backups", backupController.CreateBackup)
+	rg.POST("/backups/restore", backupController.RestoreBackup)
 
 	// System logs
 	rg.GET("/logs", func(c *gin.Context) {
@@ -249,23 +370,38 @@ func setupAdminRoutes(rg *gin.RouterGroup) {
 	}
 }
 
+// setupAccessListRoutes sets up access list management routes
+func setupAccessListRoutes(rg *gin.RouterGroup, service *services.AccessListService) {
+	accessListController := controllers.NewAccessListController(service)
+
+	accessLists := rg.Group("/access-lists")
+	accessLists.Use(middleware.RequirePermissionMiddleware(models.PermissionAccessListRead))
+	{
+		accessLists.GET("/:id/export", accessListController.ExportAccessList)
+		accessLists.POST("/:id/normalize", middleware.RequirePermissionMiddleware(models.PermissionAccessListWrite), accessListController.NormalizeAccessList)
+	}
+}
+
 // setupNginxConfigRoutes sets up nginx configuration management routes
 func setupNginxConfigRoutes(rg *gin.RouterGroup, service *services.ConfigService) {
 	configController := controllers.NewConfigController(service)
 
 	configs := rg.Group("/nginx/configs")
+	configs.Use(middleware.RequirePermissionMiddleware(models.PermissionNginxConfigRead))
 	{
 		configs.GET("", configController.ListConfigs)
-		configs.POST("", configController.CreateConfig)
+		configs.POST("", middleware.BodyLimitMiddleware(middleware.LargeBodyLimit), middleware.RequirePermissionMiddleware(models.PermissionNginxConfigWrite), configController.CreateConfig)
 		configs.GET("/:id", configController.GetConfig)
-		configs.PUT("/:id", configController.UpdateConfig)
-		configs.DELETE("/:id", configController.DeleteConfig)
-		configs.POST("/validate", configController.ValidateConfig)
-		configs.POST("/:id/deploy", configController.DeployConfig)
+		configs.PUT("/:id", middleware.BodyLimitMiddleware(middleware.LargeBodyLimit), middleware.RequirePermissionMiddleware(models.PermissionNginxConfigWrite), configController.UpdateConfig)
+		configs.DELETE("/:id", middleware.RequirePermissionMiddleware(models.PermissionNginxConfigDelete), configController.DeleteConfig)
+		configs.POST("/validate", middleware.BodyLimitMiddleware(middleware.LargeBodyLimit), configController.ValidateConfig)
+		configs.POST("/:id/deploy", middleware.RequirePermissionMiddleware(models.PermissionNginxConfigWrite), configController.DeployConfig)
 		configs.GET("/:id/history", configController.GetConfigHistory)
-		configs.POST("/:id/backup", configController.CreateConfigBackup)
-		configs.POST("/:id/restore/:version", configController.RestoreConfigFromBackup)
+		configs.POST("/:id/backup", middleware.RequirePermissionMiddleware(models.PermissionNginxConfigWrite), configController.CreateConfigBackup)
+		configs.POST("/:id/restore/:version", middleware.RequirePermissionMiddleware(models.PermissionNginxConfigWrite), configController.RestoreConfigFromBackup)
 	}
+
+	rg.GET("/nginx/search", configController.SearchContent)
 }
 
 // setupTemplateRoutes sets up configuration template management routes
@@ -273,15 +409,20 @@ func setupTemplateRoutes(rg *gin.RouterGroup, service *services.TemplateService)
 	templateController := controllers.NewTemplateController(service)
 
 	templates := rg.Group("/nginx/templates")
+	templates.Use(middleware.RequirePermissionMiddleware(models.PermissionTemplateRead))
 	{
 		templates.GET("", templateController.ListTemplates)
-		templates.POST("", templateController.CreateTemplate)
+		templates.POST("", middleware.BodyLimitMiddleware(middleware.LargeBodyLimit), middleware.RequirePermissionMiddleware(models.PermissionTemplateWrite), templateController.CreateTemplate)
 		templates.GET("/categories", templateController.GetCategories)
-		templates.POST("/init-builtin", templateController.InitializeBuiltInTemplates)
+		templates.POST("/init-builtin", middleware.RequirePermissionMiddleware(models.PermissionTemplateManage), templateController.InitializeBuiltInTemplates)
 		templates.GET("/:id", templateController.GetTemplate)
-		templates.PUT("/:id", templateController.UpdateTemplate)
-		templates.DELETE("/:id", templateController.DeleteTemplate)
+		templates.PUT("/:id", middleware.BodyLimitMiddleware(middleware.LargeBodyLimit), middleware.RequirePermissionMiddleware(models.PermissionTemplateWrite), templateController.UpdateTemplate)
+		templates.DELETE("/:id", middleware.RequirePermissionMiddleware(models.PermissionTemplateDelete), templateController.DeleteTemplate)
+		templates.POST("/:id/clone", middleware.RequirePermissionMiddleware(models.PermissionTemplateWrite), templateController.CloneTemplate)
 		templates.POST("/:id/render", templateController.RenderTemplate)
+		templates.POST("/:id/preview", templateController.PreviewTemplate)
+		templates.GET("/:id/versions", templateController.GetTemplateVersions)
+		templates.POST("/:id/rollback/:version", middleware.RequirePermissionMiddleware(models.PermissionTemplateWrite), templateController.RollbackTemplate)
 	}
 }
 
@@ -295,6 +436,22 @@ func setupAnalyticsRoutes(rg *gin.RouterGroup, service *services.AnalyticsServic
 		metricsGroup := analytics.Group("/metrics")
 		{
 			metricsGroup.POST("/query", analyticsController.QueryMetrics)
+			metricsGroup.POST("/trends", analyticsController.AnalyzeTrends)
+			metricsGroup.GET("/catalog", analyticsController.GetMetricCatalog)
+			metricsGroup.GET("/collectors", analyticsController.GetMetricCollectors)
+			metricsGroup.PUT("/collectors", middleware.AdminOnlyMiddleware(), analyticsController.UpdateMetricCollectors)
+			metricsGroup.GET("/aggregations", analyticsController.GetAggregationStatus)
+			metricsGroup.POST("/aggregations/backfill", middleware.AdminOnlyMiddleware(), analyticsController.BackfillAggregations)
+
+			// Retention Policies
+			retentionGroup := metricsGroup.Group("/retention-policies", middleware.AdminOnlyMiddleware())
+			{
+				retentionGroup.GET("", analyticsController.GetRetentionPolicies)
+				retentionGroup.POST("", analyticsController.CreateRetentionPolicy)
+				retentionGroup.PUT("/:id", analyticsController.UpdateRetentionPolicy)
+				retentionGroup.DELETE("/:id", analyticsController.DeleteRetentionPolicy)
+			}
+
 			metricsGroup.GET("/:type/:name", analyticsController.GetHistoricalMetrics)
 		}
 
@@ -302,6 +459,7 @@ func setupAnalyticsRoutes(rg *gin.RouterGroup, service *services.AnalyticsServic
 		systemGroup := analytics.Group("/system")
 		{
 			systemGroup.GET("/summary", analyticsController.GetSystemMetricsSummary)
+			systemGroup.GET("/report", analyticsController.GetPerformanceReport)
 		}
 
 		// Alert Management Routes
@@ -318,6 +476,36 @@ func setupAnalyticsRoutes(rg *gin.RouterGroup, service *services.AnalyticsServic
 
 			// Alert Instances
 			alertsGroup.GET("/instances", analyticsController.GetAlertInstances)
+
+			// Rules and channels export/import
+			alertsGroup.GET("/export", analyticsController.ExportAlertConfig)
+			alertsGroup.POST("/import", middleware.BodyLimitMiddleware(middleware.LargeBodyLimit), analyticsController.ImportAlertConfig)
+
+			// Silences
+			silencesGroup := alertsGroup.Group("/silences")
+			{
+				silencesGroup.POST("", analyticsController.CreateSilence)
+				silencesGroup.GET("", analyticsController.GetSilences)
+				silencesGroup.DELETE("/:id", analyticsController.ExpireSilence)
+			}
+		}
+
+		// Performance Insights Routes
+		insightsGroup := analytics.Group("/insights")
+		{
+			insightsGroup.GET("", analyticsController.GetInsights)
+			insightsGroup.POST("/:id/resolve", analyticsController.ResolveInsight)
+		}
+
+		// Notification Channel Routes
+		channelsGroup := analytics.Group("/notifications/channels")
+		{
+			channelsGroup.POST("", analyticsController.CreateNotificationChannel)
+			channelsGroup.GET("", analyticsController.GetNotificationChannels)
+			channelsGroup.GET("/:id", analyticsController.GetNotificationChannel)
+			channelsGroup.PUT("/:id", analyticsController.UpdateNotificationChannel)
+			channelsGroup.DELETE("/:id", analyticsController.DeleteNotificationChannel)
+			channelsGroup.POST("/:id/test", analyticsController.TestNotificationChannel)
 		}
 
 		// Dashboard Management Routes
@@ -328,6 +516,19 @@ func setupAnalyticsRoutes(rg *gin.RouterGroup, service *services.AnalyticsServic
 			dashboardsGroup.GET("/:id", analyticsController.GetDashboard)
 			dashboardsGroup.PUT("/:id", analyticsController.UpdateDashboard)
 			dashboardsGroup.DELETE("/:id", analyticsController.DeleteDashboard)
+			dashboardsGroup.POST("/:id/share", analyticsController.ShareDashboard)
 		}
 	}
 }
+
+// setupGrafanaRoutes sets up the Grafana SimpleJSON datasource routes
+func setupGrafanaRoutes(rg *gin.RouterGroup, service *services.AnalyticsService) {
+	grafanaController := controllers.NewGrafanaController(service)
+
+	grafana := rg.Group("/grafana")
+	{
+		grafana.GET("/", grafanaController.TestConnection)
+		grafana.POST("/search", grafanaController.Search)
+		grafana.POST("/query", grafanaController.Query)
+	}
+}
@@ -4,27 +4,33 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/nguyendkn/nginx-manager/configs"
 	"github.com/nguyendkn/nginx-manager/internal/controllers"
+	"github.com/nguyendkn/nginx-manager/internal/services"
 )
 
-// SetupHealthRoutes sets up health-related routes
-func SetupHealthRoutes(router *gin.Engine, env *configs.Environment) {
+// SetupHealthRoutes sets up health-related routes. /health/live and
+// /health/ready are meant for Kubernetes liveness and readiness probes.
+func SetupHealthRoutes(router *gin.Engine, env *configs.Environment, monitoringService *services.MonitoringService) {
 	// Create health controller instance
-	healthController := controllers.NewHealthController(env)
+	healthController := controllers.NewHealthController(env, monitoringService)
 
 	// Health check routes
 	router.GET("/health", healthController.HealthCheck)
+	router.GET("/health/live", healthController.Live)
+	router.GET("/health/ready", healthController.Ready)
 	router.GET("/ping", healthController.Ping)
 }
 
 // SetupHealthRoutesWithGroup sets up health-related routes with a route group
 func SetupHealthRoutesWithGroup(router *gin.Engine, env *configs.Environment, prefix string) {
 	// Create health controller instance
-	healthController := controllers.NewHealthController(env)
+	healthController := controllers.NewHealthController(env, nil)
 
 	// Create route group
 	healthGroup := router.Group(prefix)
 	{
 		healthGroup.GET("/health", healthController.HealthCheck)
+		healthGroup.GET("/health/live", healthController.Live)
+		healthGroup.GET("/health/ready", healthController.Ready)
 		healthGroup.GET("/ping", healthController.Ping)
 	}
 }
@@ -0,0 +1,16 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/configs"
+	"github.com/nguyendkn/nginx-manager/internal/controllers"
+	"github.com/nguyendkn/nginx-manager/internal/services"
+)
+
+// SetupMetricsRoutes sets up the Prometheus scrape endpoint. It sits outside
+// the JWT-protected API and is guarded by METRICS_EXPORTER_TOKEN when set.
+func SetupMetricsRoutes(router *gin.Engine, env *configs.Environment, monitoringService *services.MonitoringService) {
+	metricsController := controllers.NewMetricsExporterController(monitoringService, env.MetricsExporterToken)
+
+	router.GET("/metrics", metricsController.Metrics)
+}
@@ -1,13 +1,20 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/nguyendkn/nginx-manager/internal/database"
 	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/oschwald/maxminddb-golang"
 	"gorm.io/gorm"
 )
 
@@ -16,12 +23,32 @@ var (
 	ErrAccessListInUse    = errors.New("access list is currently in use")
 	ErrInvalidIPFormat    = errors.New("invalid IP address format")
 	ErrInvalidCIDRFormat  = errors.New("invalid CIDR format")
+	ErrGeoIPUnavailable   = errors.New("no GeoIP database configured")
+
+	ErrUnsupportedExportFormat = errors.New("unsupported export format, expected nginx, apache or json")
+)
+
+// Access list export formats
+const (
+	AccessListExportNginx  = "nginx"
+	AccessListExportApache = "apache"
+	AccessListExportJSON   = "json"
 )
 
+var accessListExportFormats = []string{AccessListExportNginx, AccessListExportApache, AccessListExportJSON}
+
 // AccessListService handles access list management
 type AccessListService struct {
 	db          *gorm.DB
 	authService *AuthService
+
+	// nginxService locates htpasswd files and reloads nginx, optional
+	nginxService *NginxService
+
+	// GeoIP country database for geo rules, optional
+	geoIPPath   string
+	geoIPReader *maxminddb.Reader
+	geoIPMutex  sync.RWMutex
 }
 
 // NewAccessListService creates a new access list service instance
@@ -32,6 +59,68 @@ func NewAccessListService(authService *AuthService) *AccessListService {
 	}
 }
 
+// SetNginxService sets the nginx service used to place htpasswd files and to
+// reload nginx when credentials change
+func (s *AccessListService) SetNginxService(nginxService *NginxService) {
+	s.nginxService = nginxService
+}
+
+// SetGeoIPDatabase opens the MaxMind country database (mmdb) used to resolve
+// geo rules, replacing any previously opened one. An empty path disables geo
+// lookups.
+func (s *AccessListService) SetGeoIPDatabase(path string) error {
+	var reader *maxminddb.Reader
+	if path != "" {
+		var err error
+		if reader, err = maxminddb.Open(path); err != nil {
+			return fmt.Errorf("failed to open GeoIP database: %w", err)
+		}
+	}
+
+	s.geoIPMutex.Lock()
+	previous := s.geoIPReader
+	s.geoIPPath = path
+	s.geoIPReader = reader
+	s.geoIPMutex.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	return nil
+}
+
+// LookupCountry resolves an IP address to its ISO country code. An empty code
+// means the database has no country for the address.
+func (s *AccessListService) LookupCountry(ipAddress string) (string, error) {
+	ip := models.ParseAccessListIP(ipAddress)
+	if ip == nil {
+		return "", ErrInvalidIPFormat
+	}
+
+	s.geoIPMutex.RLock()
+	defer s.geoIPMutex.RUnlock()
+	if s.geoIPReader == nil {
+		return "", ErrGeoIPUnavailable
+	}
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := s.geoIPReader.Lookup(net.IP(ip), &record); err != nil {
+		return "", err
+	}
+	return record.Country.ISOCode, nil
+}
+
+// geoIPDatabasePath returns the configured mmdb path, empty when geo rules are disabled
+func (s *AccessListService) geoIPDatabasePath() string {
+	s.geoIPMutex.RLock()
+	defer s.geoIPMutex.RUnlock()
+	return s.geoIPPath
+}
+
 // AccessListRequest represents access list create/update request
 type AccessListRequest struct {
 	Name        string                  `json:"name" binding:"required"`
@@ -41,14 +130,21 @@ type AccessListRequest struct {
 
 // AccessListItemRequest represents access list item request
 type AccessListItemRequest struct {
-	Type      models.AccessListItemType  `json:"type" binding:"required"`
-	Directive models.AccessListDirective `json:"directive" binding:"required"`
-	Address   string                     `json:"address,omitempty"`
-	Subnet    string                     `json:"subnet,omitempty"`
-	Username  string                     `json:"username,omitempty"`
-	Password  string                     `json:"password,omitempty"`
-	Comment   string                     `json:"comment,omitempty"`
-	Enabled   bool                       `json:"enabled"`
+	Type        models.AccessListItemType  `json:"type" binding:"required"`
+	Directive   models.AccessListDirective `json:"directive" binding:"required"`
+	Address     string                     `json:"address,omitempty"`
+	Subnet      string                     `json:"subnet,omitempty"`
+	CountryCode string                     `json:"country_code,omitempty"`
+	Username    string                     `json:"username,omitempty"`
+	Password    string                     `json:"password,omitempty"`
+	Comment     string                     `json:"comment,omitempty"`
+	Enabled     *bool                      `json:"enabled"` // defaults to true
+}
+
+// enabled reports whether the item is enabled, which it is unless the
+// request turns it off
+func (r *AccessListItemRequest) enabled() bool {
+	return r.Enabled == nil || *r.Enabled
 }
 
 // TestIPRequest represents IP testing request
@@ -62,10 +158,12 @@ type TestIPResponse struct {
 	Allowed     bool                   `json:"allowed"`
 	Message     string                 `json:"message"`
 	MatchedRule *models.AccessListItem `json:"matched_rule,omitempty"`
+	Country     string                 `json:"country,omitempty"`
+	Warnings    []string               `json:"warnings,omitempty"`
 }
 
 // CreateAccessList creates a new access list
-func (s *AccessListService) CreateAccessList(userID uint, req *AccessListRequest) (*models.AccessList, error) {
+func (s *AccessListService) CreateAccessList(userID uint, req *AccessListRequest, audit AuditContext) (*models.AccessList, error) {
 	// Validate request
 	if err := s.validateAccessListRequest(req); err != nil {
 		return nil, err
@@ -100,10 +198,11 @@ func (s *AccessListService) CreateAccessList(userID uint, req *AccessListRequest
 			Directive:    itemReq.Directive,
 			Address:      itemReq.Address,
 			Subnet:       itemReq.Subnet,
+			CountryCode:  strings.ToUpper(strings.TrimSpace(itemReq.CountryCode)),
 			Username:     itemReq.Username,
 			Password:     itemReq.Password,
 			Comment:      itemReq.Comment,
-			Enabled:      itemReq.Enabled,
+			Enabled:      itemReq.enabled(),
 		}
 
 		// Hash password for auth items
@@ -130,25 +229,32 @@ func (s *AccessListService) CreateAccessList(userID uint, req *AccessListRequest
 		return nil, err
 	}
 
+	if _, err := s.writeHtpasswd(accessList); err != nil {
+		return nil, err
+	}
+
+	auditLog := NewAuditLog(userID, models.ActionCreated, models.ObjectTypeAccessList, accessList.ID,
+		fmt.Sprintf("Created access list: %s", accessList.Name))
+	auditLog.Meta = models.JSON{"rules": accessListAuditRules(accessList.Items)}
+	RecordAudit(s.db, audit, auditLog)
+
 	return accessList, nil
 }
 
 // UpdateAccessList updates an existing access list
-func (s *AccessListService) UpdateAccessList(userID uint, id uint, req *AccessListRequest) (*models.AccessList, error) {
+func (s *AccessListService) UpdateAccessList(userID uint, id uint, req *AccessListRequest, audit AuditContext) (*models.AccessList, error) {
 	// Find existing access list
 	var accessList models.AccessList
-	if err := s.db.Preload("Items").Where("id = ? AND user_id = ?", id, userID).First(&accessList).Error; err != nil {
+	if err := s.authService.ScopeToOwner(s.db.Preload("Items"), userID, models.PermissionAccessListManage).Where("id = ?", id).First(&accessList).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrAccessListNotFound
 		}
 		return nil, err
 	}
 
-	// Check admin permission for cross-user management
-	if accessList.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return nil, err
-		}
+	// Another user's access list needs access_list:manage
+	if err := s.authService.RequireOwnerOrPermission(userID, accessList.UserID, models.PermissionAccessListWrite, models.PermissionAccessListManage); err != nil {
+		return nil, err
 	}
 
 	// Validate request
@@ -164,6 +270,9 @@ func (s *AccessListService) UpdateAccessList(userID uint, id uint, req *AccessLi
 		}
 	}()
 
+	before := accessList
+	beforeRules := accessListAuditRules(accessList.Items)
+
 	// Update access list
 	accessList.Name = req.Name
 	accessList.Description = req.Description
@@ -187,10 +296,11 @@ func (s *AccessListService) UpdateAccessList(userID uint, id uint, req *AccessLi
 			Directive:    itemReq.Directive,
 			Address:      itemReq.Address,
 			Subnet:       itemReq.Subnet,
+			CountryCode:  strings.ToUpper(strings.TrimSpace(itemReq.CountryCode)),
 			Username:     itemReq.Username,
 			Password:     itemReq.Password,
 			Comment:      itemReq.Comment,
-			Enabled:      itemReq.Enabled,
+			Enabled:      itemReq.enabled(),
 		}
 
 		// Hash password for auth items
@@ -217,25 +327,45 @@ func (s *AccessListService) UpdateAccessList(userID uint, id uint, req *AccessLi
 		return nil, err
 	}
 
+	// Items are a relation the field diff skips, so rule edits are recorded
+	// as the rule lists before and after
+	auditLog := NewUpdateAuditLog(userID, models.ObjectTypeAccessList, accessList.ID,
+		fmt.Sprintf("Updated access list: %s", accessList.Name), &before, &accessList)
+	if afterRules := accessListAuditRules(accessList.Items); !reflect.DeepEqual(beforeRules, afterRules) {
+		if auditLog.Meta == nil {
+			auditLog.Meta = models.JSON{}
+		}
+		auditLog.Meta["rules"] = AuditFieldChange{Before: beforeRules, After: afterRules}
+	}
+	RecordAudit(s.db, audit, auditLog)
+
+	// Proxy hosts using the list read credentials from its htpasswd file
+	changed, err := s.writeHtpasswd(&accessList)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := s.nginxService.reloadNginx(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &accessList, nil
 }
 
 // DeleteAccessList deletes an access list
-func (s *AccessListService) DeleteAccessList(userID uint, id uint) error {
+func (s *AccessListService) DeleteAccessList(userID uint, id uint, audit AuditContext) error {
 	// Find access list
 	var accessList models.AccessList
-	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&accessList).Error; err != nil {
+	if err := s.authService.ScopeToOwner(s.db, userID, models.PermissionAccessListManage).Where("id = ?", id).First(&accessList).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return ErrAccessListNotFound
 		}
 		return err
 	}
 
-	// Check admin permission for cross-user management
-	if accessList.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return err
-		}
+	if err := s.authService.RequireOwnerOrPermission(userID, accessList.UserID, models.PermissionAccessListDelete, models.PermissionAccessListManage); err != nil {
+		return err
 	}
 
 	// Check if access list is in use
@@ -269,7 +399,93 @@ func (s *AccessListService) DeleteAccessList(userID uint, id uint) error {
 	}
 
 	// Commit transaction
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	RecordAudit(s.db, audit, NewAuditLog(userID, models.ActionDeleted, models.ObjectTypeAccessList, accessList.ID,
+		fmt.Sprintf("Deleted access list: %s", accessList.Name)))
+
+	// Items were not loaded, so this removes the list's htpasswd file
+	_, err := s.writeHtpasswd(&accessList)
+	return err
+}
+
+// WriteHtpasswd writes the enabled auth items of an access list to its
+// htpasswd file, removing the file when the list has no credentials
+func (s *AccessListService) WriteHtpasswd(id uint) error {
+	var accessList models.AccessList
+	if err := s.db.Preload("Items").First(&accessList, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrAccessListNotFound
+		}
+		return err
+	}
+
+	_, err := s.writeHtpasswd(&accessList)
+	return err
+}
+
+// htpasswdPath returns the htpasswd file proxy hosts use for an access list
+func (s *AccessListService) htpasswdPath(id uint) string {
+	if s.nginxService != nil {
+		return s.nginxService.HtpasswdPath(id)
+	}
+	return filepath.Join(defaultHtpasswdDir, htpasswdFileName(id))
+}
+
+// writeHtpasswd materializes the htpasswd file for a loaded access list and
+// reports whether its contents changed. Stored passwords are bcrypt hashes;
+// legacy plaintext values are hashed on the way out.
+func (s *AccessListService) writeHtpasswd(accessList *models.AccessList) (bool, error) {
+	if s.nginxService == nil {
+		return false, nil
+	}
+	path := s.htpasswdPath(accessList.ID)
+
+	var content strings.Builder
+	for _, item := range accessList.GetEnabledItems() {
+		if !item.IsAuthItem() || item.Username == "" || item.Password == "" {
+			continue
+		}
+		if !item.IsPasswordHashed() {
+			if err := item.SetPassword(item.Password); err != nil {
+				return false, err
+			}
+		}
+		content.WriteString(fmt.Sprintf("%s:%s\n", item.Username, item.Password))
+	}
+
+	previous, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	exists := err == nil
+
+	if content.Len() == 0 {
+		if !exists {
+			return false, nil
+		}
+		if err := os.Remove(path); err != nil {
+			return false, fmt.Errorf("failed to remove htpasswd file: %w", err)
+		}
+		return true, nil
+	}
+
+	if exists && string(previous) == content.String() {
+		return false, nil
+	}
+
+	// Write to a temporary file first so nginx never reads a partial file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(content.String()), 0640); err != nil {
+		return false, fmt.Errorf("failed to write htpasswd file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return false, fmt.Errorf("failed to write htpasswd file: %w", err)
+	}
+	return true, nil
 }
 
 // GetAccessList gets a single access list
@@ -277,9 +493,8 @@ func (s *AccessListService) GetAccessList(userID uint, id uint) (*models.AccessL
 	var accessList models.AccessList
 	query := s.db.Preload("Items")
 
-	// Check if user has admin privileges to view all access lists
-	if err := s.authService.RequireAdmin(userID); err != nil {
-		// Non-admin users can only see their own access lists
+	// Users without access_list:read_all only see their own access lists
+	if !s.authService.HasPermission(userID, models.PermissionAccessListReadAll) {
 		query = query.Where("user_id = ?", userID)
 	}
 
@@ -300,9 +515,8 @@ func (s *AccessListService) ListAccessLists(userID uint, offset, limit int) ([]m
 
 	query := s.db.Model(&models.AccessList{}).Preload("Items")
 
-	// Check if user has admin privileges to view all access lists
-	if err := s.authService.RequireAdmin(userID); err != nil {
-		// Non-admin users can only see their own access lists
+	// Users without access_list:read_all only see their own access lists
+	if !s.authService.HasPermission(userID, models.PermissionAccessListReadAll) {
 		query = query.Where("user_id = ?", userID)
 	}
 
@@ -328,7 +542,7 @@ func (s *AccessListService) TestIP(userID uint, id uint, req *TestIPRequest) (*T
 	}
 
 	// Validate IP address
-	if net.ParseIP(req.IPAddress) == nil {
+	if models.ParseAccessListIP(req.IPAddress) == nil {
 		return &TestIPResponse{
 			IPAddress: req.IPAddress,
 			Allowed:   false,
@@ -336,8 +550,38 @@ func (s *AccessListService) TestIP(userID uint, id uint, req *TestIPRequest) (*T
 		}, nil
 	}
 
-	// Test IP access
-	allowed, err := accessList.CheckIPAccess(req.IPAddress)
+	// Geo rules are evaluated first, like the nginx if guard written by
+	// ExportAccessList, and skipped when no GeoIP database is configured
+	var warnings []string
+	var country string
+	var geoRule *models.AccessListItem
+	if accessList.HasGeoRules() {
+		code, err := s.LookupCountry(req.IPAddress)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Geo rules skipped: %v", err))
+		} else {
+			country = code
+			var geoAllowed bool
+			geoAllowed, geoRule = accessList.EvaluateGeoAccess(code)
+			if !geoAllowed {
+				message := "Denied by default (country matches no allow rule)"
+				if geoRule != nil {
+					message = fmt.Sprintf("Denied by rule: %s %s", geoRule.Directive, geoRule.GetDisplayName())
+				}
+				return &TestIPResponse{
+					IPAddress:   req.IPAddress,
+					Allowed:     false,
+					Message:     message,
+					MatchedRule: geoRule,
+					Country:     country,
+					Warnings:    warnings,
+				}, nil
+			}
+		}
+	}
+
+	// Test IP access and find the rule that decided it (if any)
+	allowed, matchedRule, err := accessList.EvaluateIPAccess(req.IPAddress)
 	if err != nil {
 		return &TestIPResponse{
 			IPAddress: req.IPAddress,
@@ -345,16 +589,8 @@ func (s *AccessListService) TestIP(userID uint, id uint, req *TestIPRequest) (*T
 			Message:   fmt.Sprintf("Error checking IP access: %v", err),
 		}, nil
 	}
-
-	// Find matching rule (if any)
-	var matchedRule *models.AccessListItem
-	for _, item := range accessList.GetEnabledItems() {
-		if item.IsIPItem() {
-			if matches, _ := item.MatchesIP(req.IPAddress); matches {
-				matchedRule = &item
-				break
-			}
-		}
+	if matchedRule == nil && allowed {
+		matchedRule = geoRule
 	}
 
 	message := "No matching rules found"
@@ -375,6 +611,8 @@ func (s *AccessListService) TestIP(userID uint, id uint, req *TestIPRequest) (*T
 		Allowed:     allowed,
 		Message:     message,
 		MatchedRule: matchedRule,
+		Country:     country,
+		Warnings:    warnings,
 	}, nil
 }
 
@@ -390,14 +628,34 @@ func (s *AccessListService) ValidateAccessList(userID uint, id uint) ([]string,
 	return accessList.ValidateRules(), nil
 }
 
-// ExportAccessList exports access list rules in nginx format
-func (s *AccessListService) ExportAccessList(userID uint, id uint) (string, error) {
+// ExportAccessList exports access list rules in the given format: nginx
+// (the default), apache or json
+func (s *AccessListService) ExportAccessList(userID uint, id uint, format string) (string, error) {
+	if format == "" {
+		format = AccessListExportNginx
+	}
+	if !containsString(accessListExportFormats, format) {
+		return "", ErrUnsupportedExportFormat
+	}
+
 	// Get access list
 	accessList, err := s.GetAccessList(userID, id)
 	if err != nil {
 		return "", err
 	}
 
+	switch format {
+	case AccessListExportApache:
+		return exportAccessListApache(accessList), nil
+	case AccessListExportJSON:
+		return exportAccessListJSON(accessList)
+	default:
+		return s.exportAccessListNginx(accessList), nil
+	}
+}
+
+// exportAccessListNginx writes allow/deny, GeoIP and auth_basic directives
+func (s *AccessListService) exportAccessListNginx(accessList *models.AccessList) string {
 	var config strings.Builder
 	config.WriteString(fmt.Sprintf("# Access List: %s\n", accessList.Name))
 	if accessList.Description != "" {
@@ -405,17 +663,35 @@ func (s *AccessListService) ExportAccessList(userID uint, id uint) (string, erro
 	}
 	config.WriteString("\n")
 
-	// Generate IP rules
-	for _, item := range accessList.GetEnabledItems() {
-		if item.IsIPItem() {
+	// Generate geo rules
+	if accessList.HasGeoRules() {
+		s.writeGeoRules(&config, accessList)
+	}
+
+	// Generate IP rules. nginx applies the first matching rule, so deny rules
+	// are written before allow rules to match TestIP, where a matching deny
+	// takes precedence, and a closing "deny all" rejects unmatched clients
+	// when allow rules exist.
+	hasAllowRules := false
+	for _, directive := range []models.AccessListDirective{models.AccessListDirectiveDeny, models.AccessListDirectiveAllow} {
+		for _, item := range accessList.GetEnabledItems() {
+			if !item.IsIPItem() || item.Directive != directive {
+				continue
+			}
 			if rule := item.GetNginxRule(); rule != "" {
 				if item.Comment != "" {
 					config.WriteString(fmt.Sprintf("# %s\n", item.Comment))
 				}
 				config.WriteString(rule + "\n")
+				if directive == models.AccessListDirectiveAllow {
+					hasAllowRules = true
+				}
 			}
 		}
 	}
+	if hasAllowRules {
+		config.WriteString("deny all;\n")
+	}
 
 	// Generate auth rules
 	authItems := []models.AccessListItem{}
@@ -428,17 +704,209 @@ func (s *AccessListService) ExportAccessList(userID uint, id uint) (string, erro
 	if len(authItems) > 0 {
 		config.WriteString("\n# HTTP Authentication\n")
 		config.WriteString("auth_basic \"Restricted Area\";\n")
-		config.WriteString("auth_basic_user_file /etc/nginx/.htpasswd;\n")
+		config.WriteString(fmt.Sprintf("auth_basic_user_file %s;\n", s.htpasswdPath(accessList.ID)))
+	}
+
+	return config.String()
+}
+
+// exportAccessListApache writes Apache 2.4 authorization directives for an
+// .htaccess file or <Directory> block. Deny rules become "Require not ip"
+// and allow rules a RequireAny group, so a matching deny wins and unmatched
+// clients are refused when allow rules exist, as in TestIP. Auth items add a
+// "Require valid-user" that must hold as well, like nginx's default
+// "satisfy all". Geo rules have no Apache core equivalent and are listed as
+// comments.
+func exportAccessListApache(accessList *models.AccessList) string {
+	var config strings.Builder
+	config.WriteString(fmt.Sprintf("# Access List: %s\n", accessList.Name))
+	if accessList.Description != "" {
+		config.WriteString(fmt.Sprintf("# Description: %s\n", accessList.Description))
+	}
+	config.WriteString("\n")
+
+	var denies, allows, geo []models.AccessListItem
+	hasAuth := false
+	for _, item := range accessList.GetEnabledItems() {
+		switch {
+		case item.IsIPItem() && item.Directive == models.AccessListDirectiveDeny:
+			denies = append(denies, item)
+		case item.IsIPItem():
+			allows = append(allows, item)
+		case item.IsGeoItem():
+			geo = append(geo, item)
+		case item.IsAuthItem():
+			hasAuth = true
+		}
+	}
+
+	if len(geo) > 0 {
+		config.WriteString("# Geo rules need mod_maxminddb and are not exported:\n")
+		for _, item := range geo {
+			config.WriteString(fmt.Sprintf("#   %s %s\n", item.Directive, item.GetDisplayName()))
+		}
+		config.WriteString("\n")
+	}
+
+	if hasAuth {
+		config.WriteString("# HTTP Authentication\n")
+		config.WriteString("AuthType Basic\n")
+		config.WriteString("AuthName \"Restricted Area\"\n")
+		config.WriteString("AuthUserFile /etc/apache2/.htpasswd\n\n")
+	}
+
+	config.WriteString("<RequireAll>\n")
+	if len(allows) == 0 && !hasAuth {
+		config.WriteString("    Require all granted\n")
+	}
+	for _, item := range denies {
+		writeApacheRequireIP(&config, "    ", "Require not ip", &item)
+	}
+	if len(allows) > 0 {
+		config.WriteString("    <RequireAny>\n")
+		for _, item := range allows {
+			writeApacheRequireIP(&config, "        ", "Require ip", &item)
+		}
+		config.WriteString("    </RequireAny>\n")
+	}
+	if hasAuth {
+		config.WriteString("    Require valid-user\n")
+	}
+	config.WriteString("</RequireAll>\n")
+
+	return config.String()
+}
+
+// writeApacheRequireIP writes one Require line for an IP or CIDR item,
+// preceded by its comment
+func writeApacheRequireIP(config *strings.Builder, indent, directive string, item *models.AccessListItem) {
+	var address string
+	switch item.Type {
+	case models.AccessListItemTypeIP:
+		if ip := models.ParseAccessListIP(item.Address); ip != nil {
+			address = ip.String()
+		}
+	case models.AccessListItemTypeCIDR:
+		if cidr, err := models.ParseAccessListCIDR(item.Subnet); err == nil {
+			address = cidr.String()
+		}
+	}
+	if address == "" {
+		return
+	}
+
+	if item.Comment != "" {
+		config.WriteString(fmt.Sprintf("%s# %s\n", indent, item.Comment))
+	}
+	config.WriteString(fmt.Sprintf("%s%s %s\n", indent, directive, address))
+}
+
+// exportedAccessList is the json export of an access list; auth items carry
+// the username only, their passwords are not exported
+type exportedAccessList struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Items       []exportedAccessItem `json:"items"`
+}
+
+type exportedAccessItem struct {
+	Type        models.AccessListItemType  `json:"type"`
+	Directive   models.AccessListDirective `json:"directive"`
+	Address     string                     `json:"address,omitempty"`
+	Subnet      string                     `json:"subnet,omitempty"`
+	CountryCode string                     `json:"country_code,omitempty"`
+	Username    string                     `json:"username,omitempty"`
+	Comment     string                     `json:"comment,omitempty"`
+	Enabled     bool                       `json:"enabled"`
+}
+
+// exportAccessListJSON dumps every rule, including disabled ones
+func exportAccessListJSON(accessList *models.AccessList) (string, error) {
+	export := exportedAccessList{
+		Name:        accessList.Name,
+		Description: accessList.Description,
+		Items:       make([]exportedAccessItem, 0, len(accessList.Items)),
 	}
+	for _, item := range accessList.Items {
+		export.Items = append(export.Items, exportedAccessItem{
+			Type:        item.Type,
+			Directive:   item.Directive,
+			Address:     item.Address,
+			Subnet:      item.Subnet,
+			CountryCode: item.CountryCode,
+			Username:    item.Username,
+			Comment:     item.Comment,
+			Enabled:     item.Enabled,
+		})
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// writeGeoRules writes the GeoIP lookup and country map (http context) and the
+// if guard (server or location context) for an access list's geo rules. A
+// country that has both an allow and a deny rule is denied, and unmatched
+// countries are denied when allow rules exist, as in EvaluateGeoAccess.
+func (s *AccessListService) writeGeoRules(config *strings.Builder, accessList *models.AccessList) {
+	path := s.geoIPDatabasePath()
+	if path == "" {
+		config.WriteString("# Geo rules skipped: no GeoIP database configured\n\n")
+		return
+	}
+
+	denied := make(map[string]int)
+	defaultDenied := 0
+	for _, item := range accessList.GetEnabledItems() {
+		if !item.IsGeoItem() || !models.IsValidCountryCode(item.CountryCode) {
+			continue
+		}
+		if item.Directive == models.AccessListDirectiveDeny {
+			denied[item.CountryCode] = 1
+			continue
+		}
+		defaultDenied = 1
+		if _, ok := denied[item.CountryCode]; !ok {
+			denied[item.CountryCode] = 0
+		}
+	}
+
+	countries := make([]string, 0, len(denied))
+	for country := range denied {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+
+	countryVar := fmt.Sprintf("$access_list_%d_country", accessList.ID)
+	deniedVar := fmt.Sprintf("$access_list_%d_geo_denied", accessList.ID)
+
+	config.WriteString("# GeoIP country rules (http context, requires ngx_http_geoip2_module)\n")
+	config.WriteString(fmt.Sprintf("geoip2 %s {\n", path))
+	config.WriteString(fmt.Sprintf("    %s country iso_code;\n", countryVar))
+	config.WriteString("}\n")
+	config.WriteString(fmt.Sprintf("map %s %s {\n", countryVar, deniedVar))
+	config.WriteString(fmt.Sprintf("    default %d;\n", defaultDenied))
+	for _, country := range countries {
+		config.WriteString(fmt.Sprintf("    %s %d;\n", country, denied[country]))
+	}
+	config.WriteString("}\n\n")
 
-	return config.String(), nil
+	config.WriteString("# GeoIP guard (server or location context)\n")
+	config.WriteString(fmt.Sprintf("if (%s) {\n", deniedVar))
+	config.WriteString("    return 403;\n")
+	config.WriteString("}\n\n")
 }
 
 // ImportAccessList imports access list rules from nginx configuration
-func (s *AccessListService) ImportAccessList(userID uint, name string, config string) (*models.AccessList, error) {
+func (s *AccessListService) ImportAccessList(userID uint, name string, config string, audit AuditContext) (*models.AccessList, error) {
 	// Parse nginx configuration and create access list
 	// This is a simplified implementation
 	items := []AccessListItemRequest{}
+	hasAllowRules := false
+	reachedAll := false // nginx never reaches rules after an "all" rule
 
 	lines := strings.Split(config, "\n")
 	for _, line := range lines {
@@ -448,18 +916,38 @@ func (s *AccessListService) ImportAccessList(userID uint, name string, config st
 		}
 
 		// Parse allow/deny rules
-		if strings.HasPrefix(line, "allow ") || strings.HasPrefix(line, "deny ") {
+		if (strings.HasPrefix(line, "allow ") || strings.HasPrefix(line, "deny ")) && !reachedAll {
 			parts := strings.Fields(line)
 			if len(parts) >= 2 {
 				directive := models.AccessListDirective(parts[0])
 				address := strings.TrimSuffix(parts[1], ";")
 
+				// A deny all after allow rules is implied, as export ends allow
+				// rules with one. Otherwise "all" covers every IPv4 and IPv6
+				// address, so allow all lets through what earlier rules did not deny.
+				if address == "all" {
+					reachedAll = true
+					if directive == models.AccessListDirectiveDeny && hasAllowRules {
+						continue
+					}
+					for _, network := range []string{"0.0.0.0/0", "::/0"} {
+						items = append(items, AccessListItemRequest{
+							Type:      models.AccessListItemTypeCIDR,
+							Directive: directive,
+							Subnet:    network,
+						})
+					}
+					continue
+				}
+				if directive == models.AccessListDirectiveAllow {
+					hasAllowRules = true
+				}
+
 				itemType := models.AccessListItemTypeIP
 				itemReq := AccessListItemRequest{
 					Type:      itemType,
 					Directive: directive,
 					Address:   address,
-					Enabled:   true,
 				}
 
 				// Check if it's a CIDR notation
@@ -481,7 +969,7 @@ func (s *AccessListService) ImportAccessList(userID uint, name string, config st
 		Items:       items,
 	}
 
-	return s.CreateAccessList(userID, req)
+	return s.CreateAccessList(userID, req, audit)
 }
 
 // validateAccessListRequest validates an access list request
@@ -504,7 +992,7 @@ func (s *AccessListService) validateAccessListRequest(req *AccessListRequest) er
 			if item.Address == "" {
 				return fmt.Errorf("item %d: IP address is required", i+1)
 			}
-			if net.ParseIP(item.Address) == nil {
+			if models.ParseAccessListIP(item.Address) == nil {
 				return fmt.Errorf("item %d: invalid IP address format", i+1)
 			}
 
@@ -512,10 +1000,18 @@ func (s *AccessListService) validateAccessListRequest(req *AccessListRequest) er
 			if item.Subnet == "" {
 				return fmt.Errorf("item %d: subnet is required", i+1)
 			}
-			if _, _, err := net.ParseCIDR(item.Subnet); err != nil {
+			if _, err := models.ParseAccessListCIDR(item.Subnet); err != nil {
 				return fmt.Errorf("item %d: invalid CIDR format", i+1)
 			}
 
+		case models.AccessListItemTypeGeo:
+			if item.CountryCode == "" {
+				return fmt.Errorf("item %d: country code is required", i+1)
+			}
+			if !models.IsValidCountryCode(strings.ToUpper(strings.TrimSpace(item.CountryCode))) {
+				return fmt.Errorf("item %d: country code must be a two-letter ISO 3166-1 code", i+1)
+			}
+
 		case models.AccessListItemTypeAuth:
 			if item.Username == "" {
 				return fmt.Errorf("item %d: username is required", i+1)
@@ -528,3 +1024,234 @@ func (s *AccessListService) validateAccessListRequest(req *AccessListRequest) er
 
 	return nil
 }
+
+// Access list normalization actions
+const (
+	NormalizeActionTrimmed    = "trimmed"
+	NormalizeActionNormalized = "normalized"
+	NormalizeActionDuplicate  = "removed_duplicate"
+	NormalizeActionDisabled   = "disabled"
+)
+
+// AccessListNormalizeChange describes one change made (or proposed) to an item
+type AccessListNormalizeChange struct {
+	ItemID uint   `json:"item_id"`
+	Action string `json:"action"`
+	Field  string `json:"field,omitempty"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// AccessListNormalizeReport lists the changes of a normalize pass. Nothing is
+// written when DryRun is set.
+type AccessListNormalizeReport struct {
+	AccessListID uint                        `json:"access_list_id"`
+	DryRun       bool                        `json:"dry_run"`
+	Changes      []AccessListNormalizeChange `json:"changes"`
+	Count        int                         `json:"count"`
+}
+
+// NormalizeAccessList cleans up an access list's items: whitespace is trimmed,
+// addresses and CIDR blocks are rewritten in canonical form (10.0.0.1/8
+// becomes 10.0.0.0/8), identical rules are removed keeping the first enabled
+// copy, and items that still fail validation are disabled.
+func (s *AccessListService) NormalizeAccessList(userID uint, id uint, dryRun bool, audit AuditContext) (*AccessListNormalizeReport, error) {
+	accessList, err := s.GetAccessList(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	beforeRules := accessListAuditRules(accessList.Items)
+
+	report := &AccessListNormalizeReport{
+		AccessListID: accessList.ID,
+		DryRun:       dryRun,
+		Changes:      []AccessListNormalizeChange{},
+	}
+
+	items := accessList.Items
+	changed := make(map[int]bool)
+	for i := range items {
+		for _, change := range normalizeAccessListItem(&items[i]) {
+			report.Changes = append(report.Changes, change)
+			changed[i] = true
+		}
+	}
+
+	// Pick the copy of each rule to keep: the first enabled one, else the first
+	keep := make(map[string]int)
+	for i := range items {
+		key := accessListItemKey(&items[i])
+		if kept, ok := keep[key]; !ok || (!items[kept].Enabled && items[i].Enabled) {
+			keep[key] = i
+		}
+	}
+
+	var duplicates []uint
+	for i := range items {
+		item := &items[i]
+		key := accessListItemKey(item)
+		if kept := keep[key]; kept != i {
+			duplicates = append(duplicates, item.ID)
+			delete(changed, i)
+			report.Changes = append(report.Changes, AccessListNormalizeChange{
+				ItemID: item.ID,
+				Action: NormalizeActionDuplicate,
+				Reason: fmt.Sprintf("duplicate of item %d", items[kept].ID),
+			})
+			continue
+		}
+
+		if errs := item.Validate(); item.Enabled && len(errs) > 0 {
+			item.Enabled = false
+			changed[i] = true
+			report.Changes = append(report.Changes, AccessListNormalizeChange{
+				ItemID: item.ID,
+				Action: NormalizeActionDisabled,
+				Reason: strings.Join(errs, "; "),
+			})
+		}
+	}
+
+	report.Count = len(report.Changes)
+	if dryRun || report.Count == 0 {
+		return report, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range items {
+			if !changed[i] {
+				continue
+			}
+			if err := tx.Select("type", "address", "subnet", "country_code", "username", "comment", "enabled").
+				Save(&items[i]).Error; err != nil {
+				return err
+			}
+		}
+		if len(duplicates) > 0 {
+			if err := tx.Where("id IN ?", duplicates).Delete(&models.AccessListItem{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var afterRules []string
+	for i := range items {
+		if keep[accessListItemKey(&items[i])] == i {
+			afterRules = append(afterRules, accessListAuditRule(&items[i]))
+		}
+	}
+	auditLog := NewAuditLog(userID, models.ActionUpdated, models.ObjectTypeAccessList, accessList.ID,
+		fmt.Sprintf("Normalized access list: %s (%d changes)", accessList.Name, report.Count))
+	auditLog.Meta = models.JSON{"rules": AuditFieldChange{Before: beforeRules, After: afterRules}}
+	RecordAudit(s.db, audit, auditLog)
+
+	return report, nil
+}
+
+// normalizeAccessListItem trims an item's fields and rewrites IP and CIDR
+// values in canonical form, returning the changes it made
+func normalizeAccessListItem(item *models.AccessListItem) []AccessListNormalizeChange {
+	var changes []AccessListNormalizeChange
+	set := func(field string, value *string, normalized, action string) {
+		if *value == normalized {
+			return
+		}
+		changes = append(changes, AccessListNormalizeChange{
+			ItemID: item.ID,
+			Action: action,
+			Field:  field,
+			Before: *value,
+			After:  normalized,
+		})
+		*value = normalized
+	}
+
+	set("address", &item.Address, strings.TrimSpace(item.Address), NormalizeActionTrimmed)
+	set("subnet", &item.Subnet, strings.TrimSpace(item.Subnet), NormalizeActionTrimmed)
+	set("username", &item.Username, strings.TrimSpace(item.Username), NormalizeActionTrimmed)
+	set("country_code", &item.CountryCode, strings.TrimSpace(item.CountryCode), NormalizeActionTrimmed)
+	set("comment", &item.Comment, strings.TrimSpace(item.Comment), NormalizeActionTrimmed)
+
+	switch item.Type {
+	case models.AccessListItemTypeIP:
+		// An IP item holding a CIDR block becomes a CIDR item
+		if strings.Contains(item.Address, "/") && item.Subnet == "" {
+			if _, err := models.ParseAccessListCIDR(item.Address); err == nil {
+				before := string(item.Type)
+				item.Type = models.AccessListItemTypeCIDR
+				item.Subnet, item.Address = item.Address, ""
+				changes = append(changes, AccessListNormalizeChange{
+					ItemID: item.ID,
+					Action: NormalizeActionNormalized,
+					Field:  "type",
+					Before: before,
+					After:  string(item.Type),
+				})
+				return append(changes, normalizeAccessListItem(item)...)
+			}
+		}
+		if ip := models.ParseAccessListIP(item.Address); ip != nil {
+			set("address", &item.Address, ip.String(), NormalizeActionNormalized)
+		}
+
+	case models.AccessListItemTypeCIDR:
+		if cidr, err := models.ParseAccessListCIDR(item.Subnet); err == nil {
+			set("subnet", &item.Subnet, cidr.String(), NormalizeActionNormalized)
+		}
+
+	case models.AccessListItemTypeGeo:
+		set("country_code", &item.CountryCode, strings.ToUpper(item.CountryCode), NormalizeActionNormalized)
+	}
+
+	return changes
+}
+
+// accessListAuditRules describes an access list's rules for the audit log.
+// Auth rules show the username only.
+func accessListAuditRules(items []models.AccessListItem) []string {
+	rules := make([]string, len(items))
+	for i := range items {
+		rules[i] = accessListAuditRule(&items[i])
+	}
+	return rules
+}
+
+// accessListAuditRule describes one rule, such as "deny cidr 10.0.0.0/8"
+func accessListAuditRule(item *models.AccessListItem) string {
+	value := item.Address
+	switch item.Type {
+	case models.AccessListItemTypeCIDR:
+		value = item.Subnet
+	case models.AccessListItemTypeGeo:
+		value = item.CountryCode
+	case models.AccessListItemTypeAuth:
+		value = item.Username
+	}
+
+	rule := fmt.Sprintf("%s %s %s", item.Directive, item.Type, value)
+	if !item.Enabled {
+		rule += " (disabled)"
+	}
+	return rule
+}
+
+// accessListItemKey identifies rules that have the same effect
+func accessListItemKey(item *models.AccessListItem) string {
+	switch item.Type {
+	case models.AccessListItemTypeIP:
+		return fmt.Sprintf("%s|%s|%s", item.Type, item.Directive, item.Address)
+	case models.AccessListItemTypeCIDR:
+		return fmt.Sprintf("%s|%s|%s", item.Type, item.Directive, item.Subnet)
+	case models.AccessListItemTypeGeo:
+		return fmt.Sprintf("%s|%s|%s", item.Type, item.Directive, item.CountryCode)
+	case models.AccessListItemTypeAuth:
+		return fmt.Sprintf("%s|%s|%s", item.Type, item.Directive, item.Username)
+	default:
+		return fmt.Sprintf("item|%d", item.ID)
+	}
+}
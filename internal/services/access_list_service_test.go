@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestImportAccessListAllRules imports configs ending in "all" rules and
+// checks that clients get the access nginx would give them
+func TestImportAccessListAllRules(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	s := NewAccessListService(NewAuthService("test"))
+
+	for _, tc := range []struct {
+		name    string
+		config  string
+		allowed map[string]bool
+	}{
+		{
+			name:    "allow all after allow rules",
+			config:  "allow 10.0.0.0/8;\nallow 2001:db8::/32;\nallow all;\n",
+			allowed: map[string]bool{"10.1.1.1": true, "203.0.113.9": true, "2001:db9::1": true},
+		},
+		{
+			name:    "allow all after deny rules",
+			config:  "deny 203.0.113.9;\ndeny 2001:db8::/32;\nallow all;\n",
+			allowed: map[string]bool{"203.0.113.9": false, "2001:db8::1": false, "198.51.100.1": true, "2001:db9::1": true},
+		},
+		{
+			name:    "deny all after allow rules",
+			config:  "allow 10.0.0.0/8;\nallow ::1;\ndeny all;\n",
+			allowed: map[string]bool{"10.1.1.1": true, "::1": true, "203.0.113.9": false, "2001:db8::1": false},
+		},
+		{
+			name:    "deny all alone",
+			config:  "deny all;\n",
+			allowed: map[string]bool{"10.1.1.1": false, "2001:db8::1": false},
+		},
+		{
+			name:    "rules after all are unreachable",
+			config:  "allow all;\ndeny 203.0.113.9;\n",
+			allowed: map[string]bool{"203.0.113.9": true},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			accessList, err := s.ImportAccessList(owner.ID, tc.name, tc.config, AuditContext{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for ip, want := range tc.allowed {
+				result, err := s.TestIP(owner.ID, accessList.ID, &TestIPRequest{IPAddress: ip})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Allowed != want {
+					t.Errorf("%s: got allowed=%v, want %v (%s)", ip, result.Allowed, want, result.Message)
+				}
+			}
+		})
+	}
+}
+
+// TestCreateAccessListItemEnabled creates items turned off and with enabled
+// left out, which are enabled
+func TestCreateAccessListItemEnabled(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	s := NewAccessListService(NewAuthService("test"))
+
+	disabled := false
+	accessList, err := s.CreateAccessList(owner.ID, &AccessListRequest{
+		Name: "office",
+		Items: []AccessListItemRequest{
+			{Type: models.AccessListItemTypeIP, Directive: models.AccessListDirectiveAllow, Address: "10.0.0.1", Enabled: &disabled},
+			{Type: models.AccessListItemTypeIP, Directive: models.AccessListDirectiveAllow, Address: "10.0.0.2"},
+		},
+	}, AuditContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []models.AccessListItem
+	if err := db.Where("access_list_id = ?", accessList.ID).Order("address").Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Enabled || !items[1].Enabled {
+		t.Errorf("got items %+v, want 10.0.0.1 disabled and 10.0.0.2 enabled", items)
+	}
+}
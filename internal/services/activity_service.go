@@ -0,0 +1,129 @@
+package services
+
+import (
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Activity event types
+const (
+	ActivityTypeProxyHost   = "proxy_host"
+	ActivityTypeCertificate = "certificate"
+	ActivityTypeNginxConfig = "nginx_config"
+)
+
+// Activity event levels
+const (
+	ActivityLevelInfo    = "info"
+	ActivityLevelWarning = "warning"
+	ActivityLevelError   = "error"
+)
+
+// activityRetention is how long activity events are kept
+const activityRetention = 30 * 24 * time.Hour
+
+// ActivityFilter narrows the activity feed
+type ActivityFilter struct {
+	Limit   int
+	SinceID uint // only events with a greater ID
+	Type    string
+	Level   string
+}
+
+// ActivityService records lifecycle events for the monitoring activity feed
+// and pushes them to WebSocket clients
+type ActivityService struct {
+	db                *gorm.DB
+	monitoringService *MonitoringService
+}
+
+// NewActivityService creates a new activity service. monitoringService may be
+// nil, in which case events are stored but not broadcast.
+func NewActivityService(monitoringService *MonitoringService) *ActivityService {
+	return &ActivityService{
+		db:                database.GetDB(),
+		monitoringService: monitoringService,
+	}
+}
+
+// Record stores a system event visible to every user. Failures are logged
+// and never fail the operation being recorded; a nil service records nothing.
+func (s *ActivityService) Record(eventType, message, level string, details models.JSON) {
+	s.record(nil, eventType, message, level, details)
+}
+
+// RecordForUser stores an event about a resource owned by userID, visible to
+// that user and admins
+func (s *ActivityService) RecordForUser(userID uint, eventType, message, level string, details models.JSON) {
+	s.record(&userID, eventType, message, level, details)
+}
+
+func (s *ActivityService) record(userID *uint, eventType, message, level string, details models.JSON) {
+	if s == nil {
+		return
+	}
+
+	event := &models.ActivityEvent{
+		Timestamp: time.Now(),
+		Type:      eventType,
+		Level:     level,
+		Message:   message,
+		Details:   details,
+		UserID:    userID,
+	}
+	if err := s.db.Create(event).Error; err != nil {
+		logger.Error("Failed to record activity event",
+			logger.String("type", eventType),
+			logger.Err(err))
+		return
+	}
+
+	if s.monitoringService != nil {
+		go s.monitoringService.PublishActivity(event)
+	}
+}
+
+// GetRecentActivity returns the newest events visible to viewer, newest first.
+// Admins see every event; other users see system events and their own.
+func (s *ActivityService) GetRecentActivity(viewer *models.User, filter ActivityFilter) ([]models.ActivityEvent, error) {
+	query := s.db.Model(&models.ActivityEvent{})
+	if !viewer.IsAdmin() {
+		query = query.Where("user_id IS NULL OR user_id = ?", viewer.ID)
+	}
+	if filter.SinceID > 0 {
+		query = query.Where("id > ?", filter.SinceID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Level != "" {
+		query = query.Where("level = ?", filter.Level)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	events := make([]models.ActivityEvent, 0)
+	if err := query.Order("timestamp DESC, id DESC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// CleanupOldActivity deletes events older than the retention period
+func (s *ActivityService) CleanupOldActivity() error {
+	result := s.db.Where("timestamp < ?", time.Now().Add(-activityRetention)).
+		Delete(&models.ActivityEvent{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		logger.Info("Cleaned up old activity events", logger.Int64("deleted", result.RowsAffected))
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// AlertConfigExportVersion is the format version written by ExportAlertConfig
+const AlertConfigExportVersion = 1
+
+// AlertConfigExport is a portable copy of a user's alert rules and
+// notification channels. Rules reference channels by name and channel
+// secrets are redacted.
+type AlertConfigExport struct {
+	Version    int                    `json:"version"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Channels   []ExportedAlertChannel `json:"channels"`
+	Rules      []ExportedAlertRule    `json:"rules"`
+}
+
+// ExportedAlertChannel is a notification channel without IDs or ownership
+type ExportedAlertChannel struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	IsEnabled     bool        `json:"is_enabled"`
+	IsFallback    bool        `json:"is_fallback"`
+	Configuration models.JSON `json:"configuration"`
+}
+
+// ExportedAlertRule is an alert rule whose channels are referenced by name
+type ExportedAlertRule struct {
+	Name             string      `json:"name"`
+	Description      string      `json:"description"`
+	MetricType       string      `json:"metric_type"`
+	MetricName       string      `json:"metric_name"`
+	Condition        string      `json:"condition"`
+	Threshold        float64     `json:"threshold"`
+	ThresholdMax     *float64    `json:"threshold_max,omitempty"`
+	Severity         string      `json:"severity"`
+	IsEnabled        bool        `json:"is_enabled"`
+	EvaluationWindow int         `json:"evaluation_window"`
+	Sustained        bool        `json:"sustained"`
+	RenotifyInterval int         `json:"renotify_interval"`
+	NotifyOnResolve  bool        `json:"notify_on_resolve"`
+	Channels         []string    `json:"channels"`
+	Tags             models.JSON `json:"tags,omitempty"`
+	MetricFilter     models.JSON `json:"metric_filter,omitempty"`
+}
+
+// AlertConfigImportResult reports what an import created. Warnings list
+// skipped items and channels whose redacted secrets must be re-entered.
+type AlertConfigImportResult struct {
+	ChannelsCreated int      `json:"channels_created"`
+	ChannelsReused  int      `json:"channels_reused"`
+	RulesCreated    int      `json:"rules_created"`
+	RulesSkipped    int      `json:"rules_skipped"`
+	Warnings        []string `json:"warnings"`
+}
+
+// AlertConfigImportError lists the problems that prevented an import
+type AlertConfigImportError struct {
+	Problems []string
+}
+
+func (e *AlertConfigImportError) Error() string {
+	return "invalid alert configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// ExportAlertConfig returns the user's alert rules and notification channels
+// with channel secrets redacted
+func (as *AnalyticsService) ExportAlertConfig(userID uint) (*AlertConfigExport, error) {
+	var channels []models.NotificationChannel
+	if err := as.db.Where("user_id = ?", userID).Order("name ASC").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+
+	var rules []models.AlertRule
+	if err := as.db.Where("user_id = ?", userID).
+		Preload("NotificationChannels").
+		Order("name ASC").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	export := &AlertConfigExport{
+		Version:    AlertConfigExportVersion,
+		ExportedAt: time.Now(),
+		Channels:   make([]ExportedAlertChannel, 0, len(channels)),
+		Rules:      make([]ExportedAlertRule, 0, len(rules)),
+	}
+
+	for i := range channels {
+		channel := &channels[i]
+		MaskChannelSecrets(channel)
+		export.Channels = append(export.Channels, ExportedAlertChannel{
+			Name:          channel.Name,
+			Type:          channel.Type,
+			IsEnabled:     channel.IsEnabled,
+			IsFallback:    channel.IsFallback,
+			Configuration: channel.Configuration,
+		})
+	}
+
+	for _, rule := range rules {
+		channelNames := make([]string, 0, len(rule.NotificationChannels))
+		for _, channel := range rule.NotificationChannels {
+			channelNames = append(channelNames, channel.Name)
+		}
+		export.Rules = append(export.Rules, ExportedAlertRule{
+			Name:             rule.Name,
+			Description:      rule.Description,
+			MetricType:       rule.MetricType,
+			MetricName:       rule.MetricName,
+			Condition:        rule.Condition,
+			Threshold:        rule.Threshold,
+			ThresholdMax:     rule.ThresholdMax,
+			Severity:         rule.Severity,
+			IsEnabled:        rule.IsEnabled,
+			EvaluationWindow: rule.EvaluationWindow,
+			Sustained:        rule.Sustained,
+			RenotifyInterval: rule.RenotifyInterval,
+			NotifyOnResolve:  rule.NotifyOnResolve,
+			Channels:         channelNames,
+			Tags:             rule.Tags,
+			MetricFilter:     rule.MetricFilter,
+		})
+	}
+
+	return export, nil
+}
+
+// ImportAlertConfig recreates exported channels and rules for the user in a
+// single transaction. Channels whose name the user already has are reused
+// as-is; new channels with redacted secrets are created disabled until the
+// secret is re-entered. Rules whose name already exists are skipped, and
+// rule channel names are remapped to the user's channel IDs.
+func (as *AnalyticsService) ImportAlertConfig(userID uint, config *AlertConfigExport) (*AlertConfigImportResult, error) {
+	if config.Version != AlertConfigExportVersion {
+		return nil, &AlertConfigImportError{Problems: []string{
+			fmt.Sprintf("unsupported export version %d, expected %d", config.Version, AlertConfigExportVersion),
+		}}
+	}
+
+	result := &AlertConfigImportResult{Warnings: []string{}}
+
+	err := as.db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.NotificationChannel
+		if err := tx.Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+			return err
+		}
+		channelsByName := make(map[string]models.NotificationChannel, len(existing))
+		for _, channel := range existing {
+			channelsByName[channel.Name] = channel
+		}
+
+		var problems []string
+		for i, exported := range config.Channels {
+			if _, ok := channelsByName[exported.Name]; ok {
+				result.ChannelsReused++
+				continue
+			}
+
+			channel := models.NotificationChannel{
+				Name:          exported.Name,
+				Type:          exported.Type,
+				IsEnabled:     exported.IsEnabled,
+				IsFallback:    exported.IsFallback,
+				Configuration: exported.Configuration,
+				UserID:        userID,
+			}
+			if channel.Configuration == nil {
+				channel.Configuration = models.JSON{}
+			}
+
+			redacted := redactedChannelSecrets(&channel)
+			if len(redacted) > 0 {
+				channel.IsEnabled = false
+				result.Warnings = append(result.Warnings, fmt.Sprintf(
+					"channel %q was created disabled: re-enter %s and enable it", channel.Name, strings.Join(redacted, ", ")))
+			}
+
+			if err := ignoreRedactedFields(as.notificationService.ValidateChannel(&channel), redacted); err != nil {
+				problems = append(problems, fmt.Sprintf("channels[%d] %q: %v", i, exported.Name, describeChannelError(err)))
+				continue
+			}
+			if err := as.clearOtherFallbacks(tx, &channel); err != nil {
+				return err
+			}
+			if err := tx.Create(&channel).Error; err != nil {
+				return err
+			}
+			channelsByName[channel.Name] = channel
+			result.ChannelsCreated++
+		}
+
+		var existingRuleNames []string
+		if err := tx.Model(&models.AlertRule{}).Where("user_id = ?", userID).Pluck("name", &existingRuleNames).Error; err != nil {
+			return err
+		}
+		ruleNames := make(map[string]bool, len(existingRuleNames))
+		for _, name := range existingRuleNames {
+			ruleNames[name] = true
+		}
+
+		for i, exported := range config.Rules {
+			if exported.Name == "" || exported.MetricType == "" || exported.MetricName == "" ||
+				exported.Condition == "" || exported.Severity == "" {
+				problems = append(problems, fmt.Sprintf("rules[%d]: name, metric type, metric name, condition and severity are required", i))
+				continue
+			}
+			if ruleNames[exported.Name] {
+				result.RulesSkipped++
+				result.Warnings = append(result.Warnings, fmt.Sprintf("rule %q already exists and was skipped", exported.Name))
+				continue
+			}
+
+			channels := make([]models.NotificationChannel, 0, len(exported.Channels))
+			for _, name := range exported.Channels {
+				channel, ok := channelsByName[name]
+				if !ok {
+					problems = append(problems, fmt.Sprintf("rules[%d] %q: unknown notification channel %q", i, exported.Name, name))
+					continue
+				}
+				channels = append(channels, channel)
+			}
+
+			rule := models.AlertRule{
+				Name:                 exported.Name,
+				Description:          exported.Description,
+				MetricType:           exported.MetricType,
+				MetricName:           exported.MetricName,
+				Condition:            exported.Condition,
+				Threshold:            exported.Threshold,
+				ThresholdMax:         exported.ThresholdMax,
+				Severity:             exported.Severity,
+				IsEnabled:            exported.IsEnabled,
+				EvaluationWindow:     exported.EvaluationWindow,
+				Sustained:            exported.Sustained,
+				RenotifyInterval:     exported.RenotifyInterval,
+				NotifyOnResolve:      exported.NotifyOnResolve,
+				NotificationChannels: channels,
+				Tags:                 exported.Tags,
+				MetricFilter:         exported.MetricFilter,
+				UserID:               userID,
+			}
+			if err := tx.Omit("NotificationChannels.*").Create(&rule).Error; err != nil {
+				return err
+			}
+			ruleNames[rule.Name] = true
+			result.RulesCreated++
+		}
+
+		if len(problems) > 0 {
+			return &AlertConfigImportError{Problems: problems}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// redactedChannelSecrets returns the secret configuration keys that still
+// hold the redaction placeholder
+func redactedChannelSecrets(channel *models.NotificationChannel) []string {
+	var redacted []string
+	for _, key := range secretConfigKeys {
+		switch value := channel.Configuration[key].(type) {
+		case string:
+			if value == maskedSecret {
+				redacted = append(redacted, key)
+			}
+		case map[string]interface{}:
+			for _, headerValue := range value {
+				if headerValue == maskedSecret {
+					redacted = append(redacted, key)
+					break
+				}
+			}
+		}
+	}
+	return redacted
+}
+
+// ignoreRedactedFields drops the validation errors of redacted keys, which
+// only get their real values once re-entered after the import
+func ignoreRedactedFields(err error, redacted []string) error {
+	validationErr, ok := err.(*ChannelValidationError)
+	if !ok || len(redacted) == 0 {
+		return err
+	}
+	for _, key := range redacted {
+		delete(validationErr.Fields, "configuration."+key)
+	}
+	if len(validationErr.Fields) == 0 {
+		return nil
+	}
+	return validationErr
+}
+
+// describeChannelError flattens a channel validation error into one line
+func describeChannelError(err error) string {
+	validationErr, ok := err.(*ChannelValidationError)
+	if !ok {
+		return err.Error()
+	}
+
+	var messages []string
+	for field, fieldMessages := range validationErr.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field, strings.Join(fieldMessages, ", ")))
+	}
+	sort.Strings(messages)
+	return strings.Join(messages, "; ")
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nguyendkn/nginx-manager/internal/models"
@@ -12,13 +15,46 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	ErrSilenceNotFound             = errors.New("silence not found")
+	ErrNotificationChannelNotFound = errors.New("notification channel not found")
+)
+
+// maskedSecret replaces secret configuration values in API responses
+const maskedSecret = "********"
+
+// secretConfigKeys lists notification channel configuration keys that are
+// masked in responses and exports. Webhook URLs carry their token and webhook
+// headers usually hold credentials, so each header value is masked.
+var secretConfigKeys = []string{"password", "webhook_url", "url", "headers"}
+
 // AnalyticsService handles historical data, alerting, and performance insights
 type AnalyticsService struct {
 	db                  *gorm.DB
 	monitoringService   *MonitoringService
 	notificationService *NotificationService
+	timeSeriesStorage   bool
+
+	// Metrics collection interval, changeable at runtime from settings
+	intervalMutex   sync.Mutex
+	metricsInterval time.Duration
+	intervalChanged chan struct{}
+
+	// Serializes incremental aggregation updates, which read and rewrite rows
+	aggregationMutex sync.Mutex
+
+	// Retention policies, loaded on first use and reloaded after changes
+	retentionMutex    sync.Mutex
+	retentionPolicies []models.RetentionPolicy
+	retentionLoaded   bool
 }
 
+// defaultMetricsInterval is how often system metrics are stored by default
+const defaultMetricsInterval = 5 * time.Minute
+
+// rawMetricRetention is how long raw metric samples are kept
+const rawMetricRetention = 365 * 24 * time.Hour
+
 // TimeRange represents a time range for queries
 type TimeRange struct {
 	Start time.Time `json:"start"`
@@ -33,6 +69,8 @@ type MetricQuery struct {
 	Aggregation string            `json:"aggregation"` // avg, sum, min, max, p50, p95, p99
 	GroupBy     string            `json:"group_by"`    // time window: 5m, 1h, 1d, 1w
 	Tags        map[string]string `json:"tags"`
+	Source      string            `json:"source"`    // e.g. proxy_host; empty matches every source
+	SourceID    *uint             `json:"source_id"` // ID of the source entity
 	Limit       int               `json:"limit"`
 }
 
@@ -50,6 +88,7 @@ type TrendAnalysis struct {
 	Trend         string            `json:"trend"` // increasing, decreasing, stable
 	ChangePercent float64           `json:"change_percent"`
 	Confidence    float64           `json:"confidence"` // 0-100
+	Algorithm     string            `json:"algorithm"`  // anomaly detection algorithm: rolling or seasonal
 	Anomalies     []Anomaly         `json:"anomalies"`
 	Forecast      []MetricDataPoint `json:"forecast,omitempty"`
 }
@@ -147,23 +186,67 @@ func NewAnalyticsService(db *gorm.DB, monitoringService *MonitoringService, noti
 		db:                  db,
 		monitoringService:   monitoringService,
 		notificationService: notificationService,
+		metricsInterval:     defaultMetricsInterval,
+		intervalChanged:     make(chan struct{}, 1),
 	}
 }
 
+// SetMetricsCollectionInterval changes how often system metrics are stored.
+// A running collection loop picks up the new interval immediately.
+func (as *AnalyticsService) SetMetricsCollectionInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultMetricsInterval
+	}
+
+	as.intervalMutex.Lock()
+	changed := as.metricsInterval != interval
+	as.metricsInterval = interval
+	as.intervalMutex.Unlock()
+
+	if changed {
+		select {
+		case as.intervalChanged <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// metricsCollectionInterval returns the current collection interval
+func (as *AnalyticsService) metricsCollectionInterval() time.Duration {
+	as.intervalMutex.Lock()
+	defer as.intervalMutex.Unlock()
+	return as.metricsInterval
+}
+
+// SetTimeSeriesStorage switches raw metrics to the lean raw_metrics table.
+// Aggregations, alerts and other low-volume data are unaffected.
+func (as *AnalyticsService) SetTimeSeriesStorage(enabled bool) {
+	as.timeSeriesStorage = enabled
+}
+
 // StoreMetric stores a historical metric
 func (as *AnalyticsService) StoreMetric(metric *models.HistoricalMetric) error {
 	if metric.Timestamp.IsZero() {
 		metric.Timestamp = time.Now()
 	}
 
-	// Set default retention (1 year for raw metrics)
-	if metric.RetentionEnd == nil {
-		metric.SetRetention(365 * 24 * time.Hour)
-	}
+	if as.timeSeriesStorage {
+		rawMetric := metric.ToRawMetric()
+		if err := as.db.Create(rawMetric).Error; err != nil {
+			logger.Error("Failed to store metric", logger.Err(err))
+			return err
+		}
+		metric.ID = uint(rawMetric.ID)
+	} else {
+		// Set default retention (1 year for raw metrics)
+		if metric.RetentionEnd == nil {
+			metric.SetRetention(as.rawRetention(metric.MetricType, metric.MetricName))
+		}
 
-	if err := as.db.Create(metric).Error; err != nil {
-		logger.Error("Failed to store metric", logger.Err(err))
-		return err
+		if err := as.db.Create(metric).Error; err != nil {
+			logger.Error("Failed to store metric", logger.Err(err))
+			return err
+		}
 	}
 
 	// Check if this metric triggers any alerts
@@ -175,8 +258,47 @@ func (as *AnalyticsService) StoreMetric(metric *models.HistoricalMetric) error {
 	return nil
 }
 
-// StoreSystemMetrics stores current system metrics as historical data
+// metricsQuery returns a query against the table holding raw metrics
+func (as *AnalyticsService) metricsQuery() *gorm.DB {
+	if as.timeSeriesStorage {
+		return as.db.Model(&models.RawMetric{})
+	}
+	return as.db.Model(&models.HistoricalMetric{})
+}
+
+// findMetrics runs a metricsQuery and returns the rows as historical metrics
+func (as *AnalyticsService) findMetrics(query *gorm.DB) ([]models.HistoricalMetric, error) {
+	if !as.timeSeriesStorage {
+		var metrics []models.HistoricalMetric
+		if err := query.Find(&metrics).Error; err != nil {
+			return nil, err
+		}
+		return metrics, nil
+	}
+
+	var rawMetrics []models.RawMetric
+	if err := query.Find(&rawMetrics).Error; err != nil {
+		return nil, err
+	}
+
+	metrics := make([]models.HistoricalMetric, len(rawMetrics))
+	for i := range rawMetrics {
+		metrics[i] = rawMetrics[i].ToHistoricalMetric()
+	}
+	return metrics, nil
+}
+
+// StoreSystemMetrics stores current system metrics as historical data. Only
+// the collectors enabled in the metric-collectors setting are stored.
 func (as *AnalyticsService) StoreSystemMetrics() error {
+	enabled, err := as.enabledMetricCollectors()
+	if err != nil {
+		return err
+	}
+	if len(enabled) == 0 {
+		return nil
+	}
+
 	metrics, err := as.monitoringService.GetSystemMetrics()
 	if err != nil {
 		return err
@@ -184,89 +306,19 @@ func (as *AnalyticsService) StoreSystemMetrics() error {
 
 	timestamp := time.Now()
 
-	// Store CPU metrics
-	cpuMetrics := []*models.HistoricalMetric{
-		{
-			Timestamp:   timestamp,
-			MetricType:  "system",
-			MetricName:  "cpu_usage",
-			Value:       metrics.CPU.Usage,
-			Unit:        "percent",
-			Source:      "system",
-			Description: "CPU usage percentage",
-		},
-		{
-			Timestamp:   timestamp,
-			MetricType:  "system",
-			MetricName:  "load_avg_1",
-			Value:       metrics.CPU.LoadAvg1,
-			Unit:        "load",
-			Source:      "system",
-			Description: "1-minute load average",
-		},
-	}
-
-	// Store Memory metrics
-	memoryMetrics := []*models.HistoricalMetric{
-		{
-			Timestamp:   timestamp,
-			MetricType:  "system",
-			MetricName:  "memory_usage",
-			Value:       metrics.Memory.UsedPercent,
-			Unit:        "percent",
-			Source:      "system",
-			Description: "Memory usage percentage",
-		},
-		{
-			Timestamp:   timestamp,
-			MetricType:  "system",
-			MetricName:  "memory_used_bytes",
-			Value:       float64(metrics.Memory.Used),
-			Unit:        "bytes",
-			Source:      "system",
-			Description: "Memory used in bytes",
-		},
-	}
-
-	// Store Disk metrics
-	diskMetrics := []*models.HistoricalMetric{
-		{
-			Timestamp:   timestamp,
-			MetricType:  "system",
-			MetricName:  "disk_usage",
-			Value:       metrics.Disk.UsedPercent,
-			Unit:        "percent",
-			Source:      "system",
-			Description: "Disk usage percentage",
-		},
-		{
-			Timestamp:   timestamp,
-			MetricType:  "system",
-			MetricName:  "disk_used_bytes",
-			Value:       float64(metrics.Disk.Used),
-			Unit:        "bytes",
-			Source:      "system",
-			Description: "Disk used in bytes",
-		},
-	}
-
-	// Store Process metrics
-	processMetrics := []*models.HistoricalMetric{
-		{
-			Timestamp:   timestamp,
-			MetricType:  "system",
-			MetricName:  "goroutines",
-			Value:       float64(metrics.Process.Goroutines),
-			Unit:        "count",
-			Source:      "system",
-			Description: "Number of Go routines",
-		},
-	}
-
-	// Combine all metrics
-	allMetrics := append(cpuMetrics, memoryMetrics...)
-	allMetrics = append(allMetrics, diskMetrics...)
-	allMetrics = append(allMetrics, processMetrics...)
+	var allMetrics []*models.HistoricalMetric
+	for _, collector := range metricCollectors {
+		if !containsString(enabled, collector.Name) {
+			continue
+		}
+		if collector.collectNginx != nil {
+			if status, err := as.monitoringService.GetNginxStatus(); err == nil {
+				allMetrics = append(allMetrics, collector.collectNginx(status, timestamp)...)
+			}
+			continue
+		}
+		allMetrics = append(allMetrics, collector.collect(metrics, timestamp)...)
+	}
 
 	// Store all metrics
 	for _, metric := range allMetrics {
@@ -286,7 +338,7 @@ func (as *AnalyticsService) QueryMetrics(query MetricQuery) ([]MetricDataPoint,
 		query.Limit = 1000
 	}
 
-	db := as.db.Model(&models.HistoricalMetric{}).
+	db := as.metricsQuery().
 		Where("metric_type = ? AND metric_name = ?", query.MetricType, query.MetricName).
 		Where("timestamp BETWEEN ? AND ?", query.TimeRange.Start, query.TimeRange.End)
 
@@ -295,15 +347,26 @@ func (as *AnalyticsService) QueryMetrics(query MetricQuery) ([]MetricDataPoint,
 		db = db.Where("tags ->> ? = ?", key, value)
 	}
 
-	var metrics []models.HistoricalMetric
+	if query.Source != "" {
+		db = db.Where("source = ?", query.Source)
+		if query.SourceID != nil {
+			db = db.Where("source_id = ?", *query.SourceID)
+		}
+	}
 
 	if query.GroupBy != "" {
+		// Stored aggregations combine every source, so a single source is
+		// rolled up from its raw samples
+		if query.Source != "" {
+			return as.rollUpMetrics(db, query)
+		}
 		// Use aggregated data if available
 		return as.queryAggregatedMetrics(query)
 	}
 
 	// Query raw metrics
-	if err := db.Order("timestamp ASC").Limit(query.Limit).Find(&metrics).Error; err != nil {
+	metrics, err := as.findMetrics(db.Order("timestamp ASC").Limit(query.Limit))
+	if err != nil {
 		return nil, err
 	}
 
@@ -335,42 +398,93 @@ func (as *AnalyticsService) queryAggregatedMetrics(query MetricQuery) ([]MetricD
 
 	dataPoints := make([]MetricDataPoint, len(aggregations))
 	for i, agg := range aggregations {
-		var value float64
-		switch query.Aggregation {
-		case "avg":
-			value = agg.Avg
-		case "sum":
-			value = agg.Sum
-		case "min":
-			value = agg.Min
-		case "max":
-			value = agg.Max
-		case "p50":
-			value = agg.P50
-		case "p95":
-			value = agg.P95
-		case "p99":
-			value = agg.P99
-		default:
-			value = agg.Avg
+		dataPoints[i] = MetricDataPoint{
+			Timestamp: agg.Timestamp,
+			Value:     aggregationValue(&agg, query.Aggregation),
+			Tags:      agg.Tags,
 		}
+	}
+
+	return dataPoints, nil
+}
 
+// rollUpMetrics groups the raw samples selected by db into query.GroupBy
+// windows and calculates the requested aggregation for each window
+func (as *AnalyticsService) rollUpMetrics(db *gorm.DB, query MetricQuery) ([]MetricDataPoint, error) {
+	if !containsString(aggregationWindows, query.GroupBy) {
+		return nil, fmt.Errorf("%w %q, expected one of: %s", ErrInvalidAggregationWindow, query.GroupBy, strings.Join(aggregationWindows, ", "))
+	}
+
+	var samples []struct {
+		Timestamp time.Time
+		Value     float64
+	}
+	if err := db.Select("timestamp", "value").Order("timestamp ASC").Find(&samples).Error; err != nil {
+		return nil, err
+	}
+
+	var windows []*models.MetricAggregation
+	values := make(map[*models.MetricAggregation][]float64)
+	for _, sample := range samples {
+		start := as.getWindowStart(sample.Timestamp, query.GroupBy)
+		if len(windows) == 0 || !windows[len(windows)-1].Timestamp.Equal(start) {
+			if len(windows) == query.Limit {
+				break
+			}
+			windows = append(windows, &models.MetricAggregation{TimeWindow: query.GroupBy, Timestamp: start})
+		}
+		agg := windows[len(windows)-1]
+		addAggregationSample(agg, sample.Value)
+		values[agg] = append(values[agg], sample.Value)
+	}
+
+	dataPoints := make([]MetricDataPoint, len(windows))
+	for i, agg := range windows {
+		sort.Float64s(values[agg])
+		as.setPercentiles(agg, values[agg])
 		dataPoints[i] = MetricDataPoint{
 			Timestamp: agg.Timestamp,
-			Value:     value,
-			Tags:      agg.Tags,
+			Value:     aggregationValue(agg, query.Aggregation),
 		}
 	}
 
 	return dataPoints, nil
 }
 
+// aggregationValue picks a statistic of an aggregation, defaulting to the average
+func aggregationValue(agg *models.MetricAggregation, aggregation string) float64 {
+	switch aggregation {
+	case "sum":
+		return agg.Sum
+	case "min":
+		return agg.Min
+	case "max":
+		return agg.Max
+	case "p50":
+		return agg.P50
+	case "p95":
+		return agg.P95
+	case "p99":
+		return agg.P99
+	default:
+		return agg.Avg
+	}
+}
+
 // AnalyzeTrends performs trend analysis on metrics
-func (as *AnalyticsService) AnalyzeTrends(metricType, metricName string, timeRange TimeRange) (*TrendAnalysis, error) {
+func (as *AnalyticsService) AnalyzeTrends(req TrendRequest) (*TrendAnalysis, error) {
+	algorithm := req.Algorithm
+	if algorithm == "" {
+		algorithm = AnomalyAlgorithmRolling
+	}
+	if algorithm != AnomalyAlgorithmRolling && algorithm != AnomalyAlgorithmSeasonal {
+		return nil, fmt.Errorf("%w %q, expected %s or %s", ErrInvalidAnomalyAlgorithm, algorithm, AnomalyAlgorithmRolling, AnomalyAlgorithmSeasonal)
+	}
+
 	query := MetricQuery{
-		MetricType: metricType,
-		MetricName: metricName,
-		TimeRange:  timeRange,
+		MetricType: req.MetricType,
+		MetricName: req.MetricName,
+		TimeRange:  req.TimeRange,
 		GroupBy:    "1h", // Hourly aggregation for trend analysis
 		Limit:      1000,
 	}
@@ -382,9 +496,10 @@ func (as *AnalyticsService) AnalyzeTrends(metricType, metricName string, timeRan
 
 	if len(dataPoints) < 2 {
 		return &TrendAnalysis{
-			MetricName: metricName,
-			TimeRange:  timeRange,
+			MetricName: req.MetricName,
+			TimeRange:  req.TimeRange,
 			Trend:      "insufficient_data",
+			Algorithm:  algorithm,
 		}, nil
 	}
 
@@ -392,18 +507,93 @@ func (as *AnalyticsService) AnalyzeTrends(metricType, metricName string, timeRan
 	trend := as.calculateTrend(dataPoints)
 
 	// Detect anomalies
-	anomalies := as.detectAnomalies(dataPoints)
+	var anomalies []Anomaly
+	if algorithm == AnomalyAlgorithmSeasonal {
+		anomalies = as.detectSeasonalAnomalies(dataPoints)
+	} else {
+		anomalies = as.detectAnomalies(dataPoints)
+	}
+	as.recordAnomalyInsights(req, algorithm, anomalies)
 
 	return &TrendAnalysis{
-		MetricName:    metricName,
-		TimeRange:     timeRange,
+		MetricName:    req.MetricName,
+		TimeRange:     req.TimeRange,
 		Trend:         trend.Direction,
 		ChangePercent: trend.ChangePercent,
 		Confidence:    trend.Confidence,
+		Algorithm:     algorithm,
 		Anomalies:     anomalies,
 	}, nil
 }
 
+// AnalyzeNetwork summarizes stored network throughput over the time range.
+// Current, average and peak are combined receive+transmit bytes per second.
+func (as *AnalyticsService) AnalyzeNetwork(timeRange TimeRange) (*NetworkMetric, error) {
+	query := MetricQuery{
+		MetricType: "network",
+		TimeRange:  timeRange,
+		Limit:      10000,
+	}
+
+	query.MetricName = "bytes_in_rate"
+	inPoints, err := as.QueryMetrics(query)
+	if err != nil {
+		return nil, err
+	}
+
+	query.MetricName = "bytes_out_rate"
+	outPoints, err := as.QueryMetrics(query)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &NetworkMetric{}
+	analysis.Trend = "unknown"
+	analysis.AlertLevel = "normal"
+	if len(inPoints) == 0 && len(outPoints) == 0 {
+		return analysis, nil
+	}
+
+	if len(inPoints) > 0 {
+		analysis.BytesInRate = inPoints[len(inPoints)-1].Value
+	}
+	if len(outPoints) > 0 {
+		analysis.BytesOutRate = outPoints[len(outPoints)-1].Value
+	}
+
+	// Combine both directions per sample timestamp
+	totals := make(map[int64]float64)
+	for _, point := range inPoints {
+		totals[point.Timestamp.Unix()] += point.Value
+	}
+	for _, point := range outPoints {
+		totals[point.Timestamp.Unix()] += point.Value
+	}
+
+	combined := make([]MetricDataPoint, 0, len(totals))
+	for unix, value := range totals {
+		combined = append(combined, MetricDataPoint{Timestamp: time.Unix(unix, 0), Value: value})
+	}
+	sort.Slice(combined, func(i, j int) bool {
+		return combined[i].Timestamp.Before(combined[j].Timestamp)
+	})
+
+	var sum float64
+	for _, point := range combined {
+		sum += point.Value
+		if point.Value > analysis.Peak {
+			analysis.Peak = point.Value
+		}
+	}
+	latest := combined[len(combined)-1]
+	analysis.Current = latest.Value
+	analysis.Average = sum / float64(len(combined))
+	analysis.LastUpdated = latest.Timestamp
+	analysis.Trend = as.calculateTrend(combined).Direction
+
+	return analysis, nil
+}
+
 // calculateTrend calculates trend direction and confidence
 func (as *AnalyticsService) calculateTrend(dataPoints []MetricDataPoint) struct {
 	Direction     string
@@ -486,25 +676,8 @@ func (as *AnalyticsService) detectAnomalies(dataPoints []MetricDataPoint) []Anom
 		stdDev := math.Sqrt(variance)
 
 		// Check if current point is anomalous (> 2 standard deviations)
-		currentValue := dataPoints[i].Value
-		expectedMin := mean - 2*stdDev
-		expectedMax := mean + 2*stdDev
-
-		if currentValue < expectedMin || currentValue > expectedMax {
-			severity := "warning"
-			if currentValue < mean-3*stdDev || currentValue > mean+3*stdDev {
-				severity = "critical"
-			}
-
-			anomalies = append(anomalies, Anomaly{
-				Timestamp:   dataPoints[i].Timestamp,
-				Value:       currentValue,
-				ExpectedMin: expectedMin,
-				ExpectedMax: expectedMax,
-				Severity:    severity,
-				Description: fmt.Sprintf("Value %.2f outside expected range [%.2f, %.2f]",
-					currentValue, expectedMin, expectedMax),
-			})
+		if anomaly, ok := checkAnomaly(dataPoints[i], mean, stdDev); ok {
+			anomalies = append(anomalies, anomaly)
 		}
 	}
 
@@ -523,39 +696,309 @@ func (as *AnalyticsService) checkAlerts(metric *models.HistoricalMetric) {
 	}
 
 	for _, rule := range alertRules {
-		if rule.EvaluateCondition(metric.Value) {
-			// Create alert instance
-			alertInstance := &models.AlertInstance{
-				AlertRuleID:    rule.ID,
-				TriggeredAt:    metric.Timestamp,
-				Status:         "triggered",
-				CurrentValue:   metric.Value,
-				ThresholdValue: rule.Threshold,
-				Message: fmt.Sprintf("Alert '%s' triggered: %s value %.2f %s threshold %.2f",
-					rule.Name, metric.MetricName, metric.Value, rule.Condition, rule.Threshold),
-				Context: models.JSON{
-					"metric_type": metric.MetricType,
-					"metric_name": metric.MetricName,
-					"source":      metric.Source,
-					"source_id":   metric.SourceID,
-					"tags":        metric.Tags,
-				},
+		if !rule.MatchesMetric(metric.Tags) {
+			continue
+		}
+
+		breached, currentValue, err := as.evaluateAlertRule(&rule, metric)
+		if err != nil {
+			logger.Error("Failed to evaluate alert rule",
+				logger.Uint("rule_id", rule.ID),
+				logger.Err(err))
+			continue
+		}
+
+		// Reuse the open instance for this rule rather than creating a new one per breach
+		var activeAlert models.AlertInstance
+		hasActive := true
+		if err := as.db.Where("alert_rule_id = ? AND status IN ?", rule.ID, []string{"triggered", "suppressed"}).
+			Order("triggered_at DESC").
+			First(&activeAlert).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				logger.Error("Failed to query active alert instance", logger.Err(err))
+				continue
 			}
+			hasActive = false
+		}
 
-			if err := as.db.Create(alertInstance).Error; err != nil {
-				logger.Error("Failed to create alert instance", logger.Err(err))
+		if !breached {
+			if hasActive {
+				as.resolveAlert(&activeAlert, &rule, metric, currentValue)
+			}
+			continue
+		}
+
+		message := fmt.Sprintf("Alert '%s' triggered: %s value %.2f %s threshold %.2f",
+			rule.Name, metric.MetricName, currentValue, rule.Condition, rule.Threshold)
+		if rule.IsRateOfChange() {
+			message = fmt.Sprintf("Alert '%s' triggered: %s changed %.2f%% over %ds (%s threshold %.2f%%)",
+				rule.Name, metric.MetricName, currentValue, rule.EvaluationWindow, rule.Condition, rule.Threshold)
+		} else if rule.Sustained {
+			message = fmt.Sprintf("Alert '%s' triggered: %s value %.2f %s threshold %.2f for %ds",
+				rule.Name, metric.MetricName, currentValue, rule.Condition, rule.Threshold, rule.EvaluationWindow)
+		}
+
+		now := time.Now()
+		silenced := as.isSilenced(&rule, metric.Tags, now)
+
+		if hasActive {
+			activeAlert.CurrentValue = currentValue
+			activeAlert.Message = message
+
+			var renotify bool
+			if activeAlert.Status == "suppressed" {
+				// Silence has ended while the breach continues: notify now
+				renotify = !silenced
+				if renotify {
+					activeAlert.Status = "triggered"
+				}
+			} else {
+				renotify = !silenced && rule.RenotifyInterval > 0 &&
+					(activeAlert.LastNotifiedAt == nil ||
+						now.Sub(*activeAlert.LastNotifiedAt) >= time.Duration(rule.RenotifyInterval)*time.Second)
+			}
+			if renotify {
+				activeAlert.LastNotifiedAt = &now
+			}
+
+			if err := as.db.Model(&activeAlert).Updates(map[string]interface{}{
+				"status":           activeAlert.Status,
+				"current_value":    activeAlert.CurrentValue,
+				"message":          activeAlert.Message,
+				"last_notified_at": activeAlert.LastNotifiedAt,
+			}).Error; err != nil {
+				logger.Error("Failed to update alert instance", logger.Err(err))
 				continue
 			}
 
-			// Update rule's last triggered time
-			now := time.Now()
-			rule.LastTriggered = &now
-			as.db.Save(&rule)
+			if renotify {
+				alertCopy := activeAlert
+				go as.sendAlertNotifications(&alertCopy, &rule)
+			}
+			continue
+		}
+
+		// Create alert instance, suppressed if a silence covers it
+		status := "triggered"
+		lastNotifiedAt := &now
+		if silenced {
+			status = "suppressed"
+			lastNotifiedAt = nil
+		}
+
+		alertInstance := &models.AlertInstance{
+			AlertRuleID:    rule.ID,
+			TriggeredAt:    metric.Timestamp,
+			Status:         status,
+			CurrentValue:   currentValue,
+			ThresholdValue: rule.Threshold,
+			Message:        message,
+			LastNotifiedAt: lastNotifiedAt,
+			Context: models.JSON{
+				"metric_type":  metric.MetricType,
+				"metric_name":  metric.MetricName,
+				"metric_value": metric.Value,
+				"source":       metric.Source,
+				"source_id":    metric.SourceID,
+				"tags":         metric.Tags,
+			},
+		}
+
+		if err := as.db.Create(alertInstance).Error; err != nil {
+			logger.Error("Failed to create alert instance", logger.Err(err))
+			continue
+		}
+
+		// Update rule's last triggered time
+		rule.LastTriggered = &now
+		as.db.Model(&rule).Update("last_triggered", rule.LastTriggered)
+
+		if silenced {
+			logger.Info("Alert suppressed by active silence",
+				logger.Uint("alert_id", alertInstance.ID),
+				logger.String("rule_name", rule.Name))
+			continue
+		}
+
+		// Send notifications
+		as.publishAlert(alertInstance, &rule)
+		go as.sendAlertNotifications(alertInstance, &rule)
+	}
+}
+
+// publishAlert pushes a copy of the alert to WebSocket clients subscribed to alerts
+func (as *AnalyticsService) publishAlert(alert *models.AlertInstance, rule *models.AlertRule) {
+	if as.monitoringService == nil {
+		return
+	}
+
+	alertCopy := *alert
+	go as.monitoringService.PublishAlert(rule.UserID, &alertCopy)
+}
 
-			// Send notifications
-			go as.sendAlertNotifications(alertInstance, &rule)
+// isSilenced returns true if an active silence owned by the rule's user
+// matches the rule and metric tags
+func (as *AnalyticsService) isSilenced(rule *models.AlertRule, metricTags models.JSON, now time.Time) bool {
+	var silences []models.Silence
+	if err := as.db.Where("created_by = ? AND starts_at <= ? AND ends_at > ?", rule.UserID, now, now).
+		Find(&silences).Error; err != nil {
+		logger.Error("Failed to query silences", logger.Err(err))
+		return false
+	}
+
+	for _, silence := range silences {
+		if silence.Matches(rule, metricTags) {
+			return true
 		}
 	}
+	return false
+}
+
+// resolveAlert marks an open alert instance as resolved once its rule is no
+// longer breached, and notifies channels if the rule asks for it
+func (as *AnalyticsService) resolveAlert(alert *models.AlertInstance, rule *models.AlertRule, metric *models.HistoricalMetric, currentValue float64) {
+	// Suppressed alerts were never announced, so their resolution isn't either
+	wasNotified := alert.Status == "triggered"
+
+	resolvedAt := metric.Timestamp
+	alert.ResolvedAt = &resolvedAt
+	alert.Status = "resolved"
+	alert.CurrentValue = currentValue
+	alert.Message = fmt.Sprintf("Alert '%s' resolved: %s value %.2f no longer %s threshold %.2f",
+		rule.Name, metric.MetricName, metric.Value, rule.Condition, rule.Threshold)
+
+	if err := as.db.Model(alert).Updates(map[string]interface{}{
+		"resolved_at":   alert.ResolvedAt,
+		"status":        alert.Status,
+		"current_value": alert.CurrentValue,
+		"message":       alert.Message,
+	}).Error; err != nil {
+		logger.Error("Failed to resolve alert instance", logger.Err(err))
+		return
+	}
+
+	logger.Info("Alert resolved",
+		logger.Uint("alert_id", alert.ID),
+		logger.String("rule_name", rule.Name))
+
+	if wasNotified {
+		as.publishAlert(alert, rule)
+	}
+	if rule.NotifyOnResolve && wasNotified {
+		go as.sendResolutionNotifications(alert, rule)
+	}
+}
+
+// evaluateAlertRule evaluates a rule against the incoming metric. Rate-of-change
+// and sustained rules look at the samples recorded over the rule's evaluation
+// window instead of the single incoming value. Returns whether the rule is
+// breached and the value to report (percent change for change_* conditions).
+func (as *AnalyticsService) evaluateAlertRule(rule *models.AlertRule, metric *models.HistoricalMetric) (bool, float64, error) {
+	if !rule.IsRateOfChange() && !rule.Sustained {
+		return rule.EvaluateCondition(metric.Value), metric.Value, nil
+	}
+
+	baseline, samples, err := as.getMetricWindow(metric, time.Duration(rule.EvaluationWindow)*time.Second)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if rule.IsRateOfChange() {
+		oldest := baseline
+		if oldest == nil && len(samples) > 0 {
+			oldest = &samples[0]
+		}
+		if oldest == nil {
+			return false, 0, nil
+		}
+
+		change, breached := rule.EvaluateChange(oldest.Value, metric.Value)
+		return breached, change, nil
+	}
+
+	// Sustained: without a sample at or before the window start we cannot
+	// tell that the breach held for the whole window
+	if baseline == nil {
+		return false, metric.Value, nil
+	}
+
+	values := []float64{baseline.Value}
+	for _, sample := range samples {
+		values = append(values, sample.Value)
+	}
+
+	return rule.EvaluateSustained(values), metric.Value, nil
+}
+
+// baselineLookback bounds how many older samples are scanned for a tagged
+// series' baseline, since samples of other series share the same metric name
+const baselineLookback = 200
+
+// getMetricWindow returns the most recent sample at or before the window start
+// (nil if none) and all samples of the same series inside the window, oldest
+// first. Samples belong to the same series when their source and tags match.
+func (as *AnalyticsService) getMetricWindow(metric *models.HistoricalMetric, window time.Duration) (*models.HistoricalMetric, []models.HistoricalMetric, error) {
+	windowStart := metric.Timestamp.Add(-window)
+
+	series := func() *gorm.DB {
+		query := as.metricsQuery().
+			Where("metric_type = ? AND metric_name = ? AND source = ?",
+				metric.MetricType, metric.MetricName, metric.Source)
+		if metric.SourceID != nil {
+			query = query.Where("source_id = ?", *metric.SourceID)
+		} else {
+			query = query.Where("source_id IS NULL")
+		}
+		return query
+	}
+
+	windowSamples, err := as.findMetrics(series().
+		Where("timestamp > ? AND timestamp <= ?", windowStart, metric.Timestamp).
+		Order("timestamp ASC"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	samples := make([]models.HistoricalMetric, 0, len(windowSamples))
+	for _, sample := range windowSamples {
+		if sameTags(sample.Tags, metric.Tags) {
+			samples = append(samples, sample)
+		}
+	}
+
+	lookback := 1
+	if len(metric.Tags) > 0 {
+		lookback = baselineLookback
+	}
+
+	baselines, err := as.findMetrics(series().
+		Where("timestamp <= ?", windowStart).
+		Order("timestamp DESC").
+		Limit(lookback))
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range baselines {
+		if sameTags(baselines[i].Tags, metric.Tags) {
+			return &baselines[i], samples, nil
+		}
+	}
+
+	return nil, samples, nil
+}
+
+// sameTags reports whether two metric tag sets are equal
+func sameTags(a, b models.JSON) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		other, ok := b[key]
+		if !ok || fmt.Sprint(value) != fmt.Sprint(other) {
+			return false
+		}
+	}
+	return true
 }
 
 // sendAlertNotifications sends notifications for an alert
@@ -565,6 +1008,20 @@ func (as *AnalyticsService) sendAlertNotifications(alert *models.AlertInstance,
 		return
 	}
 
+	// Skip dispatch if a silence has started since the alert was evaluated
+	metricTags, _ := alert.Context["tags"].(models.JSON)
+	if metricTags == nil {
+		if tags, ok := alert.Context["tags"].(map[string]interface{}); ok {
+			metricTags = models.JSON(tags)
+		}
+	}
+	if as.isSilenced(rule, metricTags, time.Now()) {
+		logger.Info("Alert notification skipped by active silence",
+			logger.Uint("alert_id", alert.ID),
+			logger.String("rule_name", rule.Name))
+		return
+	}
+
 	// Load notification channels
 	var channels []models.NotificationChannel
 	if err := as.db.Model(rule).Association("NotificationChannels").Find(&channels); err != nil {
@@ -572,6 +1029,8 @@ func (as *AnalyticsService) sendAlertNotifications(alert *models.AlertInstance,
 		return
 	}
 
+	sent := 0
+	failed := make([]string, 0)
 	for _, channel := range channels {
 		if !channel.IsEnabled {
 			continue
@@ -582,56 +1041,114 @@ func (as *AnalyticsService) sendAlertNotifications(alert *models.AlertInstance,
 			logger.Error("Failed to send alert notification",
 				logger.String("channel", channel.Name),
 				logger.Err(err))
+			failed = append(failed, channel.Name)
 		} else {
-			alert.NotificationsSent++
+			sent++
 		}
 	}
 
-	// Update alert instance
-	as.db.Save(alert)
+	deliveryStatus := models.DeliveryStatusDelivered
+	if len(failed) > 0 {
+		// A primary channel failed after its retries: try the user's fallback channel
+		if as.sendFallbackNotification(alert, rule, channels) {
+			sent++
+			deliveryStatus = models.DeliveryStatusFellBack
+		} else if sent == 0 {
+			deliveryStatus = models.DeliveryStatusFailed
+		} else {
+			deliveryStatus = models.DeliveryStatusPartial
+		}
+
+		logger.Warn("Alert delivery failed on some channels",
+			logger.Uint("alert_id", alert.ID),
+			logger.Any("failed_channels", failed),
+			logger.String("delivery_status", deliveryStatus))
+	}
+
+	// Only touch delivery columns so concurrent evaluations of the same instance aren't overwritten
+	updates := map[string]interface{}{
+		"delivery_status": deliveryStatus,
+	}
+	if sent > 0 {
+		updates["notifications_sent"] = gorm.Expr("notifications_sent + ?", sent)
+	}
+	as.db.Model(&models.AlertInstance{}).
+		Where("id = ?", alert.ID).
+		UpdateColumns(updates)
 }
 
-// createAggregations creates time-window aggregations for a metric
-func (as *AnalyticsService) createAggregations(metric *models.HistoricalMetric) {
-	timeWindows := []string{"5m", "1h", "1d", "1w"}
+// sendFallbackNotification delivers an alert through the rule owner's fallback
+// channel, skipping it if it was one of the primary channels. Returns true on success.
+func (as *AnalyticsService) sendFallbackNotification(alert *models.AlertInstance, rule *models.AlertRule, primaries []models.NotificationChannel) bool {
+	var fallback models.NotificationChannel
+	if err := as.db.Where("user_id = ? AND is_fallback = ? AND is_enabled = ?", rule.UserID, true, true).
+		First(&fallback).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			logger.Error("Failed to load fallback notification channel", logger.Err(err))
+		}
+		return false
+	}
 
-	for _, window := range timeWindows {
-		as.createAggregation(metric, window)
+	for _, channel := range primaries {
+		if channel.ID == fallback.ID {
+			return false
+		}
+	}
+
+	if err := as.notificationService.SendAlert(fallback, alert, rule); err != nil {
+		logger.Error("Failed to send alert via fallback channel",
+			logger.String("channel", fallback.Name),
+			logger.Err(err))
+		return false
 	}
+
+	logger.Info("Alert delivered via fallback channel",
+		logger.Uint("alert_id", alert.ID),
+		logger.String("channel", fallback.Name))
+	return true
 }
 
-// createAggregation creates aggregation for a specific time window
-func (as *AnalyticsService) createAggregation(metric *models.HistoricalMetric, timeWindow string) {
-	windowStart := as.getWindowStart(metric.Timestamp, timeWindow)
-	windowEnd := as.getWindowEnd(windowStart, timeWindow)
+// sendResolutionNotifications notifies a rule's channels that its alert has resolved
+func (as *AnalyticsService) sendResolutionNotifications(alert *models.AlertInstance, rule *models.AlertRule) {
+	if as.notificationService == nil {
+		logger.Warn("Notification service not available")
+		return
+	}
 
-	// Check if aggregation already exists
-	var existingAgg models.MetricAggregation
-	err := as.db.Where("metric_type = ? AND metric_name = ? AND time_window = ? AND timestamp = ?",
-		metric.MetricType, metric.MetricName, timeWindow, windowStart).First(&existingAgg).Error
+	var channels []models.NotificationChannel
+	if err := as.db.Model(rule).Association("NotificationChannels").Find(&channels); err != nil {
+		logger.Error("Failed to load notification channels", logger.Err(err))
+		return
+	}
 
-	switch err {
-	case gorm.ErrRecordNotFound:
-		// Create new aggregation
-		agg := &models.MetricAggregation{
-			MetricType: metric.MetricType,
-			MetricName: metric.MetricName,
-			TimeWindow: timeWindow,
-			Timestamp:  windowStart,
+	for _, channel := range channels {
+		if !channel.IsEnabled {
+			continue
 		}
 
-		// Calculate aggregation values
-		as.calculateAggregationValues(agg, windowStart, windowEnd)
+		if err := as.notificationService.SendResolution(channel, alert, rule); err != nil {
+			logger.Error("Failed to send resolution notification",
+				logger.String("channel", channel.Name),
+				logger.Err(err))
+		}
+	}
+}
 
-		// Set retention (longer for aggregated data)
-		retentionDuration := as.getRetentionForWindow(timeWindow)
-		agg.SetRetention(retentionDuration)
+// createAggregations creates time-window aggregations for a metric
+func (as *AnalyticsService) createAggregations(metric *models.HistoricalMetric) {
+	for _, window := range aggregationWindows {
+		as.createAggregation(metric, window)
+	}
+}
 
-		as.db.Create(agg)
-	case nil:
-		// Update existing aggregation
-		as.calculateAggregationValues(&existingAgg, windowStart, windowEnd)
-		as.db.Save(&existingAgg)
+// createAggregation adds a new sample to the aggregation of its time window
+func (as *AnalyticsService) createAggregation(metric *models.HistoricalMetric, timeWindow string) {
+	if err := as.addToAggregation(metric, timeWindow); err != nil {
+		logger.Error("Failed to update metric aggregation",
+			logger.Err(err),
+			logger.String("metric_type", metric.MetricType),
+			logger.String("metric_name", metric.MetricName),
+			logger.String("time_window", timeWindow))
 	}
 }
 
@@ -690,10 +1207,8 @@ func (as *AnalyticsService) getRetentionForWindow(window string) time.Duration {
 
 // calculateAggregationValues calculates aggregation statistics
 func (as *AnalyticsService) calculateAggregationValues(agg *models.MetricAggregation, start, end time.Time) {
-	var metrics []models.HistoricalMetric
-
-	err := as.db.Where("metric_type = ? AND metric_name = ? AND timestamp BETWEEN ? AND ?",
-		agg.MetricType, agg.MetricName, start, end).Find(&metrics).Error
+	metrics, err := as.findMetrics(as.metricsQuery().Where("metric_type = ? AND metric_name = ? AND timestamp BETWEEN ? AND ?",
+		agg.MetricType, agg.MetricName, start, end))
 	if err != nil {
 		logger.Error("Failed to query metrics for aggregation", logger.Err(err))
 		return
@@ -705,7 +1220,7 @@ func (as *AnalyticsService) calculateAggregationValues(agg *models.MetricAggrega
 
 	// Calculate basic statistics
 	values := make([]float64, len(metrics))
-	var sum float64
+	var sum, sumSquares float64
 
 	agg.Count = int64(len(metrics))
 	agg.Min = math.Inf(1)
@@ -715,6 +1230,7 @@ func (as *AnalyticsService) calculateAggregationValues(agg *models.MetricAggrega
 		value := metric.Value
 		values[i] = value
 		sum += value
+		sumSquares += value * value
 
 		if value < agg.Min {
 			agg.Min = value
@@ -725,13 +1241,12 @@ func (as *AnalyticsService) calculateAggregationValues(agg *models.MetricAggrega
 	}
 
 	agg.Sum = sum
+	agg.SumSquares = sumSquares
 	agg.Avg = sum / float64(len(metrics))
 
 	// Calculate percentiles
 	sort.Float64s(values)
-	agg.P50 = as.percentile(values, 0.5)
-	agg.P95 = as.percentile(values, 0.95)
-	agg.P99 = as.percentile(values, 0.99)
+	as.setPercentiles(agg, values)
 
 	// Calculate standard deviation
 	var variance float64
@@ -760,7 +1275,8 @@ func (as *AnalyticsService) percentile(sortedValues []float64, p float64) float6
 }
 
 // StartMetricsCollection starts automated metrics collection
-func (as *AnalyticsService) StartMetricsCollection(ctx context.Context, interval time.Duration) {
+func (as *AnalyticsService) StartMetricsCollection(ctx context.Context) {
+	interval := as.metricsCollectionInterval()
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -771,6 +1287,10 @@ func (as *AnalyticsService) StartMetricsCollection(ctx context.Context, interval
 		case <-ctx.Done():
 			logger.Info("Stopping metrics collection")
 			return
+		case <-as.intervalChanged:
+			interval = as.metricsCollectionInterval()
+			ticker.Reset(interval)
+			logger.Info("Changed metrics collection interval", logger.Duration("interval", interval))
 		case <-ticker.C:
 			if err := as.StoreSystemMetrics(); err != nil {
 				logger.Error("Failed to store system metrics", logger.Err(err))
@@ -783,18 +1303,37 @@ func (as *AnalyticsService) StartMetricsCollection(ctx context.Context, interval
 func (as *AnalyticsService) CleanupExpiredMetrics() error {
 	now := time.Now()
 
-	// Clean up historical metrics
-	result := as.db.Where("retention_end IS NOT NULL AND retention_end < ?", now).
-		Delete(&models.HistoricalMetric{})
-	if result.Error != nil {
-		return result.Error
-	}
+	// Clean up raw metrics
+	if as.timeSeriesStorage {
+		deleted, err := as.cleanupRawMetrics(now)
+		if err != nil {
+			return err
+		}
 
-	logger.Info("Cleaned up expired historical metrics",
-		logger.Int64("deleted_count", result.RowsAffected))
+		logger.Info("Cleaned up expired raw metrics",
+			logger.Int64("deleted_count", deleted))
+	} else {
+		rolledUp, err := as.rollUpExpiringMetrics(as.db.Model(&models.HistoricalMetric{}).
+			Where("retention_end IS NOT NULL AND retention_end < ?", now))
+		if err != nil {
+			return fmt.Errorf("failed to aggregate expiring metrics: %w", err)
+		}
+		if rolledUp > 0 {
+			logger.Info("Aggregated expiring metrics before cleanup", logger.Int("aggregations", rolledUp))
+		}
+
+		result := as.db.Where("retention_end IS NOT NULL AND retention_end < ?", now).
+			Delete(&models.HistoricalMetric{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		logger.Info("Cleaned up expired historical metrics",
+			logger.Int64("deleted_count", result.RowsAffected))
+	}
 
 	// Clean up metric aggregations
-	result = as.db.Where("retention_end IS NOT NULL AND retention_end < ?", now).
+	result := as.db.Where("retention_end IS NOT NULL AND retention_end < ?", now).
 		Delete(&models.MetricAggregation{})
 	if result.Error != nil {
 		return result.Error
@@ -821,14 +1360,25 @@ func (as *AnalyticsService) GetAlertRules(userID uint) ([]models.AlertRule, erro
 }
 
 // UpdateAlertRule updates an existing alert rule
-func (as *AnalyticsService) UpdateAlertRule(alertRule *models.AlertRule, userID uint) error {
+func (as *AnalyticsService) UpdateAlertRule(alertRule *models.AlertRule, userID uint, audit AuditContext) error {
 	// Verify ownership
 	var existingRule models.AlertRule
 	if err := as.db.Where("id = ? AND user_id = ?", alertRule.ID, userID).First(&existingRule).Error; err != nil {
 		return err
 	}
 
-	return as.db.Save(alertRule).Error
+	// Fields the request does not carry keep their stored values
+	alertRule.UserID = existingRule.UserID
+	alertRule.CreatedAt = existingRule.CreatedAt
+	alertRule.LastTriggered = existingRule.LastTriggered
+
+	if err := as.db.Save(alertRule).Error; err != nil {
+		return err
+	}
+
+	recordUpdateAudit(as.db, audit, userID, models.ObjectTypeAlertRule, alertRule.ID,
+		fmt.Sprintf("Updated alert rule: %s", alertRule.Name), &existingRule, alertRule)
+	return nil
 }
 
 // DeleteAlertRule deletes an alert rule
@@ -836,6 +1386,211 @@ func (as *AnalyticsService) DeleteAlertRule(ruleID, userID uint) error {
 	return as.db.Where("id = ? AND user_id = ?", ruleID, userID).Delete(&models.AlertRule{}).Error
 }
 
+// CreateSilence creates a new silence for the user
+func (as *AnalyticsService) CreateSilence(silence *models.Silence) error {
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return fmt.Errorf("silence must end after it starts")
+	}
+
+	if silence.AlertRuleID != nil {
+		var rule models.AlertRule
+		if err := as.db.Where("id = ? AND user_id = ?", *silence.AlertRuleID, silence.CreatedBy).First(&rule).Error; err != nil {
+			return fmt.Errorf("alert rule not found: %w", err)
+		}
+	}
+
+	return as.db.Create(silence).Error
+}
+
+// GetSilences retrieves silences created by a user, optionally only active ones
+func (as *AnalyticsService) GetSilences(userID uint, activeOnly bool) ([]models.Silence, error) {
+	query := as.db.Where("created_by = ?", userID)
+	if activeOnly {
+		now := time.Now()
+		query = query.Where("starts_at <= ? AND ends_at > ?", now, now)
+	}
+
+	var silences []models.Silence
+	err := query.Order("starts_at DESC").Find(&silences).Error
+	return silences, err
+}
+
+// ExpireSilence ends a silence early by moving its end time to now
+func (as *AnalyticsService) ExpireSilence(silenceID, userID uint) error {
+	var silence models.Silence
+	if err := as.db.Where("id = ? AND created_by = ?", silenceID, userID).First(&silence).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrSilenceNotFound
+		}
+		return err
+	}
+
+	now := time.Now()
+	if silence.EndsAt.Before(now) {
+		return nil
+	}
+	if silence.StartsAt.After(now) {
+		// Not started yet, nothing to keep
+		return as.db.Delete(&silence).Error
+	}
+
+	return as.db.Model(&silence).Update("ends_at", now).Error
+}
+
+// CreateNotificationChannel validates and creates a notification channel
+func (as *AnalyticsService) CreateNotificationChannel(channel *models.NotificationChannel) error {
+	if err := as.notificationService.ValidateChannel(channel); err != nil {
+		return err
+	}
+
+	return as.db.Transaction(func(tx *gorm.DB) error {
+		if err := as.clearOtherFallbacks(tx, channel); err != nil {
+			return err
+		}
+		return tx.Create(channel).Error
+	})
+}
+
+// GetNotificationChannels retrieves a user's notification channels with secrets masked
+func (as *AnalyticsService) GetNotificationChannels(userID uint) ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	if err := as.db.Where("user_id = ?", userID).Order("name ASC").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range channels {
+		MaskChannelSecrets(&channels[i])
+	}
+	return channels, nil
+}
+
+// GetNotificationChannel retrieves a single notification channel owned by the user
+func (as *AnalyticsService) GetNotificationChannel(channelID, userID uint) (*models.NotificationChannel, error) {
+	var channel models.NotificationChannel
+	if err := as.db.Where("id = ? AND user_id = ?", channelID, userID).First(&channel).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNotificationChannelNotFound
+		}
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// GetMaskedNotificationChannel retrieves a notification channel with secrets masked
+func (as *AnalyticsService) GetMaskedNotificationChannel(channelID, userID uint) (*models.NotificationChannel, error) {
+	channel, err := as.GetNotificationChannel(channelID, userID)
+	if err != nil {
+		return nil, err
+	}
+	MaskChannelSecrets(channel)
+	return channel, nil
+}
+
+// UpdateNotificationChannel validates and updates a notification channel. Masked
+// secrets in the request keep their stored values.
+func (as *AnalyticsService) UpdateNotificationChannel(channel *models.NotificationChannel, userID uint) error {
+	existing, err := as.GetNotificationChannel(channel.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	restoreMaskedSecrets(channel, existing)
+
+	channel.UserID = existing.UserID
+	channel.CreatedAt = existing.CreatedAt
+
+	if err := as.notificationService.ValidateChannel(channel); err != nil {
+		return err
+	}
+
+	return as.db.Transaction(func(tx *gorm.DB) error {
+		if err := as.clearOtherFallbacks(tx, channel); err != nil {
+			return err
+		}
+		return tx.Save(channel).Error
+	})
+}
+
+// DeleteNotificationChannel deletes a notification channel and its rule associations
+func (as *AnalyticsService) DeleteNotificationChannel(channelID, userID uint) error {
+	channel, err := as.GetNotificationChannel(channelID, userID)
+	if err != nil {
+		return err
+	}
+
+	return as.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM alert_rule_channels WHERE notification_channel_id = ?", channel.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(channel).Error
+	})
+}
+
+// TestNotificationChannel sends a sample alert through a channel owned by the user
+func (as *AnalyticsService) TestNotificationChannel(channelID, userID uint) error {
+	channel, err := as.GetNotificationChannel(channelID, userID)
+	if err != nil {
+		return err
+	}
+
+	return as.notificationService.SendTestAlert(*channel)
+}
+
+// clearOtherFallbacks keeps at most one fallback channel per user
+func (as *AnalyticsService) clearOtherFallbacks(tx *gorm.DB, channel *models.NotificationChannel) error {
+	if !channel.IsFallback {
+		return nil
+	}
+
+	return tx.Model(&models.NotificationChannel{}).
+		Where("user_id = ? AND id != ? AND is_fallback = ?", channel.UserID, channel.ID, true).
+		Update("is_fallback", false).Error
+}
+
+// MaskChannelSecrets hides secret configuration values before returning a channel
+func MaskChannelSecrets(channel *models.NotificationChannel) {
+	for _, key := range secretConfigKeys {
+		switch value := channel.Configuration[key].(type) {
+		case string:
+			if value != "" {
+				channel.Configuration[key] = maskedSecret
+			}
+		case map[string]interface{}:
+			masked := make(map[string]interface{}, len(value))
+			for name := range value {
+				masked[name] = maskedSecret
+			}
+			channel.Configuration[key] = masked
+		}
+	}
+}
+
+// restoreMaskedSecrets puts the stored values of existing back in place of
+// masked secrets, so a channel can be saved as it was returned. A masked
+// header existing does not have is dropped.
+func restoreMaskedSecrets(channel, existing *models.NotificationChannel) {
+	for _, key := range secretConfigKeys {
+		switch value := channel.Configuration[key].(type) {
+		case string:
+			if value == maskedSecret {
+				channel.Configuration[key] = existing.Configuration[key]
+			}
+		case map[string]interface{}:
+			stored, _ := existing.Configuration[key].(map[string]interface{})
+			for name, headerValue := range value {
+				if headerValue != maskedSecret {
+					continue
+				}
+				if storedValue, ok := stored[name]; ok {
+					value[name] = storedValue
+				} else {
+					delete(value, name)
+				}
+			}
+		}
+	}
+}
+
 // GetAlertInstances retrieves alert instances with filtering and pagination
 func (as *AnalyticsService) GetAlertInstances(userID uint, status, severity string, limit, offset int) ([]models.AlertInstance, int64, error) {
 	query := as.db.Model(&models.AlertInstance{}).
@@ -865,41 +1620,101 @@ func (as *AnalyticsService) GetAlertInstances(userID uint, status, severity stri
 	return instances, total, err
 }
 
-// CreateDashboard creates a new dashboard
+// CreateDashboard validates the dashboard's widgets and creates the dashboard
 func (as *AnalyticsService) CreateDashboard(dashboard *models.Dashboard) error {
+	if err := as.ValidateWidgets(dashboard.Widgets); err != nil {
+		return err
+	}
+
 	return as.db.Create(dashboard).Error
 }
 
-// GetDashboards retrieves dashboards for a user
+// GetDashboards retrieves the dashboards a user owns, was shared or can see
+// because they are public, each with the user's permission
 func (as *AnalyticsService) GetDashboards(userID uint) ([]models.Dashboard, error) {
 	var dashboards []models.Dashboard
-	err := as.db.Where("user_id = ? OR is_public = ?", userID, true).
+	err := as.db.Where("user_id = ? OR is_public = ? OR id IN (?)", userID, true,
+		as.db.Model(&models.DashboardShare{}).Select("dashboard_id").Where("user_id = ?", userID)).
 		Preload("Widgets").
 		Find(&dashboards).Error
-	return dashboards, err
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []models.DashboardShare
+	if err := as.db.Where("user_id = ?", userID).Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	shared := make(map[uint]string, len(shares))
+	for _, share := range shares {
+		shared[share.DashboardID] = share.Permission
+	}
+
+	for i := range dashboards {
+		dashboards[i].Permission = dashboardPermission(&dashboards[i], userID, shared[dashboards[i].ID])
+	}
+	return dashboards, nil
 }
 
-// GetDashboard retrieves a specific dashboard
+// GetDashboard retrieves a dashboard the user can view. Only the owner sees
+// who it is shared with.
 func (as *AnalyticsService) GetDashboard(dashboardID, userID uint) (*models.Dashboard, error) {
-	var dashboard models.Dashboard
-	err := as.db.Where("id = ? AND (user_id = ? OR is_public = ?)", dashboardID, userID, true).
-		Preload("Widgets").
-		First(&dashboard).Error
-	return &dashboard, err
+	dashboard, err := as.findDashboard(dashboardID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := as.db.Where("dashboard_id = ?", dashboard.ID).Find(&dashboard.Widgets).Error; err != nil {
+		return nil, err
+	}
+	if dashboard.Permission == models.DashboardPermissionOwner {
+		if err := as.db.Preload("User").Where("dashboard_id = ?", dashboard.ID).Find(&dashboard.Shares).Error; err != nil {
+			return nil, err
+		}
+	}
+	return dashboard, nil
 }
 
-// UpdateDashboard updates an existing dashboard
+// UpdateDashboard updates a dashboard the user owns or can edit. Editors
+// cannot change whether the dashboard is public.
 func (as *AnalyticsService) UpdateDashboard(dashboard *models.Dashboard, userID uint) error {
-	// Verify ownership
-	var existingDashboard models.Dashboard
-	if err := as.db.Where("id = ? AND user_id = ?", dashboard.ID, userID).First(&existingDashboard).Error; err != nil {
+	existingDashboard, err := as.findDashboard(dashboard.ID, userID)
+	if err != nil {
 		return err
 	}
+	if existingDashboard.Permission == models.DashboardPermissionView {
+		return ErrDashboardAccessDenied
+	}
+
+	if err := as.ValidateWidgets(dashboard.Widgets); err != nil {
+		return err
+	}
+
+	dashboard.UserID = existingDashboard.UserID
+	dashboard.CreatedAt = existingDashboard.CreatedAt
+	if existingDashboard.Permission != models.DashboardPermissionOwner {
+		dashboard.IsPublic = existingDashboard.IsPublic
+	}
+	dashboard.Shares = nil
+	dashboard.Permission = existingDashboard.Permission
 
-	return as.db.Save(dashboard).Error
+	return as.db.Omit("Shares").Save(dashboard).Error
 }
 
-// DeleteDashboard deletes a dashboard
+// DeleteDashboard deletes a dashboard the user owns, with its shares
 func (as *AnalyticsService) DeleteDashboard(dashboardID, userID uint) error {
-	return as.db.Where("id = ? AND user_id = ?", dashboardID, userID).Delete(&models.Dashboard{}).Error
+	dashboard, err := as.findDashboard(dashboardID, userID)
+	if err != nil {
+		return err
+	}
+	if dashboard.Permission != models.DashboardPermissionOwner {
+		return ErrDashboardAccessDenied
+	}
+
+	return as.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(dashboard).Error; err != nil {
+			return err
+		}
+		return tx.Where("dashboard_id = ?", dashboardID).Delete(&models.DashboardShare{}).Error
+	})
 }
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Anomaly detection algorithms for trend analysis
+const (
+	// AnomalyAlgorithmRolling compares each point with the mean and standard
+	// deviation of the 10 points before it
+	AnomalyAlgorithmRolling = "rolling"
+	// AnomalyAlgorithmSeasonal compares each point with the other points at
+	// the same hour of the day, or of the week over two weeks or more, so
+	// regular daily peaks are not reported
+	AnomalyAlgorithmSeasonal = "seasonal"
+)
+
+var ErrInvalidAnomalyAlgorithm = errors.New("unknown anomaly detection algorithm")
+
+// seasonalMinSamples is how many other points a season needs before points
+// in it are judged
+const seasonalMinSamples = 3
+
+// TrendRequest selects the metric and time range to analyze
+type TrendRequest struct {
+	MetricType string    `json:"metric_type" binding:"required"`
+	MetricName string    `json:"metric_name" binding:"required"`
+	TimeRange  TimeRange `json:"time_range"`
+	Algorithm  string    `json:"algorithm"` // rolling (default) or seasonal
+}
+
+// detectSeasonalAnomalies flags points that deviate from the baseline of their
+// season: the other points at the same hour of the day, or at the same hour
+// of the week when the data spans at least two weeks
+func (as *AnalyticsService) detectSeasonalAnomalies(dataPoints []MetricDataPoint) []Anomaly {
+	if len(dataPoints) < 2 {
+		return []Anomaly{}
+	}
+
+	season := func(t time.Time) int { return t.Hour() }
+	span := dataPoints[len(dataPoints)-1].Timestamp.Sub(dataPoints[0].Timestamp)
+	if span >= 14*24*time.Hour {
+		season = func(t time.Time) int { return int(t.Weekday())*24 + t.Hour() }
+	}
+
+	seasons := make(map[int][]float64)
+	for _, point := range dataPoints {
+		key := season(point.Timestamp)
+		seasons[key] = append(seasons[key], point.Value)
+	}
+
+	var anomalies []Anomaly
+	for _, point := range dataPoints {
+		values := seasons[season(point.Timestamp)]
+		if len(values)-1 < seasonalMinSamples {
+			continue
+		}
+
+		// The baseline leaves the point itself out, so a spike does not
+		// widen its own expected range
+		var sum float64
+		skipped := false
+		others := make([]float64, 0, len(values)-1)
+		for _, value := range values {
+			if !skipped && value == point.Value {
+				skipped = true
+				continue
+			}
+			others = append(others, value)
+			sum += value
+		}
+		mean := sum / float64(len(others))
+
+		var variance float64
+		for _, value := range others {
+			variance += (value - mean) * (value - mean)
+		}
+		stdDev := math.Sqrt(variance / float64(len(others)))
+
+		if anomaly, ok := checkAnomaly(point, mean, stdDev); ok {
+			anomalies = append(anomalies, anomaly)
+		}
+	}
+
+	return anomalies
+}
+
+// checkAnomaly reports a point more than 2 standard deviations from the
+// expected mean, as critical beyond 3
+func checkAnomaly(point MetricDataPoint, mean, stdDev float64) (Anomaly, bool) {
+	expectedMin := mean - 2*stdDev
+	expectedMax := mean + 2*stdDev
+	if point.Value >= expectedMin && point.Value <= expectedMax {
+		return Anomaly{}, false
+	}
+
+	severity := "warning"
+	if point.Value < mean-3*stdDev || point.Value > mean+3*stdDev {
+		severity = "critical"
+	}
+
+	return Anomaly{
+		Timestamp:   point.Timestamp,
+		Value:       point.Value,
+		ExpectedMin: expectedMin,
+		ExpectedMax: expectedMax,
+		Severity:    severity,
+		Description: fmt.Sprintf("Value %.2f outside expected range [%.2f, %.2f]",
+			point.Value, expectedMin, expectedMax),
+	}, true
+}
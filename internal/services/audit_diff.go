@@ -0,0 +1,151 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxAuditValueLength caps how much of a long text value, such as config
+// content, an audit log diff keeps
+const maxAuditValueLength = 512
+
+// auditIgnoredFields are bookkeeping fields that never count as a change.
+// Preloaded relationships are skipped by isAuditRelation.
+var auditIgnoredFields = []string{
+	"id", "created_at", "updated_at", "deleted_at",
+	"validation_time", "validation_logs", "content_updated_at", "has_pending_changes",
+	"pending_changes", "certificate_warnings",
+}
+
+// auditSensitiveFields are recorded as changed without their values
+var auditSensitiveFields = []string{"certificate_key", "password", "secret", "token"}
+
+// AuditFieldChange is one field's value before and after an update
+type AuditFieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditDiff compares two snapshots of the same model by their JSON fields and
+// returns the changed fields keyed by JSON name, or nil when nothing changed.
+// Sensitive fields are masked and long text values truncated.
+func AuditDiff(before, after interface{}) map[string]AuditFieldChange {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(afterFields))
+	for key := range afterFields {
+		keys = append(keys, key)
+	}
+	for key := range beforeFields {
+		if _, ok := afterFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make(map[string]AuditFieldChange)
+	for _, key := range keys {
+		if containsString(auditIgnoredFields, key) || isAuditRelation(beforeFields[key]) || isAuditRelation(afterFields[key]) ||
+			reflect.DeepEqual(beforeFields[key], afterFields[key]) ||
+			(isEmptyAuditValue(beforeFields[key]) && isEmptyAuditValue(afterFields[key])) {
+			continue
+		}
+		if containsString(auditSensitiveFields, key) {
+			changes[key] = AuditFieldChange{Before: maskedSecret, After: maskedSecret}
+			continue
+		}
+		changes[key] = AuditFieldChange{
+			Before: truncateAuditValue(beforeFields[key]),
+			After:  truncateAuditValue(afterFields[key]),
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// NewUpdateAuditLog builds an audit log entry for an update with the field
+// diff between before and after stored in Meta["changes"]
+func NewUpdateAuditLog(userID uint, objectType models.ObjectType, objectID uint, description string, before, after interface{}) *models.AuditLog {
+	auditLog := &models.AuditLog{
+		UserID:      userID,
+		Action:      models.ActionUpdated,
+		ObjectType:  objectType,
+		ObjectID:    objectID,
+		Description: description,
+	}
+	if changes := AuditDiff(before, after); changes != nil {
+		auditLog.Meta = models.JSON{"changes": changes}
+	}
+	return auditLog
+}
+
+// recordUpdateAudit writes an update audit log entry with its field diff
+func recordUpdateAudit(db *gorm.DB, audit AuditContext, userID uint, objectType models.ObjectType, objectID uint, description string, before, after interface{}) {
+	RecordAudit(db, audit, NewUpdateAuditLog(userID, objectType, objectID, description, before, after))
+}
+
+// auditFields flattens a model into its top-level JSON fields
+func auditFields(value interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// isAuditRelation reports whether a JSON value is a related model, or a list
+// of them, rather than a field of the model itself. Every model embeds
+// BaseModel, so related models carry created_at.
+func isAuditRelation(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		_, ok := v["created_at"]
+		return ok
+	case []interface{}:
+		return len(v) > 0 && isAuditRelation(v[0])
+	}
+	return false
+}
+
+// isEmptyAuditValue reports whether a JSON value is null or an empty list or
+// object, so a nil slice and an empty one do not count as a change
+func isEmptyAuditValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// truncateAuditValue shortens long strings so large content does not bloat the audit log
+func truncateAuditValue(value interface{}) interface{} {
+	text, ok := value.(string)
+	if !ok || len(text) <= maxAuditValueLength {
+		return value
+	}
+	return fmt.Sprintf("%s... (%d bytes)", strings.ToValidUTF8(text[:maxAuditValueLength], ""), len(text))
+}
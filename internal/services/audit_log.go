@@ -0,0 +1,109 @@
+package services
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// AuditContext carries the request details recorded with an audit log entry
+type AuditContext struct {
+	IPAddress string
+	UserAgent string
+}
+
+// NewAuditContext captures the client IP and user agent of a request
+func NewAuditContext(c *gin.Context) AuditContext {
+	return AuditContext{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// apply stamps the request details onto an audit log entry
+func (a AuditContext) apply(auditLog *models.AuditLog) *models.AuditLog {
+	auditLog.IPAddress = a.IPAddress
+	auditLog.UserAgent = a.UserAgent
+	return auditLog
+}
+
+// NewAuditLog builds an audit log entry for a change without a field diff,
+// such as a create or delete
+func NewAuditLog(userID uint, action models.AuditAction, objectType models.ObjectType, objectID uint, description string) *models.AuditLog {
+	return &models.AuditLog{
+		UserID:      userID,
+		Action:      action,
+		ObjectType:  objectType,
+		ObjectID:    objectID,
+		Description: description,
+	}
+}
+
+// RecordAudit writes an audit log entry with the request details. A failed
+// write is logged and does not fail the change being audited.
+func RecordAudit(db *gorm.DB, audit AuditContext, auditLog *models.AuditLog) {
+	if err := db.Create(audit.apply(auditLog)).Error; err != nil {
+		logger.Error("Failed to create audit log", logger.Err(err))
+	}
+}
+
+// AuditLogFilter selects audit log entries. Zero values match everything;
+// From and To bound created_at inclusively.
+type AuditLogFilter struct {
+	UserID     uint
+	ObjectType models.ObjectType
+	Action     models.AuditAction
+	From       time.Time
+	To         time.Time
+	Page       int
+	Limit      int
+}
+
+// AuditLogListResponse is a page of audit log entries
+type AuditLogListResponse struct {
+	AuditLogs []models.AuditLog `json:"audit_logs"`
+	Total     int64             `json:"total"`
+	Page      int               `json:"page"`
+	Limit     int               `json:"limit"`
+}
+
+// ListAuditLogs lists audit log entries matching filter, newest first, with
+// the acting user preloaded. Admin only.
+func (s *UserService) ListAuditLogs(actorID uint, filter AuditLogFilter) (*AuditLogListResponse, error) {
+	if err := s.authService.RequireAdmin(actorID); err != nil {
+		return nil, err
+	}
+
+	query := s.db.Model(&models.AuditLog{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ObjectType != "" {
+		query = query.Where("object_type = ?", filter.ObjectType)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at <= ?", filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var auditLogs []models.AuditLog
+	if err := query.Preload("User").Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).Find(&auditLogs).Error; err != nil {
+		return nil, err
+	}
+
+	return &AuditLogListResponse{AuditLogs: auditLogs, Total: total, Page: filter.Page, Limit: filter.Limit}, nil
+}
@@ -22,6 +22,10 @@ var (
 	ErrUnauthorized       = errors.New("unauthorized access")
 )
 
+// webSocketTokenIssuer marks short-lived tokens that are only accepted for the
+// monitoring WebSocket, where browsers cannot send an Authorization header
+const webSocketTokenIssuer = "nginx-manager-ws"
+
 // AuthService handles authentication and authorization
 type AuthService struct {
 	db        *gorm.DB
@@ -41,9 +45,16 @@ type JWTClaims struct {
 	UserID uint               `json:"user_id"`
 	Email  string             `json:"email"`
 	Roles  models.StringArray `json:"roles"`
+	// ImpersonatorID is the admin acting as UserID, set only on impersonation tokens
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IsImpersonation reports whether the token was issued to an admin acting as the user
+func (c *JWTClaims) IsImpersonation() bool {
+	return c.ImpersonatorID != 0
+}
+
 // LoginRequest represents login request payload
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
@@ -121,6 +132,11 @@ func (s *AuthService) RefreshToken(req *RefreshTokenRequest) (*LoginResponse, er
 		return nil, err
 	}
 
+	// Impersonation sessions end when their token expires
+	if claims.IsImpersonation() {
+		return nil, ErrTokenInvalid
+	}
+
 	// Find user
 	var user models.User
 	if err := s.db.Where("id = ? AND deleted_at IS NULL", claims.UserID).First(&user).Error; err != nil {
@@ -192,8 +208,53 @@ func (s *AuthService) GenerateRefreshToken(user *models.User, duration time.Dura
 	return token.SignedString([]byte(s.jwtSecret))
 }
 
-// ValidateToken validates JWT token and returns claims
+// GenerateWebSocketToken generates a short-lived token accepted only by the
+// monitoring WebSocket as a ?token= query parameter
+func (s *AuthService) GenerateWebSocketToken(user *models.User, duration time.Duration) (string, error) {
+	claims := &JWTClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Roles:  user.Roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    webSocketTokenIssuer,
+			Subject:   fmt.Sprintf("%d", user.ID),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.jwtSecret))
+}
+
+// ValidateToken validates JWT token and returns claims. WebSocket tokens are
+// rejected so they cannot be used against the REST API.
 func (s *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Issuer == webSocketTokenIssuer {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+// ValidateWebSocketToken validates a token issued by GenerateWebSocketToken
+func (s *AuthService) ValidateWebSocketToken(tokenString string) (*JWTClaims, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Issuer != webSocketTokenIssuer {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
+
+// parseToken verifies the signature and expiry of a JWT and returns its claims
+func (s *AuthService) parseToken(tokenString string) (*JWTClaims, error) {
 	// Remove Bearer prefix if present
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
@@ -225,8 +286,13 @@ func (s *AuthService) GetCurrentUser(tokenString string) (*models.User, error) {
 		return nil, err
 	}
 
+	return s.GetActiveUser(claims.UserID)
+}
+
+// GetActiveUser loads a user that still exists and is not disabled
+func (s *AuthService) GetActiveUser(userID uint) (*models.User, error) {
 	var user models.User
-	if err := s.db.Where("id = ? AND deleted_at IS NULL", claims.UserID).First(&user).Error; err != nil {
+	if err := s.db.Where("id = ? AND deleted_at IS NULL", userID).First(&user).Error; err != nil {
 		return nil, ErrUserNotFound
 	}
 
@@ -239,33 +305,96 @@ func (s *AuthService) GetCurrentUser(tokenString string) (*models.User, error) {
 	return &user, nil
 }
 
-// HasPermission checks if user has specific permission
+// UserPermissions returns the permission names granted by a user's roles.
+// Admins hold every permission.
+func (s *AuthService) UserPermissions(userID uint) ([]string, error) {
+	var user models.User
+	if err := s.db.Where("id = ? AND deleted_at IS NULL", userID).First(&user).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if user.IsAdmin() {
+		all := models.AllPermissions()
+		names := make([]string, len(all))
+		for i, permission := range all {
+			names[i] = permission.Name
+		}
+		return names, nil
+	}
+
+	var roles []models.Role
+	if len(user.Roles) > 0 {
+		if err := s.db.Preload("Permissions").Where("name IN ?", []string(user.Roles)).Find(&roles).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		for _, permission := range role.Permissions {
+			if !seen[permission.Name] {
+				seen[permission.Name] = true
+				names = append(names, permission.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// HasPermission checks if any of the user's roles grants permission
 func (s *AuthService) HasPermission(userID uint, permission string) bool {
+	return s.RequirePermission(userID, permission) == nil
+}
+
+// RequirePermission checks that one of the user's roles grants permission,
+// returning ErrUnauthorized when none does
+func (s *AuthService) RequirePermission(userID uint, permission string) error {
 	var user models.User
 	if err := s.db.Where("id = ? AND deleted_at IS NULL", userID).First(&user).Error; err != nil {
-		return false
+		return ErrUserNotFound
 	}
 
 	// Admin has all permissions
 	if user.IsAdmin() {
-		return true
+		return nil
+	}
+	if len(user.Roles) == 0 {
+		return ErrUnauthorized
 	}
 
-	// Add specific permission checking logic here
-	// For now, regular users have basic permissions
-	basicPermissions := []string{
-		"proxy_hosts:read",
-		"certificates:read",
-		"settings:read",
+	var count int64
+	if err := s.db.Table("role_permissions").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id AND roles.deleted_at IS NULL").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id AND permissions.deleted_at IS NULL").
+		Where("roles.name IN ? AND permissions.name = ?", []string(user.Roles), permission).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrUnauthorized
 	}
+	return nil
+}
 
-	for _, p := range basicPermissions {
-		if p == permission {
-			return true
-		}
+// ScopeToOwner limits query to userID's records unless the user holds
+// allPermission, the read_all or manage permission covering every user's
+// records. A nil service leaves only the user's own records.
+func (s *AuthService) ScopeToOwner(query *gorm.DB, userID uint, allPermission string) *gorm.DB {
+	if s != nil && s.HasPermission(userID, allPermission) {
+		return query
 	}
+	return query.Where("user_id = ?", userID)
+}
 
-	return false
+// RequireOwnerOrPermission allows acting on a resource owned by ownerID when
+// the user owns it and holds ownPermission, or holds allPermission, which
+// covers every user's resources
+func (s *AuthService) RequireOwnerOrPermission(userID, ownerID uint, ownPermission, allPermission string) error {
+	if ownerID != userID {
+		return s.RequirePermission(userID, allPermission)
+	}
+	return s.RequirePermission(userID, ownPermission)
 }
 
 // RequireAdmin checks if user has admin role
@@ -282,7 +411,8 @@ func (s *AuthService) RequireAdmin(userID uint) error {
 	return nil
 }
 
-// IsAdmin checks if user has admin role
+// IsAdmin checks if user has admin role. Kept for callers that only need the
+// admin distinction; everything else should use RequirePermission.
 func (s *AuthService) IsAdmin(userID uint) bool {
 	var user models.User
 	if err := s.db.Where("id = ? AND deleted_at IS NULL", userID).First(&user).Error; err != nil {
@@ -0,0 +1,530 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"golang.org/x/crypto/scrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FullBackupVersion is the format version written by CreateFullBackup
+const FullBackupVersion = 1
+
+const (
+	// fullBackupEntry is the archive entry holding the serialized records
+	fullBackupEntry = "backup.json"
+
+	// fullBackupMagic starts an encrypted archive, followed by the scrypt
+	// salt, the AES-GCM nonce and the sealed tar.gz
+	fullBackupMagic = "NMBACKUP1"
+
+	fullBackupSaltSize = 16
+
+	// fullBackupPrefix starts the name of every archive
+	fullBackupPrefix = "nginx-manager-backup-"
+)
+
+var (
+	ErrBackupPassphraseRequired = errors.New("a passphrase is required for backups with private keys")
+	ErrBackupDecrypt            = errors.New("failed to decrypt backup: wrong passphrase or corrupted archive")
+	ErrBackupFormat             = errors.New("not a full backup archive")
+	ErrBackupArchiveName        = errors.New("invalid backup archive name")
+	ErrBackupRestoreUnsupported = errors.New("restore reads archives from the local backup directory only")
+)
+
+// FullBackup is every proxy host, certificate, access list, template and
+// nginx config. Certificate private keys and access list password hashes are
+// only present when IncludesPrivateKeys is set, and such archives are
+// encrypted.
+type FullBackup struct {
+	Version             int                     `json:"version"`
+	CreatedAt           time.Time               `json:"created_at"`
+	IncludesPrivateKeys bool                    `json:"includes_private_keys"`
+	ProxyHosts          []models.ProxyHost      `json:"proxy_hosts"`
+	Certificates        []models.Certificate    `json:"certificates"`
+	AccessLists         []models.AccessList     `json:"access_lists"`
+	Templates           []models.ConfigTemplate `json:"templates"`
+	NginxConfigs        []models.NginxConfig    `json:"nginx_configs"`
+}
+
+// FullBackupCounts is how many records of each kind a backup holds
+type FullBackupCounts struct {
+	ProxyHosts   int `json:"proxy_hosts"`
+	Certificates int `json:"certificates"`
+	AccessLists  int `json:"access_lists"`
+	Templates    int `json:"templates"`
+	NginxConfigs int `json:"nginx_configs"`
+}
+
+// FullBackupResult describes a written backup archive
+type FullBackupResult struct {
+	Name      string           `json:"name"`
+	Location  string           `json:"location"`
+	Size      int              `json:"size"`
+	Encrypted bool             `json:"encrypted"`
+	CreatedAt time.Time        `json:"created_at"`
+	Counts    FullBackupCounts `json:"counts"`
+}
+
+// FullBackupRestoreResult reports what a restore wrote back. Warnings list
+// proxy hosts that could not be redeployed.
+type FullBackupRestoreResult struct {
+	BackupCreatedAt time.Time        `json:"backup_created_at"`
+	Counts          FullBackupCounts `json:"counts"`
+	Warnings        []string         `json:"warnings"`
+}
+
+// BackupService writes disaster recovery archives of the managed
+// configuration and restores them
+type BackupService struct {
+	db                 *gorm.DB
+	target             BackupTarget
+	includePrivateKeys bool
+	passphrase         string
+	retention          int
+	nginxService       *NginxService
+	accessListService  *AccessListService
+}
+
+// NewBackupService creates a backup service writing to target
+func NewBackupService(target BackupTarget) *BackupService {
+	return &BackupService{
+		db:     database.GetDB(),
+		target: target,
+	}
+}
+
+// SetIncludePrivateKeys makes backups carry certificate private keys and
+// access list password hashes, encrypted with passphrase. The passphrase also
+// decrypts archives on restore.
+func (s *BackupService) SetIncludePrivateKeys(include bool, passphrase string) error {
+	if include && passphrase == "" {
+		return ErrBackupPassphraseRequired
+	}
+	s.includePrivateKeys = include
+	s.passphrase = passphrase
+	return nil
+}
+
+// SetRetention sets how many archives are kept after a backup is written, on
+// targets that can prune old ones. 0 keeps every archive.
+func (s *BackupService) SetRetention(keep int) {
+	s.retention = keep
+}
+
+// SetNginxService lets a restore check the forward targets of the proxy hosts
+// it brings back and redeploy the enabled ones
+func (s *BackupService) SetNginxService(nginxService *NginxService) {
+	s.nginxService = nginxService
+}
+
+// SetAccessListService lets a restore rewrite the htpasswd files of the
+// access lists it brings back
+func (s *BackupService) SetAccessListService(accessListService *AccessListService) {
+	s.accessListService = accessListService
+}
+
+// CreateFullBackup serializes the configuration into a timestamped tar.gz
+// archive and writes it to the backup target. Archives with private keys are
+// encrypted and get a .enc suffix.
+func (s *BackupService) CreateFullBackup(ctx context.Context) (*FullBackupResult, error) {
+	backup, err := s.collectFullBackup()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := packFullBackup(backup)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fullBackupPrefix + backup.CreatedAt.Format("20060102T150405Z") + ".tar.gz"
+	if backup.IncludesPrivateKeys {
+		if data, err = encryptFullBackup(data, s.passphrase); err != nil {
+			return nil, err
+		}
+		name += ".enc"
+	}
+
+	location, err := s.target.Write(ctx, name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if pruner, ok := s.target.(BackupPruner); ok && s.retention > 0 {
+		if err := pruner.Prune(s.retention); err != nil {
+			logger.Warn("Failed to remove old full backups", logger.Err(err))
+		}
+	}
+
+	result := &FullBackupResult{
+		Name:      name,
+		Location:  location,
+		Size:      len(data),
+		Encrypted: backup.IncludesPrivateKeys,
+		CreatedAt: backup.CreatedAt,
+		Counts:    backup.counts(),
+	}
+	logger.Info("Full backup created",
+		logger.String("location", location),
+		logger.Int("size", len(data)),
+		logger.Bool("encrypted", result.Encrypted))
+	return result, nil
+}
+
+// collectFullBackup reads every live record to back up
+func (s *BackupService) collectFullBackup() (*FullBackup, error) {
+	backup := &FullBackup{
+		Version:             FullBackupVersion,
+		CreatedAt:           time.Now().UTC(),
+		IncludesPrivateKeys: s.includePrivateKeys,
+	}
+
+	if err := s.db.Order("id ASC").Find(&backup.ProxyHosts).Error; err != nil {
+		return nil, fmt.Errorf("failed to read proxy hosts: %w", err)
+	}
+	if err := s.db.Order("id ASC").Find(&backup.Certificates).Error; err != nil {
+		return nil, fmt.Errorf("failed to read certificates: %w", err)
+	}
+	if err := s.db.Preload("Items").Order("id ASC").Find(&backup.AccessLists).Error; err != nil {
+		return nil, fmt.Errorf("failed to read access lists: %w", err)
+	}
+	if err := s.db.Order("id ASC").Find(&backup.Templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to read templates: %w", err)
+	}
+	if err := s.db.Order("id ASC").Find(&backup.NginxConfigs).Error; err != nil {
+		return nil, fmt.Errorf("failed to read nginx configs: %w", err)
+	}
+
+	if !backup.IncludesPrivateKeys {
+		for i := range backup.Certificates {
+			backup.Certificates[i].ClearSensitiveData()
+		}
+		for i := range backup.AccessLists {
+			for j := range backup.AccessLists[i].Items {
+				backup.AccessLists[i].Items[j].Password = ""
+			}
+		}
+	}
+	return backup, nil
+}
+
+// ArchivePath resolves the name of an archive in the local backup directory,
+// refusing anything that is not a plain file name
+func (s *BackupService) ArchivePath(name string) (string, error) {
+	local, ok := s.target.(*LocalBackupTarget)
+	if !ok {
+		return "", ErrBackupRestoreUnsupported
+	}
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", ErrBackupArchiveName
+	}
+	return filepath.Join(local.Dir, name), nil
+}
+
+// RestoreFullBackup writes the records of an archive back, keeping their IDs:
+// existing rows are overwritten and missing ones recreated, in one
+// transaction. Rows created since the backup are left alone. Certificates and
+// access list items backed up without secrets keep their current keys and
+// passwords. Proxy hosts forwarding to a target the forward target policy
+// denies are restored disabled. Afterwards the access list htpasswd files are
+// rewritten and enabled proxy hosts redeployed.
+func (s *BackupService) RestoreFullBackup(userID uint, archivePath string, audit AuditContext) (*FullBackupRestoreResult, error) {
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	if bytes.HasPrefix(data, []byte(fullBackupMagic)) {
+		if data, err = decryptFullBackup(data, s.passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	backup, err := unpackFullBackup(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FullBackupRestoreResult{
+		BackupCreatedAt: backup.CreatedAt,
+		Counts:          backup.counts(),
+		Warnings:        []string{},
+	}
+
+	if s.nginxService != nil {
+		for i := range backup.ProxyHosts {
+			proxyHost := &backup.ProxyHosts[i]
+			if !proxyHost.Enabled {
+				continue
+			}
+			if err := s.nginxService.CheckForwardTarget(proxyHost.ForwardHost); err != nil {
+				proxyHost.Enabled = false
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("proxy host %s was restored disabled: %v", proxyHost.GetPrimaryDomain(), err))
+			}
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Unscoped().Omit(clause.Associations).Session(&gorm.Session{})
+
+		for i := range backup.Certificates {
+			cert := &backup.Certificates[i]
+			query := tx
+			if cert.CertificateKey == "" {
+				query = query.Omit(clause.Associations, "certificate_key")
+			}
+			if err := query.Save(cert).Error; err != nil {
+				return fmt.Errorf("failed to restore certificate %d: %w", cert.ID, err)
+			}
+		}
+		for i := range backup.AccessLists {
+			accessList := &backup.AccessLists[i]
+			if err := tx.Save(accessList).Error; err != nil {
+				return fmt.Errorf("failed to restore access list %d: %w", accessList.ID, err)
+			}
+			for j := range accessList.Items {
+				item := &accessList.Items[j]
+				query := tx
+				if item.Password == "" {
+					query = query.Omit(clause.Associations, "password")
+				}
+				if err := query.Save(item).Error; err != nil {
+					return fmt.Errorf("failed to restore access list item %d: %w", item.ID, err)
+				}
+			}
+		}
+		for i := range backup.Templates {
+			if err := tx.Save(&backup.Templates[i]).Error; err != nil {
+				return fmt.Errorf("failed to restore template %d: %w", backup.Templates[i].ID, err)
+			}
+		}
+		for i := range backup.NginxConfigs {
+			if err := tx.Save(&backup.NginxConfigs[i]).Error; err != nil {
+				return fmt.Errorf("failed to restore nginx config %d: %w", backup.NginxConfigs[i].ID, err)
+			}
+		}
+		for i := range backup.ProxyHosts {
+			proxyHost := &backup.ProxyHosts[i]
+			if err := tx.Save(proxyHost).Error; err != nil {
+				return fmt.Errorf("failed to restore proxy host %d: %w", proxyHost.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	RecordAudit(s.db, audit, NewAuditLog(userID, models.ActionRestored, models.ObjectTypeBackup, 0,
+		fmt.Sprintf("Restored full backup %s from %s", filepath.Base(archivePath), backup.CreatedAt.Format(time.RFC3339))))
+
+	if s.accessListService != nil {
+		for i := range backup.AccessLists {
+			accessList := &backup.AccessLists[i]
+			if err := s.accessListService.WriteHtpasswd(accessList.ID); err != nil {
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("access list %s was restored but its htpasswd file not written: %v", accessList.Name, err))
+			}
+		}
+	}
+
+	if s.nginxService != nil {
+		for i := range backup.ProxyHosts {
+			proxyHost := &backup.ProxyHosts[i]
+			if !proxyHost.Enabled {
+				continue
+			}
+			if err := s.nginxService.DeployProxyHost(proxyHost); err != nil {
+				result.Warnings = append(result.Warnings,
+					fmt.Sprintf("proxy host %s was restored but not deployed: %v", proxyHost.GetPrimaryDomain(), err))
+			}
+		}
+	}
+
+	logger.Info("Full backup restored",
+		logger.String("archive", archivePath),
+		logger.Int("proxy_hosts", result.Counts.ProxyHosts),
+		logger.Int("warnings", len(result.Warnings)))
+	return result, nil
+}
+
+// StartScheduledBackups writes a full backup every interval until ctx is
+// cancelled
+func (s *BackupService) StartScheduledBackups(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.CreateFullBackup(ctx); err != nil {
+				logger.Error("Scheduled full backup failed", logger.Err(err))
+			}
+		}
+	}
+}
+
+func (b *FullBackup) counts() FullBackupCounts {
+	return FullBackupCounts{
+		ProxyHosts:   len(b.ProxyHosts),
+		Certificates: len(b.Certificates),
+		AccessLists:  len(b.AccessLists),
+		Templates:    len(b.Templates),
+		NginxConfigs: len(b.NginxConfigs),
+	}
+}
+
+// packFullBackup writes the backup as the single JSON entry of a tar.gz
+func packFullBackup(backup *FullBackup) ([]byte, error) {
+	content, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize backup: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	header := &tar.Header{
+		Name:    fullBackupEntry,
+		Mode:    0o600,
+		Size:    int64(len(content)),
+		ModTime: backup.CreatedAt,
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return nil, fmt.Errorf("failed to build backup archive: %w", err)
+	}
+	if _, err := archive.Write(content); err != nil {
+		return nil, fmt.Errorf("failed to build backup archive: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build backup archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build backup archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// unpackFullBackup reads the JSON entry of a tar.gz backup
+func unpackFullBackup(data []byte) (*FullBackup, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrBackupFormat
+	}
+	archive := tar.NewReader(gz)
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, ErrBackupFormat
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackupFormat, err)
+		}
+		if header.Name != fullBackupEntry {
+			continue
+		}
+
+		var backup FullBackup
+		if err := json.NewDecoder(archive).Decode(&backup); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackupFormat, err)
+		}
+		if backup.Version != FullBackupVersion {
+			return nil, fmt.Errorf("unsupported backup version %d", backup.Version)
+		}
+		return &backup, nil
+	}
+}
+
+// fullBackupKey derives the AES-256 key for an archive from the passphrase
+func fullBackupKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+// encryptFullBackup seals an archive with AES-256-GCM
+func encryptFullBackup(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrBackupPassphraseRequired
+	}
+
+	salt := make([]byte, fullBackupSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := fullBackupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(fullBackupMagic)+len(salt)+len(nonce)+len(data)+gcm.Overhead())
+	out = append(out, fullBackupMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, data, []byte(fullBackupMagic)), nil
+}
+
+// decryptFullBackup opens an archive sealed by encryptFullBackup
+func decryptFullBackup(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrBackupPassphraseRequired
+	}
+
+	data = data[len(fullBackupMagic):]
+	if len(data) < fullBackupSaltSize {
+		return nil, ErrBackupDecrypt
+	}
+	salt, data := data[:fullBackupSaltSize], data[fullBackupSaltSize:]
+
+	gcm, err := fullBackupCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrBackupDecrypt
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plain, err := gcm.Open(nil, nonce, sealed, []byte(fullBackupMagic))
+	if err != nil {
+		return nil, ErrBackupDecrypt
+	}
+	return plain, nil
+}
+
+func fullBackupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := fullBackupKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestCreateFullBackupPrunesOldArchives writes a backup into a directory that
+// already holds archives and checks that only the newest are kept
+func TestCreateFullBackupPrunesOldArchives(t *testing.T) {
+	newTestDB(t)
+	dir := t.TempDir()
+
+	for _, name := range []string{
+		"nginx-manager-backup-20260101T000000Z.tar.gz",
+		"nginx-manager-backup-20260102T000000Z.tar.gz.enc",
+		"nginx-manager-backup-20260103T000000Z.tar.gz",
+		"notes.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("old"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewBackupService(&LocalBackupTarget{Dir: dir})
+	s.SetRetention(2)
+	result, err := s.CreateFullBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	want := []string{"nginx-manager-backup-20260103T000000Z.tar.gz", result.Name, "notes.txt"}
+	sort.Strings(want)
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("backup directory holds %q, want %q", names, want)
+	}
+}
+
+// TestRestoreFullBackup restores hosts and access lists that were removed
+// after the backup: a host forwarding to a target the policy now denies comes
+// back disabled, flags turned off stay off, the htpasswd file is rewritten
+// and the restore is audited
+func TestRestoreFullBackup(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+
+	accessList := &models.AccessList{
+		Name:   "staff",
+		UserID: owner.ID,
+		Items: []models.AccessListItem{{
+			Type:      models.AccessListItemTypeAuth,
+			Directive: models.AccessListDirectiveAllow,
+			Username:  "alice",
+			Password:  "secret",
+			Enabled:   true,
+		}},
+	}
+	if err := accessList.Items[0].SetPassword("secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(accessList).Error; err != nil {
+		t.Fatal(err)
+	}
+	internal := createTestProxyHost(t, db, owner.ID, "internal.example.com")
+	if err := db.Model(internal).Update("forward_host", "10.0.0.5").Error; err != nil {
+		t.Fatal(err)
+	}
+	plain := createTestProxyHost(t, db, owner.ID, "plain.example.com")
+	if err := db.Model(plain).Updates(map[string]interface{}{"enabled": false, "http2_support": false, "block_exploits": false}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	nginxService := newTestNginxService(t, &MockNginxRunner{})
+	accessLists := NewAccessListService(NewAuthService("test"))
+	accessLists.SetNginxService(nginxService)
+
+	s := NewBackupService(&LocalBackupTarget{Dir: t.TempDir()})
+	s.SetNginxService(nginxService)
+	s.SetAccessListService(accessLists)
+	if err := s.SetIncludePrivateKeys(true, "passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := s.CreateFullBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything backed up is lost, and the policy now denies the host's target
+	for _, model := range []interface{}{&models.ProxyHost{}, &models.AccessListItem{}, &models.AccessList{}, &models.HostDomain{}} {
+		if err := db.Unscoped().Where("1 = 1").Delete(model).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	policy, err := NewForwardTargetPolicy(nil, []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nginxService.SetForwardTargetPolicy(policy)
+
+	archivePath, err := s.ArchivePath(backup.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.RestoreFullBackup(admin.ID, archivePath, AuditContext{IPAddress: "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restored models.ProxyHost
+	if err := db.First(&restored, internal.ID).Error; err != nil {
+		t.Fatalf("proxy host not restored: %v", err)
+	}
+	if restored.Enabled {
+		t.Error("proxy host forwarding to a denied target was restored enabled")
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "internal.example.com") {
+		t.Errorf("got warnings %q, want one about internal.example.com", result.Warnings)
+	}
+	var restoredPlain models.ProxyHost
+	if err := db.First(&restoredPlain, plain.ID).Error; err != nil {
+		t.Fatalf("proxy host not restored: %v", err)
+	}
+	if restoredPlain.Enabled || restoredPlain.HTTP2Support || restoredPlain.BlockExploits {
+		t.Errorf("got enabled %v, http2_support %v, block_exploits %v, want all false as backed up",
+			restoredPlain.Enabled, restoredPlain.HTTP2Support, restoredPlain.BlockExploits)
+	}
+
+	htpasswd, err := os.ReadFile(nginxService.HtpasswdPath(accessList.ID))
+	if err != nil {
+		t.Fatalf("htpasswd file not rewritten: %v", err)
+	}
+	if !strings.HasPrefix(string(htpasswd), "alice:$2") {
+		t.Errorf("htpasswd file holds %q, want alice's bcrypt hash", htpasswd)
+	}
+
+	var audits int64
+	if err := db.Model(&models.AuditLog{}).
+		Where("user_id = ? AND action = ? AND object_type = ?", admin.ID, models.ActionRestored, models.ObjectTypeBackup).
+		Count(&audits).Error; err != nil {
+		t.Fatal(err)
+	}
+	if audits != 1 {
+		t.Errorf("got %d restore audit entries, want 1", audits)
+	}
+}
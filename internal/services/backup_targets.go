@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupTarget stores full backup archives
+type BackupTarget interface {
+	// Write stores an archive under name and returns where it was written
+	Write(ctx context.Context, name string, data []byte) (string, error)
+}
+
+// BackupPruner is a BackupTarget that can remove old archives. S3 targets are
+// not pruned; a bucket lifecycle rule expires their archives.
+type BackupPruner interface {
+	// Prune removes all but the newest keep archives
+	Prune(keep int) error
+}
+
+// LocalBackupTarget writes archives to a directory
+type LocalBackupTarget struct {
+	Dir string
+}
+
+// Write writes the archive readable by the owner only, since it may hold
+// certificates and access list credentials
+func (t *LocalBackupTarget) Write(ctx context.Context, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(t.Dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	path := filepath.Join(t.Dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	return path, nil
+}
+
+// Prune removes all but the newest keep archives in the directory. Archive
+// names embed their creation time, so they sort oldest first.
+func (t *LocalBackupTarget) Prune(keep int) error {
+	entries, err := os.ReadDir(t.Dir)
+	if err != nil {
+		return err
+	}
+
+	var archives []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, fullBackupPrefix) &&
+			(strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tar.gz.enc")) {
+			archives = append(archives, name)
+		}
+	}
+	if len(archives) <= keep {
+		return nil
+	}
+
+	sort.Strings(archives)
+	for _, name := range archives[:len(archives)-keep] {
+		if err := os.Remove(filepath.Join(t.Dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// S3BackupTarget uploads archives to an S3 bucket, or to an S3 compatible
+// store such as MinIO when Endpoint is set, signing requests with AWS
+// Signature Version 4
+type S3BackupTarget struct {
+	Bucket    string
+	Region    string
+	Endpoint  string // empty uses AWS; otherwise path-style requests go here
+	Prefix    string
+	AccessKey string
+	SecretKey string
+
+	client *http.Client
+}
+
+// NewS3BackupTarget creates an S3 backup target
+func NewS3BackupTarget(bucket, region, endpoint, prefix, accessKey, secretKey string) *S3BackupTarget {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3BackupTarget{
+		Bucket:    bucket,
+		Region:    region,
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Prefix:    strings.Trim(prefix, "/"),
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Write uploads the archive with a PUT Object request
+func (t *S3BackupTarget) Write(ctx context.Context, name string, data []byte) (string, error) {
+	key := name
+	if t.Prefix != "" {
+		key = t.Prefix + "/" + name
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, t.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	payloadHash := sha256.Sum256(data)
+	signS3Request(req, hex.EncodeToString(payloadHash[:]), t.Region, t.AccessKey, t.SecretKey, time.Now())
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload backup to S3: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to upload backup to S3: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return "s3://" + t.Bucket + "/" + key, nil
+}
+
+// objectURL builds a virtual-hosted AWS URL, or a path-style URL on a custom
+// endpoint
+func (t *S3BackupTarget) objectURL(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	escaped := strings.Join(segments, "/")
+
+	if t.Endpoint != "" {
+		return t.Endpoint + "/" + url.PathEscape(t.Bucket) + "/" + escaped
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", t.Bucket, t.Region, escaped)
+}
+
+// signS3Request adds AWS Signature Version 4 headers to req, signing the
+// host and every header already set on it
+func signS3Request(req *http.Request, payloadHash, region, accessKey, secretKey string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalURI := req.URL.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
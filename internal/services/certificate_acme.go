@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+// Let's Encrypt ACME directories. Staging issues certificates browsers do not
+// trust, under much higher rate limits, for testing issuance and renewal.
+const (
+	LetsEncryptProductionURL = "https://acme-v02.api.letsencrypt.org/directory"
+	LetsEncryptStagingURL    = "https://acme-staging-v02.api.letsencrypt.org/directory"
+)
+
+// Certificate meta keys for Let's Encrypt options. use_staging and
+// account_email are set by the user and override the global settings; staging
+// and acme_directory record what the certificate was issued with.
+const (
+	CertMetaUseStaging    = "use_staging"
+	CertMetaAccountEmail  = "account_email"
+	CertMetaStaging       = "staging"
+	CertMetaACMEDirectory = "acme_directory"
+)
+
+var ErrInvalidAccountEmail = errors.New("invalid ACME account email address")
+
+// acmeOrder describes how a Let's Encrypt certificate is requested
+type acmeOrder struct {
+	DirectoryURL string
+	Email        string
+	Staging      bool
+	Domains      []string
+}
+
+// SetLetsEncryptStaging makes Let's Encrypt certificates without their own
+// use_staging option come from the staging environment
+func (s *CertificateService) SetLetsEncryptStaging(staging bool) {
+	s.acmeMutex.Lock()
+	s.acmeStaging = staging
+	s.acmeMutex.Unlock()
+}
+
+// SetLetsEncryptEmail sets the ACME account contact used by certificates
+// without their own account_email
+func (s *CertificateService) SetLetsEncryptEmail(email string) error {
+	email = strings.TrimSpace(email)
+	if err := validateAccountEmail(email); err != nil {
+		return err
+	}
+	s.acmeMutex.Lock()
+	s.acmeEmail = email
+	s.acmeMutex.Unlock()
+	return nil
+}
+
+// newACMEOrder resolves the ACME directory and account contact of a
+// certificate from its meta, falling back to the global settings
+func (s *CertificateService) newACMEOrder(certificate *models.Certificate) (*acmeOrder, error) {
+	s.acmeMutex.RLock()
+	order := &acmeOrder{
+		Email:   s.acmeEmail,
+		Staging: s.acmeStaging,
+		Domains: []string(certificate.DomainNames),
+	}
+	s.acmeMutex.RUnlock()
+
+	if staging, ok := certificate.GetMetaValue(CertMetaUseStaging).(bool); ok {
+		order.Staging = staging
+	}
+	if email, ok := certificate.GetMetaValue(CertMetaAccountEmail).(string); ok && strings.TrimSpace(email) != "" {
+		order.Email = strings.TrimSpace(email)
+	}
+	if err := validateAccountEmail(order.Email); err != nil {
+		return nil, err
+	}
+
+	order.DirectoryURL = LetsEncryptProductionURL
+	if order.Staging {
+		order.DirectoryURL = LetsEncryptStagingURL
+	}
+	return order, nil
+}
+
+// recordACMEOrder notes on the certificate which environment issued it, so
+// staging certificates can be flagged as untrusted
+func recordACMEOrder(certificate *models.Certificate, order *acmeOrder) {
+	certificate.SetMetaValue(CertMetaStaging, order.Staging)
+	certificate.SetMetaValue(CertMetaACMEDirectory, order.DirectoryURL)
+
+	logger.Info("Requesting Let's Encrypt certificate",
+		logger.String("domain", certificate.GetPrimaryDomain()),
+		logger.String("directory", order.DirectoryURL),
+		logger.String("account_email", order.Email))
+}
+
+// validateAccountEmail checks an ACME account contact; empty registers
+// without one
+func validateAccountEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || !strings.Contains(email[strings.LastIndex(email, "@")+1:], ".") {
+		return ErrInvalidAccountEmail
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+// Certificate meta keys recording the last failed automatic renewal
+const (
+	certificateRenewalErrorKey    = "renewal_error"
+	certificateRenewalFailedAtKey = "renewal_failed_at"
+)
+
+// recordRenewalFailure keeps the reason an automatic renewal failed on the
+// certificate, so the expiry job knows it will not renew by itself
+func (s *CertificateService) recordRenewalFailure(certificate *models.Certificate, err error) {
+	certificate.SetMetaValue(certificateRenewalErrorKey, err.Error())
+	certificate.SetMetaValue(certificateRenewalFailedAtKey, time.Now())
+	if err := s.db.Model(certificate).UpdateColumn("meta", certificate.Meta).Error; err != nil {
+		logger.Error("Failed to record certificate renewal failure",
+			logger.Uint("certificate_id", certificate.ID),
+			logger.Err(err))
+	}
+}
+
+// needsExpiryNotification reports why a certificate expiring soon will not be
+// renewed automatically, or "" when auto-renew will take care of it
+func needsExpiryNotification(certificate *models.Certificate) string {
+	if !certificate.IsLetsEncrypt() {
+		return "Certificate is not issued by Let's Encrypt and must be replaced manually"
+	}
+	if reason, ok := certificate.GetMetaValue(certificateRenewalErrorKey).(string); ok && reason != "" {
+		return "Automatic renewal failed: " + reason
+	}
+	return ""
+}
+
+// NotifyExpiringCertificates sends an expiry notice to the owner's enabled
+// notification channels for every certificate expiring within leadDays that
+// will not renew automatically. The owner's fallback channel is used when no
+// primary channel delivers. Returns the number of certificates notified.
+func (s *CertificateService) NotifyExpiringCertificates(leadDays int) (int, error) {
+	if s.notificationService == nil {
+		return 0, nil
+	}
+
+	var certificates []models.Certificate
+	threshold := time.Now().Add(time.Duration(leadDays) * 24 * time.Hour)
+	if err := s.db.Where("expires_on IS NOT NULL AND expires_on <= ?", threshold).
+		Find(&certificates).Error; err != nil {
+		return 0, err
+	}
+
+	notified := 0
+	for i := range certificates {
+		certificate := &certificates[i]
+		reason := needsExpiryNotification(certificate)
+		if reason == "" {
+			continue
+		}
+
+		var channels []models.NotificationChannel
+		if err := s.db.Where("user_id = ? AND is_enabled = ?", certificate.UserID, true).
+			Find(&channels).Error; err != nil {
+			return notified, err
+		}
+
+		daysRemaining := int(math.Floor(time.Until(*certificate.ExpiresOn).Hours() / 24))
+		sent := 0
+		var fallback *models.NotificationChannel
+		for j := range channels {
+			if channels[j].IsFallback {
+				fallback = &channels[j]
+				continue
+			}
+			if err := s.notificationService.SendCertificateExpiry(channels[j], certificate, daysRemaining, reason); err != nil {
+				logger.Error("Failed to send certificate expiry notification",
+					logger.Uint("certificate_id", certificate.ID),
+					logger.String("channel", channels[j].Name),
+					logger.Err(err))
+				continue
+			}
+			sent++
+		}
+		if sent == 0 && fallback != nil {
+			if err := s.notificationService.SendCertificateExpiry(*fallback, certificate, daysRemaining, reason); err != nil {
+				logger.Error("Failed to send certificate expiry notification via fallback channel",
+					logger.Uint("certificate_id", certificate.ID),
+					logger.String("channel", fallback.Name),
+					logger.Err(err))
+			} else {
+				sent++
+			}
+		}
+
+		if sent > 0 {
+			notified++
+		} else if len(channels) == 0 {
+			logger.Warn("Certificate expiring with no notification channel configured",
+				logger.Uint("certificate_id", certificate.ID),
+				logger.Uint("user_id", certificate.UserID),
+				logger.Int("days_remaining", daysRemaining))
+		}
+	}
+
+	return notified, nil
+}
+
+// StartExpiryNotifications checks for expiring certificates on startup and
+// then every interval, notifying owners of those within the expiry lead time
+func (s *CertificateService) StartExpiryNotifications(ctx context.Context, interval time.Duration) {
+	check := func() {
+		_, leadDays := s.leadDays()
+		notified, err := s.NotifyExpiringCertificates(leadDays)
+		if err != nil {
+			logger.Error("Failed to check certificate expiry", logger.Err(err))
+			return
+		}
+		if notified > 0 {
+			logger.Info("Sent certificate expiry notifications", logger.Int("certificates", notified))
+		}
+	}
+
+	check()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+// formatDaysRemaining describes how long until, or since, a certificate expires
+func formatDaysRemaining(days int) string {
+	switch {
+	case days < 0:
+		return fmt.Sprintf("expired %d day(s) ago", -days)
+	case days == 0:
+		return "expires today"
+	default:
+		return fmt.Sprintf("%d day(s) remaining", days)
+	}
+}
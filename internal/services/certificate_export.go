@@ -0,0 +1,221 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+var (
+	ErrInvalidExportFormat      = errors.New("invalid export format, expected pkcs12 or pem-bundle")
+	ErrExportPassphraseRequired = errors.New("a passphrase is required for PKCS#12 export")
+	ErrCertificateNotIssued     = errors.New("certificate has no certificate and key to export")
+)
+
+// Certificate export formats
+const (
+	CertificateExportPKCS12    = "pkcs12"
+	CertificateExportPEMBundle = "pem-bundle"
+)
+
+// CertificateExport is an encoded certificate ready to be downloaded
+type CertificateExport struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ExportCertificate encodes a certificate with its private key as a PKCS#12
+// file or a zip of cert.pem, key.pem and chain.pem. Only the owner or a
+// user with certificate:manage may export, and every export is audit logged before it is returned.
+// Decoded key material is zeroed once encoded; the caller should zero Data
+// after sending it.
+func (s *CertificateService) ExportCertificate(userID uint, id uint, format, passphrase, ipAddress, userAgent string) (*CertificateExport, error) {
+	if format != CertificateExportPKCS12 && format != CertificateExportPEMBundle {
+		return nil, ErrInvalidExportFormat
+	}
+	if format == CertificateExportPKCS12 && passphrase == "" {
+		return nil, ErrExportPassphraseRequired
+	}
+
+	// GetCertificate strips the key for non-admins, so load it directly
+	var certificate models.Certificate
+	query := s.db.Where("id = ?", id)
+	if !s.authService.HasPermission(userID, models.PermissionCertificateManage) {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.First(&certificate).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCertificateNotFound
+		}
+		return nil, err
+	}
+	if !certificate.IsValid() {
+		return nil, ErrCertificateNotIssued
+	}
+
+	chain, err := parseCertificatesPEM(certificate.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	if certificate.IntermediateCertificate != "" {
+		intermediates, err := parseCertificatesPEM(certificate.IntermediateCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+		}
+		for _, intermediate := range intermediates {
+			if !containsCertificate(chain, intermediate.Raw) {
+				chain = append(chain, intermediate)
+			}
+		}
+	}
+
+	keyPEM := []byte(certificate.CertificateKey)
+	certificate.CertificateKey = ""
+	defer clear(keyPEM)
+
+	baseName := exportBaseName(&certificate)
+	export := &CertificateExport{}
+
+	switch format {
+	case CertificateExportPKCS12:
+		keyPair, err := tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: chain[0].Raw}), keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+		}
+		data, err := pkcs12.Modern.Encode(keyPair.PrivateKey, chain[0], chain[1:], passphrase)
+		zeroPrivateKey(keyPair.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode PKCS#12: %w", err)
+		}
+		export.Filename = baseName + ".p12"
+		export.ContentType = "application/x-pkcs12"
+		export.Data = data
+
+	case CertificateExportPEMBundle:
+		var chainPEM []byte
+		for _, cert := range chain[1:] {
+			chainPEM = append(chainPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+		}
+
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		files := []struct {
+			name string
+			data []byte
+		}{
+			{"cert.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: chain[0].Raw})},
+			{"key.pem", keyPEM},
+			{"chain.pem", chainPEM},
+		}
+		for _, file := range files {
+			writer, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: time.Now()})
+			if err == nil {
+				_, err = writer.Write(file.data)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to build PEM bundle: %w", err)
+			}
+		}
+		if err := archive.Close(); err != nil {
+			return nil, fmt.Errorf("failed to build PEM bundle: %w", err)
+		}
+		export.Filename = baseName + ".zip"
+		export.ContentType = "application/zip"
+		export.Data = buf.Bytes()
+	}
+
+	if err := s.db.Create(&models.AuditLog{
+		UserID:      userID,
+		Action:      models.ActionExported,
+		ObjectType:  models.ObjectTypeCertificate,
+		ObjectID:    certificate.ID,
+		Description: fmt.Sprintf("Downloaded certificate with private key: %s", certificate.GetPrimaryDomain()),
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Meta: models.JSON{
+			"format":   format,
+			"owner_id": certificate.UserID,
+		},
+	}).Error; err != nil {
+		clear(export.Data)
+		return nil, fmt.Errorf("failed to record certificate export: %w", err)
+	}
+
+	logger.Warn("Certificate private key exported",
+		logger.Uint("certificate_id", certificate.ID),
+		logger.Uint("user_id", userID),
+		logger.String("format", format),
+		logger.String("ip_address", ipAddress))
+
+	return export, nil
+}
+
+// containsCertificate reports whether chain already holds the DER certificate raw
+func containsCertificate(chain []*x509.Certificate, raw []byte) bool {
+	for _, cert := range chain {
+		if bytes.Equal(cert.Raw, raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// exportBaseName names a download after the certificate's primary domain,
+// with a wildcard written as "wildcard" so the name is a safe filename
+func exportBaseName(certificate *models.Certificate) string {
+	name := strings.ReplaceAll(certificate.GetPrimaryDomain(), "*", "wildcard")
+	name = strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" {
+		return fmt.Sprintf("certificate-%d", certificate.ID)
+	}
+	return name
+}
+
+// zeroPrivateKey overwrites the secret parts of a decoded private key. This
+// is best effort: the crypto packages may hold copies of their own.
+func zeroPrivateKey(key crypto.PrivateKey) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		zeroBigInt(k.D)
+		for _, prime := range k.Primes {
+			zeroBigInt(prime)
+		}
+		zeroBigInt(k.Precomputed.Dp)
+		zeroBigInt(k.Precomputed.Dq)
+		zeroBigInt(k.Precomputed.Qinv)
+	case *ecdsa.PrivateKey:
+		zeroBigInt(k.D)
+	case ed25519.PrivateKey:
+		clear(k)
+	}
+}
+
+// zeroBigInt clears the words backing n, not just its length
+func zeroBigInt(n *big.Int) {
+	if n != nil {
+		clear(n.Bits())
+		n.SetInt64(0)
+	}
+}
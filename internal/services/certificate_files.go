@@ -0,0 +1,100 @@
+package services
+
+import (
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+// nginxCertificatesPath is the directory generated host configs load stored
+// certificates from
+const nginxCertificatesPath = "/etc/nginx/certificates"
+
+// certificateFile returns the file a stored certificate's part is written to:
+// cert (the full chain served to clients), key, or chain (the uploaded
+// intermediates, trusted for OCSP stapling)
+func certificateFile(dir, part string, id uint) string {
+	return filepath.Join(dir, fmt.Sprintf("%s_%d.pem", part, id))
+}
+
+// writeCertificateFiles writes the files nginx loads a certificate from. The
+// chain file only exists while intermediates are uploaded, so a stale one is
+// removed. A certificate without a key has nothing to serve.
+func (s *CertificateService) writeCertificateFiles(certificate *models.Certificate) error {
+	if !certificate.IsValid() {
+		return nil
+	}
+	if err := os.MkdirAll(s.filesPath, 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(certificateFile(s.filesPath, "cert", certificate.ID), []byte(fullChainPEM(certificate)), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certificateFile(s.filesPath, "key", certificate.ID), []byte(certificate.CertificateKey), 0600); err != nil {
+		return err
+	}
+
+	chainFile := certificateFile(s.filesPath, "chain", certificate.ID)
+	if certificate.IntermediateCertificate == "" {
+		if err := os.Remove(chainFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(chainFile, []byte(certificate.IntermediateCertificate), 0644)
+}
+
+// syncCertificateFiles writes a saved certificate's files, logging a failure:
+// the certificate is already saved and the files are rewritten on its next save
+func (s *CertificateService) syncCertificateFiles(certificate *models.Certificate) {
+	if err := s.writeCertificateFiles(certificate); err != nil {
+		logger.Error("Failed to write certificate files",
+			logger.Uint("certificate_id", certificate.ID), logger.Err(err))
+	}
+}
+
+// removeCertificateFiles removes a purged certificate's files
+func (s *CertificateService) removeCertificateFiles(id uint) {
+	for _, part := range []string{"cert", "key", "chain"} {
+		if err := os.Remove(certificateFile(s.filesPath, part, id)); err != nil && !os.IsNotExist(err) {
+			logger.Warn("Failed to remove certificate file", logger.Uint("certificate_id", id), logger.Err(err))
+		}
+	}
+}
+
+// fullChainPEM returns the certificate followed by the uploaded intermediates
+// it does not already bundle
+func fullChainPEM(certificate *models.Certificate) string {
+	if certificate.IntermediateCertificate == "" {
+		return certificate.Certificate
+	}
+	chain, err := parseCertificatesPEM(certificate.Certificate)
+	if err != nil {
+		return certificate.Certificate
+	}
+	intermediates, err := parseCertificatesPEM(certificate.IntermediateCertificate)
+	if err != nil {
+		return certificate.Certificate
+	}
+
+	var full strings.Builder
+	for _, cert := range chain {
+		full.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	}
+	for _, intermediate := range intermediates {
+		duplicate := false
+		for _, existing := range chain {
+			duplicate = duplicate || existing.Equal(intermediate)
+		}
+		if !duplicate {
+			full.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: intermediate.Raw}))
+		}
+	}
+	return full.String()
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestSavingCertificateWritesFiles checks that saving a certificate writes
+// the files nginx loads it from, and that removing the intermediates removes
+// the chain file
+func TestSavingCertificateWritesFiles(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	_, intermediate, leaf := newTestChain(t)
+
+	s := NewCertificateService(t.TempDir(), t.TempDir(), NewAuthService("test"))
+	s.filesPath = t.TempDir()
+
+	req := &CertificateRequest{
+		Name:                    "example.com",
+		Provider:                models.ProviderCustom,
+		DomainNames:             []string{"example.com"},
+		Certificate:             leaf.certPEM(),
+		CertificateKey:          leaf.keyPEM(t),
+		IntermediateCertificate: intermediate.certPEM(),
+	}
+	certificate, err := s.CreateCertificate(owner.ID, req, AuditContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(part string) string {
+		t.Helper()
+		content, err := os.ReadFile(certificateFile(s.filesPath, part, certificate.ID))
+		if err != nil {
+			t.Fatalf("%s file: %v", part, err)
+		}
+		return string(content)
+	}
+	if got, want := read("cert"), leaf.certPEM()+intermediate.certPEM(); got != want {
+		t.Errorf("cert file is not the leaf followed by the intermediate:\n%s", got)
+	}
+	if got := read("chain"); got != intermediate.certPEM() {
+		t.Errorf("chain file is not the intermediate:\n%s", got)
+	}
+	if got := read("key"); got != req.CertificateKey {
+		t.Error("key file does not hold the private key")
+	}
+	info, err := os.Stat(certificateFile(s.filesPath, "key", certificate.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("key file has mode %v, want 0600", info.Mode().Perm())
+	}
+
+	// Bundling the intermediate with the leaf leaves nothing separate to trust
+	req.Certificate = leaf.certPEM() + intermediate.certPEM()
+	req.IntermediateCertificate = ""
+	if _, err := s.UpdateCertificate(owner.ID, certificate.ID, req, AuditContext{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(certificateFile(s.filesPath, "chain", certificate.ID)); !os.IsNotExist(err) {
+		t.Errorf("chain file still exists after the intermediates were removed: %v", err)
+	}
+	if got := read("cert"); strings.Count(got, "BEGIN CERTIFICATE") != 2 {
+		t.Errorf("cert file holds %d certificates, want 2", strings.Count(got, "BEGIN CERTIFICATE"))
+	}
+}
+
+// TestOCSPStaplingTrustsChainFile checks that a host stapling OCSP trusts the
+// chain file written for its certificate's intermediates
+func TestOCSPStaplingTrustsChainFile(t *testing.T) {
+	newTestDB(t)
+	_, intermediate, leaf := newTestChain(t)
+	s := newTestNginxService(t, &MockNginxRunner{})
+
+	certificate := &models.Certificate{
+		Certificate:             leaf.certPEM(),
+		CertificateKey:          leaf.keyPEM(t),
+		IntermediateCertificate: intermediate.certPEM(),
+	}
+	certificate.ID = 7
+	proxyHost := &models.ProxyHost{
+		DomainNames:   models.StringArray{"example.com"},
+		ForwardScheme: "http",
+		ForwardHost:   "127.0.0.1",
+		ForwardPort:   8080,
+		CertificateID: &certificate.ID,
+		OCSPStapling:  true,
+	}
+
+	config, err := s.renderTemplate(proxyHost, certificate, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ssl_trusted_certificate " + filepath.Join(nginxCertificatesPath, "chain_7.pem") + ";"
+	if !strings.Contains(config, want) {
+		t.Errorf("config does not contain %q:\n%s", want, config)
+	}
+}
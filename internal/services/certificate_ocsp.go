@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+var (
+	ErrNoOCSPResponder     = errors.New("certificate has no OCSP responder URL")
+	ErrSelfSignedNoOCSP    = errors.New("self-signed certificates have no OCSP responder")
+	ErrOCSPIssuerNotFound  = errors.New("issuer certificate not found; upload the intermediate certificate")
+	ErrOCSPResponderFailed = errors.New("OCSP responder request failed")
+)
+
+// OCSP statuses reported by CheckOCSPStatus
+const (
+	OCSPStatusGood    = "good"
+	OCSPStatusRevoked = "revoked"
+	OCSPStatusUnknown = "unknown"
+)
+
+// ocspRequestTimeout bounds the issuer download and the responder request
+const ocspRequestTimeout = 10 * time.Second
+
+// ocspCheckTimeout bounds a whole check, which may try several issuer URLs
+// before asking the responder
+const ocspCheckTimeout = 30 * time.Second
+
+// maxOCSPResponseSize caps how much of a responder reply is read
+const maxOCSPResponseSize = 1 << 20
+
+// OCSPStatusResult reports what a certificate's OCSP responder said about it
+type OCSPStatusResult struct {
+	CertificateID    uint       `json:"certificate_id"`
+	Status           string     `json:"status"`
+	Responder        string     `json:"responder"`
+	SerialNumber     string     `json:"serial_number"`
+	ProducedAt       time.Time  `json:"produced_at"`
+	ThisUpdate       time.Time  `json:"this_update"`
+	NextUpdate       *time.Time `json:"next_update,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason int        `json:"revocation_reason,omitempty"`
+	CheckedAt        time.Time  `json:"checked_at"`
+}
+
+// CheckOCSPStatus asks the OCSP responder named in a certificate whether it
+// is good, revoked or unknown. The issuer is taken from the intermediate
+// bundle, the rest of the certificate chain or, failing both, downloaded from
+// the certificate's issuing certificate URL.
+func (s *CertificateService) CheckOCSPStatus(userID uint, id uint) (*OCSPStatusResult, error) {
+	certificate, err := s.GetCertificate(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := parseCertificatesPEM(certificate.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	leaf := chain[0]
+
+	if len(leaf.OCSPServer) == 0 {
+		if bytes.Equal(leaf.RawIssuer, leaf.RawSubject) {
+			return nil, ErrSelfSignedNoOCSP
+		}
+		return nil, ErrNoOCSPResponder
+	}
+
+	responder := leaf.OCSPServer[0]
+	if err := s.checkOCSPURL(responder); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOCSPResponderFailed, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ocspCheckTimeout)
+	defer cancel()
+
+	// Redirects are held to the same rules as the URLs in the certificate
+	client := &http.Client{
+		Timeout: ocspRequestTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return s.checkOCSPURL(req.URL.String())
+		},
+	}
+	issuer, err := s.findOCSPIssuer(ctx, client, leaf, chain[1:], certificate.IntermediateCertificate)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCSP request: %w", err)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, responder, bytes.NewReader(request))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOCSPResponderFailed, err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/ocsp-request")
+	resp, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOCSPResponderFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s returned %s", ErrOCSPResponderFailed, responder, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOCSPResponderFailed, err)
+	}
+
+	parsed, err := ocsp.ParseResponseForCert(body, leaf, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOCSPResponderFailed, err)
+	}
+
+	result := &OCSPStatusResult{
+		CertificateID: certificate.ID,
+		Status:        OCSPStatusUnknown,
+		Responder:     responder,
+		SerialNumber:  fmt.Sprintf("%X", leaf.SerialNumber),
+		ProducedAt:    parsed.ProducedAt,
+		ThisUpdate:    parsed.ThisUpdate,
+		CheckedAt:     time.Now(),
+	}
+	if !parsed.NextUpdate.IsZero() {
+		result.NextUpdate = &parsed.NextUpdate
+	}
+
+	switch parsed.Status {
+	case ocsp.Good:
+		result.Status = OCSPStatusGood
+	case ocsp.Revoked:
+		result.Status = OCSPStatusRevoked
+		result.RevokedAt = &parsed.RevokedAt
+		result.RevocationReason = parsed.RevocationReason
+	}
+
+	return result, nil
+}
+
+// SetForwardTargetPolicy sets the policy OCSP responder and issuer URLs are
+// checked against, the same one restricting proxy host forward targets, so a
+// certificate cannot make the server request internal addresses
+func (s *CertificateService) SetForwardTargetPolicy(policy *ForwardTargetPolicy) {
+	s.forwardTargets = policy
+}
+
+// checkOCSPURL refuses a responder or issuer URL that is not http or https or
+// whose host the forward target policy denies
+func (s *CertificateService) checkOCSPURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("%q is not an http or https URL", rawURL)
+	}
+	return s.forwardTargets.Check(parsed.Hostname())
+}
+
+// findOCSPIssuer returns the certificate that signed leaf, looking in the
+// uploaded intermediates, the rest of the chain and then the AIA issuer URL
+func (s *CertificateService) findOCSPIssuer(ctx context.Context, client *http.Client, leaf *x509.Certificate, chain []*x509.Certificate, intermediatePEM string) (*x509.Certificate, error) {
+	candidates := chain
+	if intermediatePEM != "" {
+		if intermediates, err := parseCertificatesPEM(intermediatePEM); err == nil {
+			candidates = append(intermediates, candidates...)
+		}
+	}
+	for _, candidate := range candidates {
+		if leaf.CheckSignatureFrom(candidate) == nil {
+			return candidate, nil
+		}
+	}
+
+	for _, issuerURL := range leaf.IssuingCertificateURL {
+		if s.checkOCSPURL(issuerURL) != nil {
+			continue
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuerURL, nil)
+		if err != nil {
+			continue
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		// Issuers are published as DER, occasionally as PEM
+		issuer, err := x509.ParseCertificate(body)
+		if err != nil {
+			parsed, pemErr := parseCertificatesPEM(string(body))
+			if pemErr != nil {
+				continue
+			}
+			issuer = parsed[0]
+		}
+		if leaf.CheckSignatureFrom(issuer) == nil {
+			return issuer, nil
+		}
+	}
+
+	return nil, ErrOCSPIssuerNotFound
+}
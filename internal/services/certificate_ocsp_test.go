@@ -0,0 +1,97 @@
+package services
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+func TestCheckOCSPURL(t *testing.T) {
+	policy, err := NewForwardTargetPolicy(nil, []string{"169.254.0.0/16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &CertificateService{forwardTargets: policy}
+
+	for _, tc := range []struct {
+		url     string
+		allowed bool
+	}{
+		{url: "http://192.0.2.10/ocsp", allowed: true},
+		{url: "https://192.0.2.10:8443/", allowed: true},
+		{url: "http://169.254.169.254/latest/meta-data/"},
+		{url: "ftp://192.0.2.10/ocsp"},
+		{url: "file:///etc/passwd"},
+		{url: "http:///ocsp"},
+	} {
+		if err := s.checkOCSPURL(tc.url); (err == nil) != tc.allowed {
+			t.Errorf("%s: got %v, want allowed=%v", tc.url, err, tc.allowed)
+		}
+	}
+}
+
+// TestCheckOCSPStatusRefusesDeniedResponders checks that neither a responder
+// nor a redirect from it can reach a target the policy denies
+func TestCheckOCSPStatusRefusesDeniedResponders(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Redirect(w, r, "http://10.0.0.1/ocsp", http.StatusFound)
+	}))
+	t.Cleanup(server.Close)
+
+	for _, tc := range []struct {
+		name     string
+		deny     string
+		wantHits int32
+		wantErr  string
+	}{
+		{name: "denied responder", deny: "127.0.0.0/8", wantHits: 0, wantErr: "denied target 127.0.0.0/8"},
+		{name: "denied redirect", deny: "10.0.0.0/8", wantHits: 1, wantErr: "denied target 10.0.0.0/8"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hits.Store(0)
+			db := newTestDB(t)
+			owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+
+			root := newTestCertificate(t, "Test Root CA", true, nil)
+			intermediate := newTestCertificate(t, "Test Intermediate CA", true, &root)
+			leaf := newTestCertificate(t, "example.com", false, &intermediate, func(template *x509.Certificate) {
+				template.OCSPServer = []string{server.URL + "/ocsp"}
+			})
+			certificate := &models.Certificate{
+				Name:                    "example.com",
+				Provider:                models.ProviderCustom,
+				DomainNames:             models.StringArray{"example.com"},
+				Certificate:             leaf.certPEM(),
+				CertificateKey:          leaf.keyPEM(t),
+				IntermediateCertificate: intermediate.certPEM(),
+				UserID:                  owner.ID,
+			}
+			if err := db.Create(certificate).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			policy, err := NewForwardTargetPolicy(nil, []string{tc.deny})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewCertificateService(t.TempDir(), t.TempDir(), NewAuthService("test"))
+			s.SetForwardTargetPolicy(policy)
+
+			_, err = s.CheckOCSPStatus(owner.ID, certificate.ID)
+			if !errors.Is(err, ErrOCSPResponderFailed) || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got %v, want %v mentioning %q", err, ErrOCSPResponderFailed, tc.wantErr)
+			}
+			if got := hits.Load(); got != tc.wantHits {
+				t.Errorf("responder was requested %d times, want %d", got, tc.wantHits)
+			}
+		})
+	}
+}
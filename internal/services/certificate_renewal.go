@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// certificateStatusRenewing marks a certificate whose renewal is under way
+const certificateStatusRenewing = "renewing"
+
+// renewalStaleAfter is how long a renewing status keeps other renewals out.
+// A renewal interrupted by a crash or restart leaves the status behind, so an
+// older one is taken over.
+const renewalStaleAfter = 15 * time.Minute
+
+var ErrRenewalInProgress = errors.New("certificate renewal already in progress")
+
+// lockRenewal takes the in-process renewal lock of a certificate, reporting
+// false when another renewal of it is running
+func (s *CertificateService) lockRenewal(id uint) bool {
+	s.renewalMutex.Lock()
+	defer s.renewalMutex.Unlock()
+
+	if s.renewing[id] {
+		return false
+	}
+	s.renewing[id] = true
+	return true
+}
+
+// unlockRenewal releases the in-process renewal lock of a certificate
+func (s *CertificateService) unlockRenewal(id uint) {
+	s.renewalMutex.Lock()
+	delete(s.renewing, id)
+	s.renewalMutex.Unlock()
+}
+
+// claimRenewal sets the certificate's status to renewing, provided it is
+// still the status that was loaded. This keeps out renewals from other
+// servers sharing the database, which the in-process lock cannot see.
+func (s *CertificateService) claimRenewal(certificate *models.Certificate) error {
+	if certificate.Status == certificateStatusRenewing && time.Since(certificate.UpdatedAt) < renewalStaleAfter {
+		return ErrRenewalInProgress
+	}
+
+	now := time.Now()
+	result := s.db.Model(&models.Certificate{}).
+		Where("id = ? AND status = ?", certificate.ID, certificate.Status).
+		UpdateColumns(map[string]interface{}{
+			"status":     certificateStatusRenewing,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRenewalInProgress
+	}
+
+	certificate.Status = certificateStatusRenewing
+	certificate.UpdatedAt = now
+	return nil
+}
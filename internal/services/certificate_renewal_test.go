@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// blockingIssuer stands in for the certificate issuer: each call reports
+// itself on started and waits for release
+type blockingIssuer struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingIssuer() *blockingIssuer {
+	return &blockingIssuer{started: make(chan struct{}, 2), release: make(chan struct{})}
+}
+
+func (i *blockingIssuer) issue(domains []string) (string, string, error) {
+	i.calls.Add(1)
+	i.started <- struct{}{}
+	<-i.release
+	return "CERTIFICATE", "KEY", nil
+}
+
+// createExpiringCertificate creates a Let's Encrypt certificate due for renewal
+func createExpiringCertificate(t *testing.T, db *gorm.DB, userID uint) *models.Certificate {
+	t.Helper()
+
+	expires := time.Now().Add(5 * 24 * time.Hour)
+	certificate := &models.Certificate{
+		Name:        "example.com",
+		Provider:    models.ProviderLetsEncrypt,
+		DomainNames: models.StringArray{"example.com"},
+		ExpiresOn:   &expires,
+		Status:      "active",
+		UserID:      userID,
+	}
+	if err := db.Create(certificate).Error; err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+// TestConcurrentRenewals starts a manual and an automatic renewal of the same
+// certificate, each while the other is issuing: exactly one renews
+func TestConcurrentRenewals(t *testing.T) {
+	for name, manualFirst := range map[string]bool{"manual first": true, "automatic first": false} {
+		t.Run(name, func(t *testing.T) {
+			db := newTestDB(t)
+			owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+			certificate := createExpiringCertificate(t, db, owner.ID)
+
+			issuer := newBlockingIssuer()
+			s := NewCertificateService(t.TempDir(), t.TempDir(), NewAuthService("test"))
+			s.issueCertificate = issuer.issue
+
+			manualErr := make(chan error, 1)
+			autoRenewed := make(chan bool, 1)
+			renewManually := func() {
+				_, err := s.RenewCertificate(owner.ID, certificate.ID, AuditContext{})
+				manualErr <- err
+			}
+			renewAutomatically := func() { autoRenewed <- s.autoRenewCertificate(certificate.ID) }
+
+			if manualFirst {
+				go renewManually()
+				<-issuer.started
+				renewAutomatically()
+				if <-autoRenewed {
+					t.Error("the automatic renewal ran alongside the manual one")
+				}
+				close(issuer.release)
+				if err := <-manualErr; err != nil {
+					t.Errorf("manual renewal failed: %v", err)
+				}
+			} else {
+				go renewAutomatically()
+				<-issuer.started
+				renewManually()
+				if err := <-manualErr; !errors.Is(err, ErrRenewalInProgress) {
+					t.Errorf("manual renewal got %v, want ErrRenewalInProgress", err)
+				}
+				close(issuer.release)
+				if !<-autoRenewed {
+					t.Error("the automatic renewal did not renew")
+				}
+			}
+
+			if calls := issuer.calls.Load(); calls != 1 {
+				t.Errorf("certificate issued %d times, want 1", calls)
+			}
+
+			var renewed models.Certificate
+			if err := db.First(&renewed, certificate.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if renewed.Status != "active" || renewed.Certificate != "CERTIFICATE" {
+				t.Errorf("got status %q and certificate %q after renewal", renewed.Status, renewed.Certificate)
+			}
+		})
+	}
+}
+
+// TestRenewalClaimedByAnotherServer checks the renewing status keeps out a
+// renewal from another process sharing the database, which the in-process
+// lock cannot see
+func TestRenewalClaimedByAnotherServer(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	certificate := createExpiringCertificate(t, db, owner.ID)
+
+	issuer := newBlockingIssuer()
+	first := NewCertificateService(t.TempDir(), t.TempDir(), NewAuthService("test"))
+	first.issueCertificate = issuer.issue
+	second := NewCertificateService(t.TempDir(), t.TempDir(), NewAuthService("test"))
+	second.issueCertificate = issuer.issue
+
+	done := make(chan bool, 1)
+	go func() { done <- first.autoRenewCertificate(certificate.ID) }()
+	<-issuer.started
+
+	if _, err := second.RenewCertificate(owner.ID, certificate.ID, AuditContext{}); !errors.Is(err, ErrRenewalInProgress) {
+		t.Errorf("second server got %v, want ErrRenewalInProgress", err)
+	}
+
+	close(issuer.release)
+	if !<-done {
+		t.Error("first server did not renew")
+	}
+	if calls := issuer.calls.Load(); calls != 1 {
+		t.Errorf("certificate issued %d times, want 1", calls)
+	}
+}
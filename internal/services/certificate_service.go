@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"math/big"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/nguyendkn/nginx-manager/internal/database"
@@ -28,20 +29,108 @@ var (
 
 // CertificateService handles SSL certificate management
 type CertificateService struct {
-	db          *gorm.DB
-	authService *AuthService
-	certPath    string
-	keyPath     string
+	db                  *gorm.DB
+	authService         *AuthService
+	activityService     *ActivityService
+	notificationService *NotificationService
+	certPath            string
+	keyPath             string
+	filesPath           string // where nginx loads certificate files from
+	forwardTargets      *ForwardTargetPolicy
+
+	// Lead times in days, changeable at runtime from settings
+	leadTimeMutex   sync.RWMutex
+	renewalLeadDays int
+	expiryLeadDays  int
+
+	// Let's Encrypt defaults, changeable at runtime from settings
+	acmeMutex   sync.RWMutex
+	acmeStaging bool
+	acmeEmail   string
+
+	// Certificates being renewed by this process
+	renewalMutex sync.Mutex
+	renewing     map[uint]bool
+
+	// issueCertificate returns a new certificate and key for the domains
+	issueCertificate func(domains []string) (string, string, error)
 }
 
+// Default lead times before a certificate expires
+const (
+	defaultRenewalLeadDays = 30
+	defaultExpiryLeadDays  = 14
+)
+
 // NewCertificateService creates a new certificate service instance
 func NewCertificateService(certPath, keyPath string, authService *AuthService) *CertificateService {
-	return &CertificateService{
-		db:          database.GetDB(),
-		authService: authService,
-		certPath:    certPath,
-		keyPath:     keyPath,
+	s := &CertificateService{
+		db:              database.GetDB(),
+		authService:     authService,
+		certPath:        certPath,
+		keyPath:         keyPath,
+		filesPath:       nginxCertificatesPath,
+		renewalLeadDays: defaultRenewalLeadDays,
+		expiryLeadDays:  defaultExpiryLeadDays,
+		renewing:        make(map[uint]bool),
+	}
+	s.issueCertificate = s.generateSelfSignedCertificate
+	return s
+}
+
+// SetRenewalLeadDays sets how many days before expiry Let's Encrypt
+// certificates are renewed
+func (s *CertificateService) SetRenewalLeadDays(days int) {
+	if days < 1 {
+		days = defaultRenewalLeadDays
+	}
+	s.leadTimeMutex.Lock()
+	s.renewalLeadDays = days
+	s.leadTimeMutex.Unlock()
+}
+
+// SetExpiryLeadDays sets how many days before expiry owners of certificates
+// that will not auto-renew are notified
+func (s *CertificateService) SetExpiryLeadDays(days int) {
+	if days < 1 {
+		days = defaultExpiryLeadDays
 	}
+	s.leadTimeMutex.Lock()
+	s.expiryLeadDays = days
+	s.leadTimeMutex.Unlock()
+}
+
+// leadDays returns the current renewal and expiry notice lead times
+func (s *CertificateService) leadDays() (renewal, expiry int) {
+	s.leadTimeMutex.RLock()
+	defer s.leadTimeMutex.RUnlock()
+	return s.renewalLeadDays, s.expiryLeadDays
+}
+
+// SetActivityService records certificate lifecycle events in the activity feed
+func (s *CertificateService) SetActivityService(activityService *ActivityService) {
+	s.activityService = activityService
+}
+
+// SetNotificationService enables certificate expiry notifications
+func (s *CertificateService) SetNotificationService(notificationService *NotificationService) {
+	s.notificationService = notificationService
+}
+
+// recordActivity adds a certificate event to the activity feed of its owner
+func (s *CertificateService) recordActivity(certificate *models.Certificate, action, level string, err error) {
+	message := fmt.Sprintf("Certificate %s %s", certificate.GetPrimaryDomain(), action)
+	details := models.JSON{
+		"certificate_id": certificate.ID,
+		"domain":         certificate.GetPrimaryDomain(),
+		"provider":       certificate.Provider,
+		"action":         action,
+	}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+
+	s.activityService.RecordForUser(certificate.UserID, ActivityTypeCertificate, message, level, details)
 }
 
 // CertificateRequest represents certificate create/update request
@@ -57,7 +146,7 @@ type CertificateRequest struct {
 }
 
 // CreateCertificate creates a new certificate
-func (s *CertificateService) CreateCertificate(userID uint, req *CertificateRequest) (*models.Certificate, error) {
+func (s *CertificateService) CreateCertificate(userID uint, req *CertificateRequest, audit AuditContext) (*models.Certificate, error) {
 	// Validate provider
 	if !req.Provider.IsValid() {
 		return nil, errors.New("invalid certificate provider")
@@ -108,28 +197,33 @@ func (s *CertificateService) CreateCertificate(userID uint, req *CertificateRequ
 	if err := s.db.Save(certificate).Error; err != nil {
 		logger.Warn("Failed to update certificate expiry", logger.Err(err))
 	}
+	s.syncCertificateFiles(certificate)
+
+	RecordAudit(s.db, audit, NewAuditLog(userID, models.ActionCreated, models.ObjectTypeCertificate, certificate.ID,
+		fmt.Sprintf("Created certificate: %s", certificate.GetPrimaryDomain())))
+	s.recordActivity(certificate, "created", ActivityLevelInfo, nil)
 
 	return certificate, nil
 }
 
 // UpdateCertificate updates an existing certificate
-func (s *CertificateService) UpdateCertificate(userID uint, id uint, req *CertificateRequest) (*models.Certificate, error) {
+func (s *CertificateService) UpdateCertificate(userID uint, id uint, req *CertificateRequest, audit AuditContext) (*models.Certificate, error) {
 	// Find existing certificate
 	var certificate models.Certificate
-	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&certificate).Error; err != nil {
+	if err := s.authService.ScopeToOwner(s.db, userID, models.PermissionCertificateManage).Where("id = ?", id).First(&certificate).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrCertificateNotFound
 		}
 		return nil, err
 	}
 
-	// Check admin permission for cross-user management
-	if certificate.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return nil, err
-		}
+	// Another user's certificate needs certificate:manage
+	if err := s.authService.RequireOwnerOrPermission(userID, certificate.UserID, models.PermissionCertificateWrite, models.PermissionCertificateManage); err != nil {
+		return nil, err
 	}
 
+	before := certificate
+
 	// Update certificate fields
 	certificate.Name = req.Name
 	certificate.NiceName = req.NiceName
@@ -155,36 +249,43 @@ func (s *CertificateService) UpdateCertificate(userID uint, id uint, req *Certif
 	if err := s.db.Save(&certificate).Error; err != nil {
 		return nil, err
 	}
+	s.syncCertificateFiles(&certificate)
+
+	recordUpdateAudit(s.db, audit, userID, models.ObjectTypeCertificate, certificate.ID,
+		fmt.Sprintf("Updated certificate: %s", certificate.GetPrimaryDomain()), &before, &certificate)
+	s.recordActivity(&certificate, "updated", ActivityLevelInfo, nil)
 
 	return &certificate, nil
 }
 
+// certificateReferrers are the models whose certificate_id refers to a
+// certificate
+var certificateReferrers = []interface{}{&models.ProxyHost{}, &models.DeadHost{}, &models.RedirectionHost{}, &models.Stream{}}
+
 // DeleteCertificate deletes a certificate
-func (s *CertificateService) DeleteCertificate(userID uint, id uint) error {
+func (s *CertificateService) DeleteCertificate(userID uint, id uint, audit AuditContext) error {
 	// Find certificate
 	var certificate models.Certificate
-	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&certificate).Error; err != nil {
+	if err := s.authService.ScopeToOwner(s.db, userID, models.PermissionCertificateManage).Where("id = ?", id).First(&certificate).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return ErrCertificateNotFound
 		}
 		return err
 	}
 
-	// Check admin permission for cross-user management
-	if certificate.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return err
-		}
-	}
-
-	// Check if certificate is in use
-	var count int64
-	if err := s.db.Model(&models.ProxyHost{}).Where("certificate_id = ?", id).Count(&count).Error; err != nil {
+	if err := s.authService.RequireOwnerOrPermission(userID, certificate.UserID, models.PermissionCertificateDelete, models.PermissionCertificateManage); err != nil {
 		return err
 	}
 
-	if count > 0 {
-		return errors.New("certificate is currently in use by proxy hosts")
+	// Check if certificate is in use
+	for _, model := range certificateReferrers {
+		var count int64
+		if err := s.db.Model(model).Where("certificate_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("certificate is currently in use by hosts or streams")
+		}
 	}
 
 	// Delete from database
@@ -192,6 +293,10 @@ func (s *CertificateService) DeleteCertificate(userID uint, id uint) error {
 		return err
 	}
 
+	RecordAudit(s.db, audit, NewAuditLog(userID, models.ActionDeleted, models.ObjectTypeCertificate, certificate.ID,
+		fmt.Sprintf("Deleted certificate: %s", certificate.GetPrimaryDomain())))
+	s.recordActivity(&certificate, "deleted", ActivityLevelInfo, nil)
+
 	return nil
 }
 
@@ -200,8 +305,8 @@ func (s *CertificateService) GetCertificate(userID uint, id uint) (*models.Certi
 	var certificate models.Certificate
 	query := s.db.Preload("User")
 
-	// Admin can see all certificates
-	if s.authService.RequireAdmin(userID) == nil {
+	// Viewers, operators and admins can see all certificates
+	if s.authService.HasPermission(userID, models.PermissionCertificateReadAll) {
 		query = query.Where("id = ?", id)
 	} else {
 		query = query.Where("id = ? AND user_id = ?", id, userID)
@@ -214,8 +319,8 @@ func (s *CertificateService) GetCertificate(userID uint, id uint) (*models.Certi
 		return nil, err
 	}
 
-	// Clear sensitive data for non-admin users
-	if s.authService.RequireAdmin(userID) != nil {
+	// Only users who can manage every certificate see private keys
+	if !s.authService.HasPermission(userID, models.PermissionCertificateManage) {
 		certificate.ClearSensitiveData()
 	}
 
@@ -229,8 +334,8 @@ func (s *CertificateService) ListCertificates(userID uint, offset, limit int) ([
 
 	query := s.db.Model(&models.Certificate{}).Preload("User")
 
-	// Admin can see all certificates
-	if s.authService.RequireAdmin(userID) != nil {
+	// Viewers, operators and admins can see all certificates
+	if !s.authService.HasPermission(userID, models.PermissionCertificateReadAll) {
 		query = query.Where("user_id = ?", userID)
 	}
 
@@ -244,8 +349,8 @@ func (s *CertificateService) ListCertificates(userID uint, offset, limit int) ([
 		return nil, 0, err
 	}
 
-	// Clear sensitive data for non-admin users
-	if s.authService.RequireAdmin(userID) != nil {
+	// Only users who can manage every certificate see private keys
+	if !s.authService.HasPermission(userID, models.PermissionCertificateManage) {
 		for i := range certificates {
 			certificates[i].ClearSensitiveData()
 		}
@@ -255,21 +360,18 @@ func (s *CertificateService) ListCertificates(userID uint, offset, limit int) ([
 }
 
 // RenewCertificate renews a Let's Encrypt certificate
-func (s *CertificateService) RenewCertificate(userID uint, id uint) (*models.Certificate, error) {
+func (s *CertificateService) RenewCertificate(userID uint, id uint, audit AuditContext) (*models.Certificate, error) {
 	// Find certificate
 	var certificate models.Certificate
-	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&certificate).Error; err != nil {
+	if err := s.authService.ScopeToOwner(s.db, userID, models.PermissionCertificateManage).Where("id = ?", id).First(&certificate).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrCertificateNotFound
 		}
 		return nil, err
 	}
 
-	// Check admin permission for cross-user management
-	if certificate.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return nil, err
-		}
+	if err := s.authService.RequireOwnerOrPermission(userID, certificate.UserID, models.PermissionCertificateWrite, models.PermissionCertificateManage); err != nil {
+		return nil, err
 	}
 
 	// Only Let's Encrypt certificates can be renewed
@@ -277,14 +379,26 @@ func (s *CertificateService) RenewCertificate(userID uint, id uint) (*models.Cer
 		return nil, errors.New("only Let's Encrypt certificates can be renewed")
 	}
 
+	// Only one renewal of a certificate runs at a time
+	if !s.lockRenewal(certificate.ID) {
+		return nil, ErrRenewalInProgress
+	}
+	defer s.unlockRenewal(certificate.ID)
+
+	// Reload, in case a renewal finished since the certificate was read
+	if err := s.db.First(&certificate, certificate.ID).Error; err != nil {
+		return nil, err
+	}
+
 	// Check if renewal is needed
 	if !certificate.CanRenew() {
 		return nil, errors.New("certificate does not need renewal yet")
 	}
 
+	before := certificate
+
 	// Renew certificate
-	certificate.Status = "renewing"
-	if err := s.db.Save(&certificate).Error; err != nil {
+	if err := s.claimRenewal(&certificate); err != nil {
 		return nil, err
 	}
 
@@ -292,6 +406,7 @@ func (s *CertificateService) RenewCertificate(userID uint, id uint) (*models.Cer
 	if err := s.renewLetsEncryptCertificate(&certificate); err != nil {
 		certificate.Status = "error"
 		s.db.Save(&certificate)
+		s.recordActivity(&certificate, "renewal failed", ActivityLevelError, err)
 		return nil, err
 	}
 
@@ -299,6 +414,11 @@ func (s *CertificateService) RenewCertificate(userID uint, id uint) (*models.Cer
 	if err := s.db.Save(&certificate).Error; err != nil {
 		return nil, err
 	}
+	s.syncCertificateFiles(&certificate)
+
+	recordUpdateAudit(s.db, audit, userID, models.ObjectTypeCertificate, certificate.ID,
+		fmt.Sprintf("Renewed certificate: %s", certificate.GetPrimaryDomain()), &before, &certificate)
+	s.recordActivity(&certificate, "renewed", ActivityLevelInfo, nil)
 
 	return &certificate, nil
 }
@@ -321,31 +441,16 @@ func (s *CertificateService) GetExpiringSoonCertificates(days int) ([]models.Cer
 func (s *CertificateService) AutoRenewCertificates() error {
 	logger.Info("Starting automatic certificate renewal process")
 
-	// Get certificates expiring within 30 days
-	certificates, err := s.GetExpiringSoonCertificates(30)
+	// Get certificates expiring within the renewal lead time
+	renewalLeadDays, _ := s.leadDays()
+	certificates, err := s.GetExpiringSoonCertificates(renewalLeadDays)
 	if err != nil {
 		return err
 	}
 
 	renewedCount := 0
 	for _, cert := range certificates {
-		if cert.CanRenew() {
-			logger.Info("Renewing certificate", logger.String("id", fmt.Sprintf("%d", cert.ID)), logger.String("domains", cert.GetPrimaryDomain()))
-
-			if err := s.renewLetsEncryptCertificate(&cert); err != nil {
-				logger.Error("Failed to renew certificate",
-					logger.String("id", fmt.Sprintf("%d", cert.ID)),
-					logger.Err(err))
-				continue
-			}
-
-			cert.Status = "active"
-			if err := s.db.Save(&cert).Error; err != nil {
-				logger.Error("Failed to update certificate status",
-					logger.String("id", fmt.Sprintf("%d", cert.ID)),
-					logger.Err(err))
-			}
-
+		if cert.CanRenew() && s.autoRenewCertificate(cert.ID) {
 			renewedCount++
 		}
 	}
@@ -357,6 +462,68 @@ func (s *CertificateService) AutoRenewCertificates() error {
 	return nil
 }
 
+// autoRenewCertificate renews one certificate for AutoRenewCertificates,
+// skipping it when another renewal of it is running. Reports whether the
+// certificate was renewed.
+func (s *CertificateService) autoRenewCertificate(id uint) bool {
+	if !s.lockRenewal(id) {
+		logger.Info("Skipping certificate already being renewed", logger.Uint("certificate_id", id))
+		return false
+	}
+	defer s.unlockRenewal(id)
+
+	// Reload, in case a renewal finished since the certificate was listed
+	var cert models.Certificate
+	if err := s.db.First(&cert, id).Error; err != nil {
+		logger.Error("Failed to load certificate for renewal", logger.Uint("certificate_id", id), logger.Err(err))
+		return false
+	}
+	if !cert.CanRenew() {
+		return false
+	}
+
+	status := cert.Status
+	if err := s.claimRenewal(&cert); err != nil {
+		if errors.Is(err, ErrRenewalInProgress) {
+			logger.Info("Skipping certificate already being renewed", logger.Uint("certificate_id", id))
+		} else {
+			logger.Error("Failed to mark certificate as renewing", logger.Uint("certificate_id", id), logger.Err(err))
+		}
+		return false
+	}
+
+	logger.Info("Renewing certificate", logger.String("id", fmt.Sprintf("%d", cert.ID)), logger.String("domains", cert.GetPrimaryDomain()))
+
+	if err := s.renewLetsEncryptCertificate(&cert); err != nil {
+		logger.Error("Failed to renew certificate",
+			logger.String("id", fmt.Sprintf("%d", cert.ID)),
+			logger.Err(err))
+		s.recordActivity(&cert, "automatic renewal failed", ActivityLevelError, err)
+		s.recordRenewalFailure(&cert, err)
+
+		// Release the renewing status so the next run can try again
+		if err := s.db.Model(&cert).UpdateColumn("status", status).Error; err != nil {
+			logger.Error("Failed to update certificate status",
+				logger.String("id", fmt.Sprintf("%d", cert.ID)),
+				logger.Err(err))
+		}
+		return false
+	}
+
+	cert.Status = "active"
+	delete(cert.Meta, certificateRenewalErrorKey)
+	delete(cert.Meta, certificateRenewalFailedAtKey)
+	if err := s.db.Save(&cert).Error; err != nil {
+		logger.Error("Failed to update certificate status",
+			logger.String("id", fmt.Sprintf("%d", cert.ID)),
+			logger.Err(err))
+	}
+	s.syncCertificateFiles(&cert)
+	s.recordActivity(&cert, "renewed automatically", ActivityLevelInfo, nil)
+
+	return true
+}
+
 // validateDomainNames validates domain names
 func (s *CertificateService) validateDomainNames(domains []string) error {
 	if len(domains) == 0 {
@@ -375,14 +542,21 @@ func (s *CertificateService) validateDomainNames(domains []string) error {
 
 // handleLetsEncryptCertificate handles Let's Encrypt certificate creation/renewal
 func (s *CertificateService) handleLetsEncryptCertificate(certificate *models.Certificate) error {
+	order, err := s.newACMEOrder(certificate)
+	if err != nil {
+		return err
+	}
+	recordACMEOrder(certificate, order)
+
 	// In a real implementation, this would:
-	// 1. Validate domain ownership
-	// 2. Create ACME challenge
-	// 3. Request certificate from Let's Encrypt
-	// 4. Store the certificate and key
+	// 1. Register or reuse the ACME account of order.Email at order.DirectoryURL
+	// 2. Validate domain ownership
+	// 3. Create ACME challenge
+	// 4. Request certificate from Let's Encrypt
+	// 5. Store the certificate and key
 
 	// For now, we'll generate a self-signed certificate for testing
-	cert, key, err := s.generateSelfSignedCertificate([]string(certificate.DomainNames))
+	cert, key, err := s.issueCertificate(order.Domains)
 	if err != nil {
 		return err
 	}
@@ -407,14 +581,48 @@ func (s *CertificateService) handleCustomCertificate(certificate *models.Certifi
 	}
 
 	// Parse and validate certificate
-	block, _ := pem.Decode([]byte(certificate.Certificate))
-	if block == nil {
+	certs, err := parseCertificatesPEM(certificate.Certificate)
+	if err != nil {
 		return ErrInvalidCertificate
 	}
+	cert := certs[0]
+
+	if err := verifyKeyPair(cert, certificate.Certificate, certificate.CertificateKey); err != nil {
+		return err
+	}
+
+	// Intermediates may follow the leaf in the certificate or be uploaded
+	// separately; skip any that appear in both
+	chain := certs
+	if certificate.IntermediateCertificate != "" {
+		intermediates, err := parseCertificatesPEM(certificate.IntermediateCertificate)
+		if err != nil {
+			return fmt.Errorf("%w: intermediate certificate: %v", ErrInvalidCertificate, err)
+		}
+		for _, intermediate := range intermediates {
+			duplicate := false
+			for _, existing := range chain {
+				duplicate = duplicate || existing.Equal(intermediate)
+			}
+			if !duplicate {
+				chain = append(chain, intermediate)
+			}
+		}
+	}
 
-	cert, err := x509.ParseCertificate(block.Bytes)
+	warnings, err := checkCertificateChain(chain)
 	if err != nil {
-		return ErrInvalidCertificate
+		return err
+	}
+	if len(warnings) > 0 {
+		for _, warning := range warnings {
+			logger.Warn("Custom certificate chain is incomplete",
+				logger.String("domain", certificate.GetPrimaryDomain()),
+				logger.String("warning", warning))
+		}
+		certificate.SetMetaValue("chain_warnings", warnings)
+	} else {
+		delete(certificate.Meta, "chain_warnings")
 	}
 
 	// Set expiry from certificate
@@ -427,9 +635,16 @@ func (s *CertificateService) handleCustomCertificate(certificate *models.Certifi
 
 // renewLetsEncryptCertificate renews a Let's Encrypt certificate
 func (s *CertificateService) renewLetsEncryptCertificate(certificate *models.Certificate) error {
-	// In a real implementation, this would interact with Let's Encrypt ACME API
-	// For now, we'll generate a new self-signed certificate
-	cert, key, err := s.generateSelfSignedCertificate([]string(certificate.DomainNames))
+	order, err := s.newACMEOrder(certificate)
+	if err != nil {
+		return err
+	}
+	recordACMEOrder(certificate, order)
+
+	// In a real implementation, this would interact with the Let's Encrypt
+	// ACME API at order.DirectoryURL. For now, we'll generate a new
+	// self-signed certificate
+	cert, key, err := s.issueCertificate(order.Domains)
 	if err != nil {
 		return err
 	}
@@ -498,23 +713,22 @@ func (s *CertificateService) generateSelfSignedCertificate(domains []string) (st
 }
 
 // UploadCertificate uploads certificate files to an existing certificate
-func (s *CertificateService) UploadCertificate(userID uint, id uint, certificate, certificateKey, intermediateCertificate string) (*models.Certificate, error) {
+func (s *CertificateService) UploadCertificate(userID uint, id uint, certificate, certificateKey, intermediateCertificate string, audit AuditContext) (*models.Certificate, error) {
 	// Find existing certificate
 	var cert models.Certificate
-	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&cert).Error; err != nil {
+	if err := s.authService.ScopeToOwner(s.db, userID, models.PermissionCertificateManage).Where("id = ?", id).First(&cert).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrCertificateNotFound
 		}
 		return nil, err
 	}
 
-	// Check admin permission for cross-user management
-	if cert.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return nil, err
-		}
+	if err := s.authService.RequireOwnerOrPermission(userID, cert.UserID, models.PermissionCertificateWrite, models.PermissionCertificateManage); err != nil {
+		return nil, err
 	}
 
+	before := cert
+
 	// Update certificate with uploaded data
 	cert.Certificate = certificate
 	cert.CertificateKey = certificateKey
@@ -529,6 +743,11 @@ func (s *CertificateService) UploadCertificate(userID uint, id uint, certificate
 	if err := s.db.Save(&cert).Error; err != nil {
 		return nil, err
 	}
+	s.syncCertificateFiles(&cert)
+
+	recordUpdateAudit(s.db, audit, userID, models.ObjectTypeCertificate, cert.ID,
+		fmt.Sprintf("Uploaded certificate: %s", cert.GetPrimaryDomain()), &before, &cert)
+	s.recordActivity(&cert, "uploaded", ActivityLevelInfo, nil)
 
 	return &cert, nil
 }
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCertificateKeyMismatch = errors.New("private key does not match the certificate")
+	ErrInvalidPrivateKey      = errors.New("invalid private key format")
+)
+
+// certificateExpiryWarning is how close to expiry a bundle gets a warning
+const certificateExpiryWarning = 30 * 24 * time.Hour
+
+// CertificateBundleReport describes a parsed certificate bundle. Valid is set
+// when there are no errors; warnings do not prevent an upload.
+type CertificateBundleReport struct {
+	Valid         bool      `json:"valid"`
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	SANs          []string  `json:"sans"`
+	SerialNumber  string    `json:"serial_number"`
+	NotBefore     time.Time `json:"not_before"`
+	NotAfter      time.Time `json:"not_after"`
+	DaysRemaining int       `json:"days_remaining"`
+	SelfSigned    bool      `json:"self_signed"`
+	KeyMatches    bool      `json:"key_matches"`
+	ChainValid    bool      `json:"chain_valid"`
+	ChainLength   int       `json:"chain_length"` // certificates in the verified chain, including the leaf
+	Errors        []string  `json:"errors"`
+	Warnings      []string  `json:"warnings"`
+}
+
+// ValidateCertificateBundle checks a PEM certificate, private key and optional
+// intermediate chain without storing anything: the key must match the
+// certificate, the chain must verify against the system roots, the
+// certificate must be within its validity period and, when domains are
+// given, cover each of them. Intermediates may also follow the leaf in
+// certificatePEM. An error is returned only when the certificate itself
+// cannot be parsed.
+func ValidateCertificateBundle(certificatePEM, keyPEM, intermediatePEM string, domains []string) (*CertificateBundleReport, error) {
+	certs, err := parseCertificatesPEM(certificatePEM)
+	if err != nil || len(certs) == 0 {
+		return nil, ErrInvalidCertificate
+	}
+	leaf := certs[0]
+
+	report := &CertificateBundleReport{
+		Subject:      leaf.Subject.String(),
+		Issuer:       leaf.Issuer.String(),
+		SANs:         certificateSANs(leaf),
+		SerialNumber: hex.EncodeToString(leaf.SerialNumber.Bytes()),
+		NotBefore:    leaf.NotBefore,
+		NotAfter:     leaf.NotAfter,
+		SelfSigned:   isSelfSigned(leaf),
+		Errors:       []string{},
+		Warnings:     []string{},
+	}
+
+	// Key
+	if keyPEM != "" {
+		if err := checkCertificateKey(leaf, keyPEM); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		} else {
+			report.KeyMatches = true
+		}
+	} else {
+		report.Warnings = append(report.Warnings, "no private key provided, key match not checked")
+	}
+
+	// Chain
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if intermediatePEM != "" {
+		chain, err := parseCertificatesPEM(intermediatePEM)
+		if err != nil {
+			report.Errors = append(report.Errors, "invalid intermediate certificate: "+err.Error())
+		}
+		for _, cert := range chain {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	switch {
+	case err == nil:
+		report.ChainValid = true
+		report.ChainLength = len(chains[0])
+	case report.SelfSigned:
+		report.Warnings = append(report.Warnings, "certificate is self-signed and will not be trusted by browsers")
+	default:
+		report.Errors = append(report.Errors, "chain verification failed: "+err.Error())
+	}
+
+	// Validity period
+	now := time.Now()
+	report.DaysRemaining = int(leaf.NotAfter.Sub(now).Hours() / 24)
+	switch {
+	case now.After(leaf.NotAfter):
+		report.Errors = append(report.Errors, fmt.Sprintf("certificate expired on %s", leaf.NotAfter.Format(time.RFC3339)))
+	case now.Before(leaf.NotBefore):
+		report.Errors = append(report.Errors, fmt.Sprintf("certificate is not valid until %s", leaf.NotBefore.Format(time.RFC3339)))
+	case leaf.NotAfter.Sub(now) < certificateExpiryWarning:
+		report.Warnings = append(report.Warnings, fmt.Sprintf("certificate expires in %d days", report.DaysRemaining))
+	}
+
+	// Subject alternative names
+	if len(report.SANs) == 0 {
+		report.Warnings = append(report.Warnings, "certificate has no subject alternative names")
+	}
+	for _, domain := range domains {
+		if err := leaf.VerifyHostname(domain); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("certificate does not cover %s", domain))
+		}
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return report, nil
+}
+
+// parseCertificatesPEM parses every CERTIFICATE block in data, in order
+func parseCertificatesPEM(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return certs, nil
+}
+
+// checkCertificateKey parses a PKCS#1, PKCS#8 or EC private key and checks
+// that its public half matches the certificate
+func checkCertificateKey(cert *x509.Certificate, keyPEM string) error {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return ErrInvalidPrivateKey
+	}
+
+	var key crypto.Signer
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return ErrInvalidPrivateKey
+		}
+		key = signer
+	} else if parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = parsed
+	} else if parsed, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		key = parsed
+	} else {
+		return ErrInvalidPrivateKey
+	}
+
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(cert.PublicKey) {
+		return ErrCertificateKeyMismatch
+	}
+	return nil
+}
+
+// verifyKeyPair loads the certificate and key the way nginx will, through
+// tls.X509KeyPair, and reports an unusable or mismatched key as
+// ErrInvalidCertificate
+func verifyKeyPair(leaf *x509.Certificate, certificatePEM, keyPEM string) error {
+	if _, err := tls.X509KeyPair([]byte(certificatePEM), []byte(keyPEM)); err != nil {
+		// Prefer the specific reason when the key itself can be examined
+		if keyErr := checkCertificateKey(leaf, keyPEM); keyErr != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidCertificate, keyErr)
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	return nil
+}
+
+// checkCertificateChain checks that every certificate after the leaf issued
+// the one before it, the order nginx serves them in. A chain that does not
+// end in a self-signed root and cannot be completed from the system roots is
+// reported as a warning, since private CAs are legitimate.
+func checkCertificateChain(chain []*x509.Certificate) ([]string, error) {
+	for i := 1; i < len(chain); i++ {
+		if err := chain[i-1].CheckSignatureFrom(chain[i]); err != nil {
+			return nil, fmt.Errorf("%w: %q is not issued by the next certificate in the chain, %q",
+				ErrInvalidCertificate, chain[i-1].Subject.String(), chain[i].Subject.String())
+		}
+	}
+
+	if isSelfSigned(chain[len(chain)-1]) {
+		return nil, nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return []string{fmt.Sprintf("certificate chain is incomplete, %q does not lead to a trusted root: %v",
+			chain[len(chain)-1].Issuer.String(), err)}, nil
+	}
+	return nil, nil
+}
+
+// certificateSANs lists the DNS names and IP addresses a certificate covers
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.IPAddresses))
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
+
+// isSelfSigned reports whether a certificate is signed by its own key
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !strings.EqualFold(cert.Subject.String(), cert.Issuer.String()) {
+		return false
+	}
+	// CheckSignatureFrom would require the certificate to be a CA
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// DomainCoverage reports whether a certificate covers one domain
+type DomainCoverage struct {
+	Domain    string `json:"domain"`
+	Covered   bool   `json:"covered"`
+	MatchedBy string `json:"matched_by,omitempty"` // the SAN covering the domain
+}
+
+// CertValidation reports whether a stored certificate is fit to serve a set
+// of domains. Warnings summarize every problem found; none of them is fatal.
+type CertValidation struct {
+	CertificateID   uint             `json:"certificate_id"`
+	Domains         []DomainCoverage `json:"domains"`
+	AllCovered      bool             `json:"all_covered"`
+	NotAfter        time.Time        `json:"not_after"`
+	DaysUntilExpiry int              `json:"days_until_expiry"`
+	Expired         bool             `json:"expired"`
+	SelfSigned      bool             `json:"self_signed"`
+	ChainValid      bool             `json:"chain_valid"`
+	Warnings        []string         `json:"warnings"`
+}
+
+// ValidateForDomains checks a stored certificate against domains: whether
+// each is covered by a SAN, honoring wildcards, how long until it expires and
+// whether its chain verifies against the system roots
+func (s *CertificateService) ValidateForDomains(userID uint, id uint, domains []string) (*CertValidation, error) {
+	certificate, err := s.GetCertificate(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := parseCertificatesPEM(certificate.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	leaf := certs[0]
+
+	validation := &CertValidation{
+		CertificateID:   certificate.ID,
+		Domains:         make([]DomainCoverage, 0, len(domains)),
+		AllCovered:      true,
+		NotAfter:        leaf.NotAfter,
+		DaysUntilExpiry: int(time.Until(leaf.NotAfter).Hours() / 24),
+		Expired:         time.Now().After(leaf.NotAfter),
+		SelfSigned:      isSelfSigned(leaf),
+		Warnings:        []string{},
+	}
+
+	for _, domain := range domains {
+		matchedBy, covered := certificateCovers(leaf, domain)
+		validation.Domains = append(validation.Domains, DomainCoverage{Domain: domain, Covered: covered, MatchedBy: matchedBy})
+		if !covered {
+			validation.AllCovered = false
+			validation.Warnings = append(validation.Warnings, fmt.Sprintf("certificate does not cover %s", domain))
+		}
+	}
+
+	if certificate.IsStaging() {
+		validation.Warnings = append(validation.Warnings, "certificate was issued by the Let's Encrypt staging environment, which browsers do not trust")
+	}
+
+	switch {
+	case validation.Expired:
+		validation.Warnings = append(validation.Warnings, fmt.Sprintf("certificate expired on %s", leaf.NotAfter.Format(time.RFC3339)))
+	case time.Until(leaf.NotAfter) < certificateExpiryWarning:
+		validation.Warnings = append(validation.Warnings, fmt.Sprintf("certificate expires in %d days", validation.DaysUntilExpiry))
+	}
+
+	// Chain
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if certificate.IntermediateCertificate != "" {
+		if chain, err := parseCertificatesPEM(certificate.IntermediateCertificate); err == nil {
+			for _, cert := range chain {
+				intermediates.AddCert(cert)
+			}
+		}
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	switch {
+	case err == nil:
+		validation.ChainValid = true
+	case validation.SelfSigned:
+		validation.Warnings = append(validation.Warnings, "certificate is self-signed and will not be trusted by browsers")
+	default:
+		validation.Warnings = append(validation.Warnings, "chain verification failed: "+err.Error())
+	}
+
+	return validation, nil
+}
+
+// certificateCovers reports whether the certificate's SANs cover domain, and
+// which SAN does. A wildcard SAN covers exactly one label, so *.example.com
+// covers www.example.com but neither example.com nor a.b.example.com; a
+// wildcard domain is only covered by the same wildcard.
+func certificateCovers(cert *x509.Certificate, domain string) (string, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+
+	if ip := net.ParseIP(domain); ip != nil {
+		for _, certIP := range cert.IPAddresses {
+			if certIP.Equal(ip) {
+				return certIP.String(), true
+			}
+		}
+		return "", false
+	}
+
+	for _, name := range cert.DNSNames {
+		san := strings.ToLower(strings.TrimSuffix(name, "."))
+		if san == domain {
+			return name, true
+		}
+		if strings.HasPrefix(san, "*.") && !strings.HasPrefix(domain, "*.") {
+			if dot := strings.Index(domain, "."); dot > 0 && domain[dot:] == san[1:] {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// testCertificate is a generated certificate with its key
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func (c testCertificate) certPEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}))
+}
+
+func (c testCertificate) keyPEM(t *testing.T) string {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+// newTestCertificate creates a certificate for name signed by parent, or
+// self-signed when parent is nil. options adjust the template before signing.
+func newTestCertificate(t *testing.T, name string, isCA bool, parent *testCertificate, options ...func(*x509.Certificate)) testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(90 * 24 * time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		template.DNSNames = []string{name}
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+
+	for _, option := range options {
+		option(template)
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCertificate{cert: cert, key: key}
+}
+
+// newTestChain creates a root, an intermediate it signs and a leaf for
+// example.com signed by the intermediate
+func newTestChain(t *testing.T) (root, intermediate, leaf testCertificate) {
+	t.Helper()
+	root = newTestCertificate(t, "Test Root CA", true, nil)
+	intermediate = newTestCertificate(t, "Test Intermediate CA", true, &root)
+	leaf = newTestCertificate(t, "example.com", false, &intermediate)
+	return root, intermediate, leaf
+}
+
+func TestVerifyKeyPair(t *testing.T) {
+	_, intermediate, leaf := newTestChain(t)
+
+	if err := verifyKeyPair(leaf.cert, leaf.certPEM(), leaf.keyPEM(t)); err != nil {
+		t.Errorf("matching key: %v", err)
+	}
+
+	err := verifyKeyPair(leaf.cert, leaf.certPEM(), intermediate.keyPEM(t))
+	if !errors.Is(err, ErrInvalidCertificate) || !errors.Is(err, ErrCertificateKeyMismatch) {
+		t.Errorf("mismatched key: got %v, want %v and %v", err, ErrInvalidCertificate, ErrCertificateKeyMismatch)
+	}
+}
+
+func TestCheckCertificateChain(t *testing.T) {
+	root, intermediate, leaf := newTestChain(t)
+
+	for _, tc := range []struct {
+		name        string
+		chain       []*x509.Certificate
+		wantErr     bool
+		wantWarning bool
+	}{
+		{name: "leaf only", chain: []*x509.Certificate{leaf.cert}, wantWarning: true},
+		{name: "complete", chain: []*x509.Certificate{leaf.cert, intermediate.cert, root.cert}},
+		{name: "missing root", chain: []*x509.Certificate{leaf.cert, intermediate.cert}, wantWarning: true},
+		{name: "misordered", chain: []*x509.Certificate{leaf.cert, root.cert, intermediate.cert}, wantErr: true},
+		{name: "skipped intermediate", chain: []*x509.Certificate{leaf.cert, root.cert}, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			warnings, err := checkCertificateChain(tc.chain)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidCertificate) {
+					t.Fatalf("got error %v, want %v", err, ErrInvalidCertificate)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(warnings) > 0; got != tc.wantWarning {
+				t.Errorf("got warnings %q, want warning=%v", warnings, tc.wantWarning)
+			}
+		})
+	}
+}
+
+func TestHandleCustomCertificate(t *testing.T) {
+	root, intermediate, leaf := newTestChain(t)
+	s := &CertificateService{}
+
+	t.Run("mismatched key", func(t *testing.T) {
+		certificate := &models.Certificate{
+			Certificate:    leaf.certPEM(),
+			CertificateKey: root.keyPEM(t),
+		}
+		if err := s.handleCustomCertificate(certificate); !errors.Is(err, ErrCertificateKeyMismatch) {
+			t.Errorf("got %v, want %v", err, ErrCertificateKeyMismatch)
+		}
+	})
+
+	t.Run("misordered intermediates", func(t *testing.T) {
+		certificate := &models.Certificate{
+			Certificate:             leaf.certPEM(),
+			CertificateKey:          leaf.keyPEM(t),
+			IntermediateCertificate: root.certPEM() + intermediate.certPEM(),
+		}
+		err := s.handleCustomCertificate(certificate)
+		if !errors.Is(err, ErrInvalidCertificate) || !strings.Contains(err.Error(), "not issued by the next certificate") {
+			t.Errorf("got %v, want a chain order error", err)
+		}
+	})
+
+	t.Run("intermediate bundled with the leaf", func(t *testing.T) {
+		certificate := &models.Certificate{
+			Certificate:             leaf.certPEM() + intermediate.certPEM(),
+			CertificateKey:          leaf.keyPEM(t),
+			IntermediateCertificate: intermediate.certPEM() + root.certPEM(),
+		}
+		if err := s.handleCustomCertificate(certificate); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := certificate.Meta["chain_warnings"]; ok {
+			t.Errorf("complete chain has warnings %v", certificate.Meta["chain_warnings"])
+		}
+		if certificate.Status != "active" || certificate.ExpiresOn == nil || !certificate.ExpiresOn.Equal(leaf.cert.NotAfter) {
+			t.Errorf("got status %q and expiry %v, want active until %v", certificate.Status, certificate.ExpiresOn, leaf.cert.NotAfter)
+		}
+	})
+
+	t.Run("incomplete chain", func(t *testing.T) {
+		certificate := &models.Certificate{
+			Certificate:             leaf.certPEM(),
+			CertificateKey:          leaf.keyPEM(t),
+			IntermediateCertificate: intermediate.certPEM(),
+		}
+		if err := s.handleCustomCertificate(certificate); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := certificate.Meta["chain_warnings"]; !ok {
+			t.Error("incomplete chain has no chain_warnings")
+		}
+	})
+}
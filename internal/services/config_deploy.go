@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+// DeployError reports a deployment step that failed after the configuration
+// file was written. Output holds what nginx printed for the step. The previous
+// file has been restored and nginx reloaded with it unless RollbackErr is set.
+type DeployError struct {
+	Step        string // write, test or reload
+	Output      string
+	RollbackErr error
+}
+
+func (e *DeployError) Error() string {
+	message := fmt.Sprintf("nginx %s failed: %s", e.Step, strings.TrimSpace(e.Output))
+	if e.Step == "write" {
+		message = "failed to write config file: " + e.Output
+	}
+	if e.RollbackErr != nil {
+		message += "; rollback failed: " + e.RollbackErr.Error()
+	}
+	return message
+}
+
+// configFileSnapshot holds a configuration file as it was before a deployment
+type configFileSnapshot struct {
+	path    string
+	content []byte
+	mode    os.FileMode
+	exists  bool
+}
+
+// snapshotConfigFile reads the file at path, which may not exist yet
+func snapshotConfigFile(path string) (*configFileSnapshot, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file path not specified")
+	}
+
+	snapshot := &configFileSnapshot{path: path, mode: 0644}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot.content, err = os.ReadFile(path); err != nil {
+		return nil, err
+	}
+	snapshot.mode = info.Mode().Perm()
+	snapshot.exists = true
+	return snapshot, nil
+}
+
+// restore puts the file back as it was, removing it if it did not exist
+func (snapshot *configFileSnapshot) restore() error {
+	if !snapshot.exists {
+		if err := os.Remove(snapshot.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return writeFileAtomic(snapshot.path, snapshot.content, snapshot.mode)
+}
+
+// writeFileAtomic writes to a temporary file first and renames it over path,
+// so nginx never reads a partial file
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// failDeploy undoes a failed deployment: the previous file is restored and
+// nginx reloaded with it, so the live configuration is never left broken. The
+// deploy error is returned with any rollback failure attached.
+func (s *ConfigService) failDeploy(config *models.NginxConfig, snapshot *configFileSnapshot, deployErr *DeployError) error {
+	if err := snapshot.restore(); err != nil {
+		deployErr.RollbackErr = fmt.Errorf("failed to restore %s: %w", snapshot.path, err)
+	} else if err := s.nginxRunner.Reload(); err != nil {
+		deployErr.RollbackErr = err
+	}
+
+	if deployErr.RollbackErr != nil {
+		logger.Error("Failed to roll back configuration deployment",
+			logger.Uint("config_id", config.ID),
+			logger.String("file_path", config.FilePath),
+			logger.Err(deployErr.RollbackErr))
+	} else {
+		logger.Warn("Configuration deployment rolled back",
+			logger.Uint("config_id", config.ID),
+			logger.String("step", deployErr.Step))
+	}
+
+	s.recordActivity(config, "deployment failed", ActivityLevelError, deployErr)
+	return deployErr
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestDeployConfigRollsBackFailedReload simulates a reload that fails after
+// the configuration test passed and checks that the previous file is back
+func TestDeployConfigRollsBackFailedReload(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+	dir := t.TempDir()
+
+	filePath := filepath.Join(dir, "conf.d", "site.conf")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	previous := "server { listen 80; }\n"
+	if err := os.WriteFile(filePath, []byte(previous), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &models.NginxConfig{
+		Name:     "site",
+		Type:     models.ConfigTypeServer,
+		Content:  "server { listen 8080; }\n",
+		FilePath: filePath,
+		IsValid:  true,
+		UserID:   admin.ID,
+	}
+	if err := db.Create(config).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &MockNginxRunner{
+		ReloadErrQueue: []error{&NginxCommandError{Output: "bind() to 0.0.0.0:8080 failed (98: Address already in use)", Err: errors.New("exit status 1")}},
+	}
+	s := NewConfigService(dir, filepath.Join(dir, "backups"), filepath.Join(dir, "templates"), NewAuthService("test"))
+	s.SetNginxRunner(runner)
+
+	err := s.DeployConfig(admin.ID, config.ID, AuditContext{})
+
+	var deployErr *DeployError
+	if !errors.As(err, &deployErr) {
+		t.Fatalf("got error %v, want a *DeployError", err)
+	}
+	if deployErr.Step != "reload" || !strings.Contains(deployErr.Output, "Address already in use") {
+		t.Errorf("got step %q output %q, want the reload output", deployErr.Step, deployErr.Output)
+	}
+	if deployErr.RollbackErr != nil {
+		t.Errorf("rollback failed: %v", deployErr.RollbackErr)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != previous {
+		t.Errorf("config file holds %q, want the previous %q", content, previous)
+	}
+	if runner.ReloadCount != 2 {
+		t.Errorf("nginx reloaded %d times, want 2 (the failed deploy and the rollback)", runner.ReloadCount)
+	}
+}
+
+// TestDeployConfigRemovesNewFileOnFailedTest checks that a file that did not
+// exist before a failed deployment is removed again
+func TestDeployConfigRemovesNewFileOnFailedTest(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+	dir := t.TempDir()
+
+	filePath := filepath.Join(dir, "site.conf")
+	config := &models.NginxConfig{
+		Name:     "site",
+		Type:     models.ConfigTypeServer,
+		Content:  "server { listen 80 }\n",
+		FilePath: filePath,
+		IsValid:  true,
+		UserID:   admin.ID,
+	}
+	if err := db.Create(config).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewConfigService(dir, filepath.Join(dir, "backups"), filepath.Join(dir, "templates"), NewAuthService("test"))
+	s.SetNginxRunner(&MockNginxRunner{TestErr: errors.New("unexpected \"}\"")})
+
+	var deployErr *DeployError
+	if err := s.DeployConfig(admin.ID, config.ID, AuditContext{}); !errors.As(err, &deployErr) || deployErr.Step != "test" {
+		t.Fatalf("got error %v, want a test step *DeployError", err)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("config file still exists after the failed deployment: %v", err)
+	}
+}
+
+// TestManualModeBacksUpEveryDeploy deploys the same content twice in manual
+// backup mode: each deployment still takes its safety backup
+func TestManualModeBacksUpEveryDeploy(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+	dir := t.TempDir()
+
+	config := &models.NginxConfig{
+		Name:     "site",
+		Type:     models.ConfigTypeServer,
+		Content:  "server { listen 80; }\n",
+		FilePath: filepath.Join(dir, "site.conf"),
+		IsValid:  true,
+		UserID:   admin.ID,
+	}
+	if err := db.Create(config).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewConfigService(dir, filepath.Join(dir, "backups"), filepath.Join(dir, "templates"), NewAuthService("test"))
+	s.SetNginxRunner(&MockNginxRunner{})
+	if err := s.SetBackupMode(BackupModeManual); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.DeployConfig(admin.ID, config.ID, AuditContext{}); err != nil {
+			t.Fatalf("deploy %d: %v", i+1, err)
+		}
+	}
+
+	var backups int64
+	if err := db.Model(&models.ConfigBackup{}).Where("config_id = ?", config.ID).Count(&backups).Error; err != nil {
+		t.Fatal(err)
+	}
+	if backups != 2 {
+		t.Errorf("got %d safety backups, want 2", backups)
+	}
+}
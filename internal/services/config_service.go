@@ -3,10 +3,8 @@ package services
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/nguyendkn/nginx-manager/internal/database"
@@ -16,13 +14,25 @@ import (
 	"gorm.io/gorm"
 )
 
+// Config backup modes control which operations take an automatic backup
+const (
+	BackupModeAlways   = "always"    // before every update, deletion and deployment
+	BackupModeOnDeploy = "on-deploy" // before every deployment
+	BackupModeManual   = "manual"    // only the deployment safety backup
+)
+
+var backupModes = []string{BackupModeAlways, BackupModeOnDeploy, BackupModeManual}
+
 // ConfigService handles nginx configuration management
 type ConfigService struct {
 	db              *gorm.DB
 	nginxConfigPath string
 	backupPath      string
 	templatePath    string
+	backupMode      string
 	authService     *AuthService
+	activityService *ActivityService
+	nginxRunner     NginxRunner
 }
 
 // NewConfigService creates a new configuration service instance
@@ -32,10 +42,32 @@ func NewConfigService(nginxConfigPath, backupPath, templatePath string, authServ
 		nginxConfigPath: nginxConfigPath,
 		backupPath:      backupPath,
 		templatePath:    templatePath,
+		backupMode:      BackupModeAlways,
 		authService:     authService,
+		nginxRunner:     NewExecNginxRunner(DefaultNginxBinary),
 	}
 }
 
+// SetBackupMode sets when automatic backups are taken. A safety backup is
+// taken before every deployment whatever the mode.
+func (s *ConfigService) SetBackupMode(mode string) error {
+	if !containsString(backupModes, mode) {
+		return fmt.Errorf("invalid config backup mode %q, expected one of: %s", mode, strings.Join(backupModes, ", "))
+	}
+	s.backupMode = mode
+	return nil
+}
+
+// SetNginxRunner sets the runner used to test configurations and reload nginx
+func (s *ConfigService) SetNginxRunner(runner NginxRunner) {
+	s.nginxRunner = runner
+}
+
+// SetActivityService records configuration lifecycle events in the activity feed
+func (s *ConfigService) SetActivityService(activityService *ActivityService) {
+	s.activityService = activityService
+}
+
 // ConfigRequest represents configuration create/update request
 type ConfigRequest struct {
 	Name         string                 `json:"name" binding:"required"`
@@ -54,17 +86,43 @@ type ConfigListResponse struct {
 	Total   int64                `json:"total"`
 	Page    int                  `json:"page"`
 	Limit   int                  `json:"limit"`
+	Search  string               `json:"search,omitempty"`
+	Sort    ListSort             `json:"sort"`
 }
 
 // ValidationResult represents configuration validation result
 type ValidationResult struct {
-	IsValid bool     `json:"is_valid"`
-	Errors  []string `json:"errors"`
-	Output  string   `json:"output"`
+	IsValid bool              `json:"is_valid"`
+	Errors  []string          `json:"errors"`
+	Output  string            `json:"output"`
+	Issues  []ValidationIssue `json:"issues,omitempty"` // explained by ExplainValidation
+}
+
+// SearchMatch represents a single matching line with surrounding context
+type SearchMatch struct {
+	LineNumber int      `json:"line_number"`
+	Line       string   `json:"line"`
+	Before     []string `json:"before"`
+	After      []string `json:"after"`
+}
+
+// SearchResult represents a config, template or proxy host whose content matched
+type SearchResult struct {
+	Type    string        `json:"type"` // config, template, proxy_host
+	ID      uint          `json:"id"`
+	Name    string        `json:"name"`
+	Matches []SearchMatch `json:"matches"`
+}
+
+// SearchResponse represents content search results
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
 }
 
 // CreateConfig creates a new nginx configuration
-func (s *ConfigService) CreateConfig(userID uint, req *ConfigRequest) (*models.NginxConfig, error) {
+func (s *ConfigService) CreateConfig(userID uint, req *ConfigRequest, audit AuditContext) (*models.NginxConfig, error) {
 	// Validate config type
 	if !req.Type.IsValid() {
 		return nil, fmt.Errorf("invalid configuration type")
@@ -77,7 +135,7 @@ func (s *ConfigService) CreateConfig(userID uint, req *ConfigRequest) (*models.N
 		return nil, err
 	}
 	if err == nil {
-		return nil, fmt.Errorf("configuration with this name already exists")
+		return nil, errors.ErrConfigDuplicate
 	}
 
 	// Render content from template if template is used
@@ -91,7 +149,7 @@ func (s *ConfigService) CreateConfig(userID uint, req *ConfigRequest) (*models.N
 	}
 
 	// Validate configuration content
-	validation, err := s.validateConfig(content)
+	validation, err := s.validateConfig(req.Type, content)
 	if err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
@@ -112,6 +170,12 @@ func (s *ConfigService) CreateConfig(userID uint, req *ConfigRequest) (*models.N
 		TemplateID:     req.TemplateID,
 		TemplateVars:   models.JSON(req.TemplateVars),
 	}
+	config.ContentUpdatedAt = config.ValidationTime
+
+	// A soft-deleted configuration does not keep its name
+	if err := releaseDeletedName(s.db, &models.NginxConfig{}, userID, req.Name); err != nil {
+		return nil, err
+	}
 
 	// Save to database
 	if err := s.db.Create(config).Error; err != nil {
@@ -124,14 +188,15 @@ func (s *ConfigService) CreateConfig(userID uint, req *ConfigRequest) (*models.N
 	}
 
 	// Log audit event
-	s.logAuditEvent(userID, models.ObjectTypeNginxConfig, config.ID, models.ActionCreated,
+	s.logAuditEvent(audit, userID, models.ObjectTypeNginxConfig, config.ID, models.ActionCreated,
 		fmt.Sprintf("Created configuration: %s", config.Name))
+	s.recordActivity(config, "created", ActivityLevelInfo, nil)
 
 	return config, nil
 }
 
 // UpdateConfig updates an existing configuration
-func (s *ConfigService) UpdateConfig(userID uint, id uint, req *ConfigRequest) (*models.NginxConfig, error) {
+func (s *ConfigService) UpdateConfig(userID uint, id uint, req *ConfigRequest, audit AuditContext) (*models.NginxConfig, error) {
 	// Find existing configuration
 	var config models.NginxConfig
 	if err := s.db.Where("id = ?", id).First(&config).Error; err != nil {
@@ -142,20 +207,34 @@ func (s *ConfigService) UpdateConfig(userID uint, id uint, req *ConfigRequest) (
 	}
 
 	// Check permissions
-	if config.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return nil, errors.ErrPermissionDenied
-		}
+	if err := s.authService.RequireOwnerOrPermission(userID, config.UserID, models.PermissionNginxConfigWrite, models.PermissionNginxConfigManage); err != nil {
+		return nil, errors.ErrPermissionDenied
 	}
 
 	// Check if config is read-only
 	if config.IsReadOnly {
 		return nil, fmt.Errorf("cannot modify read-only configuration")
 	}
+	before := config
+
+	// Check for duplicate config name for the owner
+	if req.Name != config.Name {
+		var count int64
+		if err := s.db.Model(&models.NginxConfig{}).
+			Where("name = ? AND user_id = ? AND id != ?", req.Name, config.UserID, config.ID).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.ErrConfigDuplicate
+		}
+	}
 
 	// Create backup before modification
-	if err := s.createBackup(config.ID, "Before update", userID); err != nil {
-		logger.Warn("Failed to create backup", logger.Err(err))
+	if s.backupMode == BackupModeAlways {
+		if err := s.createBackup(config.ID, "Before update", userID); err != nil {
+			logger.Warn("Failed to create backup", logger.Err(err))
+		}
 	}
 
 	// Render content from template if template is used
@@ -169,11 +248,22 @@ func (s *ConfigService) UpdateConfig(userID uint, id uint, req *ConfigRequest) (
 	}
 
 	// Validate new configuration
-	validation, err := s.validateConfig(content)
+	validation, err := s.validateConfig(req.Type, content)
 	if err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
+	// Only content and file path changes leave the deployed file out of date
+	if content != config.Content || req.FilePath != config.FilePath {
+		config.ContentUpdatedAt = time.Now()
+	}
+
+	if req.Name != config.Name {
+		if err := releaseDeletedName(s.db, &models.NginxConfig{}, config.UserID, req.Name); err != nil {
+			return nil, err
+		}
+	}
+
 	// Update configuration
 	config.Name = req.Name
 	config.Description = req.Description
@@ -199,15 +289,17 @@ func (s *ConfigService) UpdateConfig(userID uint, id uint, req *ConfigRequest) (
 	if err := s.db.Save(&config).Error; err != nil {
 		return nil, err
 	}
+	config.HasPendingChanges = config.PendingChanges()
 
 	// Create new version
 	if err := s.createVersion(config.ID, content, "Configuration updated", userID); err != nil {
 		logger.Warn("Failed to create version", logger.Err(err))
 	}
 
-	// Log audit event
-	s.logAuditEvent(userID, models.ObjectTypeNginxConfig, config.ID, models.ActionUpdated,
-		fmt.Sprintf("Updated configuration: %s", config.Name))
+	// Log audit event with the changed fields
+	recordUpdateAudit(s.db, audit, userID, models.ObjectTypeNginxConfig, config.ID,
+		fmt.Sprintf("Updated configuration: %s", config.Name), &before, &config)
+	s.recordActivity(&config, "updated", ActivityLevelInfo, nil)
 
 	return &config, nil
 }
@@ -225,24 +317,22 @@ func (s *ConfigService) GetConfig(userID uint, id uint) (*models.NginxConfig, er
 	}
 
 	// Check permissions
-	if config.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return nil, errors.ErrPermissionDenied
-		}
+	if err := s.authService.RequireOwnerOrPermission(userID, config.UserID, models.PermissionNginxConfigRead, models.PermissionNginxConfigReadAll); err != nil {
+		return nil, errors.ErrPermissionDenied
 	}
 
 	return &config, nil
 }
 
-// ListConfigs retrieves configurations with pagination
-func (s *ConfigService) ListConfigs(userID uint, page, limit int, configType string) (*ConfigListResponse, error) {
+// ListConfigs retrieves configurations with pagination, optionally matching
+// search against name and description
+func (s *ConfigService) ListConfigs(userID uint, page, limit int, configType, search string, listSort ListSort) (*ConfigListResponse, error) {
 	offset := (page - 1) * limit
 
 	query := s.db.Model(&models.NginxConfig{}).Preload("User")
 
-	// Check if user is admin
-	isAdmin := s.authService.IsAdmin(userID)
-	if !isAdmin {
+	// Users without nginx_config:read_all only see their own configurations
+	if !s.authService.HasPermission(userID, models.PermissionNginxConfigReadAll) {
 		query = query.Where("user_id = ?", userID)
 	}
 
@@ -250,6 +340,7 @@ func (s *ConfigService) ListConfigs(userID uint, page, limit int, configType str
 	if configType != "" {
 		query = query.Where("type = ?", configType)
 	}
+	query = searchNameDescription(query, search)
 
 	var configs []models.NginxConfig
 	var total int64
@@ -260,7 +351,7 @@ func (s *ConfigService) ListConfigs(userID uint, page, limit int, configType str
 	}
 
 	// Get configs with pagination
-	if err := query.Offset(offset).Limit(limit).Find(&configs).Error; err != nil {
+	if err := listSort.apply(query).Offset(offset).Limit(limit).Find(&configs).Error; err != nil {
 		return nil, err
 	}
 
@@ -269,11 +360,104 @@ func (s *ConfigService) ListConfigs(userID uint, page, limit int, configType str
 		Total:   total,
 		Page:    page,
 		Limit:   limit,
+		Search:  search,
+		Sort:    listSort,
+	}, nil
+}
+
+// SearchContent searches config content, template content and proxy host
+// advanced config for a case-insensitive substring, limited to what the user can access
+func (s *ConfigService) SearchContent(userID uint, query string, contextLines, limit int) (*SearchResponse, error) {
+	pattern := "%" + strings.ToLower(query) + "%"
+
+	results := make([]SearchResult, 0)
+
+	// Configurations
+	configQuery := s.db.Model(&models.NginxConfig{}).Where("LOWER(content) LIKE ?", pattern)
+	if !s.authService.HasPermission(userID, models.PermissionNginxConfigReadAll) {
+		configQuery = configQuery.Where("user_id = ?", userID)
+	}
+	var configs []models.NginxConfig
+	if err := configQuery.Limit(limit).Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		if matches := findContentMatches(config.Content, query, contextLines); len(matches) > 0 {
+			results = append(results, SearchResult{Type: "config", ID: config.ID, Name: config.Name, Matches: matches})
+		}
+	}
+
+	// Templates
+	templateQuery := s.db.Model(&models.ConfigTemplate{}).Where("LOWER(content) LIKE ?", pattern)
+	if !s.authService.HasPermission(userID, models.PermissionTemplateReadAll) {
+		templateQuery = templateQuery.Where("user_id = ? OR is_public = true OR is_built_in = true", userID)
+	}
+	var templates []models.ConfigTemplate
+	if err := templateQuery.Limit(limit).Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	for _, tmpl := range templates {
+		if matches := findContentMatches(tmpl.Content, query, contextLines); len(matches) > 0 {
+			results = append(results, SearchResult{Type: "template", ID: tmpl.ID, Name: tmpl.Name, Matches: matches})
+		}
+	}
+
+	// Proxy host advanced configuration
+	hostQuery := s.db.Model(&models.ProxyHost{}).Where("LOWER(advanced_config) LIKE ?", pattern)
+	if !s.authService.HasPermission(userID, models.PermissionProxyHostReadAll) {
+		hostQuery = hostQuery.Where("user_id = ?", userID)
+	}
+	var proxyHosts []models.ProxyHost
+	if err := hostQuery.Limit(limit).Find(&proxyHosts).Error; err != nil {
+		return nil, err
+	}
+	for _, host := range proxyHosts {
+		if matches := findContentMatches(host.AdvancedConfig, query, contextLines); len(matches) > 0 {
+			results = append(results, SearchResult{Type: "proxy_host", ID: host.ID, Name: host.GetPrimaryDomain(), Matches: matches})
+		}
+	}
+
+	return &SearchResponse{
+		Query:   query,
+		Results: results,
+		Total:   len(results),
 	}, nil
 }
 
+// findContentMatches returns every line containing query (case-insensitive)
+// along with up to contextLines lines before and after it
+func findContentMatches(content, query string, contextLines int) []SearchMatch {
+	lines := strings.Split(content, "\n")
+	needle := strings.ToLower(query)
+
+	var matches []SearchMatch
+	for i, line := range lines {
+		if !strings.Contains(strings.ToLower(line), needle) {
+			continue
+		}
+
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i + contextLines + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		matches = append(matches, SearchMatch{
+			LineNumber: i + 1,
+			Line:       line,
+			Before:     lines[start:i],
+			After:      lines[i+1 : end],
+		})
+	}
+
+	return matches
+}
+
 // DeleteConfig deletes a configuration
-func (s *ConfigService) DeleteConfig(userID uint, id uint) error {
+func (s *ConfigService) DeleteConfig(userID uint, id uint, audit AuditContext) error {
 	// Find configuration
 	var config models.NginxConfig
 	if err := s.db.Where("id = ?", id).First(&config).Error; err != nil {
@@ -284,10 +468,8 @@ func (s *ConfigService) DeleteConfig(userID uint, id uint) error {
 	}
 
 	// Check permissions
-	if config.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return errors.ErrPermissionDenied
-		}
+	if err := s.authService.RequireOwnerOrPermission(userID, config.UserID, models.PermissionNginxConfigDelete, models.PermissionNginxConfigManage); err != nil {
+		return errors.ErrPermissionDenied
 	}
 
 	// Check if config is read-only
@@ -301,8 +483,10 @@ func (s *ConfigService) DeleteConfig(userID uint, id uint) error {
 	}
 
 	// Create final backup
-	if err := s.createBackup(config.ID, "Before deletion", userID); err != nil {
-		logger.Warn("Failed to create backup before deletion", logger.Err(err))
+	if s.backupMode == BackupModeAlways {
+		if err := s.createBackup(config.ID, "Before deletion", userID); err != nil {
+			logger.Warn("Failed to create backup before deletion", logger.Err(err))
+		}
 	}
 
 	// Delete from database (soft delete due to BaseModel)
@@ -311,14 +495,17 @@ func (s *ConfigService) DeleteConfig(userID uint, id uint) error {
 	}
 
 	// Log audit event
-	s.logAuditEvent(userID, models.ObjectTypeNginxConfig, config.ID, models.ActionDeleted,
+	s.logAuditEvent(audit, userID, models.ObjectTypeNginxConfig, config.ID, models.ActionDeleted,
 		fmt.Sprintf("Deleted configuration: %s", config.Name))
+	s.recordActivity(&config, "deleted", ActivityLevelInfo, nil)
 
 	return nil
 }
 
-// DeployConfig deploys a configuration to nginx
-func (s *ConfigService) DeployConfig(userID uint, id uint) error {
+// DeployConfig deploys a configuration to nginx. If the test or reload fails
+// the previous file is restored and nginx reloaded, and a *DeployError with
+// the nginx output is returned.
+func (s *ConfigService) DeployConfig(userID uint, id uint, audit AuditContext) error {
 	// Find configuration
 	var config models.NginxConfig
 	if err := s.db.Where("id = ?", id).First(&config).Error; err != nil {
@@ -329,10 +516,8 @@ func (s *ConfigService) DeployConfig(userID uint, id uint) error {
 	}
 
 	// Check permissions
-	if config.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return errors.ErrPermissionDenied
-		}
+	if err := s.authService.RequireOwnerOrPermission(userID, config.UserID, models.PermissionNginxConfigWrite, models.PermissionNginxConfigManage); err != nil {
+		return errors.ErrPermissionDenied
 	}
 
 	// Validate configuration
@@ -340,69 +525,89 @@ func (s *ConfigService) DeployConfig(userID uint, id uint) error {
 		return errors.ErrConfigValidationFailed
 	}
 
-	// Create backup before deployment
-	if err := s.createBackup(config.ID, "Before deployment", userID); err != nil {
+	// Create safety backup before deployment, in every backup mode
+	if _, err := s.writeBackup(&config, "Before deployment", userID, true); err != nil {
 		return fmt.Errorf("backup failed: %w", err)
 	}
 
+	// Keep the file being replaced so a failed deployment can be undone
+	snapshot, err := snapshotConfigFile(config.FilePath)
+	if err != nil {
+		err = fmt.Errorf("failed to read current config file: %w", err)
+		s.recordActivity(&config, "deployment failed", ActivityLevelError, err)
+		return err
+	}
+
 	// Write configuration to file
 	if err := s.writeConfigToFile(&config); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+		return s.failDeploy(&config, snapshot, &DeployError{Step: "write", Output: err.Error()})
 	}
 
 	// Test nginx configuration
-	if err := s.testNginxConfig(); err != nil {
-		return fmt.Errorf("nginx test failed: %w", err)
+	if err := s.nginxRunner.Test(""); err != nil {
+		return s.failDeploy(&config, snapshot, &DeployError{Step: "test", Output: nginxOutput(err)})
 	}
 
 	// Reload nginx
-	if err := s.reloadNginx(); err != nil {
-		return fmt.Errorf("nginx reload failed: %w", err)
+	if err := s.nginxRunner.Reload(); err != nil {
+		return s.failDeploy(&config, snapshot, &DeployError{Step: "reload", Output: nginxOutput(err)})
 	}
 
 	// Update config status
+	deployedAt := time.Now()
 	config.Status = models.StatusActive
 	config.IsActive = true
+	config.LastDeployedAt = &deployedAt
 	if err := s.db.Save(&config).Error; err != nil {
 		return err
 	}
 
 	// Log audit event
-	s.logAuditEvent(userID, models.ObjectTypeNginxConfig, config.ID, models.ActionUpdated,
+	s.logAuditEvent(audit, userID, models.ObjectTypeNginxConfig, config.ID, models.ActionUpdated,
 		fmt.Sprintf("Deployed configuration: %s", config.Name))
+	s.recordActivity(&config, "deployed", ActivityLevelInfo, nil)
 
 	return nil
 }
 
-// ValidateConfig validates nginx configuration syntax
-func (s *ConfigService) ValidateConfig(userID uint, content string) (*ValidationResult, error) {
-	return s.validateConfig(content)
+// ValidateConfig validates nginx configuration syntax. Without a type, the
+// context is detected from the content's top-level directives.
+func (s *ConfigService) ValidateConfig(userID uint, configType models.ConfigType, content string) (*ValidationResult, error) {
+	return s.validateConfig(configType, content)
 }
 
-// validateConfig performs nginx configuration validation
-func (s *ConfigService) validateConfig(content string) (*ValidationResult, error) {
+// validateConfig performs nginx configuration validation. Fragments are
+// wrapped in the events and http blocks they need to be a complete
+// configuration, and reported lines refer to the original content.
+func (s *ConfigService) validateConfig(configType models.ConfigType, content string) (*ValidationResult, error) {
 	// Create temporary file
 	tempFile := filepath.Join(os.TempDir(), fmt.Sprintf("nginx_test_%d.conf", time.Now().UnixNano()))
 	defer os.Remove(tempFile)
 
 	// Write content to temporary file
-	if err := os.WriteFile(tempFile, []byte(content), 0644); err != nil {
+	wrapped, offset := wrapConfig(configType, content)
+	if err := os.WriteFile(tempFile, []byte(wrapped), 0644); err != nil {
 		return nil, fmt.Errorf("failed to write temp file: %w", err)
 	}
 
 	// Run nginx -t on the temporary file
-	cmd := exec.Command("nginx", "-t", "-c", tempFile)
-	output, err := cmd.CombinedOutput()
+	var output string
+	err := s.nginxRunner.Test(tempFile)
+	if err != nil {
+		output = nginxOutput(err)
+	}
+	mapped := mapValidationOutput(output, tempFile, offset, strings.Count(content, "\n")+1)
 
 	result := &ValidationResult{
 		IsValid: err == nil,
-		Output:  string(output),
+		Output:  mapped,
 		Errors:  []string{},
+		Issues:  contentIssues(mapped, content),
 	}
 
 	if err != nil {
 		// Parse nginx error output
-		lines := strings.Split(string(output), "\n")
+		lines := strings.Split(mapped, "\n")
 		for _, line := range lines {
 			line = strings.TrimSpace(line)
 			if line != "" && !strings.Contains(line, "test is successful") {
@@ -414,7 +619,9 @@ func (s *ConfigService) validateConfig(content string) (*ValidationResult, error
 	return result, nil
 }
 
-// writeConfigToFile writes configuration content to nginx config file
+// writeConfigToFile writes configuration content to nginx config file. The
+// content is written to a temporary file that replaces the target, so nginx
+// never reads a partially written file.
 func (s *ConfigService) writeConfigToFile(config *models.NginxConfig) error {
 	if config.FilePath == "" {
 		return fmt.Errorf("file path not specified")
@@ -427,31 +634,13 @@ func (s *ConfigService) writeConfigToFile(config *models.NginxConfig) error {
 	}
 
 	// Write content to file
-	if err := os.WriteFile(config.FilePath, []byte(config.Content), 0644); err != nil {
+	if err := writeFileAtomic(config.FilePath, []byte(config.Content), 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
 	return nil
 }
 
-// testNginxConfig tests nginx configuration
-func (s *ConfigService) testNginxConfig() error {
-	cmd := exec.Command("nginx", "-t")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("nginx test failed: %s", string(output))
-	}
-	return nil
-}
-
-// reloadNginx reloads nginx configuration
-func (s *ConfigService) reloadNginx() error {
-	cmd := exec.Command("nginx", "-s", "reload")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("nginx reload failed: %s", string(output))
-	}
-	return nil
-}
-
 // renderFromTemplate renders configuration from template
 func (s *ConfigService) renderFromTemplate(templateID uint, vars map[string]interface{}) (string, error) {
 	// Get template
@@ -464,15 +653,20 @@ func (s *ConfigService) renderFromTemplate(templateID uint, vars map[string]inte
 	}
 
 	// Parse template
-	t, err := template.New("config").Parse(tmpl.Content)
+	t, err := parseConfigTemplate("config", tmpl.Content)
 	if err != nil {
 		return "", fmt.Errorf("template parse failed: %w", err)
 	}
 
+	// Check variables against the template's schema
+	if problems := validateTemplateVariables(tmpl.Variables, vars, false); len(problems) > 0 {
+		return "", fmt.Errorf("invalid template variables: %s", strings.Join(problems, "; "))
+	}
+
 	// Render template with variables
 	var result strings.Builder
-	if err := t.Execute(&result, vars); err != nil {
-		return "", fmt.Errorf("template execution failed: %w", err)
+	if err := t.Execute(&result, applyTemplateDefaults(tmpl.Variables, vars)); err != nil {
+		return "", fmt.Errorf("template execution failed: %s", describeTemplateExecError(err))
 	}
 
 	return result.String(), nil
@@ -500,6 +694,16 @@ func (s *ConfigService) createVersion(configID uint, content, comment string, us
 	return s.db.Create(newVersion).Error
 }
 
+// CreateBackup takes a manual backup of a configuration
+func (s *ConfigService) CreateBackup(userID uint, id uint, reason string) (*models.ConfigBackup, error) {
+	config, err := s.GetConfig(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.writeBackup(config, reason, userID, false)
+}
+
 // createBackup creates a configuration backup
 func (s *ConfigService) createBackup(configID uint, reason string, userID uint) error {
 	// Get configuration
@@ -508,44 +712,70 @@ func (s *ConfigService) createBackup(configID uint, reason string, userID uint)
 		return err
 	}
 
+	_, err := s.writeBackup(&config, reason, userID, true)
+	return err
+}
+
+// writeBackup stores a backup of the configuration content in the database
+// and the backup directory
+func (s *ConfigService) writeBackup(config *models.NginxConfig, reason string, userID uint, auto bool) (*models.ConfigBackup, error) {
 	// Generate backup name
 	backupName := fmt.Sprintf("%s_backup_%d", config.Name, time.Now().Unix())
 	backupFilePath := filepath.Join(s.backupPath, backupName+".conf")
 
 	// Create backup
 	backup := &models.ConfigBackup{
-		ConfigID:   configID,
+		ConfigID:   config.ID,
 		BackupName: backupName,
 		Content:    config.Content,
 		FilePath:   backupFilePath,
 		Reason:     reason,
-		AutoBackup: true,
+		AutoBackup: auto,
 		CreatedBy:  userID,
 	}
 
 	// Save backup to database
 	if err := s.db.Create(backup).Error; err != nil {
-		return err
+		return nil, err
 	}
 
 	// Write backup file
 	if err := os.MkdirAll(s.backupPath, 0755); err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := os.WriteFile(backupFilePath, []byte(config.Content), 0644); err != nil {
+		return nil, err
+	}
+	return backup, nil
+}
+
+// recordActivity adds a configuration event to the activity feed of its owner
+func (s *ConfigService) recordActivity(config *models.NginxConfig, action, level string, err error) {
+	details := models.JSON{
+		"config_id": config.ID,
+		"name":      config.Name,
+		"type":      config.Type,
+		"action":    action,
+	}
+	if err != nil {
+		details["error"] = err.Error()
 	}
 
-	return os.WriteFile(backupFilePath, []byte(config.Content), 0644)
+	s.activityService.RecordForUser(config.UserID, ActivityTypeNginxConfig,
+		fmt.Sprintf("Configuration %s %s", config.Name, action), level, details)
 }
 
 // logAuditEvent logs an audit event
-func (s *ConfigService) logAuditEvent(userID uint, objectType models.ObjectType, objectID uint, action models.AuditAction, description string) {
+func (s *ConfigService) logAuditEvent(audit AuditContext, userID uint, objectType models.ObjectType, objectID uint, action models.AuditAction, description string) {
 	auditLog := &models.AuditLog{
 		UserID:      userID,
 		Action:      action,
 		ObjectType:  objectType,
 		ObjectID:    objectID,
 		Description: description,
-		IPAddress:   "", // This should be populated from the request context
-		UserAgent:   "", // This should be populated from the request context
+		IPAddress:   audit.IPAddress,
+		UserAgent:   audit.UserAgent,
 	}
 
 	if err := s.db.Create(auditLog).Error; err != nil {
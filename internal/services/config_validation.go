@@ -0,0 +1,172 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// validationSourceName replaces the temporary file path in nginx -t output,
+// so messages point at the user's content rather than the test scaffold
+const validationSourceName = "content"
+
+// Contexts a configuration is validated in. Fragments are wrapped in the
+// blocks of their context so nginx -t sees a complete configuration.
+const (
+	configContextMain   = "main"
+	configContextHTTP   = "http"
+	configContextServer = "server"
+)
+
+// mainOnlyDirectives only appear at the top of a complete nginx.conf
+var mainOnlyDirectives = []string{"events", "http", "stream", "mail", "worker_processes", "user", "pid", "load_module", "daemon", "master_process"}
+
+// serverDirectives place a snippet inside a server block
+var serverDirectives = []string{"location", "listen", "server_name", "root", "return", "rewrite", "try_files"}
+
+// configContext returns the context content must be validated in. Main
+// configurations are complete files; upstream and server blocks live in http,
+// and location blocks in a server. Custom content and server snippets without
+// an enclosing server block are placed by their top-level directives.
+func configContext(configType models.ConfigType, content string) string {
+	directives := topLevelDirectives(content)
+
+	switch configType {
+	case models.ConfigTypeMain:
+		return configContextMain
+	case models.ConfigTypeLocation:
+		return configContextServer
+	case models.ConfigTypeUpstream:
+		return configContextHTTP
+	case models.ConfigTypeServer:
+		if containsString(directives, "server") {
+			return configContextHTTP
+		}
+		return configContextServer
+	}
+
+	for _, directive := range directives {
+		if containsString(mainOnlyDirectives, directive) {
+			return configContextMain
+		}
+	}
+	for _, directive := range directives {
+		if containsString(serverDirectives, directive) {
+			return configContextServer
+		}
+	}
+	return configContextHTTP
+}
+
+// wrapConfig wraps content in a minimal scaffold for its context, returning
+// the configuration to test and the number of lines added before content
+func wrapConfig(configType models.ConfigType, content string) (string, int) {
+	var prefix, suffix string
+	switch configContext(configType, content) {
+	case configContextMain:
+		return content, 0
+	case configContextServer:
+		prefix = "events {}\nhttp {\nserver {\n"
+		suffix = "\n}\n}\n"
+	default:
+		prefix = "events {}\nhttp {\n"
+		suffix = "\n}\n"
+	}
+	return prefix + content + suffix, strings.Count(prefix, "\n")
+}
+
+// topLevelDirectives lists the directive names at the outermost level of
+// content, skipping comments, quoted strings and the insides of blocks
+func topLevelDirectives(content string) []string {
+	var directives []string
+	depth := 0
+	expectName := true
+	var quote byte
+
+	for i := 0; i < len(content); i++ {
+		ch := content[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+			expectName = false
+		case ch == '{':
+			depth++
+			expectName = true
+		case ch == '}':
+			if depth > 0 {
+				depth--
+			}
+			expectName = true
+		case ch == ';':
+			expectName = true
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+		default:
+			start := i
+			for i+1 < len(content) && !strings.ContainsRune(" \t\r\n;{}#\"'", rune(content[i+1])) {
+				i++
+			}
+			if expectName && depth == 0 {
+				directives = append(directives, content[start:i+1])
+			}
+			expectName = false
+		}
+	}
+	return directives
+}
+
+// quotedTokenPattern matches the first quoted token of an nginx message, such
+// as the directive in `unknown directive "foo"`
+var quotedTokenPattern = regexp.MustCompile(`"([^"]+)"`)
+
+// mapValidationOutput rewrites the nginx -t output for a wrapped
+// configuration, replacing the temporary file with validationSourceName and
+// its line numbers with lines of the original content. Errors reported in the
+// scaffold, such as a missing closing brace, are clamped to the first or last
+// line of content.
+func mapValidationOutput(output, tempFile string, offset, contentLines int) string {
+	location := regexp.MustCompile(regexp.QuoteMeta(tempFile) + `:(\d+)`)
+	output = location.ReplaceAllStringFunc(output, func(match string) string {
+		line, _ := strconv.Atoi(location.FindStringSubmatch(match)[1])
+		line -= offset
+		if line < 1 {
+			line = 1
+		}
+		if line > contentLines {
+			line = contentLines
+		}
+		return fmt.Sprintf("%s:%d", validationSourceName, line)
+	})
+	return strings.ReplaceAll(output, tempFile, validationSourceName)
+}
+
+// contentIssues parses nginx -t output into issues, adding the column of the
+// quoted token a message refers to when it can be found on the reported line
+func contentIssues(output, content string) []ValidationIssue {
+	lines := strings.Split(content, "\n")
+	issues := parseValidationIssues(output)
+	for i := range issues {
+		issue := &issues[i]
+		if issue.File != validationSourceName || issue.Line < 1 || issue.Line > len(lines) {
+			continue
+		}
+		if token := quotedTokenPattern.FindStringSubmatch(issue.Message); token != nil {
+			if index := strings.Index(lines[issue.Line-1], token[1]); index >= 0 {
+				issue.Column = index + 1
+			}
+		}
+	}
+	return issues
+}
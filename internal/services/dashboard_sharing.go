@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDashboardNotFound      = errors.New("dashboard not found")
+	ErrDashboardAccessDenied  = errors.New("dashboard access denied")
+	ErrInvalidDashboardShare  = errors.New("invalid dashboard share")
+	ErrDashboardShareNotOwner = errors.New("only the dashboard owner can change sharing")
+)
+
+// DashboardShareEntry grants a user view or edit access
+type DashboardShareEntry struct {
+	UserID     uint   `json:"user_id" binding:"required"`
+	Permission string `json:"permission" binding:"required"` // view, edit
+}
+
+// DashboardShareRequest adds or changes shares and removes users' access.
+// A user in both lists is removed.
+type DashboardShareRequest struct {
+	Add    []DashboardShareEntry `json:"add"`
+	Remove []uint                `json:"remove"`
+}
+
+// ShareDashboard changes who a dashboard is shared with and returns its
+// shares. Only the owner can change sharing.
+func (as *AnalyticsService) ShareDashboard(dashboardID, userID uint, req DashboardShareRequest) ([]models.DashboardShare, error) {
+	dashboard, err := as.findDashboard(dashboardID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if dashboard.Permission != models.DashboardPermissionOwner {
+		return nil, ErrDashboardShareNotOwner
+	}
+
+	for _, entry := range req.Add {
+		if entry.Permission != models.DashboardPermissionView && entry.Permission != models.DashboardPermissionEdit {
+			return nil, fmt.Errorf("%w: permission %q for user %d, expected view or edit", ErrInvalidDashboardShare, entry.Permission, entry.UserID)
+		}
+		if entry.UserID == dashboard.UserID {
+			return nil, fmt.Errorf("%w: user %d owns the dashboard", ErrInvalidDashboardShare, entry.UserID)
+		}
+	}
+
+	err = as.db.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range req.Add {
+			var user models.User
+			if err := tx.Select("id").First(&user, entry.UserID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: user %d not found", ErrInvalidDashboardShare, entry.UserID)
+				}
+				return err
+			}
+
+			share := models.DashboardShare{DashboardID: dashboard.ID, UserID: entry.UserID}
+			if err := tx.Where(share).Assign(models.DashboardShare{Permission: entry.Permission}).
+				FirstOrCreate(&share).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(req.Remove) > 0 {
+			if err := tx.Where("dashboard_id = ? AND user_id IN ?", dashboard.ID, req.Remove).
+				Delete(&models.DashboardShare{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []models.DashboardShare
+	if err := as.db.Preload("User").Where("dashboard_id = ?", dashboard.ID).Order("user_id ASC").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// findDashboard loads a dashboard with the user's permission on it. A
+// dashboard the user cannot see is reported as not found.
+func (as *AnalyticsService) findDashboard(dashboardID, userID uint) (*models.Dashboard, error) {
+	var dashboard models.Dashboard
+	if err := as.db.First(&dashboard, dashboardID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDashboardNotFound
+		}
+		return nil, err
+	}
+
+	var shares []models.DashboardShare
+	if err := as.db.Where("dashboard_id = ? AND user_id = ?", dashboardID, userID).Limit(1).Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	sharedPermission := ""
+	if len(shares) > 0 {
+		sharedPermission = shares[0].Permission
+	}
+
+	dashboard.Permission = dashboardPermission(&dashboard, userID, sharedPermission)
+	if dashboard.Permission == "" {
+		return nil, ErrDashboardNotFound
+	}
+	return &dashboard, nil
+}
+
+// dashboardPermission returns the strongest access a user has to a dashboard,
+// given the permission it was shared with them at, or "" for none
+func dashboardPermission(dashboard *models.Dashboard, userID uint, sharedPermission string) string {
+	switch {
+	case dashboard.UserID == userID:
+		return models.DashboardPermissionOwner
+	case sharedPermission == models.DashboardPermissionEdit:
+		return models.DashboardPermissionEdit
+	case sharedPermission == models.DashboardPermissionView || dashboard.IsPublic:
+		return models.DashboardPermissionView
+	default:
+		return ""
+	}
+}
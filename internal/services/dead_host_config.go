@@ -0,0 +1,88 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// deadHostConfigFile returns the config file written for a dead host
+func (s *NginxService) deadHostConfigFile(deadHost *models.DeadHost) string {
+	return filepath.Join(s.sitesPath, fmt.Sprintf("dead_host_%d.conf", deadHost.ID))
+}
+
+// DeadHostPagePath returns the custom 404 page written for a dead host, kept
+// next to the main nginx configuration
+func (s *NginxService) DeadHostPagePath(deadHost *models.DeadHost) string {
+	return filepath.Join(filepath.Dir(s.configPath), "dead_hosts", fmt.Sprintf("dead_host_%d.html", deadHost.ID))
+}
+
+// BuildDeadHostConfig renders the nginx configuration for a dead host without
+// writing it
+func (s *NginxService) BuildDeadHostConfig(deadHost *models.DeadHost) string {
+	certificate := s.loadHostCertificate(deadHost.CertificateID)
+	wantsSSL := deadHost.SSLForced || deadHost.CertificateID != nil
+	return s.generateDeadHostConfig(deadHost, s.resolveHostSSL(deadHost.GetPrimaryDomain(), certificate, wantsSSL))
+}
+
+// generateDeadHostConfig generates a server block that answers every request
+// with 404, serving the custom page when one is set
+func (s *NginxService) generateDeadHostConfig(deadHost *models.DeadHost, ssl *hostSSL) string {
+	var config strings.Builder
+
+	config.WriteString("server {\n")
+	writeHostServerHeader(&config, deadHost.DomainNames, ssl, deadHost.SSLForced)
+
+	if deadHost.HasCustomPage() {
+		pagePath := s.DeadHostPagePath(deadHost)
+		pageName := filepath.Base(pagePath)
+		config.WriteString(fmt.Sprintf("    error_page 404 /%s;\n", pageName))
+		config.WriteString(fmt.Sprintf("    location = /%s {\n", pageName))
+		config.WriteString(fmt.Sprintf("        root %s;\n", filepath.Dir(pagePath)))
+		config.WriteString("        internal;\n")
+		config.WriteString("    }\n")
+	}
+
+	config.WriteString("    location / {\n")
+	config.WriteString("        return 404;\n")
+	config.WriteString("    }\n")
+	config.WriteString("}\n")
+
+	return config.String()
+}
+
+// DeployDeadHost writes a dead host's configuration and custom page, then
+// reloads nginx. Dead hosts are not staged; they are deployed immediately.
+func (s *NginxService) DeployDeadHost(deadHost *models.DeadHost) error {
+	pagePath := s.DeadHostPagePath(deadHost)
+	if deadHost.HasCustomPage() {
+		if err := os.MkdirAll(filepath.Dir(pagePath), 0755); err != nil {
+			return fmt.Errorf("%w: %v", ErrNginxConfigGeneration, err)
+		}
+		if err := os.WriteFile(pagePath, []byte(deadHost.CustomPage), 0644); err != nil {
+			return fmt.Errorf("%w: %v", ErrNginxConfigGeneration, err)
+		}
+	} else if err := os.Remove(pagePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	configContent := s.BuildDeadHostConfig(deadHost)
+	if err := os.WriteFile(s.deadHostConfigFile(deadHost), []byte(configContent), 0644); err != nil {
+		return fmt.Errorf("%w: %v", ErrNginxConfigGeneration, err)
+	}
+	return s.reloadNginx()
+}
+
+// UndeployDeadHost removes a dead host's configuration and custom page, then
+// reloads nginx
+func (s *NginxService) UndeployDeadHost(deadHost *models.DeadHost) error {
+	for _, file := range []string{s.deadHostConfigFile(deadHost), s.DeadHostPagePath(deadHost)} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return s.reloadNginx()
+}
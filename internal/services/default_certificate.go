@@ -0,0 +1,245 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// DefaultCertificateSettingID is the setting choosing the fallback certificate
+const DefaultCertificateSettingID = "default-certificate"
+
+// Default certificate modes
+const (
+	DefaultCertificateSelfSigned  = "self-signed" // a generated self-signed certificate
+	DefaultCertificateCertificate = "certificate" // a stored certificate
+	DefaultCertificateNone        = "none"        // no fallback; hosts without a certificate stay on HTTP
+)
+
+var ErrInvalidDefaultCertificate = errors.New("invalid default certificate")
+
+// selfSignedDefaultValidity is how long the generated fallback certificate lasts
+const selfSignedDefaultValidity = 10 * 365 * 24 * time.Hour
+
+// DefaultCertificateSetting chooses the certificate served by hosts that need
+// HTTPS but have no valid certificate of their own
+type DefaultCertificateSetting struct {
+	Mode          string `json:"mode"`
+	CertificateID *uint  `json:"certificate_id,omitempty"`
+}
+
+// hostSSL is the certificate a generated server block listens on 443 with.
+// Certificate is nil when the fallback self-signed certificate is used.
+type hostSSL struct {
+	CertificatePath string
+	KeyPath         string
+	Certificate     *models.Certificate
+	Fallback        bool
+}
+
+// GetDefaultCertificate returns the fallback certificate setting, which is a
+// generated self-signed certificate until changed
+func (s *NginxService) GetDefaultCertificate() (*DefaultCertificateSetting, error) {
+	var setting models.Setting
+	if err := s.db.Where("id = ?", DefaultCertificateSettingID).First(&setting).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &DefaultCertificateSetting{Mode: DefaultCertificateSelfSigned}, nil
+		}
+		return nil, err
+	}
+
+	result := &DefaultCertificateSetting{Mode: DefaultCertificateSelfSigned}
+	value, ok := setting.GetValue().(map[string]interface{})
+	if !ok {
+		return result, nil
+	}
+	if mode, ok := value["mode"].(string); ok && mode != "" {
+		result.Mode = mode
+	}
+	if id, ok := value["certificate_id"].(float64); ok && id > 0 {
+		certificateID := uint(id)
+		result.CertificateID = &certificateID
+	}
+	return result, nil
+}
+
+// SetDefaultCertificate saves the fallback certificate setting. A stored
+// certificate must have both a certificate and a key.
+func (s *NginxService) SetDefaultCertificate(req *DefaultCertificateSetting) error {
+	value := map[string]interface{}{"mode": req.Mode}
+	switch req.Mode {
+	case DefaultCertificateSelfSigned, DefaultCertificateNone:
+	case DefaultCertificateCertificate:
+		if req.CertificateID == nil {
+			return fmt.Errorf("%w: certificate_id is required", ErrInvalidDefaultCertificate)
+		}
+		var certificate models.Certificate
+		if err := s.db.Where("id = ?", *req.CertificateID).First(&certificate).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrCertificateNotFound
+			}
+			return err
+		}
+		if !certificate.IsValid() {
+			return fmt.Errorf("%w: certificate %d has no certificate and key", ErrInvalidDefaultCertificate, certificate.ID)
+		}
+		value["certificate_id"] = certificate.ID
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidDefaultCertificate, req.Mode)
+	}
+
+	setting := models.Setting{ID: DefaultCertificateSettingID}
+	if err := s.db.Where("id = ?", setting.ID).First(&setting).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	setting.Name = "Default Certificate"
+	setting.SetValue(value)
+	return s.db.Save(&setting).Error
+}
+
+// UsesFallbackCertificate reports whether a host that wants HTTPS will be
+// served with the default certificate because its own is missing or unusable
+func (s *NginxService) UsesFallbackCertificate(certificateID *uint, sslForced bool) bool {
+	if !sslForced && certificateID == nil {
+		return false
+	}
+	if certificate := s.loadHostCertificate(certificateID); certificate != nil && certificate.IsValid() {
+		return false
+	}
+	setting, err := s.GetDefaultCertificate()
+	return err == nil && setting.Mode != DefaultCertificateNone
+}
+
+// resolveHostSSL picks the certificate a host's 443 block uses: its own when
+// valid, otherwise the default certificate when the host wants HTTPS, so one
+// host without a certificate cannot stop the whole configuration loading.
+// Returns nil when the host is served over plain HTTP only.
+func (s *NginxService) resolveHostSSL(host string, certificate *models.Certificate, wantsSSL bool) *hostSSL {
+	if certificate != nil && certificate.IsValid() {
+		return &hostSSL{
+			CertificatePath: certificateFile(nginxCertificatesPath, "cert", certificate.ID),
+			KeyPath:         certificateFile(nginxCertificatesPath, "key", certificate.ID),
+			Certificate:     certificate,
+		}
+	}
+	if !wantsSSL {
+		return nil
+	}
+
+	ssl, err := s.defaultHostSSL()
+	if err != nil {
+		logger.Warn("Host has no valid certificate and no default certificate is available; serving HTTP only",
+			logger.String("host", host), logger.Err(err))
+		return nil
+	}
+	if ssl == nil {
+		logger.Warn("Host has no valid certificate and the default certificate is disabled; serving HTTP only",
+			logger.String("host", host))
+		return nil
+	}
+
+	logger.Warn("Host has no valid certificate; using the default certificate", logger.String("host", host))
+	return ssl
+}
+
+// defaultHostSSL returns the configured default certificate, generating the
+// self-signed one on first use, or nil when the fallback is disabled
+func (s *NginxService) defaultHostSSL() (*hostSSL, error) {
+	setting, err := s.GetDefaultCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	switch setting.Mode {
+	case DefaultCertificateNone:
+		return nil, nil
+	case DefaultCertificateCertificate:
+		certificate := s.loadHostCertificate(setting.CertificateID)
+		if certificate == nil || !certificate.IsValid() {
+			return nil, fmt.Errorf("%w: configured certificate is missing or incomplete", ErrInvalidDefaultCertificate)
+		}
+		return &hostSSL{
+			CertificatePath: certificateFile(nginxCertificatesPath, "cert", certificate.ID),
+			KeyPath:         certificateFile(nginxCertificatesPath, "key", certificate.ID),
+			Fallback:        true,
+		}, nil
+	}
+
+	certificatePath, keyPath := s.SelfSignedDefaultCertificatePaths()
+	if err := ensureSelfSignedDefaultCertificate(certificatePath, keyPath); err != nil {
+		return nil, err
+	}
+	return &hostSSL{CertificatePath: certificatePath, KeyPath: keyPath, Fallback: true}, nil
+}
+
+// SelfSignedDefaultCertificatePaths returns where the generated fallback
+// certificate and key are kept, next to the main nginx configuration
+func (s *NginxService) SelfSignedDefaultCertificatePaths() (string, string) {
+	dir := filepath.Join(filepath.Dir(s.configPath), "certificates")
+	return filepath.Join(dir, "default_cert.pem"), filepath.Join(dir, "default_key.pem")
+}
+
+// ensureSelfSignedDefaultCertificate writes a self-signed certificate and key
+// unless both files already exist
+func ensureSelfSignedDefaultCertificate(certificatePath, keyPath string) error {
+	if _, err := os.Stat(certificatePath); err == nil {
+		if _, err := os.Stat(keyPath); err == nil {
+			return nil
+		}
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:   "nginx-manager default",
+			Organization: []string{"Nginx Manager"},
+		},
+		NotBefore:   time.Now(),
+		NotAfter:    time.Now().Add(selfSignedDefaultValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certificatePath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certificatePath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0644); err != nil {
+		return err
+	}
+
+	logger.Info("Generated self-signed default certificate", logger.String("path", certificatePath))
+	return nil
+}
@@ -0,0 +1,98 @@
+//go:build !windows
+
+package services
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// listMountedDisks returns usage for every mounted filesystem whose type is
+// not excluded. Mounts are read from /proc/self/mounts; where that is not
+// available only the root filesystem is reported.
+func listMountedDisks(excludedFSTypes map[string]bool) ([]DiskStats, error) {
+	data, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		root, err := statfsDisk("/")
+		if err != nil {
+			return nil, err
+		}
+		return []DiskStats{root}, nil
+	}
+
+	var disks []DiskStats
+	index := make(map[string]int)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		device, mountPoint, fsType := fields[0], unescapeMountField(fields[1]), fields[2]
+		if excludedFSTypes[fsType] {
+			continue
+		}
+
+		stats, err := statfsDisk(mountPoint)
+		if err != nil || stats.Total == 0 {
+			continue
+		}
+		stats.Device = device
+		stats.FSType = fsType
+
+		// A later mount on the same path shadows the earlier one
+		if i, exists := index[mountPoint]; exists {
+			disks[i] = stats
+			continue
+		}
+		index[mountPoint] = len(disks)
+		disks = append(disks, stats)
+	}
+
+	return disks, nil
+}
+
+// statfsDisk reads total, free and used space for the filesystem mounted at path
+func statfsDisk(path string) (DiskStats, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return DiskStats{}, err
+	}
+
+	blockSize := uint64(fs.Bsize)
+	stats := DiskStats{
+		MountPoint: path,
+		Total:      uint64(fs.Blocks) * blockSize,
+		Free:       uint64(fs.Bavail) * blockSize,
+	}
+	stats.Used = (uint64(fs.Blocks) - uint64(fs.Bfree)) * blockSize
+
+	// Match df: usage is relative to the space available to unprivileged users
+	if usable := stats.Used + stats.Free; usable > 0 {
+		stats.UsedPercent = float64(stats.Used) / float64(usable) * 100
+	}
+
+	return stats, nil
+}
+
+// unescapeMountField decodes the octal escapes (\040 for space etc.) used in /proc/self/mounts
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+3 < len(field) {
+			if value, err := strconv.ParseUint(field[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
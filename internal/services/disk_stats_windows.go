@@ -0,0 +1,12 @@
+package services
+
+// listMountedDisks is not implemented on Windows; no disks are reported
+// rather than made-up values
+func listMountedDisks(excludedFSTypes map[string]bool) ([]DiskStats, error) {
+	return nil, nil
+}
+
+// statfsDisk is not implemented on Windows
+func statfsDisk(path string) (DiskStats, error) {
+	return DiskStats{}, ErrDiskStatsUnsupported
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// forwardTargetLookupTimeout bounds resolving a forward host, so a slow DNS
+// server does not hold up saving a proxy host
+const forwardTargetLookupTimeout = 5 * time.Second
+
+var (
+	ErrForwardTargetNotAllowed  = errors.New("forward target is not allowed")
+	ErrInvalidForwardTargetRule = errors.New("invalid forward target rule")
+)
+
+// forwardTargetRule is one allowlist or denylist entry: a network, or a host
+// name matched exactly or, written as "*.example.com", by suffix
+type forwardTargetRule struct {
+	raw     string
+	network *net.IPNet
+	host    string
+}
+
+// ForwardTargetPolicy restricts the hosts proxy hosts forward to, so users
+// of a shared install cannot reach internal services such as the cloud
+// metadata endpoint. Host names are resolved and every address checked.
+// nginx resolves the name again when it loads the config, so a name whose
+// records change afterwards is not caught.
+type ForwardTargetPolicy struct {
+	allow []forwardTargetRule
+	deny  []forwardTargetRule
+
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewForwardTargetPolicy builds a policy from allowlist and denylist entries,
+// each a CIDR, an IP address, a host name or a "*." host name suffix. A
+// target must match the allowlist, if any, and nothing in the denylist. With
+// both lists empty there is nothing to enforce and nil is returned.
+func NewForwardTargetPolicy(allowlist, denylist []string) (*ForwardTargetPolicy, error) {
+	allow, err := parseForwardTargetRules(allowlist)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parseForwardTargetRules(denylist)
+	if err != nil {
+		return nil, err
+	}
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+
+	return &ForwardTargetPolicy{
+		allow:  allow,
+		deny:   deny,
+		lookup: net.DefaultResolver.LookupIPAddr,
+	}, nil
+}
+
+// parseForwardTargetRules parses allowlist or denylist entries, skipping
+// blank ones
+func parseForwardTargetRules(entries []string) ([]forwardTargetRule, error) {
+	var rules []forwardTargetRule
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		rule := forwardTargetRule{raw: entry}
+		switch {
+		case strings.Contains(entry, "/"):
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %v", ErrInvalidForwardTargetRule, entry, err)
+			}
+			rule.network = network
+		case net.ParseIP(entry) != nil:
+			rule.network = singleIPNetwork(net.ParseIP(entry))
+		default:
+			rule.host = normalizeForwardHost(entry)
+			if strings.ContainsAny(rule.host, " :") || strings.Contains(strings.TrimPrefix(rule.host, "*."), "*") {
+				return nil, fmt.Errorf("%w %q: expected a CIDR, IP address, host name or *.suffix", ErrInvalidForwardTargetRule, entry)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// singleIPNetwork returns the network holding just ip
+func singleIPNetwork(ip net.IP) *net.IPNet {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+// normalizeForwardHost lowercases a host and strips IPv6 brackets and a
+// trailing dot
+func normalizeForwardHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.TrimSuffix(host, ".")
+}
+
+// Check returns an error wrapping ErrForwardTargetNotAllowed when proxy hosts
+// may not forward to host. A nil policy allows every host. A name that does
+// not resolve is refused, since its addresses cannot be checked.
+func (p *ForwardTargetPolicy) Check(host string) error {
+	if p == nil {
+		return nil
+	}
+
+	name := normalizeForwardHost(host)
+	var addresses []net.IP
+	if ip := net.ParseIP(name); ip != nil {
+		addresses = []net.IP{ip}
+		name = ""
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), forwardTargetLookupTimeout)
+		resolved, err := p.lookup(ctx, name)
+		cancel()
+		if err != nil || len(resolved) == 0 {
+			return fmt.Errorf("%w: %s could not be resolved to check it against the forward target rules", ErrForwardTargetNotAllowed, host)
+		}
+		for _, address := range resolved {
+			addresses = append(addresses, address.IP)
+		}
+	}
+
+	if rule := matchHostRule(p.deny, name); rule != "" {
+		return fmt.Errorf("%w: %s matches denied target %s", ErrForwardTargetNotAllowed, host, rule)
+	}
+	for _, address := range addresses {
+		if rule := matchNetworkRule(p.deny, address); rule != "" {
+			if name == "" {
+				return fmt.Errorf("%w: %s is in denied target %s", ErrForwardTargetNotAllowed, host, rule)
+			}
+			return fmt.Errorf("%w: %s resolves to %s, in denied target %s", ErrForwardTargetNotAllowed, host, address, rule)
+		}
+	}
+
+	// An allowlisted name is trusted whatever it resolves to; otherwise every
+	// address must be allowlisted
+	if len(p.allow) == 0 || matchHostRule(p.allow, name) != "" {
+		return nil
+	}
+	for _, address := range addresses {
+		if matchNetworkRule(p.allow, address) == "" {
+			if name == "" {
+				return fmt.Errorf("%w: %s is not in the forward target allowlist", ErrForwardTargetNotAllowed, host)
+			}
+			return fmt.Errorf("%w: %s resolves to %s, which is not in the forward target allowlist", ErrForwardTargetNotAllowed, host, address)
+		}
+	}
+	return nil
+}
+
+// matchHostRule returns the first host name rule matching name, or ""
+func matchHostRule(rules []forwardTargetRule, name string) string {
+	if name == "" {
+		return ""
+	}
+	for _, rule := range rules {
+		if rule.host == "" {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(rule.host, "*"); ok {
+			if strings.HasSuffix(name, suffix) {
+				return rule.raw
+			}
+		} else if name == rule.host {
+			return rule.raw
+		}
+	}
+	return ""
+}
+
+// matchNetworkRule returns the first network rule holding ip, or ""
+func matchNetworkRule(rules []forwardTargetRule, ip net.IP) string {
+	for _, rule := range rules {
+		if rule.network != nil && rule.network.Contains(ip) {
+			return rule.raw
+		}
+	}
+	return ""
+}
+
+// SetForwardTargetPolicy sets the policy proxy host forward targets are
+// checked against; nil allows any target
+func (s *NginxService) SetForwardTargetPolicy(policy *ForwardTargetPolicy) {
+	s.forwardTargets = policy
+}
+
+// CheckForwardTarget checks a proxy host forward host against the configured
+// policy, returning an error wrapping ErrForwardTargetNotAllowed if refused
+func (s *NginxService) CheckForwardTarget(host string) error {
+	return s.forwardTargets.Check(host)
+}
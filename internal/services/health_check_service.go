@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Health check probe limits
+const (
+	MinHealthCheckInterval = 10 * time.Second
+	MaxHealthCheckInterval = 24 * time.Hour
+
+	// healthCheckTick is how often the scheduler looks for hosts due a probe
+	healthCheckTick = 5 * time.Second
+)
+
+// HealthCheckMetricType is the metric type probe results are stored under:
+// upstream_up is 1 or 0 and upstream_latency_ms the response time
+const HealthCheckMetricType = "health_check"
+
+var (
+	ErrInvalidHealthCheckPath     = errors.New("health check path must start with /")
+	ErrInvalidHealthCheckInterval = fmt.Errorf("health check interval must be between %d and %d seconds",
+		int(MinHealthCheckInterval/time.Second), int(MaxHealthCheckInterval/time.Second))
+	ErrInvalidHealthCheckTimeout = errors.New("health check timeout must be positive and shorter than the interval")
+)
+
+// HealthCheckService periodically probes the upstream of every enabled proxy
+// host over HTTP(S), records the outcome on the host and stores it as a
+// metric so alert rules can fire on failing upstreams
+type HealthCheckService struct {
+	db               *gorm.DB
+	analyticsService *AnalyticsService
+	client           *http.Client
+	forwardTargets   *ForwardTargetPolicy
+	concurrency      int
+	interval         time.Duration
+	timeout          time.Duration
+}
+
+// HealthCheckResult is the outcome of one upstream probe
+type HealthCheckResult struct {
+	ProxyHostID uint      `json:"proxy_host_id"`
+	URL         string    `json:"url"`
+	Healthy     bool      `json:"healthy"`
+	Skipped     bool      `json:"skipped,omitempty"` // the forward target policy denies the upstream
+	StatusCode  int       `json:"status_code,omitempty"`
+	LatencyMs   int64     `json:"latency_ms"`
+	Error       string    `json:"error,omitempty"`
+	CheckedAt   time.Time `json:"checked_at"` // when the probe started
+}
+
+// NewHealthCheckService creates a health check service. interval and timeout
+// apply to hosts without their own settings; concurrency bounds the number of
+// simultaneous probes.
+func NewHealthCheckService(analyticsService *AnalyticsService, concurrency int, interval, timeout time.Duration) *HealthCheckService {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &HealthCheckService{
+		db:               database.GetDB(),
+		analyticsService: analyticsService,
+		client: &http.Client{
+			// Upstreams commonly use self-signed certificates, and nginx does
+			// not verify them by default either
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+			// A redirect is an answer from the upstream, not something to follow
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		concurrency: concurrency,
+		interval:    interval,
+		timeout:     timeout,
+	}
+}
+
+// SetForwardTargetPolicy sets the policy proxy host forward targets are
+// checked against, so the manager never probes an upstream the policy denies
+func (s *HealthCheckService) SetForwardTargetPolicy(policy *ForwardTargetPolicy) {
+	s.forwardTargets = policy
+}
+
+// ValidateHealthCheck checks the per-host health check settings; nil uses the
+// defaults. A host without its own interval is probed every defaultInterval,
+// which its timeout must then be shorter than; 0 means background checks are
+// off and the timeout is not compared.
+func ValidateHealthCheck(healthCheck *models.HealthCheckConfig, defaultInterval time.Duration) error {
+	if healthCheck == nil {
+		return nil
+	}
+	if healthCheck.Path != "" && !strings.HasPrefix(healthCheck.Path, "/") {
+		return ErrInvalidHealthCheckPath
+	}
+	interval := time.Duration(healthCheck.Interval) * time.Second
+	if healthCheck.Interval != 0 && (interval < MinHealthCheckInterval || interval > MaxHealthCheckInterval) {
+		return ErrInvalidHealthCheckInterval
+	}
+	if healthCheck.Interval == 0 {
+		interval = defaultInterval
+	}
+	timeout := time.Duration(healthCheck.Timeout) * time.Second
+	if healthCheck.Timeout < 0 || (interval > 0 && timeout >= interval) {
+		return ErrInvalidHealthCheckTimeout
+	}
+	return nil
+}
+
+// StartHealthChecks probes each enabled proxy host whenever its interval has
+// elapsed, until ctx is done
+func (s *HealthCheckService) StartHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(healthCheckTick)
+	defer ticker.Stop()
+
+	logger.Info("Started upstream health checks", logger.Duration("interval", s.interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CheckDueProxyHosts(ctx); err != nil {
+				logger.Error("Failed to run upstream health checks", logger.Err(err))
+			}
+		}
+	}
+}
+
+// CheckDueProxyHosts probes the enabled proxy hosts whose last check is older
+// than their interval, with at most concurrency probes at a time, and waits
+// for them to finish. Disabled hosts are not probed, so their last status is
+// cleared rather than left to go stale.
+func (s *HealthCheckService) CheckDueProxyHosts(ctx context.Context) error {
+	// UpdateColumns skips the hooks and updated_at, which track user edits
+	if err := s.db.Model(&models.ProxyHost{}).Where("enabled = ? AND last_health_status <> ?", false, "").
+		UpdateColumns(map[string]interface{}{"last_health_status": "", "last_checked_at": nil}).Error; err != nil {
+		return err
+	}
+
+	var proxyHosts []models.ProxyHost
+	if err := s.db.Where("enabled = ?", true).Find(&proxyHosts).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var due []*models.ProxyHost
+	for i := range proxyHosts {
+		if proxyHosts[i].IsHealthCheckEnabled() && s.isDue(&proxyHosts[i], now) {
+			due = append(due, &proxyHosts[i])
+		}
+	}
+
+	probeProxyHosts(ctx, due, s.concurrency, func(proxyHost *models.ProxyHost) {
+		s.record(proxyHost, s.Probe(ctx, proxyHost))
+	})
+	return nil
+}
+
+// Probe requests the health check URL of a proxy host. Any response below
+// 500 counts as healthy, since the upstream answered. An upstream the forward
+// target policy denies is skipped without a request.
+func (s *HealthCheckService) Probe(ctx context.Context, proxyHost *models.ProxyHost) HealthCheckResult {
+	result := HealthCheckResult{
+		ProxyHostID: proxyHost.ID,
+		URL:         proxyHost.GetHealthCheckURL(),
+		CheckedAt:   time.Now(),
+	}
+	if err := s.forwardTargets.Check(proxyHost.ForwardHost); err != nil {
+		result.Skipped = true
+		result.Error = err.Error()
+		return result
+	}
+
+	var status string
+	latency, failure := timeProbe(ctx, s.hostTimeout(proxyHost), func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, result.URL, nil)
+		if err != nil {
+			return err
+		}
+		// Upstreams often route on the Host header nginx forwards
+		req.Host = proxyHost.GetPrimaryDomain()
+		req.Header.Set("User-Agent", "nginx-manager-health-check")
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		result.StatusCode, status = resp.StatusCode, resp.Status
+		return resp.Body.Close()
+	})
+	result.LatencyMs = latency.Milliseconds()
+	if failure != "" {
+		result.Error = failure
+		return result
+	}
+
+	result.Healthy = result.StatusCode < http.StatusInternalServerError
+	if !result.Healthy {
+		result.Error = status
+	}
+	return result
+}
+
+// record saves the probe outcome on the proxy host and stores it as metrics,
+// which runs the alert rules watching them. A skipped probe clears the status
+// and stores no metrics, since nothing about the upstream is known.
+func (s *HealthCheckService) record(proxyHost *models.ProxyHost, result HealthCheckResult) {
+	status := models.HealthStatusUnhealthy
+	if result.Healthy {
+		status = models.HealthStatusHealthy
+	}
+	if result.Skipped {
+		status = ""
+	}
+
+	// UpdateColumns skips the hooks and updated_at, which track user edits
+	if err := s.db.Model(&models.ProxyHost{}).Where("id = ?", proxyHost.ID).UpdateColumns(map[string]interface{}{
+		"last_health_status": status,
+		"last_checked_at":    result.CheckedAt,
+	}).Error; err != nil {
+		logger.Error("Failed to save proxy host health status", logger.Uint("proxy_host_id", proxyHost.ID), logger.Err(err))
+	}
+
+	if result.Skipped {
+		logger.Debug("Skipped health check of a denied upstream",
+			logger.Uint("proxy_host_id", proxyHost.ID), logger.String("error", result.Error))
+		return
+	}
+
+	if proxyHost.LastHealthStatus != "" && proxyHost.LastHealthStatus != status {
+		logger.Info("Proxy host upstream health changed",
+			logger.Uint("proxy_host_id", proxyHost.ID),
+			logger.String("status", status),
+			logger.String("error", result.Error))
+	}
+
+	if s.analyticsService == nil {
+		return
+	}
+
+	up := 0.0
+	if result.Healthy {
+		up = 1
+	}
+	proxyHostID := proxyHost.ID
+	metrics := []*models.HistoricalMetric{
+		{MetricName: "upstream_up", Value: up, Unit: "bool", Description: "Upstream answered the health check"},
+		{MetricName: "upstream_latency_ms", Value: float64(result.LatencyMs), Unit: "ms", Description: "Health check response time"},
+	}
+	for _, metric := range metrics {
+		metric.Timestamp = result.CheckedAt
+		metric.MetricType = HealthCheckMetricType
+		metric.Source = "proxy_host"
+		metric.SourceID = &proxyHostID
+		metric.Tags = models.JSON{"proxy_host_id": strconv.FormatUint(uint64(proxyHostID), 10)}
+
+		if err := s.analyticsService.StoreMetric(metric); err != nil {
+			logger.Error("Failed to store health check metric",
+				logger.String("metric_name", metric.MetricName),
+				logger.Uint("proxy_host_id", proxyHostID),
+				logger.Err(err))
+		}
+	}
+}
+
+// isDue reports whether the host's interval has elapsed since its last check
+func (s *HealthCheckService) isDue(proxyHost *models.ProxyHost, now time.Time) bool {
+	if proxyHost.LastCheckedAt == nil {
+		return true
+	}
+	interval := s.interval
+	if proxyHost.HealthCheck != nil && proxyHost.HealthCheck.Interval > 0 {
+		interval = time.Duration(proxyHost.HealthCheck.Interval) * time.Second
+	}
+	return now.Sub(*proxyHost.LastCheckedAt) >= interval
+}
+
+// hostTimeout returns the probe timeout of a proxy host
+func (s *HealthCheckService) hostTimeout(proxyHost *models.ProxyHost) time.Duration {
+	if proxyHost.HealthCheck != nil && proxyHost.HealthCheck.Timeout > 0 {
+		return time.Duration(proxyHost.HealthCheck.Timeout) * time.Second
+	}
+	return s.timeout
+}
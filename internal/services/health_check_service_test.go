@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestHealthCheckSkipsDeniedUpstream probes an upstream the forward target
+// policy denies and checks that no request is sent and the old status is
+// cleared
+func TestHealthCheckSkipsDeniedUpstream(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+
+	requests := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer upstream.Close()
+	address, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(address.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxyHost := createTestProxyHost(t, db, owner.ID, "probed.example.com")
+	if err := db.Model(proxyHost).Updates(map[string]interface{}{
+		"forward_port":       port,
+		"last_health_status": models.HealthStatusHealthy,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewHealthCheckService(nil, 2, time.Minute, time.Second)
+	s.db = db
+	policy, err := NewForwardTargetPolicy(nil, []string{"127.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetForwardTargetPolicy(policy)
+
+	if err := s.CheckDueProxyHosts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 0 {
+		t.Errorf("denied upstream got %d requests, want none", requests)
+	}
+	var stored models.ProxyHost
+	if err := db.First(&stored, proxyHost.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastHealthStatus != "" || stored.LastCheckedAt == nil {
+		t.Errorf("got status %q checked at %v, want no status and a check time", stored.LastHealthStatus, stored.LastCheckedAt)
+	}
+
+	s.SetForwardTargetPolicy(nil)
+	if result := s.Probe(context.Background(), &stored); !result.Healthy || requests != 1 {
+		t.Errorf("allowed upstream: got %+v after %d requests, want healthy after 1", result, requests)
+	}
+}
+
+// TestHealthCheckClearsDisabledHosts checks that a disabled host's last
+// status is cleared instead of going stale
+func TestHealthCheckClearsDisabledHosts(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	proxyHost := createTestProxyHost(t, db, owner.ID, "disabled.example.com")
+	if err := db.Model(proxyHost).Updates(map[string]interface{}{
+		"enabled":            false,
+		"last_health_status": models.HealthStatusUnhealthy,
+		"last_checked_at":    time.Now(),
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewHealthCheckService(nil, 1, time.Minute, time.Second)
+	s.db = db
+	if err := s.CheckDueProxyHosts(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored models.ProxyHost
+	if err := db.First(&stored, proxyHost.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastHealthStatus != "" || stored.LastCheckedAt != nil {
+		t.Errorf("got status %q checked at %v, want both cleared", stored.LastHealthStatus, stored.LastCheckedAt)
+	}
+}
+
+func TestValidateHealthCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		healthCheck     models.HealthCheckConfig
+		defaultInterval time.Duration
+		want            error
+	}{
+		{"timeout within own interval", models.HealthCheckConfig{Interval: 30, Timeout: 10}, 5 * time.Second, nil},
+		{"timeout over own interval", models.HealthCheckConfig{Interval: 30, Timeout: 30}, time.Minute, ErrInvalidHealthCheckTimeout},
+		{"timeout within default interval", models.HealthCheckConfig{Timeout: 10}, time.Minute, nil},
+		{"timeout over default interval", models.HealthCheckConfig{Timeout: 60}, time.Minute, ErrInvalidHealthCheckTimeout},
+		{"background checks off", models.HealthCheckConfig{Timeout: 600}, 0, nil},
+		{"interval too short", models.HealthCheckConfig{Interval: 5}, time.Minute, ErrInvalidHealthCheckInterval},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidateHealthCheck(&tc.healthCheck, tc.defaultInterval); !errors.Is(err, tc.want) {
+				t.Errorf("got %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
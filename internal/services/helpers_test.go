@@ -0,0 +1,42 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens a migrated, seeded SQLite database in a temporary directory
+// and makes it the database services use
+func newTestDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	config := &database.DatabaseConfig{Driver: "sqlite", Database: filepath.Join(t.TempDir(), "test.db")}
+	if err := database.InitDatabase(config); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+	t.Cleanup(func() { database.CloseDatabase() })
+
+	db := database.GetDB()
+	if err := database.AutoMigrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := database.SeedData(db); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	return db
+}
+
+// createTestUser creates a user holding role
+func createTestUser(t *testing.T, db *gorm.DB, email string, role models.RoleName) *models.User {
+	t.Helper()
+
+	user := &models.User{Email: email, Name: email, Roles: models.StringArray{string(role)}}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user %s: %v", email, err)
+	}
+	return user
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+// loadHostCertificate loads the certificate a host references, or nil when it
+// has none or it cannot be loaded
+func (s *NginxService) loadHostCertificate(certificateID *uint) *models.Certificate {
+	if certificateID == nil {
+		return nil
+	}
+
+	var certificate models.Certificate
+	if err := s.db.Where("id = ?", *certificateID).First(&certificate).Error; err != nil {
+		logger.Warn("Failed to load certificate", logger.Err(err))
+		return nil
+	}
+	return &certificate
+}
+
+// writeHostServerHeader writes the listen, certificate and server_name
+// directives shared by dead and redirection hosts. Plain HTTP is always
+// served; with a certificate HTTPS is added and, when sslForced is set, HTTP
+// requests are redirected to it.
+func writeHostServerHeader(config *strings.Builder, domains []string, ssl *hostSSL, sslForced bool) {
+	// Listen directives
+	config.WriteString("    listen 80;\n")
+	if ssl != nil {
+		config.WriteString("    listen 443 ssl;\n")
+		if ssl.Fallback {
+			config.WriteString("    # No valid certificate assigned; serving the default certificate\n")
+		}
+		config.WriteString(fmt.Sprintf("    ssl_certificate %s;\n", ssl.CertificatePath))
+		config.WriteString(fmt.Sprintf("    ssl_certificate_key %s;\n", ssl.KeyPath))
+	}
+
+	// Server names
+	config.WriteString("    server_name")
+	for _, domain := range domains {
+		config.WriteString(" " + domain)
+	}
+	config.WriteString(";\n")
+
+	if ssl != nil && sslForced {
+		config.WriteString("    if ($scheme = http) {\n")
+		config.WriteString("        return 301 https://$host$request_uri;\n")
+		config.WriteString("    }\n")
+	}
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCertificateNotOwned = errors.New("certificate belongs to another user")
+	ErrAccessListNotOwned  = errors.New("access list belongs to another user")
+)
+
+// CheckHostReferences checks that the certificate and access list a host
+// refers to exist and belong to userID. Users allowed to manage every
+// certificate or access list, such as admins, may reference anyone's; a nil
+// authService allows only the user's own. Returns ErrCertificateNotFound or
+// ErrAccessListNotFound for a missing or deleted record and
+// ErrCertificateNotOwned or ErrAccessListNotOwned for another user's.
+func CheckHostReferences(db *gorm.DB, authService *AuthService, userID uint, certificateID, accessListID *uint) error {
+	if certificateID != nil {
+		var certificate models.Certificate
+		if err := db.Select("id", "user_id").First(&certificate, *certificateID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCertificateNotFound
+			}
+			return err
+		}
+		if !canReference(authService, userID, certificate.UserID, models.PermissionCertificateManage) {
+			return ErrCertificateNotOwned
+		}
+	}
+
+	if accessListID != nil {
+		var accessList models.AccessList
+		if err := db.Select("id", "user_id").First(&accessList, *accessListID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAccessListNotFound
+			}
+			return err
+		}
+		if !canReference(authService, userID, accessList.UserID, models.PermissionAccessListManage) {
+			return ErrAccessListNotOwned
+		}
+	}
+
+	return nil
+}
+
+// canReference reports whether userID may attach a record owned by ownerID
+func canReference(authService *AuthService, userID, ownerID uint, managePermission string) bool {
+	if ownerID == userID {
+		return true
+	}
+	return authService != nil && authService.HasPermission(userID, managePermission)
+}
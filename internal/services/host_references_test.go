@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+func TestCheckHostReferences(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	other := createTestUser(t, db, "other@example.test", models.RoleUser)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+	auth := NewAuthService("test")
+
+	ownCertificate := &models.Certificate{Name: "own", Provider: models.ProviderCustom, UserID: owner.ID}
+	foreignCertificate := &models.Certificate{Name: "foreign", Provider: models.ProviderCustom, UserID: other.ID}
+	ownAccessList := &models.AccessList{Name: "own", UserID: owner.ID}
+	deletedAccessList := &models.AccessList{Name: "deleted", UserID: owner.ID}
+	for _, record := range []interface{}{ownCertificate, foreignCertificate, ownAccessList, deletedAccessList} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Delete(deletedAccessList).Error; err != nil {
+		t.Fatal(err)
+	}
+	missing := uint(9999)
+
+	for _, tc := range []struct {
+		name          string
+		userID        uint
+		certificateID *uint
+		accessListID  *uint
+		want          error
+	}{
+		{name: "no references", userID: owner.ID},
+		{name: "own records", userID: owner.ID, certificateID: &ownCertificate.ID, accessListID: &ownAccessList.ID},
+		{name: "foreign certificate", userID: owner.ID, certificateID: &foreignCertificate.ID, want: ErrCertificateNotOwned},
+		{name: "admin with foreign certificate", userID: admin.ID, certificateID: &foreignCertificate.ID},
+		{name: "missing certificate", userID: admin.ID, certificateID: &missing, want: ErrCertificateNotFound},
+		{name: "deleted access list", userID: owner.ID, accessListID: &deletedAccessList.ID, want: ErrAccessListNotFound},
+		{name: "access list of another user", userID: other.ID, accessListID: &ownAccessList.ID, want: ErrAccessListNotOwned},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckHostReferences(db, auth, tc.userID, tc.certificateID, tc.accessListID)
+			if tc.want == nil && err != nil || tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("got %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// UnmatchedProxyHostTag is the tag used for requests whose Host header doesn't match a managed proxy host
+const UnmatchedProxyHostTag = "unmatched"
+
+// HTTPMetricsService aggregates request metrics per proxy host and
+// periodically stores them as historical metrics
+type HTTPMetricsService struct {
+	db               *gorm.DB
+	analyticsService *AnalyticsService
+
+	buckets     map[string]*httpMetricBucket
+	bucketMutex sync.Mutex
+
+	hostIndex         map[string]uint
+	hostIndexLoadedAt time.Time
+	hostIndexTTL      time.Duration
+	hostIndexMutex    sync.RWMutex
+}
+
+// httpMetricBucket accumulates request stats for a single proxy host tag
+type httpMetricBucket struct {
+	requests     int64
+	errors       int64
+	totalLatency time.Duration
+	maxLatency   time.Duration
+}
+
+// NewHTTPMetricsService creates a new HTTP metrics service
+func NewHTTPMetricsService(analyticsService *AnalyticsService) *HTTPMetricsService {
+	return &HTTPMetricsService{
+		db:               database.GetDB(),
+		analyticsService: analyticsService,
+		buckets:          make(map[string]*httpMetricBucket),
+		hostIndex:        make(map[string]uint),
+		hostIndexTTL:     time.Minute,
+	}
+}
+
+// Record records a completed request against the proxy host matching its Host
+// header and returns the tag it was recorded under
+func (s *HTTPMetricsService) Record(host string, status int, latency time.Duration) string {
+	tag := UnmatchedProxyHostTag
+	if id, ok := s.ResolveProxyHost(host); ok {
+		tag = strconv.FormatUint(uint64(id), 10)
+	}
+
+	s.bucketMutex.Lock()
+	defer s.bucketMutex.Unlock()
+
+	bucket, exists := s.buckets[tag]
+	if !exists {
+		bucket = &httpMetricBucket{}
+		s.buckets[tag] = bucket
+	}
+
+	bucket.requests++
+	if status >= 500 {
+		bucket.errors++
+	}
+	bucket.totalLatency += latency
+	if latency > bucket.maxLatency {
+		bucket.maxLatency = latency
+	}
+
+	return tag
+}
+
+// ResolveProxyHost finds the enabled proxy host serving the given Host header.
+// Exact domain matches win over wildcard ("*.example.com") matches.
+func (s *HTTPMetricsService) ResolveProxyHost(host string) (uint, bool) {
+	host = normalizeHost(host)
+	if host == "" {
+		return 0, false
+	}
+
+	s.refreshHostIndex()
+
+	s.hostIndexMutex.RLock()
+	defer s.hostIndexMutex.RUnlock()
+
+	if id, ok := s.hostIndex[host]; ok {
+		return id, true
+	}
+
+	labels := strings.Split(host, ".")
+	for i := 1; i < len(labels); i++ {
+		if id, ok := s.hostIndex["*."+strings.Join(labels[i:], ".")]; ok {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// refreshHostIndex reloads the domain to proxy host index once it is older than the TTL
+func (s *HTTPMetricsService) refreshHostIndex() {
+	s.hostIndexMutex.RLock()
+	fresh := time.Since(s.hostIndexLoadedAt) < s.hostIndexTTL
+	s.hostIndexMutex.RUnlock()
+	if fresh || s.db == nil {
+		return
+	}
+
+	var proxyHosts []models.ProxyHost
+	if err := s.db.Select("id", "domain_names").Where("enabled = ?", true).Find(&proxyHosts).Error; err != nil {
+		logger.Warn("Failed to load proxy host domains for metrics", logger.Err(err))
+		return
+	}
+
+	index := make(map[string]uint)
+	for _, proxyHost := range proxyHosts {
+		for _, domain := range proxyHost.DomainNames {
+			index[normalizeHost(domain)] = proxyHost.ID
+		}
+	}
+
+	s.hostIndexMutex.Lock()
+	s.hostIndex = index
+	s.hostIndexLoadedAt = time.Now()
+	s.hostIndexMutex.Unlock()
+}
+
+// Flush stores the accumulated request metrics and resets the counters
+func (s *HTTPMetricsService) Flush() {
+	s.bucketMutex.Lock()
+	buckets := s.buckets
+	s.buckets = make(map[string]*httpMetricBucket)
+	s.bucketMutex.Unlock()
+
+	if s.analyticsService == nil || len(buckets) == 0 {
+		return
+	}
+
+	timestamp := time.Now()
+	for tag, bucket := range buckets {
+		source := "http"
+		var sourceID *uint
+		if tag != UnmatchedProxyHostTag {
+			if id, err := strconv.ParseUint(tag, 10, 32); err == nil {
+				proxyHostID := uint(id)
+				source = "proxy_host"
+				sourceID = &proxyHostID
+			}
+		}
+
+		avgLatency := float64(bucket.totalLatency.Milliseconds()) / float64(bucket.requests)
+
+		metrics := []*models.HistoricalMetric{
+			{MetricName: "request_count", Value: float64(bucket.requests), Unit: "requests", Description: "HTTP requests"},
+			{MetricName: "error_count", Value: float64(bucket.errors), Unit: "requests", Description: "HTTP 5xx responses"},
+			{MetricName: "avg_latency_ms", Value: avgLatency, Unit: "ms", Description: "Average request latency"},
+			{MetricName: "max_latency_ms", Value: float64(bucket.maxLatency.Milliseconds()), Unit: "ms", Description: "Maximum request latency"},
+		}
+
+		for _, metric := range metrics {
+			metric.Timestamp = timestamp
+			metric.MetricType = "http"
+			metric.Source = source
+			metric.SourceID = sourceID
+			metric.Tags = models.JSON{"proxy_host_id": tag}
+
+			if err := s.analyticsService.StoreMetric(metric); err != nil {
+				logger.Error("Failed to store HTTP metric",
+					logger.String("metric_name", metric.MetricName),
+					logger.String("proxy_host_id", tag),
+					logger.Err(err))
+			}
+		}
+	}
+}
+
+// StartFlusher periodically flushes accumulated request metrics
+func (s *HTTPMetricsService) StartFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.Flush()
+			return
+		case <-ticker.C:
+			s.Flush()
+		}
+	}
+}
+
+// normalizeHost lowercases a Host header value and strips any port
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+var (
+	ErrImpersonateAdmin = errors.New("admin users cannot be impersonated")
+	ErrImpersonateSelf  = errors.New("cannot impersonate yourself")
+)
+
+// ImpersonationResponse represents a token issued to act as another user
+type ImpersonationResponse struct {
+	User           *models.User `json:"user"`
+	Token          string       `json:"token"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	ImpersonatorID uint         `json:"impersonator_id"`
+}
+
+// Impersonate issues a short-lived access token that acts as targetUserID on
+// behalf of the admin impersonatorID. Admins cannot be impersonated. The token
+// is only returned once the audit log entry has been written.
+func (s *AuthService) Impersonate(impersonatorID, targetUserID uint, duration time.Duration, ipAddress, userAgent string) (*ImpersonationResponse, error) {
+	if err := s.RequireAdmin(impersonatorID); err != nil {
+		return nil, err
+	}
+	if impersonatorID == targetUserID {
+		return nil, ErrImpersonateSelf
+	}
+
+	target, err := s.GetActiveUser(targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsAdmin() {
+		return nil, ErrImpersonateAdmin
+	}
+
+	expiresAt := time.Now().Add(duration)
+	claims := &JWTClaims{
+		UserID:         target.ID,
+		Email:          target.Email,
+		Roles:          target.Roles,
+		ImpersonatorID: impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "nginx-manager",
+			Subject:   fmt.Sprintf("%d", target.ID),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.jwtSecret))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&models.AuditLog{
+		UserID:      impersonatorID,
+		Action:      models.ActionImpersonated,
+		ObjectType:  models.ObjectTypeUser,
+		ObjectID:    target.ID,
+		Description: fmt.Sprintf("Started impersonating %s until %s", target.Email, expiresAt.UTC().Format(time.RFC3339)),
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Meta: models.JSON{
+			"impersonator_id": impersonatorID,
+			"target_user_id":  target.ID,
+			"expires_at":      expiresAt,
+		},
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to record impersonation: %w", err)
+	}
+
+	logger.Warn("Admin started impersonating user",
+		logger.Uint("impersonator_id", impersonatorID),
+		logger.Uint("target_user_id", target.ID),
+		logger.String("ip_address", ipAddress))
+
+	return &ImpersonationResponse{
+		User:           target,
+		Token:          token,
+		ExpiresAt:      expiresAt,
+		ImpersonatorID: impersonatorID,
+	}, nil
+}
+
+// ValidateImpersonator checks that the admin behind an impersonation token
+// still exists and is still an admin, so revoking the role ends the session
+func (s *AuthService) ValidateImpersonator(claims *JWTClaims) error {
+	if !claims.IsImpersonation() {
+		return nil
+	}
+	if err := s.RequireAdmin(claims.ImpersonatorID); err != nil {
+		return ErrTokenInvalid
+	}
+	return nil
+}
+
+// LogImpersonatedRequest records a request made with an impersonation token
+func (s *AuthService) LogImpersonatedRequest(claims *JWTClaims, method, path string, status int, ipAddress, userAgent string) {
+	auditLog := &models.AuditLog{
+		UserID:      claims.ImpersonatorID,
+		Action:      models.ActionImpersonatedRequest,
+		ObjectType:  models.ObjectTypeUser,
+		ObjectID:    claims.UserID,
+		Description: fmt.Sprintf("%s %s as %s (%d)", method, path, claims.Email, status),
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Meta: models.JSON{
+			"impersonator_id": claims.ImpersonatorID,
+			"target_user_id":  claims.UserID,
+			"method":          method,
+			"path":            path,
+			"status":          status,
+		},
+	}
+
+	if err := s.db.Create(auditLog).Error; err != nil {
+		logger.Error("Failed to create audit log", logger.Err(err), logger.Uint("impersonator_id", claims.ImpersonatorID))
+	}
+}
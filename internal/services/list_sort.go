@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Sort orders accepted by list endpoints
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// ConfigSortFields are the columns configurations can be sorted by
+var ConfigSortFields = []string{"name", "created_at", "updated_at"}
+
+// TemplateSortFields are the columns templates can be sorted by
+var TemplateSortFields = []string{"name", "created_at", "updated_at", "usage_count"}
+
+// ListSort is the sort applied to a list, returned so clients can show it
+type ListSort struct {
+	Field string `json:"field"`
+	Order string `json:"order"`
+}
+
+// defaultListSort keeps lists in the order they were created
+var defaultListSort = ListSort{Field: "created_at", Order: SortOrderAsc}
+
+// NewListSort validates a requested sort against the allowed columns, which
+// are the only values ever placed in the ORDER BY clause. Without a field the
+// list keeps its creation order; without an order names sort ascending and
+// dates and counts descending, so the newest or most used come first.
+func NewListSort(field, order string, allowed []string) (ListSort, error) {
+	field = strings.ToLower(strings.TrimSpace(field))
+	order = strings.ToLower(strings.TrimSpace(order))
+
+	if field == "" {
+		field = defaultListSort.Field
+		if order == "" {
+			order = defaultListSort.Order
+		}
+	}
+	if !containsString(allowed, field) {
+		return ListSort{}, fmt.Errorf("invalid sort field %q, expected one of: %s", field, strings.Join(allowed, ", "))
+	}
+
+	switch order {
+	case "":
+		order = SortOrderDesc
+		if field == "name" {
+			order = SortOrderAsc
+		}
+	case SortOrderAsc, SortOrderDesc:
+	default:
+		return ListSort{}, fmt.Errorf("invalid sort order %q, expected asc or desc", order)
+	}
+
+	return ListSort{Field: field, Order: order}, nil
+}
+
+// apply orders query by the sort, breaking ties by ID so pages are stable
+func (listSort ListSort) apply(query *gorm.DB) *gorm.DB {
+	if listSort.Field == "" {
+		listSort = defaultListSort
+	}
+	order := strings.ToUpper(listSort.Order)
+	return query.Order(listSort.Field + " " + order).Order("id " + order)
+}
+
+// searchNameDescription filters query to rows whose name or description
+// contains search, ignoring case
+func searchNameDescription(query *gorm.DB, search string) *gorm.DB {
+	if search = strings.TrimSpace(search); search == "" {
+		return query
+	}
+	pattern := "%" + strings.ToLower(search) + "%"
+	return query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?", pattern, pattern)
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAggregationWindow = errors.New("unknown aggregation window")
+	ErrInvalidBackfillRange     = errors.New("backfill needs a start time before its end time")
+)
+
+// aggregationWindows are the time windows metrics are rolled up into, from
+// finest to coarsest
+var aggregationWindows = []string{"5m", "1h", "1d", "1w"}
+
+// AggregationWindowStatus reports the rollups stored for one time window
+type AggregationWindowStatus struct {
+	Window string     `json:"window"`
+	Count  int64      `json:"count"`
+	Oldest *time.Time `json:"oldest,omitempty"`
+	Newest *time.Time `json:"newest,omitempty"`
+}
+
+// AggregationStatus reports how far a metric's rollups reach compared with
+// its newest raw sample
+type AggregationStatus struct {
+	MetricType   string                    `json:"metric_type"`
+	MetricName   string                    `json:"metric_name"`
+	LatestSample *time.Time                `json:"latest_sample,omitempty"`
+	Windows      []AggregationWindowStatus `json:"windows"`
+}
+
+// AggregationBackfillRequest selects the rollups to rebuild. Without a metric
+// name every metric of the type is backfilled; without windows every window.
+type AggregationBackfillRequest struct {
+	MetricType string    `json:"metric_type" binding:"required"`
+	MetricName string    `json:"metric_name"`
+	TimeRange  TimeRange `json:"time_range"`
+	Windows    []string  `json:"windows"`
+}
+
+// AggregationBackfillResult reports the rollups a backfill rebuilt
+type AggregationBackfillResult struct {
+	Windows []string `json:"windows"`
+	Metrics int      `json:"metrics"`
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+}
+
+// GetAggregationStatus reports the stored rollups of a metric for each window
+func (as *AnalyticsService) GetAggregationStatus(metricType, metricName string) (*AggregationStatus, error) {
+	status := &AggregationStatus{
+		MetricType: metricType,
+		MetricName: metricName,
+		Windows:    make([]AggregationWindowStatus, 0, len(aggregationWindows)),
+	}
+
+	var latest []time.Time
+	if err := as.metricsQuery().
+		Where("metric_type = ? AND metric_name = ?", metricType, metricName).
+		Order("timestamp DESC").Limit(1).
+		Pluck("timestamp", &latest).Error; err != nil {
+		return nil, err
+	}
+	if len(latest) > 0 {
+		status.LatestSample = &latest[0]
+	}
+
+	for _, window := range aggregationWindows {
+		windowStatus := AggregationWindowStatus{Window: window}
+		query := func() *gorm.DB {
+			return as.db.Model(&models.MetricAggregation{}).
+				Where("metric_type = ? AND metric_name = ? AND time_window = ?", metricType, metricName, window)
+		}
+
+		if err := query().Count(&windowStatus.Count).Error; err != nil {
+			return nil, err
+		}
+		if windowStatus.Count > 0 {
+			var oldest, newest models.MetricAggregation
+			if err := query().Order("timestamp ASC").First(&oldest).Error; err != nil {
+				return nil, err
+			}
+			if err := query().Order("timestamp DESC").First(&newest).Error; err != nil {
+				return nil, err
+			}
+			windowStatus.Oldest = &oldest.Timestamp
+			windowStatus.Newest = &newest.Timestamp
+		}
+		status.Windows = append(status.Windows, windowStatus)
+	}
+
+	return status, nil
+}
+
+// BackfillAggregations rebuilds the rollups of a metric for every window that
+// holds raw samples in timeRange, filling gaps left while the server was down
+// or for a newly added window. An empty metricName backfills every metric of
+// metricType and no windows means all of them.
+func (as *AnalyticsService) BackfillAggregations(metricType, metricName string, timeRange TimeRange, windows []string) (*AggregationBackfillResult, error) {
+	if timeRange.Start.IsZero() || timeRange.End.IsZero() || !timeRange.Start.Before(timeRange.End) {
+		return nil, ErrInvalidBackfillRange
+	}
+	if len(windows) == 0 {
+		windows = aggregationWindows
+	}
+	for _, window := range windows {
+		if !containsString(aggregationWindows, window) {
+			return nil, fmt.Errorf("%w %q, expected one of: %s", ErrInvalidAggregationWindow, window, strings.Join(aggregationWindows, ", "))
+		}
+	}
+
+	metricNames := []string{metricName}
+	if metricName == "" {
+		metricNames = nil
+		if err := as.metricsQuery().
+			Where("metric_type = ? AND timestamp BETWEEN ? AND ?", metricType, timeRange.Start, timeRange.End).
+			Distinct("metric_name").
+			Pluck("metric_name", &metricNames).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	result := &AggregationBackfillResult{Windows: windows, Metrics: len(metricNames)}
+	for _, name := range metricNames {
+		var timestamps []time.Time
+		if err := as.metricsQuery().
+			Where("metric_type = ? AND metric_name = ? AND timestamp BETWEEN ? AND ?", metricType, name, timeRange.Start, timeRange.End).
+			Pluck("timestamp", &timestamps).Error; err != nil {
+			return nil, err
+		}
+
+		for _, window := range windows {
+			for _, windowStart := range as.windowStarts(timestamps, window) {
+				as.aggregationMutex.Lock()
+				created, err := as.upsertAggregation(metricType, name, window, windowStart)
+				as.aggregationMutex.Unlock()
+				if err != nil {
+					return nil, err
+				}
+				if created {
+					result.Created++
+				} else {
+					result.Updated++
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// rollUpExpiringMetrics makes sure every window holding the raw samples
+// selected by expiring has an aggregation before the samples are deleted, so
+// cleanup never loses data that was not rolled up. Windows whose aggregation
+// would already have expired are skipped. It returns the number of
+// aggregations created.
+func (as *AnalyticsService) rollUpExpiringMetrics(expiring *gorm.DB) (int, error) {
+	type windowKey struct {
+		metricType, metricName, window string
+		start                          int64
+	}
+	now := time.Now()
+	windows := make(map[windowKey]time.Time)
+
+	var batch []struct {
+		ID         uint64
+		MetricType string
+		MetricName string
+		Timestamp  time.Time
+	}
+	err := expiring.Select("id", "metric_type", "metric_name", "timestamp").
+		FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+			for _, sample := range batch {
+				for _, window := range aggregationWindows {
+					start := as.getWindowStart(sample.Timestamp, window)
+					if start.Add(as.windowRetention(sample.MetricType, sample.MetricName, window)).Before(now) {
+						continue
+					}
+					windows[windowKey{sample.MetricType, sample.MetricName, window, start.UnixNano()}] = start
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for key, start := range windows {
+		var count int64
+		if err := as.db.Model(&models.MetricAggregation{}).
+			Where("metric_type = ? AND metric_name = ? AND time_window = ? AND timestamp = ?",
+				key.metricType, key.metricName, key.window, start).
+			Count(&count).Error; err != nil {
+			return created, err
+		}
+		if count > 0 {
+			continue
+		}
+
+		as.aggregationMutex.Lock()
+		_, err := as.upsertAggregation(key.metricType, key.metricName, key.window, start)
+		as.aggregationMutex.Unlock()
+		if err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
+
+// windowStarts returns the distinct starts of the windows holding timestamps,
+// in order
+func (as *AnalyticsService) windowStarts(timestamps []time.Time, window string) []time.Time {
+	seen := make(map[int64]bool)
+	var starts []time.Time
+	for _, timestamp := range timestamps {
+		start := as.getWindowStart(timestamp, window)
+		if !seen[start.UnixNano()] {
+			seen[start.UnixNano()] = true
+			starts = append(starts, start)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+// addToAggregation adds the value of a new sample to the running statistics
+// of its window's aggregation, so each sample costs a row update instead of
+// a scan of the whole window
+func (as *AnalyticsService) addToAggregation(metric *models.HistoricalMetric, timeWindow string) error {
+	as.aggregationMutex.Lock()
+	defer as.aggregationMutex.Unlock()
+
+	windowStart := as.getWindowStart(metric.Timestamp, timeWindow)
+
+	var agg models.MetricAggregation
+	err := as.db.Where("metric_type = ? AND metric_name = ? AND time_window = ? AND timestamp = ?",
+		metric.MetricType, metric.MetricName, timeWindow, windowStart).First(&agg).Error
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		agg = models.MetricAggregation{
+			MetricType: metric.MetricType,
+			MetricName: metric.MetricName,
+			TimeWindow: timeWindow,
+			Timestamp:  windowStart,
+		}
+		agg.SetRetention(as.windowRetention(metric.MetricType, metric.MetricName, timeWindow))
+	case err != nil:
+		return err
+	case agg.Count > 0 && len(agg.Digest) == 0:
+		// Aggregations written before incremental updates have no running
+		// state, so they are recalculated once from the raw samples
+		_, err := as.upsertAggregation(metric.MetricType, metric.MetricName, timeWindow, windowStart)
+		return err
+	}
+
+	addAggregationSample(&agg, metric.Value)
+	return as.db.Save(&agg).Error
+}
+
+// addAggregationSample updates the count, sum, extremes, average, standard
+// deviation and estimated percentiles of an aggregation with one more value
+func addAggregationSample(agg *models.MetricAggregation, value float64) {
+	agg.Count++
+	agg.Sum += value
+	agg.SumSquares += value * value
+
+	if agg.Count == 1 || value < agg.Min {
+		agg.Min = value
+	}
+	if agg.Count == 1 || value > agg.Max {
+		agg.Max = value
+	}
+
+	count := float64(agg.Count)
+	agg.Avg = agg.Sum / count
+	// Rounding can leave a tiny negative variance for constant values
+	agg.StdDev = math.Sqrt(math.Max(agg.SumSquares/count-agg.Avg*agg.Avg, 0))
+
+	agg.Digest = addDigestValue(agg.Digest, value)
+	agg.P50 = digestPercentile(agg.Digest, 0.5)
+	agg.P95 = digestPercentile(agg.Digest, 0.95)
+	agg.P99 = digestPercentile(agg.Digest, 0.99)
+}
+
+// setPercentiles sets the exact percentiles of an aggregation from the sorted
+// values of its window, and the digest later samples are added to
+func (as *AnalyticsService) setPercentiles(agg *models.MetricAggregation, sortedValues []float64) {
+	agg.P50 = as.percentile(sortedValues, 0.5)
+	agg.P95 = as.percentile(sortedValues, 0.95)
+	agg.P99 = as.percentile(sortedValues, 0.99)
+	agg.Digest = newPercentileDigest(sortedValues)
+}
+
+// upsertAggregation recalculates the rollup of a metric for the window
+// starting at windowStart from its raw samples, creating it if needed
+func (as *AnalyticsService) upsertAggregation(metricType, metricName, timeWindow string, windowStart time.Time) (bool, error) {
+	windowEnd := as.getWindowEnd(windowStart, timeWindow)
+
+	// Check if aggregation already exists
+	var existingAgg models.MetricAggregation
+	err := as.db.Where("metric_type = ? AND metric_name = ? AND time_window = ? AND timestamp = ?",
+		metricType, metricName, timeWindow, windowStart).First(&existingAgg).Error
+
+	switch err {
+	case gorm.ErrRecordNotFound:
+		// Create new aggregation
+		agg := &models.MetricAggregation{
+			MetricType: metricType,
+			MetricName: metricName,
+			TimeWindow: timeWindow,
+			Timestamp:  windowStart,
+		}
+
+		// Calculate aggregation values
+		as.calculateAggregationValues(agg, windowStart, windowEnd)
+
+		// Set retention (longer for aggregated data)
+		retentionDuration := as.windowRetention(metricType, metricName, timeWindow)
+		agg.SetRetention(retentionDuration)
+
+		return true, as.db.Create(agg).Error
+	case nil:
+		// Update existing aggregation
+		as.calculateAggregationValues(&existingAgg, windowStart, windowEnd)
+		return false, as.db.Save(&existingAgg).Error
+	default:
+		return false, err
+	}
+}
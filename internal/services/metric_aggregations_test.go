@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// newTestAnalyticsService returns an AnalyticsService on a test database
+// holding the metric tables, which AutoMigrate does not create
+func newTestAnalyticsService(t testing.TB) (*AnalyticsService, *gorm.DB) {
+	t.Helper()
+
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&models.HistoricalMetric{}, &models.MetricAggregation{}); err != nil {
+		t.Fatalf("migrate metrics: %v", err)
+	}
+	return NewAnalyticsService(db, nil, nil), db
+}
+
+// TestAddToAggregationIsIncremental adds samples that are not stored as raw
+// metrics: the statistics must come from the aggregation row alone
+func TestAddToAggregationIsIncremental(t *testing.T) {
+	as, db := newTestAnalyticsService(t)
+	start := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 500; i++ {
+		metric := &models.HistoricalMetric{
+			MetricType: "system",
+			MetricName: "cpu_usage",
+			Value:      float64(i),
+			Timestamp:  start.Add(time.Duration(i) * 100 * time.Millisecond),
+		}
+		if err := as.addToAggregation(metric, "5m"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var agg models.MetricAggregation
+	if err := db.Where("metric_type = ? AND metric_name = ? AND time_window = ?", "system", "cpu_usage", "5m").
+		First(&agg).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if agg.Count != 500 || agg.Sum != 125250 || agg.Min != 1 || agg.Max != 500 || agg.Avg != 250.5 {
+		t.Errorf("got count %d sum %v min %v max %v avg %v", agg.Count, agg.Sum, agg.Min, agg.Max, agg.Avg)
+	}
+	for _, tc := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"p50", agg.P50, 250.5},
+		{"p95", agg.P95, 475.05},
+		{"p99", agg.P99, 495.01},
+		{"std_dev", agg.StdDev, math.Sqrt((500*500 - 1) / 12.0)},
+	} {
+		if math.Abs(tc.got-tc.want) > 5 {
+			t.Errorf("%s: got %v, want about %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+// BenchmarkAggregateSample compares adding a sample to a window that holds
+// 1000 samples by recalculating the window from its raw samples, as every
+// sample used to, with the incremental update
+func BenchmarkAggregateSample(b *testing.B) {
+	const samplesPerWindow = 1000
+	start := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	setup := func(b *testing.B) *AnalyticsService {
+		as, db := newTestAnalyticsService(b)
+		metrics := make([]models.HistoricalMetric, samplesPerWindow)
+		for i := range metrics {
+			metrics[i] = models.HistoricalMetric{
+				MetricType: "system",
+				MetricName: "cpu_usage",
+				Value:      float64(i % 97),
+				Timestamp:  start.Add(time.Duration(i) * 100 * time.Millisecond),
+			}
+		}
+		if err := db.CreateInBatches(metrics, 200).Error; err != nil {
+			b.Fatal(err)
+		}
+		if _, err := as.upsertAggregation("system", "cpu_usage", "5m", start); err != nil {
+			b.Fatal(err)
+		}
+		return as
+	}
+
+	sample := &models.HistoricalMetric{
+		MetricType: "system",
+		MetricName: "cpu_usage",
+		Value:      50,
+		Timestamp:  start.Add(time.Minute),
+	}
+
+	b.Run(fmt.Sprintf("recalculate/%d", samplesPerWindow), func(b *testing.B) {
+		as := setup(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := as.upsertAggregation(sample.MetricType, sample.MetricName, "5m", start); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run(fmt.Sprintf("incremental/%d", samplesPerWindow), func(b *testing.B) {
+		as := setup(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := as.addToAggregation(sample, "5m"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
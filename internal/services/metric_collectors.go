@@ -0,0 +1,323 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// MetricCollectorsSettingID is the setting holding the enabled collector names
+const MetricCollectorsSettingID = "metric-collectors"
+
+var ErrInvalidMetricCollector = errors.New("unknown metric collector")
+
+// MetricCollector turns one part of a system metrics sample into stored series
+type MetricCollector struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	Metrics        []string `json:"metrics"`
+	DefaultEnabled bool     `json:"default_enabled"`
+
+	collect func(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric
+	// collectNginx replaces collect for collectors fed by the nginx status
+	collectNginx func(status *NginxStatus, timestamp time.Time) []*models.HistoricalMetric
+}
+
+// MetricCollectorStatus reports a collector and whether it is enabled
+type MetricCollectorStatus struct {
+	MetricCollector
+	Enabled bool `json:"enabled"`
+}
+
+// metricCollectors lists every collector StoreSystemMetrics can run, in the
+// order their series are stored. The defaults match what was stored before
+// collectors became configurable.
+var metricCollectors = []MetricCollector{
+	{
+		Name:           "cpu",
+		Description:    "Overall CPU usage",
+		Metrics:        []string{"system.cpu_usage"},
+		DefaultEnabled: true,
+		collect:        collectCPUUsage,
+	},
+	{
+		Name:           "cpu_per_core",
+		Description:    "CPU usage of each core",
+		Metrics:        []string{"system.cpu_core_<n>_usage"},
+		DefaultEnabled: true,
+		collect:        collectCPUPerCore,
+	},
+	{
+		Name:           "load",
+		Description:    "1, 5 and 15 minute load averages",
+		Metrics:        []string{"system.load_avg_1", "system.load_avg_5", "system.load_avg_15"},
+		DefaultEnabled: true,
+		collect:        collectLoad,
+	},
+	{
+		Name:           "memory",
+		Description:    "Memory usage",
+		Metrics:        []string{"system.memory_usage", "system.memory_used_bytes"},
+		DefaultEnabled: true,
+		collect:        collectMemory,
+	},
+	{
+		Name:           "disk",
+		Description:    "Disk usage of each mount point",
+		Metrics:        []string{"system.disk_usage", "system.disk_used_bytes"},
+		DefaultEnabled: true,
+		collect:        collectDisk,
+	},
+	{
+		Name:           "network",
+		Description:    "Network receive and transmit throughput",
+		Metrics:        []string{"network.bytes_in_rate", "network.bytes_out_rate"},
+		DefaultEnabled: true,
+		collect:        collectNetworkRate,
+	},
+	{
+		Name:           "network_totals",
+		Description:    "Bytes received and sent since boot",
+		Metrics:        []string{"network.bytes_recv_total", "network.bytes_sent_total"},
+		DefaultEnabled: false,
+		collect:        collectNetworkTotals,
+	},
+	{
+		Name:           "process",
+		Description:    "Goroutines in the manager process",
+		Metrics:        []string{"system.goroutines"},
+		DefaultEnabled: true,
+		collect:        collectProcess,
+	},
+	{
+		Name:           "gc",
+		Description:    "Garbage collection runs in the manager process",
+		Metrics:        []string{"system.gc_runs"},
+		DefaultEnabled: false,
+		collect:        collectGC,
+	},
+	{
+		Name:           "nginx",
+		Description:    "nginx connections and requests from stub_status",
+		Metrics:        []string{"nginx.active_connections", "nginx.reading", "nginx.writing", "nginx.waiting", "nginx.requests_total"},
+		DefaultEnabled: true,
+		collectNginx:   collectNginxStubStatus,
+	},
+}
+
+// DefaultMetricCollectors returns the collector names enabled on a fresh install
+func DefaultMetricCollectors() []string {
+	var names []string
+	for _, collector := range metricCollectors {
+		if collector.DefaultEnabled {
+			names = append(names, collector.Name)
+		}
+	}
+	return names
+}
+
+// GetMetricCollectors lists every collector and whether it is enabled
+func (as *AnalyticsService) GetMetricCollectors() ([]MetricCollectorStatus, error) {
+	enabled, err := as.enabledMetricCollectors()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MetricCollectorStatus, len(metricCollectors))
+	for i, collector := range metricCollectors {
+		statuses[i] = MetricCollectorStatus{
+			MetricCollector: collector,
+			Enabled:         containsString(enabled, collector.Name),
+		}
+	}
+	return statuses, nil
+}
+
+// SetMetricCollectors saves the enabled collector set. Series that are
+// disabled stop being stored; samples already stored are kept.
+func (as *AnalyticsService) SetMetricCollectors(names []string) error {
+	var enabled []interface{}
+	for _, name := range names {
+		if findMetricCollector(name) == nil {
+			return fmt.Errorf("%w: %s", ErrInvalidMetricCollector, name)
+		}
+		if !containsInterface(enabled, name) {
+			enabled = append(enabled, name)
+		}
+	}
+	if enabled == nil {
+		enabled = []interface{}{}
+	}
+
+	setting := models.Setting{ID: MetricCollectorsSettingID}
+	if err := as.db.Where("id = ?", setting.ID).First(&setting).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	setting.Name = "Metric Collectors"
+	setting.SetValue(enabled)
+	return as.db.Save(&setting).Error
+}
+
+// enabledMetricCollectors reads the enabled collector names from settings,
+// falling back to the defaults when the setting has not been saved
+func (as *AnalyticsService) enabledMetricCollectors() ([]string, error) {
+	var setting models.Setting
+	if err := as.db.Where("id = ?", MetricCollectorsSettingID).First(&setting).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return DefaultMetricCollectors(), nil
+		}
+		return nil, err
+	}
+
+	values, ok := setting.GetValue().([]interface{})
+	if !ok {
+		return DefaultMetricCollectors(), nil
+	}
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		if name, ok := value.(string); ok && findMetricCollector(name) != nil {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// findMetricCollector looks up a collector by name
+func findMetricCollector(name string) *MetricCollector {
+	for i := range metricCollectors {
+		if metricCollectors[i].Name == name {
+			return &metricCollectors[i]
+		}
+	}
+	return nil
+}
+
+// containsInterface reports whether values holds value
+func containsInterface(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// systemMetric builds a system-sourced historical metric
+func systemMetric(timestamp time.Time, metricType, name string, value float64, unit, description string, tags models.JSON) *models.HistoricalMetric {
+	return &models.HistoricalMetric{
+		Timestamp:   timestamp,
+		MetricType:  metricType,
+		MetricName:  name,
+		Value:       value,
+		Unit:        unit,
+		Tags:        tags,
+		Source:      "system",
+		Description: description,
+	}
+}
+
+// collectNginxStubStatus stores the stub_status counters when they could be scraped
+func collectNginxStubStatus(status *NginxStatus, timestamp time.Time) []*models.HistoricalMetric {
+	stub := status.StubStatus
+	if stub == nil {
+		return nil
+	}
+
+	metrics := []*models.HistoricalMetric{
+		systemMetric(timestamp, "nginx", "active_connections", float64(stub.Active), "count", "Active client connections", nil),
+		systemMetric(timestamp, "nginx", "reading", float64(stub.Reading), "count", "Connections reading the request header", nil),
+		systemMetric(timestamp, "nginx", "writing", float64(stub.Writing), "count", "Connections writing the response", nil),
+		systemMetric(timestamp, "nginx", "waiting", float64(stub.Waiting), "count", "Idle keepalive connections", nil),
+		systemMetric(timestamp, "nginx", "requests_total", float64(stub.Requests), "count", "Client requests since nginx started", nil),
+	}
+	for _, metric := range metrics {
+		metric.Source = "nginx"
+	}
+	return metrics
+}
+
+// collectCPUUsage stores usage only when the platform provides a real measurement
+func collectCPUUsage(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric {
+	if !metrics.CPU.UsageAvailable {
+		return nil
+	}
+	return []*models.HistoricalMetric{
+		systemMetric(timestamp, "system", "cpu_usage", metrics.CPU.Usage, "percent", "CPU usage percentage", nil),
+	}
+}
+
+func collectCPUPerCore(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric {
+	if !metrics.CPU.UsageAvailable {
+		return nil
+	}
+	var result []*models.HistoricalMetric
+	for core, usage := range metrics.CPU.PerCore {
+		result = append(result, systemMetric(timestamp, "system", fmt.Sprintf("cpu_core_%d_usage", core), usage, "percent",
+			fmt.Sprintf("CPU core %d usage percentage", core), models.JSON{"core": core}))
+	}
+	return result
+}
+
+func collectLoad(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric {
+	return []*models.HistoricalMetric{
+		systemMetric(timestamp, "system", "load_avg_1", metrics.CPU.LoadAvg1, "load", "1-minute load average", nil),
+		systemMetric(timestamp, "system", "load_avg_5", metrics.CPU.LoadAvg5, "load", "5-minute load average", nil),
+		systemMetric(timestamp, "system", "load_avg_15", metrics.CPU.LoadAvg15, "load", "15-minute load average", nil),
+	}
+}
+
+func collectMemory(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric {
+	return []*models.HistoricalMetric{
+		systemMetric(timestamp, "system", "memory_usage", metrics.Memory.UsedPercent, "percent", "Memory usage percentage", nil),
+		systemMetric(timestamp, "system", "memory_used_bytes", float64(metrics.Memory.Used), "bytes", "Memory used in bytes", nil),
+	}
+}
+
+// collectDisk stores one series per mount point
+func collectDisk(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric {
+	var result []*models.HistoricalMetric
+	for _, disk := range metrics.Disks {
+		tags := models.JSON{"mount": disk.MountPoint}
+		result = append(result,
+			systemMetric(timestamp, "system", "disk_usage", disk.UsedPercent, "percent", "Disk usage percentage", tags),
+			systemMetric(timestamp, "system", "disk_used_bytes", float64(disk.Used), "bytes", "Disk used in bytes", tags),
+		)
+	}
+	return result
+}
+
+// collectNetworkRate stores throughput once a previous sample exists to derive rates from
+func collectNetworkRate(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric {
+	if !metrics.Network.RateAvailable {
+		return nil
+	}
+	tags := models.JSON{"interfaces": strings.Join(metrics.Network.Interfaces, ",")}
+	return []*models.HistoricalMetric{
+		systemMetric(timestamp, "network", "bytes_in_rate", metrics.Network.BytesRecvPerSec, "bytes/sec", "Network receive throughput", tags),
+		systemMetric(timestamp, "network", "bytes_out_rate", metrics.Network.BytesSentPerSec, "bytes/sec", "Network transmit throughput", tags),
+	}
+}
+
+func collectNetworkTotals(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric {
+	tags := models.JSON{"interfaces": strings.Join(metrics.Network.Interfaces, ",")}
+	return []*models.HistoricalMetric{
+		systemMetric(timestamp, "network", "bytes_recv_total", float64(metrics.Network.BytesRecv), "bytes", "Bytes received since boot", tags),
+		systemMetric(timestamp, "network", "bytes_sent_total", float64(metrics.Network.BytesSent), "bytes", "Bytes sent since boot", tags),
+	}
+}
+
+func collectProcess(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric {
+	return []*models.HistoricalMetric{
+		systemMetric(timestamp, "system", "goroutines", float64(metrics.Process.Goroutines), "count", "Number of Go routines", nil),
+	}
+}
+
+func collectGC(metrics *SystemMetrics, timestamp time.Time) []*models.HistoricalMetric {
+	return []*models.HistoricalMetric{
+		systemMetric(timestamp, "system", "gc_runs", float64(metrics.Process.GCRuns), "count", "Completed garbage collection cycles", nil),
+	}
+}
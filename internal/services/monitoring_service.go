@@ -1,44 +1,145 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/nguyendkn/nginx-manager/internal/models"
 	"github.com/nguyendkn/nginx-manager/pkg/logger"
 )
 
 // MonitoringService handles system monitoring and real-time metrics
 type MonitoringService struct {
-	startTime    time.Time
-	connections  map[string]*websocket.Conn
-	upgrader     websocket.Upgrader
-	nginxService *NginxService
+	startTime           time.Time
+	connections         map[string]*wsClient
+	connMutex           sync.RWMutex
+	upgrader            websocket.Upgrader
+	allowedOrigins      []string // nil allows every origin
+	nginxService        *NginxService
+	nginxRunner         NginxRunner
+	diskExcludedFSTypes map[string]bool
+	netInterfaces       map[string]bool // nil includes every interface except loopback
+
+	lastNetSample *netSample
+	netMutex      sync.Mutex
+
+	// nginxStatus is shared by every caller until it is older than nginxStatusTTL
+	nginxStatus      *NginxStatus
+	nginxStatusAt    time.Time
+	nginxStatusTTL   time.Duration
+	nginxStatusMutex sync.Mutex
+
+	// stubStatusURL is the stub_status page scraped for connection stats
+	stubStatusURL    string
+	stubStatusClient *http.Client
+
+	errorLogPath  string
+	errorLogMutex sync.Mutex
+
+	// pongWait is how long a WebSocket client may go without answering a ping
+	pongWait time.Duration
+}
+
+// DefaultNginxStatusCacheTTL is how long a polled nginx status is reused
+const DefaultNginxStatusCacheTTL = 5 * time.Second
+
+// DefaultNginxErrorLogPath is where nginx writes its error log by default
+const DefaultNginxErrorLogPath = "/var/log/nginx/error.log"
+
+// wsClient is an authenticated WebSocket connection. gorilla/websocket allows
+// only one concurrent writer, so writes are serialized with writeMutex.
+type wsClient struct {
+	conn       *websocket.Conn
+	userID     uint
+	isAdmin    bool
+	writeMutex sync.Mutex
+
+	subscriptions map[string]time.Duration // topic -> push interval; alerts are pushed as they happen
+	lastSent      map[string]time.Time
+	subMutex      sync.Mutex
+}
+
+// WebSocket topics a client can subscribe to
+const (
+	TopicMetrics     = "metrics"
+	TopicNginxStatus = "nginx_status"
+	TopicAlerts      = "alerts"
+	TopicActivity    = "activity"
+	TopicErrorLog    = "error_log" // admins only
+)
+
+var wsTopics = []string{TopicMetrics, TopicNginxStatus, TopicAlerts, TopicActivity, TopicErrorLog}
+
+const (
+	wsDefaultTopicInterval = 5 * time.Second
+	wsMinTopicInterval     = time.Second
+	wsMaxTopicInterval     = time.Hour
+)
+
+// wsControlMessage is a control message sent by a WebSocket client, e.g.
+// {"action":"subscribe","topics":["nginx_status"],"intervals":{"nginx_status":10}}
+type wsControlMessage struct {
+	Action    string         `json:"action"`
+	Topics    []string       `json:"topics"`
+	Intervals map[string]int `json:"intervals"` // seconds per topic
+}
+
+// netSample is a snapshot of cumulative interface counters used to derive rates
+type netSample struct {
+	takenAt   time.Time
+	bytesRecv uint64
+	bytesSent uint64
+}
+
+// DefaultDiskExcludedFSTypes are pseudo filesystems left out of disk stats.
+// overlay is kept since it is the root filesystem inside Docker containers.
+var DefaultDiskExcludedFSTypes = []string{
+	"tmpfs", "devtmpfs", "proc", "sysfs", "cgroup", "cgroup2", "devpts", "mqueue",
+	"debugfs", "tracefs", "securityfs", "pstore", "bpf", "configfs", "fusectl",
+	"hugetlbfs", "autofs", "binfmt_misc", "rpc_pipefs", "nsfs", "squashfs",
 }
 
 // SystemMetrics represents comprehensive system metrics
 type SystemMetrics struct {
-	Timestamp time.Time `json:"timestamp"`
-	CPU       CPUStats  `json:"cpu"`
-	Memory    MemStats  `json:"memory"`
-	Disk      DiskStats `json:"disk"`
-	Network   NetStats  `json:"network"`
-	Process   ProcStats `json:"process"`
+	Timestamp time.Time   `json:"timestamp"`
+	CPU       CPUStats    `json:"cpu"`
+	Memory    MemStats    `json:"memory"`
+	Disk      DiskStats   `json:"disk"`  // root filesystem
+	Disks     []DiskStats `json:"disks"` // every mounted filesystem
+	Network   NetStats    `json:"network"`
+	Process   ProcStats   `json:"process"`
 }
 
 // CPUStats represents CPU usage statistics
 type CPUStats struct {
-	Usage     float64 `json:"usage"`
-	LoadAvg1  float64 `json:"load_avg_1"`
-	LoadAvg5  float64 `json:"load_avg_5"`
-	LoadAvg15 float64 `json:"load_avg_15"`
+	Usage          float64   `json:"usage"`
+	PerCore        []float64 `json:"per_core"`
+	UsageAvailable bool      `json:"usage_available"` // false when the platform has no usage source
+	LoadAvg1       float64   `json:"load_avg_1"`
+	LoadAvg5       float64   `json:"load_avg_5"`
+	LoadAvg15      float64   `json:"load_avg_15"`
+}
+
+// cpuSampleInterval is the time between the two /proc/stat samples used to derive CPU usage
+const cpuSampleInterval = 250 * time.Millisecond
+
+// cpuTimes holds cumulative jiffies for one line of /proc/stat
+type cpuTimes struct {
+	idle  uint64
+	total uint64
 }
 
 // MemStats represents memory usage statistics
@@ -54,6 +155,9 @@ type MemStats struct {
 
 // DiskStats represents disk usage statistics
 type DiskStats struct {
+	MountPoint  string  `json:"mount_point"`
+	Device      string  `json:"device,omitempty"`
+	FSType      string  `json:"fs_type,omitempty"`
 	Total       uint64  `json:"total"`
 	Free        uint64  `json:"free"`
 	Used        uint64  `json:"used"`
@@ -62,10 +166,14 @@ type DiskStats struct {
 
 // NetStats represents network statistics
 type NetStats struct {
-	BytesRecv   uint64 `json:"bytes_recv"`
-	BytesSent   uint64 `json:"bytes_sent"`
-	PacketsRecv uint64 `json:"packets_recv"`
-	PacketsSent uint64 `json:"packets_sent"`
+	Interfaces      []string `json:"interfaces"`
+	BytesRecv       uint64   `json:"bytes_recv"` // cumulative since boot
+	BytesSent       uint64   `json:"bytes_sent"` // cumulative since boot
+	PacketsRecv     uint64   `json:"packets_recv"`
+	PacketsSent     uint64   `json:"packets_sent"`
+	BytesRecvPerSec float64  `json:"bytes_recv_per_sec"`
+	BytesSentPerSec float64  `json:"bytes_sent_per_sec"`
+	RateAvailable   bool     `json:"rate_available"` // false until two samples have been taken
 }
 
 // ProcStats represents process statistics
@@ -79,38 +187,73 @@ type ProcStats struct {
 
 // NginxStatus represents nginx service status
 type NginxStatus struct {
-	Running     bool      `json:"running"`
-	PID         int       `json:"pid"`
-	Version     string    `json:"version"`
-	ConfigTest  bool      `json:"config_test"`
-	LastReload  time.Time `json:"last_reload"`
-	Connections int       `json:"connections"`
-}
-
-// ActivityEvent represents a system activity event
-type ActivityEvent struct {
-	ID        string    `json:"id"`
-	Timestamp time.Time `json:"timestamp"`
-	Type      string    `json:"type"`
-	Message   string    `json:"message"`
-	Level     string    `json:"level"`
-	Details   gin.H     `json:"details"`
+	Running         bool      `json:"running"`
+	PID             int       `json:"pid"`
+	Version         string    `json:"version"`
+	ConfigTest      bool      `json:"config_test"`
+	LastReload      time.Time `json:"last_reload"`
+	Connections     int       `json:"connections"`      // active connections, from stub_status
+	ControlStrategy string    `json:"control_strategy"` // direct, systemd or docker
+
+	// StubStatus is nil when scraping is off, nginx is not running or the
+	// scrape failed, in which case StubStatusError says why
+	StubStatus      *NginxStubStatus `json:"stub_status,omitempty"`
+	StubStatusError string           `json:"stub_status_error,omitempty"`
 }
 
 // NewMonitoringService creates a new monitoring service
 func NewMonitoringService(nginxService *NginxService) *MonitoringService {
-	return &MonitoringService{
-		startTime:    time.Now(),
-		connections:  make(map[string]*websocket.Conn),
-		nginxService: nginxService,
-		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				// Allow connections from any origin in development
-				// In production, this should be more restrictive
-				return true
-			},
-		},
+	s := &MonitoringService{
+		startTime:           time.Now(),
+		connections:         make(map[string]*wsClient),
+		nginxService:        nginxService,
+		nginxRunner:         NewExecNginxRunner(DefaultNginxBinary),
+		diskExcludedFSTypes: toSet(DefaultDiskExcludedFSTypes),
+		nginxStatusTTL:      DefaultNginxStatusCacheTTL,
+		errorLogPath:        DefaultNginxErrorLogPath,
+		stubStatusURL:       DefaultNginxStubStatusURL,
+		stubStatusClient:    &http.Client{Timeout: stubStatusTimeout},
+		pongWait:            wsPongWait,
 	}
+	s.upgrader = websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	return s
+}
+
+// SetNginxRunner sets the runner used to poll the nginx status
+func (s *MonitoringService) SetNginxRunner(runner NginxRunner) {
+	s.nginxRunner = runner
+}
+
+// SetAllowedOrigins restricts WebSocket upgrades to the given origins, using
+// the same values as the CORS configuration; "*" allows any origin
+func (s *MonitoringService) SetAllowedOrigins(origins []string) {
+	if containsString(origins, "*") {
+		s.allowedOrigins = nil
+		return
+	}
+	s.allowedOrigins = origins
+}
+
+// checkOrigin accepts requests without an Origin header (non-browser clients),
+// same-origin requests and origins allowed by SetAllowedOrigins
+func (s *MonitoringService) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || s.allowedOrigins == nil {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range s.allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	logger.Warn("Rejected WebSocket origin", logger.String("origin", origin))
+	return false
 }
 
 // GetSystemMetrics collects comprehensive system metrics
@@ -136,11 +279,12 @@ func (s *MonitoringService) GetSystemMetrics() (*SystemMetrics, error) {
 	}
 
 	// Collect disk stats
-	diskStats, err := s.getDiskStats()
+	disks, diskStats, err := s.getDiskStats()
 	if err != nil {
 		logger.Warn("Failed to get disk stats", logger.Err(err))
 	} else {
 		metrics.Disk = diskStats
+		metrics.Disks = disks
 	}
 
 	// Collect network stats
@@ -161,27 +305,33 @@ func (s *MonitoringService) GetSystemMetrics() (*SystemMetrics, error) {
 func (s *MonitoringService) getCPUStats() (CPUStats, error) {
 	stats := CPUStats{}
 
-	// For Windows and cross-platform compatibility, we'll use simulated data
-	// In a production environment, you would use platform-specific APIs
-	stats.Usage = 15.5 + float64(time.Now().Unix()%20)
-	stats.LoadAvg1 = 1.2
-	stats.LoadAvg5 = 1.5
-	stats.LoadAvg15 = 1.8
-
-	// For Linux/Unix systems, try to read from /proc/loadavg
-	if runtime.GOOS != "windows" {
-		if data, err := os.ReadFile("/proc/loadavg"); err == nil {
-			fields := strings.Fields(string(data))
-			if len(fields) >= 3 {
-				if val, err := strconv.ParseFloat(fields[0], 64); err == nil {
-					stats.LoadAvg1 = val
-				}
-				if val, err := strconv.ParseFloat(fields[1], 64); err == nil {
-					stats.LoadAvg5 = val
-				}
-				if val, err := strconv.ParseFloat(fields[2], 64); err == nil {
-					stats.LoadAvg15 = val
-				}
+	// Windows has no /proc; usage and load averages are reported as zero with
+	// UsageAvailable=false rather than made-up values
+	if runtime.GOOS == "windows" {
+		return stats, nil
+	}
+
+	// Derive overall and per-core usage from two /proc/stat samples
+	if usage, perCore, err := sampleCPUUsage(cpuSampleInterval); err == nil {
+		stats.Usage = usage
+		stats.PerCore = perCore
+		stats.UsageAvailable = true
+	} else {
+		logger.Debug("Failed to sample CPU usage", logger.Err(err))
+	}
+
+	// Load averages from /proc/loadavg
+	if data, err := os.ReadFile("/proc/loadavg"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) >= 3 {
+			if val, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				stats.LoadAvg1 = val
+			}
+			if val, err := strconv.ParseFloat(fields[1], 64); err == nil {
+				stats.LoadAvg5 = val
+			}
+			if val, err := strconv.ParseFloat(fields[2], 64); err == nil {
+				stats.LoadAvg15 = val
 			}
 		}
 	}
@@ -189,6 +339,89 @@ func (s *MonitoringService) getCPUStats() (CPUStats, error) {
 	return stats, nil
 }
 
+// sampleCPUUsage reads /proc/stat twice, interval apart, and returns the overall
+// and per-core utilization percentages over that interval
+func sampleCPUUsage(interval time.Duration) (float64, []float64, error) {
+	before, err := readProcStat()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	time.Sleep(interval)
+
+	after, err := readProcStat()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(after) != len(before) {
+		return 0, nil, fmt.Errorf("cpu count changed between samples")
+	}
+
+	perCore := make([]float64, len(after)-1)
+	for i := 1; i < len(after); i++ {
+		perCore[i-1] = cpuUsagePercent(before[i], after[i])
+	}
+
+	return cpuUsagePercent(before[0], after[0]), perCore, nil
+}
+
+// readProcStat parses the aggregate "cpu" line followed by each "cpuN" line of /proc/stat
+func readProcStat() ([]cpuTimes, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return nil, err
+	}
+
+	var times []cpuTimes
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+
+		var sample cpuTimes
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid /proc/stat value %q: %w", field, err)
+			}
+			// guest and guest_nice are already included in user and nice
+			if i >= 8 {
+				break
+			}
+			sample.total += value
+			// idle and iowait
+			if i == 3 || i == 4 {
+				sample.idle += value
+			}
+		}
+		times = append(times, sample)
+	}
+
+	if len(times) == 0 {
+		return nil, fmt.Errorf("no cpu lines found in /proc/stat")
+	}
+	return times, nil
+}
+
+// cpuUsagePercent returns the busy percentage between two samples of the same CPU
+func cpuUsagePercent(before, after cpuTimes) float64 {
+	if after.total <= before.total {
+		return 0
+	}
+
+	total := float64(after.total - before.total)
+	var idle float64
+	if after.idle > before.idle {
+		idle = float64(after.idle - before.idle)
+	}
+	usage := (total - idle) / total * 100
+	if usage < 0 {
+		return 0
+	}
+	return usage
+}
+
 // getMemoryStats gets memory usage statistics
 func (s *MonitoringService) getMemoryStats() (MemStats, error) {
 	var memStats runtime.MemStats
@@ -236,54 +469,135 @@ func (s *MonitoringService) getMemoryStats() (MemStats, error) {
 	return stats, nil
 }
 
-// getDiskStats gets disk usage statistics
-func (s *MonitoringService) getDiskStats() (DiskStats, error) {
-	stats := DiskStats{}
+// SetDiskExcludedFSTypes overrides the filesystem types left out of disk stats
+func (s *MonitoringService) SetDiskExcludedFSTypes(fsTypes []string) {
+	s.diskExcludedFSTypes = toSet(fsTypes)
+}
 
-	// Cross-platform disk stats - simplified for demo
-	// In production, use platform-specific APIs
-	stats.Total = 500 * 1024 * 1024 * 1024 // 500GB
-	stats.Used = stats.Total / 2           // 50% usage
-	stats.Free = stats.Total - stats.Used
-	stats.UsedPercent = 50.0
+// getDiskStats returns usage for each mounted filesystem and, separately,
+// for the root filesystem
+func (s *MonitoringService) getDiskStats() ([]DiskStats, DiskStats, error) {
+	disks, err := listMountedDisks(s.diskExcludedFSTypes)
+	if err != nil {
+		return nil, DiskStats{}, err
+	}
 
-	return stats, nil
+	root := DiskStats{MountPoint: "/"}
+	for _, disk := range disks {
+		if disk.MountPoint == "/" {
+			root = disk
+			break
+		}
+	}
+
+	return disks, root, nil
 }
 
-// getNetworkStats gets network usage statistics
+// toSet converts a list of strings into a lookup set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// SetNetworkInterfaces limits network stats to the named interfaces. An empty
+// list includes every interface except loopback.
+func (s *MonitoringService) SetNetworkInterfaces(interfaces []string) {
+	s.netMutex.Lock()
+	defer s.netMutex.Unlock()
+
+	if len(interfaces) == 0 {
+		s.netInterfaces = nil
+	} else {
+		s.netInterfaces = toSet(interfaces)
+	}
+	s.lastNetSample = nil
+}
+
+// getNetworkStats reads interface counters and derives throughput rates from
+// the previous call's sample
 func (s *MonitoringService) getNetworkStats() (NetStats, error) {
 	stats := NetStats{}
 
-	// Cross-platform network stats - simplified for demo
-	// In production, use platform-specific APIs
-	stats.BytesRecv = 1024 * 1024 * 100 // 100MB
-	stats.BytesSent = 1024 * 1024 * 50  // 50MB
-	stats.PacketsRecv = 10000
-	stats.PacketsSent = 8000
-
-	// For Linux, try to read from /proc/net/dev
-	if runtime.GOOS != "windows" {
-		if data, err := os.ReadFile("/proc/net/dev"); err == nil {
-			lines := strings.Split(string(data), "\n")
-			for _, line := range lines {
-				if strings.Contains(line, ":") {
-					fields := strings.Fields(line)
-					if len(fields) >= 10 {
-						if recv, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
-							stats.BytesRecv += recv
-						}
-						if sent, err := strconv.ParseUint(fields[9], 10, 64); err == nil {
-							stats.BytesSent += sent
-						}
-					}
-				}
-			}
+	// Windows has no /proc; counters and rates are reported as zero
+	if runtime.GOOS == "windows" {
+		return stats, nil
+	}
+
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return stats, err
+	}
+
+	s.netMutex.Lock()
+	defer s.netMutex.Unlock()
+
+	for _, line := range strings.Split(string(data), "\n") {
+		name, counters, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if !s.includeInterface(name) {
+			continue
+		}
+
+		fields := strings.Fields(counters)
+		if len(fields) < 10 {
+			continue
+		}
+
+		stats.Interfaces = append(stats.Interfaces, name)
+		if value, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			stats.BytesRecv += value
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			stats.PacketsRecv += value
+		}
+		if value, err := strconv.ParseUint(fields[8], 10, 64); err == nil {
+			stats.BytesSent += value
+		}
+		if value, err := strconv.ParseUint(fields[9], 10, 64); err == nil {
+			stats.PacketsSent += value
+		}
+	}
+
+	now := time.Now()
+	if previous := s.lastNetSample; previous != nil {
+		if elapsed := now.Sub(previous.takenAt).Seconds(); elapsed > 0 {
+			stats.BytesRecvPerSec = counterRate(previous.bytesRecv, stats.BytesRecv, elapsed)
+			stats.BytesSentPerSec = counterRate(previous.bytesSent, stats.BytesSent, elapsed)
+			stats.RateAvailable = true
 		}
 	}
+	s.lastNetSample = &netSample{
+		takenAt:   now,
+		bytesRecv: stats.BytesRecv,
+		bytesSent: stats.BytesSent,
+	}
 
 	return stats, nil
 }
 
+// includeInterface reports whether an interface is selected for network stats
+func (s *MonitoringService) includeInterface(name string) bool {
+	if s.netInterfaces == nil {
+		return name != "lo"
+	}
+	return s.netInterfaces[name]
+}
+
+// counterRate returns the per-second increase of a cumulative counter. A
+// counter that went backwards (interface reset or wrap) yields zero.
+func counterRate(previous, current uint64, elapsedSeconds float64) float64 {
+	if current < previous {
+		return 0
+	}
+	return float64(current-previous) / elapsedSeconds
+}
+
 // getProcessStats gets process-specific statistics
 func (s *MonitoringService) getProcessStats() ProcStats {
 	var memStats runtime.MemStats
@@ -298,236 +612,568 @@ func (s *MonitoringService) getProcessStats() ProcStats {
 	}
 }
 
-// GetNginxStatus gets nginx service status
+// SetNginxStatusCacheTTL sets how long a polled nginx status is reused; zero
+// polls on every call
+func (s *MonitoringService) SetNginxStatusCacheTTL(ttl time.Duration) {
+	s.nginxStatusMutex.Lock()
+	defer s.nginxStatusMutex.Unlock()
+
+	s.nginxStatusTTL = ttl
+}
+
+// InvalidateNginxStatus discards the cached nginx status so the next call polls
+// again, e.g. after nginx has been started, stopped or reloaded
+func (s *MonitoringService) InvalidateNginxStatus() {
+	s.nginxStatusMutex.Lock()
+	defer s.nginxStatusMutex.Unlock()
+
+	s.nginxStatus = nil
+}
+
+// NginxControlActions are the actions accepted by ControlNginx
+var NginxControlActions = []string{"start", "stop", "restart", "reload", "test"}
+
+var ErrInvalidNginxAction = errors.New("invalid nginx control action")
+
+// ControlNginx starts, stops, restarts, reloads or tests nginx through the
+// configured control strategy. The cached status is discarded after every
+// action except test.
+func (s *MonitoringService) ControlNginx(action string) error {
+	var err error
+	switch action {
+	case "start":
+		err = s.nginxRunner.Start()
+	case "stop":
+		err = s.nginxRunner.Stop()
+	case "restart":
+		err = s.nginxRunner.Restart()
+	case "reload":
+		err = s.nginxRunner.Reload()
+	case "test":
+		return s.nginxRunner.Test("")
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidNginxAction, action)
+	}
+
+	s.InvalidateNginxStatus()
+	return err
+}
+
+// Uptime returns how long the application has been running
+func (s *MonitoringService) Uptime() time.Duration {
+	return time.Since(s.startTime)
+}
+
+// IsNginxRunning asks the nginx runner whether nginx is running, bypassing
+// the status cache
+func (s *MonitoringService) IsNginxRunning() bool {
+	return s.nginxRunner.IsRunning()
+}
+
+// ErrDiskStatsUnsupported is returned where filesystem usage cannot be read
+var ErrDiskStatsUnsupported = errors.New("disk stats are not supported on this platform")
+
+// GetRootDiskStats reads usage of the root filesystem directly, including
+// when its type (such as overlay in containers) is excluded from the mount list
+func (s *MonitoringService) GetRootDiskStats() (DiskStats, error) {
+	return statfsDisk("/")
+}
+
+// GetNginxStatus gets nginx service status. The status is polled at most once
+// per cache TTL and shared by every caller, so dashboards with many WebSocket
+// clients do not each spawn pgrep and nginx subprocesses.
 func (s *MonitoringService) GetNginxStatus() (*NginxStatus, error) {
+	s.nginxStatusMutex.Lock()
+	defer s.nginxStatusMutex.Unlock()
+
+	if s.nginxStatus == nil || time.Since(s.nginxStatusAt) >= s.nginxStatusTTL {
+		s.nginxStatus = s.pollNginxStatus()
+		s.nginxStatusAt = time.Now()
+	}
+
+	status := *s.nginxStatus
+	return &status, nil
+}
+
+// pollNginxStatus runs the subprocesses that make up the nginx status
+func (s *MonitoringService) pollNginxStatus() *NginxStatus {
 	status := &NginxStatus{
-		Running:    false,
-		ConfigTest: false,
-		LastReload: time.Now(),
+		Running:         false,
+		ConfigTest:      false,
+		LastReload:      time.Now(),
+		ControlStrategy: s.nginxRunner.Strategy(),
 	}
 
 	// Check if nginx is running and get basic status
-	if running := s.isNginxRunning(); running {
+	if running := s.nginxRunner.IsRunning(); running {
 		status.Running = true
 	}
 
 	// Test nginx configuration
-	if configValid := s.testNginxConfig(); configValid {
+	if err := s.nginxRunner.Test(""); err == nil {
 		status.ConfigTest = true
 	}
 
 	// Get nginx version
-	if version, err := s.getNginxVersion(); err == nil {
+	if version, err := s.nginxRunner.Version(); err == nil {
 		status.Version = version
 	}
 
 	// Get nginx PID
-	if pid, err := s.getNginxPID(); err == nil {
+	if pid, err := s.nginxRunner.PID(); err == nil {
 		status.PID = pid
 	}
 
-	return status, nil
-}
-
-// isNginxRunning checks if nginx is running
-func (s *MonitoringService) isNginxRunning() bool {
-	if runtime.GOOS == "windows" {
-		cmd := exec.Command("tasklist", "/fi", "imagename eq nginx.exe")
-		output, err := cmd.Output()
-		if err != nil {
-			return false
+	// Get connection and request counters
+	if status.Running && s.stubStatusURL != "" {
+		if stubStatus, err := s.ScrapeStubStatus(s.stubStatusURL); err != nil {
+			status.StubStatusError = err.Error()
+		} else {
+			status.StubStatus = stubStatus
+			status.Connections = int(stubStatus.Active)
 		}
-		return strings.Contains(string(output), "nginx.exe")
 	}
 
-	// For Linux/Unix
-	cmd := exec.Command("pgrep", "nginx")
-	err := cmd.Run()
-	return err == nil
+	return status
 }
 
-// testNginxConfig tests nginx configuration
-func (s *MonitoringService) testNginxConfig() bool {
-	cmd := exec.Command("nginx", "-t")
-	err := cmd.Run()
-	return err == nil
-}
-
-// getNginxVersion gets nginx version
-func (s *MonitoringService) getNginxVersion() (string, error) {
-	cmd := exec.Command("nginx", "-v")
-	output, err := cmd.CombinedOutput()
+// HandleWebSocket handles WebSocket connections for real-time updates for an
+// authenticated user; non-admin users receive a reduced view of the metrics
+func (s *MonitoringService) HandleWebSocket(c *gin.Context, user *models.User) {
+	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		return "", err
+		logger.Error("Failed to upgrade to WebSocket", logger.Err(err))
+		return
 	}
 
-	// Parse version from output like "nginx version: nginx/1.18.0"
-	version := strings.TrimSpace(string(output))
-	if strings.Contains(version, "nginx/") {
-		parts := strings.Split(version, "nginx/")
-		if len(parts) > 1 {
-			return parts[1], nil
-		}
+	client := &wsClient{conn: conn, userID: user.ID, isAdmin: user.IsAdmin()}
+	defer conn.Close()
+
+	// Client IDs are namespaced per user so one user cannot displace another's connection
+	clientID := c.Query("client_id")
+	if clientID == "" {
+		clientID = fmt.Sprintf("client_%d", time.Now().UnixNano())
 	}
+	clientID = fmt.Sprintf("%d:%s", user.ID, clientID)
 
-	return version, nil
-}
+	s.addClient(clientID, client)
+	defer s.removeClient(clientID, client)
 
-// getNginxPID gets nginx master process PID
-func (s *MonitoringService) getNginxPID() (int, error) {
-	// For Windows
-	if runtime.GOOS == "windows" {
-		cmd := exec.Command("tasklist", "/fi", "imagename eq nginx.exe", "/fo", "csv")
-		output, err := cmd.Output()
-		if err != nil {
-			return 0, err
-		}
+	logger.Info("WebSocket client connected",
+		logger.String("client_id", clientID),
+		logger.Uint("user_id", user.ID))
+
+	// Clients receive metrics, nginx status and activity until they subscribe otherwise
+	client.subscribe([]string{TopicMetrics, TopicNginxStatus, TopicActivity}, nil)
+	s.pushDueTopics(map[string]*wsClient{clientID: client})
+
+	// Ping the client so half-open connections are noticed and dropped
+	stopKeepalive := s.keepAlive(client)
+	defer stopKeepalive()
 
-		lines := strings.Split(string(output), "\n")
-		if len(lines) > 1 {
-			fields := strings.Split(lines[1], ",")
-			if len(fields) > 1 {
-				pidStr := strings.Trim(fields[1], `"`)
-				return strconv.Atoi(pidStr)
+	// Keep connection alive and handle incoming control messages
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				logger.Info("Dropping unresponsive WebSocket client", logger.String("client_id", clientID))
+			} else {
+				logger.Info("WebSocket client disconnected", logger.String("client_id", clientID))
 			}
+			break
 		}
-		return 0, fmt.Errorf("nginx not found")
+		s.handleControlMessage(clientID, client, data)
 	}
+}
 
-	// For Linux/Unix
-	cmd := exec.Command("pgrep", "-f", "nginx: master")
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, err
+// handleControlMessage applies a subscribe or unsubscribe request and replies
+// with the client's resulting subscriptions
+func (s *MonitoringService) handleControlMessage(clientID string, client *wsClient, data []byte) {
+	var msg wsControlMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		s.sendToClient(client, "error", gin.H{"message": "invalid control message: " + err.Error()})
+		return
 	}
 
-	pidStr := strings.TrimSpace(string(output))
-	return strconv.Atoi(pidStr)
-}
+	for _, topic := range msg.Topics {
+		if !containsString(wsTopics, topic) {
+			s.sendToClient(client, "error", gin.H{
+				"message": fmt.Sprintf("unknown topic %q; allowed topics: %s", topic, strings.Join(wsTopics, ", ")),
+			})
+			return
+		}
+		if topic == TopicErrorLog && msg.Action == "subscribe" && !client.isAdmin {
+			s.sendToClient(client, "error", gin.H{"message": "the error_log topic requires admin access"})
+			return
+		}
+	}
 
-// HandleWebSocket handles WebSocket connections for real-time updates
-func (s *MonitoringService) HandleWebSocket(c *gin.Context) {
-	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		logger.Error("Failed to upgrade to WebSocket", logger.Err(err))
+	switch msg.Action {
+	case "subscribe":
+		client.subscribe(msg.Topics, msg.Intervals)
+	case "unsubscribe":
+		client.unsubscribe(msg.Topics)
+	default:
+		s.sendToClient(client, "error", gin.H{"message": fmt.Sprintf("unknown action %q; allowed actions: subscribe, unsubscribe", msg.Action)})
 		return
 	}
-	defer conn.Close()
 
-	clientID := c.Query("client_id")
-	if clientID == "" {
-		clientID = fmt.Sprintf("client_%d", time.Now().UnixNano())
+	s.sendToClient(client, "subscriptions", client.subscriptionIntervals())
+
+	// Deliver newly subscribed topics right away instead of on the next tick
+	s.pushDueTopics(map[string]*wsClient{clientID: client})
+}
+
+// subscribe adds topics with an optional per-topic interval in seconds,
+// clamped to [wsMinTopicInterval, wsMaxTopicInterval]
+func (c *wsClient) subscribe(topics []string, intervals map[string]int) {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[string]time.Duration)
+		c.lastSent = make(map[string]time.Time)
 	}
 
-	s.connections[clientID] = conn
-	defer delete(s.connections, clientID)
+	for _, topic := range topics {
+		// Alerts, activity and error log lines are pushed as they happen and have no interval
+		if topic == TopicAlerts || topic == TopicActivity || topic == TopicErrorLog {
+			c.subscriptions[topic] = 0
+			continue
+		}
 
-	logger.Info("WebSocket client connected", logger.String("client_id", clientID))
+		interval := wsDefaultTopicInterval
+		if seconds, ok := intervals[topic]; ok {
+			interval = time.Duration(seconds) * time.Second
+		}
+		if interval < wsMinTopicInterval {
+			interval = wsMinTopicInterval
+		}
+		if interval > wsMaxTopicInterval {
+			interval = wsMaxTopicInterval
+		}
+		c.subscriptions[topic] = interval
+	}
+}
+
+// unsubscribe removes topics; an empty list removes every topic
+func (c *wsClient) unsubscribe(topics []string) {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
 
-	// Send initial metrics
-	if metrics, err := s.GetSystemMetrics(); err == nil {
-		s.sendToClient(conn, "metrics", metrics)
+	if len(topics) == 0 {
+		topics = wsTopics
 	}
+	for _, topic := range topics {
+		delete(c.subscriptions, topic)
+		delete(c.lastSent, topic)
+	}
+}
 
-	if nginxStatus, err := s.GetNginxStatus(); err == nil {
-		s.sendToClient(conn, "nginx_status", nginxStatus)
+// subscriptionIntervals returns the subscribed topics with their interval in seconds
+func (c *wsClient) subscriptionIntervals() map[string]int {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	intervals := make(map[string]int, len(c.subscriptions))
+	for topic, interval := range c.subscriptions {
+		intervals[topic] = int(interval / time.Second)
 	}
+	return intervals
+}
 
-	// Keep connection alive and handle incoming messages
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			logger.Info("WebSocket client disconnected", logger.String("client_id", clientID))
-			break
+// isSubscribed reports whether the client subscribed to topic
+func (c *wsClient) isSubscribed(topic string) bool {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	_, ok := c.subscriptions[topic]
+	return ok
+}
+
+// takeDueTopics returns the periodic topics whose interval has elapsed and
+// marks them as sent
+func (c *wsClient) takeDueTopics(now time.Time) map[string]bool {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	due := make(map[string]bool)
+	for _, topic := range []string{TopicMetrics, TopicNginxStatus} {
+		interval, ok := c.subscriptions[topic]
+		if !ok || now.Sub(c.lastSent[topic]) < interval {
+			continue
 		}
+		due[topic] = true
+		c.lastSent[topic] = now
+	}
+	return due
+}
+
+// addClient registers a connection, closing any previous one with the same ID
+func (s *MonitoringService) addClient(clientID string, client *wsClient) {
+	s.connMutex.Lock()
+	previous := s.connections[clientID]
+	s.connections[clientID] = client
+	s.connMutex.Unlock()
+
+	if previous != nil {
+		previous.conn.Close()
+	}
+}
+
+// removeClient unregisters a connection unless it has already been replaced
+func (s *MonitoringService) removeClient(clientID string, client *wsClient) {
+	s.connMutex.Lock()
+	defer s.connMutex.Unlock()
+
+	if s.connections[clientID] == client {
+		delete(s.connections, clientID)
 	}
 }
 
 // sendToClient sends data to a specific WebSocket client
-func (s *MonitoringService) sendToClient(conn *websocket.Conn, eventType string, data interface{}) {
+func (s *MonitoringService) sendToClient(client *wsClient, eventType string, data interface{}) error {
 	message := gin.H{
 		"type":      eventType,
 		"timestamp": time.Now(),
 		"data":      data,
 	}
 
-	if err := conn.WriteJSON(message); err != nil {
+	client.writeMutex.Lock()
+	defer client.writeMutex.Unlock()
+
+	// A stalled client must not block broadcasts to everyone else
+	client.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := client.conn.WriteJSON(message); err != nil {
 		logger.Error("Failed to send WebSocket message", logger.Err(err))
+		return err
 	}
+	return nil
 }
 
-// BroadcastMetrics broadcasts system metrics to all connected clients
+// BroadcastMetrics pushes metrics and nginx status to every client whose
+// subscription interval for the topic has elapsed. Call it more often than
+// the shortest topic interval.
 func (s *MonitoringService) BroadcastMetrics() {
-	metrics, err := s.GetSystemMetrics()
-	if err != nil {
-		logger.Error("Failed to get system metrics", logger.Err(err))
-		return
+	s.pushDueTopics(s.snapshotClients())
+}
+
+// PublishAlert pushes an alert event to clients subscribed to alerts that
+// belong to the alert rule's owner or to an admin
+func (s *MonitoringService) PublishAlert(ownerID uint, alert interface{}) {
+	s.publishEvent(TopicAlerts, "alert", &ownerID, alert)
+}
+
+// PublishActivity pushes an activity event to subscribed clients. Events
+// without an owner are visible to everyone, others only to the owner and admins.
+func (s *MonitoringService) PublishActivity(event *models.ActivityEvent) {
+	s.publishEvent(TopicActivity, "activity", event.UserID, event)
+}
+
+// publishEvent sends an event-driven topic to its subscribers, limited to the
+// owner and admins when ownerID is set
+func (s *MonitoringService) publishEvent(topic, eventType string, ownerID *uint, data interface{}) {
+	for clientID, client := range s.snapshotClients() {
+		if !client.isSubscribed(topic) {
+			continue
+		}
+		if ownerID != nil && !client.isAdmin && client.userID != *ownerID {
+			continue
+		}
+		if err := s.sendToClient(client, eventType, data); err != nil {
+			s.dropClient(clientID, client)
+		}
 	}
+}
 
-	nginxStatus, err := s.GetNginxStatus()
-	if err != nil {
-		logger.Error("Failed to get nginx status", logger.Err(err))
+// publishAdminEvent sends an event-driven topic to its subscribed admins
+func (s *MonitoringService) publishAdminEvent(topic, eventType string, data interface{}) {
+	for clientID, client := range s.snapshotClients() {
+		if !client.isAdmin || !client.isSubscribed(topic) {
+			continue
+		}
+		if err := s.sendToClient(client, eventType, data); err != nil {
+			s.dropClient(clientID, client)
+		}
+	}
+}
+
+// hasSubscriber reports whether any client subscribed to topic
+func (s *MonitoringService) hasSubscriber(topic string) bool {
+	for _, client := range s.snapshotClients() {
+		if client.isSubscribed(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotClients copies the connection map so slow writes do not hold the lock
+func (s *MonitoringService) snapshotClients() map[string]*wsClient {
+	s.connMutex.RLock()
+	defer s.connMutex.RUnlock()
+
+	clients := make(map[string]*wsClient, len(s.connections))
+	for clientID, client := range s.connections {
+		clients[clientID] = client
+	}
+	return clients
+}
+
+// pushDueTopics sends each client the periodic topics that are due, collecting
+// metrics and nginx status only if some client needs them
+func (s *MonitoringService) pushDueTopics(clients map[string]*wsClient) {
+	now := time.Now()
+	dueByClient := make(map[string]map[string]bool, len(clients))
+	needed := make(map[string]bool)
+	for clientID, client := range clients {
+		due := client.takeDueTopics(now)
+		if len(due) == 0 {
+			continue
+		}
+		dueByClient[clientID] = due
+		for topic := range due {
+			needed[topic] = true
+		}
+	}
+
+	// Skip collecting data nobody will receive
+	if len(needed) == 0 {
 		return
 	}
 
-	for clientID, conn := range s.connections {
-		s.sendToClient(conn, "metrics", metrics)
-		s.sendToClient(conn, "nginx_status", nginxStatus)
+	var metrics, publicMetrics *SystemMetrics
+	if needed[TopicMetrics] {
+		var err error
+		if metrics, err = s.GetSystemMetrics(); err != nil {
+			logger.Error("Failed to get system metrics", logger.Err(err))
+		} else {
+			publicMetrics = scopeSystemMetrics(metrics, false)
+		}
+	}
 
-		// Remove disconnected clients
-		if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-			logger.Info("Removing disconnected client", logger.String("client_id", clientID))
-			delete(s.connections, clientID)
+	var nginxStatus, publicStatus *NginxStatus
+	if needed[TopicNginxStatus] {
+		var err error
+		if nginxStatus, err = s.GetNginxStatus(); err != nil {
+			logger.Error("Failed to get nginx status", logger.Err(err))
+		} else {
+			publicStatus = scopeNginxStatus(nginxStatus, false)
+		}
+	}
+
+	for clientID, due := range dueByClient {
+		client := clients[clientID]
+
+		var err error
+		if due[TopicMetrics] && metrics != nil {
+			data := publicMetrics
+			if client.isAdmin {
+				data = metrics
+			}
+			err = s.sendToClient(client, TopicMetrics, data)
+		}
+		if err == nil && due[TopicNginxStatus] && nginxStatus != nil {
+			data := publicStatus
+			if client.isAdmin {
+				data = nginxStatus
+			}
+			err = s.sendToClient(client, TopicNginxStatus, data)
+		}
+
+		if err != nil {
+			s.dropClient(clientID, client)
 		}
 	}
 }
 
-// GetRecentActivity gets recent system activity events
-func (s *MonitoringService) GetRecentActivity(limit int) ([]ActivityEvent, error) {
-	// In a real implementation, this would read from a database or log file
-	// For demo purposes, we'll generate some sample events
-	events := []ActivityEvent{
-		{
-			ID:        "evt_001",
-			Timestamp: time.Now().Add(-5 * time.Minute),
-			Type:      "certificate",
-			Message:   "Certificate renewed successfully",
-			Level:     "info",
-			Details:   gin.H{"domain": "example.com", "provider": "letsencrypt"},
-		},
-		{
-			ID:        "evt_002",
-			Timestamp: time.Now().Add(-10 * time.Minute),
-			Type:      "proxy_host",
-			Message:   "Proxy host created",
-			Level:     "info",
-			Details:   gin.H{"domain": "api.example.com", "target": "localhost:3000"},
+// dropClient removes and closes a client whose connection failed
+func (s *MonitoringService) dropClient(clientID string, client *wsClient) {
+	logger.Info("Removing disconnected client", logger.String("client_id", clientID))
+	s.removeClient(clientID, client)
+	client.conn.Close()
+}
+
+// scopeSystemMetrics returns the metrics a user may see. Non-admin users get
+// usage figures only, without process details, device names or interfaces.
+func scopeSystemMetrics(metrics *SystemMetrics, isAdmin bool) *SystemMetrics {
+	if isAdmin {
+		return metrics
+	}
+
+	scoped := &SystemMetrics{
+		Timestamp: metrics.Timestamp,
+		CPU:       metrics.CPU,
+		Memory: MemStats{
+			Total:       metrics.Memory.Total,
+			Available:   metrics.Memory.Available,
+			Used:        metrics.Memory.Used,
+			UsedPercent: metrics.Memory.UsedPercent,
 		},
-		{
-			ID:        "evt_003",
-			Timestamp: time.Now().Add(-15 * time.Minute),
-			Type:      "nginx",
-			Message:   "Nginx configuration reloaded",
-			Level:     "info",
-			Details:   gin.H{"config_test": true},
+		Disk: metrics.Disk,
+		Network: NetStats{
+			BytesRecvPerSec: metrics.Network.BytesRecvPerSec,
+			BytesSentPerSec: metrics.Network.BytesSentPerSec,
+			RateAvailable:   metrics.Network.RateAvailable,
 		},
+		Process: ProcStats{Uptime: metrics.Process.Uptime},
 	}
+	scoped.Disk.Device = ""
+	scoped.Disk.FSType = ""
+	return scoped
+}
 
-	if limit > 0 && limit < len(events) {
-		events = events[:limit]
+// scopeNginxStatus hides the nginx PID and version from non-admin users
+func scopeNginxStatus(status *NginxStatus, isAdmin bool) *NginxStatus {
+	if isAdmin {
+		return status
 	}
 
-	return events, nil
+	scoped := *status
+	scoped.PID = 0
+	scoped.Version = ""
+	scoped.StubStatusError = ""
+	return &scoped
 }
 
-// StartMetricsBroadcast starts periodic metrics broadcasting
-func (s *MonitoringService) StartMetricsBroadcast(interval time.Duration) {
+// StartMetricsBroadcast broadcasts metrics every interval until ctx is done
+func (s *MonitoringService) StartMetricsBroadcast(ctx context.Context, interval time.Duration) {
 	ticker := time.NewTicker(interval)
-	go func() {
-		for range ticker.C {
+	defer ticker.Stop()
+
+	logger.Info("Started metrics broadcasting", logger.Duration("interval", interval))
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping metrics broadcasting")
+			return
+		case <-ticker.C:
 			s.BroadcastMetrics()
 		}
-	}()
+	}
+}
 
-	logger.Info("Started metrics broadcasting", logger.Duration("interval", interval))
+// CloseConnections sends every WebSocket client a going-away close frame and
+// closes its connection. http.Server.Shutdown does not track hijacked
+// connections, so this is registered to run when the server shuts down; each
+// client's read loop then ends and removes it.
+func (s *MonitoringService) CloseConnections() {
+	clients := s.snapshotClients()
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	deadline := time.Now().Add(wsWriteWait) // shared, so stalled clients cannot add up
+
+	for _, client := range clients {
+		client.writeMutex.Lock()
+		if err := client.conn.WriteControl(websocket.CloseMessage, message, deadline); err != nil {
+			logger.Debug("Failed to send WebSocket close frame", logger.Err(err))
+		}
+		client.writeMutex.Unlock()
+		client.conn.Close()
+	}
+
+	if len(clients) > 0 {
+		logger.Info("Closed WebSocket connections", logger.Int("count", len(clients)))
+	}
 }
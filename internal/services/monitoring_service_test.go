@@ -0,0 +1,174 @@
+package services
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// newWebSocketTestServer serves HandleWebSocket, authenticating every
+// connection as user 1, an admin when the admin query parameter is true
+func newWebSocketTestServer(t *testing.T, s *MonitoringService) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/ws", func(c *gin.Context) {
+		var user models.User
+		if c.Query("admin") == "true" {
+			user.Roles = models.StringArray{string(models.RoleAdmin)}
+		} else {
+			user.Roles = models.StringArray{string(models.RoleUser)}
+		}
+		user.ID = 1
+		s.HandleWebSocket(c, &user)
+	})
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// dialWebSocket connects to the test server
+func dialWebSocket(server *httptest.Server, query string) (*websocket.Conn, error) {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?" + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	return conn, err
+}
+
+// waitForClients waits until the service has exactly want connections
+func waitForClients(t *testing.T, s *MonitoringService, want int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		got := len(s.snapshotClients())
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d WebSocket clients, want %d", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestConcurrentBroadcasts runs broadcasts and events while clients connect,
+// read and disconnect. Run it with -race.
+func TestConcurrentBroadcasts(t *testing.T) {
+	s := NewMonitoringService(nil)
+	s.SetNginxRunner(&MockNginxRunner{})
+	server := newWebSocketTestServer(t, s)
+
+	const (
+		clients     = 8
+		connections = 5 // per client goroutine
+		publishers  = 4
+	)
+
+	stop := make(chan struct{})
+	var publishing sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		publishing.Add(1)
+		go func(i int) {
+			defer publishing.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				switch i % 3 {
+				case 0:
+					s.BroadcastMetrics()
+				case 1:
+					s.PublishActivity(&models.ActivityEvent{Type: "proxy_host", Message: "updated"})
+				default:
+					s.PublishAlert(1, map[string]string{"rule": "cpu"})
+				}
+			}
+		}(i)
+	}
+
+	var connecting sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		connecting.Add(1)
+		go func(i int) {
+			defer connecting.Done()
+			for j := 0; j < connections; j++ {
+				query := "client_id=c" + string(rune('a'+i))
+				if i%2 == 0 {
+					query += "&admin=true"
+				}
+				conn, err := dialWebSocket(server, query)
+				if err != nil {
+					t.Errorf("dial: %v", err)
+					return
+				}
+				if err := conn.WriteJSON(wsControlMessage{Action: "subscribe", Topics: []string{TopicAlerts}}); err != nil {
+					t.Errorf("subscribe: %v", err)
+				}
+				for k := 0; k < 3; k++ {
+					conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+					if _, _, err := conn.ReadMessage(); err != nil {
+						break
+					}
+				}
+				conn.Close()
+			}
+		}(i)
+	}
+
+	connecting.Wait()
+	close(stop)
+	publishing.Wait()
+
+	waitForClients(t, s, 0, 5*time.Second)
+}
+
+// TestBroadcastScopesMetricsByRole checks that only admins receive the full
+// nginx status
+func TestBroadcastScopesMetricsByRole(t *testing.T) {
+	s := NewMonitoringService(nil)
+	s.SetNginxRunner(&MockNginxRunner{Running: true, VersionString: "nginx/1.25.3", ProcessID: 42})
+	s.stubStatusURL = ""
+	server := newWebSocketTestServer(t, s)
+
+	for _, tc := range []struct {
+		query   string
+		wantPID bool
+	}{
+		{query: "admin=true", wantPID: true},
+		{query: "admin=false", wantPID: false},
+	} {
+		conn, err := dialWebSocket(server, tc.query)
+		if err != nil {
+			t.Fatalf("%s: dial: %v", tc.query, err)
+		}
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+		var status *NginxStatus
+		for status == nil {
+			var message struct {
+				Type string      `json:"type"`
+				Data NginxStatus `json:"data"`
+			}
+			if err := conn.ReadJSON(&message); err != nil {
+				t.Fatalf("%s: read: %v", tc.query, err)
+			}
+			if message.Type == TopicNginxStatus {
+				status = &message.Data
+			}
+		}
+		conn.Close()
+
+		if got := status.PID == 42; got != tc.wantPID {
+			t.Errorf("%s: PID %d in nginx status, want visible=%v", tc.query, status.PID, tc.wantPID)
+		}
+	}
+}
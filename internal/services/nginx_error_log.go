@@ -0,0 +1,280 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+// Error log query limits
+const (
+	DefaultErrorLogLines = 100
+	MaxErrorLogLines     = 1000
+
+	// errorLogChunkSize is how much of the log is read per step backwards
+	errorLogChunkSize = 64 * 1024
+	// maxErrorLogScanBytes bounds how far back a query reads, so a filter
+	// matching nothing does not read a huge log in full
+	maxErrorLogScanBytes = 16 * 1024 * 1024
+)
+
+// ErrorLogLevels are the nginx error log levels, least severe first
+var ErrorLogLevels = []string{"debug", "info", "notice", "warn", "error", "crit", "alert", "emerg"}
+
+var ErrInvalidErrorLogLevel = fmt.Errorf("invalid error log level; allowed levels: %s", strings.Join(ErrorLogLevels, ", "))
+
+// errorLogLinePattern matches nginx's "2006/01/02 15:04:05 [error] 12#12: ..." prefix
+var errorLogLinePattern = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[(\w+)\] (.*)$`)
+
+// errorLogTimeLayout is the timestamp format of the nginx error log, written
+// in the server's local time
+const errorLogTimeLayout = "2006/01/02 15:04:05"
+
+// ErrorLogEntry is one line of the nginx error log. Lines without the usual
+// prefix, such as output from a crashed worker, have no time or level.
+type ErrorLogEntry struct {
+	Time    *time.Time `json:"time,omitempty"`
+	Level   string     `json:"level,omitempty"`
+	Message string     `json:"message"`
+}
+
+// ErrorLog is the tail of the nginx error log
+type ErrorLog struct {
+	Path    string          `json:"path"`
+	Exists  bool            `json:"exists"` // false until nginx first writes the log
+	Entries []ErrorLogEntry `json:"entries"`
+}
+
+// SetErrorLogPath sets the nginx error log read by GetErrorLog and tailed to
+// the error_log WebSocket topic
+func (s *MonitoringService) SetErrorLogPath(path string) {
+	s.errorLogMutex.Lock()
+	defer s.errorLogMutex.Unlock()
+
+	s.errorLogPath = path
+}
+
+// getErrorLogPath returns the configured nginx error log
+func (s *MonitoringService) getErrorLogPath() string {
+	s.errorLogMutex.Lock()
+	defer s.errorLogMutex.Unlock()
+
+	return s.errorLogPath
+}
+
+// GetErrorLog returns up to lines of the most recent nginx error log entries,
+// oldest first. A non-zero since drops older entries and minLevel, if set,
+// those less severe. A log that does not exist yet gives no entries.
+func (s *MonitoringService) GetErrorLog(lines int, since time.Time, minLevel string) (*ErrorLog, error) {
+	if lines < 1 {
+		lines = DefaultErrorLogLines
+	}
+	if lines > MaxErrorLogLines {
+		lines = MaxErrorLogLines
+	}
+	minSeverity := 0
+	if minLevel != "" {
+		if minSeverity = errorLogSeverity(minLevel); minSeverity < 0 {
+			return nil, ErrInvalidErrorLogLevel
+		}
+	}
+
+	result := &ErrorLog{Path: s.getErrorLogPath(), Entries: []ErrorLogEntry{}}
+	file, err := os.Open(result.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return result, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	result.Exists = true
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read backwards a chunk at a time until enough entries match, the since
+	// boundary is passed or the scan limit is reached
+	var entries []ErrorLogEntry // newest first
+	var partial []byte          // start of the earliest line read, which may continue in the previous chunk
+	offset := info.Size()
+	for offset > 0 && info.Size()-offset < maxErrorLogScanBytes {
+		size := int64(errorLogChunkSize)
+		if size > offset {
+			size = offset
+		}
+		offset -= size
+
+		chunk := make([]byte, size, size+int64(len(partial)))
+		if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		chunk = append(chunk, partial...)
+
+		chunkLines := bytes.Split(chunk, []byte("\n"))
+		partial = nil
+		if offset > 0 {
+			partial, chunkLines = chunkLines[0], chunkLines[1:]
+		}
+
+		done := false
+		for i := len(chunkLines) - 1; i >= 0 && !done; i-- {
+			entries, done = collectErrorLogEntry(entries, chunkLines[i], since, minSeverity, lines)
+		}
+		if done {
+			break
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		result.Entries = append(result.Entries, entries[i])
+	}
+	return result, nil
+}
+
+// collectErrorLogEntry appends line to entries when it passes the filters
+// and reports whether reading further back is pointless: the limit is
+// reached or the line is older than since
+func collectErrorLogEntry(entries []ErrorLogEntry, line []byte, since time.Time, minSeverity, limit int) ([]ErrorLogEntry, bool) {
+	text := strings.TrimRight(string(line), "\r")
+	if text == "" {
+		return entries, false
+	}
+
+	entry := ParseErrorLogLine(text)
+	if entry.Time != nil && !since.IsZero() && entry.Time.Before(since) {
+		return entries, true
+	}
+	if minSeverity > 0 && errorLogSeverity(entry.Level) < minSeverity {
+		return entries, false
+	}
+
+	entries = append(entries, entry)
+	return entries, len(entries) >= limit
+}
+
+// ParseErrorLogLine splits an nginx error log line into its time, level and
+// message
+func ParseErrorLogLine(line string) ErrorLogEntry {
+	match := errorLogLinePattern.FindStringSubmatch(line)
+	if match == nil {
+		return ErrorLogEntry{Message: line}
+	}
+
+	entry := ErrorLogEntry{Level: match[2], Message: match[3]}
+	if t, err := time.ParseInLocation(errorLogTimeLayout, match[1], time.Local); err == nil {
+		entry.Time = &t
+	}
+	return entry
+}
+
+// errorLogSeverity returns the position of level in ErrorLogLevels, or -1
+func errorLogSeverity(level string) int {
+	for i, l := range ErrorLogLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// StartErrorLogTail polls the nginx error log every interval and pushes new
+// lines to clients subscribed to the error_log topic, until ctx is done.
+// Lines written while nobody is subscribed are skipped, and a rotated or
+// truncated log is followed from its start.
+func (s *MonitoringService) StartErrorLogTail(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	path := s.getErrorLogPath()
+	logger.Info("Started nginx error log tail", logger.String("path", path), logger.Duration("interval", interval))
+
+	// Start at the current end; GetErrorLog serves what came before
+	var offset int64
+	var current os.FileInfo
+	if info, err := os.Stat(path); err == nil {
+		offset, current = info.Size(), info
+	}
+	var partial []byte
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				logger.Error("Failed to stat nginx error log", logger.String("path", path), logger.Err(err))
+			}
+			offset, current, partial = 0, nil, nil
+			continue
+		}
+		if current != nil && (!os.SameFile(current, info) || info.Size() < offset) {
+			offset, partial = 0, nil
+		}
+		current = info
+		if info.Size() == offset {
+			continue
+		}
+
+		// Nobody is listening, so skip ahead instead of reading
+		if !s.hasSubscriber(TopicErrorLog) {
+			offset, partial = info.Size(), nil
+			continue
+		}
+
+		data, err := readFileRange(path, offset, info.Size())
+		if err != nil {
+			logger.Error("Failed to read nginx error log", logger.String("path", path), logger.Err(err))
+			continue
+		}
+		offset += int64(len(data))
+
+		// Hold back an unfinished last line until nginx completes it
+		data = append(partial, data...)
+		end := bytes.LastIndexByte(data, '\n')
+		if end < 0 {
+			partial = data
+			continue
+		}
+		partial = append([]byte(nil), data[end+1:]...)
+
+		var entries []ErrorLogEntry
+		for _, line := range strings.Split(string(data[:end]), "\n") {
+			if line = strings.TrimRight(line, "\r"); line != "" {
+				entries = append(entries, ParseErrorLogLine(line))
+			}
+		}
+		if len(entries) > 0 {
+			s.publishAdminEvent(TopicErrorLog, TopicErrorLog, entries)
+		}
+	}
+}
+
+// readFileRange reads path from offset up to end
+func readFileRange(path string, offset, end int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data := make([]byte, end-offset)
+	n, err := file.ReadAt(data, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data[:n], nil
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// DefaultNginxBinary is the nginx binary used when no path is configured,
+// looked up on PATH
+const DefaultNginxBinary = "nginx"
+
+// Nginx control strategies select how nginx is started, stopped and reloaded
+const (
+	NginxControlDirect  = "direct"  // run the nginx binary and signal it
+	NginxControlSystemd = "systemd" // systemctl on the nginx unit
+	NginxControlDocker  = "docker"  // docker exec and start/stop on the nginx container
+)
+
+var nginxControlStrategies = []string{NginxControlDirect, NginxControlSystemd, NginxControlDocker}
+
+// NginxRunner runs nginx commands. Services use it instead of executing the
+// binary directly, so the binary can be configured and replaced in tests.
+type NginxRunner interface {
+	// Test checks a configuration file, or the default configuration when
+	// configPath is empty
+	Test(configPath string) error
+	Reload() error
+	Start() error
+	Stop() error
+	Restart() error
+	Version() (string, error)
+	IsRunning() bool
+	PID() (int, error)
+	// Strategy names the control strategy, such as systemd
+	Strategy() string
+}
+
+// NginxCommandError reports an nginx command that failed, with its output
+type NginxCommandError struct {
+	Args   []string
+	Output string
+	Err    error
+}
+
+func (e *NginxCommandError) Error() string {
+	output := strings.TrimSpace(e.Output)
+	if output == "" {
+		output = e.Err.Error()
+	}
+	return fmt.Sprintf("%s failed: %s", strings.Join(e.Args, " "), output)
+}
+
+func (e *NginxCommandError) Unwrap() error {
+	return e.Err
+}
+
+// nginxOutput returns what nginx printed for a failed command, or the error
+// itself when nginx could not be run
+func nginxOutput(err error) string {
+	var commandErr *NginxCommandError
+	if errors.As(err, &commandErr) && strings.TrimSpace(commandErr.Output) != "" {
+		return commandErr.Output
+	}
+	return err.Error()
+}
+
+// ExecNginxRunner runs the nginx binary at a configured path. With the
+// systemd strategy nginx is started, stopped and reloaded through systemctl;
+// with the docker strategy every command runs in the nginx container.
+type ExecNginxRunner struct {
+	binaryPath string
+	strategy   string
+	target     string // systemd unit or docker container
+}
+
+// NewExecNginxRunner creates a runner for the nginx binary at binaryPath,
+// falling back to DefaultNginxBinary when it is empty. It controls nginx
+// directly until SetControl selects another strategy.
+func NewExecNginxRunner(binaryPath string) *ExecNginxRunner {
+	if binaryPath == "" {
+		binaryPath = DefaultNginxBinary
+	}
+	return &ExecNginxRunner{binaryPath: binaryPath, strategy: NginxControlDirect}
+}
+
+// SetControl selects the control strategy. target is the systemd unit,
+// defaulting to nginx, or the docker container, which is required.
+func (r *ExecNginxRunner) SetControl(strategy, target string) error {
+	if !containsString(nginxControlStrategies, strategy) {
+		return fmt.Errorf("invalid nginx control strategy %q, expected one of: %s", strategy, strings.Join(nginxControlStrategies, ", "))
+	}
+	if strategy == NginxControlSystemd && target == "" {
+		target = "nginx"
+	}
+	if strategy == NginxControlDocker && target == "" {
+		return fmt.Errorf("the docker nginx control strategy needs the nginx container name")
+	}
+	r.strategy = strategy
+	r.target = target
+	return nil
+}
+
+// Strategy returns the control strategy
+func (r *ExecNginxRunner) Strategy() string {
+	return r.strategy
+}
+
+// CheckAvailable reports whether the commands of the control strategy can be
+// found, so a misconfiguration is reported at startup instead of on the
+// first reload
+func (r *ExecNginxRunner) CheckAvailable() error {
+	command := r.binaryPath
+	switch r.strategy {
+	case NginxControlSystemd:
+		command = "systemctl"
+	case NginxControlDocker:
+		command = "docker"
+	}
+	if _, err := exec.LookPath(command); err != nil {
+		return fmt.Errorf("%s is not available for the %s nginx control strategy: %w", command, r.strategy, err)
+	}
+	return nil
+}
+
+// Test runs nginx -t, returning a *NginxCommandError with the nginx output
+// when the configuration is rejected. With docker, a config file is copied
+// into the container before testing.
+func (r *ExecNginxRunner) Test(configPath string) error {
+	args := []string{"-t"}
+	if configPath == "" {
+		_, err := r.runNginx(args...)
+		return err
+	}
+
+	if r.strategy == NginxControlDocker {
+		containerPath := "/tmp/" + filepath.Base(configPath)
+		if _, err := r.run("docker", "cp", configPath, r.target+":"+containerPath); err != nil {
+			return err
+		}
+		defer r.run("docker", "exec", r.target, "rm", "-f", containerPath)
+		configPath = containerPath
+	}
+
+	_, err := r.runNginx(append(args, "-c", configPath)...)
+	return err
+}
+
+// Reload reloads the nginx configuration
+func (r *ExecNginxRunner) Reload() error {
+	if r.strategy == NginxControlSystemd {
+		_, err := r.run("systemctl", "reload", r.target)
+		return err
+	}
+	_, err := r.runNginx("-s", "reload")
+	return err
+}
+
+// Start starts nginx
+func (r *ExecNginxRunner) Start() error {
+	var err error
+	switch r.strategy {
+	case NginxControlSystemd:
+		_, err = r.run("systemctl", "start", r.target)
+	case NginxControlDocker:
+		_, err = r.run("docker", "start", r.target)
+	default:
+		_, err = r.run(r.binaryPath)
+	}
+	return err
+}
+
+// Stop stops nginx, letting workers finish their requests
+func (r *ExecNginxRunner) Stop() error {
+	var err error
+	switch r.strategy {
+	case NginxControlSystemd:
+		_, err = r.run("systemctl", "stop", r.target)
+	case NginxControlDocker:
+		_, err = r.run("docker", "stop", r.target)
+	default:
+		_, err = r.run(r.binaryPath, "-s", "quit")
+	}
+	return err
+}
+
+// Restart stops and starts nginx
+func (r *ExecNginxRunner) Restart() error {
+	var err error
+	switch r.strategy {
+	case NginxControlSystemd:
+		_, err = r.run("systemctl", "restart", r.target)
+	case NginxControlDocker:
+		_, err = r.run("docker", "restart", r.target)
+	default:
+		if err = r.Stop(); err == nil {
+			err = r.Start()
+		}
+	}
+	return err
+}
+
+// Version returns the nginx version, such as 1.18.0
+func (r *ExecNginxRunner) Version() (string, error) {
+	output, err := r.runNginx("-v")
+	if err != nil {
+		return "", err
+	}
+
+	// Parse version from output like "nginx version: nginx/1.18.0"
+	version := strings.TrimSpace(output)
+	if strings.Contains(version, "nginx/") {
+		parts := strings.Split(version, "nginx/")
+		if len(parts) > 1 {
+			return parts[1], nil
+		}
+	}
+
+	return version, nil
+}
+
+// IsRunning checks if nginx is running
+func (r *ExecNginxRunner) IsRunning() bool {
+	switch r.strategy {
+	case NginxControlSystemd:
+		return exec.Command("systemctl", "is-active", "--quiet", r.target).Run() == nil
+	case NginxControlDocker:
+		output, err := r.run("docker", "inspect", "-f", "{{.State.Running}}", r.target)
+		return err == nil && strings.TrimSpace(output) == "true"
+	}
+
+	if runtime.GOOS == "windows" {
+		cmd := exec.Command("tasklist", "/fi", "imagename eq nginx.exe")
+		output, err := cmd.Output()
+		if err != nil {
+			return false
+		}
+		return strings.Contains(string(output), "nginx.exe")
+	}
+
+	// For Linux/Unix
+	cmd := exec.Command("pgrep", "nginx")
+	err := cmd.Run()
+	return err == nil
+}
+
+// PID returns the PID of the nginx master process
+func (r *ExecNginxRunner) PID() (int, error) {
+	switch r.strategy {
+	case NginxControlSystemd:
+		return r.parsePID("systemctl", "show", "-p", "MainPID", "--value", r.target)
+	case NginxControlDocker:
+		return r.parsePID("docker", "inspect", "-f", "{{.State.Pid}}", r.target)
+	}
+
+	// For Windows
+	if runtime.GOOS == "windows" {
+		cmd := exec.Command("tasklist", "/fi", "imagename eq nginx.exe", "/fo", "csv")
+		output, err := cmd.Output()
+		if err != nil {
+			return 0, err
+		}
+
+		lines := strings.Split(string(output), "\n")
+		if len(lines) > 1 {
+			fields := strings.Split(lines[1], ",")
+			if len(fields) > 1 {
+				pidStr := strings.Trim(fields[1], `"`)
+				return strconv.Atoi(pidStr)
+			}
+		}
+		return 0, fmt.Errorf("nginx not found")
+	}
+
+	// For Linux/Unix
+	return r.parsePID("pgrep", "-f", "nginx: master")
+}
+
+// parsePID runs a command printing a PID; 0 means nginx is not running
+func (r *ExecNginxRunner) parsePID(name string, args ...string) (int, error) {
+	output, err := exec.Command(name, args...).Output()
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return 0, err
+	}
+	if pid == 0 {
+		return 0, fmt.Errorf("nginx not running")
+	}
+	return pid, nil
+}
+
+// runNginx runs the nginx binary, inside the container with docker
+func (r *ExecNginxRunner) runNginx(args ...string) (string, error) {
+	if r.strategy == NginxControlDocker {
+		return r.run("docker", append([]string{"exec", r.target, "nginx"}, args...)...)
+	}
+	return r.run(r.binaryPath, args...)
+}
+
+// run executes a command and returns its combined output
+func (r *ExecNginxRunner) run(name string, args ...string) (string, error) {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return string(output), &NginxCommandError{Args: append([]string{name}, args...), Output: string(output), Err: err}
+	}
+	return string(output), nil
+}
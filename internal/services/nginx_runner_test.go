@@ -0,0 +1,259 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// MockNginxRunner is an NginxRunner for tests. It returns the configured
+// results and records the calls made to it.
+type MockNginxRunner struct {
+	mutex sync.Mutex
+
+	TestErr        error
+	ReloadErr      error
+	ControlErr     error // returned by Start, Stop and Restart
+	VersionString  string
+	VersionErr     error
+	Running        bool
+	ProcessID      int
+	PIDErr         error
+	StrategyName   string
+	TestedConfigs  []string
+	ReloadCount    int
+	ReloadErrQueue []error  // returned by successive reloads before ReloadErr
+	Actions        []string // start, stop and restart calls in order
+}
+
+// Test records configPath and returns TestErr
+func (m *MockNginxRunner) Test(configPath string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.TestedConfigs = append(m.TestedConfigs, configPath)
+	return m.TestErr
+}
+
+// Reload counts the reload and returns the next queued error, or ReloadErr
+func (m *MockNginxRunner) Reload() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.ReloadCount++
+	if len(m.ReloadErrQueue) > 0 {
+		err := m.ReloadErrQueue[0]
+		m.ReloadErrQueue = m.ReloadErrQueue[1:]
+		return err
+	}
+	return m.ReloadErr
+}
+
+// Start records the action and returns ControlErr
+func (m *MockNginxRunner) Start() error {
+	return m.control("start")
+}
+
+// Stop records the action and returns ControlErr
+func (m *MockNginxRunner) Stop() error {
+	return m.control("stop")
+}
+
+// Restart records the action and returns ControlErr
+func (m *MockNginxRunner) Restart() error {
+	return m.control("restart")
+}
+
+func (m *MockNginxRunner) control(action string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Actions = append(m.Actions, action)
+	return m.ControlErr
+}
+
+// Version returns VersionString and VersionErr
+func (m *MockNginxRunner) Version() (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.VersionString, m.VersionErr
+}
+
+// IsRunning returns Running
+func (m *MockNginxRunner) IsRunning() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.Running
+}
+
+// PID returns ProcessID and PIDErr
+func (m *MockNginxRunner) PID() (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.ProcessID, m.PIDErr
+}
+
+// Strategy returns StrategyName, defaulting to direct
+func (m *MockNginxRunner) Strategy() string {
+	if m.StrategyName == "" {
+		return NginxControlDirect
+	}
+	return m.StrategyName
+}
+
+// fakeNginx writes a shell script standing in for the nginx binary. It prints
+// its arguments, and with -t fails when the config file contains "invalid".
+func fakeNginx(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake nginx binary is a shell script")
+	}
+
+	path := filepath.Join(t.TempDir(), "nginx")
+	script := `#!/bin/sh
+if [ "$1" = "-v" ]; then
+	echo "nginx version: nginx/1.25.3" >&2
+	exit 0
+fi
+echo "nginx $*"
+if [ "$1" = "-t" ] && [ -n "$3" ] && grep -q invalid "$3"; then
+	echo "nginx: [emerg] unknown directive \"invalid\" in $3:1" >&2
+	exit 1
+fi
+`
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExecNginxRunnerSetControl(t *testing.T) {
+	for _, tc := range []struct {
+		strategy, target string
+		wantTarget       string
+		wantErr          bool
+	}{
+		{strategy: NginxControlDirect},
+		{strategy: NginxControlSystemd, wantTarget: "nginx"},
+		{strategy: NginxControlSystemd, target: "openresty", wantTarget: "openresty"},
+		{strategy: NginxControlDocker, target: "proxy", wantTarget: "proxy"},
+		{strategy: NginxControlDocker, wantErr: true},
+		{strategy: "supervisord", wantErr: true},
+	} {
+		r := NewExecNginxRunner("")
+		err := r.SetControl(tc.strategy, tc.target)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s %q: expected an error", tc.strategy, tc.target)
+			}
+			if r.Strategy() != NginxControlDirect {
+				t.Errorf("%s %q: a rejected strategy replaced direct control", tc.strategy, tc.target)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %q: %v", tc.strategy, tc.target, err)
+			continue
+		}
+		if r.Strategy() != tc.strategy || r.target != tc.wantTarget {
+			t.Errorf("%s %q: got strategy %s and target %q, want target %q", tc.strategy, tc.target, r.Strategy(), r.target, tc.wantTarget)
+		}
+	}
+}
+
+func TestExecNginxRunnerTest(t *testing.T) {
+	r := NewExecNginxRunner(fakeNginx(t))
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "valid.conf")
+	invalid := filepath.Join(dir, "invalid.conf")
+	if err := os.WriteFile(valid, []byte("events {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalid, []byte("invalid;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Test(valid); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+
+	err := r.Test(invalid)
+	var commandErr *NginxCommandError
+	if !errors.As(err, &commandErr) {
+		t.Fatalf("invalid config: got %v, want a *NginxCommandError", err)
+	}
+	if got := strings.Join(commandErr.Args[1:], " "); got != "-t -c "+invalid {
+		t.Errorf("ran nginx %s, want nginx -t -c %s", got, invalid)
+	}
+	if output := nginxOutput(err); !strings.Contains(output, `unknown directive "invalid"`) {
+		t.Errorf("nginx output %q does not explain the failure", output)
+	}
+}
+
+func TestExecNginxRunnerVersion(t *testing.T) {
+	version, err := NewExecNginxRunner(fakeNginx(t)).Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.25.3" {
+		t.Errorf("got version %q, want 1.25.3", version)
+	}
+}
+
+func TestExecNginxRunnerMissingBinary(t *testing.T) {
+	err := NewExecNginxRunner("nginx-manager-no-such-nginx").Test("")
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("got %v, want %v", err, exec.ErrNotFound)
+	}
+}
+
+// TestValidateStreamConfigUsesRunner checks that a stream's configuration is
+// tested through the nginx runner, and skipped when nginx is not installed
+func TestValidateStreamConfigUsesRunner(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		testErr error
+		wantErr bool
+	}{
+		{name: "accepted"},
+		{name: "rejected", testErr: &NginxCommandError{Args: []string{"nginx", "-t"}, Output: "nginx: [emerg] invalid port\n", Err: errors.New("exit status 1")}, wantErr: true},
+		{name: "not installed", testErr: &NginxCommandError{Args: []string{"nginx", "-t"}, Err: &exec.Error{Name: "nginx", Err: exec.ErrNotFound}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newTestDB(t)
+			owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+
+			runner := &MockNginxRunner{TestErr: tc.testErr}
+			s := NewStreamService(t.TempDir(), NewAuthService("test"))
+			s.SetNginxService(newTestNginxService(t, runner))
+
+			req := &StreamRequest{IncomingPort: 2222, ForwardingHost: "10.0.0.5", ForwardingPort: 22}
+			_, err := s.CreateStream(owner.ID, req)
+			if len(runner.TestedConfigs) == 0 || !strings.Contains(runner.TestedConfigs[0], "nginx_stream_test") {
+				t.Fatalf("tested configs %q, want the stream config first", runner.TestedConfigs)
+			}
+
+			// Without nginx the reload still fails, but the stream is saved
+			var configErr *StreamConfigError
+			var count int64
+			db.Model(&models.Stream{}).Count(&count)
+			if !tc.wantErr {
+				if errors.As(err, &configErr) || count != 1 {
+					t.Fatalf("got %v with %d streams saved, want the stream saved", err, count)
+				}
+				return
+			}
+			if !errors.As(err, &configErr) || configErr.Output != "nginx: [emerg] invalid port" {
+				t.Fatalf("got %v, want a *StreamConfigError with the nginx output", err)
+			}
+			if count != 0 {
+				t.Errorf("a rejected stream was saved")
+			}
+		})
+	}
+}
@@ -3,8 +3,11 @@ package services
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
@@ -20,6 +23,12 @@ var (
 	ErrInvalidDomainName     = errors.New("invalid domain name")
 	ErrNginxConfigGeneration = errors.New("failed to generate nginx configuration")
 	ErrNginxReload           = errors.New("failed to reload nginx")
+	ErrInvalidProxyBind      = errors.New("proxy bind address must be an IP assigned to this host")
+	ErrInvalidProxyBuffers   = errors.New("proxy buffers must be a count and size such as \"8 16k\"")
+	ErrInvalidBufferSize     = errors.New("proxy buffer size must be a size such as \"16k\" or \"1m\"")
+	ErrInvalidKeepalive      = fmt.Errorf("upstream keepalive must be between 0 and %d connections", MaxUpstreamKeepalive)
+	ErrInvalidRateLimitRate  = errors.New("rate limit requests per second must be greater than 0")
+	ErrInvalidRateLimitBurst = errors.New("rate limit burst must not be negative")
 )
 
 // NginxService handles nginx configuration management
@@ -30,6 +39,58 @@ type NginxService struct {
 	backupPath   string
 	templatePath string
 	authService  *AuthService
+	nginxRunner  NginxRunner
+
+	// When staged, proxy host edits are only marked pending until ApplyPendingChanges
+	stagedDeploy bool
+
+	// Restricts where proxy hosts may forward to; nil allows anywhere
+	forwardTargets *ForwardTargetPolicy
+
+	// Default interval between upstream health probes; 0 when they are off
+	healthCheckInterval time.Duration
+}
+
+// PendingChange describes a staged proxy host change that has not been deployed
+type PendingChange struct {
+	ProxyHostID   uint     `json:"proxy_host_id"`
+	PrimaryDomain string   `json:"primary_domain"`
+	Action        string   `json:"action"` // create, update, remove
+	Diff          []string `json:"diff"`
+}
+
+// OrphanedConfig is a proxy host config file in the sites directory with no
+// corresponding proxy host record
+type OrphanedConfig struct {
+	File        string    `json:"file"`
+	ProxyHostID uint      `json:"proxy_host_id"`
+	Size        int64     `json:"size"`
+	ModifiedAt  time.Time `json:"modified_at"`
+}
+
+// OrphanCleanupResult summarizes the removal of orphaned config files
+type OrphanCleanupResult struct {
+	Removed []OrphanedConfig `json:"removed"`
+	Failed  []string         `json:"failed"`
+	Count   int              `json:"count"`
+}
+
+var (
+	nginxSizePattern    = regexp.MustCompile(`^[1-9][0-9]*[kKmM]?$`)
+	proxyBuffersPattern = regexp.MustCompile(`^([1-9][0-9]*)\s+([1-9][0-9]*[kKmM]?)$`)
+)
+
+// MaxUpstreamKeepalive caps the idle upstream connections cached per worker
+const MaxUpstreamKeepalive = 1024
+
+// proxyHostConfigPattern matches config files written by generateConfig
+var proxyHostConfigPattern = regexp.MustCompile(`^proxy_host_(\d+)\.conf$`)
+
+// ApplyResult summarizes a batch deployment of pending changes
+type ApplyResult struct {
+	Applied []PendingChange `json:"applied"`
+	Failed  []string        `json:"failed"`
+	Count   int             `json:"count"`
 }
 
 // NewNginxService creates a new nginx service instance
@@ -41,27 +102,62 @@ func NewNginxService(configPath, sitesPath, backupPath, templatePath string, aut
 		backupPath:   backupPath,
 		templatePath: templatePath,
 		authService:  authService,
+		nginxRunner:  NewExecNginxRunner(DefaultNginxBinary),
 	}
 }
 
+// SetNginxRunner sets the runner used to test the configuration and reload nginx
+func (s *NginxService) SetNginxRunner(runner NginxRunner) {
+	s.nginxRunner = runner
+}
+
+// SetHealthCheckInterval sets the default upstream health check interval,
+// which per-host timeouts are validated against
+func (s *NginxService) SetHealthCheckInterval(interval time.Duration) {
+	s.healthCheckInterval = interval
+}
+
+// HealthCheckInterval returns the default upstream health check interval
+func (s *NginxService) HealthCheckInterval() time.Duration {
+	return s.healthCheckInterval
+}
+
+// SetStagedDeploy switches between immediate deployment and staged "apply pending changes" mode
+func (s *NginxService) SetStagedDeploy(enabled bool) {
+	s.stagedDeploy = enabled
+}
+
+// IsStagedDeploy returns true if proxy host edits are staged until applied
+func (s *NginxService) IsStagedDeploy() bool {
+	return s.stagedDeploy
+}
+
 // ProxyHostRequest represents proxy host create/update request
 type ProxyHostRequest struct {
-	DomainNames           []string               `json:"domain_names" binding:"required"`
-	ForwardScheme         models.ForwardScheme   `json:"forward_scheme" binding:"required"`
-	ForwardHost           string                 `json:"forward_host" binding:"required"`
-	ForwardPort           int                    `json:"forward_port" binding:"required"`
-	AccessListID          *uint                  `json:"access_list_id"`
-	CertificateID         *uint                  `json:"certificate_id"`
-	SSLForced             bool                   `json:"ssl_forced"`
-	CachingEnabled        bool                   `json:"caching_enabled"`
-	BlockExploits         bool                   `json:"block_exploits"`
-	AllowWebsocketUpgrade bool                   `json:"allow_websocket_upgrade"`
-	HTTP2Support          bool                   `json:"http2_support"`
-	HSTSEnabled           bool                   `json:"hsts_enabled"`
-	HSTSSubdomains        bool                   `json:"hsts_subdomains"`
-	AdvancedConfig        string                 `json:"advanced_config"`
-	Enabled               bool                   `json:"enabled"`
-	Locations             map[string]interface{} `json:"locations"`
+	DomainNames           []string                  `json:"domain_names" binding:"required"`
+	ForwardScheme         models.ForwardScheme      `json:"forward_scheme" binding:"required"`
+	ForwardHost           string                    `json:"forward_host" binding:"required"`
+	ForwardPort           int                       `json:"forward_port" binding:"required"`
+	AccessListID          *uint                     `json:"access_list_id"`
+	CertificateID         *uint                     `json:"certificate_id"`
+	SSLForced             bool                      `json:"ssl_forced"`
+	CachingEnabled        bool                      `json:"caching_enabled"`
+	BlockExploits         bool                      `json:"block_exploits"`
+	AllowWebsocketUpgrade bool                      `json:"allow_websocket_upgrade"`
+	HTTP2Support          bool                      `json:"http2_support"`
+	HSTSEnabled           bool                      `json:"hsts_enabled"`
+	HSTSSubdomains        bool                      `json:"hsts_subdomains"`
+	OCSPStapling          bool                      `json:"ocsp_stapling"`
+	AdvancedConfig        string                    `json:"advanced_config"`
+	ProxyBind             string                    `json:"proxy_bind"`
+	ProxyBuffering        *bool                     `json:"proxy_buffering"`
+	ProxyBufferSize       string                    `json:"proxy_buffer_size"`
+	ProxyBuffers          string                    `json:"proxy_buffers"`
+	UpstreamKeepalive     int                       `json:"upstream_keepalive"`
+	RateLimit             *models.RateLimitConfig   `json:"rate_limit"`
+	HealthCheck           *models.HealthCheckConfig `json:"health_check"`
+	Enabled               bool                      `json:"enabled"`
+	Locations             map[string]interface{}    `json:"locations"`
 }
 
 // CreateProxyHost creates a new proxy host
@@ -81,6 +177,35 @@ func (s *NginxService) CreateProxyHost(userID uint, req *ProxyHostRequest) (*mod
 		return nil, errors.New("invalid forward scheme")
 	}
 
+	if err := s.CheckForwardTarget(req.ForwardHost); err != nil {
+		return nil, err
+	}
+
+	// Validate outgoing source address
+	if err := ValidateProxyBind(req.ProxyBind); err != nil {
+		return nil, err
+	}
+
+	// Validate proxy buffer sizes
+	if err := ValidateProxyBuffers(req.ProxyBufferSize, req.ProxyBuffers); err != nil {
+		return nil, err
+	}
+
+	// Validate upstream keepalive
+	if err := ValidateUpstreamKeepalive(req.UpstreamKeepalive); err != nil {
+		return nil, err
+	}
+
+	// Validate rate limiting
+	if err := ValidateRateLimit(req.RateLimit); err != nil {
+		return nil, err
+	}
+
+	// Validate upstream health checks
+	if err := ValidateHealthCheck(req.HealthCheck, s.healthCheckInterval); err != nil {
+		return nil, err
+	}
+
 	// Create proxy host model
 	proxyHost := &models.ProxyHost{
 		DomainNames:           models.StringArray(req.DomainNames),
@@ -96,7 +221,15 @@ func (s *NginxService) CreateProxyHost(userID uint, req *ProxyHostRequest) (*mod
 		HTTP2Support:          req.HTTP2Support,
 		HSTSEnabled:           req.HSTSEnabled,
 		HSTSSubdomains:        req.HSTSSubdomains,
+		OCSPStapling:          req.OCSPStapling,
 		AdvancedConfig:        req.AdvancedConfig,
+		ProxyBind:             req.ProxyBind,
+		ProxyBuffering:        req.ProxyBuffering,
+		ProxyBufferSize:       req.ProxyBufferSize,
+		ProxyBuffers:          req.ProxyBuffers,
+		UpstreamKeepalive:     req.UpstreamKeepalive,
+		RateLimit:             req.RateLimit,
+		HealthCheck:           req.HealthCheck,
 		Enabled:               req.Enabled,
 		Locations:             models.JSON(req.Locations),
 		UserID:                userID,
@@ -107,6 +240,14 @@ func (s *NginxService) CreateProxyHost(userID uint, req *ProxyHostRequest) (*mod
 		return nil, err
 	}
 
+	// Stage the change instead of deploying it
+	if s.stagedDeploy {
+		if err := s.markPending(proxyHost); err != nil {
+			return nil, err
+		}
+		return proxyHost, nil
+	}
+
 	// Generate nginx configuration
 	if err := s.generateConfig(proxyHost); err != nil {
 		// Rollback database changes
@@ -125,23 +266,23 @@ func (s *NginxService) CreateProxyHost(userID uint, req *ProxyHostRequest) (*mod
 }
 
 // UpdateProxyHost updates an existing proxy host
-func (s *NginxService) UpdateProxyHost(userID uint, id uint, req *ProxyHostRequest) (*models.ProxyHost, error) {
+func (s *NginxService) UpdateProxyHost(userID uint, id uint, req *ProxyHostRequest, audit AuditContext) (*models.ProxyHost, error) {
 	// Find existing proxy host
 	var proxyHost models.ProxyHost
-	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&proxyHost).Error; err != nil {
+	if err := s.authService.ScopeToOwner(s.db, userID, models.PermissionProxyHostManage).Where("id = ?", id).First(&proxyHost).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, ErrProxyHostNotFound
 		}
 		return nil, err
 	}
 
-	// Check admin permission for cross-user management
-	if proxyHost.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return nil, err
-		}
+	// Another user's proxy host needs proxy_host:manage
+	if err := s.authService.RequireOwnerOrPermission(userID, proxyHost.UserID, models.PermissionProxyHostWrite, models.PermissionProxyHostManage); err != nil {
+		return nil, err
 	}
 
+	before := proxyHost
+
 	// Validate domain names
 	if err := s.validateDomainNames(req.DomainNames); err != nil {
 		return nil, err
@@ -152,6 +293,35 @@ func (s *NginxService) UpdateProxyHost(userID uint, id uint, req *ProxyHostReque
 		return nil, err
 	}
 
+	if err := s.CheckForwardTarget(req.ForwardHost); err != nil {
+		return nil, err
+	}
+
+	// Validate outgoing source address
+	if err := ValidateProxyBind(req.ProxyBind); err != nil {
+		return nil, err
+	}
+
+	// Validate proxy buffer sizes
+	if err := ValidateProxyBuffers(req.ProxyBufferSize, req.ProxyBuffers); err != nil {
+		return nil, err
+	}
+
+	// Validate upstream keepalive
+	if err := ValidateUpstreamKeepalive(req.UpstreamKeepalive); err != nil {
+		return nil, err
+	}
+
+	// Validate rate limiting
+	if err := ValidateRateLimit(req.RateLimit); err != nil {
+		return nil, err
+	}
+
+	// Validate upstream health checks
+	if err := ValidateHealthCheck(req.HealthCheck, s.healthCheckInterval); err != nil {
+		return nil, err
+	}
+
 	// Backup current configuration
 	if err := s.backupConfig(&proxyHost); err != nil {
 		logger.Warn("Failed to backup config", logger.Err(err))
@@ -171,7 +341,15 @@ func (s *NginxService) UpdateProxyHost(userID uint, id uint, req *ProxyHostReque
 	proxyHost.HTTP2Support = req.HTTP2Support
 	proxyHost.HSTSEnabled = req.HSTSEnabled
 	proxyHost.HSTSSubdomains = req.HSTSSubdomains
+	proxyHost.OCSPStapling = req.OCSPStapling
 	proxyHost.AdvancedConfig = req.AdvancedConfig
+	proxyHost.ProxyBind = req.ProxyBind
+	proxyHost.ProxyBuffering = req.ProxyBuffering
+	proxyHost.ProxyBufferSize = req.ProxyBufferSize
+	proxyHost.ProxyBuffers = req.ProxyBuffers
+	proxyHost.UpstreamKeepalive = req.UpstreamKeepalive
+	proxyHost.RateLimit = req.RateLimit
+	proxyHost.HealthCheck = req.HealthCheck
 	proxyHost.Enabled = req.Enabled
 	proxyHost.Locations = models.JSON(req.Locations)
 
@@ -179,6 +357,16 @@ func (s *NginxService) UpdateProxyHost(userID uint, id uint, req *ProxyHostReque
 	if err := s.db.Save(&proxyHost).Error; err != nil {
 		return nil, err
 	}
+	recordUpdateAudit(s.db, audit, userID, models.ObjectTypeProxyHost, proxyHost.ID,
+		fmt.Sprintf("Updated proxy host: %s", proxyHost.GetPrimaryDomain()), &before, &proxyHost)
+
+	// Stage the change instead of deploying it
+	if s.stagedDeploy {
+		if err := s.markPending(&proxyHost); err != nil {
+			return nil, err
+		}
+		return &proxyHost, nil
+	}
 
 	// Regenerate nginx configuration
 	if err := s.generateConfig(&proxyHost); err != nil {
@@ -197,18 +385,15 @@ func (s *NginxService) UpdateProxyHost(userID uint, id uint, req *ProxyHostReque
 func (s *NginxService) DeleteProxyHost(userID uint, id uint) error {
 	// Find proxy host
 	var proxyHost models.ProxyHost
-	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&proxyHost).Error; err != nil {
+	if err := s.authService.ScopeToOwner(s.db, userID, models.PermissionProxyHostManage).Where("id = ?", id).First(&proxyHost).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return ErrProxyHostNotFound
 		}
 		return err
 	}
 
-	// Check admin permission for cross-user management
-	if proxyHost.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return err
-		}
+	if err := s.authService.RequireOwnerOrPermission(userID, proxyHost.UserID, models.PermissionProxyHostDelete, models.PermissionProxyHostManage); err != nil {
+		return err
 	}
 
 	// Backup configuration before deletion
@@ -216,6 +401,14 @@ func (s *NginxService) DeleteProxyHost(userID uint, id uint) error {
 		logger.Warn("Failed to backup config before deletion", logger.Err(err))
 	}
 
+	// Stage the removal: the soft-deleted row keeps its pending flag until applied
+	if s.stagedDeploy {
+		if err := s.markPending(&proxyHost); err != nil {
+			return err
+		}
+		return s.db.Delete(&proxyHost).Error
+	}
+
 	// Delete from database
 	if err := s.db.Delete(&proxyHost).Error; err != nil {
 		return err
@@ -239,8 +432,8 @@ func (s *NginxService) GetProxyHost(userID uint, id uint) (*models.ProxyHost, er
 	var proxyHost models.ProxyHost
 	query := s.db.Preload("User").Preload("Certificate").Preload("AccessList")
 
-	// Admin can see all proxy hosts
-	if s.authService.RequireAdmin(userID) == nil {
+	// Viewers, operators and admins can see all proxy hosts
+	if s.authService.HasPermission(userID, models.PermissionProxyHostReadAll) {
 		query = query.Where("id = ?", id)
 	} else {
 		query = query.Where("id = ? AND user_id = ?", id, userID)
@@ -263,8 +456,8 @@ func (s *NginxService) ListProxyHosts(userID uint, offset, limit int) ([]models.
 
 	query := s.db.Model(&models.ProxyHost{}).Preload("User").Preload("Certificate").Preload("AccessList")
 
-	// Admin can see all proxy hosts
-	if s.authService.RequireAdmin(userID) != nil {
+	// Viewers, operators and admins can see all proxy hosts
+	if !s.authService.HasPermission(userID, models.PermissionProxyHostReadAll) {
 		query = query.Where("user_id = ?", userID)
 	}
 
@@ -297,30 +490,35 @@ func (s *NginxService) validateDomainNames(domains []string) error {
 	return nil
 }
 
-// checkDuplicateDomains checks for duplicate domain names
+// checkDuplicateDomains checks that no live host already uses the domains
 func (s *NginxService) checkDuplicateDomains(excludeID uint, domains []string) error {
-	for _, domain := range domains {
-		var count int64
-		query := s.db.Model(&models.ProxyHost{}).Where("JSON_EXTRACT(domain_names, '$') LIKE ?", "%"+domain+"%")
-
-		if excludeID > 0 {
-			query = query.Where("id != ?", excludeID)
-		}
+	return CheckDomainsAvailable(s.db, domains, (&models.ProxyHost{}).TableName(), excludeID)
+}
 
-		if err := query.Count(&count).Error; err != nil {
-			return err
-		}
+// generateConfig generates nginx configuration for proxy host
+func (s *NginxService) generateConfig(proxyHost *models.ProxyHost) error {
+	configContent, err := s.buildConfig(proxyHost)
+	if err != nil {
+		return err
+	}
 
-		if count > 0 {
-			return fmt.Errorf("domain %s is already in use", domain)
-		}
+	// Write configuration file
+	configFile := filepath.Join(s.sitesPath, fmt.Sprintf("proxy_host_%d.conf", proxyHost.ID))
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// generateConfig generates nginx configuration for proxy host
-func (s *NginxService) generateConfig(proxyHost *models.ProxyHost) error {
+// BuildProxyHostConfig renders the nginx configuration for a proxy host
+// without writing it, so a change can be previewed before it is saved
+func (s *NginxService) BuildProxyHostConfig(proxyHost *models.ProxyHost) (string, error) {
+	return s.buildConfig(proxyHost)
+}
+
+// buildConfig renders the nginx configuration for a proxy host without writing it
+func (s *NginxService) buildConfig(proxyHost *models.ProxyHost) (string, error) {
 	// Load certificate if specified
 	var certificate *models.Certificate
 	if proxyHost.CertificateID != nil {
@@ -332,40 +530,33 @@ func (s *NginxService) generateConfig(proxyHost *models.ProxyHost) error {
 	// Load access list if specified
 	var accessList *models.AccessList
 	if proxyHost.AccessListID != nil {
-		if err := s.db.Preload("AccessListAuths").Preload("AccessListClients").
+		if err := s.db.Preload("Items").
 			Where("id = ?", *proxyHost.AccessListID).First(&accessList).Error; err != nil {
 			logger.Warn("Failed to load access list", logger.Err(err))
 		}
 	}
 
 	// Generate configuration content
-	configContent, err := s.renderTemplate(proxyHost, certificate, accessList)
-	if err != nil {
-		return err
-	}
-
-	// Write configuration file
-	configFile := filepath.Join(s.sitesPath, fmt.Sprintf("proxy_host_%d.conf", proxyHost.ID))
-	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
-		return err
-	}
-
-	return nil
+	return s.renderTemplate(proxyHost, certificate, accessList)
 }
 
 // renderTemplate renders nginx configuration template
 func (s *NginxService) renderTemplate(proxyHost *models.ProxyHost, certificate *models.Certificate, accessList *models.AccessList) (string, error) {
 	templateFile := filepath.Join(s.templatePath, "proxy_host.tmpl")
 
+	wantsSSL := proxyHost.SSLForced || proxyHost.CertificateID != nil
+	ssl := s.resolveHostSSL(proxyHost.GetPrimaryDomain(), certificate, wantsSSL)
+
 	tmpl, err := template.ParseFiles(templateFile)
 	if err != nil {
 		// Fallback to basic template
-		return s.generateBasicConfig(proxyHost, certificate, accessList), nil
+		return s.generateBasicConfig(proxyHost, ssl, accessList), nil
 	}
 
 	data := map[string]interface{}{
 		"ProxyHost":   proxyHost,
 		"Certificate": certificate,
+		"SSL":         ssl,
 		"AccessList":  accessList,
 	}
 
@@ -377,15 +568,31 @@ func (s *NginxService) renderTemplate(proxyHost *models.ProxyHost, certificate *
 	return buf.String(), nil
 }
 
-// generateBasicConfig generates basic nginx configuration
-func (s *NginxService) generateBasicConfig(proxyHost *models.ProxyHost, certificate *models.Certificate, accessList *models.AccessList) string {
+// generateBasicConfig generates basic nginx configuration. ssl is nil for a
+// plain HTTP host.
+func (s *NginxService) generateBasicConfig(proxyHost *models.ProxyHost, ssl *hostSSL, accessList *models.AccessList) string {
 	var config strings.Builder
 
+	// Site files are included inside the http {} block of nginx.conf, so the
+	// zone is declared here, ahead of the server block; limit_req_zone is not
+	// allowed inside server {}
+	if proxyHost.HasRateLimit() {
+		config.WriteString(RateLimitZoneDirective(proxyHost) + "\n\n")
+	}
+
+	// Named upstream so idle connections to the backend are reused
+	if proxyHost.UsesNamedUpstream() {
+		config.WriteString(fmt.Sprintf("upstream %s {\n", proxyHost.GetUpstreamName()))
+		config.WriteString(fmt.Sprintf("    server %s;\n", proxyHost.GetUpstreamServer()))
+		config.WriteString(fmt.Sprintf("    keepalive %d;\n", proxyHost.UpstreamKeepalive))
+		config.WriteString("}\n\n")
+	}
+
 	// Server block
 	config.WriteString("server {\n")
 
 	// Listen directives
-	if certificate != nil && certificate.IsValid() {
+	if ssl != nil {
 		config.WriteString("    listen 443 ssl")
 		if proxyHost.HTTP2Support {
 			config.WriteString(" http2")
@@ -393,8 +600,25 @@ func (s *NginxService) generateBasicConfig(proxyHost *models.ProxyHost, certific
 		config.WriteString(";\n")
 
 		// SSL configuration
-		config.WriteString(fmt.Sprintf("    ssl_certificate /etc/nginx/certificates/cert_%d.pem;\n", certificate.ID))
-		config.WriteString(fmt.Sprintf("    ssl_certificate_key /etc/nginx/certificates/key_%d.pem;\n", certificate.ID))
+		if ssl.Fallback {
+			config.WriteString("    # No valid certificate assigned; serving the default certificate\n")
+		}
+		config.WriteString(fmt.Sprintf("    ssl_certificate %s;\n", ssl.CertificatePath))
+		config.WriteString(fmt.Sprintf("    ssl_certificate_key %s;\n", ssl.KeyPath))
+
+		// OCSP responses are verified against the issuer chain, which is the
+		// intermediate bundle when one was uploaded. The default certificate
+		// is not stapled.
+		if proxyHost.OCSPStapling && ssl.Certificate != nil {
+			certificate := ssl.Certificate
+			trustedCertificate := certificateFile(nginxCertificatesPath, "cert", certificate.ID)
+			if certificate.IntermediateCertificate != "" {
+				trustedCertificate = certificateFile(nginxCertificatesPath, "chain", certificate.ID)
+			}
+			config.WriteString("    ssl_stapling on;\n")
+			config.WriteString("    ssl_stapling_verify on;\n")
+			config.WriteString(fmt.Sprintf("    ssl_trusted_certificate %s;\n", trustedCertificate))
+		}
 	} else {
 		config.WriteString("    listen 80;\n")
 	}
@@ -409,20 +633,76 @@ func (s *NginxService) generateBasicConfig(proxyHost *models.ProxyHost, certific
 	// Access control
 	if accessList != nil {
 		config.WriteString("    # Access control\n")
-		// Add access control directives
+		hasAllowRules := false
+		for _, directive := range []models.AccessListDirective{models.AccessListDirectiveDeny, models.AccessListDirectiveAllow} {
+			for _, item := range accessList.GetEnabledItems() {
+				if !item.IsIPItem() || item.Directive != directive {
+					continue
+				}
+				if rule := item.GetNginxRule(); rule != "" {
+					config.WriteString("    " + rule + "\n")
+					hasAllowRules = hasAllowRules || directive == models.AccessListDirectiveAllow
+				}
+			}
+		}
+		if hasAllowRules {
+			config.WriteString("    deny all;\n")
+		}
+		for _, item := range accessList.GetEnabledItems() {
+			if item.IsAuthItem() {
+				config.WriteString("    auth_basic \"Restricted Area\";\n")
+				config.WriteString(fmt.Sprintf("    auth_basic_user_file %s;\n", s.HtpasswdPath(accessList.ID)))
+				break
+			}
+		}
 	}
 
 	// Proxy configuration
 	config.WriteString("    location / {\n")
-	config.WriteString(fmt.Sprintf("        proxy_pass %s;\n", proxyHost.GetTargetURL()))
+	if proxyHost.UsesNamedUpstream() {
+		config.WriteString(fmt.Sprintf("        proxy_pass %s://%s;\n", proxyHost.ForwardScheme, proxyHost.GetUpstreamName()))
+	} else {
+		config.WriteString(fmt.Sprintf("        proxy_pass %s;\n", proxyHost.GetTargetURL()))
+	}
 	config.WriteString("        proxy_set_header Host $host;\n")
 	config.WriteString("        proxy_set_header X-Real-IP $remote_addr;\n")
 	config.WriteString("        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;\n")
 	config.WriteString("        proxy_set_header X-Forwarded-Proto $scheme;\n")
 
+	// Websockets and upstream keepalive both need HTTP/1.1 to the backend.
+	// With keepalive the client's Connection header is passed through so
+	// plain requests don't close the upstream connection.
+	if proxyHost.AllowWebsocketUpgrade || proxyHost.UsesNamedUpstream() {
+		config.WriteString("        proxy_http_version 1.1;\n")
+	}
 	if proxyHost.AllowWebsocketUpgrade {
 		config.WriteString("        proxy_set_header Upgrade $http_upgrade;\n")
-		config.WriteString("        proxy_set_header Connection \"upgrade\";\n")
+		if proxyHost.UsesNamedUpstream() {
+			config.WriteString("        proxy_set_header Connection $http_connection;\n")
+		} else {
+			config.WriteString("        proxy_set_header Connection \"upgrade\";\n")
+		}
+	} else if proxyHost.UsesNamedUpstream() {
+		config.WriteString("        proxy_set_header Connection \"\";\n")
+	}
+
+	if proxyHost.ProxyBind != "" {
+		config.WriteString(fmt.Sprintf("        proxy_bind %s;\n", proxyHost.ProxyBind))
+	}
+
+	if proxyHost.HasRateLimit() {
+		config.WriteString("        " + RateLimitDirective(proxyHost) + "\n")
+	}
+
+	// Buffering
+	if !proxyHost.IsProxyBufferingEnabled() {
+		config.WriteString("        proxy_buffering off;\n")
+	}
+	if proxyHost.ProxyBufferSize != "" {
+		config.WriteString(fmt.Sprintf("        proxy_buffer_size %s;\n", proxyHost.ProxyBufferSize))
+	}
+	if proxyHost.ProxyBuffers != "" {
+		config.WriteString(fmt.Sprintf("        proxy_buffers %s;\n", proxyHost.ProxyBuffers))
 	}
 
 	config.WriteString("    }\n")
@@ -441,7 +721,7 @@ func (s *NginxService) generateBasicConfig(proxyHost *models.ProxyHost, certific
 	config.WriteString("}\n")
 
 	// HTTP to HTTPS redirect if SSL is forced
-	if proxyHost.SSLForced && certificate != nil {
+	if proxyHost.SSLForced && ssl != nil {
 		config.WriteString("\nserver {\n")
 		config.WriteString("    listen 80;\n")
 		config.WriteString("    server_name")
@@ -456,6 +736,94 @@ func (s *NginxService) generateBasicConfig(proxyHost *models.ProxyHost, certific
 	return config.String()
 }
 
+// ValidateProxyBuffers checks the proxy_buffer_size and proxy_buffers values.
+// Empty values are valid and keep the nginx defaults.
+func ValidateProxyBuffers(bufferSize, buffers string) error {
+	if bufferSize != "" && !nginxSizePattern.MatchString(bufferSize) {
+		return ErrInvalidBufferSize
+	}
+	if buffers == "" {
+		return nil
+	}
+
+	match := proxyBuffersPattern.FindStringSubmatch(buffers)
+	if match == nil {
+		return ErrInvalidProxyBuffers
+	}
+	if count, err := strconv.Atoi(match[1]); err != nil || count < 2 {
+		return fmt.Errorf("%w: at least 2 buffers are required", ErrInvalidProxyBuffers)
+	}
+	return nil
+}
+
+// ValidateRateLimit checks a proxy host's rate limit; nil disables rate limiting
+func ValidateRateLimit(rateLimit *models.RateLimitConfig) error {
+	if rateLimit == nil {
+		return nil
+	}
+	if rateLimit.RequestsPerSecond <= 0 {
+		return ErrInvalidRateLimitRate
+	}
+	if rateLimit.Burst < 0 {
+		return ErrInvalidRateLimitBurst
+	}
+	return nil
+}
+
+// RateLimitZoneDirective returns the limit_req_zone directive declaring a
+// host's zone, keyed by client address. It belongs in the http context.
+func RateLimitZoneDirective(proxyHost *models.ProxyHost) string {
+	return fmt.Sprintf("limit_req_zone $binary_remote_addr zone=%s:10m rate=%dr/s;",
+		proxyHost.GetRateLimitZoneName(), proxyHost.RateLimit.RequestsPerSecond)
+}
+
+// RateLimitDirective returns the limit_req directive applying a host's zone
+func RateLimitDirective(proxyHost *models.ProxyHost) string {
+	directive := "limit_req zone=" + proxyHost.GetRateLimitZoneName()
+	if proxyHost.RateLimit.Burst > 0 {
+		directive += fmt.Sprintf(" burst=%d", proxyHost.RateLimit.Burst)
+	}
+	if proxyHost.RateLimit.NoDelay {
+		directive += " nodelay"
+	}
+	return directive + ";"
+}
+
+// ValidateUpstreamKeepalive checks the number of idle upstream connections;
+// zero disables keepalive and the generated upstream block
+func ValidateUpstreamKeepalive(connections int) error {
+	if connections < 0 || connections > MaxUpstreamKeepalive {
+		return ErrInvalidKeepalive
+	}
+	return nil
+}
+
+// ValidateProxyBind checks that an outgoing source address is an IP assigned
+// to one of this host's interfaces. An empty address is valid and disables proxy_bind.
+func ValidateProxyBind(address string) error {
+	if address == "" {
+		return nil
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ErrInvalidProxyBind
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return fmt.Errorf("failed to list local addresses: %w", err)
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return nil
+		}
+	}
+
+	return ErrInvalidProxyBind
+}
+
 // backupConfig creates a backup of current configuration
 func (s *NginxService) backupConfig(proxyHost *models.ProxyHost) error {
 	configFile := filepath.Join(s.sitesPath, fmt.Sprintf("proxy_host_%d.conf", proxyHost.ID))
@@ -487,10 +855,413 @@ func (s *NginxService) removeConfig(proxyHost *models.ProxyHost) error {
 	return os.Remove(configFile)
 }
 
-// reloadNginx reloads nginx configuration
+// DeployProxyHost writes a proxy host's configuration and reloads nginx, or
+// only marks it pending when staged deployment is enabled
+func (s *NginxService) DeployProxyHost(proxyHost *models.ProxyHost) error {
+	if s.stagedDeploy {
+		return s.markPending(proxyHost)
+	}
+
+	if err := s.generateConfig(proxyHost); err != nil {
+		return fmt.Errorf("%w: %v", ErrNginxConfigGeneration, err)
+	}
+	return s.reloadNginx()
+}
+
+// UndeployProxyHost removes a proxy host's configuration and reloads nginx, or
+// only marks it pending when staged deployment is enabled
+func (s *NginxService) UndeployProxyHost(proxyHost *models.ProxyHost) error {
+	if s.stagedDeploy {
+		return s.markPending(proxyHost)
+	}
+
+	if err := s.removeConfig(proxyHost); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.reloadNginx()
+}
+
+// GetPendingChanges lists staged proxy host changes visible to the user with a
+// diff between the deployed configuration and what apply would write
+func (s *NginxService) GetPendingChanges(userID uint) ([]PendingChange, error) {
+	proxyHosts, err := s.findPendingProxyHosts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]PendingChange, 0, len(proxyHosts))
+	for i := range proxyHosts {
+		change, err := s.describePendingChange(&proxyHosts[i])
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, *change)
+	}
+
+	return changes, nil
+}
+
+// ApplyPendingChanges deploys all staged proxy host changes visible to the
+// user and reloads nginx once. Pending flags are cleared only after a successful
+// reload; if the reload fails the previous config files are restored.
+func (s *NginxService) ApplyPendingChanges(userID uint) (*ApplyResult, error) {
+	proxyHosts, err := s.findPendingProxyHosts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ApplyResult{
+		Applied: make([]PendingChange, 0),
+		Failed:  make([]string, 0),
+	}
+	if len(proxyHosts) == 0 {
+		return result, nil
+	}
+
+	appliedIDs := make([]uint, 0, len(proxyHosts))
+	snapshots := make([]*configFileSnapshot, 0, len(proxyHosts))
+	for i := range proxyHosts {
+		proxyHost := &proxyHosts[i]
+
+		change, err := s.describePendingChange(proxyHost)
+		if err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("proxy host %d: %v", proxyHost.ID, err))
+			continue
+		}
+
+		// Keep the file being replaced so a failed reload can be undone
+		snapshot, err := snapshotConfigFile(filepath.Join(s.sitesPath, fmt.Sprintf("proxy_host_%d.conf", proxyHost.ID)))
+		if err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("proxy host %d: %v", proxyHost.ID, err))
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+
+		if change.Action == "remove" {
+			if err := s.backupConfig(proxyHost); err != nil {
+				logger.Warn("Failed to backup config", logger.Err(err))
+			}
+			if err := s.removeConfig(proxyHost); err != nil && !os.IsNotExist(err) {
+				result.Failed = append(result.Failed, fmt.Sprintf("proxy host %d: %v", proxyHost.ID, err))
+				continue
+			}
+		} else {
+			if err := s.backupConfig(proxyHost); err != nil {
+				logger.Warn("Failed to backup config", logger.Err(err))
+			}
+			if err := s.generateConfig(proxyHost); err != nil {
+				result.Failed = append(result.Failed, fmt.Sprintf("proxy host %d: %v", proxyHost.ID, err))
+				continue
+			}
+		}
+
+		result.Applied = append(result.Applied, *change)
+		appliedIDs = append(appliedIDs, proxyHost.ID)
+	}
+
+	if len(appliedIDs) > 0 {
+		if err := s.reloadNginx(); err != nil {
+			if rollbackErr := s.restoreConfigFiles(snapshots); rollbackErr != nil {
+				return nil, fmt.Errorf("%w: %v; rollback failed: %v", ErrNginxReload, err, rollbackErr)
+			}
+			return nil, fmt.Errorf("%w: %v; the previous configuration was restored", ErrNginxReload, err)
+		}
+
+		if err := s.db.Unscoped().Model(&models.ProxyHost{}).
+			Where("id IN ?", appliedIDs).
+			UpdateColumn("pending_changes", false).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	result.Count = len(result.Applied)
+
+	logger.Info("Applied pending nginx changes",
+		logger.Uint("user_id", userID),
+		logger.Int("applied", result.Count),
+		logger.Int("failed", len(result.Failed)))
+
+	return result, nil
+}
+
+// restoreConfigFiles puts back the config files replaced by a failed apply and
+// reloads nginx with them, so the live configuration is never left broken
+func (s *NginxService) restoreConfigFiles(snapshots []*configFileSnapshot) error {
+	for _, snapshot := range snapshots {
+		if err := snapshot.restore(); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", snapshot.path, err)
+		}
+	}
+	if err := s.nginxRunner.Reload(); err != nil {
+		return err
+	}
+
+	logger.Warn("Pending changes rolled back", logger.Int("files", len(snapshots)))
+	return nil
+}
+
+// findPendingProxyHosts returns pending proxy hosts, including soft-deleted
+// ones awaiting removal, scoped to the user unless they hold proxy_host:manage
+func (s *NginxService) findPendingProxyHosts(userID uint) ([]models.ProxyHost, error) {
+	query := s.db.Unscoped().Where("pending_changes = ?", true)
+	if !s.authService.HasPermission(userID, models.PermissionProxyHostManage) {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var proxyHosts []models.ProxyHost
+	if err := query.Order("id ASC").Find(&proxyHosts).Error; err != nil {
+		return nil, err
+	}
+	return proxyHosts, nil
+}
+
+// describePendingChange compares the deployed config file with the config apply would produce
+func (s *NginxService) describePendingChange(proxyHost *models.ProxyHost) (*PendingChange, error) {
+	configFile := filepath.Join(s.sitesPath, fmt.Sprintf("proxy_host_%d.conf", proxyHost.ID))
+
+	current := ""
+	exists := false
+	if content, err := os.ReadFile(configFile); err == nil {
+		current = string(content)
+		exists = true
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	change := &PendingChange{
+		ProxyHostID:   proxyHost.ID,
+		PrimaryDomain: proxyHost.GetPrimaryDomain(),
+	}
+
+	if proxyHost.DeletedAt.Valid || !proxyHost.Enabled {
+		change.Action = "remove"
+		change.Diff = diffLines(current, "")
+		return change, nil
+	}
+
+	desired, err := s.buildConfig(proxyHost)
+	if err != nil {
+		return nil, err
+	}
+
+	change.Action = "update"
+	if !exists {
+		change.Action = "create"
+	}
+	change.Diff = diffLines(current, desired)
+
+	return change, nil
+}
+
+// markPending flags a proxy host as having undeployed changes
+func (s *NginxService) markPending(proxyHost *models.ProxyHost) error {
+	proxyHost.PendingChanges = true
+	return s.db.Unscoped().Model(&models.ProxyHost{}).
+		Where("id = ?", proxyHost.ID).
+		UpdateColumn("pending_changes", true).Error
+}
+
+// diffLines produces a simple line diff: unchanged lines are prefixed with
+// two spaces, removed lines with "- " and added lines with "+ "
+func diffLines(oldText, newText string) []string {
+	var oldLines, newLines []string
+	if oldText != "" {
+		oldLines = strings.Split(strings.TrimRight(oldText, "\n"), "\n")
+	}
+	if newText != "" {
+		newLines = strings.Split(strings.TrimRight(newText, "\n"), "\n")
+	}
+
+	// Longest common subsequence table
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := make([]string, 0, len(oldLines)+len(newLines))
+	i, j := 0, 0
+	for i < len(oldLines) && j < len(newLines) {
+		switch {
+		case oldLines[i] == newLines[j]:
+			diff = append(diff, "  "+oldLines[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "- "+oldLines[i])
+			i++
+		default:
+			diff = append(diff, "+ "+newLines[j])
+			j++
+		}
+	}
+	for ; i < len(oldLines); i++ {
+		diff = append(diff, "- "+oldLines[i])
+	}
+	for ; j < len(newLines); j++ {
+		diff = append(diff, "+ "+newLines[j])
+	}
+
+	return diff
+}
+
+// FindOrphanedConfigs lists proxy host config files whose proxy host no longer
+// exists. Soft-deleted hosts with a staged removal are not orphans; applying
+// pending changes removes their files.
+func (s *NginxService) FindOrphanedConfigs() ([]OrphanedConfig, error) {
+	entries, err := os.ReadDir(s.sitesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []OrphanedConfig{}, nil
+		}
+		return nil, err
+	}
+
+	var liveIDs []uint
+	if err := s.db.Unscoped().Model(&models.ProxyHost{}).
+		Where("deleted_at IS NULL OR pending_changes = ?", true).
+		Pluck("id", &liveIDs).Error; err != nil {
+		return nil, err
+	}
+	live := make(map[uint]bool, len(liveIDs))
+	for _, id := range liveIDs {
+		live[id] = true
+	}
+
+	orphans := make([]OrphanedConfig, 0)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		match := proxyHostConfigPattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		id, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || live[uint(id)] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		orphans = append(orphans, OrphanedConfig{
+			File:        entry.Name(),
+			ProxyHostID: uint(id),
+			Size:        info.Size(),
+			ModifiedAt:  info.ModTime(),
+		})
+	}
+
+	return orphans, nil
+}
+
+// CleanupOrphanedConfigs backs up and removes orphaned config files, then
+// reloads nginx once. If files is non-empty only those orphans are removed;
+// names that are not currently orphaned are reported as failed.
+func (s *NginxService) CleanupOrphanedConfigs(files []string) (*OrphanCleanupResult, error) {
+	orphans, err := s.FindOrphanedConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OrphanCleanupResult{
+		Removed: make([]OrphanedConfig, 0),
+		Failed:  make([]string, 0),
+	}
+
+	selected := orphans
+	if len(files) > 0 {
+		byName := make(map[string]OrphanedConfig, len(orphans))
+		for _, orphan := range orphans {
+			byName[orphan.File] = orphan
+		}
+
+		selected = make([]OrphanedConfig, 0, len(files))
+		for _, file := range files {
+			orphan, ok := byName[file]
+			if !ok {
+				result.Failed = append(result.Failed, fmt.Sprintf("%s: not an orphaned config file", file))
+				continue
+			}
+			selected = append(selected, orphan)
+		}
+	}
+
+	if err := os.MkdirAll(s.backupPath, 0755); err != nil {
+		return nil, err
+	}
+
+	for _, orphan := range selected {
+		configFile := filepath.Join(s.sitesPath, orphan.File)
+
+		content, err := os.ReadFile(configFile)
+		if err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", orphan.File, err))
+			continue
+		}
+		backupFile := filepath.Join(s.backupPath, fmt.Sprintf("orphan_%s_%d.bak", orphan.File, time.Now().Unix()))
+		if err := os.WriteFile(backupFile, content, 0644); err != nil {
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: backup failed: %v", orphan.File, err))
+			continue
+		}
+
+		if err := os.Remove(configFile); err != nil && !os.IsNotExist(err) {
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", orphan.File, err))
+			continue
+		}
+		result.Removed = append(result.Removed, orphan)
+	}
+
+	result.Count = len(result.Removed)
+	if result.Count > 0 {
+		if err := s.reloadNginx(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNginxReload, err)
+		}
+	}
+
+	logger.Info("Cleaned up orphaned nginx configs",
+		logger.Int("removed", result.Count),
+		logger.Int("failed", len(result.Failed)))
+
+	return result, nil
+}
+
+// HtpasswdPath returns the htpasswd file written for an access list, kept
+// next to the main nginx configuration
+func (s *NginxService) HtpasswdPath(accessListID uint) string {
+	return filepath.Join(filepath.Dir(s.configPath), htpasswdFileName(accessListID))
+}
+
+// defaultHtpasswdDir is where htpasswd files live when no nginx service is set
+const defaultHtpasswdDir = "/etc/nginx"
+
+// htpasswdFileName names the htpasswd file of an access list
+func htpasswdFileName(accessListID uint) string {
+	return fmt.Sprintf("access_%d.htpasswd", accessListID)
+}
+
+// reloadNginx tests the nginx configuration and reloads nginx, returning
+// the nginx output when either step fails
 func (s *NginxService) reloadNginx() error {
-	// In production, this would execute nginx reload command
-	// For now, we'll just log the action
+	if err := s.nginxRunner.Test(""); err != nil {
+		return fmt.Errorf("nginx configuration test failed: %w", err)
+	}
+	if err := s.nginxRunner.Reload(); err != nil {
+		return fmt.Errorf("nginx reload failed: %w", err)
+	}
+
 	logger.Info("Nginx configuration reloaded")
 	return nil
 }
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// newTestNginxService returns an NginxService writing into temporary
+// directories and running nginx through runner
+func newTestNginxService(t *testing.T, runner NginxRunner) *NginxService {
+	t.Helper()
+	dir := t.TempDir()
+
+	sitesPath := filepath.Join(dir, "sites")
+	if err := os.MkdirAll(sitesPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := NewNginxService(filepath.Join(dir, "nginx.conf"), sitesPath, filepath.Join(dir, "backups"), filepath.Join(dir, "templates"), NewAuthService("test"))
+	s.SetNginxRunner(runner)
+	return s
+}
+
+// createTestProxyHost creates an enabled proxy host for domain
+func createTestProxyHost(t *testing.T, db *gorm.DB, userID uint, domain string) *models.ProxyHost {
+	t.Helper()
+
+	proxyHost := &models.ProxyHost{
+		DomainNames:   models.StringArray{domain},
+		ForwardScheme: "http",
+		ForwardHost:   "127.0.0.1",
+		ForwardPort:   8080,
+		Enabled:       true,
+		UserID:        userID,
+	}
+	if err := db.Create(proxyHost).Error; err != nil {
+		t.Fatalf("create proxy host %s: %v", domain, err)
+	}
+	return proxyHost
+}
+
+// TestApplyPendingChangesRollsBackFailedReload checks that a failed reload
+// restores every file the apply replaced or created
+func TestApplyPendingChangesRollsBackFailedReload(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+
+	runner := &MockNginxRunner{ReloadErrQueue: []error{errors.New("bind() failed")}}
+	s := newTestNginxService(t, runner)
+	s.SetStagedDeploy(true)
+
+	existing := createTestProxyHost(t, db, admin.ID, "existing.example.com")
+	created := createTestProxyHost(t, db, admin.ID, "new.example.com")
+
+	existingFile := filepath.Join(s.sitesPath, fmt.Sprintf("proxy_host_%d.conf", existing.ID))
+	createdFile := filepath.Join(s.sitesPath, fmt.Sprintf("proxy_host_%d.conf", created.ID))
+	previous := "# deployed before the apply\n"
+	if err := os.WriteFile(existingFile, []byte(previous), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.ProxyHost{}).Where("id IN ?", []uint{existing.ID, created.ID}).
+		UpdateColumn("pending_changes", true).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.ApplyPendingChanges(admin.ID); !errors.Is(err, ErrNginxReload) {
+		t.Fatalf("got error %v, want ErrNginxReload", err)
+	}
+
+	content, err := os.ReadFile(existingFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != previous {
+		t.Errorf("existing host config holds %q, want the previous %q", content, previous)
+	}
+	if _, err := os.Stat(createdFile); !os.IsNotExist(err) {
+		t.Errorf("new host config exists after the rollback: %v", err)
+	}
+	if runner.ReloadCount != 2 {
+		t.Errorf("nginx reloaded %d times, want 2 (the failed apply and the rollback)", runner.ReloadCount)
+	}
+
+	var pending int64
+	db.Model(&models.ProxyHost{}).Where("pending_changes = ?", true).Count(&pending)
+	if pending != 2 {
+		t.Errorf("%d hosts still pending, want 2", pending)
+	}
+}
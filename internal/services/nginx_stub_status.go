@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// DefaultNginxStubStatusURL is where nginx's stub_status page is scraped
+// unless configured otherwise
+const DefaultNginxStubStatusURL = "http://127.0.0.1/nginx_status"
+
+// stubStatusTimeout bounds one scrape, so a hung nginx does not stall the
+// status poll
+const stubStatusTimeout = 2 * time.Second
+
+// maxStubStatusBody bounds how much of a response is read; the real page is
+// around 100 bytes
+const maxStubStatusBody = 4096
+
+// ErrStubStatusUnavailable means the stub_status page could not be read or
+// parsed, usually because the module is not enabled at the configured URL
+var ErrStubStatusUnavailable = errors.New("nginx stub_status is not available")
+
+// stubStatusGuidance explains how to enable stub_status for the scrape URL
+const stubStatusGuidance = `enable it with a location such as "location = /nginx_status { stub_status; allow 127.0.0.1; deny all; }" ` +
+	`in a server block listening on that address, or set NGINX_STUB_STATUS_URL to where it is served`
+
+var stubStatusPattern = regexp.MustCompile(`Active connections:\s*(\d+)\s+server accepts handled requests\s+(\d+)\s+(\d+)\s+(\d+)\s+Reading:\s*(\d+)\s+Writing:\s*(\d+)\s+Waiting:\s*(\d+)`)
+
+// NginxStubStatus is the connection and request counters of nginx's
+// stub_status page. Accepts, handled and requests count since nginx started.
+type NginxStubStatus struct {
+	Active   int64 `json:"active"`
+	Accepts  int64 `json:"accepts"`
+	Handled  int64 `json:"handled"`
+	Requests int64 `json:"requests"`
+	Reading  int64 `json:"reading"`
+	Writing  int64 `json:"writing"`
+	Waiting  int64 `json:"waiting"`
+}
+
+// SetStubStatusURL sets the stub_status page scraped for connection stats;
+// empty turns scraping off
+func (s *MonitoringService) SetStubStatusURL(url string) {
+	s.nginxStatusMutex.Lock()
+	defer s.nginxStatusMutex.Unlock()
+
+	s.stubStatusURL = url
+	s.nginxStatus = nil
+}
+
+// ScrapeStubStatus reads nginx's stub_status page. Errors wrap
+// ErrStubStatusUnavailable and say how to enable the page.
+func (s *MonitoringService) ScrapeStubStatus(url string) (*NginxStubStatus, error) {
+	resp, err := s.stubStatusClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("%w at %s: %v; %s", ErrStubStatusUnavailable, url, err, stubStatusGuidance)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w at %s: got %s; %s", ErrStubStatusUnavailable, url, resp.Status, stubStatusGuidance)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStubStatusBody))
+	if err != nil {
+		return nil, fmt.Errorf("%w at %s: %v", ErrStubStatusUnavailable, url, err)
+	}
+
+	status, err := parseStubStatus(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w at %s: %v; %s", ErrStubStatusUnavailable, url, err, stubStatusGuidance)
+	}
+	return status, nil
+}
+
+// parseStubStatus parses the body of a stub_status page
+func parseStubStatus(body string) (*NginxStubStatus, error) {
+	match := stubStatusPattern.FindStringSubmatch(body)
+	if match == nil {
+		return nil, errors.New("response is not a stub_status page")
+	}
+
+	values := make([]int64, len(match)-1)
+	for i, field := range match[1:] {
+		value, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return &NginxStubStatus{
+		Active:   values[0],
+		Accepts:  values[1],
+		Handled:  values[2],
+		Requests: values[3],
+		Reading:  values[4],
+		Writing:  values[5],
+		Waiting:  values[6],
+	}, nil
+}
@@ -2,22 +2,44 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
+	"net/mail"
 	"net/smtp"
+	"net/url"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
+	"github.com/nguyendkn/nginx-manager/internal/database"
 	"github.com/nguyendkn/nginx-manager/internal/models"
 	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
 )
 
 // NotificationService handles alert notifications via multiple channels
 type NotificationService struct {
+	db             *gorm.DB // holds notifications queued by quiet hours
 	emailTemplates map[string]*template.Template
 	httpClient     *http.Client
+
+	// Webhook retry backoff defaults
+	retryBaseDelay    time.Duration
+	retryMaxTotalWait time.Duration
+}
+
+// QuietHoursConfig represents a channel's quiet hours window. Start and End
+// are "HH:MM" in the given timezone; a window may wrap past midnight.
+type QuietHoursConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"`
 }
 
 // EmailConfig represents email configuration
@@ -45,8 +67,17 @@ type WebhookConfig struct {
 	URL        string            `json:"url"`
 	Method     string            `json:"method"`
 	Headers    map[string]string `json:"headers"`
-	Timeout    int               `json:"timeout"`
+	Timeout    int               `json:"timeout"` // seconds per attempt
 	RetryCount int               `json:"retry_count"`
+	BackoffMs  int               `json:"backoff_ms"` // base backoff delay, overrides the service default
+}
+
+// webhookRetryPolicy controls how webhook deliveries are retried
+type webhookRetryPolicy struct {
+	RetryCount   int
+	Timeout      time.Duration // per attempt, zero uses the HTTP client timeout
+	BaseDelay    time.Duration
+	MaxTotalWait time.Duration // cap on time spent sleeping between attempts
 }
 
 // TeamsConfig represents Microsoft Teams webhook configuration
@@ -56,13 +87,26 @@ type TeamsConfig struct {
 	ThemeColor string `json:"theme_color"`
 }
 
+// DiscordConfig represents Discord webhook configuration
+type DiscordConfig struct {
+	WebhookURL string `json:"webhook_url"`
+	Username   string `json:"username"`
+	AvatarURL  string `json:"avatar_url"`
+}
+
+// discordMaxRetries bounds how many times a rate-limited Discord request is retried
+const discordMaxRetries = 3
+
 // NewNotificationService creates a new notification service
 func NewNotificationService() *NotificationService {
 	ns := &NotificationService{
+		db:             database.GetDB(),
 		emailTemplates: make(map[string]*template.Template),
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		retryBaseDelay:    500 * time.Millisecond,
+		retryMaxTotalWait: 30 * time.Second,
 	}
 
 	// Initialize email templates
@@ -71,8 +115,38 @@ func NewNotificationService() *NotificationService {
 	return ns
 }
 
-// SendAlert sends an alert notification through the specified channel
+// SetRetryBackoff configures the default webhook backoff base delay and the cap
+// on total time spent waiting between retries
+func (ns *NotificationService) SetRetryBackoff(baseDelay, maxTotalWait time.Duration) {
+	ns.retryBaseDelay = baseDelay
+	ns.retryMaxTotalWait = maxTotalWait
+}
+
+// SendAlert sends an alert notification through the specified channel.
+// Non-critical alerts are queued while the channel is inside its quiet hours.
 func (ns *NotificationService) SendAlert(channel models.NotificationChannel, alert *models.AlertInstance, rule *models.AlertRule) error {
+	if rule.Severity != "critical" {
+		quiet, err := ns.inQuietHours(channel, time.Now())
+		if err != nil {
+			logger.Warn("Invalid quiet hours configuration, delivering immediately",
+				logger.Uint("channel_id", channel.ID),
+				logger.Err(err))
+		} else if quiet {
+			err := ns.queueNotification(channel, alert, rule)
+			if err == nil {
+				return nil
+			}
+			logger.Error("Failed to queue alert for quiet hours, delivering immediately",
+				logger.Uint("channel_id", channel.ID),
+				logger.Err(err))
+		}
+	}
+
+	return ns.dispatchAlert(channel, alert, rule)
+}
+
+// dispatchAlert delivers an alert through the channel's transport
+func (ns *NotificationService) dispatchAlert(channel models.NotificationChannel, alert *models.AlertInstance, rule *models.AlertRule) error {
 	switch channel.Type {
 	case "email":
 		return ns.sendEmailAlert(channel, alert, rule)
@@ -82,6 +156,325 @@ func (ns *NotificationService) SendAlert(channel models.NotificationChannel, ale
 		return ns.sendWebhookAlert(channel, alert, rule)
 	case "teams":
 		return ns.sendTeamsAlert(channel, alert, rule)
+	case "discord":
+		return ns.sendDiscordAlert(channel, alert, rule)
+	default:
+		return fmt.Errorf("unsupported notification channel type: %s", channel.Type)
+	}
+}
+
+// inQuietHours reports whether the channel is currently inside its quiet hours
+func (ns *NotificationService) inQuietHours(channel models.NotificationChannel, now time.Time) (bool, error) {
+	raw, ok := channel.Configuration["quiet_hours"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	var config QuietHoursConfig
+	if err := ns.parseConfig(raw, &config); err != nil {
+		return false, err
+	}
+
+	return config.IsActive(now)
+}
+
+// IsActive reports whether the given time falls inside the quiet hours window
+func (q QuietHoursConfig) IsActive(now time.Time) (bool, error) {
+	if !q.Enabled {
+		return false, nil
+	}
+
+	location := time.UTC
+	if q.Timezone != "" {
+		loc, err := time.LoadLocation(q.Timezone)
+		if err != nil {
+			return false, fmt.Errorf("invalid timezone %q: %v", q.Timezone, err)
+		}
+		location = loc
+	}
+
+	start, err := parseClockMinutes(q.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClockMinutes(q.End)
+	if err != nil {
+		return false, err
+	}
+
+	local := now.In(location)
+	current := local.Hour()*60 + local.Minute()
+
+	if start == end {
+		return false, nil
+	}
+	if start < end {
+		return current >= start && current < end, nil
+	}
+	// Window wraps past midnight, e.g. 22:00-07:00
+	return current >= start || current < end, nil
+}
+
+// parseClockMinutes parses an "HH:MM" string into minutes since midnight
+func parseClockMinutes(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// queueNotification stores an alert until the channel's quiet hours end
+func (ns *NotificationService) queueNotification(channel models.NotificationChannel, alert *models.AlertInstance, rule *models.AlertRule) error {
+	if ns.db == nil {
+		return fmt.Errorf("no database to queue notifications in")
+	}
+
+	queued := &models.QueuedNotification{
+		ChannelID:      channel.ID,
+		AlertID:        alert.ID,
+		RuleName:       rule.Name,
+		Severity:       rule.Severity,
+		Message:        alert.Message,
+		CurrentValue:   alert.CurrentValue,
+		ThresholdValue: alert.ThresholdValue,
+		TriggeredAt:    alert.TriggeredAt,
+		QueuedAt:       time.Now(),
+	}
+	if err := ns.db.Create(queued).Error; err != nil {
+		return err
+	}
+
+	logger.Info("Alert notification queued for quiet hours",
+		logger.Uint("channel_id", channel.ID),
+		logger.String("rule_name", rule.Name))
+	return nil
+}
+
+// FlushQueuedNotifications sends a summary of queued alerts for every channel
+// whose quiet hours have ended. Each channel is loaded again, so the summary
+// goes out with its current settings; the queue of a deleted or disabled
+// channel is dropped.
+func (ns *NotificationService) FlushQueuedNotifications() {
+	if ns.db == nil {
+		return
+	}
+
+	var channelIDs []uint
+	if err := ns.db.Model(&models.QueuedNotification{}).Distinct().Pluck("channel_id", &channelIDs).Error; err != nil {
+		logger.Error("Failed to list queued notifications", logger.Err(err))
+		return
+	}
+
+	now := time.Now()
+	for _, channelID := range channelIDs {
+		var channel models.NotificationChannel
+		err := ns.db.First(&channel, channelID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("Failed to load channel for quiet hours summary", logger.Uint("channel_id", channelID), logger.Err(err))
+			continue
+		}
+		if err == nil && channel.IsEnabled {
+			if quiet, err := ns.inQuietHours(channel, now); err == nil && quiet {
+				continue
+			}
+		}
+
+		var items []models.QueuedNotification
+		if err := ns.db.Where("channel_id = ?", channelID).Order("id ASC").Find(&items).Error; err != nil {
+			logger.Error("Failed to load queued notifications", logger.Uint("channel_id", channelID), logger.Err(err))
+			continue
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		// Only the loaded rows are removed: alerts queued meanwhile wait for
+		// the next flush. A failed summary is not retried.
+		ids := make([]uint, len(items))
+		for i := range items {
+			ids[i] = items[i].ID
+		}
+		if err := ns.db.Delete(&models.QueuedNotification{}, ids).Error; err != nil {
+			logger.Error("Failed to clear queued notifications", logger.Uint("channel_id", channelID), logger.Err(err))
+			continue
+		}
+
+		if !channel.IsEnabled {
+			logger.Info("Dropped quiet hours queue of a deleted or disabled channel",
+				logger.Uint("channel_id", channelID),
+				logger.Int("alerts", len(items)))
+			continue
+		}
+		if err := ns.sendQueuedSummary(channel, items); err != nil {
+			logger.Error("Failed to send quiet hours summary",
+				logger.Uint("channel_id", channelID),
+				logger.Int("alerts", len(items)),
+				logger.Err(err))
+		}
+	}
+}
+
+// StartQueueFlusher periodically flushes notifications queued during quiet hours
+func (ns *NotificationService) StartQueueFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ns.FlushQueuedNotifications()
+		}
+	}
+}
+
+// sendQueuedSummary delivers a single digest of the alerts queued during quiet hours
+func (ns *NotificationService) sendQueuedSummary(channel models.NotificationChannel, items []models.QueuedNotification) error {
+	title := fmt.Sprintf("Nginx Manager: %d alert(s) received during quiet hours", len(items))
+
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, fmt.Sprintf("[%s] %s at %s: %s (value %.2f, threshold %.2f)",
+			strings.ToUpper(item.Severity), item.RuleName,
+			item.TriggeredAt.Format("2006-01-02 15:04:05"), item.Message,
+			item.CurrentValue, item.ThresholdValue))
+	}
+
+	alerts := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		alerts = append(alerts, map[string]interface{}{
+			"alert_id":      item.AlertID,
+			"rule_name":     item.RuleName,
+			"severity":      item.Severity,
+			"message":       item.Message,
+			"current_value": item.CurrentValue,
+			"threshold":     item.ThresholdValue,
+			"triggered_at":  item.TriggeredAt,
+		})
+	}
+	payload := map[string]interface{}{
+		"type":   "quiet_hours_summary",
+		"count":  len(items),
+		"alerts": alerts,
+	}
+
+	return ns.sendTextNotification(channel, title, lines, "warning", payload)
+}
+
+// SendResolution notifies a channel that a previously triggered alert has resolved
+func (ns *NotificationService) SendResolution(channel models.NotificationChannel, alert *models.AlertInstance, rule *models.AlertRule) error {
+	title := fmt.Sprintf("Resolved: %s", rule.Name)
+	lines := []string{alert.Message}
+	if alert.ResolvedAt != nil {
+		lines = append(lines, fmt.Sprintf("Resolved At: %s", alert.ResolvedAt.Format("2006-01-02 15:04:05")))
+	}
+
+	payload := map[string]interface{}{
+		"type":          "alert_resolved",
+		"alert_id":      alert.ID,
+		"rule_name":     rule.Name,
+		"severity":      rule.Severity,
+		"message":       alert.Message,
+		"current_value": alert.CurrentValue,
+		"threshold":     alert.ThresholdValue,
+		"triggered_at":  alert.TriggeredAt,
+		"resolved_at":   alert.ResolvedAt,
+	}
+
+	return ns.sendTextNotification(channel, title, lines, "info", payload)
+}
+
+// SendCertificateExpiry warns a channel that a certificate which will not
+// renew automatically is about to expire, or already has
+func (ns *NotificationService) SendCertificateExpiry(channel models.NotificationChannel, certificate *models.Certificate, daysRemaining int, reason string) error {
+	title := fmt.Sprintf("Certificate expiring: %s (%s)", certificate.GetPrimaryDomain(), formatDaysRemaining(daysRemaining))
+	lines := []string{
+		fmt.Sprintf("Domains: %s", strings.Join(certificate.DomainNames, ", ")),
+		fmt.Sprintf("Days Remaining: %d", daysRemaining),
+		fmt.Sprintf("Expires On: %s", certificate.ExpiresOn.Format("2006-01-02 15:04:05")),
+		fmt.Sprintf("Provider: %s", certificate.Provider),
+		reason,
+	}
+
+	severity := "warning"
+	if daysRemaining <= 7 {
+		severity = "critical"
+	}
+
+	payload := map[string]interface{}{
+		"type":           "certificate_expiry",
+		"certificate_id": certificate.ID,
+		"name":           certificate.Name,
+		"domains":        certificate.DomainNames,
+		"provider":       certificate.Provider,
+		"expires_on":     certificate.ExpiresOn,
+		"days_remaining": daysRemaining,
+		"reason":         reason,
+	}
+
+	return ns.sendTextNotification(channel, title, lines, severity, payload)
+}
+
+// sendTextNotification delivers a plain title-and-lines message through the
+// channel's transport. Generic webhooks receive webhookPayload instead.
+func (ns *NotificationService) sendTextNotification(channel models.NotificationChannel, title string, lines []string, severity string, webhookPayload map[string]interface{}) error {
+	switch channel.Type {
+	case "email":
+		var emailConfig EmailConfig
+		if err := ns.parseConfig(channel.Configuration, &emailConfig); err != nil {
+			return fmt.Errorf("invalid email configuration: %v", err)
+		}
+		body := "<h3>" + title + "</h3><ul><li>" + strings.Join(lines, "</li><li>") + "</li></ul>"
+		return ns.sendEmail(emailConfig, title, body)
+	case "slack":
+		webhookURL, ok := channel.Configuration["webhook_url"].(string)
+		if !ok {
+			return fmt.Errorf("missing webhook_url in Slack configuration")
+		}
+		payload := map[string]interface{}{
+			"text": fmt.Sprintf("*%s*\n%s", title, strings.Join(lines, "\n")),
+		}
+		return ns.sendWebhookRequest(webhookURL, payload)
+	case "webhook":
+		var webhookConfig WebhookConfig
+		if err := ns.parseConfig(channel.Configuration, &webhookConfig); err != nil {
+			return fmt.Errorf("invalid webhook configuration: %v", err)
+		}
+		if webhookConfig.URL == "" {
+			return fmt.Errorf("missing url in webhook configuration")
+		}
+		return ns.sendWebhookRequestWithRetry(webhookConfig.URL, webhookPayload, ns.retryPolicyFor(webhookConfig))
+	case "teams":
+		var teamsConfig TeamsConfig
+		if err := ns.parseConfig(channel.Configuration, &teamsConfig); err != nil {
+			return fmt.Errorf("invalid Teams configuration: %v", err)
+		}
+		payload := map[string]interface{}{
+			"@type":      "MessageCard",
+			"@context":   "http://schema.org/extensions",
+			"themeColor": ns.getTeamsSeverityColor(severity),
+			"summary":    title,
+			"title":      title,
+			"text":       strings.Join(lines, "\n\n"),
+		}
+		return ns.sendWebhookRequest(teamsConfig.WebhookURL, payload)
+	case "discord":
+		var discordConfig DiscordConfig
+		if err := ns.parseConfig(channel.Configuration, &discordConfig); err != nil {
+			return fmt.Errorf("invalid Discord configuration: %v", err)
+		}
+		payload := map[string]interface{}{
+			"embeds": []map[string]interface{}{
+				{
+					"title":       title,
+					"description": strings.Join(lines, "\n"),
+					"color":       ns.getDiscordSeverityColor(severity),
+				},
+			},
+		}
+		return ns.sendDiscordRequest(discordConfig, payload)
 	default:
 		return fmt.Errorf("unsupported notification channel type: %s", channel.Type)
 	}
@@ -124,8 +517,11 @@ func (ns *NotificationService) sendSlackAlert(channel models.NotificationChannel
 
 // sendWebhookAlert sends an alert via generic webhook
 func (ns *NotificationService) sendWebhookAlert(channel models.NotificationChannel, alert *models.AlertInstance, rule *models.AlertRule) error {
-	url, ok := channel.Configuration["url"].(string)
-	if !ok {
+	var webhookConfig WebhookConfig
+	if err := ns.parseConfig(channel.Configuration, &webhookConfig); err != nil {
+		return fmt.Errorf("invalid webhook configuration: %v", err)
+	}
+	if webhookConfig.URL == "" {
 		return fmt.Errorf("missing url in webhook configuration")
 	}
 
@@ -139,7 +535,7 @@ func (ns *NotificationService) sendWebhookAlert(channel models.NotificationChann
 		"triggered_at":  alert.TriggeredAt,
 	}
 
-	return ns.sendWebhookRequest(url, payload)
+	return ns.sendWebhookRequestWithRetry(webhookConfig.URL, payload, ns.retryPolicyFor(webhookConfig))
 }
 
 // sendTeamsAlert sends an alert to Microsoft Teams
@@ -186,6 +582,271 @@ func (ns *NotificationService) sendTeamsAlert(channel models.NotificationChannel
 	return ns.sendWebhookRequest(teamsConfig.WebhookURL, payload)
 }
 
+// ChannelValidationError holds field-level notification channel configuration errors
+type ChannelValidationError struct {
+	Fields map[string][]string
+}
+
+func (e *ChannelValidationError) Error() string {
+	return fmt.Sprintf("invalid notification channel configuration (%d field(s))", len(e.Fields))
+}
+
+// ValidateChannel validates a notification channel's type-specific
+// configuration, returning a *ChannelValidationError on failure
+func (ns *NotificationService) ValidateChannel(channel *models.NotificationChannel) error {
+	fields := make(map[string][]string)
+	addError := func(field, message string) {
+		fields[field] = append(fields[field], message)
+	}
+
+	if strings.TrimSpace(channel.Name) == "" {
+		addError("name", "name is required")
+	}
+
+	config := channel.Configuration
+	if config == nil {
+		config = models.JSON{}
+	}
+
+	switch channel.Type {
+	case "email":
+		var emailConfig EmailConfig
+		if err := ns.parseConfig(config, &emailConfig); err != nil {
+			addError("configuration", err.Error())
+			break
+		}
+		if emailConfig.SMTPHost == "" {
+			addError("configuration.smtp_host", "SMTP host is required")
+		}
+		if emailConfig.SMTPPort < 1 || emailConfig.SMTPPort > 65535 {
+			addError("configuration.smtp_port", "SMTP port must be between 1 and 65535")
+		}
+		if _, err := mail.ParseAddress(emailConfig.FromAddress); err != nil {
+			addError("configuration.from_address", "a valid from address is required")
+		}
+		if len(emailConfig.ToAddresses) == 0 {
+			addError("configuration.to_addresses", "at least one recipient is required")
+		}
+		for _, address := range emailConfig.ToAddresses {
+			if _, err := mail.ParseAddress(address); err != nil {
+				addError("configuration.to_addresses", fmt.Sprintf("invalid address: %s", address))
+			}
+		}
+	case "slack", "teams":
+		webhookURL, _ := config["webhook_url"].(string)
+		if err := validateHTTPURL(webhookURL); err != nil {
+			addError("configuration.webhook_url", err.Error())
+		}
+	case "discord":
+		webhookURL, _ := config["webhook_url"].(string)
+		if err := validateDiscordWebhookURL(webhookURL); err != nil {
+			addError("configuration.webhook_url", err.Error())
+		}
+	case "webhook":
+		var webhookConfig WebhookConfig
+		if err := ns.parseConfig(config, &webhookConfig); err != nil {
+			addError("configuration", err.Error())
+			break
+		}
+		if err := validateHTTPURL(webhookConfig.URL); err != nil {
+			addError("configuration.url", err.Error())
+		}
+		if webhookConfig.Timeout < 0 {
+			addError("configuration.timeout", "timeout cannot be negative")
+		}
+		if webhookConfig.RetryCount < 0 || webhookConfig.RetryCount > 10 {
+			addError("configuration.retry_count", "retry count must be between 0 and 10")
+		}
+		if webhookConfig.BackoffMs < 0 {
+			addError("configuration.backoff_ms", "backoff cannot be negative")
+		}
+	default:
+		addError("type", "type must be one of: email, slack, webhook, teams, discord")
+	}
+
+	// Optional quiet hours window
+	if raw, exists := config["quiet_hours"]; exists {
+		rawMap, ok := raw.(map[string]interface{})
+		var quietHours QuietHoursConfig
+		if !ok {
+			addError("configuration.quiet_hours", "quiet hours must be an object")
+		} else if err := ns.parseConfig(rawMap, &quietHours); err != nil {
+			addError("configuration.quiet_hours", err.Error())
+		} else if quietHours.Enabled {
+			if _, err := quietHours.IsActive(time.Now()); err != nil {
+				addError("configuration.quiet_hours", err.Error())
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ChannelValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// SendTestAlert sends a sample alert through the channel, bypassing quiet hours
+func (ns *NotificationService) SendTestAlert(channel models.NotificationChannel) error {
+	now := time.Now()
+	rule := &models.AlertRule{
+		Name:       "Test Notification",
+		MetricType: "system",
+		MetricName: "cpu_usage",
+		Condition:  "gt",
+		Threshold:  80,
+		Severity:   "info",
+	}
+	alert := &models.AlertInstance{
+		TriggeredAt:    now,
+		Status:         "triggered",
+		CurrentValue:   85,
+		ThresholdValue: 80,
+		Message:        fmt.Sprintf("This is a test notification for channel '%s' from Nginx Manager", channel.Name),
+	}
+
+	return ns.dispatchAlert(channel, alert, rule)
+}
+
+// validateHTTPURL ensures a webhook URL is an absolute http(s) URL
+func validateHTTPURL(rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("URL is required")
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("URL must be an absolute http or https URL")
+	}
+
+	return nil
+}
+
+// sendDiscordAlert sends an alert to a Discord webhook as an embed
+func (ns *NotificationService) sendDiscordAlert(channel models.NotificationChannel, alert *models.AlertInstance, rule *models.AlertRule) error {
+	var discordConfig DiscordConfig
+	if err := ns.parseConfig(channel.Configuration, &discordConfig); err != nil {
+		return fmt.Errorf("invalid Discord configuration: %v", err)
+	}
+
+	payload := map[string]interface{}{
+		"embeds": []map[string]interface{}{
+			{
+				"title":       fmt.Sprintf("%s Alert: %s", strings.Title(rule.Severity), rule.Name),
+				"description": alert.Message,
+				"color":       ns.getDiscordSeverityColor(rule.Severity),
+				"timestamp":   alert.TriggeredAt.Format(time.RFC3339),
+				"fields": []map[string]interface{}{
+					{
+						"name":   "Metric",
+						"value":  rule.MetricName,
+						"inline": true,
+					},
+					{
+						"name":   "Current Value",
+						"value":  fmt.Sprintf("%.2f", alert.CurrentValue),
+						"inline": true,
+					},
+					{
+						"name":   "Threshold",
+						"value":  fmt.Sprintf("%.2f", alert.ThresholdValue),
+						"inline": true,
+					},
+					{
+						"name":   "Triggered At",
+						"value":  alert.TriggeredAt.Format("2006-01-02 15:04:05"),
+						"inline": false,
+					},
+				},
+			},
+		},
+	}
+
+	return ns.sendDiscordRequest(discordConfig, payload)
+}
+
+// sendDiscordRequest posts a payload to a Discord webhook, waiting and
+// retrying when Discord responds with 429 and a retry-after header
+func (ns *NotificationService) sendDiscordRequest(config DiscordConfig, payload map[string]interface{}) error {
+	if err := validateDiscordWebhookURL(config.WebhookURL); err != nil {
+		return err
+	}
+
+	if config.Username != "" {
+		payload["username"] = config.Username
+	}
+	if config.AvatarURL != "" {
+		payload["avatar_url"] = config.AvatarURL
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := ns.httpClient.Post(config.WebhookURL, "application/json", bytes.NewBuffer(jsonData))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < discordMaxRetries {
+			wait := parseRetryAfter(resp.Header.Get("Retry-After"))
+			logger.Warn("Discord rate limit hit, retrying",
+				logger.Duration("retry_after", wait),
+				logger.Int("attempt", attempt+1))
+			time.Sleep(wait)
+			continue
+		}
+
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("discord webhook request failed with status: %d", resp.StatusCode)
+		}
+
+		logger.Info("Alert notification sent successfully",
+			logger.String("channel_type", "discord"),
+			logger.Int("status_code", resp.StatusCode))
+		return nil
+	}
+}
+
+// validateDiscordWebhookURL ensures the webhook points at Discord over HTTPS
+func validateDiscordWebhookURL(rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("missing webhook_url in Discord configuration")
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid Discord webhook URL: %v", err)
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	validHost := host == "discord.com" || host == "discordapp.com" ||
+		strings.HasSuffix(host, ".discord.com") || strings.HasSuffix(host, ".discordapp.com")
+	if parsed.Scheme != "https" || !validHost {
+		return fmt.Errorf("discord webhook URL must be https on discord.com or discordapp.com")
+	}
+
+	return nil
+}
+
+// parseRetryAfter parses a Retry-After header given in (possibly fractional)
+// seconds, falling back to one second
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return time.Second
+	}
+	if seconds > 60 {
+		seconds = 60
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
 // sendEmail sends an email using SMTP
 func (ns *NotificationService) sendEmail(config EmailConfig, subject, body string) error {
 	// Create message
@@ -205,28 +866,125 @@ func (ns *NotificationService) sendEmail(config EmailConfig, subject, body strin
 	return smtp.SendMail(addr, auth, config.FromAddress, config.ToAddresses, []byte(message))
 }
 
-// sendWebhookRequest sends a webhook request
+// sendWebhookRequest sends a webhook request without retries
 func (ns *NotificationService) sendWebhookRequest(url string, payload interface{}) error {
+	return ns.sendWebhookRequestWithRetry(url, payload, webhookRetryPolicy{})
+}
+
+// retryPolicyFor builds the retry policy for a generic webhook channel
+func (ns *NotificationService) retryPolicyFor(config WebhookConfig) webhookRetryPolicy {
+	policy := webhookRetryPolicy{
+		RetryCount:   config.RetryCount,
+		Timeout:      time.Duration(config.Timeout) * time.Second,
+		BaseDelay:    ns.retryBaseDelay,
+		MaxTotalWait: ns.retryMaxTotalWait,
+	}
+	if config.BackoffMs > 0 {
+		policy.BaseDelay = time.Duration(config.BackoffMs) * time.Millisecond
+	}
+	return policy
+}
+
+// sendWebhookRequestWithRetry sends a webhook request, retrying network errors
+// and 5xx responses with exponential backoff and jitter. It gives up once the
+// retries are exhausted or the next wait would exceed the policy's total cap.
+func (ns *NotificationService) sendWebhookRequestWithRetry(url string, payload interface{}, policy webhookRetryPolicy) error {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	resp, err := ns.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	maxAttempts := policy.RetryCount + 1
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var lastErr error
+	lastStatus := 0
+	attempt := 0
+	var waited time.Duration
+
+	for attempt < maxAttempts {
+		attempt++
+
+		status, err := ns.postJSON(url, jsonData, policy.Timeout)
+		if err == nil && status < 400 {
+			logger.Info("Alert notification sent successfully",
+				logger.String("url", url),
+				logger.Int("status_code", status),
+				logger.Int("attempt", attempt))
+			return nil
+		}
+
+		if err != nil {
+			lastErr = err
+		} else {
+			lastStatus = status
+			lastErr = fmt.Errorf("status %d", status)
+			if status < 500 {
+				// Client errors won't succeed on retry
+				break
+			}
+		}
+
+		if attempt >= maxAttempts {
+			break
+		}
+
+		delay := backoffDelay(policy.BaseDelay, attempt)
+		if policy.MaxTotalWait > 0 && waited+delay > policy.MaxTotalWait {
+			break
+		}
+
+		logger.Warn("Webhook request failed, retrying",
+			logger.String("url", url),
+			logger.Int("attempt", attempt),
+			logger.Duration("backoff", delay),
+			logger.Err(lastErr))
+
+		time.Sleep(delay)
+		waited += delay
+	}
+
+	return fmt.Errorf("webhook request failed after %d attempt(s) (last status: %d): %v", attempt, lastStatus, lastErr)
+}
+
+// postJSON posts a JSON body and returns the response status code
+func (ns *NotificationService) postJSON(url string, body []byte, timeout time.Duration) (int, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer resp.Body.Close()
+	req.Header.Set("Content-Type", "application/json")
 
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("webhook request failed with status: %d", resp.StatusCode)
+	resp, err := ns.httpClient.Do(req)
+	if err != nil {
+		return 0, err
 	}
+	defer resp.Body.Close()
 
-	logger.Info("Alert notification sent successfully",
-		logger.String("url", url),
-		logger.Int("status_code", resp.StatusCode))
+	return resp.StatusCode, nil
+}
 
-	return nil
+// backoffDelay returns base * 2^(attempt-1) plus up to 50% random jitter
+func backoffDelay(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	if attempt > 16 {
+		attempt = 16
+	}
+
+	delay := base << uint(attempt-1)
+	jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay + jitter
 }
 
 // generateEmailBody generates HTML email body for alerts
@@ -316,6 +1074,15 @@ func (ns *NotificationService) parseConfig(config map[string]interface{}, target
 	return json.Unmarshal(data, target)
 }
 
+// getDiscordSeverityColor returns the Teams severity color as a Discord embed color integer
+func (ns *NotificationService) getDiscordSeverityColor(severity string) int {
+	color, err := strconv.ParseInt(ns.getTeamsSeverityColor(severity), 16, 32)
+	if err != nil {
+		return 0xFFA500
+	}
+	return int(color)
+}
+
 func (ns *NotificationService) getTeamsSeverityColor(severity string) string {
 	switch severity {
 	case "critical":
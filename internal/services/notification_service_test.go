@@ -0,0 +1,109 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// webhookRecorder is a webhook endpoint that keeps every payload it receives
+type webhookRecorder struct {
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var payload map[string]interface{}
+	_ = json.NewDecoder(req.Body).Decode(&payload)
+	r.mu.Lock()
+	r.payloads = append(r.payloads, payload)
+	r.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *webhookRecorder) received() []map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]map[string]interface{}(nil), r.payloads...)
+}
+
+func TestQuietHoursQueueSurvivesRestart(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&models.NotificationChannel{}); err != nil {
+		t.Fatalf("migrate channels: %v", err)
+	}
+	user := createTestUser(t, db, "owner@example.test", models.RoleUser)
+
+	oldHook, newHook := &webhookRecorder{}, &webhookRecorder{}
+	oldServer, newServer := httptest.NewServer(oldHook), httptest.NewServer(newHook)
+	defer oldServer.Close()
+	defer newServer.Close()
+
+	// A window around the current time keeps quiet hours active
+	now := time.Now().UTC()
+	channel := &models.NotificationChannel{
+		Name:      "ops",
+		Type:      "webhook",
+		IsEnabled: true,
+		UserID:    user.ID,
+		Configuration: models.JSON{
+			"url": oldServer.URL,
+			"quiet_hours": map[string]interface{}{
+				"enabled": true,
+				"start":   now.Add(-time.Hour).Format("15:04"),
+				"end":     now.Add(time.Hour).Format("15:04"),
+			},
+		},
+	}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	ns := NewNotificationService()
+	rule := &models.AlertRule{Name: "High CPU", Severity: "warning"}
+	alert := &models.AlertInstance{Message: "CPU at 91%", CurrentValue: 91, ThresholdValue: 80, TriggeredAt: now}
+	if err := ns.SendAlert(*channel, alert, rule); err != nil {
+		t.Fatalf("send alert: %v", err)
+	}
+
+	var queued int64
+	db.Model(&models.QueuedNotification{}).Where("channel_id = ?", channel.ID).Count(&queued)
+	if queued != 1 {
+		t.Fatalf("queued notifications = %d, want 1", queued)
+	}
+	if got := len(oldHook.received()); got != 0 {
+		t.Fatalf("webhook received %d requests during quiet hours, want 0", got)
+	}
+
+	// Quiet hours end and the webhook moves while the service restarts
+	channel.Configuration = models.JSON{"url": newServer.URL}
+	if err := db.Save(channel).Error; err != nil {
+		t.Fatalf("update channel: %v", err)
+	}
+	NewNotificationService().FlushQueuedNotifications()
+
+	payloads := newHook.received()
+	if len(payloads) != 1 {
+		t.Fatalf("new webhook received %d requests, want 1 summary", len(payloads))
+	}
+	if got := len(oldHook.received()); got != 0 {
+		t.Errorf("old webhook received %d requests, want 0", got)
+	}
+	alerts, _ := payloads[0]["alerts"].([]interface{})
+	if len(alerts) != 1 {
+		t.Fatalf("summary has %d alerts, want 1: %v", len(alerts), payloads[0])
+	}
+	if name := alerts[0].(map[string]interface{})["rule_name"]; name != rule.Name {
+		t.Errorf("summary rule_name = %v, want %q", name, rule.Name)
+	}
+
+	db.Model(&models.QueuedNotification{}).Count(&queued)
+	if queued != 0 {
+		t.Errorf("queued notifications after flush = %d, want 0", queued)
+	}
+}
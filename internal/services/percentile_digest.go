@@ -0,0 +1,111 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// digestCompression bounds the size of a percentile digest: it is compressed
+// to about this many centroids once it holds twice as many. Centroids near
+// the tails stay small, so p95 and p99 stay accurate.
+const digestCompression = 100
+
+// newPercentileDigest builds a digest from a window's sorted values
+func newPercentileDigest(sortedValues []float64) models.PercentileDigest {
+	digest := make(models.PercentileDigest, len(sortedValues))
+	for i, value := range sortedValues {
+		digest[i] = models.DigestCentroid{Mean: value, Count: 1}
+	}
+	if len(digest) > 2*digestCompression {
+		digest = compressDigest(digest)
+	}
+	return digest
+}
+
+// addDigestValue adds one value to a digest, keeping its centroids sorted
+func addDigestValue(digest models.PercentileDigest, value float64) models.PercentileDigest {
+	i := sort.Search(len(digest), func(i int) bool { return digest[i].Mean > value })
+	digest = append(digest, models.DigestCentroid{})
+	copy(digest[i+1:], digest[i:])
+	digest[i] = models.DigestCentroid{Mean: value, Count: 1}
+
+	if len(digest) > 2*digestCompression {
+		digest = compressDigest(digest)
+	}
+	return digest
+}
+
+// compressDigest merges neighbouring centroids as a merging t-digest does:
+// a centroid may grow until it spans one unit of the k1 scale function, which
+// keeps centroids small near the extremes and large around the median
+func compressDigest(digest models.PercentileDigest) models.PercentileDigest {
+	var total float64
+	for _, centroid := range digest {
+		total += centroid.Count
+	}
+
+	compressed := make(models.PercentileDigest, 0, digestCompression)
+	current := digest[0]
+	var before float64 // values in the centroids already emitted
+	limit := total * digestScaleInverse(digestScale(0)+1)
+
+	for _, centroid := range digest[1:] {
+		if before+current.Count+centroid.Count <= limit {
+			current.Count += centroid.Count
+			current.Mean += (centroid.Mean - current.Mean) * centroid.Count / current.Count
+			continue
+		}
+		compressed = append(compressed, current)
+		before += current.Count
+		limit = total * digestScaleInverse(digestScale(before/total)+1)
+		current = centroid
+	}
+	return append(compressed, current)
+}
+
+// digestScale is the t-digest k1 scale function for quantile q
+func digestScale(q float64) float64 {
+	return digestCompression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// digestScaleInverse returns the quantile at scale k, capped at 1
+func digestScaleInverse(k float64) float64 {
+	angle := k * 2 * math.Pi / digestCompression
+	if angle >= math.Pi/2 {
+		return 1
+	}
+	return (math.Sin(angle) + 1) / 2
+}
+
+// digestPercentile estimates the p-th percentile of the digested values. Each
+// centroid sits at the middle rank of its values and ranks in between are
+// interpolated, so for a digest of single values this matches percentile.
+func digestPercentile(digest models.PercentileDigest, p float64) float64 {
+	if len(digest) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, centroid := range digest {
+		total += centroid.Count
+	}
+	rank := p * (total - 1)
+
+	var before float64
+	previousRank, previousMean := 0.0, digest[0].Mean
+	for i, centroid := range digest {
+		centroidRank := before + (centroid.Count-1)/2
+		if rank <= centroidRank {
+			if i == 0 || centroidRank == previousRank {
+				return centroid.Mean
+			}
+			weight := (rank - previousRank) / (centroidRank - previousRank)
+			return previousMean + (centroid.Mean-previousMean)*weight
+		}
+		before += centroid.Count
+		previousRank, previousMean = centroidRank, centroid.Mean
+	}
+	return digest[len(digest)-1].Mean
+}
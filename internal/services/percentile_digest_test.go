@@ -0,0 +1,63 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestDigestPercentileExactForFewValues checks that a digest that was never
+// compressed gives the same percentiles as the sorted values
+func TestDigestPercentileExactForFewValues(t *testing.T) {
+	as := &AnalyticsService{}
+	values := []float64{12, 3, 7, 7, 42, 1, 19, 5, 8, 30}
+
+	var digest models.PercentileDigest
+	for _, value := range values {
+		digest = addDigestValue(digest, value)
+	}
+	sort.Float64s(values)
+
+	for _, p := range []float64{0, 0.25, 0.5, 0.95, 0.99, 1} {
+		if got, want := digestPercentile(digest, p), as.percentile(values, p); got != want {
+			t.Errorf("p%v: got %v, want %v", p*100, got, want)
+		}
+	}
+}
+
+// TestDigestPercentileAccuracy adds many values one at a time and compares
+// the estimated percentiles with the exact ones
+func TestDigestPercentileAccuracy(t *testing.T) {
+	as := &AnalyticsService{}
+	random := rand.New(rand.NewSource(1))
+
+	for name, draw := range map[string]func() float64{
+		"uniform":     func() float64 { return random.Float64() * 100 },
+		"exponential": func() float64 { return random.ExpFloat64() * 50 },
+	} {
+		values := make([]float64, 20000)
+		var digest models.PercentileDigest
+		for i := range values {
+			values[i] = draw()
+			digest = addDigestValue(digest, values[i])
+		}
+		sort.Float64s(values)
+
+		if len(digest) > 2*digestCompression {
+			t.Errorf("%s: digest holds %d centroids, want at most %d", name, len(digest), 2*digestCompression)
+		}
+
+		for _, p := range []float64{0.5, 0.95, 0.99} {
+			got, want := digestPercentile(digest, p), as.percentile(values, p)
+			// Compare by rank: the estimate must fall within 1% of the values
+			// of the exact percentile
+			rank := float64(sort.SearchFloat64s(values, got)) / float64(len(values))
+			if math.Abs(rank-p) > 0.01 {
+				t.Errorf("%s p%v: got %v at rank %.4f, want about %v", name, p*100, got, rank, want)
+			}
+		}
+	}
+}
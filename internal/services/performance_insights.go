@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsightNotFound = errors.New("insight not found")
+
+// InsightFilter narrows a list of performance insights. Empty fields match
+// every insight.
+type InsightFilter struct {
+	Type     string
+	Category string
+	Severity string
+	Resolved *bool
+	Limit    int
+	Offset   int
+}
+
+// RecordInsight stores a performance insight, or refreshes the one already
+// recorded with the same fingerprint, so repeated analyses of the same data do
+// not duplicate the feed. A refresh updates what was found but keeps whether
+// the insight was resolved. Without a fingerprint the insight is always added.
+func (as *AnalyticsService) RecordInsight(insight *models.PerformanceInsight) error {
+	if insight.Fingerprint != nil && *insight.Fingerprint == "" {
+		insight.Fingerprint = nil
+	}
+
+	return as.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "fingerprint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"severity", "title", "description", "data", "recommendations", "updated_at"}),
+	}).Create(insight).Error
+}
+
+// insightFingerprint returns a fingerprint for RecordInsight
+func insightFingerprint(format string, args ...interface{}) *string {
+	fingerprint := fmt.Sprintf(format, args...)
+	return &fingerprint
+}
+
+// ListInsights returns performance insights, newest first, and the number
+// matching the filter
+func (as *AnalyticsService) ListInsights(filter InsightFilter) ([]models.PerformanceInsight, int64, error) {
+	query := as.db.Model(&models.PerformanceInsight{})
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.Resolved != nil {
+		query = query.Where("is_resolved = ?", *filter.Resolved)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var insights []models.PerformanceInsight
+	err := query.Order("created_at DESC").Order("id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&insights).Error
+	return insights, total, err
+}
+
+// ResolveInsight marks a performance insight as resolved
+func (as *AnalyticsService) ResolveInsight(id uint) (*models.PerformanceInsight, error) {
+	var insight models.PerformanceInsight
+	if err := as.db.First(&insight, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInsightNotFound
+		}
+		return nil, err
+	}
+
+	if !insight.IsResolved {
+		now := time.Now()
+		insight.IsResolved = true
+		insight.ResolvedAt = &now
+		if err := as.db.Save(&insight).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &insight, nil
+}
+
+// recordAnomalyInsights stores the anomalies found by a trend analysis as
+// insights. Failures are logged so they never fail the analysis itself.
+func (as *AnalyticsService) recordAnomalyInsights(req TrendRequest, algorithm string, anomalies []Anomaly) {
+	for _, anomaly := range anomalies {
+		insight := &models.PerformanceInsight{
+			Type:        "anomaly",
+			Severity:    anomaly.Severity,
+			Title:       fmt.Sprintf("Anomaly in %s.%s", req.MetricType, req.MetricName),
+			Description: anomaly.Description,
+			Category:    "performance",
+			Source:      req.MetricType,
+			Data: models.JSON{
+				"metric_type":  req.MetricType,
+				"metric_name":  req.MetricName,
+				"timestamp":    anomaly.Timestamp,
+				"value":        anomaly.Value,
+				"expected_min": anomaly.ExpectedMin,
+				"expected_max": anomaly.ExpectedMax,
+				"algorithm":    algorithm,
+			},
+			Recommendations: models.StringArray{
+				fmt.Sprintf("Review deployments, traffic and upstream health around %s", anomaly.Timestamp.Format(time.RFC3339)),
+			},
+			Fingerprint: insightFingerprint("anomaly:%s:%s:%d", req.MetricType, req.MetricName, anomaly.Timestamp.Unix()),
+		}
+
+		if err := as.RecordInsight(insight); err != nil {
+			logger.Error("Failed to record anomaly insight",
+				logger.Err(err),
+				logger.String("metric_type", req.MetricType),
+				logger.String("metric_name", req.MetricName))
+			return
+		}
+	}
+}
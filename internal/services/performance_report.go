@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+// reportResource describes a resource the performance report analyzes and
+// the usage percentages at which it warns and turns critical
+type reportResource struct {
+	name           string
+	metricName     string
+	tags           map[string]string
+	warning        float64
+	critical       float64
+	recommendation string
+}
+
+var reportResources = []reportResource{
+	{
+		name:           "cpu",
+		metricName:     "cpu_usage",
+		warning:        80,
+		critical:       90,
+		recommendation: "Spread traffic over more upstreams or add CPU capacity",
+	},
+	{
+		name:           "memory",
+		metricName:     "memory_usage",
+		warning:        85,
+		critical:       95,
+		recommendation: "Review worker_connections and buffer sizes, or add memory",
+	},
+	{
+		name:           "disk",
+		metricName:     "disk_usage",
+		tags:           map[string]string{"mount": "/"},
+		warning:        80,
+		critical:       90,
+		recommendation: "Rotate or archive logs and remove old backups to free disk space",
+	},
+}
+
+// GeneratePerformanceReport analyzes resource usage, traffic and alerts over
+// the time range. A resource at a warning or critical level adds a
+// recommendation, which is recorded as a performance insight so it stays in
+// the insights feed after the report.
+func (as *AnalyticsService) GeneratePerformanceReport(timeRange TimeRange) (*PerformanceReport, error) {
+	now := time.Now()
+	report := &PerformanceReport{
+		TimeRange: timeRange,
+		SystemHealth: SystemHealthScore{
+			Components:  make(map[string]float64),
+			Trend:       "stable",
+			LastChecked: now,
+		},
+		Alerts:          []models.AlertInstance{},
+		Recommendations: []models.PerformanceInsight{},
+		GeneratedAt:     now,
+	}
+
+	var increasing, decreasing int
+	for _, resource := range reportResources {
+		metric, err := as.analyzeResource(resource, timeRange)
+		if err != nil {
+			return nil, err
+		}
+
+		switch resource.name {
+		case "cpu":
+			report.ResourceAnalysis.CPU = *metric
+		case "memory":
+			report.ResourceAnalysis.Memory = *metric
+		case "disk":
+			report.ResourceAnalysis.Disk = *metric
+		}
+
+		if metric.LastUpdated.IsZero() {
+			continue
+		}
+		report.SystemHealth.Components[resource.name] = math.Max(0, 100-metric.Average)
+		switch metric.Trend {
+		case "increasing":
+			increasing++
+		case "decreasing":
+			decreasing++
+		}
+
+		if metric.AlertLevel == "normal" {
+			continue
+		}
+		insight := resourceInsight(resource, metric, timeRange)
+		if err := as.RecordInsight(insight); err != nil {
+			logger.Error("Failed to record performance report recommendation",
+				logger.Err(err), logger.String("resource", resource.name))
+		}
+		report.Recommendations = append(report.Recommendations, *insight)
+	}
+
+	// Usage rising means health is declining
+	switch {
+	case increasing > decreasing:
+		report.SystemHealth.Trend = "declining"
+	case decreasing > increasing:
+		report.SystemHealth.Trend = "improving"
+	}
+
+	if len(report.SystemHealth.Components) > 0 {
+		var sum float64
+		for _, score := range report.SystemHealth.Components {
+			sum += score
+		}
+		report.SystemHealth.Overall = sum / float64(len(report.SystemHealth.Components))
+	}
+
+	network, err := as.AnalyzeNetwork(timeRange)
+	if err != nil {
+		return nil, err
+	}
+	report.ResourceAnalysis.Network = *network
+
+	if err := as.db.Where("status = ? AND triggered_at BETWEEN ? AND ?", "triggered", timeRange.Start, timeRange.End).
+		Order("triggered_at DESC").
+		Limit(100).
+		Find(&report.Alerts).Error; err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// analyzeResource summarizes a resource's stored usage samples over the time
+// range. Without samples the trend is unknown and the level normal.
+func (as *AnalyticsService) analyzeResource(resource reportResource, timeRange TimeRange) (*ResourceMetric, error) {
+	points, err := as.QueryMetrics(MetricQuery{
+		MetricType: "system",
+		MetricName: resource.metricName,
+		TimeRange:  timeRange,
+		Tags:       resource.tags,
+		Limit:      10000,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metric := &ResourceMetric{Trend: "unknown", AlertLevel: "normal"}
+	if len(points) == 0 {
+		return metric, nil
+	}
+
+	var sum float64
+	for _, point := range points {
+		sum += point.Value
+		if point.Value > metric.Peak {
+			metric.Peak = point.Value
+		}
+	}
+	latest := points[len(points)-1]
+	metric.Current = latest.Value
+	metric.Average = sum / float64(len(points))
+	metric.LastUpdated = latest.Timestamp
+
+	trend := as.calculateTrend(points)
+	metric.Trend = trend.Direction
+	metric.PredictedPeak = metric.Peak
+	if trend.Direction == "increasing" {
+		metric.PredictedPeak = math.Min(100, metric.Peak*(1+math.Abs(trend.ChangePercent)/100))
+	}
+
+	switch {
+	case metric.Current >= resource.critical:
+		metric.AlertLevel = "critical"
+	case metric.Current >= resource.warning:
+		metric.AlertLevel = "warning"
+	}
+	return metric, nil
+}
+
+// resourceInsight builds the recommendation for a resource at a warning or
+// critical level. Its fingerprint is per resource, so every report refreshes
+// the same insight rather than adding one.
+func resourceInsight(resource reportResource, metric *ResourceMetric, timeRange TimeRange) *models.PerformanceInsight {
+	return &models.PerformanceInsight{
+		Type:        "recommendation",
+		Severity:    metric.AlertLevel,
+		Title:       fmt.Sprintf("High %s usage", resource.name),
+		Description: fmt.Sprintf("%s usage is %.1f%% (average %.1f%%, peak %.1f%%), above the %.0f%% warning level", resource.name, metric.Current, metric.Average, metric.Peak, resource.warning),
+		Category:    "resources",
+		Source:      "system",
+		Data: models.JSON{
+			"metric_type": "system",
+			"metric_name": resource.metricName,
+			"current":     metric.Current,
+			"average":     metric.Average,
+			"peak":        metric.Peak,
+			"trend":       metric.Trend,
+			"start":       timeRange.Start,
+			"end":         timeRange.End,
+		},
+		Recommendations: models.StringArray{resource.recommendation},
+		Fingerprint:     insightFingerprint("recommendation:%s", resource.name),
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// storeUsage stores one system usage sample a minute for each value, ending
+// a minute ago
+func storeUsage(t *testing.T, as *AnalyticsService, metricName string, tags models.JSON, values ...float64) {
+	t.Helper()
+	start := time.Now().Add(-time.Duration(len(values)) * time.Minute)
+	for i, value := range values {
+		metric := &models.HistoricalMetric{
+			MetricType: "system",
+			MetricName: metricName,
+			Value:      value,
+			Unit:       "percent",
+			Tags:       tags,
+			Timestamp:  start.Add(time.Duration(i) * time.Minute),
+		}
+		if err := as.db.Create(metric).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestPerformanceReportRecordsRecommendations checks that a resource above
+// its warning level is recommended on and recorded once however many reports
+// are generated, and that resolving it survives the next report
+func TestPerformanceReportRecordsRecommendations(t *testing.T) {
+	as, db := newTestAnalyticsService(t)
+	if err := db.AutoMigrate(&models.AlertInstance{}); err != nil {
+		t.Fatal(err)
+	}
+	storeUsage(t, as, "cpu_usage", nil, 70, 85, 95)
+	storeUsage(t, as, "memory_usage", nil, 40, 40, 40)
+	storeUsage(t, as, "disk_usage", models.JSON{"mount": "/"}, 50, 60, 85)
+	timeRange := TimeRange{Start: time.Now().Add(-time.Hour), End: time.Now()}
+
+	report, err := as.GeneratePerformanceReport(timeRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ResourceAnalysis.CPU.AlertLevel != "critical" || report.ResourceAnalysis.Disk.AlertLevel != "warning" ||
+		report.ResourceAnalysis.Memory.AlertLevel != "normal" {
+		t.Errorf("got cpu %q, memory %q, disk %q, want critical, normal, warning", report.ResourceAnalysis.CPU.AlertLevel,
+			report.ResourceAnalysis.Memory.AlertLevel, report.ResourceAnalysis.Disk.AlertLevel)
+	}
+	if len(report.Recommendations) != 2 {
+		t.Fatalf("got %d recommendations, want 2", len(report.Recommendations))
+	}
+
+	insights, total, err := as.ListInsights(InsightFilter{Type: "recommendation", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("got %d recorded recommendations, want 2", total)
+	}
+	var cpu models.PerformanceInsight
+	for _, insight := range insights {
+		if insight.Data["metric_name"] == "cpu_usage" {
+			cpu = insight
+		}
+	}
+	if cpu.ID == 0 || cpu.Severity != "critical" {
+		t.Fatalf("got cpu insight %+v, want a critical one", cpu)
+	}
+	if _, err := as.ResolveInsight(cpu.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The CPU settles at a warning level: the same insight is refreshed
+	storeUsage(t, as, "cpu_usage", nil, 82)
+	if _, err := as.GeneratePerformanceReport(timeRange); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, total, err = as.ListInsights(InsightFilter{Type: "recommendation"}); err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Errorf("got %d recorded recommendations after a second report, want 2", total)
+	}
+	var refreshed models.PerformanceInsight
+	if err := db.First(&refreshed, cpu.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if refreshed.Severity != "warning" || !refreshed.IsResolved {
+		t.Errorf("got severity %q and resolved %v, want a resolved warning", refreshed.Severity, refreshed.IsResolved)
+	}
+}
+
+// TestRecordInsightWithoutFingerprint checks that insights without a
+// fingerprint never collide
+func TestRecordInsightWithoutFingerprint(t *testing.T) {
+	as, _ := newTestAnalyticsService(t)
+
+	empty := ""
+	for _, fingerprint := range []*string{nil, nil, &empty, &empty} {
+		insight := &models.PerformanceInsight{Type: "trend", Severity: "info", Title: "Traffic", Fingerprint: fingerprint}
+		if err := as.RecordInsight(insight); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, total, err := as.ListInsights(InsightFilter{}); err != nil {
+		t.Fatal(err)
+	} else if total != 4 {
+		t.Errorf("got %d insights, want 4", total)
+	}
+}
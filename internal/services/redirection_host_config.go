@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+var (
+	ErrInvalidRedirectCode   = errors.New("redirect code must be 301, 302, 307 or 308")
+	ErrInvalidRedirectScheme = errors.New("forward scheme must be http, https or auto")
+	ErrInvalidRedirectTarget = errors.New("forward domain name must be a hostname or IP address with an optional port")
+)
+
+// RedirectSchemeAuto keeps the scheme of the incoming request
+const RedirectSchemeAuto = "auto"
+
+var (
+	validRedirectCodes   = []int{301, 302, 307, 308}
+	validRedirectSchemes = []string{string(models.SchemeHTTP), string(models.SchemeHTTPS), RedirectSchemeAuto}
+
+	// redirectHostnameLabel matches one DNS label
+	redirectHostnameLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+// ValidateRedirectCode checks that code is a redirect status nginx can return
+func ValidateRedirectCode(code int) error {
+	for _, valid := range validRedirectCodes {
+		if code == valid {
+			return nil
+		}
+	}
+	return ErrInvalidRedirectCode
+}
+
+// ValidateRedirectScheme checks the scheme a redirection host forwards to
+func ValidateRedirectScheme(scheme string) error {
+	if !containsString(validRedirectSchemes, scheme) {
+		return ErrInvalidRedirectScheme
+	}
+	return nil
+}
+
+// ValidateRedirectTarget checks that target is a hostname or IP address,
+// optionally followed by a port, with no scheme or path
+func ValidateRedirectTarget(target string) error {
+	host := target
+	if h, port, err := net.SplitHostPort(target); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return ErrInvalidRedirectTarget
+		}
+		host = h
+	}
+
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if host == "" || len(host) > 253 {
+		return ErrInvalidRedirectTarget
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if !redirectHostnameLabel.MatchString(label) {
+			return ErrInvalidRedirectTarget
+		}
+	}
+	return nil
+}
+
+// redirectionHostConfigFile returns the config file written for a redirection host
+func (s *NginxService) redirectionHostConfigFile(redirectionHost *models.RedirectionHost) string {
+	return filepath.Join(s.sitesPath, fmt.Sprintf("redirection_host_%d.conf", redirectionHost.ID))
+}
+
+// BuildRedirectionHostConfig renders the nginx configuration for a
+// redirection host without writing it
+func (s *NginxService) BuildRedirectionHostConfig(redirectionHost *models.RedirectionHost) string {
+	certificate := s.loadHostCertificate(redirectionHost.CertificateID)
+	wantsSSL := redirectionHost.SSLForced || redirectionHost.CertificateID != nil
+	return generateRedirectionHostConfig(redirectionHost, s.resolveHostSSL(redirectionHost.GetPrimaryDomain(), certificate, wantsSSL))
+}
+
+// generateRedirectionHostConfig generates a server block that redirects every
+// request to the forward domain, keeping the request URI when PreservePath is set
+func generateRedirectionHostConfig(redirectionHost *models.RedirectionHost, ssl *hostSSL) string {
+	var config strings.Builder
+
+	config.WriteString("server {\n")
+	writeHostServerHeader(&config, redirectionHost.DomainNames, ssl, redirectionHost.SSLForced)
+
+	if redirectionHost.AdvancedConfig != "" {
+		config.WriteString("\n    # Advanced configuration\n")
+		for _, line := range strings.Split(strings.TrimRight(redirectionHost.AdvancedConfig, "\n"), "\n") {
+			config.WriteString("    " + line + "\n")
+		}
+		config.WriteString("\n")
+	}
+
+	scheme := redirectionHost.ForwardScheme
+	if scheme == RedirectSchemeAuto {
+		scheme = "$scheme"
+	}
+	target := scheme + "://" + redirectionHost.ForwardDomainName
+	if redirectionHost.PreservePath {
+		target += "$request_uri"
+	}
+
+	config.WriteString("    location / {\n")
+	config.WriteString(fmt.Sprintf("        return %d %s;\n", redirectionHost.StatusCode, target))
+	config.WriteString("    }\n")
+	config.WriteString("}\n")
+
+	return config.String()
+}
+
+// DeployRedirectionHost writes a redirection host's configuration and reloads
+// nginx. Redirection hosts are not staged; they are deployed immediately.
+func (s *NginxService) DeployRedirectionHost(redirectionHost *models.RedirectionHost) error {
+	configContent := s.BuildRedirectionHostConfig(redirectionHost)
+	if err := os.WriteFile(s.redirectionHostConfigFile(redirectionHost), []byte(configContent), 0644); err != nil {
+		return fmt.Errorf("%w: %v", ErrNginxConfigGeneration, err)
+	}
+	return s.reloadNginx()
+}
+
+// UndeployRedirectionHost removes a redirection host's configuration and
+// reloads nginx
+func (s *NginxService) UndeployRedirectionHost(redirectionHost *models.RedirectionHost) error {
+	if err := os.Remove(s.redirectionHostConfigFile(redirectionHost)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.reloadNginx()
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRetentionPolicy  = errors.New("invalid retention policy")
+	ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+)
+
+// ListRetentionPolicies returns all retention policies
+func (as *AnalyticsService) ListRetentionPolicies() ([]models.RetentionPolicy, error) {
+	var policies []models.RetentionPolicy
+	if err := as.db.Order("metric_type ASC, metric_name ASC, id ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// CreateRetentionPolicy adds a retention policy. It applies to metrics and
+// aggregations stored from now on; existing rows keep their retention.
+func (as *AnalyticsService) CreateRetentionPolicy(policy *models.RetentionPolicy) error {
+	if err := validateRetentionPolicy(policy); err != nil {
+		return err
+	}
+	if err := as.db.Create(policy).Error; err != nil {
+		return err
+	}
+	as.reloadRetentionPolicies()
+	return nil
+}
+
+// UpdateRetentionPolicy replaces a retention policy
+func (as *AnalyticsService) UpdateRetentionPolicy(policy *models.RetentionPolicy) error {
+	var existing models.RetentionPolicy
+	if err := as.db.First(&existing, policy.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRetentionPolicyNotFound
+		}
+		return err
+	}
+	if err := validateRetentionPolicy(policy); err != nil {
+		return err
+	}
+
+	policy.CreatedAt = existing.CreatedAt
+	if err := as.db.Save(policy).Error; err != nil {
+		return err
+	}
+	as.reloadRetentionPolicies()
+	return nil
+}
+
+// DeleteRetentionPolicy removes a retention policy, returning the metrics it
+// matched to the next matching policy or the defaults
+func (as *AnalyticsService) DeleteRetentionPolicy(id uint) error {
+	result := as.db.Delete(&models.RetentionPolicy{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRetentionPolicyNotFound
+	}
+	as.reloadRetentionPolicies()
+	return nil
+}
+
+// validateRetentionPolicy checks a policy and normalizes its name pattern
+func validateRetentionPolicy(policy *models.RetentionPolicy) error {
+	policy.MetricType = strings.TrimSpace(policy.MetricType)
+	policy.MetricName = strings.TrimSpace(policy.MetricName)
+	if policy.MetricType == "" {
+		return fmt.Errorf("%w: metric_type is required", ErrInvalidRetentionPolicy)
+	}
+	if policy.MetricName == "" {
+		policy.MetricName = "*"
+	}
+	if _, err := path.Match(policy.MetricName, ""); err != nil {
+		return fmt.Errorf("%w: metric_name %q is not a valid pattern", ErrInvalidRetentionPolicy, policy.MetricName)
+	}
+	if policy.RawRetentionDays < 0 {
+		return fmt.Errorf("%w: raw_retention_days cannot be negative", ErrInvalidRetentionPolicy)
+	}
+
+	for window, value := range policy.WindowRetentionDays {
+		if !containsString(aggregationWindows, window) {
+			return fmt.Errorf("%w: unknown aggregation window %q, expected one of: %s",
+				ErrInvalidRetentionPolicy, window, strings.Join(aggregationWindows, ", "))
+		}
+		if _, ok := retentionDays(value); !ok {
+			return fmt.Errorf("%w: retention of window %s must be a non-negative number of days", ErrInvalidRetentionPolicy, window)
+		}
+	}
+	return nil
+}
+
+// retentionDays reads a whole, non-negative number of days decoded from JSON
+func retentionDays(value interface{}) (int, bool) {
+	days, ok := value.(float64)
+	if !ok || days < 0 || days != math.Trunc(days) {
+		return 0, false
+	}
+	return int(days), true
+}
+
+// rawRetention returns how long raw samples of a metric are kept
+func (as *AnalyticsService) rawRetention(metricType, metricName string) time.Duration {
+	if policy := as.matchRetentionPolicy(metricType, metricName); policy != nil && policy.RawRetentionDays > 0 {
+		return time.Duration(policy.RawRetentionDays) * 24 * time.Hour
+	}
+	return rawMetricRetention
+}
+
+// windowRetention returns how long aggregations of a metric are kept for a
+// time window
+func (as *AnalyticsService) windowRetention(metricType, metricName, window string) time.Duration {
+	if policy := as.matchRetentionPolicy(metricType, metricName); policy != nil {
+		if days, ok := retentionDays(policy.WindowRetentionDays[window]); ok && days > 0 {
+			return time.Duration(days) * 24 * time.Hour
+		}
+	}
+	return as.getRetentionForWindow(window)
+}
+
+// matchRetentionPolicy returns the policy for a metric. When several match,
+// an exact name wins over a pattern and a longer pattern over a shorter one.
+func (as *AnalyticsService) matchRetentionPolicy(metricType, metricName string) *models.RetentionPolicy {
+	var best *models.RetentionPolicy
+	bestExact := false
+	policies := as.loadRetentionPolicies()
+	for i, policy := range policies {
+		if policy.MetricType != metricType {
+			continue
+		}
+		if matched, _ := path.Match(policy.MetricName, metricName); !matched {
+			continue
+		}
+
+		exact := policy.MetricName == metricName
+		if best == nil || (exact && !bestExact) ||
+			(exact == bestExact && len(policy.MetricName) > len(best.MetricName)) {
+			best = &policies[i]
+			bestExact = exact
+		}
+	}
+	return best
+}
+
+// loadRetentionPolicies returns the cached policies, loading them on first
+// use. A failed load is retried on the next call and the defaults apply.
+func (as *AnalyticsService) loadRetentionPolicies() []models.RetentionPolicy {
+	as.retentionMutex.Lock()
+	defer as.retentionMutex.Unlock()
+
+	if !as.retentionLoaded {
+		policies, err := as.ListRetentionPolicies()
+		if err != nil {
+			return nil
+		}
+		as.retentionPolicies = policies
+		as.retentionLoaded = true
+	}
+	return as.retentionPolicies
+}
+
+// reloadRetentionPolicies drops the cached policies after a change
+func (as *AnalyticsService) reloadRetentionPolicies() {
+	as.retentionMutex.Lock()
+	as.retentionLoaded = false
+	as.retentionMutex.Unlock()
+}
+
+// cleanupRawMetrics deletes samples from the raw_metrics table that are older
+// than their series' raw retention, after aggregating the windows they fall
+// in. The table has no per-row retention, so each series is cut off by its
+// policy.
+func (as *AnalyticsService) cleanupRawMetrics(now time.Time) (int64, error) {
+	var series []struct {
+		MetricType string
+		MetricName string
+	}
+	if err := as.db.Model(&models.RawMetric{}).
+		Distinct("metric_type", "metric_name").
+		Find(&series).Error; err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, s := range series {
+		cutoff := now.Add(-as.rawRetention(s.MetricType, s.MetricName))
+		if _, err := as.rollUpExpiringMetrics(as.db.Model(&models.RawMetric{}).
+			Where("metric_type = ? AND metric_name = ? AND timestamp < ?", s.MetricType, s.MetricName, cutoff)); err != nil {
+			return deleted, fmt.Errorf("failed to aggregate expiring metrics: %w", err)
+		}
+
+		result := as.db.Where("metric_type = ? AND metric_name = ? AND timestamp < ?", s.MetricType, s.MetricName, cutoff).
+			Delete(&models.RawMetric{})
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// aggregationWindowsOf returns the windows holding an aggregation of a metric
+func aggregationWindowsOf(t *testing.T, as *AnalyticsService, metricName string) map[string]models.MetricAggregation {
+	t.Helper()
+
+	var aggregations []models.MetricAggregation
+	if err := as.db.Where("metric_type = ? AND metric_name = ?", "system", metricName).
+		Find(&aggregations).Error; err != nil {
+		t.Fatal(err)
+	}
+	windows := make(map[string]models.MetricAggregation, len(aggregations))
+	for _, agg := range aggregations {
+		windows[agg.TimeWindow] = agg
+	}
+	return windows
+}
+
+// TestCleanupRollsUpExpiringHistoricalMetrics stores expired samples that were
+// never aggregated and checks that cleanup aggregates them before deleting
+// them, skipping windows whose aggregation would already have expired
+func TestCleanupRollsUpExpiringHistoricalMetrics(t *testing.T) {
+	as, db := newTestAnalyticsService(t)
+	expired := time.Now().Add(-time.Hour)
+
+	// Ten days old: every window's aggregation is still kept. Sixty days old:
+	// 5m aggregations are kept for 30 days, so that window is skipped.
+	for name, age := range map[string]time.Duration{"recent": 10 * 24 * time.Hour, "old": 60 * 24 * time.Hour} {
+		timestamp := time.Now().Add(-age).Truncate(time.Hour).Add(time.Minute)
+		for i, value := range []float64{10, 20, 30} {
+			metric := &models.HistoricalMetric{
+				MetricType:   "system",
+				MetricName:   name,
+				Value:        value,
+				Timestamp:    timestamp.Add(time.Duration(i) * time.Second),
+				RetentionEnd: &expired,
+			}
+			if err := db.Create(metric).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := as.CleanupExpiredMetrics(); err != nil {
+		t.Fatal(err)
+	}
+
+	var remaining int64
+	db.Model(&models.HistoricalMetric{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("%d expired metrics remain", remaining)
+	}
+
+	recent := aggregationWindowsOf(t, as, "recent")
+	for _, window := range aggregationWindows {
+		agg, ok := recent[window]
+		if !ok {
+			t.Errorf("recent: no %s aggregation after cleanup", window)
+			continue
+		}
+		if agg.Count != 3 || agg.Avg != 20 || agg.Min != 10 || agg.Max != 30 {
+			t.Errorf("recent %s: got count %d avg %v min %v max %v", window, agg.Count, agg.Avg, agg.Min, agg.Max)
+		}
+	}
+
+	old := aggregationWindowsOf(t, as, "old")
+	if _, ok := old["5m"]; ok {
+		t.Error("old: created a 5m aggregation that has already expired")
+	}
+	for _, window := range []string{"1h", "1d", "1w"} {
+		if agg, ok := old[window]; !ok || agg.Count != 3 {
+			t.Errorf("old: %s aggregation %+v, want one of 3 samples", window, agg)
+		}
+	}
+}
+
+// TestCleanupRollsUpExpiringRawMetrics does the same with time-series
+// storage, where raw samples expire by age
+func TestCleanupRollsUpExpiringRawMetrics(t *testing.T) {
+	as, db := newTestAnalyticsService(t)
+	if err := database.MigrateTimeSeriesStorage(db); err != nil {
+		t.Fatal(err)
+	}
+	as.SetTimeSeriesStorage(true)
+
+	// Past the one year raw retention, but within the five years 1w
+	// aggregations are kept
+	timestamp := time.Now().Add(-400 * 24 * time.Hour)
+	for i, value := range []float64{1, 2, 3, 4} {
+		metric := &models.RawMetric{
+			MetricType: "system",
+			MetricName: "memory_usage",
+			Value:      value,
+			Timestamp:  timestamp.Add(time.Duration(i) * time.Second),
+		}
+		if err := db.Create(metric).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A sample that has not expired stays
+	if err := db.Create(&models.RawMetric{MetricType: "system", MetricName: "memory_usage", Value: 5, Timestamp: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := as.CleanupExpiredMetrics(); err != nil {
+		t.Fatal(err)
+	}
+
+	var remaining int64
+	db.Model(&models.RawMetric{}).Count(&remaining)
+	if remaining != 1 {
+		t.Errorf("%d raw metrics remain, want the 1 unexpired sample", remaining)
+	}
+
+	windows := aggregationWindowsOf(t, as, "memory_usage")
+	if agg, ok := windows["1w"]; !ok || agg.Count != 4 || agg.Sum != 10 {
+		t.Errorf("1w aggregation %+v, want one of the 4 expired samples", agg)
+	}
+	for _, window := range []string{"5m", "1h", "1d"} {
+		if _, ok := windows[window]; ok {
+			t.Errorf("created a %s aggregation that has already expired", window)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSettingNotFound     = errors.New("setting not found")
+	ErrInvalidSettingValue = errors.New("invalid setting value")
+	ErrSettingManaged      = errors.New("setting is managed by its own endpoint")
+)
+
+// Settings applied to running services when they change
+const (
+	CertificateRenewalDaysSettingID    = "certificate-renewal-days"
+	CertificateExpiryLeadDaysSettingID = "certificate-expiry-lead-days"
+	MetricsCollectionIntervalSettingID = "metrics-collection-interval"
+	LetsEncryptStagingSettingID        = "letsencrypt-staging"
+	LetsEncryptEmailSettingID          = "letsencrypt-email"
+)
+
+// managedSettings have dedicated endpoints that validate them and cannot be
+// written through the generic settings API
+var managedSettings = []string{DefaultCertificateSettingID, MetricCollectorsSettingID}
+
+// SettingReloadHook applies a setting to the running services. It is called
+// after the setting is saved and once on startup with the stored value.
+type SettingReloadHook func(setting *models.Setting) error
+
+// SettingsService handles system settings
+type SettingsService struct {
+	db        *gorm.DB
+	hookMutex sync.RWMutex
+	hooks     map[string][]SettingReloadHook
+}
+
+// NewSettingsService creates a new settings service
+func NewSettingsService() *SettingsService {
+	return &SettingsService{
+		db:    database.GetDB(),
+		hooks: make(map[string][]SettingReloadHook),
+	}
+}
+
+// OnChange registers a hook run whenever the setting key changes
+func (s *SettingsService) OnChange(key string, hook SettingReloadHook) {
+	s.hookMutex.Lock()
+	defer s.hookMutex.Unlock()
+	s.hooks[key] = append(s.hooks[key], hook)
+}
+
+// Reload runs every registered hook with the stored setting, so values saved
+// before a restart override the defaults services were started with
+func (s *SettingsService) Reload() error {
+	s.hookMutex.RLock()
+	keys := make([]string, 0, len(s.hooks))
+	for key := range s.hooks {
+		keys = append(keys, key)
+	}
+	s.hookMutex.RUnlock()
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var setting models.Setting
+		if err := s.db.Where("id = ?", key).First(&setting).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			return err
+		}
+		s.runHooks(&setting)
+	}
+	return nil
+}
+
+// Get returns a setting with any secret value hidden
+func (s *SettingsService) Get(key string) (*models.Setting, error) {
+	var setting models.Setting
+	if err := s.db.Where("id = ?", key).First(&setting).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSettingNotFound
+		}
+		return nil, err
+	}
+
+	setting.ClearSensitiveData()
+	return &setting, nil
+}
+
+// GetAll returns every setting with secret values hidden
+func (s *SettingsService) GetAll() ([]models.Setting, error) {
+	var settings []models.Setting
+	if err := s.db.Order("id ASC").Find(&settings).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range settings {
+		settings[i].ClearSensitiveData()
+	}
+	return settings, nil
+}
+
+// Set changes a single setting
+func (s *SettingsService) Set(actorID uint, key string, value interface{}, ipAddress, userAgent string) (*models.Setting, error) {
+	settings, err := s.SetMany(actorID, map[string]interface{}{key: value}, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return &settings[0], nil
+}
+
+// SetMany changes several settings in one transaction. Only existing settings
+// can be changed and each value must match the setting's type; nil clears a
+// value. Each change is audit logged, and reload hooks run once all are saved.
+func (s *SettingsService) SetMany(actorID uint, values map[string]interface{}, ipAddress, userAgent string) ([]models.Setting, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if containsString(managedSettings, key) {
+			return nil, fmt.Errorf("%w: %s", ErrSettingManaged, key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var changed []models.Setting
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			var setting models.Setting
+			if err := tx.Where("id = ?", key).First(&setting).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return fmt.Errorf("%w: %s", ErrSettingNotFound, key)
+				}
+				return err
+			}
+
+			value, err := normalizeSettingValue(setting.Type, values[key])
+			if err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrInvalidSettingValue, key, err)
+			}
+
+			before := setting
+			setting.Value = models.JSON{"value": value}
+			if err := tx.Save(&setting).Error; err != nil {
+				return err
+			}
+
+			// Audit secrets only as "changed"
+			beforeAudit, afterAudit := before, setting
+			if setting.IsSecret {
+				beforeAudit.Value = models.JSON{"value": maskedSecret}
+				afterAudit.Value = models.JSON{"value": maskedSecret}
+			}
+			auditLog := NewUpdateAuditLog(actorID, models.ObjectTypeSetting, 0,
+				fmt.Sprintf("Updated setting: %s", setting.Name), beforeAudit, afterAudit)
+			auditLog.IPAddress = ipAddress
+			auditLog.UserAgent = userAgent
+			if auditLog.Meta == nil {
+				auditLog.Meta = models.JSON{}
+			}
+			auditLog.Meta["setting_id"] = setting.ID
+			if setting.IsSecret {
+				auditLog.Meta["secret_changed"] = true
+			}
+			if err := tx.Create(auditLog).Error; err != nil {
+				return err
+			}
+
+			changed = append(changed, setting)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range changed {
+		s.runHooks(&changed[i])
+		changed[i].ClearSensitiveData()
+	}
+
+	logger.Info("Settings updated",
+		logger.Uint("actor_id", actorID),
+		logger.Int("count", len(changed)))
+
+	return changed, nil
+}
+
+// runHooks applies a saved setting. Failures are logged rather than returned:
+// the value is already stored and will be applied again on the next reload.
+func (s *SettingsService) runHooks(setting *models.Setting) {
+	s.hookMutex.RLock()
+	hooks := s.hooks[setting.ID]
+	s.hookMutex.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(setting); err != nil {
+			logger.Error("Failed to apply setting",
+				logger.String("setting_id", setting.ID),
+				logger.Err(err))
+		}
+	}
+}
+
+// normalizeSettingValue checks value against the setting type. Whole JSON
+// numbers are stored as ints, and int settings must be positive.
+func normalizeSettingValue(settingType models.SettingType, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch settingType {
+	case models.SettingTypeInt:
+		number, ok := value.(float64)
+		if !ok {
+			if integer, isInt := value.(int); isInt {
+				number, ok = float64(integer), true
+			}
+		}
+		if !ok || number != float64(int(number)) {
+			return nil, errors.New("expected a whole number")
+		}
+		if number < 1 {
+			return nil, errors.New("expected a positive number")
+		}
+		return int(number), nil
+	case models.SettingTypeBool:
+		if _, ok := value.(bool); !ok {
+			return nil, errors.New("expected true or false")
+		}
+	case models.SettingTypeJSON:
+	default:
+		if _, ok := value.(string); !ok {
+			return nil, errors.New("expected a string")
+		}
+	}
+	return value, nil
+}
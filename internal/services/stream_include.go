@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// ErrStreamIncludeMissing means nginx.conf does not include the streams
+// directory from a stream {} block, so stream configs would never load
+var ErrStreamIncludeMissing = errors.New("nginx.conf does not include the stream configs")
+
+var (
+	nginxCommentPattern     = regexp.MustCompile(`#[^\n]*`)
+	nginxStreamBlockPattern = regexp.MustCompile(`(^|[;{}\s])stream\s*\{`)
+	nginxIncludePattern     = regexp.MustCompile(`(?:^|[;{}\s])include\s+([^;]+);`)
+)
+
+// CheckStreamInclude verifies that the main nginx configuration includes the
+// stream config files from a stream {} block. Without an nginx service there
+// is no configuration to check.
+func (s *StreamService) CheckStreamInclude() error {
+	if s.nginxService == nil {
+		return nil
+	}
+
+	configPath := s.nginxService.configPath
+	guidance := fmt.Sprintf(`add "stream { include %s; }" at the top level of %s`,
+		filepath.Join(s.streamsPath, "*.conf"), configPath)
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("%w: %v; %s", ErrStreamIncludeMissing, err, guidance)
+	}
+
+	// A stream config file name for the include patterns to match
+	var probe models.Stream
+	probe.ID = 1
+	sample := s.streamConfigFile(&probe)
+
+	for _, block := range nginxBlocks(nginxCommentPattern.ReplaceAllString(string(content), ""), nginxStreamBlockPattern) {
+		for _, match := range nginxIncludePattern.FindAllStringSubmatch(block, -1) {
+			pattern := strings.Trim(strings.TrimSpace(match[1]), `"'`)
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(configPath), pattern)
+			}
+			if ok, _ := filepath.Match(pattern, sample); ok {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrStreamIncludeMissing, guidance)
+}
+
+// nginxBlocks returns the bodies of the blocks whose opening matches start
+func nginxBlocks(content string, start *regexp.Regexp) []string {
+	var blocks []string
+	for _, loc := range start.FindAllStringIndex(content, -1) {
+		depth := 1
+		for i := loc[1]; i < len(content); i++ {
+			switch content[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			if depth == 0 {
+				blocks = append(blocks, content[loc[1]:i])
+				break
+			}
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrStreamNotFound        = errors.New("stream not found")
+	ErrStreamPortInUse       = errors.New("incoming port is already used by another stream")
+	ErrInvalidStreamPort     = errors.New("ports must be between 1 and 65535")
+	ErrInvalidStreamProtocol = errors.New("protocol must be tcp, udp or both")
+	ErrInvalidStreamHost     = errors.New("forwarding host must be a hostname or IP address")
+)
+
+var validStreamProtocols = []string{models.StreamProtocolTCP, models.StreamProtocolUDP, models.StreamProtocolBoth}
+
+// StreamConfigError reports a stream configuration rejected by nginx -t
+type StreamConfigError struct {
+	Output string
+}
+
+func (e *StreamConfigError) Error() string {
+	return "invalid stream configuration: " + e.Output
+}
+
+// StreamService manages TCP/UDP stream proxies. Each enabled stream is written
+// as a server block to its own file in streamsPath, which nginx.conf must
+// include from its stream {} block; CheckStreamInclude verifies this before
+// a stream is enabled.
+type StreamService struct {
+	db           *gorm.DB
+	streamsPath  string
+	nginxService *NginxService
+	authService  *AuthService
+}
+
+// NewStreamService creates a new stream service writing into streamsPath
+func NewStreamService(streamsPath string, authService *AuthService) *StreamService {
+	return &StreamService{
+		db:          database.GetDB(),
+		streamsPath: streamsPath,
+		authService: authService,
+	}
+}
+
+// SetNginxService sets the nginx service used to reload nginx after stream changes
+func (s *StreamService) SetNginxService(nginxService *NginxService) {
+	s.nginxService = nginxService
+}
+
+// StreamRequest represents stream create/update request
+type StreamRequest struct {
+	IncomingPort   int    `json:"incoming_port" binding:"required"`
+	ForwardingHost string `json:"forwarding_host" binding:"required"`
+	ForwardingPort int    `json:"forwarding_port" binding:"required"`
+	Protocol       string `json:"protocol"` // tcp, udp or both; defaults to tcp
+	Enabled        bool   `json:"enabled"`
+}
+
+// ListStreams lists the user's streams with pagination
+func (s *StreamService) ListStreams(userID uint, offset, limit int) ([]models.Stream, int64, error) {
+	var streams []models.Stream
+	var total int64
+
+	// Viewers, operators and admins can see all streams
+	query := s.authService.ScopeToOwner(s.db.Model(&models.Stream{}), userID, models.PermissionStreamReadAll)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("incoming_port ASC").Offset(offset).Limit(limit).Find(&streams).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return streams, total, nil
+}
+
+// GetStream gets a single stream
+func (s *StreamService) GetStream(userID uint, id uint) (*models.Stream, error) {
+	return s.findStream(userID, id, models.PermissionStreamReadAll)
+}
+
+// findStream loads a stream of the user, or of anyone when the user holds
+// allPermission
+func (s *StreamService) findStream(userID uint, id uint, allPermission string) (*models.Stream, error) {
+	var stream models.Stream
+	if err := s.authService.ScopeToOwner(s.db, userID, allPermission).Where("id = ?", id).First(&stream).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrStreamNotFound
+		}
+		return nil, err
+	}
+	return &stream, nil
+}
+
+// CreateStream validates and saves a stream, then writes its configuration
+// when enabled
+func (s *StreamService) CreateStream(userID uint, req *StreamRequest) (*models.Stream, error) {
+	if err := s.validateStreamRequest(req, 0); err != nil {
+		return nil, err
+	}
+
+	stream := &models.Stream{UserID: userID}
+	applyStreamRequest(stream, req)
+	if err := s.validateStreamConfig(stream); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(stream).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.syncStreamConfig(stream); err != nil {
+		return stream, err
+	}
+	return stream, nil
+}
+
+// UpdateStream validates and saves changes to a stream, then rewrites or
+// removes its configuration
+func (s *StreamService) UpdateStream(userID uint, id uint, req *StreamRequest) (*models.Stream, error) {
+	stream, err := s.findStream(userID, id, models.PermissionStreamManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validateStreamRequest(req, stream.ID); err != nil {
+		return nil, err
+	}
+
+	applyStreamRequest(stream, req)
+	if err := s.validateStreamConfig(stream); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(stream).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.syncStreamConfig(stream); err != nil {
+		return stream, err
+	}
+	return stream, nil
+}
+
+// DeleteStream removes a stream and its configuration. The row is deleted
+// permanently so its incoming port can be reused.
+func (s *StreamService) DeleteStream(userID uint, id uint) error {
+	stream, err := s.findStream(userID, id, models.PermissionStreamManage)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(stream).Error; err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.streamConfigFile(stream)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.reloadNginx()
+}
+
+// BuildStreamConfig renders the stream {} server block for a stream
+func (s *StreamService) BuildStreamConfig(stream *models.Stream) string {
+	var config strings.Builder
+
+	config.WriteString(fmt.Sprintf("# Stream %d: %s port %d\n", stream.ID, stream.GetProtocol(), stream.IncomingPort))
+	config.WriteString("server {\n")
+	if stream.IsTCPEnabled() {
+		config.WriteString(fmt.Sprintf("    listen %d;\n", stream.IncomingPort))
+	}
+	if stream.IsUDPEnabled() {
+		config.WriteString(fmt.Sprintf("    listen %d udp;\n", stream.IncomingPort))
+	}
+	config.WriteString(fmt.Sprintf("    proxy_pass %s;\n",
+		net.JoinHostPort(stream.ForwardingHost, strconv.Itoa(stream.ForwardingPort))))
+	config.WriteString("}\n")
+
+	return config.String()
+}
+
+// validateStreamRequest checks ports, protocol and host, and that no other
+// stream listens on the incoming port
+func (s *StreamService) validateStreamRequest(req *StreamRequest, excludeID uint) error {
+	if req.Protocol == "" {
+		req.Protocol = models.StreamProtocolTCP
+	}
+	if !containsString(validStreamProtocols, req.Protocol) {
+		return ErrInvalidStreamProtocol
+	}
+	if req.IncomingPort < 1 || req.IncomingPort > 65535 || req.ForwardingPort < 1 || req.ForwardingPort > 65535 {
+		return ErrInvalidStreamPort
+	}
+	req.ForwardingHost = strings.TrimSpace(req.ForwardingHost)
+	if net.ParseIP(req.ForwardingHost) == nil {
+		// A hostname, without the port ValidateRedirectTarget would accept
+		if strings.Contains(req.ForwardingHost, ":") || ValidateRedirectTarget(req.ForwardingHost) != nil {
+			return ErrInvalidStreamHost
+		}
+	}
+
+	var count int64
+	query := s.db.Model(&models.Stream{}).Where("incoming_port = ?", req.IncomingPort)
+	if excludeID > 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrStreamPortInUse
+	}
+
+	return nil
+}
+
+// applyStreamRequest copies a validated request onto the model
+func applyStreamRequest(stream *models.Stream, req *StreamRequest) {
+	stream.IncomingPort = req.IncomingPort
+	stream.ForwardingHost = req.ForwardingHost
+	stream.ForwardingPort = req.ForwardingPort
+	stream.SetProtocol(req.Protocol)
+	stream.Enabled = req.Enabled
+}
+
+// validateStreamConfig runs nginx -t through the nginx service's runner on the
+// stream's server block wrapped in a minimal stream {} configuration. It is
+// skipped without an nginx service or when the nginx binary is not installed.
+// An enabled stream also needs nginx.conf to include the stream configs.
+func (s *StreamService) validateStreamConfig(stream *models.Stream) error {
+	if stream.Enabled {
+		if err := s.CheckStreamInclude(); err != nil {
+			return err
+		}
+	}
+
+	if s.nginxService == nil {
+		return nil
+	}
+
+	tempFile := filepath.Join(os.TempDir(), fmt.Sprintf("nginx_stream_test_%d.conf", time.Now().UnixNano()))
+	defer os.Remove(tempFile)
+
+	content := "events {}\nstream {\n" + s.BuildStreamConfig(stream) + "}\n"
+	if err := os.WriteFile(tempFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	if err := s.nginxService.nginxRunner.Test(tempFile); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil
+		}
+		return &StreamConfigError{Output: strings.TrimSpace(nginxOutput(err))}
+	}
+	return nil
+}
+
+// streamConfigFile returns the config file written for a stream
+func (s *StreamService) streamConfigFile(stream *models.Stream) string {
+	return filepath.Join(s.streamsPath, fmt.Sprintf("stream_%d.conf", stream.ID))
+}
+
+// syncStreamConfig writes an enabled stream's configuration or removes a
+// disabled one, then reloads nginx
+func (s *StreamService) syncStreamConfig(stream *models.Stream) error {
+	configFile := s.streamConfigFile(stream)
+	if stream.Enabled {
+		if err := os.MkdirAll(s.streamsPath, 0755); err != nil {
+			return fmt.Errorf("%w: %v", ErrNginxConfigGeneration, err)
+		}
+		if err := os.WriteFile(configFile, []byte(s.BuildStreamConfig(stream)), 0644); err != nil {
+			return fmt.Errorf("%w: %v", ErrNginxConfigGeneration, err)
+		}
+	} else if err := os.Remove(configFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return s.reloadNginx()
+}
+
+// reloadNginx reloads nginx through the nginx service when one is set
+func (s *StreamService) reloadNginx() error {
+	if s.nginxService == nil {
+		return nil
+	}
+	return s.nginxService.reloadNginx()
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// TemplateFunction documents a helper function available to configuration
+// templates
+type TemplateFunction struct {
+	Name        string `json:"name"`
+	Usage       string `json:"usage"`
+	Description string `json:"description"`
+}
+
+// templateFunctions are the only functions configuration templates can call
+// besides the text/template builtins. None of them touch the file system,
+// environment or network.
+var templateFunctions = []struct {
+	TemplateFunction
+	fn interface{}
+}{
+	{TemplateFunction{"default", `{{default "80" .port}}`, "Returns the value, or the fallback when the value is empty"}, templateDefault},
+	{TemplateFunction{"quote", `{{quote .path}}`, "Wraps the value in double quotes, escaping quotes and backslashes for nginx"}, templateQuote},
+	{TemplateFunction{"toUpper", `{{toUpper .name}}`, "Converts the value to upper case"}, templateToUpper},
+	{TemplateFunction{"toLower", `{{toLower .domain}}`, "Converts the value to lower case"}, templateToLower},
+	{TemplateFunction{"trim", `{{trim .domain}}`, "Removes leading and trailing whitespace"}, templateTrim},
+	{TemplateFunction{"join", `{{join " " .domains}}`, "Joins the items of a list with the separator"}, templateJoin},
+	{TemplateFunction{"nginxHTML", `{{nginxHTML .message}}`, "Escapes text as HTML for a double-quoted nginx string"}, nginxHTML},
+}
+
+// configTemplateFuncs are the helper functions available to configuration templates
+var configTemplateFuncs = func() template.FuncMap {
+	funcs := make(template.FuncMap, len(templateFunctions))
+	for _, function := range templateFunctions {
+		funcs[function.Name] = function.fn
+	}
+	return funcs
+}()
+
+// GetTemplateFunctions documents the helper functions available to templates
+func GetTemplateFunctions() []TemplateFunction {
+	functions := make([]TemplateFunction, len(templateFunctions))
+	for i, function := range templateFunctions {
+		functions[i] = function.TemplateFunction
+	}
+	return functions
+}
+
+// parseConfigTemplate parses a configuration template with the curated
+// functions. Referencing a variable that is not set fails execution instead
+// of rendering "<no value>".
+func parseConfigTemplate(name, content string) (*template.Template, error) {
+	return template.New(name).Funcs(configTemplateFuncs).Option("missingkey=error").Parse(content)
+}
+
+// missingKeyPattern matches the execution error for an unset variable, such
+// as `template: config:3:15: executing "config" at <.domain>: map has no entry for key "domain"`
+var missingKeyPattern = regexp.MustCompile(`^template: [^:]*:(\d+):\d+: .*map has no entry for key "([^"]+)"`)
+
+// describeTemplateExecError turns a template execution error into a message
+// naming the unset variable and its line when a variable is missing
+func describeTemplateExecError(err error) string {
+	if match := missingKeyPattern.FindStringSubmatch(err.Error()); match != nil {
+		return fmt.Sprintf("variable %q is not set (line %s)", match[2], match[1])
+	}
+	return err.Error()
+}
+
+// templateDefault returns value unless it is empty, in which case fallback
+func templateDefault(fallback, value interface{}) interface{} {
+	if value == nil {
+		return fallback
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return fallback
+		}
+	}
+	return value
+}
+
+// templateQuote wraps value in double quotes for an nginx directive
+func templateQuote(value interface{}) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(fmt.Sprint(value)) + `"`
+}
+
+func templateToUpper(value interface{}) string {
+	return strings.ToUpper(fmt.Sprint(value))
+}
+
+func templateToLower(value interface{}) string {
+	return strings.ToLower(fmt.Sprint(value))
+}
+
+func templateTrim(value interface{}) string {
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// templateJoin joins the items of a list, or formats a single value
+func templateJoin(separator string, list interface{}) string {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Sprint(list)
+	}
+
+	items := make([]string, v.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(items, separator)
+}
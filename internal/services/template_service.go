@@ -2,6 +2,8 @@ package services
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -14,8 +16,9 @@ import (
 
 // TemplateService handles configuration template management
 type TemplateService struct {
-	db          *gorm.DB
-	authService *AuthService
+	db            *gorm.DB
+	authService   *AuthService
+	configService *ConfigService
 }
 
 // NewTemplateService creates a new template service instance
@@ -26,6 +29,11 @@ func NewTemplateService(authService *AuthService) *TemplateService {
 	}
 }
 
+// SetConfigService sets the config service used to validate template previews
+func (s *TemplateService) SetConfigService(configService *ConfigService) {
+	s.configService = configService
+}
+
 // TemplateRequest represents template create/update request
 type TemplateRequest struct {
 	Name        string                  `json:"name" binding:"required"`
@@ -34,6 +42,7 @@ type TemplateRequest struct {
 	Content     string                  `json:"content" binding:"required"`
 	Variables   map[string]interface{}  `json:"variables"`
 	IsPublic    bool                    `json:"is_public"`
+	Comment     string                  `json:"comment"` // recorded with the template version
 }
 
 // TemplateListResponse represents paginated template list
@@ -42,11 +51,15 @@ type TemplateListResponse struct {
 	Total     int64                   `json:"total"`
 	Page      int                     `json:"page"`
 	Limit     int                     `json:"limit"`
+	Search    string                  `json:"search,omitempty"`
+	Sort      ListSort                `json:"sort"`
 }
 
-// TemplateRenderRequest represents template render request
+// TemplateRenderRequest represents template render request. With Strict,
+// variables the template does not declare are rejected.
 type TemplateRenderRequest struct {
 	Variables map[string]interface{} `json:"variables" binding:"required"`
+	Strict    bool                   `json:"strict"`
 }
 
 // TemplateRenderResponse represents template render response
@@ -56,8 +69,24 @@ type TemplateRenderResponse struct {
 	Errors  []string `json:"errors,omitempty"`
 }
 
+// TemplatePreviewRequest represents template preview request. Type selects
+// the context the rendered configuration is validated in and defaults to
+// custom, which places it by its top-level directives.
+type TemplatePreviewRequest struct {
+	Variables map[string]interface{} `json:"variables"`
+	Strict    bool                   `json:"strict"`
+	Type      models.ConfigType      `json:"type"`
+}
+
+// TemplatePreviewResponse represents template preview response. Validation is
+// only set when the template rendered.
+type TemplatePreviewResponse struct {
+	TemplateRenderResponse
+	Validation *ValidationResult `json:"validation,omitempty"`
+}
+
 // CreateTemplate creates a new configuration template
-func (s *TemplateService) CreateTemplate(userID uint, req *TemplateRequest) (*models.ConfigTemplate, error) {
+func (s *TemplateService) CreateTemplate(userID uint, req *TemplateRequest, audit AuditContext) (*models.ConfigTemplate, error) {
 	// Validate category
 	if !req.Category.IsValid() {
 		return nil, fmt.Errorf("invalid template category")
@@ -91,20 +120,31 @@ func (s *TemplateService) CreateTemplate(userID uint, req *TemplateRequest) (*mo
 		UserID:      userID,
 	}
 
-	// Save to database
-	if err := s.db.Create(tmpl).Error; err != nil {
+	// A soft-deleted template does not keep its name
+	if err := releaseDeletedName(s.db, &models.ConfigTemplate{}, userID, req.Name); err != nil {
+		return nil, err
+	}
+
+	// Save to database with its initial version
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tmpl).Error; err != nil {
+			return err
+		}
+		return createTemplateVersion(tx, tmpl, "Initial version", userID)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	// Log audit event
-	s.logAuditEvent(userID, models.ObjectTypeConfigTemplate, tmpl.ID, models.ActionCreated,
+	s.logAuditEvent(audit, userID, models.ObjectTypeConfigTemplate, tmpl.ID, models.ActionCreated,
 		fmt.Sprintf("Created template: %s", tmpl.Name))
 
 	return tmpl, nil
 }
 
 // UpdateTemplate updates an existing template
-func (s *TemplateService) UpdateTemplate(userID uint, id uint, req *TemplateRequest) (*models.ConfigTemplate, error) {
+func (s *TemplateService) UpdateTemplate(userID uint, id uint, req *TemplateRequest, audit AuditContext) (*models.ConfigTemplate, error) {
 	// Find existing template
 	var tmpl models.ConfigTemplate
 	if err := s.db.Where("id = ?", id).First(&tmpl).Error; err != nil {
@@ -115,17 +155,8 @@ func (s *TemplateService) UpdateTemplate(userID uint, id uint, req *TemplateRequ
 	}
 
 	// Check permissions
-	if tmpl.UserID != userID && !tmpl.IsBuiltIn {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return nil, errors.ErrPermissionDenied
-		}
-	}
-
-	// Built-in templates can only be modified by admins
-	if tmpl.IsBuiltIn {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return nil, fmt.Errorf("built-in templates can only be modified by administrators")
-		}
+	if err := s.requireTemplateWrite(userID, &tmpl); err != nil {
+		return nil, err
 	}
 
 	// Validate category
@@ -138,21 +169,49 @@ func (s *TemplateService) UpdateTemplate(userID uint, id uint, req *TemplateRequ
 		return nil, fmt.Errorf("template validation failed: %w", err)
 	}
 
-	// Update template
-	tmpl.Name = req.Name
-	tmpl.Description = req.Description
-	tmpl.Category = req.Category
-	tmpl.Content = req.Content
-	tmpl.Variables = models.JSON(req.Variables)
-	tmpl.IsPublic = req.IsPublic
+	// Check for duplicate template name for the owner
+	if req.Name != tmpl.Name {
+		var count int64
+		if err := s.db.Model(&models.ConfigTemplate{}).
+			Where("name = ? AND user_id = ? AND id != ?", req.Name, tmpl.UserID, tmpl.ID).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, errors.ErrTemplateDuplicate
+		}
+		if err := releaseDeletedName(s.db, &models.ConfigTemplate{}, tmpl.UserID, req.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Templates created before versioning get their current content as
+		// the first version, so the update can be rolled back
+		if err := ensureTemplateHistory(tx, &tmpl); err != nil {
+			return err
+		}
+
+		// Update template
+		tmpl.Name = req.Name
+		tmpl.Description = req.Description
+		tmpl.Category = req.Category
+		tmpl.Content = req.Content
+		tmpl.Variables = models.JSON(req.Variables)
+		tmpl.IsPublic = req.IsPublic
 
-	// Save to database
-	if err := s.db.Save(&tmpl).Error; err != nil {
+		// Save to database
+		if err := tx.Save(&tmpl).Error; err != nil {
+			return err
+		}
+		return createTemplateVersion(tx, &tmpl, req.Comment, userID)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	// Log audit event
-	s.logAuditEvent(userID, models.ObjectTypeConfigTemplate, tmpl.ID, models.ActionUpdated,
+	s.logAuditEvent(audit, userID, models.ObjectTypeConfigTemplate, tmpl.ID, models.ActionUpdated,
 		fmt.Sprintf("Updated template: %s", tmpl.Name))
 
 	return &tmpl, nil
@@ -171,8 +230,8 @@ func (s *TemplateService) GetTemplate(userID uint, id uint) (*models.ConfigTempl
 	}
 
 	// Check permissions
-	if tmpl.UserID != userID && !tmpl.IsPublic && !tmpl.IsBuiltIn {
-		if err := s.authService.RequireAdmin(userID); err != nil {
+	if !tmpl.IsPublic && !tmpl.IsBuiltIn {
+		if err := s.authService.RequireOwnerOrPermission(userID, tmpl.UserID, models.PermissionTemplateRead, models.PermissionTemplateReadAll); err != nil {
 			return nil, errors.ErrPermissionDenied
 		}
 	}
@@ -180,17 +239,81 @@ func (s *TemplateService) GetTemplate(userID uint, id uint) (*models.ConfigTempl
 	return &tmpl, nil
 }
 
+// CloneTemplate copies a template the user can read into a private template
+// of their own, named after the original with a "(copy)" suffix
+func (s *TemplateService) CloneTemplate(userID uint, id uint, audit AuditContext) (*models.ConfigTemplate, error) {
+	source, err := s.GetTemplate(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := s.cloneName(userID, source.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &models.ConfigTemplate{
+		Name:        name,
+		Description: source.Description,
+		Category:    source.Category,
+		Content:     source.Content,
+		Variables:   source.Variables.Clone(),
+		IsBuiltIn:   false,
+		IsPublic:    false,
+		UsageCount:  0,
+		UserID:      userID,
+	}
+
+	// A soft-deleted template does not keep its name
+	if err := releaseDeletedName(s.db, &models.ConfigTemplate{}, userID, name); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(tmpl).Error; err != nil {
+			return err
+		}
+		return createTemplateVersion(tx, tmpl, fmt.Sprintf("Cloned from template %s", source.Name), userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAuditEvent(audit, userID, models.ObjectTypeConfigTemplate, tmpl.ID, models.ActionCreated,
+		fmt.Sprintf("Cloned template: %s from %s", tmpl.Name, source.Name))
+
+	return tmpl, nil
+}
+
+// cloneName returns the first of "name (copy)", "name (copy 2)", ... that the
+// user has no template named
+func (s *TemplateService) cloneName(userID uint, name string) (string, error) {
+	for n := 1; ; n++ {
+		candidate := name + " (copy)"
+		if n > 1 {
+			candidate = fmt.Sprintf("%s (copy %d)", name, n)
+		}
+
+		var count int64
+		if err := s.db.Model(&models.ConfigTemplate{}).
+			Where("name = ? AND user_id = ?", candidate, userID).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+}
+
 // ListTemplates retrieves templates with pagination and filtering
-func (s *TemplateService) ListTemplates(userID uint, page, limit int, category string, includePublic bool) (*TemplateListResponse, error) {
+func (s *TemplateService) ListTemplates(userID uint, page, limit int, category string, includePublic bool, search string, listSort ListSort) (*TemplateListResponse, error) {
 	offset := (page - 1) * limit
 
 	query := s.db.Model(&models.ConfigTemplate{}).Preload("User")
 
-	// Check if user is admin
-	isAdmin := s.authService.IsAdmin(userID)
-
 	// Apply access filters
-	if !isAdmin {
+	if !s.authService.HasPermission(userID, models.PermissionTemplateReadAll) {
 		if includePublic {
 			query = query.Where("user_id = ? OR is_public = true OR is_built_in = true", userID)
 		} else {
@@ -202,6 +325,7 @@ func (s *TemplateService) ListTemplates(userID uint, page, limit int, category s
 	if category != "" {
 		query = query.Where("category = ?", category)
 	}
+	query = searchNameDescription(query, search)
 
 	var templates []models.ConfigTemplate
 	var total int64
@@ -212,7 +336,7 @@ func (s *TemplateService) ListTemplates(userID uint, page, limit int, category s
 	}
 
 	// Get templates with pagination
-	if err := query.Offset(offset).Limit(limit).Find(&templates).Error; err != nil {
+	if err := listSort.apply(query).Offset(offset).Limit(limit).Find(&templates).Error; err != nil {
 		return nil, err
 	}
 
@@ -221,11 +345,13 @@ func (s *TemplateService) ListTemplates(userID uint, page, limit int, category s
 		Total:     total,
 		Page:      page,
 		Limit:     limit,
+		Search:    search,
+		Sort:      listSort,
 	}, nil
 }
 
 // DeleteTemplate deletes a template
-func (s *TemplateService) DeleteTemplate(userID uint, id uint) error {
+func (s *TemplateService) DeleteTemplate(userID uint, id uint, audit AuditContext) error {
 	// Find template
 	var tmpl models.ConfigTemplate
 	if err := s.db.Where("id = ?", id).First(&tmpl).Error; err != nil {
@@ -236,10 +362,8 @@ func (s *TemplateService) DeleteTemplate(userID uint, id uint) error {
 	}
 
 	// Check permissions
-	if tmpl.UserID != userID {
-		if err := s.authService.RequireAdmin(userID); err != nil {
-			return errors.ErrPermissionDenied
-		}
+	if err := s.authService.RequireOwnerOrPermission(userID, tmpl.UserID, models.PermissionTemplateDelete, models.PermissionTemplateManage); err != nil {
+		return errors.ErrPermissionDenied
 	}
 
 	// Built-in templates cannot be deleted
@@ -262,7 +386,7 @@ func (s *TemplateService) DeleteTemplate(userID uint, id uint) error {
 	}
 
 	// Log audit event
-	s.logAuditEvent(userID, models.ObjectTypeConfigTemplate, tmpl.ID, models.ActionDeleted,
+	s.logAuditEvent(audit, userID, models.ObjectTypeConfigTemplate, tmpl.ID, models.ActionDeleted,
 		fmt.Sprintf("Deleted template: %s", tmpl.Name))
 
 	return nil
@@ -276,34 +400,94 @@ func (s *TemplateService) RenderTemplate(userID uint, id uint, req *TemplateRend
 		return nil, err
 	}
 
+	result := renderTemplate(tmpl, req.Variables, req.Strict)
+	if !result.IsValid {
+		return result, nil
+	}
+
+	// Increment usage count
+	s.incrementUsageCount(id)
+
+	return result, nil
+}
+
+// PreviewTemplate renders a template and runs the rendered configuration
+// through nginx validation, catching templates that render but produce an
+// invalid configuration. Previews do not count as template usage.
+func (s *TemplateService) PreviewTemplate(userID uint, id uint, req *TemplatePreviewRequest) (*TemplatePreviewResponse, error) {
+	if s.configService == nil {
+		return nil, fmt.Errorf("configuration validation is not available")
+	}
+
+	tmpl, err := s.GetTemplate(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &TemplatePreviewResponse{TemplateRenderResponse: *renderTemplate(tmpl, req.Variables, req.Strict)}
+	if !preview.IsValid {
+		return preview, nil
+	}
+
+	configType := req.Type
+	if configType == "" {
+		configType = models.ConfigTypeCustom
+	}
+	validation, err := s.configService.validateConfig(configType, preview.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Validation = validation
+	preview.IsValid = validation.IsValid
+	preview.Errors = append(preview.Errors, validation.Errors...)
+	return preview, nil
+}
+
+// renderTemplate checks vars against the template's schema and renders it.
+// Problems are reported in the response rather than as an error.
+func renderTemplate(tmpl *models.ConfigTemplate, vars map[string]interface{}, strict bool) *TemplateRenderResponse {
 	// Parse template
-	t, err := template.New("template").Parse(tmpl.Content)
+	t, err := parseConfigTemplate("template", tmpl.Content)
 	if err != nil {
 		return &TemplateRenderResponse{
 			Content: "",
 			IsValid: false,
 			Errors:  []string{fmt.Sprintf("Template parse error: %s", err.Error())},
-		}, nil
+		}
+	}
+
+	// Check variables against the template's schema
+	if problems := validateTemplateVariables(tmpl.Variables, vars, strict); len(problems) > 0 {
+		return &TemplateRenderResponse{
+			Content: "",
+			IsValid: false,
+			Errors:  problems,
+		}
 	}
 
 	// Render template with variables
 	var result strings.Builder
-	if err := t.Execute(&result, req.Variables); err != nil {
+	if err := t.Execute(&result, applyTemplateDefaults(tmpl.Variables, vars)); err != nil {
 		return &TemplateRenderResponse{
 			Content: "",
 			IsValid: false,
-			Errors:  []string{fmt.Sprintf("Template execution error: %s", err.Error())},
-		}, nil
+			Errors:  []string{fmt.Sprintf("Template execution error: %s", describeTemplateExecError(err))},
+		}
 	}
 
-	// Increment usage count
-	s.incrementUsageCount(id)
-
 	return &TemplateRenderResponse{
 		Content: result.String(),
 		IsValid: true,
 		Errors:  []string{},
-	}, nil
+	}
+}
+
+// TemplateCategoriesResponse lists the template categories and the helper
+// functions templates can use
+type TemplateCategoriesResponse struct {
+	Categories []string           `json:"categories"`
+	Functions  []TemplateFunction `json:"functions"`
 }
 
 // GetCategories returns all available template categories
@@ -318,40 +502,198 @@ func (s *TemplateService) GetCategories() []string {
 	}
 }
 
-// CreateBuiltInTemplates creates default built-in templates
-func (s *TemplateService) CreateBuiltInTemplates() error {
+// BuiltInTemplatesResult reports what initializing built-in templates did
+type BuiltInTemplatesResult struct {
+	Created int      `json:"created"`
+	Skipped int      `json:"skipped"`          // already present
+	Failed  []string `json:"failed,omitempty"` // names of templates that could not be created
+}
+
+// InitializeBuiltInTemplates creates the built-in templates on behalf of
+// userID, who must be an administrator
+func (s *TemplateService) InitializeBuiltInTemplates(userID uint) (*BuiltInTemplatesResult, error) {
+	if err := s.authService.RequireAdmin(userID); err != nil {
+		return nil, errors.ErrPermissionDenied
+	}
+
+	result, err := s.CreateBuiltInTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Built-in templates initialized",
+		logger.Int("created", result.Created),
+		logger.Int("skipped", result.Skipped),
+		logger.Int("failed", len(result.Failed)),
+		logger.Uint("user_id", userID))
+
+	return result, nil
+}
+
+// CreateBuiltInTemplates creates the default built-in templates that do not
+// exist yet, so it is safe to run on every start
+func (s *TemplateService) CreateBuiltInTemplates() (*BuiltInTemplatesResult, error) {
 	templates := s.getBuiltInTemplates()
+	result := &BuiltInTemplatesResult{Failed: []string{}}
 
 	for _, tmpl := range templates {
 		// Check if template already exists
 		var existing models.ConfigTemplate
 		err := s.db.Where("name = ? AND is_built_in = true", tmpl.Name).First(&existing).Error
 		if err == nil {
-			// Template already exists, skip
+			result.Skipped++
 			continue
 		}
 		if err != gorm.ErrRecordNotFound {
-			return err
+			return nil, err
 		}
 
-		// Create built-in template
-		if err := s.db.Create(&tmpl).Error; err != nil {
+		// Create built-in template with its first version
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&tmpl).Error; err != nil {
+				return err
+			}
+			return createTemplateVersion(tx, &tmpl, "Initial version", tmpl.UserID)
+		})
+		if err != nil {
 			logger.Error("Failed to create built-in template",
 				logger.String("name", tmpl.Name),
 				logger.Err(err))
+			result.Failed = append(result.Failed, tmpl.Name)
+			continue
 		}
+		result.Created++
 	}
 
-	return nil
+	return result, nil
 }
 
 // validateTemplate validates template syntax
 func (s *TemplateService) validateTemplate(content string) error {
 	// Parse template to check syntax
-	_, err := template.New("test").Parse(content)
+	_, err := parseConfigTemplate("test", content)
 	return err
 }
 
+// nginxHTML escapes text for use as HTML inside a double-quoted nginx string.
+// Besides HTML escaping, "$" and "\" are written as character references so
+// nginx neither interpolates variables nor treats them as escapes.
+func nginxHTML(value interface{}) string {
+	escaped := template.HTMLEscapeString(fmt.Sprint(value))
+	return strings.NewReplacer("$", "&#36;", `\`, "&#92;").Replace(escaped)
+}
+
+// applyTemplateDefaults fills variables missing from vars with the "default"
+// value from the template's variable schema. Declared variables without a
+// default are set empty, so optional variables can be tested with if while
+// undeclared ones still fail under missingkey=error.
+func applyTemplateDefaults(schema models.JSON, vars map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(vars))
+	for name, definition := range schema {
+		result[name] = ""
+		if spec, ok := definition.(map[string]interface{}); ok {
+			if value, hasDefault := spec["default"]; hasDefault {
+				result[name] = value
+			}
+		}
+	}
+	for name, value := range vars {
+		result[name] = value
+	}
+	return result
+}
+
+// validateTemplateVariables checks vars against the template's variable
+// schema. Required variables without a default must be set and not empty, and
+// values must match the declared type and, when listed, one of the options.
+// With strict, variables missing from the schema are reported too.
+func validateTemplateVariables(schema models.JSON, vars map[string]interface{}, strict bool) []string {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		spec, ok := schema[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		value, provided := vars[name]
+		if !provided || value == nil || value == "" {
+			_, hasDefault := spec["default"]
+			if required, _ := spec["required"].(bool); required && !hasDefault {
+				problems = append(problems, fmt.Sprintf("Variable %q is required", name))
+			}
+			continue
+		}
+
+		varType, _ := spec["type"].(string)
+		if !templateValueHasType(value, varType) {
+			problems = append(problems, fmt.Sprintf("Variable %q must be of type %s", name, varType))
+			continue
+		}
+
+		if options := reflect.ValueOf(spec["options"]); options.Kind() == reflect.Slice && options.Len() > 0 {
+			allowed := make([]string, options.Len())
+			for i := range allowed {
+				allowed[i] = fmt.Sprint(options.Index(i).Interface())
+			}
+			if !containsString(allowed, fmt.Sprint(value)) {
+				problems = append(problems, fmt.Sprintf("Variable %q must be one of: %s", name, strings.Join(allowed, ", ")))
+			}
+		}
+	}
+
+	if strict {
+		var unknown []string
+		for name := range vars {
+			if _, declared := schema[name]; !declared {
+				unknown = append(unknown, name)
+			}
+		}
+		sort.Strings(unknown)
+		for _, name := range unknown {
+			problems = append(problems, fmt.Sprintf("Variable %q is not declared by the template", name))
+		}
+	}
+
+	return problems
+}
+
+// templateValueHasType reports whether value matches a schema type. Values
+// decoded from JSON are float64 numbers, []interface{} arrays and
+// map[string]interface{} objects; unknown types accept any value.
+func templateValueHasType(value interface{}, varType string) bool {
+	kind := reflect.ValueOf(value).Kind()
+	switch varType {
+	case "string":
+		return kind == reflect.String
+	case "number", "integer":
+		switch kind {
+		case reflect.Float32, reflect.Float64:
+			if varType == "integer" {
+				number := reflect.ValueOf(value).Float()
+				return number == float64(int64(number))
+			}
+			return true
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		}
+		return false
+	case "boolean":
+		return kind == reflect.Bool
+	case "array":
+		return kind == reflect.Slice || kind == reflect.Array
+	case "object":
+		return kind == reflect.Map
+	}
+	return true
+}
+
 // incrementUsageCount increments the usage count for a template
 func (s *TemplateService) incrementUsageCount(templateID uint) {
 	if err := s.db.Model(&models.ConfigTemplate{}).Where("id = ?", templateID).
@@ -591,6 +933,72 @@ server {
 			UserID:    1,
 		},
 
+		// Maintenance Page Template
+		{
+			Name:        "Maintenance Page",
+			Description: "Standalone maintenance or coming-soon holding page served for an entire domain",
+			Category:    models.CategoryCustom,
+			Content: `server {
+    listen 80;
+    listen [::]:80;
+    server_name {{.domain}};
+
+    default_type text/html;
+    charset utf-8;
+    add_header Cache-Control "no-store" always;
+    {{if and (eq (print .status_code) "503") .retry_after}}add_header Retry-After {{.retry_after}} always;{{end}}
+
+    location = /favicon.ico {
+        access_log off;
+        return 204;
+    }
+
+    location / {
+        return {{.status_code}} "<!DOCTYPE html><html lang=\"en\"><head><meta charset=\"utf-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1\"><title>{{nginxHTML .title}}</title><style>body{margin:0;min-height:100vh;display:flex;align-items:center;justify-content:center;font-family:system-ui,sans-serif;background:#f5f5f5;color:#333}main{max-width:36rem;padding:2rem;text-align:center}h1{font-size:2rem;margin:0 0 1rem}p{font-size:1.1rem;line-height:1.5}</style></head><body><main><h1>{{nginxHTML .title}}</h1><p>{{nginxHTML .message}}</p></main></body></html>";
+    }
+}`,
+			Variables: models.JSON{
+				"domain": map[string]interface{}{
+					"type":        "string",
+					"description": "Domain name(s) that should show the holding page",
+					"required":    true,
+					"example":     "example.com www.example.com",
+				},
+				"title": map[string]interface{}{
+					"type":        "string",
+					"description": "Page title and heading",
+					"required":    false,
+					"default":     "Down for maintenance",
+					"example":     "Coming soon",
+				},
+				"message": map[string]interface{}{
+					"type":        "string",
+					"description": "Message shown below the heading",
+					"required":    false,
+					"default":     "We're performing scheduled maintenance and will be back shortly.",
+					"example":     "Our new site is launching next week.",
+				},
+				"status_code": map[string]interface{}{
+					"type":        "number",
+					"description": "HTTP status code: 503 for maintenance, 200 for a coming-soon page",
+					"required":    false,
+					"default":     503,
+					"example":     503,
+					"options":     []int{503, 200},
+				},
+				"retry_after": map[string]interface{}{
+					"type":        "number",
+					"description": "Seconds sent in the Retry-After header with 503 responses; omit to leave it out",
+					"required":    false,
+					"default":     3600,
+					"example":     3600,
+				},
+			},
+			IsBuiltIn: true,
+			IsPublic:  true,
+			UserID:    1,
+		},
+
 		// WebSocket Proxy Template
 		{
 			Name:        "WebSocket Proxy",
@@ -661,15 +1069,15 @@ server {
 }
 
 // logAuditEvent logs an audit event
-func (s *TemplateService) logAuditEvent(userID uint, objectType models.ObjectType, objectID uint, action models.AuditAction, description string) {
+func (s *TemplateService) logAuditEvent(audit AuditContext, userID uint, objectType models.ObjectType, objectID uint, action models.AuditAction, description string) {
 	auditLog := &models.AuditLog{
 		UserID:      userID,
 		Action:      action,
 		ObjectType:  objectType,
 		ObjectID:    objectID,
 		Description: description,
-		IPAddress:   "",
-		UserAgent:   "",
+		IPAddress:   audit.IPAddress,
+		UserAgent:   audit.UserAgent,
 	}
 
 	if err := s.db.Create(auditLog).Error; err != nil {
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/errors"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// ListTemplateVersions returns the versions of a template, newest first.
+// Anyone who can read the template can read its history.
+func (s *TemplateService) ListTemplateVersions(userID uint, id uint) ([]models.TemplateVersion, error) {
+	if _, err := s.GetTemplate(userID, id); err != nil {
+		return nil, err
+	}
+
+	var versions []models.TemplateVersion
+	if err := s.db.Preload("CreatedByUser").
+		Where("template_id = ?", id).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// RollbackTemplate restores the content and variables of a previous version.
+// The rollback is recorded as a new version, so it can itself be undone.
+func (s *TemplateService) RollbackTemplate(userID uint, id uint, version int, comment string, audit AuditContext) (*models.ConfigTemplate, error) {
+	var tmpl models.ConfigTemplate
+	if err := s.db.Where("id = ?", id).First(&tmpl).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.ErrTemplateNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.requireTemplateWrite(userID, &tmpl); err != nil {
+		return nil, err
+	}
+
+	if comment == "" {
+		comment = fmt.Sprintf("Rolled back to version %d", version)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureTemplateHistory(tx, &tmpl); err != nil {
+			return err
+		}
+
+		var target models.TemplateVersion
+		if err := tx.Where("template_id = ? AND version = ?", id, version).First(&target).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.ErrTemplateVersionNotFound
+			}
+			return err
+		}
+
+		tmpl.Content = target.Content
+		tmpl.Variables = target.Variables
+		if err := tx.Save(&tmpl).Error; err != nil {
+			return err
+		}
+		return createTemplateVersion(tx, &tmpl, comment, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAuditEvent(audit, userID, models.ObjectTypeConfigTemplate, tmpl.ID, models.ActionUpdated,
+		fmt.Sprintf("Rolled back template %s to version %d", tmpl.Name, version))
+
+	logger.Info("Template rolled back",
+		logger.Uint("template_id", tmpl.ID),
+		logger.Int("version", version),
+		logger.Uint("user_id", userID))
+
+	return &tmpl, nil
+}
+
+// requireTemplateWrite checks that userID may change tmpl. Built-in templates
+// can only be changed with template:manage.
+func (s *TemplateService) requireTemplateWrite(userID uint, tmpl *models.ConfigTemplate) error {
+	if tmpl.IsBuiltIn {
+		if err := s.authService.RequirePermission(userID, models.PermissionTemplateManage); err != nil {
+			return fmt.Errorf("built-in templates can only be modified by administrators and operators")
+		}
+		return nil
+	}
+
+	if err := s.authService.RequireOwnerOrPermission(userID, tmpl.UserID, models.PermissionTemplateWrite, models.PermissionTemplateManage); err != nil {
+		return errors.ErrPermissionDenied
+	}
+	return nil
+}
+
+// createTemplateVersion records the template's current content and variables
+// as its next version
+func createTemplateVersion(tx *gorm.DB, tmpl *models.ConfigTemplate, comment string, userID uint) error {
+	var latest int
+	if err := tx.Model(&models.TemplateVersion{}).
+		Where("template_id = ?", tmpl.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.TemplateVersion{
+		TemplateID: tmpl.ID,
+		Version:    latest + 1,
+		Content:    tmpl.Content,
+		Variables:  tmpl.Variables,
+		Comment:    comment,
+		CreatedBy:  userID,
+	}).Error
+}
+
+// ensureTemplateHistory records the current content of a template without
+// versions, such as a built-in or one created before versioning, as its
+// first version attributed to the template's owner
+func ensureTemplateHistory(tx *gorm.DB, tmpl *models.ConfigTemplate) error {
+	var count int64
+	if err := tx.Model(&models.TemplateVersion{}).Where("template_id = ?", tmpl.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return createTemplateVersion(tx, tmpl, "Initial version", tmpl.UserID)
+}
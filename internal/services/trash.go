@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// Deleted proxy hosts and certificates stay soft-deleted in their tables and
+// form the trash. Owners can list and restore their own deleted rows; purging
+// removes a row for good and is admin only.
+
+// ListDeletedProxyHosts lists the user's soft-deleted proxy hosts, most
+// recently deleted first
+func (s *NginxService) ListDeletedProxyHosts(userID uint, offset, limit int) ([]models.ProxyHost, int64, error) {
+	query := s.db.Unscoped().Model(&models.ProxyHost{}).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var proxyHosts []models.ProxyHost
+	if err := query.Order("deleted_at DESC").Offset(offset).Limit(limit).Find(&proxyHosts).Error; err != nil {
+		return nil, 0, err
+	}
+	return proxyHosts, total, nil
+}
+
+// RestoreProxyHost brings a soft-deleted proxy host back. Its domains were
+// freed when it was deleted, so restoring fails with ErrDomainInUse when
+// another host has claimed one of them since. A certificate or access list
+// deleted in the meantime is dropped from the host, which could not deploy
+// with it. The caller redeploys the host.
+func (s *NginxService) RestoreProxyHost(userID uint, id uint, audit AuditContext) (*models.ProxyHost, error) {
+	proxyHost, err := s.findDeletedProxyHost(s.db.Where("user_id = ?", userID), id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		certificateID, err := liveReference(tx, &models.Certificate{}, proxyHost.CertificateID)
+		if err != nil {
+			return err
+		}
+		accessListID, err := liveReference(tx, &models.AccessList{}, proxyHost.AccessListID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&models.ProxyHost{}).Where("id = ?", proxyHost.ID).
+			UpdateColumns(map[string]interface{}{
+				"deleted_at":     nil,
+				"certificate_id": certificateID,
+				"access_list_id": accessListID,
+			}).Error; err != nil {
+			return err
+		}
+		proxyHost.CertificateID, proxyHost.AccessListID = certificateID, accessListID
+		return models.SyncHostDomains(tx, proxyHost.TableName(), proxyHost.ID, proxyHost.DomainNames)
+	})
+	if err != nil {
+		return nil, err
+	}
+	proxyHost.DeletedAt = gorm.DeletedAt{}
+
+	RecordAudit(s.db, audit, NewAuditLog(userID, models.ActionRestored, models.ObjectTypeProxyHost, proxyHost.ID,
+		fmt.Sprintf("Restored proxy host: %s", proxyHost.GetPrimaryDomain())))
+
+	return proxyHost, nil
+}
+
+// PurgeProxyHost permanently deletes a proxy host from the trash. Admin only.
+func (s *NginxService) PurgeProxyHost(actorID uint, id uint, audit AuditContext) error {
+	if err := s.authService.RequireAdmin(actorID); err != nil {
+		return err
+	}
+
+	proxyHost, err := s.findDeletedProxyHost(s.db, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(proxyHost).Error; err != nil {
+		return err
+	}
+
+	RecordAudit(s.db, audit, NewAuditLog(actorID, models.ActionPurged, models.ObjectTypeProxyHost, proxyHost.ID,
+		fmt.Sprintf("Permanently deleted proxy host: %s", proxyHost.GetPrimaryDomain())))
+	return nil
+}
+
+// liveReference returns id if the record of model it refers to exists and is
+// not deleted, and nil otherwise
+func liveReference(tx *gorm.DB, model interface{}, id *uint) (*uint, error) {
+	if id == nil {
+		return nil, nil
+	}
+	var count int64
+	if err := tx.Model(model).Where("id = ?", *id).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	return id, nil
+}
+
+// findDeletedProxyHost finds a soft-deleted proxy host within scope
+func (s *NginxService) findDeletedProxyHost(scope *gorm.DB, id uint) (*models.ProxyHost, error) {
+	var proxyHost models.ProxyHost
+	if err := scope.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&proxyHost).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProxyHostNotFound
+		}
+		return nil, err
+	}
+	return &proxyHost, nil
+}
+
+// ListDeletedCertificates lists the user's soft-deleted certificates, most
+// recently deleted first
+func (s *CertificateService) ListDeletedCertificates(userID uint, offset, limit int) ([]models.Certificate, int64, error) {
+	query := s.db.Unscoped().Model(&models.Certificate{}).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var certificates []models.Certificate
+	if err := query.Order("deleted_at DESC").Offset(offset).Limit(limit).Find(&certificates).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Only users who can manage every certificate see private keys
+	if !s.authService.HasPermission(userID, models.PermissionCertificateManage) {
+		for i := range certificates {
+			certificates[i].ClearSensitiveData()
+		}
+	}
+	return certificates, total, nil
+}
+
+// RestoreCertificate brings a soft-deleted certificate back
+func (s *CertificateService) RestoreCertificate(userID uint, id uint, audit AuditContext) (*models.Certificate, error) {
+	certificate, err := s.findDeletedCertificate(s.db.Where("user_id = ?", userID), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Unscoped().Model(&models.Certificate{}).Where("id = ?", certificate.ID).
+		UpdateColumn("deleted_at", nil).Error; err != nil {
+		return nil, err
+	}
+	certificate.DeletedAt = gorm.DeletedAt{}
+
+	RecordAudit(s.db, audit, NewAuditLog(userID, models.ActionRestored, models.ObjectTypeCertificate, certificate.ID,
+		fmt.Sprintf("Restored certificate: %s", certificate.GetPrimaryDomain())))
+	s.recordActivity(certificate, "restored", ActivityLevelInfo, nil)
+
+	if !s.authService.HasPermission(userID, models.PermissionCertificateManage) {
+		certificate.ClearSensitiveData()
+	}
+	return certificate, nil
+}
+
+// PurgeCertificate permanently deletes a certificate from the trash. Hosts and
+// streams, deleted or not, that still point at it lose the reference. Admin
+// only.
+func (s *CertificateService) PurgeCertificate(actorID uint, id uint, audit AuditContext) error {
+	if err := s.authService.RequireAdmin(actorID); err != nil {
+		return err
+	}
+
+	certificate, err := s.findDeletedCertificate(s.db, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range certificateReferrers {
+			if err := tx.Unscoped().Model(model).
+				Where("certificate_id = ?", certificate.ID).
+				UpdateColumn("certificate_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(certificate).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.removeCertificateFiles(certificate.ID)
+
+	RecordAudit(s.db, audit, NewAuditLog(actorID, models.ActionPurged, models.ObjectTypeCertificate, certificate.ID,
+		fmt.Sprintf("Permanently deleted certificate: %s", certificate.GetPrimaryDomain())))
+	return nil
+}
+
+// findDeletedCertificate finds a soft-deleted certificate within scope
+func (s *CertificateService) findDeletedCertificate(scope *gorm.DB, id uint) (*models.Certificate, error) {
+	var certificate models.Certificate
+	if err := scope.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&certificate).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCertificateNotFound
+		}
+		return nil, err
+	}
+	return &certificate, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// TestRestoreProxyHostDropsDeletedReferences restores a proxy host whose
+// certificate was purged and whose access list was deleted while it was in
+// the trash
+func TestRestoreProxyHostDropsDeletedReferences(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+
+	certificate := &models.Certificate{Name: "purged", Provider: models.ProviderCustom, UserID: owner.ID}
+	accessList := &models.AccessList{Name: "deleted", UserID: owner.ID}
+	kept := &models.AccessList{Name: "kept", UserID: owner.ID}
+	for _, record := range []interface{}{certificate, accessList, kept} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestNginxService(t, &MockNginxRunner{})
+	restore := func(accessListID uint) *models.ProxyHost {
+		t.Helper()
+		proxyHost := createTestProxyHost(t, db, owner.ID, "restored.example.com")
+		if err := db.Model(proxyHost).Updates(map[string]interface{}{"certificate_id": certificate.ID, "access_list_id": accessListID}).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Delete(proxyHost).Error; err != nil {
+			t.Fatal(err)
+		}
+		return proxyHost
+	}
+
+	withDeleted := restore(accessList.ID)
+	if err := db.Delete(accessList).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(certificate).Error; err != nil {
+		t.Fatal(err)
+	}
+	certificates := NewCertificateService(t.TempDir(), t.TempDir(), NewAuthService("test"))
+	certificates.filesPath = t.TempDir()
+	if err := certificates.PurgeCertificate(admin.ID, certificate.ID, AuditContext{}); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := s.RestoreProxyHost(owner.ID, withDeleted.ID, AuditContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored models.ProxyHost
+	if err := db.First(&stored, withDeleted.ID).Error; err != nil {
+		t.Fatalf("restored host: %v", err)
+	}
+	if stored.CertificateID != nil || stored.AccessListID != nil || restored.CertificateID != nil || restored.AccessListID != nil {
+		t.Errorf("restored host refers to certificate %v and access list %v, want neither", stored.CertificateID, stored.AccessListID)
+	}
+	if err := db.Delete(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// A live access list is kept
+	withLive := restore(kept.ID)
+	if _, err := s.RestoreProxyHost(owner.ID, withLive.ID, AuditContext{}); err != nil {
+		t.Fatal(err)
+	}
+	var live models.ProxyHost
+	if err := db.First(&live, withLive.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if live.AccessListID == nil || *live.AccessListID != kept.ID {
+		t.Errorf("restored host refers to access list %v, want %d", live.AccessListID, kept.ID)
+	}
+}
+
+// TestPurgeCertificateClearsHostReferences purges a certificate that deleted
+// dead and redirection hosts still refer to
+func TestPurgeCertificateClearsHostReferences(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+
+	certificate := &models.Certificate{Name: "purged", Provider: models.ProviderCustom, UserID: owner.ID}
+	if err := db.Create(certificate).Error; err != nil {
+		t.Fatal(err)
+	}
+	deadHost := &models.DeadHost{DomainNames: models.StringArray{"dead.example.com"}, CertificateID: &certificate.ID, UserID: owner.ID}
+	redirectionHost := &models.RedirectionHost{
+		DomainNames:       models.StringArray{"old.example.com"},
+		ForwardScheme:     "https",
+		ForwardDomainName: "new.example.com",
+		StatusCode:        301,
+		CertificateID:     &certificate.ID,
+		UserID:            owner.ID,
+	}
+	for _, host := range []interface{}{deadHost, redirectionHost} {
+		if err := db.Create(host).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewCertificateService(t.TempDir(), t.TempDir(), NewAuthService("test"))
+	s.filesPath = t.TempDir()
+	if err := s.DeleteCertificate(owner.ID, certificate.ID, AuditContext{}); err == nil {
+		t.Fatal("deleted a certificate live hosts use")
+	}
+
+	for _, host := range []interface{}{deadHost, redirectionHost} {
+		if err := db.Delete(host).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.DeleteCertificate(owner.ID, certificate.ID, AuditContext{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PurgeCertificate(admin.ID, certificate.ID, AuditContext{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, model := range []interface{}{&models.DeadHost{}, &models.RedirectionHost{}} {
+		var count int64
+		if err := db.Unscoped().Model(model).Where("certificate_id = ?", certificate.ID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%T: %d rows still refer to the purged certificate", model, count)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// Hosts, configs and templates are soft-deleted: a deleted row stays in its
+// table with deleted_at set. A soft-deleted row never blocks reuse, so its
+// domains and name are free again as soon as it is deleted. Host domains are
+// checked against the host_domains index, whose rows are removed when their
+// host is deleted. Names go through GORM's default scope, which skips
+// soft-deleted rows, and names guarded by a (name, user_id) unique index are
+// released from soft-deleted rows before being reused, since the index still
+// covers those rows. Streams
+// are deleted permanently, so their incoming port needs no special handling.
+
+var ErrDomainInUse = models.ErrDomainInUse
+
+// CheckDomainsAvailable checks that no live proxy, dead or redirection host
+// uses any of domains by exact lookup in the normalized host_domains index.
+// The host being updated is excluded by passing its table and ID; excludeID
+// 0 excludes nothing. Saving a host re-checks under the index's unique
+// constraint, so this only gives an early, friendlier error.
+func CheckDomainsAvailable(db *gorm.DB, domains []string, excludeTable string, excludeID uint) error {
+	normalized := models.NormalizeDomains(domains)
+	if len(normalized) == 0 {
+		return nil
+	}
+
+	query := db.Model(&models.HostDomain{}).Where("domain IN ?", normalized)
+	if excludeID > 0 {
+		query = query.Where("NOT (host_type = ? AND host_id = ?)", excludeTable, excludeID)
+	}
+
+	var taken []string
+	if err := query.Limit(1).Pluck("domain", &taken).Error; err != nil {
+		return err
+	}
+	if len(taken) > 0 {
+		return fmt.Errorf("%w: %s", ErrDomainInUse, taken[0])
+	}
+	return nil
+}
+
+// releaseDeletedName renames soft-deleted rows of model that still hold name
+// for userID, freeing it in the (name, user_id) unique index while keeping
+// the rows for history
+func releaseDeletedName(db *gorm.DB, model interface{}, userID uint, name string) error {
+	var ids []uint
+	if err := db.Unscoped().Model(model).
+		Where("user_id = ? AND name = ? AND deleted_at IS NOT NULL", userID, name).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := db.Unscoped().Model(model).
+			Where("id = ?", id).
+			UpdateColumn("name", fmt.Sprintf("%s (deleted #%d)", name, id)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	pkgerrors "github.com/nguyendkn/nginx-manager/pkg/errors"
+)
+
+// TestRecreateProxyHostAfterDelete checks that a deleted proxy host's domain
+// can be reused while a live host's cannot
+func TestRecreateProxyHostAfterDelete(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+	s := newTestNginxService(t, &MockNginxRunner{})
+
+	req := &ProxyHostRequest{
+		DomainNames:   []string{"app.example.com"},
+		ForwardScheme: "http",
+		ForwardHost:   "127.0.0.1",
+		ForwardPort:   8080,
+		Enabled:       true,
+	}
+	first, err := s.CreateProxyHost(admin.ID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upper := *req
+	upper.DomainNames = []string{"APP.example.com"}
+	if _, err := s.CreateProxyHost(admin.ID, &upper); !errors.Is(err, ErrDomainInUse) {
+		t.Fatalf("live domain: got %v, want %v", err, ErrDomainInUse)
+	}
+
+	if err := s.DeleteProxyHost(admin.ID, first.ID); err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.CreateProxyHost(admin.ID, req)
+	if err != nil {
+		t.Fatalf("recreate after delete: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Errorf("recreated host reused ID %d", first.ID)
+	}
+}
+
+// TestRecreateConfigAfterDelete checks that a deleted configuration's name
+// can be reused while the deleted row is kept
+func TestRecreateConfigAfterDelete(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+
+	dir := t.TempDir()
+	s := NewConfigService(dir, filepath.Join(dir, "backups"), filepath.Join(dir, "templates"), NewAuthService("test"))
+	s.SetNginxRunner(&MockNginxRunner{})
+
+	req := &ConfigRequest{Name: "gzip", Type: models.ConfigTypeCustom, Content: "gzip on;"}
+	first, err := s.CreateConfig(owner.ID, req, AuditContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateConfig(owner.ID, req, AuditContext{}); !errors.Is(err, pkgerrors.ErrConfigDuplicate) {
+		t.Fatalf("live name: got %v, want %v", err, pkgerrors.ErrConfigDuplicate)
+	}
+
+	if err := s.DeleteConfig(owner.ID, first.ID, AuditContext{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateConfig(owner.ID, req, AuditContext{}); err != nil {
+		t.Fatalf("recreate after delete: %v", err)
+	}
+
+	var deleted models.NginxConfig
+	if err := db.Unscoped().First(&deleted, first.ID).Error; err != nil {
+		t.Fatalf("deleted configuration was not kept: %v", err)
+	}
+	if deleted.Name == req.Name {
+		t.Errorf("deleted configuration still holds the name %q", req.Name)
+	}
+}
+
+// TestRecreateTemplateAfterDelete checks that a deleted template's name can be
+// reused
+func TestRecreateTemplateAfterDelete(t *testing.T) {
+	db := newTestDB(t)
+	owner := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	s := NewTemplateService(NewAuthService("test"))
+
+	req := &TemplateRequest{Name: "Reverse proxy", Category: models.CategoryProxy, Content: "proxy_pass {{ .upstream }};"}
+	first, err := s.CreateTemplate(owner.ID, req, AuditContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateTemplate(owner.ID, req, AuditContext{}); !errors.Is(err, pkgerrors.ErrTemplateDuplicate) {
+		t.Fatalf("live name: got %v, want %v", err, pkgerrors.ErrTemplateDuplicate)
+	}
+
+	if err := s.DeleteTemplate(owner.ID, first.ID, AuditContext{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateTemplate(owner.ID, req, AuditContext{}); err != nil {
+		t.Fatalf("recreate after delete: %v", err)
+	}
+}
+
+// TestTransferOverDeletedName checks that a name held only by one of the
+// target's deleted configurations does not block a transfer
+func TestTransferOverDeletedName(t *testing.T) {
+	db := newTestDB(t)
+	admin := createTestUser(t, db, "root@example.test", models.RoleAdmin)
+	source := createTestUser(t, db, "owner@example.test", models.RoleUser)
+	target := createTestUser(t, db, "other@example.test", models.RoleUser)
+
+	for _, userID := range []uint{source.ID, target.ID} {
+		config := &models.NginxConfig{Name: "gzip", Type: models.ConfigTypeCustom, Content: "gzip on;", UserID: userID}
+		if err := db.Create(config).Error; err != nil {
+			t.Fatal(err)
+		}
+		if userID == target.ID {
+			if err := db.Delete(config).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	s := NewUserService(NewAuthService("test"))
+	if _, err := s.TransferResources(source.ID, target.ID, admin.ID, "", ""); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+
+	var names []string
+	if err := db.Model(&models.NginxConfig{}).Where("user_id = ?", target.ID).Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "gzip" {
+		t.Errorf("target owns configurations %q, want [gzip]", names)
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// UpstreamHealthService checks reachability of proxy host upstreams
+type UpstreamHealthService struct {
+	db          *gorm.DB
+	authService *AuthService
+	concurrency int
+	timeout     time.Duration
+	cacheTTL    time.Duration
+
+	cache      map[uint]UpstreamHealthResult
+	cacheMutex sync.RWMutex
+}
+
+// UpstreamHealthResult represents the result of a single upstream check
+type UpstreamHealthResult struct {
+	ProxyHostID   uint      `json:"proxy_host_id"`
+	PrimaryDomain string    `json:"primary_domain"`
+	Target        string    `json:"target"`
+	Healthy       bool      `json:"healthy"`
+	LatencyMs     int64     `json:"latency_ms"`
+	Error         string    `json:"error,omitempty"`
+	CheckedAt     time.Time `json:"checked_at"`
+	Cached        bool      `json:"cached"`
+}
+
+// UpstreamHealthSummary represents fleet-wide upstream health
+type UpstreamHealthSummary struct {
+	Results   []UpstreamHealthResult `json:"results"`
+	Total     int                    `json:"total"`
+	Healthy   int                    `json:"healthy"`
+	Unhealthy int                    `json:"unhealthy"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
+
+// NewUpstreamHealthService creates a new upstream health service. concurrency
+// bounds the number of simultaneous checks, timeout applies to each check and
+// cacheTTL controls how long results are reused.
+func NewUpstreamHealthService(authService *AuthService, concurrency int, timeout, cacheTTL time.Duration) *UpstreamHealthService {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &UpstreamHealthService{
+		db:          database.GetDB(),
+		authService: authService,
+		concurrency: concurrency,
+		timeout:     timeout,
+		cacheTTL:    cacheTTL,
+		cache:       make(map[uint]UpstreamHealthResult),
+	}
+}
+
+// CheckUpstream checks a single proxy host's upstream, using a cached result if still fresh
+func (s *UpstreamHealthService) CheckUpstream(ctx context.Context, userID, proxyHostID uint) (*UpstreamHealthResult, error) {
+	var proxyHost models.ProxyHost
+	query := s.db.Where("id = ?", proxyHostID)
+	if !s.authService.HasPermission(userID, models.PermissionProxyHostReadAll) {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.First(&proxyHost).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrProxyHostNotFound
+		}
+		return nil, err
+	}
+
+	result := s.checkWithCache(ctx, &proxyHost)
+	return &result, nil
+}
+
+// CheckAllUpstreams checks every enabled proxy host the user can access using a
+// bounded worker pool. Results are sent on the returned channel as they
+// complete; the channel is closed once all checks finish or ctx is cancelled.
+func (s *UpstreamHealthService) CheckAllUpstreams(ctx context.Context, userID uint) (<-chan UpstreamHealthResult, error) {
+	var proxyHosts []models.ProxyHost
+	query := s.db.Where("enabled = ?", true)
+	if !s.authService.HasPermission(userID, models.PermissionProxyHostReadAll) {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.Find(&proxyHosts).Error; err != nil {
+		return nil, err
+	}
+
+	results := make(chan UpstreamHealthResult, len(proxyHosts))
+	targets := make([]*models.ProxyHost, len(proxyHosts))
+	for i := range proxyHosts {
+		targets[i] = &proxyHosts[i]
+	}
+
+	go func() {
+		defer close(results)
+		probeProxyHosts(ctx, targets, s.concurrency, func(proxyHost *models.ProxyHost) {
+			results <- s.checkWithCache(ctx, proxyHost)
+		})
+	}()
+
+	return results, nil
+}
+
+// GetFleetHealth runs CheckAllUpstreams and collects the results into a summary
+func (s *UpstreamHealthService) GetFleetHealth(ctx context.Context, userID uint) (*UpstreamHealthSummary, error) {
+	results, err := s.CheckAllUpstreams(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &UpstreamHealthSummary{
+		Results:   make([]UpstreamHealthResult, 0),
+		CheckedAt: time.Now(),
+	}
+	for result := range results {
+		summary.Results = append(summary.Results, result)
+		if result.Healthy {
+			summary.Healthy++
+		} else {
+			summary.Unhealthy++
+		}
+	}
+	summary.Total = len(summary.Results)
+
+	return summary, nil
+}
+
+// checkWithCache returns a cached result if it is younger than the TTL, otherwise probes the upstream
+func (s *UpstreamHealthService) checkWithCache(ctx context.Context, proxyHost *models.ProxyHost) UpstreamHealthResult {
+	s.cacheMutex.RLock()
+	cached, ok := s.cache[proxyHost.ID]
+	s.cacheMutex.RUnlock()
+
+	if ok && time.Since(cached.CheckedAt) < s.cacheTTL {
+		cached.Cached = true
+		return cached
+	}
+
+	result := s.probe(ctx, proxyHost)
+
+	s.cacheMutex.Lock()
+	s.cache[proxyHost.ID] = result
+	s.cacheMutex.Unlock()
+
+	return result
+}
+
+// probe opens a TCP connection to the proxy host's upstream within the per-check timeout
+func (s *UpstreamHealthService) probe(ctx context.Context, proxyHost *models.ProxyHost) UpstreamHealthResult {
+	target := net.JoinHostPort(proxyHost.ForwardHost, strconv.Itoa(proxyHost.ForwardPort))
+	result := UpstreamHealthResult{
+		ProxyHostID:   proxyHost.ID,
+		PrimaryDomain: proxyHost.GetPrimaryDomain(),
+		Target:        target,
+	}
+
+	latency, failure := timeProbe(ctx, s.timeout, func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+	result.LatencyMs = latency.Milliseconds()
+	result.CheckedAt = time.Now()
+
+	if failure != "" {
+		result.Error = failure
+		logger.Debug("Upstream health check failed",
+			logger.Uint("proxy_host_id", proxyHost.ID),
+			logger.String("target", target),
+			logger.String("error", failure))
+		return result
+	}
+
+	result.Healthy = true
+	return result
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// probeProxyHosts calls probe for each proxy host from at most concurrency
+// workers and waits for them to finish. Hosts not yet handed to a worker when
+// ctx is done are skipped.
+func probeProxyHosts(ctx context.Context, proxyHosts []*models.ProxyHost, concurrency int, probe func(*models.ProxyHost)) {
+	if len(proxyHosts) == 0 {
+		return
+	}
+
+	jobs := make(chan *models.ProxyHost)
+	var wg sync.WaitGroup
+	workers := concurrency
+	if workers > len(proxyHosts) {
+		workers = len(proxyHosts)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for proxyHost := range jobs {
+				probe(proxyHost)
+			}
+		}()
+	}
+
+dispatch:
+	for _, proxyHost := range proxyHosts {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- proxyHost:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// timeProbe runs check with a deadline of timeout and returns how long it
+// took, and why it failed or "" if it succeeded
+func timeProbe(ctx context.Context, timeout time.Duration, check func(context.Context) error) (time.Duration, string) {
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(checkCtx)
+	latency := time.Since(start)
+
+	switch {
+	case err == nil:
+		return latency, ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return latency, fmt.Sprintf("timed out after %s", timeout)
+	default:
+		return latency, err.Error()
+	}
+}
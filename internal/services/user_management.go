@@ -0,0 +1,314 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailTaken     = errors.New("email is already in use")
+	ErrDeleteSelf     = errors.New("cannot delete your own account")
+	ErrPasswordLength = errors.New("password must be at least 8 characters")
+)
+
+// minPasswordLength matches the change-password request validation
+const minPasswordLength = 8
+
+// CreateUserRequest represents a new user created by an admin
+type CreateUserRequest struct {
+	Email      string   `json:"email" binding:"required,email"`
+	Name       string   `json:"name" binding:"required"`
+	Nickname   string   `json:"nickname"`
+	Password   string   `json:"password" binding:"required,min=8"`
+	Roles      []string `json:"roles"`
+	IsDisabled bool     `json:"is_disabled"`
+}
+
+// UpdateUserRequest represents a partial user update. Users may change their
+// own name, nickname and avatar; email, password, roles and the disabled flag
+// need an admin.
+type UpdateUserRequest struct {
+	Email      *string  `json:"email" binding:"omitempty,email"`
+	Name       *string  `json:"name"`
+	Nickname   *string  `json:"nickname"`
+	Avatar     *string  `json:"avatar"`
+	Password   *string  `json:"password"`
+	Roles      []string `json:"roles"`
+	IsDisabled *bool    `json:"is_disabled"`
+}
+
+// UserListResponse represents a page of users
+type UserListResponse struct {
+	Users []models.User `json:"users"`
+	Total int64         `json:"total"`
+	Page  int           `json:"page"`
+	Limit int           `json:"limit"`
+}
+
+// ListUsers lists users with pagination, optionally matching search against
+// email, name and nickname. Admin only.
+func (s *UserService) ListUsers(actorID uint, page, limit int, search string) (*UserListResponse, error) {
+	if err := s.authService.RequireAdmin(actorID); err != nil {
+		return nil, err
+	}
+
+	query := s.db.Model(&models.User{})
+	if search = strings.TrimSpace(search); search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(email) LIKE ? OR LOWER(name) LIKE ? OR LOWER(nickname) LIKE ?", pattern, pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var users []models.User
+	if err := query.Order("id ASC").Offset((page - 1) * limit).Limit(limit).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return &UserListResponse{Users: users, Total: total, Page: page, Limit: limit}, nil
+}
+
+// GetUser gets a single user. Users may read their own account; anyone else
+// needs an admin.
+func (s *UserService) GetUser(actorID, userID uint) (*models.User, error) {
+	if actorID != userID {
+		if err := s.authService.RequireAdmin(actorID); err != nil {
+			return nil, err
+		}
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateUser creates a user with a hashed password. Without roles the user
+// gets the default user role. Admin only.
+func (s *UserService) CreateUser(actorID uint, req *CreateUserRequest, ipAddress, userAgent string) (*models.User, error) {
+	if err := s.authService.RequireAdmin(actorID); err != nil {
+		return nil, err
+	}
+	if len(req.Password) < minPasswordLength {
+		return nil, ErrPasswordLength
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if err := s.checkEmailAvailable(email, 0); err != nil {
+		return nil, err
+	}
+
+	roles := models.StringArray{string(models.RoleUser)}
+	if len(req.Roles) > 0 {
+		var err error
+		if roles, err = s.validateRoles(req.Roles); err != nil {
+			return nil, err
+		}
+	}
+
+	// The BeforeCreate hook hashes the password
+	user := models.User{
+		Email:      email,
+		Name:       strings.TrimSpace(req.Name),
+		Nickname:   strings.TrimSpace(req.Nickname),
+		Password:   req.Password,
+		Roles:      roles,
+		IsDisabled: req.IsDisabled,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AuditLog{
+			UserID:      actorID,
+			Action:      models.ActionCreated,
+			ObjectType:  models.ObjectTypeUser,
+			ObjectID:    user.ID,
+			Description: fmt.Sprintf("Created user: %s", user.Email),
+			IPAddress:   ipAddress,
+			UserAgent:   userAgent,
+			Meta:        models.JSON{"roles": user.Roles, "is_disabled": user.IsDisabled},
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("User created",
+		logger.Uint("user_id", user.ID),
+		logger.Uint("actor_id", actorID))
+
+	user.Password = ""
+	return &user, nil
+}
+
+// UpdateUser applies a partial update. The last active admin cannot be
+// disabled or lose the admin role.
+func (s *UserService) UpdateUser(actorID, userID uint, req *UpdateUserRequest, ipAddress, userAgent string) (*models.User, error) {
+	if actorID != userID || req.Email != nil || req.Password != nil || req.Roles != nil || req.IsDisabled != nil {
+		if err := s.authService.RequireAdmin(actorID); err != nil {
+			return nil, err
+		}
+	}
+
+	var roles models.StringArray
+	if req.Roles != nil {
+		var err error
+		if roles, err = s.validateRoles(req.Roles); err != nil {
+			return nil, err
+		}
+	}
+	if req.Password != nil && len(*req.Password) < minPasswordLength {
+		return nil, ErrPasswordLength
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		before := user
+
+		if req.Email != nil {
+			email := strings.ToLower(strings.TrimSpace(*req.Email))
+			if email != user.Email {
+				if err := s.checkEmailAvailable(email, user.ID); err != nil {
+					return err
+				}
+				user.Email = email
+			}
+		}
+		if req.Name != nil {
+			user.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.Nickname != nil {
+			user.Nickname = strings.TrimSpace(*req.Nickname)
+		}
+		if req.Avatar != nil {
+			user.Avatar = *req.Avatar
+		}
+
+		demoted := req.Roles != nil && !containsString(roles, string(models.RoleAdmin))
+		disabled := req.IsDisabled != nil && *req.IsDisabled
+		if demoted || disabled {
+			if err := ensureAnotherAdmin(tx, &before); err != nil {
+				return err
+			}
+		}
+		if req.Roles != nil {
+			user.Roles = roles
+		}
+		if req.IsDisabled != nil {
+			user.IsDisabled = *req.IsDisabled
+		}
+		if req.Password != nil {
+			if err := user.SetPassword(*req.Password); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+
+		auditLog := NewUpdateAuditLog(actorID, models.ObjectTypeUser, user.ID,
+			fmt.Sprintf("Updated user: %s", user.Email), before, user)
+		auditLog.IPAddress = ipAddress
+		auditLog.UserAgent = userAgent
+		if req.Password != nil {
+			// Password is not serialized, so the diff cannot show it
+			if auditLog.Meta == nil {
+				auditLog.Meta = models.JSON{}
+			}
+			auditLog.Meta["password_changed"] = true
+		}
+		return tx.Create(auditLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("User updated",
+		logger.Uint("user_id", user.ID),
+		logger.Uint("actor_id", actorID))
+
+	user.Password = ""
+	return &user, nil
+}
+
+// DeleteUser soft-deletes a user. Admins cannot delete themselves or the last
+// active admin. Admin only.
+func (s *UserService) DeleteUser(actorID, userID uint, ipAddress, userAgent string) error {
+	if err := s.authService.RequireAdmin(actorID); err != nil {
+		return err
+	}
+	if actorID == userID {
+		return ErrDeleteSelf
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if err := ensureAnotherAdmin(tx, &user); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AuditLog{
+			UserID:      actorID,
+			Action:      models.ActionDeleted,
+			ObjectType:  models.ObjectTypeUser,
+			ObjectID:    user.ID,
+			Description: fmt.Sprintf("Deleted user: %s", user.Email),
+			IPAddress:   ipAddress,
+			UserAgent:   userAgent,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("User deleted",
+		logger.Uint("user_id", userID),
+		logger.Uint("actor_id", actorID))
+
+	return nil
+}
+
+// checkEmailAvailable returns ErrEmailTaken when another user, including a
+// soft-deleted one still holding the unique index, uses email
+func (s *UserService) checkEmailAvailable(email string, exceptID uint) error {
+	var count int64
+	if err := s.db.Unscoped().Model(&models.User{}).
+		Where("LOWER(email) = ? AND id <> ?", email, exceptID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+	return nil
+}
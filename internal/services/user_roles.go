@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownRole   = errors.New("unknown role")
+	ErrRolesRequired = errors.New("at least one role is required")
+	ErrLastAdmin     = errors.New("cannot delete, disable or demote the last active admin")
+)
+
+// ListRoles returns every role with its permissions
+func (s *UserService) ListRoles() ([]models.Role, error) {
+	var roles []models.Role
+	if err := s.db.Preload("Permissions").Order("id ASC").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// AssignRoles replaces a user's roles and records the change for actorID.
+// Every role must exist, and the last active admin keeps the admin role so
+// the system cannot be locked out of user administration.
+func (s *UserService) AssignRoles(actorID, userID uint, roleNames []string, ipAddress, userAgent string) (*models.User, error) {
+	roles, err := s.validateRoles(roleNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		before := user
+
+		if !containsString(roles, string(models.RoleAdmin)) {
+			if err := ensureAnotherAdmin(tx, &user); err != nil {
+				return err
+			}
+		}
+
+		// UpdateColumn skips the password hashing hook
+		if err := tx.Model(&user).UpdateColumn("roles", roles).Error; err != nil {
+			return err
+		}
+		user.Roles = roles
+
+		auditLog := NewUpdateAuditLog(actorID, models.ObjectTypeUser, user.ID,
+			fmt.Sprintf("Assigned roles to %s: %s", user.Email, strings.Join(roles, ", ")), before, user)
+		auditLog.IPAddress = ipAddress
+		auditLog.UserAgent = userAgent
+		return tx.Create(auditLog).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Assigned user roles",
+		logger.Uint("user_id", userID),
+		logger.Uint("actor_id", actorID),
+		logger.String("roles", strings.Join(roles, ",")))
+
+	user.Password = ""
+	return &user, nil
+}
+
+// validateRoles trims and de-duplicates role names and checks that each one
+// exists in the roles table
+func (s *UserService) validateRoles(roleNames []string) (models.StringArray, error) {
+	var roles models.StringArray
+	for _, name := range roleNames {
+		name = strings.TrimSpace(name)
+		if name != "" && !containsString(roles, name) {
+			roles = append(roles, name)
+		}
+	}
+	if len(roles) == 0 {
+		return nil, ErrRolesRequired
+	}
+
+	var known []string
+	if err := s.db.Model(&models.Role{}).Where("name IN ?", []string(roles)).Pluck("name", &known).Error; err != nil {
+		return nil, err
+	}
+	for _, name := range roles {
+		if !containsString(known, name) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRole, name)
+		}
+	}
+	return roles, nil
+}
+
+// ensureAnotherAdmin returns ErrLastAdmin when user is the only active admin,
+// so deleting, disabling or demoting them would leave nobody able to
+// administer users. Roles are stored as a JSON array, so admins are matched in
+// Go rather than in SQL.
+func ensureAnotherAdmin(tx *gorm.DB, user *models.User) error {
+	if !user.IsAdmin() || user.IsDisabled {
+		return nil
+	}
+
+	var users []models.User
+	if err := tx.Select("id", "roles").Where("is_disabled = ? AND id <> ?", false, user.ID).Find(&users).Error; err != nil {
+		return err
+	}
+	for i := range users {
+		if users[i].IsAdmin() {
+			return nil
+		}
+	}
+	return ErrLastAdmin
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/database"
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTransferTargetNotFound = errors.New("target user not found")
+	ErrTransferSameUser       = errors.New("source and target user are the same")
+)
+
+// TransferConflictError reports resources whose names already exist for the target user
+type TransferConflictError struct {
+	Conflicts map[string][]string
+}
+
+func (e *TransferConflictError) Error() string {
+	var parts []string
+	for resource, names := range e.Conflicts {
+		parts = append(parts, fmt.Sprintf("%s: %s", resource, strings.Join(names, ", ")))
+	}
+	return "target user already owns resources with the same name (" + strings.Join(parts, "; ") + ")"
+}
+
+// TransferResult reports how many resources of each kind were reassigned
+type TransferResult struct {
+	SourceUserID uint             `json:"source_user_id"`
+	TargetUserID uint             `json:"target_user_id"`
+	Transferred  map[string]int64 `json:"transferred"`
+	Total        int64            `json:"total"`
+}
+
+// transferableResource describes an owned table and its owner column
+type transferableResource struct {
+	name   string
+	model  interface{}
+	column string
+}
+
+// transferableResources lists every resource owned by a user that moves with
+// an ownership transfer. Audit logs, tokens and version history stay with the
+// original user.
+var transferableResources = []transferableResource{
+	{name: "proxy_hosts", model: &models.ProxyHost{}, column: "user_id"},
+	{name: "certificates", model: &models.Certificate{}, column: "user_id"},
+	{name: "access_lists", model: &models.AccessList{}, column: "user_id"},
+	{name: "redirection_hosts", model: &models.RedirectionHost{}, column: "user_id"},
+	{name: "streams", model: &models.Stream{}, column: "user_id"},
+	{name: "dead_hosts", model: &models.DeadHost{}, column: "user_id"},
+	{name: "nginx_configs", model: &models.NginxConfig{}, column: "user_id"},
+	{name: "config_templates", model: &models.ConfigTemplate{}, column: "user_id"},
+	{name: "dashboards", model: &models.Dashboard{}, column: "user_id"},
+	{name: "alert_rules", model: &models.AlertRule{}, column: "user_id"},
+	{name: "notification_channels", model: &models.NotificationChannel{}, column: "user_id"},
+	{name: "silences", model: &models.Silence{}, column: "created_by"},
+}
+
+// UserService handles user administration
+type UserService struct {
+	db          *gorm.DB
+	authService *AuthService
+}
+
+// NewUserService creates a new user service
+func NewUserService(authService *AuthService) *UserService {
+	return &UserService{
+		db:          database.GetDB(),
+		authService: authService,
+	}
+}
+
+// TransferResources reassigns everything owned by sourceUserID to
+// targetUserID in a single transaction and records an audit log entry for
+// actorID. Nothing is moved if the target already owns a config or template
+// with a conflicting name.
+func (s *UserService) TransferResources(sourceUserID, targetUserID, actorID uint, ipAddress, userAgent string) (*TransferResult, error) {
+	if sourceUserID == targetUserID {
+		return nil, ErrTransferSameUser
+	}
+
+	var source, target models.User
+	if err := s.db.First(&source, sourceUserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if err := s.db.First(&target, targetUserID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrTransferTargetNotFound
+		}
+		return nil, err
+	}
+
+	result := &TransferResult{
+		SourceUserID: sourceUserID,
+		TargetUserID: targetUserID,
+		Transferred:  make(map[string]int64),
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.checkTransferConflicts(tx, sourceUserID, targetUserID); err != nil {
+			return err
+		}
+
+		for _, resource := range transferableResources {
+			// Analytics tables are created on demand and may not exist yet
+			if !tx.Migrator().HasTable(resource.model) {
+				continue
+			}
+
+			update := tx.Model(resource.model).
+				Where(resource.column+" = ?", sourceUserID).
+				Update(resource.column, targetUserID)
+			if update.Error != nil {
+				return fmt.Errorf("failed to transfer %s: %w", resource.name, update.Error)
+			}
+
+			result.Transferred[resource.name] = update.RowsAffected
+			result.Total += update.RowsAffected
+		}
+
+		meta := models.JSON{"target_user_id": targetUserID}
+		for name, count := range result.Transferred {
+			meta[name] = count
+		}
+
+		return tx.Create(&models.AuditLog{
+			UserID:     actorID,
+			Action:     models.ActionTransferred,
+			ObjectType: models.ObjectTypeUser,
+			ObjectID:   sourceUserID,
+			Description: fmt.Sprintf("Transferred %d resources from %s to %s",
+				result.Total, source.Email, target.Email),
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			Meta:      meta,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Transferred user resources",
+		logger.Uint("source_user_id", sourceUserID),
+		logger.Uint("target_user_id", targetUserID),
+		logger.Uint("actor_id", actorID),
+		logger.Int64("total", result.Total))
+
+	return result, nil
+}
+
+// checkTransferConflicts finds configs and templates whose (name, user) unique
+// index would be violated by the transfer. Only the target's live resources
+// conflict; names held by its soft-deleted ones are released.
+func (s *UserService) checkTransferConflicts(tx *gorm.DB, sourceUserID, targetUserID uint) error {
+	conflicts := make(map[string][]string)
+
+	uniqueByName := []transferableResource{
+		{name: "nginx_configs", model: &models.NginxConfig{}},
+		{name: "config_templates", model: &models.ConfigTemplate{}},
+	}
+	for _, resource := range uniqueByName {
+		var names []string
+		if err := tx.Model(resource.model).
+			Where("user_id = ?", sourceUserID).
+			Where("name IN (?)", tx.Model(resource.model).Select("name").Where("user_id = ?", targetUserID)).
+			Pluck("name", &names).Error; err != nil {
+			return err
+		}
+		if len(names) > 0 {
+			conflicts[resource.name] = names
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return &TransferConflictError{Conflicts: conflicts}
+	}
+
+	for _, resource := range uniqueByName {
+		var names []string
+		if err := tx.Model(resource.model).Where("user_id = ?", sourceUserID).Pluck("name", &names).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := releaseDeletedName(tx, resource.model, targetUserID, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
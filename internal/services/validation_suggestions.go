@@ -0,0 +1,399 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxValidationConflicts caps the conflicting sources listed per issue
+const maxValidationConflicts = 10
+
+// ValidationIssue is one message from nginx -t, with an explanation and fix
+// when it matches a known error
+type ValidationIssue struct {
+	Level       string               `json:"level"` // emerg, alert, crit, error, warn
+	Message     string               `json:"message"`
+	File        string               `json:"file,omitempty"`
+	Line        int                  `json:"line,omitempty"`
+	Column      int                  `json:"column,omitempty"`
+	Explanation string               `json:"explanation,omitempty"`
+	Suggestion  string               `json:"suggestion,omitempty"`
+	Conflicts   []ValidationConflict `json:"conflicts,omitempty"`
+}
+
+// ValidationConflict identifies a host or configuration that likely causes an issue
+type ValidationConflict struct {
+	Type string `json:"type"` // proxy_host, dead_host, redirection_host, stream, certificate, nginx_config
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// nginxMessagePattern matches a line of nginx -t output such as
+// `nginx: [emerg] unknown directive "foo" in /etc/nginx/nginx.conf:12`
+var nginxMessagePattern = regexp.MustCompile(`^nginx: \[(\w+)\] (.+?)(?: in (\S+):(\d+))?$`)
+
+// validationRule maps an nginx error message to guidance. Explanation and
+// suggestion may reference submatches of pattern as $1, $2...
+type validationRule struct {
+	pattern     *regexp.Regexp
+	explanation string
+	suggestion  string
+	conflicts   func(f *conflictFinder, match []string) []ValidationConflict
+}
+
+var validationRules = []validationRule{
+	{
+		pattern:     regexp.MustCompile(`^duplicate listen options for (\S+)`),
+		explanation: "Another server block already listens on $1 with different options. Options such as ssl, http2 and default_server can only be set once per address and port.",
+		suggestion:  "Use the same listen options on $1 in every server block, or move this host to another port.",
+		conflicts: func(f *conflictFinder, match []string) []ValidationConflict {
+			return f.listeners(match[1])
+		},
+	},
+	{
+		pattern:     regexp.MustCompile(`^a duplicate default server for (\S+)`),
+		explanation: "More than one server block is marked default_server on $1.",
+		suggestion:  "Remove default_server from all but one server block listening on $1.",
+		conflicts: func(f *conflictFinder, match []string) []ValidationConflict {
+			return f.listeners(match[1])
+		},
+	},
+	{
+		pattern:     regexp.MustCompile(`^conflicting server name "([^"]+)" on (\S+), ignored`),
+		explanation: "The domain $1 is served by more than one server block on $2; nginx only uses the first one.",
+		suggestion:  "Remove $1 from all but one host, or disable the duplicate host.",
+		conflicts: func(f *conflictFinder, match []string) []ValidationConflict {
+			return f.domainOwners(match[1])
+		},
+	},
+	{
+		pattern:     regexp.MustCompile(`^bind\(\) to (\S+) failed`),
+		explanation: "nginx could not listen on $1 because another process or stream already uses it.",
+		suggestion:  "Free the port or change the listening port of the host or stream using it.",
+		conflicts: func(f *conflictFinder, match []string) []ValidationConflict {
+			return f.listeners(match[1])
+		},
+	},
+	{
+		pattern:     regexp.MustCompile(`^duplicate upstream "([^"]+)"`),
+		explanation: "An upstream named $1 is defined more than once.",
+		suggestion:  "Rename one of the upstream blocks; generated proxy host upstreams are named proxy_host_<id>.",
+		conflicts: func(f *conflictFinder, match []string) []ValidationConflict {
+			return f.generatedName(match[1])
+		},
+	},
+	{
+		pattern:     regexp.MustCompile(`^duplicate zone "([^"]+)"`),
+		explanation: "A shared memory zone named $1 is declared more than once.",
+		suggestion:  "Give each limit_req_zone or cache zone a unique name; generated rate limit zones are named proxy_host_<id>_limit.",
+		conflicts: func(f *conflictFinder, match []string) []ValidationConflict {
+			return f.generatedName(match[1])
+		},
+	},
+	{
+		pattern:     regexp.MustCompile(`^host not found in upstream "([^"]+)"`),
+		explanation: "The backend $1 could not be resolved when nginx loaded the configuration.",
+		suggestion:  "Check the forward host for typos, make sure it resolves from the nginx server, or use an IP address.",
+		conflicts: func(f *conflictFinder, match []string) []ValidationConflict {
+			return f.forwardTargets(match[1])
+		},
+	},
+	{
+		pattern:     regexp.MustCompile(`^cannot load certificate(?: key)? "([^"]+)"`),
+		explanation: "The certificate file $1 is missing or unreadable.",
+		suggestion:  "Re-upload or renew the certificate, or detach it from the host.",
+		conflicts: func(f *conflictFinder, match []string) []ValidationConflict {
+			return f.certificateUsers(match[1])
+		},
+	},
+	{
+		pattern:     regexp.MustCompile(`^unknown directive "([^"]+)"`),
+		explanation: "nginx does not recognize the directive $1.",
+		suggestion:  "Check the spelling of $1, or install the nginx module that provides it.",
+	},
+	{
+		pattern:     regexp.MustCompile(`^"([^"]+)" directive is duplicate`),
+		explanation: "The $1 directive appears more than once in the same block.",
+		suggestion:  "Remove the repeated $1 directive; it is often set both by the host settings and in advanced configuration.",
+	},
+	{
+		pattern:     regexp.MustCompile(`^"([^"]+)" directive is not allowed here`),
+		explanation: "The $1 directive is used in a block where nginx does not accept it.",
+		suggestion:  "Move $1 to the right context; for example limit_req_zone and upstream belong in http, not in server or location.",
+	},
+	{
+		pattern:     regexp.MustCompile(`^invalid number of arguments in "([^"]+)" directive`),
+		explanation: "The $1 directive has too many or too few arguments.",
+		suggestion:  "Check the arguments of $1 against the nginx documentation; a missing semicolon can also merge two directives.",
+	},
+	{
+		pattern:     regexp.MustCompile(`^directive "([^"]+)" is not terminated by ";"`),
+		explanation: "The $1 directive is missing its closing semicolon.",
+		suggestion:  "Add a ; at the end of the $1 directive.",
+	},
+	{
+		pattern:     regexp.MustCompile(`^unexpected "\}"|^unexpected end of file, expecting "\}"`),
+		explanation: "The braces in the configuration are unbalanced.",
+		suggestion:  "Make sure every { has a matching }.",
+	},
+	{
+		pattern:     regexp.MustCompile(`^no "events" section in configuration`),
+		explanation: "The content was tested as a complete nginx.conf, but it has no events block.",
+		suggestion:  "Set the configuration type to server, upstream or location so the snippet is validated inside an http block, or add an events {} block.",
+	},
+}
+
+// ExplainValidation adds explanations, fixes and the hosts or configurations
+// most likely involved to the issues of a validation result, parsing them
+// from the nginx output if the result has none. Only sources the user can
+// access are cross-referenced.
+func (s *ConfigService) ExplainValidation(userID uint, result *ValidationResult) {
+	finder := &conflictFinder{
+		db:      s.db,
+		userID:  userID,
+		isAdmin: s.authService.IsAdmin(userID),
+	}
+
+	issues := result.Issues
+	if issues == nil {
+		issues = parseValidationIssues(result.Output)
+	}
+
+	result.Issues = []ValidationIssue{}
+	for _, issue := range issues {
+		for _, rule := range validationRules {
+			submatches := rule.pattern.FindStringSubmatchIndex(issue.Message)
+			if submatches == nil {
+				continue
+			}
+			issue.Explanation = string(rule.pattern.ExpandString(nil, rule.explanation, issue.Message, submatches))
+			issue.Suggestion = string(rule.pattern.ExpandString(nil, rule.suggestion, issue.Message, submatches))
+			if rule.conflicts != nil {
+				issue.Conflicts = rule.conflicts(finder, rule.pattern.FindStringSubmatch(issue.Message))
+			}
+			break
+		}
+		result.Issues = append(result.Issues, issue)
+	}
+}
+
+// parseValidationIssues extracts the emerg/error/warn lines of nginx -t output
+func parseValidationIssues(output string) []ValidationIssue {
+	var issues []ValidationIssue
+	for _, line := range strings.Split(output, "\n") {
+		match := nginxMessagePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		issue := ValidationIssue{
+			Level:   match[1],
+			Message: match[2],
+			File:    match[3],
+		}
+		if match[4] != "" {
+			issue.Line, _ = strconv.Atoi(match[4])
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// conflictFinder looks up hosts and configurations related to a validation
+// issue, limited to the user's own unless they are an admin
+type conflictFinder struct {
+	db      *gorm.DB
+	userID  uint
+	isAdmin bool
+}
+
+// scoped restricts a query on model to what the user can access
+func (f *conflictFinder) scoped(model interface{}) *gorm.DB {
+	query := f.db.Model(model)
+	if !f.isAdmin {
+		query = query.Where("user_id = ?", f.userID)
+	}
+	return query
+}
+
+// domainHost is the subset of a host record needed to match domains
+type domainHost struct {
+	ID          uint
+	DomainNames models.StringArray
+}
+
+// domainOwners finds the hosts serving domain
+func (f *conflictFinder) domainOwners(domain string) []ValidationConflict {
+	var conflicts []ValidationConflict
+	for _, source := range []struct {
+		kind  string
+		model interface{}
+	}{
+		{"proxy_host", &models.ProxyHost{}},
+		{"dead_host", &models.DeadHost{}},
+		{"redirection_host", &models.RedirectionHost{}},
+	} {
+		var hosts []domainHost
+		f.scoped(source.model).Select("id", "domain_names").Find(&hosts)
+		for _, host := range hosts {
+			for _, name := range host.DomainNames {
+				if strings.EqualFold(name, domain) {
+					conflicts = append(conflicts, ValidationConflict{Type: source.kind, ID: host.ID, Name: host.DomainNames[0]})
+					break
+				}
+			}
+		}
+	}
+
+	serverName := regexp.MustCompile(`server_name[^;]*\s` + regexp.QuoteMeta(domain) + `[\s;]`)
+	conflicts = append(conflicts, f.configsMatching(serverName)...)
+	return limitConflicts(conflicts)
+}
+
+// listeners finds the hosts, streams and configurations listening on an
+// address such as 0.0.0.0:443
+func (f *conflictFinder) listeners(address string) []ValidationConflict {
+	_, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		portStr = address
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil
+	}
+
+	var conflicts []ValidationConflict
+
+	// Generated host configs listen on 443 with a certificate, otherwise on 80
+	if port == 80 || port == 443 {
+		var proxyHosts []models.ProxyHost
+		query := f.scoped(&models.ProxyHost{}).Where("enabled = ?", true)
+		if port == 443 {
+			query = query.Where("certificate_id IS NOT NULL")
+		} else {
+			query = query.Where("certificate_id IS NULL OR ssl_forced = ?", true)
+		}
+		query.Select("id", "domain_names").Find(&proxyHosts)
+		for _, host := range proxyHosts {
+			conflicts = append(conflicts, ValidationConflict{Type: "proxy_host", ID: host.ID, Name: host.GetPrimaryDomain()})
+		}
+	}
+
+	var streams []models.Stream
+	f.scoped(&models.Stream{}).Where("incoming_port = ?", port).Find(&streams)
+	for _, stream := range streams {
+		conflicts = append(conflicts, ValidationConflict{
+			Type: "stream",
+			ID:   stream.ID,
+			Name: fmt.Sprintf("%s port %d", stream.GetProtocol(), stream.IncomingPort),
+		})
+	}
+
+	listen := regexp.MustCompile(`listen\s+(\S*:)?` + strconv.Itoa(port) + `[\s;]`)
+	conflicts = append(conflicts, f.configsMatching(listen)...)
+	return limitConflicts(conflicts)
+}
+
+// forwardTargets finds the hosts and streams forwarding to target, which may
+// include a port
+func (f *conflictFinder) forwardTargets(target string) []ValidationConflict {
+	host := target
+	if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+
+	var conflicts []ValidationConflict
+
+	var proxyHosts []models.ProxyHost
+	f.scoped(&models.ProxyHost{}).Where("forward_host = ?", host).Select("id", "domain_names").Find(&proxyHosts)
+	for _, proxyHost := range proxyHosts {
+		conflicts = append(conflicts, ValidationConflict{Type: "proxy_host", ID: proxyHost.ID, Name: proxyHost.GetPrimaryDomain()})
+	}
+
+	var streams []models.Stream
+	f.scoped(&models.Stream{}).Where("forwarding_host = ?", host).Find(&streams)
+	for _, stream := range streams {
+		conflicts = append(conflicts, ValidationConflict{
+			Type: "stream",
+			ID:   stream.ID,
+			Name: fmt.Sprintf("%s port %d", stream.GetProtocol(), stream.IncomingPort),
+		})
+	}
+
+	return limitConflicts(conflicts)
+}
+
+// certificateFilePattern matches the certificate files written for a certificate ID
+var certificateFilePattern = regexp.MustCompile(`(?:cert|key)_(\d+)\.pem$`)
+
+// certificateUsers finds the certificate behind a certificate file path and
+// the proxy hosts using it
+func (f *conflictFinder) certificateUsers(path string) []ValidationConflict {
+	match := certificateFilePattern.FindStringSubmatch(path)
+	if match == nil {
+		return nil
+	}
+	certificateID, _ := strconv.ParseUint(match[1], 10, 32)
+
+	var conflicts []ValidationConflict
+
+	var certificate models.Certificate
+	if err := f.scoped(&models.Certificate{}).Where("id = ?", certificateID).First(&certificate).Error; err == nil {
+		conflicts = append(conflicts, ValidationConflict{Type: "certificate", ID: certificate.ID, Name: certificate.Name})
+	}
+
+	var proxyHosts []models.ProxyHost
+	f.scoped(&models.ProxyHost{}).Where("certificate_id = ?", certificateID).Select("id", "domain_names").Find(&proxyHosts)
+	for _, proxyHost := range proxyHosts {
+		conflicts = append(conflicts, ValidationConflict{Type: "proxy_host", ID: proxyHost.ID, Name: proxyHost.GetPrimaryDomain()})
+	}
+
+	return limitConflicts(conflicts)
+}
+
+// generatedNamePattern matches upstream and zone names generated for a proxy host
+var generatedNamePattern = regexp.MustCompile(`^proxy_host_(\d+)(?:_limit)?$`)
+
+// generatedName finds the proxy host an upstream or zone name was generated
+// for, and the configurations declaring the same name
+func (f *conflictFinder) generatedName(name string) []ValidationConflict {
+	var conflicts []ValidationConflict
+
+	if match := generatedNamePattern.FindStringSubmatch(name); match != nil {
+		var proxyHost models.ProxyHost
+		if err := f.scoped(&models.ProxyHost{}).Where("id = ?", match[1]).First(&proxyHost).Error; err == nil {
+			conflicts = append(conflicts, ValidationConflict{Type: "proxy_host", ID: proxyHost.ID, Name: proxyHost.GetPrimaryDomain()})
+		}
+	}
+
+	declaration := regexp.MustCompile(`(upstream\s+|zone=)` + regexp.QuoteMeta(name) + `[\s{:]`)
+	conflicts = append(conflicts, f.configsMatching(declaration)...)
+	return limitConflicts(conflicts)
+}
+
+// configsMatching finds the nginx configurations whose content matches pattern
+func (f *conflictFinder) configsMatching(pattern *regexp.Regexp) []ValidationConflict {
+	var configs []models.NginxConfig
+	f.scoped(&models.NginxConfig{}).Select("id", "name", "content").Find(&configs)
+
+	var conflicts []ValidationConflict
+	for _, config := range configs {
+		if pattern.MatchString(config.Content) {
+			conflicts = append(conflicts, ValidationConflict{Type: "nginx_config", ID: config.ID, Name: config.Name})
+		}
+	}
+	return conflicts
+}
+
+// limitConflicts caps the number of conflicting sources reported
+func limitConflicts(conflicts []ValidationConflict) []ValidationConflict {
+	if len(conflicts) > maxValidationConflicts {
+		return conflicts[:maxValidationConflicts]
+	}
+	return conflicts
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nguyendkn/nginx-manager/pkg/logger"
+)
+
+const (
+	wsWriteWait = 10 * time.Second // time allowed to write one message
+	wsPongWait  = 60 * time.Second // time allowed between pongs before the client is dropped
+)
+
+// keepAlive sets the client's read deadline, extends it on every pong and
+// pings the client until the returned stop function is called. A client that
+// stops answering makes the read loop time out, which removes it.
+func (s *MonitoringService) keepAlive(client *wsClient) (stop func()) {
+	pongWait := s.pongWait
+	client.conn.SetReadDeadline(time.Now().Add(pongWait))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	done := make(chan struct{})
+	go s.pingClient(client, pongWait*9/10, done) // pings must come more often than pongWait
+	return func() { close(done) }
+}
+
+// pingClient pings a client every period until done is closed. A failed ping
+// closes the connection so the read loop ends and removes the client right
+// away instead of waiting for the pong deadline.
+func (s *MonitoringService) pingClient(client *wsClient, period time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			client.writeMutex.Lock()
+			err := client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+			client.writeMutex.Unlock()
+			if err != nil {
+				logger.Debug("Failed to ping WebSocket client", logger.Err(err))
+				client.conn.Close()
+				return
+			}
+		}
+	}
+}
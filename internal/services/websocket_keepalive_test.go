@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// TestKeepAliveReapsDeadClient connects a client that never reads, so it
+// never answers pings, and checks that the server drops it
+func TestKeepAliveReapsDeadClient(t *testing.T) {
+	s := NewMonitoringService(nil)
+	s.SetNginxRunner(&MockNginxRunner{})
+	s.pongWait = 200 * time.Millisecond
+	server := newWebSocketTestServer(t, s)
+
+	conn, err := dialWebSocket(server, "client_id=dead")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	waitForClients(t, s, 1, time.Second)
+	waitForClients(t, s, 0, 5*time.Second)
+}
+
+// TestKeepAliveKeepsResponsiveClient checks that a client answering pings
+// outlives the pong deadline
+func TestKeepAliveKeepsResponsiveClient(t *testing.T) {
+	s := NewMonitoringService(nil)
+	s.SetNginxRunner(&MockNginxRunner{})
+	s.pongWait = 200 * time.Millisecond
+	server := newWebSocketTestServer(t, s)
+
+	conn, err := dialWebSocket(server, "client_id=alive")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Reading runs the default ping handler, which answers with a pong
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	waitForClients(t, s, 1, time.Second)
+	time.Sleep(4 * s.pongWait)
+	waitForClients(t, s, 1, time.Second)
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nguyendkn/nginx-manager/internal/models"
+)
+
+// MetricCatalogEntry identifies a metric series that can be queried
+type MetricCatalogEntry struct {
+	MetricType string `json:"metric_type"`
+	MetricName string `json:"metric_name"`
+	BuiltIn    bool   `json:"built_in"`
+}
+
+// WidgetValidationError holds field-level dashboard widget errors, keyed as
+// "widgets[i].field"
+type WidgetValidationError struct {
+	Fields map[string][]string
+}
+
+func (e *WidgetValidationError) Error() string {
+	return fmt.Sprintf("invalid dashboard widgets (%d field(s))", len(e.Fields))
+}
+
+// builtInMetrics are the series written by StoreSystemMetrics and the HTTP
+// metrics middleware; they are valid before any samples have been stored
+var builtInMetrics = []MetricCatalogEntry{
+	{MetricType: "system", MetricName: "cpu_usage"},
+	{MetricType: "system", MetricName: "load_avg_1"},
+	{MetricType: "system", MetricName: "load_avg_5"},
+	{MetricType: "system", MetricName: "load_avg_15"},
+	{MetricType: "system", MetricName: "memory_usage"},
+	{MetricType: "system", MetricName: "memory_used_bytes"},
+	{MetricType: "system", MetricName: "disk_usage"},
+	{MetricType: "system", MetricName: "disk_used_bytes"},
+	{MetricType: "system", MetricName: "goroutines"},
+	{MetricType: "system", MetricName: "gc_runs"},
+	{MetricType: "network", MetricName: "bytes_in_rate"},
+	{MetricType: "network", MetricName: "bytes_out_rate"},
+	{MetricType: "network", MetricName: "bytes_recv_total"},
+	{MetricType: "network", MetricName: "bytes_sent_total"},
+	{MetricType: "http", MetricName: "request_count"},
+	{MetricType: "http", MetricName: "error_count"},
+	{MetricType: "http", MetricName: "avg_latency_ms"},
+	{MetricType: "http", MetricName: "max_latency_ms"},
+	{MetricType: HealthCheckMetricType, MetricName: "upstream_up"},
+	{MetricType: HealthCheckMetricType, MetricName: "upstream_latency_ms"},
+	{MetricType: "nginx", MetricName: "active_connections"},
+	{MetricType: "nginx", MetricName: "reading"},
+	{MetricType: "nginx", MetricName: "writing"},
+	{MetricType: "nginx", MetricName: "waiting"},
+	{MetricType: "nginx", MetricName: "requests_total"},
+}
+
+var (
+	validWidgetTypes        = []string{"chart", "metric", "table", "gauge"}
+	validWidgetDataSources  = []string{"metrics", "logs", "nginx_status"}
+	validWidgetAggregations = []string{"avg", "sum", "min", "max", "p50", "p95", "p99"}
+	validWidgetWindows      = []string{"5m", "1h", "1d", "1w"}
+
+	// widgetFilterKeys lists the filter keys accepted per non-metric data source
+	widgetFilterKeys = map[string][]string{
+		"logs":         {"level", "status", "method", "host", "path", "message"},
+		"nginx_status": {"field", "server", "upstream"},
+	}
+
+	widgetMetricQueryPattern = regexp.MustCompile(`^([a-z0-9_]+)\.([a-z0-9_]+)$`)
+	widgetStatusPattern      = regexp.MustCompile(`^([1-5][0-9]{2}|[1-5]xx)$`)
+)
+
+// GetMetricCatalog returns the built-in metrics plus every series that has stored samples
+func (as *AnalyticsService) GetMetricCatalog() ([]MetricCatalogEntry, error) {
+	var stored []MetricCatalogEntry
+	if err := as.metricsQuery().
+		Distinct("metric_type", "metric_name").
+		Scan(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	catalog := make([]MetricCatalogEntry, 0, len(builtInMetrics)+len(stored))
+	for _, entry := range builtInMetrics {
+		entry.BuiltIn = true
+		seen[entry.MetricType+"."+entry.MetricName] = true
+		catalog = append(catalog, entry)
+	}
+	for _, entry := range stored {
+		if key := entry.MetricType + "." + entry.MetricName; !seen[key] {
+			seen[key] = true
+			catalog = append(catalog, entry)
+		}
+	}
+
+	sort.Slice(catalog, func(i, j int) bool {
+		if catalog[i].MetricType != catalog[j].MetricType {
+			return catalog[i].MetricType < catalog[j].MetricType
+		}
+		return catalog[i].MetricName < catalog[j].MetricName
+	})
+
+	return catalog, nil
+}
+
+// ValidateWidgets checks each widget's type, data source and query, returning
+// a *WidgetValidationError on failure. Metric widgets must reference a series
+// in the catalog ("metric_type.metric_name") and may set "aggregation" and
+// "window" in their configuration; log and nginx_status widgets take a
+// space-separated "key:value" filter.
+func (as *AnalyticsService) ValidateWidgets(widgets []models.DashboardWidget) error {
+	if len(widgets) == 0 {
+		return nil
+	}
+
+	catalog, err := as.GetMetricCatalog()
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(catalog))
+	for _, entry := range catalog {
+		known[entry.MetricType+"."+entry.MetricName] = true
+	}
+
+	fields := make(map[string][]string)
+	for i := range widgets {
+		widget := &widgets[i]
+		addError := func(field, message string) {
+			key := fmt.Sprintf("widgets[%d].%s", i, field)
+			fields[key] = append(fields[key], message)
+		}
+
+		if strings.TrimSpace(widget.Title) == "" {
+			addError("title", "title is required")
+		}
+		if !containsString(validWidgetTypes, widget.Type) {
+			addError("type", fmt.Sprintf("type must be one of: %s", strings.Join(validWidgetTypes, ", ")))
+		}
+
+		query := strings.TrimSpace(widget.Query)
+		switch widget.DataSource {
+		case "metrics":
+			validateMetricWidgetQuery(query, known, addError)
+			validateMetricWidgetConfig(widget.Configuration, addError)
+		case "logs", "nginx_status":
+			for _, message := range validateWidgetFilter(query, widgetFilterKeys[widget.DataSource]) {
+				addError("query", message)
+			}
+		default:
+			addError("data_source", fmt.Sprintf("data_source must be one of: %s", strings.Join(validWidgetDataSources, ", ")))
+		}
+	}
+
+	if len(fields) > 0 {
+		return &WidgetValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// validateMetricWidgetQuery checks a "metric_type.metric_name" query against the catalog
+func validateMetricWidgetQuery(query string, known map[string]bool, addError func(field, message string)) {
+	if query == "" {
+		addError("query", "query is required for metrics widgets")
+		return
+	}
+	if !widgetMetricQueryPattern.MatchString(query) {
+		addError("query", fmt.Sprintf("query %q must have the form metric_type.metric_name", query))
+		return
+	}
+	if !known[query] {
+		addError("query", fmt.Sprintf("unknown metric %q; see /analytics/metrics/catalog", query))
+	}
+}
+
+// validateMetricWidgetConfig checks the optional aggregation and window settings
+func validateMetricWidgetConfig(config models.JSON, addError func(field, message string)) {
+	if config == nil {
+		return
+	}
+
+	if value, ok := config["aggregation"]; ok {
+		aggregation, isString := value.(string)
+		if !isString || !containsString(validWidgetAggregations, aggregation) {
+			addError("configuration.aggregation",
+				fmt.Sprintf("aggregation must be one of: %s", strings.Join(validWidgetAggregations, ", ")))
+		}
+	}
+
+	if value, ok := config["window"]; ok {
+		window, isString := value.(string)
+		if !isString || !containsString(validWidgetWindows, window) {
+			addError("configuration.window",
+				fmt.Sprintf("window must be one of: %s", strings.Join(validWidgetWindows, ", ")))
+		}
+	}
+}
+
+// validateWidgetFilter parses a space-separated list of key:value terms and
+// returns a message for every malformed term or unsupported key. An empty
+// filter matches everything.
+func validateWidgetFilter(filter string, allowedKeys []string) []string {
+	var messages []string
+	for _, term := range strings.Fields(filter) {
+		key, value, found := strings.Cut(term, ":")
+		if !found || key == "" || value == "" {
+			messages = append(messages, fmt.Sprintf("filter term %q must have the form key:value", term))
+			continue
+		}
+		if !containsString(allowedKeys, key) {
+			messages = append(messages, fmt.Sprintf("unsupported filter key %q; allowed keys: %s", key, strings.Join(allowedKeys, ", ")))
+			continue
+		}
+		if key == "status" && !widgetStatusPattern.MatchString(value) {
+			messages = append(messages, fmt.Sprintf("status %q must be an HTTP status code or class such as 5xx", value))
+		}
+	}
+	return messages
+}
+
+// containsString reports whether value is in values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Machine-readable codes sent as error_code in error responses, so clients
+// can tell apart errors that share an HTTP status
+const (
+	CodeTemplateNotFound        = "TEMPLATE_NOT_FOUND"
+	CodeTemplateRenderFailed    = "TEMPLATE_RENDER_FAILED"
+	CodeTemplateValidation      = "TEMPLATE_VALIDATION_FAILED"
+	CodeTemplateDuplicate       = "TEMPLATE_DUPLICATE"
+	CodeTemplateInUse           = "TEMPLATE_IN_USE"
+	CodeTemplateVersionNotFound = "TEMPLATE_VERSION_NOT_FOUND"
+
+	CodeConfigNotFound         = "CONFIG_NOT_FOUND"
+	CodeConfigValidationFailed = "CONFIG_VALIDATION_FAILED"
+	CodeConfigInUse            = "CONFIG_IN_USE"
+	CodeConfigDuplicate        = "CONFIG_DUPLICATE"
+
+	CodeDomainDuplicate = "DOMAIN_DUPLICATE"
+
+	CodeBackupFailed     = "BACKUP_FAILED"
+	CodePermissionDenied = "PERMISSION_DENIED"
+
+	// User management codes, whose sentinels live in the services package
+	CodeUserNotFound     = "USER_NOT_FOUND"
+	CodeEmailTaken       = "EMAIL_TAKEN"
+	CodeLastAdmin        = "LAST_ADMIN"
+	CodeDeleteSelf       = "DELETE_SELF"
+	CodeUnknownRole      = "UNKNOWN_ROLE"
+	CodeRolesRequired    = "ROLES_REQUIRED"
+	CodePasswordTooShort = "PASSWORD_TOO_SHORT"
+
+	// Generic codes for errors without a sentinel of their own
+	CodeInvalidPayload   = "INVALID_PAYLOAD"
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeBadRequest       = "BAD_REQUEST"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeConflict         = "CONFLICT"
+	CodePayloadTooLarge  = "PAYLOAD_TOO_LARGE"
+	CodeTooManyRequests  = "TOO_MANY_REQUESTS"
+	CodeInternal         = "INTERNAL_ERROR"
+	CodeUnavailable      = "SERVICE_UNAVAILABLE"
+)
+
+// sentinelCodes maps each sentinel to its code. Order matters only for
+// errors wrapping more than one sentinel; the first match wins.
+var sentinelCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrTemplateNotFound, CodeTemplateNotFound},
+	{ErrTemplateRenderFailed, CodeTemplateRenderFailed},
+	{ErrTemplateValidation, CodeTemplateValidation},
+	{ErrTemplateDuplicate, CodeTemplateDuplicate},
+	{ErrTemplateInUse, CodeTemplateInUse},
+	{ErrTemplateVersionNotFound, CodeTemplateVersionNotFound},
+	{ErrConfigNotFound, CodeConfigNotFound},
+	{ErrConfigValidationFailed, CodeConfigValidationFailed},
+	{ErrConfigInUse, CodeConfigInUse},
+	{ErrConfigDuplicate, CodeConfigDuplicate},
+	{ErrDomainDuplicate, CodeDomainDuplicate},
+	{ErrBackupFailed, CodeBackupFailed},
+	{ErrPermissionDenied, CodePermissionDenied},
+}
+
+// statusCodes are the generic codes used when an error has no sentinel code
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusTooManyRequests:       CodeTooManyRequests,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+}
+
+// Code returns the code for an error response: the code of the sentinel err
+// wraps, INVALID_PAYLOAD for a request body that could not be decoded, or
+// else the generic code for the HTTP status
+func Code(status int, err error) string {
+	if err != nil {
+		for _, sc := range sentinelCodes {
+			if errors.Is(err, sc.err) {
+				return sc.code
+			}
+		}
+		if status == http.StatusBadRequest && isPayloadError(err) {
+			return CodeInvalidPayload
+		}
+	}
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// isPayloadError reports whether err came from binding a JSON request body:
+// an empty or truncated body, malformed JSON, a mistyped field or a failed
+// binding tag
+func isPayloadError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var validationErrs validator.ValidationErrors
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || errors.As(err, &validationErrs)
+}
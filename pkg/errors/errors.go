@@ -5,16 +5,21 @@ import "errors"
 // Common service errors
 var (
 	// Template errors
-	ErrTemplateNotFound     = errors.New("template not found")
-	ErrTemplateRenderFailed = errors.New("template render failed")
-	ErrTemplateValidation   = errors.New("template validation failed")
-	ErrTemplateDuplicate    = errors.New("template with this name already exists")
-	ErrTemplateInUse        = errors.New("template is in use")
+	ErrTemplateNotFound        = errors.New("template not found")
+	ErrTemplateRenderFailed    = errors.New("template render failed")
+	ErrTemplateValidation      = errors.New("template validation failed")
+	ErrTemplateDuplicate       = errors.New("template with this name already exists")
+	ErrTemplateInUse           = errors.New("template is in use")
+	ErrTemplateVersionNotFound = errors.New("template version not found")
 
 	// Configuration errors
 	ErrConfigNotFound         = errors.New("configuration not found")
 	ErrConfigValidationFailed = errors.New("configuration validation failed")
 	ErrConfigInUse            = errors.New("configuration is in use")
+	ErrConfigDuplicate        = errors.New("configuration with this name already exists")
+
+	// Host errors
+	ErrDomainDuplicate = errors.New("domain already exists")
 
 	// General errors
 	ErrBackupFailed     = errors.New("backup operation failed")
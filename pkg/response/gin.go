@@ -76,7 +76,7 @@ func ErrorJSON(c *gin.Context, code int, message string, err error) {
 		// logger.Warn(message, logger.String("method", c.Request.Method), logger.String("path", c.Request.URL.Path), logger.Int("status", code))
 	}
 
-	JSON(c, code, response)
+	JSON(c, response.Code, response)
 }
 
 // BadRequestJSON sends a bad request JSON response
@@ -135,29 +135,38 @@ func ListJSON(c *gin.Context, data interface{}, count int, message string) {
 
 // Pagination Helper Functions for Gin
 
-// GetPaginationParams extracts pagination parameters from Gin context
-func GetPaginationParams(c *gin.Context) (page, limit int) {
-	page = 1
-	limit = 10
+// Page sizes used by ParsePagination
+const (
+	DefaultPageLimit = 10
+	MaxPageLimit     = 100
+)
 
-	if pageStr := c.Query("page"); pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
-	}
+// ParsePagination reads ?page= and ?limit= the same way for every list
+// endpoint. ?per_page= is accepted in place of limit for older clients. A
+// missing or invalid value falls back to the default, and limits above
+// MaxPageLimit are capped.
+func ParsePagination(c *gin.Context) PageParams {
+	params := PageParams{Page: 1, Limit: DefaultPageLimit}
 
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		params.Page = p
 	}
 
-	// Set reasonable limits
-	if limit > 100 {
-		limit = 100
+	limitStr, ok := c.GetQuery("limit")
+	if !ok {
+		limitStr = c.Query("per_page")
+	}
+	if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+		params.Limit = min(l, MaxPageLimit)
 	}
 
-	return page, limit
+	return params
+}
+
+// GetPaginationParams extracts pagination parameters from Gin context
+func GetPaginationParams(c *gin.Context) (page, limit int) {
+	params := ParsePagination(c)
+	return params.Page, params.Limit
 }
 
 // GetPaginationParamsWithDefaults extracts pagination parameters with custom defaults
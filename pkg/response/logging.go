@@ -34,7 +34,18 @@ func SuccessJSONWithLog(c *gin.Context, data interface{}, message string) {
 
 // ErrorJSONWithLog sends an error JSON response with comprehensive logging
 func ErrorJSONWithLog(c *gin.Context, code int, message string, err error) {
-	response := Error(code, message, err)
+	sendErrorWithLog(c, Error(code, message, err), err)
+}
+
+// ErrorWithCode sends an error JSON response with an explicit machine-readable
+// error code, for errors that have no pkg/errors sentinel, with logging
+func ErrorWithCode(c *gin.Context, status int, errorCode, message string, err error) {
+	sendErrorWithLog(c, Error(status, message, err).WithErrorCode(errorCode), err)
+}
+
+// sendErrorWithLog logs an error response at a level matching its status and sends it
+func sendErrorWithLog(c *gin.Context, response ErrorResponse, err error) {
+	code := response.Code
 
 	// Prepare log fields
 	fields := []zap.Field{
@@ -42,7 +53,8 @@ func ErrorJSONWithLog(c *gin.Context, code int, message string, err error) {
 		logger.String("path", c.Request.URL.Path),
 		logger.String("ip", c.ClientIP()),
 		logger.Int("status", code),
-		logger.String("message", message),
+		logger.String("error_code", response.ErrorCode),
+		logger.String("message", response.Message),
 	}
 
 	if requestID := c.GetString("request_id"); requestID != "" {
@@ -174,14 +186,18 @@ func ValidationErrorJSONWithLog(c *gin.Context, errors map[string][]string, mess
 
 // PaginatedJSONWithLog sends a paginated JSON response with logging
 func PaginatedJSONWithLog(c *gin.Context, data interface{}, page, limit int, total int64, message string) {
-	response := Paginated(data, page, limit, total, message)
+	PaginatedResponseJSONWithLog(c, Paginated(data, page, limit, total, message))
+}
 
+// PaginatedResponseJSONWithLog sends a built paginated response, such as one
+// with sideloaded records, with logging
+func PaginatedResponseJSONWithLog(c *gin.Context, response PaginatedResponse) {
 	fields := []zap.Field{
 		logger.String("method", c.Request.Method),
 		logger.String("path", c.Request.URL.Path),
-		logger.Int("page", page),
-		logger.Int("limit", limit),
-		logger.Int64("total", total),
+		logger.Int("page", response.Pagination.Page),
+		logger.Int("limit", response.Pagination.Limit),
+		logger.Int64("total", response.Pagination.Total),
 	}
 
 	if requestID := c.GetString("request_id"); requestID != "" {
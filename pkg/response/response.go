@@ -1,7 +1,12 @@
 package response
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
+
+	apperrors "github.com/nguyendkn/nginx-manager/pkg/errors"
 )
 
 // Response represents the standard API response structure
@@ -12,9 +17,12 @@ type Response struct {
 	Timestamp time.Time   `json:"timestamp"`
 }
 
-// ErrorResponse represents an error response with additional error details
+// ErrorResponse represents an error response with additional error details.
+// ErrorCode is a stable machine-readable code such as DOMAIN_DUPLICATE; Code
+// stays the HTTP status and Message is meant for people.
 type ErrorResponse struct {
 	Code      int                    `json:"code"`
+	ErrorCode string                 `json:"error_code"`
 	Message   string                 `json:"message"`
 	Error     string                 `json:"error,omitempty"`
 	Details   map[string]interface{} `json:"details,omitempty"`
@@ -24,17 +32,20 @@ type ErrorResponse struct {
 // ValidationErrorResponse represents validation error response
 type ValidationErrorResponse struct {
 	Code      int                 `json:"code"`
+	ErrorCode string              `json:"error_code"`
 	Message   string              `json:"message"`
 	Errors    map[string][]string `json:"errors"`
 	Timestamp time.Time           `json:"timestamp"`
 }
 
-// PaginatedResponse represents a paginated response
+// PaginatedResponse represents a paginated response. Included carries related
+// records sideloaded by ?include= on list endpoints that support it.
 type PaginatedResponse struct {
 	Code       int         `json:"code"`
 	Message    string      `json:"message"`
 	Data       interface{} `json:"data"`
 	Pagination Pagination  `json:"pagination"`
+	Included   interface{} `json:"included,omitempty"`
 	Timestamp  time.Time   `json:"timestamp"`
 }
 
@@ -48,6 +59,17 @@ type Pagination struct {
 	HasPrev    bool  `json:"has_prev"`
 }
 
+// PageParams is the page requested by a list endpoint
+type PageParams struct {
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+}
+
+// Offset returns the number of records before the page
+func (p PageParams) Offset() int {
+	return GetOffset(p.Page, p.Limit)
+}
+
 // ListResponse represents a simple list response without pagination
 type ListResponse struct {
 	Code      int         `json:"code"`
@@ -138,14 +160,29 @@ func List(data interface{}, count int, message string) ListResponse {
 
 // Error Response Builders
 
-// Error creates a generic error response
+// Error creates a generic error response. The error code is taken from the
+// pkg/errors sentinel err wraps, falling back to a generic code for the status.
+// A request body over the size limit is always reported as 413 with the limit.
 func Error(code int, message string, err error) ErrorResponse {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return ErrorResponse{
+			Code:      StatusRequestEntityTooLarge,
+			ErrorCode: apperrors.CodePayloadTooLarge,
+			Message:   fmt.Sprintf("Request body exceeds the %d byte limit", tooLarge.Limit),
+			Error:     err.Error(),
+			Details:   map[string]interface{}{"limit_bytes": tooLarge.Limit},
+			Timestamp: time.Now(),
+		}
+	}
+
 	errorMsg := ""
 	if err != nil {
 		errorMsg = err.Error()
 	}
 	return ErrorResponse{
 		Code:      code,
+		ErrorCode: apperrors.Code(code, err),
 		Message:   message,
 		Error:     errorMsg,
 		Timestamp: time.Now(),
@@ -207,6 +244,7 @@ func ValidationError(errors map[string][]string, message string) ValidationError
 	}
 	return ValidationErrorResponse{
 		Code:      StatusBadRequest,
+		ErrorCode: apperrors.CodeValidationFailed,
 		Message:   message,
 		Errors:    errors,
 		Timestamp: time.Now(),
@@ -266,12 +304,24 @@ func GetOffset(page, limit int) int {
 
 // Response Utilities
 
+// WithIncluded adds sideloaded records to a paginated response
+func (p PaginatedResponse) WithIncluded(included interface{}) PaginatedResponse {
+	p.Included = included
+	return p
+}
+
 // WithDetails adds additional details to an error response
 func (e ErrorResponse) WithDetails(details map[string]interface{}) ErrorResponse {
 	e.Details = details
 	return e
 }
 
+// WithErrorCode overrides the machine-readable error code
+func (e ErrorResponse) WithErrorCode(errorCode string) ErrorResponse {
+	e.ErrorCode = errorCode
+	return e
+}
+
 // WithError adds an error to the error response
 func (e ErrorResponse) WithError(err error) ErrorResponse {
 	if err != nil {
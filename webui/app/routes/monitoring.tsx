@@ -67,9 +67,9 @@ export default function MonitoringPage() {
 
   // WebSocket connection for real-time updates
   useEffect(() => {
-    const connectWebSocket = () => {
+    const connectWebSocket = async () => {
       try {
-        const ws = monitoringApi.createWebSocket(
+        const ws = await monitoringApi.createWebSocket(
           (message: WebSocketMessage) => {
             switch (message.type) {
               case 'metrics':
@@ -59,12 +59,13 @@ export interface NginxStatus {
 }
 
 export interface ActivityEvent {
-  id: string;
+  id: number;
   timestamp: string;
   type: string;
   message: string;
   level: string;
   details: Record<string, any>;
+  user_id: number | null;
 }
 
 export interface ActivityFeedResponse {
@@ -107,7 +108,7 @@ export interface NginxControlResponse {
 }
 
 export interface WebSocketMessage {
-  type: 'metrics' | 'nginx_status' | 'activity' | 'error';
+  type: 'metrics' | 'nginx_status' | 'alert' | 'activity' | 'subscriptions' | 'error';
   timestamp: string;
   data: any;
 }
@@ -118,6 +119,7 @@ const ENDPOINTS = {
   NGINX_STATUS: '/api/v1/monitoring/nginx-status',
   ACTIVITY_FEED: '/api/v1/monitoring/activity-feed',
   WEBSOCKET: '/api/v1/monitoring/ws',
+  WEBSOCKET_TOKEN: '/api/v1/monitoring/ws-token',
   NGINX_CONTROL: '/api/v1/monitoring/nginx/control',
 } as const;
 
@@ -165,14 +167,21 @@ export const monitoringApi = {
   },
 
   // WebSocket connection for real-time updates
-  createWebSocket: (
+  createWebSocket: async (
     onMessage: (message: WebSocketMessage) => void,
     onError?: (error: Event) => void,
     onClose?: (event: CloseEvent) => void
-  ): WebSocket => {
+  ): Promise<WebSocket> => {
+    // Browsers cannot send an Authorization header on the WebSocket handshake,
+    // so fetch a short-lived token and pass it as a query parameter
+    const tokenResponse = await apiClient.post<ApiResponse<{ token: string; expires_at: string }>>(
+      ENDPOINTS.WEBSOCKET_TOKEN
+    );
+    const token = tokenResponse.data.data?.token ?? '';
+
     // Get the base URL and convert to WebSocket protocol
     const baseUrl = apiClient.defaults.baseURL || window.location.origin;
-    const wsUrl = baseUrl.replace(/^http/, 'ws') + ENDPOINTS.WEBSOCKET;
+    const wsUrl = baseUrl.replace(/^http/, 'ws') + ENDPOINTS.WEBSOCKET + '?token=' + encodeURIComponent(token);
 
     const ws = new WebSocket(wsUrl);
 